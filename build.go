@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+// buildJob is an image build scheduled by buildAll.
+type buildJob struct {
+	name  string   // client or simulator name
+	bases []string // base images used by the Dockerfile
+	build func(ctx context.Context) error
+
+	deps []*buildJob
+	done chan struct{}
+	err  error
+}
+
+// buildAll runs the given image builds, at most 'parallelism' at a time.
+//
+// Builds are ordered by their base images: the first build using a certain base image runs
+// before all other builds using the same base, so the base is pulled (or built) only once
+// and later builds can reuse its cached layers. The function returns when all builds have
+// finished. Build errors are stored in the jobs.
+func buildAll(ctx context.Context, parallelism int, jobs []*buildJob) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	leaders := make(map[string]*buildJob)
+	for _, job := range jobs {
+		job.done = make(chan struct{})
+		job.deps = nil
+		for _, base := range job.bases {
+			if leader, ok := leaders[base]; ok {
+				job.deps = append(job.deps, leader)
+			} else {
+				leaders[base] = job
+			}
+		}
+	}
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, parallelism)
+	)
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job *buildJob) {
+			defer wg.Done()
+			defer close(job.done)
+			// Wait for dependencies. Their errors are ignored here, the build
+			// of this image will fail by itself if the base is unavailable.
+			for _, dep := range job.deps {
+				select {
+				case <-dep.done:
+				case <-ctx.Done():
+					job.err = ctx.Err()
+					return
+				}
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				job.err = ctx.Err()
+				return
+			}
+			job.err = job.build(ctx)
+			<-sem
+		}(job)
+	}
+	wg.Wait()
+}
+
+// buildError reports the failed builds of a buildAll call.
+type buildError struct {
+	kind   string // "clients" or "simulators"
+	failed []*buildJob
+	total  int
+}
+
+// buildFailures returns an error listing all failed jobs, or nil if all builds succeeded.
+func buildFailures(kind string, jobs []*buildJob) error {
+	err := &buildError{kind: kind, total: len(jobs)}
+	for _, job := range jobs {
+		if job.err != nil {
+			err.failed = append(err.failed, job)
+		}
+	}
+	if len(err.failed) == 0 {
+		return nil
+	}
+	return err
+}
+
+func (e *buildError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d of %d %s failed to build:", len(e.failed), e.total, e.kind)
+	for _, job := range e.failed {
+		fmt.Fprintf(&sb, "\n    %s: %v", job.name, job.err)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the error of the first failed build. This makes the hint
+// of the error available to fatal.
+func (e *buildError) Unwrap() error {
+	return e.failed[0].err
+}
+
+// buildLogDir returns the directory of the build logs of a run.
+func buildLogDir(resultsRoot, runID string) string {
+	return filepath.Join(resultsRoot, "builds", runID)
+}
+
+// recordBuildFailures adds the failed jobs of a buildAll call to the build failures
+// of the run. The kind is "client" or "simulator".
+func (r *simRunner) recordBuildFailures(kind string, jobs []*buildJob) {
+	for _, job := range jobs {
+		if job.err == nil {
+			continue
+		}
+		cause, logFile := libhive.BuildFailureOf(job.err)
+		if cause == "" {
+			cause = libhive.BuildCauseUnknown
+		}
+		if logFile != "" {
+			if rel, err := filepath.Rel(r.env.LogDir, logFile); err == nil {
+				logFile = filepath.ToSlash(rel)
+			}
+		}
+		r.buildFailures = append(r.buildFailures, buildFailure{
+			Name:  job.name,
+			Kind:  kind,
+			Cause: cause,
+			Log:   logFile,
+			Error: job.err.Error(),
+		})
+	}
+}
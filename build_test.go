@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+func TestBuildAllOrder(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	job := func(name string, bases ...string) *buildJob {
+		return &buildJob{name: name, bases: bases, build: func(context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}}
+	}
+	jobs := []*buildJob{job("a", "base1"), job("b", "base1"), job("c", "base2"), job("d", "base1", "base2")}
+	buildAll(context.Background(), 4, jobs)
+
+	pos := make(map[string]int)
+	for i, name := range order {
+		pos[name] = i
+	}
+	if len(order) != 4 || pos["b"] < pos["a"] || pos["d"] < pos["a"] || pos["d"] < pos["c"] {
+		t.Fatalf("wrong build order %v", order)
+	}
+}
+
+func TestBuildFailures(t *testing.T) {
+	hintErr := &libhive.Error{Code: libhive.CodeBuildFailed, Message: "can't build a", Hint: "look at the output"}
+	jobs := []*buildJob{
+		{name: "a", err: hintErr},
+		{name: "b"},
+		{name: "c", err: errors.New("pull failed")},
+	}
+	err := buildFailures("clients", jobs)
+	want := "2 of 3 clients failed to build:\n    a: can't build a\n    c: pull failed"
+	if err == nil || err.Error() != want {
+		t.Fatalf("wrong error:\n%v\nwant:\n%s", err, want)
+	}
+	if hint := libhive.ErrorHint(err); hint != hintErr.Hint {
+		t.Errorf("wrong hint %q", hint)
+	}
+	if err := buildFailures("clients", jobs[1:2]); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRecordBuildFailures(t *testing.T) {
+	r := &simRunner{env: libhive.SimEnv{LogDir: "/results"}}
+	buildErr := &libhive.Error{
+		Code:    libhive.CodeBuildFailed,
+		Message: "can't build hive/clients/a:latest",
+		Context: map[string]string{"cause": string(libhive.BuildCauseCompile), "log": "/results/builds/run/clients/a.log"},
+	}
+	jobs := []*buildJob{
+		{name: "a", err: buildErr},
+		{name: "b"},
+		{name: "c", err: errors.New("wrapper failed")},
+	}
+	r.recordBuildFailures("client", jobs)
+
+	want := []buildFailure{
+		{Name: "a", Kind: "client", Cause: libhive.BuildCauseCompile, Log: "builds/run/clients/a.log", Error: buildErr.Error()},
+		{Name: "c", Kind: "client", Cause: libhive.BuildCauseUnknown, Error: "wrapper failed"},
+	}
+	if !reflect.DeepEqual(r.buildFailures, want) {
+		t.Fatalf("wrong build failures\n%+v\nwant\n%+v", r.buildFailures, want)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/hive/internal/libdocker"
+)
+
+// imageRef is a docker image reference, e.g. "ethereum/client-go:v1.10.8@sha256:...".
+type imageRef struct {
+	Repo   string // repository including the registry host
+	Tag    string // may be empty
+	Digest string // "sha256:<hex>", may be empty
+}
+
+var imageDigestRE = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// parseImageRef parses a docker image reference.
+func parseImageRef(s string) (imageRef, error) {
+	var ref imageRef
+	if s == "" || strings.ContainsAny(s, " \t\n") {
+		return ref, fmt.Errorf("invalid image reference %q", s)
+	}
+	if at := strings.IndexByte(s, '@'); at >= 0 {
+		ref.Digest = s[at+1:]
+		s = s[:at]
+		if !imageDigestRE.MatchString(ref.Digest) {
+			return ref, fmt.Errorf("invalid image digest %q, want sha256:<64 hex digits>", ref.Digest)
+		}
+	}
+	// The tag follows the last colon after the last slash. Colons before
+	// the first slash separate the port of the registry host.
+	if colon := strings.LastIndexByte(s, ':'); colon > strings.LastIndexByte(s, '/') {
+		ref.Tag = s[colon+1:]
+		s = s[:colon]
+		if ref.Tag == "" {
+			return ref, fmt.Errorf("empty tag in image reference")
+		}
+	}
+	if s == "" || strings.HasSuffix(s, "/") {
+		return ref, fmt.Errorf("missing repository in image reference")
+	}
+	ref.Repo = s
+	return ref, nil
+}
+
+func (ref imageRef) String() string {
+	s := ref.Repo
+	if ref.Tag != "" {
+		s += ":" + ref.Tag
+	}
+	if ref.Digest != "" {
+		s += "@" + ref.Digest
+	}
+	return s
+}
+
+// pinned returns the reference to the image by its digest.
+func (ref imageRef) pinned() string {
+	return ref.Repo + "@" + ref.Digest
+}
+
+// wrapperBranch returns the value of the 'branch' build argument which makes the
+// Dockerfile in dir use the given image as its base. This works for Dockerfiles with
+// an instruction like 'FROM <repository>:$branch'. The reference must have a digest.
+func wrapperBranch(dir, dockerfile string, ref imageRef) (string, error) {
+	const placeholder = "hive-branch-placeholder"
+	for _, base := range libdocker.DockerfileBaseImages(dir, dockerfile, map[string]string{"branch": placeholder}) {
+		if base == ref.Repo+":"+placeholder {
+			tag := ref.Tag
+			if tag == "" {
+				tag = "latest"
+			}
+			return tag + "@" + ref.Digest, nil
+		}
+	}
+	return "", fmt.Errorf("the Dockerfile does not build on %s:$branch", ref.Repo)
+}
+
+// clientImageSources validates the --client.image and --client.nowrap settings
+// against the client list.
+func clientImageSources(clientList []string, images map[string]string, nowrap []string) (map[string]imageRef, map[string]bool, error) {
+	inList := make(map[string]bool, len(clientList))
+	for _, client := range clientList {
+		inList[client] = true
+	}
+	refs := make(map[string]imageRef, len(images))
+	for client, image := range images {
+		if !inList[client] {
+			return nil, nil, fmt.Errorf("--client.image: client %q is not in the client list", client)
+		}
+		ref, err := parseImageRef(image)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--client.image: client %s: %v", client, err)
+		}
+		refs[client] = ref
+	}
+	skip := make(map[string]bool, len(nowrap))
+	for _, client := range nowrap {
+		if client == "" {
+			continue
+		}
+		if _, ok := refs[client]; !ok {
+			return nil, nil, fmt.Errorf("--client.nowrap: client %q has no --client.image", client)
+		}
+		skip[client] = true
+	}
+	return refs, skip, nil
+}
+
+// checkClientDockerfiles validates the --client.dockerfile setting. Whether the
+// client supports the selected Dockerfile is checked when building the client.
+func checkClientDockerfiles(clientList []string, dockerfiles map[string]string, nowrap map[string]bool) error {
+	inList := make(map[string]bool, len(clientList))
+	for _, client := range clientList {
+		inList[client] = true
+	}
+	for client := range dockerfiles {
+		switch {
+		case !inList[client]:
+			return fmt.Errorf("--client.dockerfile: client %q is not in the client list", client)
+		case nowrap[client]:
+			return fmt.Errorf("--client.dockerfile: client %q uses its --client.image without Dockerfile", client)
+		}
+	}
+	return nil
+}
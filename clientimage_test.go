@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		input string
+		want  imageRef
+		err   string
+	}{
+		{input: "ethereum/client-go", want: imageRef{Repo: "ethereum/client-go"}},
+		{input: "ethereum/client-go:v1.10.8", want: imageRef{Repo: "ethereum/client-go", Tag: "v1.10.8"}},
+		{input: "ethereum/client-go@" + testDigest, want: imageRef{Repo: "ethereum/client-go", Digest: testDigest}},
+		{input: "ethereum/client-go:v1.10.8@" + testDigest, want: imageRef{Repo: "ethereum/client-go", Tag: "v1.10.8", Digest: testDigest}},
+		{input: "registry.example.com:5000/geth", want: imageRef{Repo: "registry.example.com:5000/geth"}},
+		{input: "registry.example.com:5000/geth:rc1", want: imageRef{Repo: "registry.example.com:5000/geth", Tag: "rc1"}},
+		{input: "", err: "invalid image reference"},
+		{input: "geth:", err: "empty tag"},
+		{input: ":v1", err: "missing repository"},
+		{input: "geth@sha256:abc", err: "invalid image digest"},
+	}
+	for _, test := range tests {
+		ref, err := parseImageRef(test.input)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: wrong error %v, want %q", test.input, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if ref != test.want {
+			t.Errorf("%q: wrong result %+v, want %+v", test.input, ref, test.want)
+		}
+		if ref.String() != test.input {
+			t.Errorf("%q: wrong String() %q", test.input, ref.String())
+		}
+	}
+}
+
+func TestWrapperBranch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hive-image-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dockerfile := `ARG branch=latest
+FROM ethereum/client-go:alltools-latest AS tools
+FROM ethereum/client-go:$branch
+`
+	ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(dockerfile), 0644)
+
+	ref := imageRef{Repo: "ethereum/client-go", Tag: "v1.10.8", Digest: testDigest}
+	branch, err := wrapperBranch(dir, "", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v1.10.8@" + testDigest; branch != want {
+		t.Errorf("wrong branch %q, want %q", branch, want)
+	}
+	ref.Tag = ""
+	if branch, _ := wrapperBranch(dir, "", ref); branch != "latest@"+testDigest {
+		t.Errorf("wrong branch without tag %q", branch)
+	}
+	if _, err := wrapperBranch(dir, "", imageRef{Repo: "example/other", Digest: testDigest}); err == nil {
+		t.Error("no error for image of other repository")
+	}
+}
+
+func TestClientImageSources(t *testing.T) {
+	clients := []string{"go-ethereum", "go-ethereum_rc"}
+	images := map[string]string{"go-ethereum_rc": "ethereum/client-go:v1.10.8"}
+
+	refs, nowrap, err := clientImageSources(clients, images, []string{"go-ethereum_rc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs["go-ethereum_rc"].Tag != "v1.10.8" || len(refs) != 1 {
+		t.Errorf("wrong image refs %v", refs)
+	}
+	if !nowrap["go-ethereum_rc"] || len(nowrap) != 1 {
+		t.Errorf("wrong nowrap set %v", nowrap)
+	}
+
+	if _, _, err := clientImageSources(clients, map[string]string{"besu": "hyperledger/besu"}, nil); err == nil {
+		t.Error("no error for image of client not in list")
+	}
+	if _, _, err := clientImageSources(clients, images, []string{"go-ethereum"}); err == nil {
+		t.Error("no error for nowrap client without image")
+	}
+}
+
+func TestCheckClientDockerfiles(t *testing.T) {
+	clients := []string{"lighthouse-bn", "go-ethereum_rc"}
+	nowrap := map[string]bool{"go-ethereum_rc": true}
+
+	if err := checkClientDockerfiles(clients, map[string]string{"lighthouse-bn": "minimal"}, nowrap); err != nil {
+		t.Error(err)
+	}
+	if err := checkClientDockerfiles(clients, map[string]string{"besu": "minimal"}, nowrap); err == nil {
+		t.Error("no error for Dockerfile of client not in list")
+	}
+	if err := checkClientDockerfiles(clients, map[string]string{"go-ethereum_rc": "minimal"}, nowrap); err == nil {
+		t.Error("no error for Dockerfile of nowrap client")
+	}
+}
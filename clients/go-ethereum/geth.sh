@@ -7,7 +7,7 @@
 #  - `genesis.json` file is located in the filesystem root (mandatory)
 #  - `chain.rlp` file is located in the filesystem root (optional)
 #  - `blocks` folder is located in the filesystem root (optional)
-#  - `keys` folder is located in the filesystem root (optional)
+#  - `keys` folder with keystore files is located in the filesystem root (optional)
 #
 # This script assumes the following environment variables:
 #
@@ -30,6 +30,8 @@
 #  - HIVE_FORK_MUIRGLACIER        block number for Muir Glacier transition
 #  - HIVE_FORK_BERLIN             block number for Berlin transition
 #  - HIVE_FORK_LONDON             block number for London
+#  - HIVE_SHANGHAI_TIMESTAMP      timestamp for Shanghai transition
+#  - HIVE_CANCUN_TIMESTAMP        timestamp for Cancun transition
 #
 # Clique PoA:
 #
@@ -43,6 +45,8 @@
 #  - HIVE_SKIP_POW                if set, skip PoW verification during block import
 #  - HIVE_LOGLEVEL		          client loglevel (0-5)
 #  - HIVE_GRAPHQL_ENABLED         enables graphql on port 8545
+#  - HIVE_KEYSTORE_PASSWORD       password of the keystore files in /keys
+#  - HIVE_UNLOCK                  comma separated list of accounts to unlock
 
 # Immediately abort the script on any error encountered
 set -e
@@ -116,6 +120,13 @@ fi
 
 set -e
 
+# Import keystore files.
+if [ -d /keys ]; then
+    echo "Importing keystore..."
+    mkdir -p ~/.ethereum/keystore
+    cp /keys/*.json ~/.ethereum/keystore/
+fi
+
 # Import clique signing key.
 if [ "$HIVE_CLIQUE_PRIVATEKEY" != "" ]; then
     # Create password file.
@@ -129,6 +140,15 @@ if [ "$HIVE_CLIQUE_PRIVATEKEY" != "" ]; then
     fi
 fi
 
+# Unlock keystore accounts, unless the clique signer is unlocked.
+if [ "$HIVE_UNLOCK" != "" ] && [[ "$FLAGS" != *--unlock* ]]; then
+    echo "Unlocking accounts $HIVE_UNLOCK"
+    for account in ${HIVE_UNLOCK//,/ }; do
+        echo "$HIVE_KEYSTORE_PASSWORD" >> /geth-unlock-password.txt
+    done
+    FLAGS="$FLAGS --password /geth-unlock-password.txt --unlock $HIVE_UNLOCK --allow-insecure-unlock"
+fi
+
 # Configure any mining operation
 if [ "$HIVE_MINER" != "" ]; then
 	FLAGS="$FLAGS --mine --miner.threads 1 --miner.etherbase $HIVE_MINER"
@@ -0,0 +1,14 @@
+#!/bin/bash
+
+# Prints the slashing protection database of the validator client
+# in EIP-3076 interchange format.
+
+set -e
+
+lighthouse \
+    --testnet-dir=/data/testnet_setup \
+    account validator \
+    --validator-dir=/data/validators \
+    slashing-protection export /tmp/slashing_protection.json >&2
+
+cat /tmp/slashing_protection.json
@@ -23,6 +23,16 @@ done
 
 cp -r /hive/input/secrets /data/secrets
 
+# Import slashing protection data of a previous validator client in
+# EIP-3076 interchange format.
+if [ -f /hive/input/slashing_protection.json ]; then
+  lighthouse \
+      --testnet-dir=/data/testnet_setup \
+      account validator \
+      --validator-dir=/data/validators \
+      slashing-protection import /hive/input/slashing_protection.json
+fi
+
 LOG=info
 case "$HIVE_LOGLEVEL" in
     0|1) LOG=error ;;
@@ -40,4 +50,4 @@ lighthouse \
     --validators-dir="/data/validators" \
     --secrets-dir="/data/secrets" \
     --init-slashing-protection \
-    --beacon-nodes="http://$HIVE_ETH2_BN_API_IP:$HIVE_ETH2_BN_API_PORT"
+    --beacon-nodes="${HIVE_ETH2_BN_API_ADDRS:-http://$HIVE_ETH2_BN_API_IP:$HIVE_ETH2_BN_API_PORT}"
@@ -28,6 +28,8 @@
 #  - HIVE_FORK_PETERSBURG      block number for ConstantinopleFix/PetersBurg transition
 #  - HIVE_FORK_BERLIN          block number for Berlin transition
 #  - HIVE_FORK_LONDON          block number for London
+#  - HIVE_SHANGHAI_TIMESTAMP   timestamp for Shanghai transition
+#  - HIVE_CANCUN_TIMESTAMP     timestamp for Cancun transition
 #
 # Clique PoA:
 #
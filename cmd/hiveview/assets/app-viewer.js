@@ -84,6 +84,29 @@ var hacks = {
     },
 }
 
+// logStreams holds the logs of the stdout and stderr output of a client, if they
+// were logged separately. The viewer then shows buttons to switch between them.
+var logStreams = null
+
+// loadFile loads the file entered in the input field.
+function loadFile() {
+    logStreams = null
+    $("#streams").hide()
+    fetchFile()
+}
+
+// showStream switches between the combined log and the logs of its output streams.
+function showStream(stream) {
+    let params = new URLSearchParams(location.search)
+    if (stream) {
+        params.set("stream", stream)
+    } else {
+        params.delete("stream")
+    }
+    history.pushState(null, null, "?" + params.toString())
+    navigate()
+}
+
 // fetchFile loads up a new file to view
 function fetchFile(line /* optional jump to line */ ) {
     let url = $("#fileload").val()
@@ -94,7 +117,8 @@ function fetchFile(line /* optional jump to line */ ) {
         success: function(data) {
             hacks.showSpinner(false);
             let newsearch = "?file=" + url;
-            if (window.location.search != newsearch) {
+            // When switching streams, the URL is set by showStream.
+            if (!logStreams && window.location.search != newsearch) {
                 history.pushState(null, null, newsearch);
             }
             document.title = url;
@@ -122,6 +146,20 @@ function navigate() {
     if (params) {
         let f = params.get("file");
         if (f) {
+            logStreams = null
+            $("#streams").hide()
+            if (params.get("stdout") && params.get("stderr")) {
+                logStreams = {stdout: params.get("stdout"), stderr: params.get("stderr")}
+                let stream = params.get("stream")
+                if (stream == "stdout" || stream == "stderr") {
+                    f = logStreams[stream]
+                } else {
+                    stream = "all"
+                }
+                $("#streams button").removeClass("active")
+                $("#stream-" + stream).addClass("active")
+                $("#streams").show()
+            }
             $("#fileload").val(f)
             hacks.showText("viewer", "Loading file...");
             fetchFile(num);
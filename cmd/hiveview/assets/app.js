@@ -163,22 +163,32 @@ function progress(message) {
     $("#debug").text((new Date()).toLocaleTimeString() + " | " + message + "\n" + a);
 }
 
-function resultStats(fails, success, total) {
+function resultStats(fails, success, total, skips) {
     f = parseInt(fails), s = parseInt(success);
     t = parseInt(total);
     f = isNaN(f) ? "?" : f;
     s = isNaN(s) ? "?" : s;
     t = isNaN(t) ? "?" : t;
-    return '<b><span class="text-danger">' + f +
+    let txt = '<b><span class="text-danger">' + f +
         '</span>&nbsp;:&nbsp;<span class="text-success">' + s +
         '</span> &nbsp;/&nbsp;' + t + '</b>';
+    if (skips > 0) {
+        txt += ' <span class="text-muted">(' + skips + ' skipped)</span>';
+    }
+    return txt;
 }
 
-function logview(data, name) {
+// logview returns a link to the viewer. If the stdout and stderr output of a client
+// is logged separately, the viewer can switch between the streams.
+function logview(data, name, stdout, stderr) {
     if (!name) {
         name = "log"
     }
-    return utils.get_link("viewer.html?file=" + escape(data), name)
+    let url = "viewer.html?file=" + escape(data)
+    if (stdout && stderr) {
+        url += "&stdout=" + escape(stdout) + "&stderr=" + escape(stderr)
+    }
+    return utils.get_link(url, name)
 }
 
 function onFileListing(data, error) {
@@ -225,8 +235,21 @@ function onFileListing(data, error) {
             },
             {
                 title: "Suite",
-                data: "name",
+                data: null,
                 width: "20%",
+                render: function(data) {
+                    let name = utils.html_encode(data.name);
+                    if (data.tags) {
+                        Object.keys(data.tags).sort().forEach(function(key) {
+                            let tag = utils.html_encode(key + "=" + data.tags[key]);
+                            name += ' <a class="badge badge-secondary" href="?tag=' + encodeURIComponent(key + "=" + data.tags[key]) + '">' + tag + '</a>';
+                        });
+                    }
+                    if (data.shard) {
+                        name += ' <span class="badge badge-info">shard ' + data.shard.index + '/' + data.shard.count + '</span>';
+                    }
+                    return name;
+                },
             },
             {
                 title: "Clients",
@@ -242,10 +265,14 @@ function onFileListing(data, error) {
                 data: null,
                 width: "9em",
                 render: function(data) {
+                    let skips = data.skips ? ", " + data.skips + " skipped" : ""
+                    if (data.notRun) {
+                        skips += ", " + data.notRun + " not run"
+                    }
                     if (data.fails > 0) {
-                        return "&#x2715; <b>Fail (" + data.fails + " / " + (data.fails + data.passes) + ")</b>"
+                        return "&#x2715; <b>Fail (" + data.fails + " / " + (data.fails + data.passes) + ")</b>" + skips
                     }
-                    return "&#x2713 (" + data.passes + ")"
+                    return "&#x2713 (" + data.passes + skips + ")"
                 },
             },
             {
@@ -272,6 +299,8 @@ function onFileListing(data, error) {
         ],
     });
 
+    showClients(suites);
+
     $('#filetable tbody').on('click', 'button', function() {
         // Documentation about spinners: https://getbootstrap.com/docs/4.4/components/spinners/
         let spinClasses = "spinner-border spinner-border-sm"
@@ -296,10 +325,216 @@ function onFileListing(data, error) {
     });
 }
 
+// showClients groups the suite listing by client version and displays
+// the aggregate results in the client table.
+function showClients(suites) {
+    let byClient = {};
+    suites.forEach(function(suite) {
+        for (let name in suite.clientStats) {
+            let stats = suite.clientStats[name];
+            let key = name + "@" + stats.version;
+            if (!byClient[key]) {
+                byClient[key] = {name: name, version: stats.version, passes: 0, fails: 0, skips: 0, suites: []};
+            }
+            let c = byClient[key];
+            c.passes += stats.passes;
+            c.fails += stats.fails;
+            c.skips += stats.skips || 0;
+            c.suites.push({suite: suite, passes: stats.passes, fails: stats.fails, skips: stats.skips});
+        }
+    });
+    let clients = Object.values(byClient);
+    progress("Got " + clients.length + " client versions");
+
+    let table = $("#clienttable").DataTable({
+        data: clients,
+        pageLength: 50,
+        autoWidth: false,
+        order: [[1, 'asc']],
+        columns: [
+            {
+                className: 'details-control',
+                orderable: false,
+                data: null,
+                defaultContent: '',
+                width: "20px",
+            },
+            {
+                title: "Client",
+                data: "name",
+                width: "20%",
+            },
+            {
+                title: "Version",
+                data: "version",
+                width: "40%",
+                className: "ellipsis",
+            },
+            {
+                title: "Suites",
+                data: "suites",
+                width: "5em",
+                render: function(data) {
+                    return data.length;
+                },
+            },
+            {
+                title: "Results",
+                data: null,
+                width: "12em",
+                render: function(data) {
+                    return resultStats(data.fails, data.passes, data.fails + data.passes, data.skips);
+                },
+            },
+            {
+                title: "Pass rate",
+                data: null,
+                width: "6em",
+                render: function(data, type) {
+                    let total = data.passes + data.fails;
+                    let rate = total ? 100 * data.passes / total : 0;
+                    return type === "display" ? rate.toFixed(1) + "%" : rate;
+                },
+            },
+        ],
+    });
+
+    $('#clienttable tbody').on('click', 'td.details-control', function() {
+        let tr = $(this).closest('tr');
+        let row = table.row(tr);
+        if (row.child.isShown()) {
+            row.child.hide();
+            tr.removeClass('shown');
+        } else {
+            row.child(formatClientSuites(row.data())).show();
+            tr.addClass('shown');
+        }
+    });
+}
+
+// formatClientSuites renders the suites of a client table row.
+function formatClientSuites(client) {
+    let txt = '<div class="details-box"><table class="table table-sm">';
+    client.suites.forEach(function(s) {
+        let load = "loadTestSuite(" + JSON.stringify(s.suite.fileName) + ", function(ok) { if (ok) { openTestSuitePage(" + JSON.stringify(s.suite.fileName) + ") } })";
+        txt += "<tr>";
+        txt += "<td>" + utils.html_encode(new Date(s.suite.start).toISOString()) + "</td>";
+        txt += "<td>" + utils.html_encode(s.suite.name) + "</td>";
+        txt += "<td>" + resultStats(s.fails, s.passes, s.fails + s.passes, s.skips) + "</td>";
+        txt += "<td>" + utils.get_js_link(load, "load") + "</td>";
+        txt += "</tr>";
+    });
+    txt += "</table></div>";
+    return txt;
+}
+
+// runSearch sends the search query to the server and displays the results.
+function runSearch(query) {
+    progress("Searching for " + query);
+    $.ajax("search.jsonl?q=" + encodeURIComponent(query), {
+        success: showSearchResults,
+        error: function(x, status, err) {
+            progress("search failed: " + x.responseText);
+            alert("Search failed: " + x.responseText);
+        },
+    });
+}
+
+// showSearchResults displays the results of a search.
+function showSearchResults(data) {
+    let results = [];
+    data.split("\n").forEach(function(line) {
+        if (line.trim()) {
+            results.push(JSON.parse(line));
+        }
+    });
+    progress("Got " + results.length + " search results");
+
+    if ($.fn.dataTable.isDataTable("#searchresults")) {
+        $("#searchresults").DataTable().clear().rows.add(results).draw();
+        return;
+    }
+    $("#searchresults").DataTable({
+        data: results,
+        pageLength: 50,
+        autoWidth: false,
+        order: [[0, 'desc']],
+        columns: [
+            {
+                title: "Start",
+                data: "start",
+                width: "14em",
+                render: function(data) {
+                    return utils.html_encode(new Date(data).toISOString());
+                },
+            },
+            {
+                title: "Suite",
+                data: null,
+                width: "20%",
+                render: function(data) {
+                    let load = "loadTestSuite(" + JSON.stringify(data.fileName) + ", function(ok) { if (ok) { openTestSuitePage(" + JSON.stringify(data.fileName) + ") } })";
+                    return utils.get_js_link(load, data.suite);
+                },
+            },
+            {
+                title: "Test",
+                data: "test",
+                width: "20%",
+                render: function(data) {
+                    return utils.html_encode(data);
+                },
+            },
+            {
+                title: "Clients",
+                data: "clients",
+                width: "12%",
+                render: function(data) {
+                    return utils.html_encode(data.join(", "));
+                },
+            },
+            {
+                title: "Result",
+                data: null,
+                width: "5em",
+                render: function(data) {
+                    if (data.notRun) {
+                        return "<span class='text-muted' title='time limit'>Not run</span>";
+                    }
+                    if (data.skip) {
+                        return "<span class='text-muted' title='" + data.skip + "'>Skip</span>";
+                    }
+                    return data.pass ? "&#x2713" : "&#x2715; <b>Fail</b>";
+                },
+            },
+            {
+                title: "Match",
+                data: null,
+                render: function(data) {
+                    let txt = "";
+                    if (data.logFile) {
+                        txt += utils.get_link(resultsRoot + data.logFile, "log line " + data.line) + ": ";
+                    }
+                    return txt + utils.html_encode(data.text || "");
+                },
+            },
+        ],
+    });
+}
+
 $(document).ready(function() {
+    // Handle search.
+    $("#searchform").on("submit", function(ev) {
+        ev.preventDefault();
+        let query = $("#searchquery").val().trim();
+        if (query) {
+            runSearch(query);
+        }
+    });
+
     // Retrieve the list of files
     progress("Loading file list...")
-    $.ajax("listing.jsonl", {
+    $.ajax(listingURL(), {
         success: onFileListing,
         failure: function(status, err) {
             alert(err);
@@ -314,6 +549,17 @@ $(document).ready(function() {
     navigationDispatch();
 });
 
+// listingURL returns the URL of the suite listing. Tag filters in the
+// page URL, like ?tag=release=v1.10.8, are passed on to the server.
+function listingURL() {
+    let query = new URLSearchParams();
+    new URLSearchParams(location.search).getAll("tag").forEach(function(tag) {
+        query.append("tag", tag);
+    });
+    let qs = query.toString();
+    return qs ? "listing.jsonl?" + qs : "listing.jsonl";
+}
+
 // navigationDispatch switches to the tab selected by the URL.
 function navigationDispatch() {
     let suite = nav.load("suite");
@@ -387,6 +633,17 @@ function formatTestDetails(d) {
         txt += utils.urls_to_links(utils.html_encode(d.summaryResult.details));
         txt += "</code></pre></p>";
     }
+    if (d.attempts) {
+        d.attempts.forEach(function(attempt, i) {
+            txt += "<p><b>Failed attempt " + (i + 1) + " of " + (d.attempts.length + 1) + "</b>";
+            if (attempt.summaryResult.category) {
+                txt += " (" + utils.html_encode(attempt.summaryResult.category) + ")";
+            }
+            txt += "<pre><code>";
+            txt += utils.urls_to_links(utils.html_encode(attempt.summaryResult.details));
+            txt += "</code></pre></p>";
+        });
+    }
     txt += "</div>";
     return txt;
 }
@@ -480,6 +737,12 @@ function onSuiteData(data, jsonsource) {
                 title: "Status",
                 data: "summaryResult",
                 render: function(summaryResult) {
+                    if (summaryResult.notRun) {
+                        return "<span class='text-muted' title='time limit'>Not run</span>"
+                    };
+                    if (summaryResult.skip) {
+                        return "<span class='text-muted' title='" + summaryResult.skip + "'>Skip</span>"
+                    };
                     if (summaryResult.pass) {
                         return "&#x2713"
                     };
@@ -495,7 +758,9 @@ function onSuiteData(data, jsonsource) {
                     let logs = []
                     for (let instanceID in clientInfo) {
                         let instanceInfo = clientInfo[instanceID]
-                        logs.push(logview("results/" + instanceInfo.logFile, instanceInfo.name))
+                        let stdout = instanceInfo.stdoutLogFile && "results/" + instanceInfo.stdoutLogFile
+                        let stderr = instanceInfo.stderrLogFile && "results/" + instanceInfo.stderrLogFile
+                        logs.push(logview("results/" + instanceInfo.logFile, instanceInfo.name, stdout, stderr))
                     }
                     return logs.join(",")
                 },
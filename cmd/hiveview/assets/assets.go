@@ -212,137 +212,174 @@ var _escData = map[string]*_escFile{
 	"/app-viewer.js": {
 		name:    "app-viewer.js",
 		local:   "assets/app-viewer.js",
-		size:    4520,
-		modtime: 1603658490,
+		size:    5904,
+		modtime: 1792209819,
 		compressed: `
-H4sIAAAAAAAC/6xXX3PjthF/16fYIDdz5JmmYrdP1uk6zU1uclOlk4mdp6YPELE0YYMAA4CWXY++e2cB
-SgRpy+l04gdZIvb//va34HIJNw0C77oCWmMRjAWFzpXwTwO15S3ujL13oBEFgvENWvAN18DBdYq7BkwN
-d7/3aJ8WD9xCw6t7B2t4XgAALD+Efy2/x43UCJVF7tEB14AKW9QelLxH8A2Cx7ZT3CM0aPEq6AHAR28/
-Hb7TLwFSrNnm+RkUGdzvGVSKO7dmum9ZeLhm4ymwTx+XXkxNfHp+hspoT+73+8n5x+XB34flJPQrqHtd
-eWl0pvt2i7YAj48+HxKlP4UevIU1CFP1lFsZ0/0hZpoxb1k+lRYXb4mLRNyLi9Kh/7v3Vm57jxmTghXA
-NgzOoOPW4Vfth9Dyt9RCtUiT6vWWIFWQFW/Yjhlc/u8ZXJZSa7Q3+OhhHco3HtqSdx1q8bmRSmReXOSr
-04eXyaFF31sN3oYn+2IRgbcEh/7HDX26AK9G3jZK3jbRZzic9jRt5buMlUcFFCwvLbbmAT9T8TI2OVqR
-M9N72EnfBFdGidTSt6FH5KHsuKXC5CUX4qQtqUdTGnfzvJq+5RoscsG3CqHX0rtwFr4lOSlTzeFJIrAG
-9j07PpY1kCR8govvLv+aKgQlU8E6fC7D+eTwYA3+kdjb/5mWf3rV8NByZarSmy/yEUV2mcNZ0Fq9gEFj
-dgFxQhJhPTngsO11FYiLMAhGh1JLgdrLWqI4cFNEyqCfFFaY9qsoohV+8woLVEbgf9K5uEU/DMX3T19F
-NDAdJJo24s3RaOk6JX3GftPJENXGQkbyEtbw3QokfIyqpUJ965sVyLOzF6VGH1KCdaTn8kBqmYQzuCii
-hX/Jf+cTtZDFZPLISP5KQ2iJUCWpVlLfgjAaC9BmR67fO9hJi9B3ocy3vfdozyslq3upb1MbzhBmeOTu
-SDcgHQRRFEUqelHCzxYfpOndONkkHOdUpLKXZVhxwapMxGcm/xLFpCAhLgQK8CaE/OsvG2i4ayb0EOiz
-NDp7H+J7X4z4MNu7eQti3QPrZO8y30iXl9x7O9BsPq18I5039qnsetdce+4x071SBcRP9i3xydSKFCxP
-SHGfrxZpbtfooUXPz6WuTTkBHj2mQUxABGfAgPDhCiBXMfhAL1mCzyiceD0Fd0Y+WD5hf3r0gsTTICZz
-TMC67oJ+Moeuk3o+ei5KwTowb8ulhlIZLtCyJNKDZODgMHdsUDzfGivQwvTnuWvZakJsc98hyqgzknvi
-Im0OoHJ4QjfdMyfUX1l0n4fbDNWJCK6WCuEs/KOOJ1zYKwFbhIorhQK2TwHgsUAF8NpT6n1VoXN1rxTU
-6KvmsDEHL0kHiD+L4EbzFtN6nMTCg8QdNSOi4cebnzZU/aS6ejNQ4TAzA/8eNYeL12qOb0rE8h0IdP6P
-w7B8d95bxfLZraexWLMkpeM+2S8Wy2WsxxeqLtXMEalxWtKx4t4ABbk41GcUzwL7LD+A6eiAK7jr244U
-wsGHJRyKF9a0VQOAySx5Ynn5wFUWaWIszDATmbf9IdR3Jb/jj9nYid6qK/oYyU5wz2+eOrwCRqVk48nQ
-+nTXcc9PkFniv+bKYdKSQyIadw65rRrq8d8omTURSm/VVJYmaie1MLtSmYqT53JQ/GY9WpkH8sdcOapO
-He4nv44o8dIrhPXLAI/8PcxAqEtBcvlJnqfO5i/SDE9fyWOePi0cqlq4PJLSqQT2Y/vQWpMS5N3vBTjP
-fe+KePb/dZIrtD5jX7hUcSkSImFoJC2L33T0ckXP4tf4OAZET6P71TxqWlT7xTgwmj/IW2riIdLlEj43
-WN2Hm8/kYqDDTo7LjN48dU8XHGp79PIapoKG67fO2+yigIsc1qHEaV2ineNrz1smLg87d/8y1kAIRCHl
-caw7bnlLzEaE8esvm+uAzJ/D02yG+3xMIqrNF10dg+StI27LGPlLVxxp1vN+v0IpdX4CETPSZRvDBV3u
-SL8sSzYDyUh19KYzPTu+o/X4Yo8tEoEAvgCId9lhJunVi4un7AjqQ06U4DcjXtJUaSM0hlZBzXvl4zV/
-ixXvHYI2vqE8dtzFxSfGC9HJ7E/uEXzkbacw8Giy0ya4GCDEhfjhAbXfSOeRZu19ZzqaFkwvjzk8p1MQ
-BiRfLf4bAAD//6qI6yuoEQAA
+H4sIAAAAAAAC/6xYX3PbNhJ/16fYopmGjGWq9t2TFaZzzTTTzLk3nXNu7qHtA0SsRMQQwAKgFZ+r736z
+ACmClORkOuUDRQH7H7u/XXKxgA81Am+aOWyNRTAWFDpXwL8MrC3f4s7YewcaUSAYX6MFX3MNHFyjuKvB
+rOHj7y3ax9kDt1Dz6t5BCU8zAIDFq/Cz5fd4KzVCZZF7dMA1oMItag9K3iP4GsHjtlHcI9Ro8SbwAcBr
+b9/0z/RPgBQlu316AkUC93sGleLOlUy3WxYWSzbsAnvzeuHFWMSbpyeojPakfr8f7b9e9PpeLUam38C6
+1ZWXRme63a7QzsHjJ593jtKl0IO3UIIwVUu+FdHdH6KnGfOW5WNqcfUcuUjIvbgqHPp/eG/lqvWYMSnY
+HNgtgwtouHX4XvvOtPw5thAt4qR4PUdIEWTzZ2RHD66/3IPrQmqN9gN+8lCG8A2btuBNg1q8raUSmRdX
++fL85nWyadG3VoO3YWU/n4XfxQIc+h9v6e5CetVyUyu5qaPOsDk+0/QoX2SsODCgYHlhcWse8C0FL2Oj
+rSUpM62HnfR1UGWUSCV9Hc6INBQNtxSYvOBCnJUl9SBK427qV91uuQaLXPCVQmi19C7shafEJ2WqaXoS
+CZTAvmeHZbkGooQ3cPXt9d9ThsBkKijDfRH2R5u9NPhnIm//V0r+6aTg7siVqQpv3slPKLLrHC4C1/Io
+DWqzCxknJAHWowMOq1ZXAbgoB8HoEGopUHu5lih6bIqZ0vEngRVm+17MoxT+4QQKVEbg/9K62KDviuL7
+x/ciChgXElWbgzIRWrhGSZ+xX3VSRGtjISN6CSV8uwQJryNroVBvfL0EeXFxFGr0wSUoIzwXPahlEi7g
+ah4l/CJ/y0dswYtR5ZGQ/MSBUBOhSFKspN6AMBrnoM2OVL90sJMWoW1CmDet92gvKyWre6k3qQxnQK6B
+B3Mgwg1IB4EUxTwlvSrgZ4sP0rRuqGwijnUqUtrrIrS4IFUm5BORf4tkUhARFwIFeBNM/s+/b6Hmrh7B
+Q4DPwujsZbDv5XzID7P6OD2CGPeAOtmLzNfS5QX33nYwm48jX0vnjX0smtbVd557zHSr1BzinX1NeDKW
+IgXLE1Dc58tZ6tsdetii55dSr00xSjxahjKmQJdEcAEMKD/cHEhVND7AS5bkZyROtJ5Ld0Y6WD5Cf1o6
+AvHUiFEdU2LdNYE/qUPXSD0tPRepoAzIu+VSQ6EMF2hZvjyiDBgc6o51jJcrYwVaGP+9dFu2HAHbVDdd
+Hc8A7omK9HAAlcMzvGmfOcN+otG97aYZipMDDmupEC7CD514goWtErBCqLhSKGD1GBI8BmgOfO3J9baq
+0Ll1qxSs0Vd13zE7LckJEH7OgxrNt5jG42wuPEjcoe2z4ccPP91S9JPo6tsOCrua6fD3wNkNXstpfpMj
+lu9AoPOfN8Py3WVrFcsnU09tcc0Slw79ZD+bLRagzObOW+RbB7VRwnXh27jQTWoE54VpPXAt6BGtBdP6
+pvW0zwnKUPs5yED8SBJ3aIOEDQpw2HDLParHiEXRYyLV3dGuWu+NduANuJ30VQ0r9DvE0MK2RRjBExvL
+ABmd5Vy8o7Sgh2g3OQmoPVoUILsuqMnYtUQlill/zgfmrD/iYx39sOPiMsuLWgrMIrCFNIoCukCGig6k
+nSPoUlegMtuV1ChIU4hmGmjpXR/WTl1i6yA5i5sHm9HTNNvZjDvC9Tvktqp/Dqs0rHASUbiwms8OxT6S
+Q1eUQ7mTsbjL5p0t+exkkXccAhVSnnVMHfHsC1D/u27SJyne3Hkr9SbrGofmD3LD/RDeQ8C7424b4MHn
+cObehNQaYjacT+iSi1dgGtrgCj6224YYwsarBaTRbK3qgJbEkiaWFw9cdac+FHCH3Zm3bV9SLwr+kX/K
+hgC1Vt3QbWjKgnv+4bHBG2BU8mzY6SAqncm452eabqJ/zZXDfDmiIkc07uKZQwnsO3KmpGi3Vo1pFwv4
+byjGkLI06nT5Nz8MCtKBQ0/IOiRiMRJCGfVVUkDffAM7qYXZFZMEhK/KwbKpc5/PmIF17MR+9O+AkF56
+hVAeO32YXTr8D7GeE11+dsahbMmPvA6rJ/yYuk/DFp1EeHEipnMO7IeUQGtNOhx8/J0KkvvWzePen8sO
+rtD6jL3jUsWBkLIcuuSAC2C/6qjlhtbiY1yOBtFqVL+cWk1D2n42FOFQxP3XkwW8rbG6D1P/aCjWYR6N
+aUWQr9tth8PLA2idCmrh2pXzNruaw1UOZQhxGpco5/DK/5yI637e3B/bGkCG2mfx54B3cCKyTYe8NZQ9
+Em4IgUlfOt4R5/r4hfNUy0rf00+2rlRmqjM2epZT+U7W0VqWn8rzkQlPUcINnJI676aHm5Oy98eSsW+F
+k9CM+szUn56jhF4x/PEHjFeDwhO+0LWGMvHpl8j42xHpyYG3vw5WM64UO+Y9WklOqhuHpp9neOXlA7L8
+GdbLWK30mH6M+QJOSg8CjUl67I+yadIS1/kZ9JkMt+zWcEGdhfiLomATQBpaNX1RGu8dvoW1ePS+MEsI
+AtAF8HmR9fhPMeTiMTsAaH/ooV0N2JTmAk3etaGRe81b5ePnlBVWvHUI2vjQIXfcxRcMUcw+6/3ZeR0/
+8W2jkDSk7w4jDOrgigvxwwNqfyudR8L1l41pCJkxfUnP4SlF3ADG+XL2/wEAsdxyQBAXAAA=
 `,
 	},
 
 	"/app.js": {
 		name:    "app.js",
 		local:   "assets/app.js",
-		size:    17090,
-		modtime: 1625438776,
+		size:    27076,
+		modtime: 1792209819,
 		compressed: `
-H4sIAAAAAAAC/7Q7f3fbuJH/+1PMstmIjCVScpw4li2723hzTZvd7Euy13e1/FKIhCTYFMACoGR34/vs
-9wYAf4pynHYvf8QSMBjMDAbzE4oFVxokVXmq1QchNEzAi9z3yNvbyzVLFUzgtz0AgOiZ+QPP4M+ffno3
-oDwWCeMLNxiZv0u9Sj+bGTqGec5jzQT3lZaBQ2IQRe+oBr2kcPH+J0gEMA1zISFXYQmzJhISmEAi4nxF
-uQ5jSYmmP6YUv/k9TW81kZT0gpNyTRIyzqn8RG+RE6XlSW3L/6GqD297KyAbIimIeW0Ollpn4yhSmsQ3
-Yk3lPBWbMBar6J85VciCikYHo+NXL4cRcljyPmB8cE3WRMWSZXpw/c+cyrs64rdwnSsNieA9DWQhKa1Y
-lFTnkhdUo0wtvff9vT23nPKYZCpPiaaQEE2AccUSCgQ0sYLXZFETtL7L+vD7S1vfZTvF7Hse7Js9T7Y5
-E7nu5KyhSfCD1pLNck2hU6eI1vKrOnW7m3rv1qvRdhsqqssdfW/m9VvUozBImsIEbtsM1JgjaRqqfKa0
-ZHzhv+ybgZTyhV7CAF4Guzi+YFLfQS7TQUakYnwBYm5OJ5cpLIlagqILpLshgwXVn3Hyc0YkWamaHOpC
-QMol1WuCxP923xg3qgkT2DCeiE2Yipjg+hCx1hgZtQSxJhItgFkdqixl2u89rV86VCUfIRlMYHgCDE7N
-IieLE2D7+3UaC8QZYRImBvSSXRWoJ3XUTtprkl4mFI//1w9vX4tVJjgeKyK4HF4FV3jyO6ZHV0GJ7b59
-ghb1rnN6bXRIAeFAeLwUckCtPsFcihX0cq5lrjRNepAyfgM9vJ+9rUPDudpp5TLtA9qu9rGRB2xdw8iR
-lv4uJZ17fdSeBpC2txP/bKtux70s6L1WbZKv1f8PxV5lOMdoQq7Vf8RAYTI/0CwlMVXw64d3ChgHxrNc
-w4bppbU2yJ4zfblM1WctDMfqIX8Fn5aE36h+4ShymUq6oLfGR5RwKUVvChOIfN8/HyPspbo6H0+jaRT4
-5+PL6eBk/HQy3Z8+6U83V/t/DM4vfxj8nQz+NRwcT8Pp4Gr/i38+3mw20/DLNnAbNsBNptHldP9/v59G
-03C6mQ4+Xz0Lzqfn53a36f7k6cn3f8QpnPiDHQ6n302j6XS6uXoWBOdBtCXej9YUoBRCacXpS9ov5QP+
-iuh42b7VbrUJGsJC9y1oH+yKaqv74CsXr/h6Osu1Fhz0XUYnnv3iQZwSpSbeTHOYaT5I6JzkqfbOLuyH
-08gCnm3dRzteO2vB45TFN9+u4xbTQ4qOJKOiO6IfgDTsGFDHUCbZisg78/lWeUHrYrwWXKMpat+PNl7H
-nNcH9yn4lqv0idxQBb+BR7wxeCOvD2EYOjtqbhpiUHCapGeniT4jp1Giz06T5Gx0GiXJWRjaW7YiN/Rz
-QueMM5T455QpXTsAtJttuSPIA6JP0i0PhHfvht7hhW8jLK5m8iBK3fY8iW6J+obeNSGQypBkGeXJ6yVL
-Ez/RLRxm24fC2CTZ2jZpbYvsXN7Qu6uvbZ7Ur1f7nBG8ecBzIVdEf9ZsRVVG6lciGfUhOWifScLmcyTn
-AAaQjJpRwmeMETyvGmRz8M2CUxi2z8ICD7wW3xb9AP928VGEqD8RvQznqRDSbhDBq5eHQ/xXD1Fx5vtJ
-OdWkdtmJ5vnLXVjcTBPJqhPJyx0oXm4jUJ0IRnb9thWCc/AT2Acv8QIYN4RNYH8Cvk/gyxdYBgi4RMCl
-Awy6IVcGcoWQqwISI3kcUN6WU/iME2TLRizzFcFQiiRkllLIOdPWdJtPNaVKRVxXBFSQVMRwCqPhweEO
-V4IA++D9yevQB5ybmP8jg+KbMYdavGG3NPEPkG3vr3/yTr5lm52IfioQ3ff37vf2ogg4WQNTQCBlWqOQ
-NEuZvgMtQGkhKegl4wsTrLhEoA9KgF4SDRkVWUohJtyGmYybVfl8Hu6hbiDuMkGPIBUkcaQpg2xN0hyz
-Xejd0LtescWvH965kH7PMkmS2lHd0Lv2UX3364d3HymR8fIXk4C0xZpJsZBUKd/7UUohxzCTYqOohERQ
-hbmvyrNMSA0tPCG8/RGEhM2S6HOo+bmaiHmeprsNG6ebNlK/TG6UGQ0wGjFcNcLdKHLiN/9bcS3YmnK0
-9Bj4J1Z6aofUzLKm2BpyQeNv0zWYPIrMlktz7qyN1ojbCk9ZtvoGxviITheAhHC6sbug7T3HeNvh0MKF
-e0HTelcLvjP636C0Rc6SoSzuwixXy4+aaOrjmfXB/V9g2iLO3pAyrCyVaEWVIgtabBMLrkRKw1Qsyqm9
-gjE0jk987w8JneULLzDOs+Blaxy5ggskMAhCLd6JmKT0E1vRQgZ4geELoHzcTjgy5ThAgpMGubZQhvwq
-f05Yqvqg8jimChMmoUla0I9+DZN9+pZrCxn0jfkvB906R7auT1lMJyUipn4mP/tzNN54jmNw3lKVc6qa
-UxVCO6erORcOuGvUO52dnWIcUITWKK9BQviCSu+sB/swh/3y+HqnEcKePeUzlZ2M7Z/t5Y4tu151rAe7
-MrJ/EErDPs7OznpNYadisWZ0Y6LFPnCyKrXDmKf6AP7D76jpqVhY53G/tztF8RAzleFSr9LzOUvpBI+b
-qphk1IanbscGRYK/YSl9x5RGzbF0UTR+BR2VTfwvoQHxmijMGbkoMjbFVvUUXCvBU8Zd3lNkQwCtm+Yh
-mp/JimJEHkajF6+Onh+8eH5wNJiTIxo/j4+S4TF5Fc9nRy8Oh8dH86PjZP7qYDR6FeIOXr+JjTtMH+94
-DJoqtNNM0zaY0kRqhNuaYKt3YmGyA0vJwavhYDic0Tk9fDV89XI2ovOjw/lsNjqeHc+OksPn9DAZKLbK
-U6KFxBvdRpkRpajyxjBsTZiL0zGu2L+Qh8PnL1oTccoo17jk8qo1lVBbeGCCI/GflkxZzk0xjiqtYE0l
-mzPjFogGh8v4YYXCMkUgSuIlCL2k0uQdbD6nEoP2lUioCqcc3mpjvBgiRMwbASqjMZszK27Vh5nQS8hN
-IXBB9RKIdUSSGlwxdVv3DTxC6SVV1KAiiAuDrCmf8gH8bUkNKbjcLhrkPKFyYA62STluVV9jtm6CdKLp
-Tbd1yDCCB1O7aC7jRgOtTWBojTRqr/nuLoGROVquyyvzHW+DKwWi0Q3CuZA/knjpl06WpnTVB8YTersV
-o+Bcd7zX5RXF7Bom8JeP738OjbE1qINa0ccSZ5yaf2ngQ3MR+vjJGJi+HTSXwH52eftrd2ZmiKBDwE/F
-3a0VJet7iNm1nbi3f0phbQsvvCCafMLPfsUuCm/sMFZnlJEFfWdKsWN4Ubs7JNfibyzB4TlJFa1mhEyo
-HMPl5bAPPbwpvava/YlFmq+4GsNlQ85NqRunw3RK0bagzADTzJbmVDQ7A7M9re8yRJEQ3bV4Y8n3Rgfh
-C7rqAJCUG1Z21hq6os0iQjCwoRZvP77fjpBKdWpu2vr6gFA6zGxNHsYu72b4YPi9929u/NoZxZ1bxzsB
-it2fb+1u9AL9Pmr3GDyapixTTP1OR2KswrVg3Pf6rRThPzmDX0zNbYccTOi6UwTH/7m6mQoJMmb8Gpxt
-10k6JOE9/cPtwdHoxQmczs7eEJaCj6FKDQ/Gr5GJX/3GqPlifasJcgOMsbzO/e73vk7B89rGFqtB6v1u
-h/NuKzKomwsbdjygol0aauwa2swti7fzANHkfkU5i8DUK/vmGCvjut9RFiQ5/zc19WA4zG5/H2E8pM3o
-6DEQg4kLrk35x6ogjgedq2aawwRTj0eU+NWqXhj3mslKKkhCpQdSYPCuNNG58oBIRgZLliSUTzwtc+qd
-ubyjmarcau8MZQy+yVOQjX3oBQVs0UvodasB2ZgCUYcW2AvonH4fvEsMwa+84CF1Qnb3wbMJESKRZPMN
-iuQc9X1RQnzi96q4AfRMJHe9IBTc75l2QK8PRR+j391SjiK4cLVrUwEAMhO5BpWZNwBqbDpiahxFC6pn
-QmilJclMWywRsYoOw8MoLnqyKiqWNXtmOPo6tVZkAp4DGsyMjkLz60CtvMZik0BNqmAplGLjP/H1kqkA
-Azvc1+9p2QuCEGHrXhzXz12q2DisJohTT4zCHFopMt9LmMINE68PqFzBFk+cSpi41WG8ZGkiKfe90Glr
-EJIkMXz7NQk00aRkRtNOJKi1rqphDATjC6+5VvALwZG1Wl8TL4ZJU1dq0b7Lbo8t5ox9aMU+jr1Q0pVY
-020mmtDo6+zeXfbD8FjjhCbw/q9eR7DVINAYR6/frG+X1i2j/BNV2oRZv5AF9c0xtyDvgaaKPoIiTLvQ
-kdLk8WS9Fqss16i/5E4BF2PjlJ3kW3R01p1JUnJgqe+7Ey2qmLYc9cQvektBKClJ7vyte2x7dZLRNTVZ
-nemuibm5M6pVqijZLcoVYRg6vXoSkmty63upLXmYgkLq9Wvyc/WecbM2UlknlGEum694So1sqwZJqdQ+
-TtSyt8K8lf2HPxOepBQ4WbOFNVDGsLkG/xPfCzlZD1LGbzxj97yiIVpllOtm4WgdmsKu/5uHqZM3BroO
-NZELqkOW3NeEb92seU1DkuTHNeUaOaacSt/LRIa8oSpUtF0wldX64NsTaJyM7bZdg9YsqA3T8ZIq0MIc
-pCYzUDSlsaYJzO6KInVYFam6tnDcGitlah4TwzVqnO/Z+o8j0NxbHGi/hnh/8X4Mc3YLTIMSsKGglmID
-hfI469A3NXR7xNCzV7sHfs4tzWhfAhA8psB0TxmVp0m44woYOmrHJm6QqO33BHtV6X3RZM0cZwBfvoC3
-HmQsTdVgKVZ0oMnMdWxwGU3pyiXctkq+oDVpmNmnTw1UqMmsLphizPdQGF5JkG0CbZmk4mxqx1kW4PBk
-a6e4bc4MVD06LZsZjJuXZNVLxJpG79WqbEa1S0G4sMXIorqvThtcUQG36ze0H4XUhSGoi8Hx3zhN863e
-cTE1JDODu4T1uu+WEhg6IBGcvuEV+8aXs5QWld8yENsvF5VnXINrTm7bwSKar7mPNZFw/c/bJTr3J+GC
-6r98fP+zX39Guw9eVF/Y3x1EWzZ8E0BUdk5ww+8Fxiu2styi4T4wmV1l62/7sMOWVjyZ6jTMqY6XbdZM
-3li6qKBNnk0RoDLGzvlEz/bgGfxC5VzIlUJsmkrzptF2zLwfb2mcm4P8YOTjmTjLXP09eBZV50xvaWyX
-119fYfiKM064PRvC2cJXEJKM+YFrSdnXSokkG9+RFsEnV1832ynTLhVrKkmaOnxoHvI0PcHb897OFM+h
-DXRt6zpoxVQd2Dk3la/My50a/Bs7A26q2dV4I9KESh+dqRK5jGnfVV0LGbjEoAIoCqShF1wOr9w5wBvz
-nMNUiEv0cyFBig0kVJsyQF3g9vkHXq0LO+snNVvyj+QfwOwFFZItGCepjbTF7JrG9gGxKVOLTXkntHmw
-1ztN2LpIrdzGg5m49c56rhl1q2F/At5pdnY6O8No+zSanZ3OZHSG6mfTx9o7cj8JbXdnH7zTKDvzXGpj
-CihhrZIP303MG4ZK9Wtb1Zr75ejs7KJaXRHRBnQJbf2toN9FZY2UIOjYLiqpuK8x4JTCXo+wOKmHWDF0
-GzBDcybp2SmS0MHjY0nvJKKbCbPTaWR2bXNUQSVsXUw5/dW3+qTVP2vbuErFa5potE5pmcc6N+/2nYc0
-xY96s8wlgjUnx5Jmu6hsd13QdXaQQcJUjAbhDtaH3d2vxzSK4iWNb5S5ETOiWAyx4Hi53AsP0+Srb5RJ
-oUUsUhMc4SolVhRuuNhwUDTOJS7cUHLDKaZSYZ0caxn+m0rFBFetjpyH9LwmtnvWahuOtoYqCY22az9V
-YzATYv6RcKbvXiOf/vpwOHoedBSU2qL6AZRZZuWDkliRG7SB5r0Lsb9I4FRvhLwBRXWeAX60glS4LWa0
-HfuUzciD4cFwMDwcHBx8Gh2NRwfj0fNwOHp1eDwcHY7+3rWU8qR74VE4enk8ejF6+fy4c2HjfnSKsuxd
-emNTDeh3Q7irhVTAlH+1XFo79Ld8LnbvTA5fziidHe+EqI67gu3vhizOPyOojp9TDFP1QwsYV5pwzYim
-yQ87jucwPDh6cXg8enVw9PeHcKFXZOn2/pFrRxYMdLSPG3j+RtTbGlnuZB5Z877fVWK7b7Y5Czv1kWpb
-sAXG56IMj8vA9rMRqavYmGJTLZxrQuJN8gJjov1HWnDE1/A/tZzFTDYtRyuf+2CqOEBXmb6DtYUB+4OQ
-2o+Eut7ZPoC3bMt2wNm3Sh2qmtCUOuPeteTrdZOmJItmVkOYXe+RO4VUxLrtMpEJLZnCAGlJJXWmP77Z
-EJkMYrHKiGYz+8TP/OxApAlUyU1NopXoNeIjc00l/CXnFA6GB6PwcUx5VaZZ6OJrwddUajS6yFyZ98RE
-1bvt5U93bsrjLF1I/XDMMtujbgJd3rhW9n0re1oIbXIJu9L9KgpTDFxqBr2yhFNF6BAT3tMwoyApHg5J
-2b9o0ocNOgqaIDsJVVoKU+lYoXbpJb0DelswaVL0WtD+nY3CG1l6NR3GKSXSD0KHtV4aRpHXQGvCbsn6
-LWe6fMnjEpsiIF7W+vftRGZ3B9/Ip7uBPxp+Wwf/4Bs6+JioMKm0mzcvVjn06G1GeNIb2CrnVzpWtSZw
-r4j9Y8G1FGnv3+lBPdThcr/5cA/lx9DrPdQF22qCtTytseCx4IljfwzIxiMbdJ+6nePjmvlHx99/lTT4
-aHL7MWCEAUICF/rxzy90/kC/vxnaPKIN2FjwUHe7mVQg5Y/vcD/f0Zo+2fuW7rjpcZ88JsgqDuPFYxTl
-05Ji5m5D1eJlmKQYpCT1Wt7jW91ffY9hor9HHE4F/VCn1hBfuoH2v9LP25gupm8v0D98HXWBvlzH5wIm
-tYWXFcar3RjEwjmbzqZqHXnoIsV+c9QEVsG3v20wOz/6nUnjBdJDd7jdky3CB0W1gjwz6mKtLE1M8AhF
-F2Ov9vN0NY6izWYTVu4y5FRH9JasspSqiGQskmLz2Zld4666amjdTWCdhFv2evdvjLWsNUPjVCiqtO2x
-Nn/iIsUGJqUbNJ3Zxi+t0UpIsbFtzZCpj0ux4X7Q1q9CYIgOvVJq+lymKg4DMPsDa962CuuSJbT9cks3
-G5c9hfvWie/sDEYRvMcttaOle0N/u7CGc7b1HAQh7tVBT9kK7iCmUXNv1iQL2dYqLa4mgtL13Rv/qrga
-NFmqVV23QbseTVY4nXXvRIsq5+a7SrZb16io4XaiLou6TWqeRXv3e/8XAAD//5oNpr/CQgAA
+H4sIAAAAAAAC/9x8f3fbtpLo//4UU7aNyFiiLMf5JVv27SbN2+ymTU+S7j3v2j5ZiIQkxBSgAqBlt/X7
+7O8MAJLgD8l26rtnz9UftkQMBjMDDDAzmGEiuNIgqcozrT4IoWECwdD9HgY7O7lmmYIJ/LEDADB8bP7B
+Y/j3Tz+9G1CeiJTxuXs4NP8Xepl9Ni10DLOcJ5oJHiotI4fEIBq+oxr0gsLr9z9BKoBpmAkJuYpLmEsi
+IYUJpCLJl5TrOJGUaPpjRvFX2NP0ShNJSS86LPukMeOcyk/0CjlRWh56Q/5fqvrwtrcEsiaSgph5bbDQ
+ejUeDpUmyYW4pHKWiXWciOXwt5wqZEENR/ujly+e7Q2Rw5L3AeODL+SSqESylR58+S2n8tpH/Ba+5EpD
+KnhPA5lLSisWJdW55AXVKFNL701/Z8d1pzwhK5VnRFNIiSbAuGIpBQKaWMFrMvcEra9XfXh4aevr1UYx
+h0EAu2bMwzZnItednNVWEvygtWTTXFPoXFNEa3nrmrraTH1wFXi0XcWK6nLEMJgG/Qb1KAySZTCBqyYD
+HnMky2KVT5WWjM/DZ33zIKN8rhcwgGfRJo5fM6mvIZfZYEWkYnwOYmZmJ5cZLIhagKJzpLsmgznVn7Hx
+84pIslSeHHwhIOWS6kuCxP9xU3tuliZMYM14KtZxJhKC/WPE6jEyagjikkgFE9s7VquM6bD3yFc6XEoh
+QjKYwN4hMDgynZwsDoHt7vo0FohXhEmYGNBTdl6gnvionbQvSXaaUpz+Xz+8fSWWK8FxWhHB6d55dI4z
+v6F5dB6V2G6aM2hRb5qnV2YNKSAcCE8WQg6oXU8wk2IJvZxrmStN0x5kjF9AD/Wz15o0bPNmK5dZH3Dv
+ak4b2bLX1TY50li/C0lnQR9XTw1IW+3Ef+2l26GXBb1fVJPkL+qfQ3FQbZzjAHbhi/pLDBRb5ge6ykhC
+Ffz64Z0CxoHxVa5hzfTC7jbIntv6cpmpz1oYjtW28wo+LQi/UP3ioMhlJumcXpkzooTLqAZJYQLDMAxP
+xgh7qs5PxmfDs2EUnoxPzwaH40eTs92z7/pn6/Pdv0Unpz8M/kEGv+8NXp7FZ4Pz3T/Dk/F6vT6L/2wD
+N2EjHORseHq2+/++PxuexWfrs8Hn88fRydnJiR3tbHfy6PD7v2ETNnxrH8dn35wNz87O1uePo+gkGrbE
++9FuBSiFWFpxhpL2S/lAuCQ6WTS12vU2RkNcrH0L2gfboxrqJrpF8YqfR9Nca8FBX6/oJLA/AkgyotQk
+mGoOU80HKZ2RPNPB8Wv75WhoAY9b+mife3MteJKx5OL+a9xi2rbQkWRc6I7oLZCGHQPqGFpJtiTy2ny/
+UkHUUIxXgmvK2/rRxOuYC/rgvkX3UaVP5IIq+AMCEowhGAV9iOMYbipNQwwKjtLs+CjVx+RomOrjozQ9
+Hh0N0/Q4jq2WLckF/ZzSGeMMJf45Y0p7E4D7ZlPuCLJF9GnWOoEyquGCXgPj0ERYqGa6FaVunjypboj6
+gl7XIZDKmKxWlKevFixLw1RHh+1ht5mxadoaNm0Mi+ycXtDr89sGT331as4zgtcneCbkkujPmi2pWhFf
+JdJRH9L95pykbDZDcvZhAOmobiV8VjCBIKgeshmEpsMR7DXnwgIPggbfFv0A/3fxUZioPxG9iGeZENIO
+MIQXzw728OObqNjy/aRsqlO76ETz5NkmLK6ljmTZieTZBhTP2ghUJ4KR7d/eheAEwhR2IUiDCMY1YRPY
+nUAYEvjzT1hECLhAwIUDjLohlwZyiZDLAhJ2QeEDFbQOhc/YQFp7xCJfEg6SkpRMMwo5Z9pu3eabt6gy
+kfgLARdIJhI4gtHe/sGGowQBdiH4t6BjPWDbxPwdGhT3xhxr8YZd0TTcR7aD//y34PA+w2xE9FOB6Ka/
+c7OzMxwCJ5fAFBDImNYoJM0ypq9BC1BaSAp6wfjcGCvOEeiDEqAXRMOKilVGISHcmpmMm175bBbv4NpA
+3KWDPoRMkNSRpgyyS5LlFMQMehf0ulcM8euHd86k37FMktSbqgt63Zyqb3798O4jJTJZ/GIckKZYV1LM
+JVUqDH6UUsgxTKVYKyohFVSh76vy1UpIDQ08Mbz9EYSE9YLoEwiiGlInYp5n2eaNjdN1E2lYOjfKPI3Q
+GjFc1czd4dCJ3/y14pqzS8pxp1dAeGqlpzZIzXSri60ml4xqsO4aTO5EZuNIc8dZEy1+LFo87BGqb2DM
+GdF5BCAhnK7tKDCB4CSA3QKHFs7ci+q7d9Xhmwk0KW2Qs2Aoi+t4lavFR000DXHO+uD+FphaxFkNKQRY
+LaIlVYrMaTEMxqlERuNMzMumnYIxAhP4Lgy+Tek0nweROTwLXlrPkSt4jQRGUazFO5GQjH5iS1rIABUY
+/gSUjxsJn5xxfECiwxq5NlCG/KpwRlim+qDyJKFK9UELTVCLL9iqnDw83tDnp2+5th2iPij/oevuqNd+
+k0EYHZaImPqZ/BzOcA/H6RyDOzRV2aaqNlUhtG26atOHpSi18bh6R9PjI7QICiMbJTdICZ9TGRz3YBdm
+sFtOZO9oiLDHj/hUrQ7H9l+7u+PM9lcd/cH2HNp/CKVhF1unx73DnWJRGnnCcd2mQLJ3J9CD9rDLXNM0
+OA7NqKbvLvTMtxVNIze0w3+z4+0qGq1qu3tnYn7J6Nq12G2cX4AWdn9ldE1lDG9tEEfpVOTa7B1Kp1RK
+ELlG/1PMgECSMco1ImUK8c5pCoqiImqaXfc9hGbDV2umkwVMqV5Tyh1+SVFrq1XoyDNmdB84WdK+o6Lv
+SChkZfZxBPCFh79hAkEm5oEnhoxqPIewxXGIcc+TGcvoBHWBqoSsqLXdq9mxzD961BjYudo4ScEjC+Qj
+sU+M6j2yHRutiKo9RQ0/05yahruakgr+hmX0HVMa9dsKieIRVRBXnVz/R2hABo2t7I6i4dCIPTWxVwVf
+lOAZ41TVfFaAxn4YIJqfyZKi3xQPR09fPH+y//TJ/vPBjDynyZPkebr3krxIZtPnTw/2Xj6fPX+Zzl7s
+j0YvYhwh6NexcYfp4zVPQFOlQeVM0yaY0kRqhGs1sOU7MTc+nKVk/8XeYG9vSmf04MXei2fTEZ09P5hN
+p6OX05fT5+nBE3qQDhRbYuBZSNx3myhXRCmqgjHsNRrMvtbxXLHfkYeDJ08bDVYnsMvpeaMppTY8xARH
+4j8tmLKcm5ApVVrBJZVsxszhTbTTL2WVB4VlQnWUJAsQekGl8Q7ZbEYl5RqWIqUqPuPwVpsjhiFCxLwW
+oFY0YTNmxa36MBV6AbkJ186pXgCx5oKkBldC3dB9A49QekEVNagI4tJqDGf8jA/g7wtqSNGLotMg5ymV
+A+zZoByH8vvg7wZIJ5reWXsNGUZwYjxNejwslV0b890epbh6zW+nBEbmCiZwem5+oza4gC0ejVE8E/JH
+kizC0hTCUGkfGE/pVcuSxLZuq7zLdhHTLzCB//j4/ufYnIUGdeSF5ixxxvQITw18bBShj9/M7ta3D40S
+2O8uuvLKzZl5RJSy3wrd9ULH/hhi+sVtRvZfKay28OLXRJNP+D2s2EXhjR3Gao5WZE7fmYD5GJ56ukNy
+Lf7OUnw8I5miVYuQKZVjOD3d60MPNaV37ulPIrJ8ydUYTmtyrksdP5rpjOLegjIDDAYE/RaQpdltMO1m
+jHONIUiJ7uq8tuQHo/34KV12AEjKDSsbI0JdPkFhxxnYWIu3H9+37djic9Pf2fJzi1A6ttlKHsa63cju
+/t73f5VXY7fb89kedt7tqulo1nd02NnZRGAQRpO52jQCft5Pv9BEx+hCeB1iJaQOO1S74RxuoluTeSfZ
+6NTsQmBO+HK0puvS9TGCsGYeKcO/JJ1TMH8HiiaCp0ReB4AXDJPgRJP5BA0/yluXQ1uoQAvRmqnIgjFB
+SWEidn1uNtB9s31W1ILIdJsgPX59s9ZnmfGZCI4NJugVnJifsdl6kfphoyEROXeWtW/83o36QvnIkj6c
+kr1yBsDGbSfZCFDo2pNOXTMSw518DAHNMrZSTD3Q9mME+kUwHgb9RtDir4jiF3ML8FXbzcu/vrVmVDsn
+aeJWjPlxAkEfSkUpvKig8KICEzncvti50B9yvm21O7ST2li2mxmMCw0y58HXqJoxSZtO44aJDR59e7X/
+fPT0EI6mx28IyyAsybF4kJqhoTGsPTU/rFlsXJkIXdeg8Dvvr2SOkiceARa758oGUfBga+9dy8j3T362
+7G4vNbBLAY2JguZPy3jZuD7RerpF9wqHNygTlWDXWGHRA8qCpCdffe7vra4eRhi3Kiv7vbINTLzdnS3s
+dxp19ppqbiM8d7hTVUv/JjKox4QwYkxlAFJgQEBponMVAJGMDBYsTSmfBFrmNDh2x0yts77SwTHKGGxg
+BtnYhV4Rjykvb3vdy4CsYdK9CqxCOvu9D8EpetPnQbRtOSG7GHkwcSdEIsn6HgvJ2dw3xZ2NWoi1O9FC
+a+QXLd+Fvco5AD0V6XUvigUPe+ZmtteH4kq5353dMxzCa3eNaIKxQKYi16BWJh1LjU1yghoPh3Oqp0Jo
+pSVZmQyFVCRqeBAfDJPCAlLDols9fQGfvsrsPjOBwAENpmb1Qv3nQC2DWmcUP0wqjyiWYh1+F+oFUxF6
+b0YoPS17URQjrG+qY/+ZM3Zr01gHcQt3AiVaKVZhkDKFA6ZBH3DZRS2eOJUwcb3jBO9LJeVhELt1HMUk
+TQ3foSeBOpqMTGnWiQTXswswm62D8XlQ7yv4a8GRNS/FBFXGxKKWat7UcjdGizmzczQMTsdeLOlSXNI2
+E4c7zVPRjt21sxgePU5oCu//M+iwcGsEmm0TD+4uSLGi/BNV2vhSv5A5DWcdXssN0EzRO1CEsRU8cml6
+d7LQ7s81laDItQIuxub4dpJv0NF5BUjSkgNLfd/NaHRYar8NFnvqD3Mp8pWNE5mdwMQVkf7ptQvZYPhK
+GU3mKaRMrTJyrXZczJHM55LOiaZFhm5xDeX6WhWrIp0dO4+Tp1EcF+0wiYGHXlin7eGZ5/7qKBMskHWk
+wkDElg5z/9GVboGrTMGkDXyKaM7biRLolE2c3wPB32xaKdEqdlJqL+RvCq6c69ZePjUA5J0bf8DOocM7
+rg/TB2tnYQzTLDX7zdhc9puRG8YrbzYtnoKlBCZ1EuodktKkmzga7O8mlLMxCyDzswlTWtAWxv7880/Y
+awF6waw/zA8XjqoY92kpZeCNXUrDG+umfbsXVVdLRWR2UoQb7M1qWAgnOuwIxuMKcB2LzNpdCBq6o4Li
+gG2GMS3c7bE4N8SDBONGfeiRr43FeQ5rL6UaJT1IBNdSZL2vMSi3masuY86lGY2h19tm0rYs2nt695s9
+C3O3cZ8o2p1H/i+7PjYPfbkRoBj94GvjCveLMG6JfahN7QWJDxdSRZAiafvBPKkP9uj6Sl9qtP9g3PmX
+9ZXf3vcd6z5scuj7XuwjOnzQcA9I8tUh5md3l07f+HrbnEmTYAATn+1a4ONwY0/kACYOwQlmsMHjGpqh
+axs3D6PGHCGJMJlMIHC2UAAnBnuZYTUyoZXvAxib54d/wVFDd8w7IbodMp3GrY242zkzIpSeY5JkQlGl
+rb9Td2KkQA+2cpFq1Sdo10ixtv5FzNTHhVjzMGrdmJUgC5bS5qWHrrsDPbQNa6nKnfZ2iTO0uaF277Zb
+lKHJOm1RFCO+jjFLJ6pjwIah3B7BLVzPYFZ+1oSVl6Gxsnk76LTQvulbpLWk7LKIQBSTOhVXwfGRxeya
+7A/zFx3cIkBu0cYbbebmWkCnweZV+M5DALv2LtNW27DZdagsztLfNSvcW2TiIoI/zKqw39r+1F2RRnAD
+N5GX5egSZ4IjLY87H6cmgNm+xClv34pxzM1g4xrOjHk01On9cBcoeUH2bSj8nb2yT8udW5Ubuv/MbuV3
+p9ArjQlxSvt2ZoPtKIaVYAtj2GvDJXZ8NEzZZQHUTj6SObcpg6AoT51u2Ae2oMulISkqL6ms+ZHmuXMd
+PZUpMYYGQTsNxrYaP1tIY4NbQJdSF5Mv5CoMLBEmXSU7+c0m7LTv2WzPvqcbLhFsbNxVO5SzEaqtmtoU
+0lIFrvrghUs2p546wdjwgHXyr2JJ1UpwRbFcsbFjkYxKXTB8t243/Q6Hv8ZGp/ztRuZkVnfZa51rlowt
+JzLPTe7F4R2TLzLGaSvVmnEaa8mWHSeJWyLGIfQSLQyajU5d21Mr8HiempsQ11I6akjQd/GMx2nhk8VM
+Vf5Z8K3tV3bzKf6u3ey5dnjsUiLDCM9VhedR6MCiOJWkdmb5OSc3O7fjbrqNsrls/3fkcNw/faO0tg8e
+zNrecmR0JGz8a2Vs3Pnkr4W5H+TYb2NsnfkbJ6t9whlsNhj4cBOEPGxeorq79QFnaOMCNZ3+V2U1jPb/
+udwWCQwQPKQG2pPsK1XwAaIZ90g6KG7a/cvJXpU33rM8TXqYFQcZWzLdO/7ZJiK4q8rg63J/LtjqIeiq
+JWWgtveOP16w1dfQ5seA0EqGkzIBwSR4NNMiTHLDwy2an7A6+J5r5t4bs/UDg+CWfLlMzN9syULwjPhG
+Brr/IpVd8HEZb2EOaFFV2S2ZMdN2IRjD10yVoWGTZuNiweB/543cnWIkNzs734VF1SqWgZP0OmxFPoZD
++HfC04yWtm3djkL/PDAxlUDl0yXT/hFHL30Z08t4Jekl5drVcDdvh4sXWFTYzZMgwruEMHLWbT2QUnNx
+ik/TA+oycb0aZ8noJTXGvKlKFjNzwa0aVnB5N1kUEMRxHES+y+Ru/3798C7s9ohqpQrVpKBfksv6q082
+OkPWpcGGDp9lpzFnnFyyuU0lMBEvVU5fzMnlABe1m7yiinzD3HFyGZtquPCPAK3gYIzTqYmcUx2z9Caq
++8DuFSQkTX/E+UaOKacyDFZihbzRoO/R9pqplffygHYDTrqZMyzWKYVcq4LE3+41K7Wr2Bg+ETNnmsri
+fhXRIBPYqQ8Zu6BgUkklzShRdHI5ikd78Ys+EEntjVkKgtcdcb8+x5t2z7ErVnNXXWDB6R0rG3/IsjDQ
+ZN7lC2pSyy0wo7qqcdunDwhy2Lqw+61660uzQtAKFiFOICgEaQMBJlqgYNx4HhxWlbCNyXNFTlQVAtRk
+CopmNNE0xZtyN32eRLtWgCdZO8ETsyjRkA3tJUqxE5oEiOYNN77s4P3r92OYsStgGpSANTXuORSK7dIs
++q62CzUQejZHogdhzi3N6ClGIHhCgemeMs4ATeMNuQTu2rVu9O+035FR8mbWpc8aPggis9VfDlYsy9Rg
+IZZ0oMk0qCYTaybczmkrP+fUk4ZpffTIQMWaTGu7snsWBiiMoCTITmfLKSnmxpvOslwJZ9abxVZfKw0/
+AbAs0GXc1I9Vb9fyNpyS1GLnKQXhzmMji2o7dathDOVw/driRyF1YYh8MZSlgd5sml9+FTGybjnHUWol
+e61FYOiAVHD6hlfsm6QoltGiSK/MddstO5Vz7MHVG9tnVJEw6eXhYCn5l9+uFuYGAzcV9CwbFk0w9Dv2
+Nxtelo3QZGJVx5Dghl8MqLgrqQYNN5EJ1YZ3iPtVPJlYIcyotiFLn0IkGcpcn6hJno3FQHVWFkG9xzvw
+GH6hEo0X5Q4HxC3M8QDBj1c0yc1EOl/H2HNG9XewpKqcZ3pFE9vdf6MQXjxhixNuL6rCYGEUkxULI7e7
+m15l3MouuU8LWg2nzCsA8IV1JMuqUCHay4eoPe9tSxGqMtDe0D5oxZQP7GwPlS/N22g8+De2BVzTTq0g
+9Y3IUipD3PuVyGVC+1C/lnFHSAVQRDTjIDrdO3fzAG/M9Y42dpV35WMu0NwVTk3g9joIVeu1bQ3TSpng
+v9P/BmYVVEg2Z5xkRpRY9UUT+1I8bJRiXerEbXdHvUZUf3V8ND3G6As6SEdTOdxwy5H69xsrdKXK7TiN
+vbpHLL4Pgo5qZxyq68phevy66l0RsdPputTefxV2UemR4gcJqluMkoobjwG3KKx6FDeoW1kxdBswQ/NK
+0uMjJKGDx7uS3klENxNmpKOhGbWbI6I1Xa7qiW7V07bd5Vr6wJoWep3pN+bSARy4LStgsAv2ohvEzD7y
+hirD6yO3elqOOFLswBtCSIimcyE7C7gKumy9QVugt2E01Qjb8uEqxrsm994T3E1PxzTfZarb1lbVY/P9
+nFdk3jzaqp3N24CwCZSWeaJz8wpSZxjh87FfUe4SqT3bhqX1muqyJvw1vVztryBlKsFz4BouD7pLxO9S
+TZ0saHKhzEY4JYolkAiOe6p7WY2phPcHWkmhRSIyYxNjLyWWFC64WHNQNMkldlxTcsGpUlTFPjn2QPiv
+IomvXrZuAsGviC0xb9TWj1qPKgmN2mGiqnp+JcTsI+FMX79CPsPLg73Rk6gj6tQU1Q+gTDcrH9DCvNIM
+VG5e3UPsy1U51WshL0BRna8Av1pBKhwWM8I7xikr9vf39vcGeweD/f1Po+fj0f549CTeG704eLk3Ohj9
+o6sr5Wl3x+fx6NnL0dPRsycvOzvW9KVTlFAU+Adjk03f74ZwqoZUwBm/tRDJm/S3WLe4cWRy8GxK6fTl
+RohquivY/mbIYv5XBJfj54xsuGSoUHOlCdeMaJr+sGF6DuL9508PXo5e7D//xzZcLgDYGn/oavYLBjre
+seB/gr8T9dYjy83MHaOGN5vifTf1dwEU+9RHqm1kFrDCtPSKSn/msxGpq3ho1iHXIVGTgshs3OEdD27E
+VzM7PFfVNNZ3joYb/8GkPQEeDddler29IfPed9z1ysAteIvhv+mA25h6ntKMus29q8vtdQd1SRb3RTVh
+dr1asVNIxdnWTPsyHgVTwBQsqKRu608u1kSmA6wWIppN7dvKzBtURZZC5dOquEP0mP4GZKaphP/IOYX9
+vf1RfDemgirAUKzFV4JfUqlBCxNJqxLKifJfSVG+hfiinM7yCPEnx3SzqQ51oNOLc/8dM5WDOS8S0U1P
+L7kBu5qHQRlYrRwzSAjvaZhSkBQnh2Tsd5r2YU2BU5qCFpBSpaUwAa4lMBOcvAZ6VTBpIjOer/aNdb58
+ZrzmMu/BYfUD4ShyD9QTdkPWbznT5etunD9b+EEL7yUXTf91S2o9yqc7Q2K0d78Uif17pEigf8qk0q4d
+cEFy6NGrFeFpb2CrhP5lk/HNDo4vQ3DsjwHZeJD7+Vuy9Z+//P5W0uCjCemMTegchAQu9N3zW3S+NVne
+N23ucGNY67DtQrkG+D9zs9x9th/ekcSHvGJuY27fNf81anEp3L04/8m9RttU2H+3G2x/dT+9i+ZhfC4T
+c2v7u+MNJEWrL/Vj4nevyr81h8SY03dY7RX09vSluXeuNj+l4WSN5IS+fQ2Mw+2oC/RlPz4TMPE6nlYY
+z7dicG+Zm9RQxfbpO2tu421GrTB8M+htQ1EpO4aiUt51qAp081Bi7mySzqr2GsYyo6D2tPO1f9H9kwoM
+JXd+r4mvHKNtW3+z9qKwOhXVCvKVUQp7ONPU+BxQXEkX4EVp+3ptqxCslRVzqof0iixXGVVDsmJDKdaf
+3WltrJyuiPtfLfUw9tAdSj0uiY1UT0rr6aurPQqBITqmgGQmH8PcocEAzPjA9D+9QASvFFbmTZSWlq3V
+I34Y/sGKRxpmrydbL0DnQmko3dC95ba6ionqLFUNHaCHHV5ZhdOdYZ1occm59q4LnpYaFTc+nahbqcul
+x36z8/8HAKP4VAvEaQAA
 `,
 	},
 
@@ -360,7 +397,7 @@ H4sIAAAAAAAC/wEAAP//AAAAAAAAAAA=
 		name:    "details_close.png",
 		local:   "assets/details_close.png",
 		size:    686,
-		modtime: 1603364414,
+		modtime: 1792204166,
 		compressed: `
 H4sIAAAAAAAC/wCuAlH9iVBORw0KGgoAAAANSUhEUgAAABQAAAAUCAYAAACNiR0NAAACdUlEQVR4Aa2V
 30tTYRjHVSgsoQKhXPUPlKZMlImiIIg/pggiiDeCBAdqyC4Kf9zVhVdeelX33QnCbtYvxZsxxkoqKxiz
@@ -373,7 +410,7 @@ uJfPGtkzZ2fBWvZ4PB6DHtqrokaol7/0GqLM2hq2enpsxMmpvf3VVVD8UOJxV42Uet1Wy7J+M5mam8P3
 CKgjUdFQv/Lu7q7JZHxqCl9dLqGtSJtL4VJ80Wvc8xD2V9YfRUbmLZPp5WVstLTYaSWt+lmt1tISKH5Q
 /VFqbGOTl6GNTk7iQ3Oz4FSrU8UfuTqdBJHxceQz+2APe/8Zm+Jgh8PhNxBlt7YQNQysNzVivbEJ7wXG
 iiZEJiZwEI2C4mFgr/aoKnn0jk0TiWfPsTE4iHcN9xBuaMCn/n5sLy7iKJk8ffTqikfvfC4H9laWdX2l
-UqltaDEu9/o664LtIOVfsOf4L+APb5yaiwyN8+8AAAAASUVORK5CYIIBAAD//1c8bPquAgAA
+UqltaDEu9/o664LtIOVfsOf4L+APb5yaiwyN8+8AAAAASUVORK5CYIIDAFc8bPquAgAA
 `,
 	},
 
@@ -381,7 +418,7 @@ UqltaDEu9/o664LtIOVfsOf4L+APb5yaiwyN8+8AAAAASUVORK5CYIIBAAD//1c8bPquAgAA
 		name:    "details_open.png",
 		local:   "assets/details_open.png",
 		size:    709,
-		modtime: 1603364414,
+		modtime: 1792204166,
 		compressed: `
 H4sIAAAAAAAC/wDFAjr9iVBORw0KGgoAAAANSUhEUgAAABQAAAAUCAYAAACNiR0NAAACjElEQVR4Aa2V
 30uTURjHnVBUgglCaXVTJJVLMdhQm7+1JEvJiKAggmgSQiKJl0V0E13UVf0HQZmBLcvlpqa55tqV0YoM
@@ -395,88 +432,133 @@ s7OzETWn6XENa/trYemywuK0IpGsTotgRU3fcVBzouWG6pOnpqY0BhuGGpDXeUjIW7JKcfuX3tjjP9k4
 FBkZN4PtY+042JGzzNMcGDJ35C7tmcU++tJmjE63OpSUuLEJ62GcfXUO+59kr8rJnlOY0WfAGtYaY/PH
 YPv9fhdEY7++4nz/BWS17UPWY0Es2avWp3vOYOTnCCheBtaqHskJr95kZBL3AvdR2XkEex5mYbdQ+qwC
 d97dRTAcXHn1Moyrt16PA2tN//V8jY+Pf4cS/VWerzU9sDay1gd2Xf4F/AZqlpeB9836LwAAAABJRU5E
-rkJgggEAAP//wNSxe8UCAAA=
+rkJgggMAwNSxe8UCAAA=
 `,
 	},
 
 	"/index.html": {
 		name:    "index.html",
 		local:   "assets/index.html",
-		size:    6301,
-		modtime: 1625438328,
+		size:    7977,
+		modtime: 1792204166,
 		compressed: `
-H4sIAAAAAAAC/6xZbXPbNvJ/70+xZec/TuYvkn5KmrqS2yaOm/aah9ZOL3nVWQFLERYIsAAoib25+y73
-8j5Hv9jNgpQs03KSdq4zHYvAYve3zwtk/Nn562dX7988hzJU+mxvzH9Ao5lNEjIJLxDKsz2A8WdpCj/a
-hTIz3UKJRgqHRSAJ0xZeogvKwAurfYDLJRlJ8E1V+u6XMnB0cPjFCJpaIp+IC0cHkKaRc0UBoQyhTum3
-Ri0myTNrApmQXrU1JSC6r0kSaBVyBvgViBKdpzB5e3WRPkluuBisaJIsFC1r68LW2aWSoZxIWihBafwY
-gTIqKNSpF6hpcjgCXzpl5mmwaaHCxNhko/ilcKoOHtARXP/WkGtHMLU2+OCwBjQSJAYMONXk12r5eAa8
-E5OEtfOneS6spKxjkAlb5d3P9Dh7lB1klTLZtU9AmUAzp0I7SXyJR48ep6tXv786whMd5k9PTl6K/Iff
-j+urE/X2UFT088HF/J0/qV2T/7DCnyYJgHDWe+vUTJlJgsaatrKNT87GeYfpXng+oJjXGMpso5yQJiLd
-LOQn2Ul2mF/7m6V7kB8/OUmXxeX5xdHzRwfvj84Pm7fy+uD1cfPy6Q/munl7fvJ9+cX7Jb6Xh+q3Yh6u
-D95a+eS7Z89X+vj17Ikqlk8fJ39emTxnzDf+yAyF/DA7PMiOHjHs3vxMcNUR9PBvs2S3P7u8BEfeNk5Q
-5/wbp0sqsNFhBLVu/Lb7+411GGhl5uBITxIfWk2+JAoJlI6Kv2R34YeGF36n5X+ZW/tkdfLsO//6+P9f
-lKvFk6v8p0dv8F2Y/y00j5vZo6vX9OqXx7On6oL+/ua7i1dfvmzK18XR8U9ffl9cl/db/mNafcABBxH/
-bg+wHmd747yrOHvjyJiFZY5qjYIq+scexP8qXHVJfAqHBwcH9eqrPYB/7gF8TisSjnyjgx/B54XSFDHA
-+mT8SjW2tgmnUKgVya/WTJVZM/3yPp6QSQqotE+ndrVhahfkCm2X6eoUsAl2fTLIDTkXImf15sgUxXzm
-bGPkKTROP9jPe8pfbU0mq81s/yEYmzqqCQMIMoFc/2cNWDTOW3cKtVXr5SjVZb60S/PXxAttPX1cfq8e
-aa1qr/wdU5xCqaQks8bKtTu92VyfW28vSxUo9TUKOgVjlw7rTso478Ngbzy1so2xJ9UChEbvJwnrhcqQ
-SwvdKBljE2BcHp69UAsa5+Xh2bh0eb+8ddDZZU88ZKjTw83O7T2DCyg0rVJhdVMZMLhIa6U1p5+cJP1H
-GnCagLOaJglHm/IhAXQKU+sUmYBBWTNJFuSCEqi3ZAGMcUtWGnMMRVALui2htBV1YjiF0mBnM5bGm+sc
-/HybdgtOD6WPB3+bZ7/pSZMIJCdJcA0lZ1fkA/hGBfLjHD+M9zbQPms+FWtP/ilwN6QDxAVq30P+s2Ar
-8h5n9Mlo1/SfAveGdjfeb6e2CbfwjnOpFpsIvfUxDNf74jXgNO0HoDsR2o9Yt6NvcLZGQ1CgJOB6cm8k
-bunPJ3Svo8YpaU1y2u4I3G2xnK9HZ89XJBoeDgO7rg+28mhAWJ9dleQpEvUhGdsyLlBpLu2jOIwJNDAl
-0BYlyQyeaSXmYA3s/2hR7kOwcQsQBDmuHx2rbJzXA3ld82CNN60kWZuo5HIGgrROp9ZJcjxARJJbRt12
-3QetvDN1/oRxt5PtPvsqa6CnO4Wxr9FEoRub/8oDdHL2yoZSmVlvQGgpjHMmPtvpkl18JHkRB6ruVP1J
-h4TmAuk/cO7GH1tN+QMegW7uTw4PDv7vf+qeHcn/Mf/cqi93HRRLAPRt666Rn1JhHcX9ESwJpJJQ4oL4
-iuPJ+Man0ZB8rYEKldEthKWFwrqKryTgAwaKJLdYQ0yYqbZiLkpOhUgCD1rCEkJJnFz8PxS0hMo6epjB
-VUk92ZL3Z2TI8cVuwHihEFBTKHMmJr5ysay6CZyLCI5q61Wwrs3gwtkKQokhro5ABViiB1rVsVAOOEfC
-LlogEFYeVMUXPgbcI2NJ1NUUWCoOZ95UDngyEo0PthoyJR/SEp0h78lnu4L2lV2OOuFSSbMfYGndHNhv
-NbmCRNBtrDY3uHo61EtsPdSN1tAhGQjXGEta70IGvyyJzc+0LdfeBrVu1yrJbU19nP4NZ+oSh941lgul
-owzOleyYCetcxAoLcqpogRbk2pjxX8P3veF9TUKxyKGVnG14ZLcVMUQDJTpZWDfvgwHr2lkUzKzTI7G2
-9iMoqY1xK2xV8RQ5dCkbMZrg3TuYksDG0zoIClRamVmy0yVXjOHNH//h8fTyd6X/+PesVfDgmzk65Cx8
-CCWy3sqDkoQjLv6uMX30eAg2RMNONYr5ANPUrjJ4bxvwtTLQ1IBgbHzQ8EpyTkgr5lxx1lPoiLPMhQhc
-hXKHnzuxabRuoQQfLVRvqYJI8lF2bUxIn8G3xsYoGEoasN23TpTEd8JAfn8THB1faZeGK7mP62SCcn0H
-5VyDglNvpkLZTAdcH9wpC738yOjnH9+kHcyHGVzaEWDBTkA/59bBFOsMtQO+W8naMRgBah2/txSxbh8M
-EcO2IC0oD/HeCMmyxLDvobWN251GkevXSad+H+QqAM6U8Z3cdWEBjT5E8s4Odz3GDAV6uif6rN/koeOM
-QC5uBMa6KsZVQD33gLG4x3zhBTZQXBrISkq1oK6OJxm8sEvOzFEs+qw+LcjEGsw6eQyK5x2vjCDALk6G
-/LatmcSxSFqwfV1RZhY7Q1cU0IAnIwFNC4ZCrGx9y4oUA9ZLjJ6NxuSkSBsjyUXsHA1QoIv5jtrbrlF1
-VorVs3cJ/x6wLdCH1LdGrGc4LqzWVSQ7Z/ZMLIiSxLzjxhDqo5rDSlO1vmINGEtLPhbDCuexstTWe8XT
-RBdd5/aya1hVrTkzIw/AEDDm4SVryr5GNwyQTsON4zLYFSgbX/LQE8Of7SeVD8oIFnW69nJs4NyCjOBp
-FoK1+m4KjRt9NliKrzJn654XuhuQVrvJlGEk2ARbxSfZJbaj+6mDIwy3szr2nVg0uUxS/CyQ039j2WwX
-w3E+RH7XXJtpiMU9v+gs0ylm3c7p6MLGRhmzGf51DC2h8zH+YuiVcdiPPvLkeEh0jTGskDJQOyub6INs
-gHX9PMcHO99SKCUtbO0zZfOMm6VrjIejk/yLLj77Rl9Q4MTrMfHZHVXFdyUntnlqqrxb45qcfcxA6yeN
-m5Xjs3OaNjyyz8Z5eTy0kOumZsk0PAjX7kNj8H2Xz83P/sc47x5l9uJrabxTscZY1/H1c/0sG9qa+uf7
-a1xgt5r0r7VY14PH13He/WPEfwMAAP//qdyqfJ0YAAA=
+H4sIAAAAAAAC/8RZ3XbbNhK+91NMmbPHyVmRlH+Spqqktonjpt3mp7XTja96RsRQhAUCLABKYvfnXfZy
+n6MvtgcAKcuUnDg9e3Z9YxEYzHzzPyDHn529eX559fYFFLYU04Ox+wcC5XwSkYzcAiGbHgCMP4tj+EEt
+uZyLBgqULNOYW2Iwa+AVasslvFTCWLhYkWQEX5eFCb+4hOPh0ecDqCuG7oRfOB5CHHvOJVmEwtoqpl9r
+vpxEz5W0JG182VQUQRaeJpGltU0dwC8hK1AbspN3l+fx0+iGi8SSJtGS06pS2m6dXXFmiwmjJc8o9g8D
+4JJbjiI2GQqaHA3AFJrLRWxVnHM7kSraKH6RaV5ZA6gJrn+tSTcDmClljdVYAUoGDC1anAkynVrGnwGj
+s0nktDOjNM0UoyQwSDJVpuFnfJI8ToZJyWVybSLg0tJcc9tMIlPg8eMn8fr1b6+P8VTYxbPT01dZ+v1v
+J9XlKX93lJX00/B88d6cVrpOv1/jj5MIINPKGKX5nMtJhFLJplS1iabjNGC6E56xmC0qtEWyUS5j0iPd
+LKSnyWlylF6bm6U7kJ88PY1X+cXZ+fGLx8Or47Oj+h27Hr45qV89+15e1+/OTr8rPr9a4RU74r/mC3s9
+fKfY02+fv1iLkzfzpzxfPXsSfboyaeow3/gjkWTTo+RomBw/drBb8zuCy0DQwr/N0rn9+cUFaDKq1hkF
+5984nVGOtbADqERttt3fbnRhILhcgCYxiYxtBJmCyEZQaMr/kN0z0zd8ZvZa/ueFUk/Xp8+/NW9O/vyy
+WC+fXqY/Pn6L7+3iL7Z+Us8fX76h1z8/mT/j5/TXt9+ev/7iVV28yY9Pfvziu/y6uNvyH9PqAw4Yevz7
+PeD0mB6M01BxDsaesROWaKoEZlTS3w7A/5W4Dkk8gqPhcFitvzwA+McBwANaU6bJ1MKaATzIuSCPYQAP
+MsFJ2u7JEOqsaCmh4+t3Y4GNqu0Icr4m9mUnkstO5Bd3SYSEkUUuTDxT6w1TtSSdC7WK1yPA2qrupGUb
+clemtBKbIzPMFnOtaslGUGvx8DBtKX9RFcmkkvPDRyBVrKkitJCRtKTbfx3grNZG6RFUinfLXqpOTKFW
+8o+Jz4Qy9HH5rXokBK8MNzumGEHBGSPZYXWVPb7Z7M5126uCW4pNhRmNQKqVxipIGadtkByMZ4o1PjIZ
+X0Im0JhJ5PRCLknHuag585ELMC6Opi/5ksZpcTQdFzptl7cOarVqifsMRXy02bm9J3EJuaB1nClRlxIk
+LuOKC+GSk02i9iG2OItAK0GTyEUbNzYC1BxjpV18ouVKTqIlacszFFuyAMa4JSv2GYiZ5Uu6LaFQJQUx
+LsFiq+ZzJ81tdhn6YJt2C04LpY0Hc5tnu2lIUGaJTSKra4qml2QsmJpbMuMUP4z3NtCQkea+WFvy+8Dd
+kPYQ5ygMRdPnYfsT4YaScV+0gfo+YDvK/Vgv/O4nQm3r0X2xtuT3Absh3Y/WBcOn2rUkY3BO90bb0d8H
+7g3tfrzfzFRtb+Edp4wvpwf7HvqF4K5KYHEWt4PnTu63o+3tvO6drVAS5MgIXKW+M8e39HcnRKujwBkJ
+QWzW7CkJ22IBxsXx9MWastoSA+tc16ZxcdwjrKaXBRkCe5PsgJoAl8hFaKkoGWQoYUYgFDJiCTwXPFuA
+knD4g0J2CFb5LUDISLvKHFgl47TqyfON2Gu8aeFRZ6LCNQrISIh4pjQj7QY3T3LLqNuu+6CV9xalTzDu
+dhnbte+m1uyx6U8hmUDlYAsC1wxaR3Q2njUQ2MOStOFKbhkVQasVWAWGyJ9vz9gCLWiUgHPk0li/F7h8
+0NJbA9L/yNY7NfJjpt4qwbuW7irlHkOfc8lMiHBnUndL9PGaIxe1JmjHmwTOubCkzQjG7qY2DSYZjf29
+bTpoV72dN4u3ZEFL4m7GuiNJw9qMcqU35+Dh1dXVVfzqVXx29qjjbHE+WlAzWaKo6YMCjEVbm1GFxvzd
+adESJ/ANYy0JlyOh5qbdAasAhVEQbNiFlaPoS1gVPCuAGzBCrRJ4scayEtTZJJohg5lQ2SJqmYzmKiZb
+kKa6hKD58fD4KB4+iYdHcBvGbgDmSpc+KgIw97gJP/cQcym4JChn8UnP6wBjLqvagm0qCu8Hoi1W/pZx
+m1c36ZY6Po7AD4/tu4ERnA6pjMDfNAolGOlJFEIKAqMd2bPaWiVb4aaeldxupM2shJmVcaV5ie5wF53h
+UM8GqQN3Z2Leuqt8IDUhvOSIjobDP/1X83R3PvhYom4PIHf1HK4ktHQjGJsKpRe66UO/uDSNpq+VLbic
+t00FGrLj1BFP92b6Pj6MTBZNN6eqex3q+sDd524ctHUF/H+4Z89A9DH/3Jq5dh3kxyJoL0m7Rn7mK5nf
+H8CKgHEGBS4JMiUNSVOb2BvSvWKDErkUDdiVAhflBuIYjEVLnqSXUyjb2pIVyGVbsR82hAX4AhOGDshp
+BaXS9CiBy4JaspXbn5MkjZZYj/GSI6AgW6SOmNzrPyfLFY/QSqlShlulmwTOtSrbLkqVGgC3sEIDtK4o
+2+XsCdtqaglLA7x0Lx/BbpA5SRTmLFhxF85uk2tw9/CsNlaVfaZkbFyglmQMmWRf0L5Wq0EQzjiThxZW
+Si/A+a0inVNmReOHhRtcLR2KFTYGqloICEh6wgU68dC60IFfFb6+O9oGMLM1CtF0KrFtTY1/EyVdpq6w
+712pLJhaUwJnnAVmmdLaY4UlaZ43QEvSjc/4r+C71vCmoow7kX0raVW710eqJAdRQoGa5Uov2mDAqtIK
+M8cs6BEpVZkBFNT4uM1UWZLcdakzojfB+/cwowxrQ10QuF7L5Tza65JLh+Ht7/+2pOHiNy5+/9e84fDw
+6wVqdFn4CAp0enMDnBEOwCrQtWyjx4BV1ht2JjBb9DDN1DqBK1WDqbiEugIEqfzLdcMZAQJT2YI0bN55
+DMBY1NYD57bY4+cgNvbWzXnmjua8tVROxNxR51qfkCaBb6TyUdCX1GN7qHRWkLEuDc3hJjgCX6ZW0lVy
+49dJWq7Dts81yF3qzbkt6lmP68OdstDK94x++uFtHGA+SuBCDcIYAmgWrnXcDMBgVY/vVrIGBgNAIfzz
+liJKH4IkcrAVMAXchKEAolWB9tBAo2q9P40816+ioH4b5NwCzjezeVdYQKCxnjzYYddjjmGGhu6IPmU2
+eahdRqAFJQmk0qWPK4tiYQB9cff54hacgfxST1ZU8CWFOh4l8FKtXGYOfNEHblyeSl+DnU4GLXd3QMNl
+RoAhTvr8tq0Z+asiU6DausLl3HeGUBRQgiHJAGUDkqyvbG3L8hT9gRW9Z92WT4q4loy0x+6iAXLUPt/9
+/OsbVbCSr56tS9zvHtscjY1NI7PuXusKq9IlseDMlomCrKBsEbg5CNVx5cJKUNm90OsxZoqML4YlLnxl
+qZQxfCaoja4zdREaVlkJl5meB6B1XwmM18h6X6PuB0jQcOO4BPYFysaXKNvwd/Zj3FguMydq1HnZN3Ay
+hmTmbvhglRK7KTSuxbS35L8QTLueZ8NbIcH3k3EJKP2r8dJ1blhhM7ib2mpCezurDaAJRdOVSfKPOWr3
+r7Nsso/hOO0j3zXXZhpy4l6cB8sExZTef9lUvlH6bIZ/nkBDqI2PPx96rgVg8JEhvSTtWoB0CnEJlVas
+9j5Ieli7T0XuYPAt2YLRUlUm4SpNXLPUtTRwfJp+HuKzbfQ5WZd4LSZ3dk9VMaHkdNe4NKy5mpx8zEDd
+C/SblZPpGc1qN7LPx2lx0reQDlMzczRuEK70h8bgu17IbX62P8Zp+ARw4L/cuR7jNcaq8l/iuk+EN1fF
+9BqXGFaj9sshVlXvQ+A4DR/G/zMALmDZCSkfAAA=
+`,
+	},
+
+	"/live.html": {
+		name:    "live.html",
+		local:   "assets/live.html",
+		size:    4356,
+		modtime: 1792206309,
+		compressed: `
+H4sIAAAAAAAC/6RYUXPbuBF+96/YIOmFHIukbCuenErpZs6Jr0ljO4nd63U6fYBJSIQNAix2aVnX5L93
+AJISZcnnpOWDBS4WHxa7++2CTp+9uTi5+sfHt1BQqaZ7qfsBxfV8woRmTiB4Pt0DSEtBHLKCWxQ0YTXN
+otfMT5AkJaaFvBMQgXI/ttZp0oidgpL6FqxQE4a0VAILIYhBYcVswgqiCsdJgsSz24pTEV8bQ0iWV1mu
+48yUyUqQjOJRfJBkiGtZXEodZ4gMpCYxt5KWE4YFP3o9in69Neb1/ejkF7w42v9LcX/3+ir59Ooj/41u
+/0r1cT1/dXUhzn89nv8sT8XfP/5yev7jWV1czA6PPv34bnZTMMisQTRWzqWeMK6NXpamxubY/ixuBPC8
+5FLDf/zYPRXPc6nnYzg4rO7/7MVf/d8Ya0kiyo0WPXVT8UzScgzD+HhDm8Q9RaXRpqc8M5qiGS+lWo7h
+8vTMaBN9FvNacTs4MRqN4jj4IK+F5SSNBqcwOBNamYFDwopnAp7JsjKWuKb1fmmyOlGKmZUVAdpsHaHM
+5CK++Xct7NKHpRlGR/GreOijcLMVhMNXx9H9+e/nh3yk6Pbn0egsS97/flRdjeTfDrJSfB6e3v6Go8rW
+yft7/mnyuMPTpDGpbx0tKzFhzkfJDb/jjZQ1EUkSuCpEk413UiygMkohJE4Q36DRA1gUMivApa1XuK6l
+yhFm1pRAhfATYAXWigDJCl7GHvmO2wZ20guKO7a942oMB8PhcLC3mkgSyOs2EjNjS07o0UmWAq4FLYTQ
+QAsDOSeBwBGKcVmOEeMVRLd+DLNaZ24UOCMHQCbsmeAeJQgQJnDmeFTy+2A4aMYzZYwNAi0W8IaTCNzS
+CFavDi8MIfHWh+EWZgGTPg5CAkfHw+G2YrmteDwM4U9wPNzQdUbittgKqq2GAvaBjRnswyVZqedBGcYV
+zy+JWwoOB8CGLHyogtsqK+ivvYCQQPogkXredCIcgDaLASwkFSecxNzY5S7v1gom8CJgaa0gUxxx8lJJ
+pKjWnjw5lNfRELDkSsGKvS+nbNNVfsd4ZuxbnhXB2pCHO3a7KtnuquSUhb4sBBRrXopwS1/OIOifAn74
+ASjOHjlT9ygZ86oSOg8YsIHfCyuuuzNe83wuwP+Ncq7nwr7sGbIC37bm6077tFn8j4Y0Pq1J5GsDPKc7
+lgQUo0sCH8/wmyyqVbelkpv6X8Nd6VmrnZllhc6F7eWVrfVOghKn2hHA1q6/aS0yEjn8BGz9QgYYjIEt
+uCSp5650ANtAehGw501Z6tzQ4u573GYq3Fpiay3zboVTtLV+9wa+fAEWsW11oXiFYmOB9663d9PvvbmB
+N8EHebwbt+KIAvuwjWRbc8al2lD0gm09vJXVhp4XhHtb7qdrky8bOj333RgbEQtjUVa0DB7E3EF5tRiV
+zEQQxlbcCYtutMVgfIzBQucwAYzd75cvnX92qpJtyU7Wp7iZz5U4cekfsPX1gQ3g2TOPt53iZLuE9jC5
+g+kJcnm34g42VaQhWi7vOp71K9gm2zBGWdaKk7Fh+PTem8Rti+Eu3mLHW3ck2IegcdZPwDwTII5j9t0b
+Yp1lArFvfZto3wn0sOhhm4ff64AtX/os/a4Qerd1bSxAx2At9XxFugHMuEIR/n+o7ni1Feiy9Z//GoCu
+lRoA2Xo3sGNQB0X20Srar5fuRtarlg+Z8yKeC3p/eXEesPWtjYWxS/7G2qbghjFXC77E4FEk96CgK1kK
+U7dp5zYfNBWsu7v9kdHrxvEiyE1Wl0KTKwQ8X67xwuYS3d5T06T5ckqda/y11dFL5hPmPhbaa2pajKYf
+Vl9MxaiVVm3SsIc0ZB6grfvTNKnaBXl3G2HWLFpsJ6ZOnBkVYRkdsunnWsO7N2mS0zTN8wfzB8P1raXZ
+q2kY0yhN8vwPcd82reKbgbvW8g3QHzk+jdxSvQFv+8t0+CT2KZfqSeyG/Q1005C+AfnyVlbV0w7phdW3
+sD5ymuSqHRG/VqLDaF783wjLdrVvUuvYU/fh3r3b9Yufnl66FWlCxcMJx5RdcheHXXLnw11y54Fd8s9N
+xWquwTsVRCY0QVeDNlXSZH2UNNk4Zurr0DRNqCOdV3F+mnpyuta3lybNbJo0//P47wBF+L5eBBEAAA==
 `,
 	},
 
 	"/viewer.html": {
 		name:    "viewer.html",
 		local:   "assets/viewer.html",
-		size:    2723,
-		modtime: 1603658490,
+		size:    3203,
+		modtime: 1792209819,
 		compressed: `
-H4sIAAAAAAAC/6xW23LbyBF991e0R5Uqu0IA4k2RIICp2LGcOJZkO4pjPw6ABjHiYGZ2psGLt/bftwYA
-Seiy+7C10gPJnr6cPt04g+TlP2/f3n3/9A4qquXiReI/QHK1TBkq5g3Ii8ULgKRG4pBX3DqklDVUBues
-PSBBEhelkAhrgRu0SdSZDlGK15iyAl1uhSGhFYNcK0JFKfsHuJpL2YdCqS0QbmnvMAKhnBEWC8h2sBRU
-NRk43dh8X409rsMbqrQdlKi0FDVXnaMUagUWZcoc7SS6CpEYVBbLlFVExsVR5IjnK8OpCjOtyZHlJi9U
-mOs6OhiiWTgLx1Hu3NEW1kKFuXMMhCJcWkG7lLmKT89nwdeV1ufb2dv37nb6139V2/X5XfR5/ol/o9V/
-qDlrlvO7W7z5erZ8I67w/5/eX91cXDfVbTmZfr74d3lfMcitdk5bsRQqZVxptat147qu2l78N4BQNXUc
-Z1hqi/Bza/J/PRsxcCL7SgqFry/bw18OUUfnTNsCbWDFsqJ4bLbgtBQFnBTjYl7wy4PfRhRUxTDF+nJQ
-SGobg11m/NXkb6PpeDSdj8Lpw2q5Lobg9BptKfUmhrVwIpN4TFdqRUHJayF3Mfz36lorHXzBZSO5Hb3V
-ymnJ3eijyNByv1ngHUbXqKQe1VppZ3j+OJsTPzCG8cRsn6A+mcwmF5NBwEbbIthYbmJQ2tZcDo4qQRi0
-BWIwFocNkg0rsaykJxCLQacZz1dLqxtVBPuSZVlmRTGMPqm5UMcYw4tCqOUQcu8nNS+yhkirQYl+KGf7
-ofSIeCbxMe/Po7l8tAcx/M4K7FeFF6JxMUyHpPZIxqenfxlCKcQ69LKC9oik5nYpVAznZgun/v/ymfbP
-hsl7c0DatGFPTzJNpOtHh8+1fFael/yPdE3aBBJLer79gVP7JD316p8GL3iB39bBaP6MvYeXojbaEld0
-rJdEvVi0utEqMjibH9XP70h4/1ODdtdKXvc1mIbz8LRVuPsnAjeZnwXbmx83Ez6TtHozm13n0YcfU3M3
-E/8b5zV+Ob1afXMzY5vow5Z/Tn9bzJKog7QYoKOdwZR5kqJ7vuadlXWgI25M0F0EHtez8aJIGW55bST6
-JGyYsNSadbp5VwkHwoHTNYJrvdubKITvuoGcK/BPGyjcgL/rHKwFB6oQpCCSCEKZhoBneo0j4KoALp1u
-M+8dGytDeBcuQ2B/9ynSrkpIW2JdCK5RQXtDkQbDLYncD9yb0IUv2mxSrPBpgpOP81PWTbfvP4m6qzvJ
-dLFr6SjEuuXCq0vftLct+p1LSm1r0Mo1WS0oZUeu4xIpr66ExFevLy1SYxWUXDpki8O+ttlzyZ1LWUtF
-4J8yM/AASDqOjvSzfYQvHfgrymrJwEieY6VlgTZlke+05agF73/5QbDoQebnqwfcGFTFAxAASS+ZHY6u
-3S75UU8PyDJSkJEKjBU1t7tHqQCuPDWPbEM0PiVaBlZLX4w4NY4Bt4IHlSgKVCkj26Df3MEs+jxRB+ZB
-pw/dHvxMIs9jP9njwRBOp7v9O9dBdw5dJc5w1S0JEmeLebd6ML64gDdJ5E8Prrz1s3wTNFayxRe+SSL+
-tHh78ezLe23pqN6/vCVR69AuaBfVL2wSda+kvwYAAP//0Gc+kaMKAAA=
+H4sIAAAAAAAC/7RXW3fcthF+16+YwKcn9umSlLS7qkSR21O7Udo0tmxHTZPHITlcQgsCLDDci33633tA
+7oW7kvrQNtKDQMzl+2Yw/EAl3/z5/t3Drx+/g4prNTtL/B9QqOepIC38BmExOwNIamKEvELriFPRchlc
+i87AkhXNSqkIlpJWZJOo39pHaawpFQW53MqGpdECcqOZNKfiT+BqVGobCqWxwLTmncMIpHaNtFRAtoG5
+5KrNwJnW5js0cYqDLVfGDiAqo2SNundUUi/AkkqF440iVxGxgMpSmYqKuXFxFDnGfNEgV2FmDDu22OSF
+DnNTR/uNaBJOwosod+6wF9ZSh7lzAqRmmlvJm1S4CsfXk+DnhTHX68m77939+Pd/qdbL64fo0/Qj/sKL
+v3F71c6nD/f04eer+Vt5R//4+P3dh5v3bXVfXo4/3fy1fKwE5NY4Z6ycS50K1EZvatO6vqquFr8CCHVb
+x3FGpbEEX7st/7PtRgzIbF8rqenNbWf81z7q4JwZW5ANrJxXHF80a3BGyQJeFRfFtMDbvd9KFlzFMKb6
+dgCkjI3BzjN8ffmH0fhiNJ6OwvExWm6KITmzJFsqs4phKZ3MFB3SlUZzUGIt1SaGn+7eG22CzzRvFdrR
+O6OdUehGP8qMLPrJAu8wek9amVFttHEN5qfZnPxCMVxcNusnrF9dTi5vLgcBK2OLYGWxiUEbW6MamCrJ
+FHQAMTSWhgWyDSs5r5RvIBWDSjPMF3NrWl0EO8iyLLOiGEa/qlHqQ0yDRSH1fEh566cMFlnLbDR8PT2U
+K6qHvoyZotO+P8/m9mQOYvgPI7AbFSxk62IYD5u6ZXJxfv67IZVCLkMvK2QPTGq0c6ljuG7WcO5/b58p
+/6pZP9kO2DRd2FNLZphNfWJ8ruSr8rrE/6ZqNk2gqOTnyx84dW/SU6/t2+AFL/DTCl+PJ/V/nHv4RtaN
+sYyaD3hJtBULv+4VGZzND+rnZyR8/GdLdtNJXr8MxuE0PO8U7vGJwF1Or4L1hy8fLnGiePF2MnmfRz98
+GTcPE/n3i7ymz+d3i1/cpLFt9MMaP6Uvi1kS9ZRmA3a8aSgVvknRIy6x3xU96QibJugvgvDxhXhZpILW
+WDeKfBIxTFga0ykowEMlHUgHztQErvPubqIQfjUt5KjBv22gaQX+rnOwlAhcESjJrAikbloGzMySRoC6
+AFTOdJl3jq1VIXwXzkMQf/Qp0h4l5DWLPoSWpKG7odhAg5Zl7g/cb5ELz7psSi7oaYJXP07PRX+62/qT
+qL+6k8wUm64dhVx2vfDqsi3a7/UrgKQ0tgajXZvVklNx6HXsK7+Til6/ubXErdVQonIkdqHb5LlC51LR
+dSLwL1kz8ABIOsOg+2IX4ZEDf0NZowQ0CnOqjCrIpiIqZV+h6Lj7J89GREeZn0cPsGlIF0ckAJKtYvY8
++mr75Ac53TPLWEPGOmisrNFuTlIB3BHn1cnekI1PSVaANcqDMXLrBKCVGFSyKEingm1LfnAHR7HNE/Vk
+jio9djt6TCLfx9nZiWFIp5fd7SfXXnb2VSWuQd3PCDGK2bSfPLi4uYG3SeSte1fs/CyugtYqMfuMqyTC
+vXWA6dvXHQfsV4GroVQGuRfGvvuOLWHtBHT6lIpCukbhxl+8+mjUjs7vhfMyLXvqgaPc6MKf3AAjQKUE
+GJ0rmS+8gJnVT53h9bffvhEzVOpp6/8PqI4L0/ILwL3Rw/er34oBWfsyA7J2y4CsPWUwHKnBsvuw2NHw
+d0ePuPs4T6LOYXa2j9oKUhL1/3L8ewAWCo0LgwwAAA==
 `,
 	},
 
@@ -496,6 +578,7 @@ var _escDirs = map[string][]os.FileInfo{
 		_escData["/details_close.png"],
 		_escData["/details_open.png"],
 		_escData["/index.html"],
+		_escData["/live.html"],
 		_escData["/viewer.html"],
 	},
 }
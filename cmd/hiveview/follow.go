@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	followRetryInterval = 2 * time.Second // delay between stream connection attempts
+	followPollInterval  = time.Second     // delay between reads of an event log
+	followMaxFailures   = 20              // failed tests kept per suite
+)
+
+// liveRun is the state of an in-progress hive run. It is built from the
+// events of the hive result stream, or of its event log written with
+// hive --results-stream.file.
+type liveRun struct {
+	mu        sync.Mutex
+	url       string
+	connected bool
+	runID     string
+	started   time.Time
+	lastEvent time.Time
+	suites    []*liveSuite
+	suiteMap  map[string]*liveSuite // simulator/suite ID -> suite
+}
+
+type liveSuite struct {
+	Simulator string                       `json:"simulator"`
+	ID        libhive.TestSuiteID          `json:"id"`
+	Name      string                       `json:"name"`
+	Start     time.Time                    `json:"start"`
+	End       *time.Time                   `json:"end,omitempty"`
+	Passes    int                          `json:"passes"`
+	Fails     int                          `json:"fails"`
+	Skips     int                          `json:"skips"`
+	Running   map[libhive.TestID]*liveTest `json:"-"`
+	Failures  []*liveTest                  `json:"failures"`
+}
+
+type liveTest struct {
+	ID       libhive.TestID          `json:"id"`
+	Name     string                  `json:"name"`
+	Start    time.Time               `json:"start"`
+	Category libhive.FailureCategory `json:"category,omitempty"`
+}
+
+// liveSnapshot is the JSON response of the /live.json endpoint.
+type liveSnapshot struct {
+	Stream    string             `json:"stream"`
+	Connected bool               `json:"connected"`
+	RunID     string             `json:"runID"`
+	Now       time.Time          `json:"now"`
+	Started   *time.Time         `json:"started,omitempty"`
+	LastEvent *time.Time         `json:"lastEvent,omitempty"`
+	Passes    int                `json:"passes"`
+	Fails     int                `json:"fails"`
+	Skips     int                `json:"skips"`
+	Suites    []liveSuiteSummary `json:"suites"`
+}
+
+type liveSuiteSummary struct {
+	*liveSuite
+	Running []*liveTest `json:"running"`
+}
+
+func newLiveRun(url string) *liveRun {
+	return &liveRun{url: url, suiteMap: make(map[string]*liveSuite)}
+}
+
+// follow reads events from the result stream or event log. It never returns.
+func (r *liveRun) follow() {
+	if strings.HasPrefix(r.url, "ws://") || strings.HasPrefix(r.url, "wss://") {
+		r.followStream()
+	} else {
+		r.followFile()
+	}
+}
+
+// followStream reads events from the result stream, reconnecting when the connection
+// is lost.
+func (r *liveRun) followStream() {
+	for {
+		conn, _, err := websocket.DefaultDialer.Dial(r.url, nil)
+		if err != nil {
+			log.Printf("Can't connect to result stream: %v", err)
+			time.Sleep(followRetryInterval)
+			continue
+		}
+		log.Printf("Following result stream %s", r.url)
+		r.setConnected(true)
+		for {
+			var ev libhive.StreamEvent
+			if err := conn.ReadJSON(&ev); err != nil {
+				log.Printf("Result stream disconnected: %v", err)
+				break
+			}
+			r.apply(&ev)
+		}
+		conn.Close()
+		r.setConnected(false)
+		time.Sleep(followRetryInterval)
+	}
+}
+
+// followFile reads the events appended to an event log, like tail -F.
+func (r *liveRun) followFile() {
+	tail := &fileTail{path: r.url}
+	var (
+		following bool
+		lastErr   string
+	)
+	for ; ; time.Sleep(followPollInterval) {
+		lines, err := tail.lines()
+		for _, line := range lines {
+			var ev libhive.StreamEvent
+			if err := json.Unmarshal([]byte(line), &ev); err != nil {
+				log.Printf("Invalid event in %s: %v", r.url, err)
+				continue
+			}
+			r.apply(&ev)
+		}
+		switch {
+		case err == nil && !following:
+			log.Printf("Following event log %s", r.url)
+		case err != nil && (following || err.Error() != lastErr):
+			log.Printf("Can't read event log: %v", err)
+			lastErr = err.Error()
+		}
+		following = err == nil
+		r.setConnected(following)
+	}
+}
+
+// fileTail reads the lines appended to a file. When the file is truncated, it is read
+// again from the start. When the file is replaced, e.g. by log rotation, the remaining
+// lines of the old file are read before switching to the new file.
+type fileTail struct {
+	path    string
+	fd      *os.File
+	offset  int64  // read position in fd
+	partial []byte // incomplete last line
+}
+
+// lines returns the complete lines appended to the file since the previous call.
+func (t *fileTail) lines() ([]string, error) {
+	var lines []string
+	if t.fd != nil {
+		current, err := t.fd.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(t.path); err == nil && !os.SameFile(info, current) {
+			lines, err = t.read()
+			t.close()
+			if err != nil {
+				return lines, err
+			}
+		} else if current.Size() < t.offset {
+			t.offset, t.partial = 0, nil
+		}
+	}
+	if t.fd == nil {
+		fd, err := os.Open(t.path)
+		if err != nil {
+			return lines, err
+		}
+		t.fd, t.offset, t.partial = fd, 0, nil
+	}
+	more, err := t.read()
+	return append(lines, more...), err
+}
+
+// read reads the complete lines from the current offset to the end of the file.
+func (t *fileTail) read() ([]string, error) {
+	if _, err := t.fd.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(t.fd)
+	t.offset += int64(len(data))
+	data = append(t.partial, data...)
+	var lines []string
+	for {
+		nl := bytes.IndexByte(data, '\n')
+		if nl < 0 {
+			break
+		}
+		lines = append(lines, string(data[:nl]))
+		data = data[nl+1:]
+	}
+	t.partial = append([]byte(nil), data...)
+	return lines, err
+}
+
+func (t *fileTail) close() {
+	if t.fd != nil {
+		t.fd.Close()
+		t.fd = nil
+	}
+}
+
+func (r *liveRun) setConnected(c bool) {
+	r.mu.Lock()
+	r.connected = c
+	r.mu.Unlock()
+}
+
+// apply updates the run state with an event.
+func (r *liveRun) apply(ev *libhive.StreamEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ev.RunID != "" && ev.RunID != r.runID {
+		// A new run has started, forget the old one.
+		r.runID = ev.RunID
+		r.started = ev.Time
+		r.suites = nil
+		r.suiteMap = make(map[string]*liveSuite)
+	}
+	if r.started.IsZero() {
+		r.started = ev.Time
+	}
+	r.lastEvent = ev.Time
+
+	key := fmt.Sprintf("%s/%d", ev.Simulator, ev.SuiteID)
+	suite := r.suiteMap[key]
+	if suite == nil {
+		// Suites are also created for test events, in case the
+		// suiteStart event was sent before we connected.
+		suite = &liveSuite{
+			Simulator: ev.Simulator,
+			ID:        ev.SuiteID,
+			Name:      ev.Suite,
+			Start:     ev.Time,
+			Running:   make(map[libhive.TestID]*liveTest),
+		}
+		r.suiteMap[key] = suite
+		r.suites = append(r.suites, suite)
+	}
+
+	switch ev.Type {
+	case "suiteEnd":
+		end := ev.Time
+		suite.End = &end
+		suite.Running = make(map[libhive.TestID]*liveTest)
+		// The suite counts are complete, unlike ours when
+		// we connected in the middle of the suite.
+		suite.Passes, suite.Fails, suite.Skips = ev.Tests-ev.Fails-ev.Skips, ev.Fails, ev.Skips
+	case "testStart":
+		suite.Running[ev.TestID] = &liveTest{ID: ev.TestID, Name: ev.Test, Start: ev.Time}
+	case "testEnd":
+		test := suite.Running[ev.TestID]
+		if test == nil {
+			test = &liveTest{ID: ev.TestID, Name: ev.Test, Start: ev.Time}
+		}
+		delete(suite.Running, ev.TestID)
+		if ev.Skip != "" {
+			suite.Skips++
+			break
+		}
+		if ev.Pass != nil && *ev.Pass {
+			suite.Passes++
+			break
+		}
+		suite.Fails++
+		test.Category = ev.Category
+		suite.Failures = append(suite.Failures, test)
+		if len(suite.Failures) > followMaxFailures {
+			suite.Failures = suite.Failures[1:]
+		}
+	}
+}
+
+// snapshot returns the current state of the run. It must be called
+// with r.mu held.
+func (r *liveRun) snapshot() *liveSnapshot {
+	s := &liveSnapshot{
+		Stream:    r.url,
+		Connected: r.connected,
+		RunID:     r.runID,
+		Now:       time.Now(),
+		Suites:    make([]liveSuiteSummary, 0, len(r.suites)),
+	}
+	if !r.started.IsZero() {
+		started, last := r.started, r.lastEvent
+		s.Started, s.LastEvent = &started, &last
+	}
+	for _, suite := range r.suites {
+		summary := liveSuiteSummary{liveSuite: suite, Running: make([]*liveTest, 0, len(suite.Running))}
+		for _, test := range suite.Running {
+			summary.Running = append(summary.Running, test)
+		}
+		sort.Slice(summary.Running, func(i, j int) bool { return summary.Running[i].ID < summary.Running[j].ID })
+		s.Passes += suite.Passes
+		s.Fails += suite.Fails
+		s.Skips += suite.Skips
+		s.Suites = append(s.Suites, summary)
+	}
+	return s
+}
+
+// ServeHTTP serves the run state as JSON.
+func (r *liveRun) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// The snapshot refers to the suites, so it is encoded while holding the lock.
+	r.mu.Lock()
+	content, err := json.Marshal(r.snapshot())
+	r.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	w.Write(content)
+}
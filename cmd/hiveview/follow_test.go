@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hiveview-follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+	tail := &fileTail{path: path}
+	defer tail.close()
+
+	appendFile := func(file, data string) {
+		t.Helper()
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(step string, want ...string) {
+		t.Helper()
+		lines, err := tail.lines()
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		if len(lines) != 0 || len(want) != 0 {
+			if !reflect.DeepEqual(lines, want) {
+				t.Fatalf("%s: wrong lines %q, want %q", step, lines, want)
+			}
+		}
+	}
+
+	if _, err := tail.lines(); !os.IsNotExist(err) {
+		t.Fatalf("missing file: wrong error %v", err)
+	}
+
+	appendFile(path, "a\nb\npart")
+	check("initial content", "a", "b")
+	appendFile(path, "ial\nc\n")
+	check("appended lines", "partial", "c")
+	check("no change")
+
+	// Truncate the file. It is read again from the start.
+	if err := ioutil.WriteFile(path, []byte("d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check("truncated", "d")
+
+	// Rotate the file. Lines appended to the old file before the new
+	// file is noticed are still read.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(path+".1", "e\n")
+	check("renamed", "e")
+	appendFile(path+".1", "f\n")
+	appendFile(path, "g\n")
+	check("rotated", "f", "g")
+	appendFile(path+".1", "ignored\n")
+	appendFile(path, "h\n")
+	check("after rotation", "h")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
@@ -18,7 +19,8 @@ import (
 const listLimit = 200 // number of runs reported
 
 // generateListing processes hive simulation output files and generates a listing file.
-func generateListing(output io.Writer, logdir string) error {
+// Only suites of runs matching the tag filter are listed.
+func generateListing(output io.Writer, logdir string, filter tagFilter) error {
 	logfiles, err := ioutil.ReadDir(logdir)
 	if err != nil {
 		return err
@@ -32,7 +34,7 @@ func generateListing(output io.Writer, logdir string) error {
 			continue
 		}
 		entry, err := convertSummaryFile(logdir, finfo)
-		if err != nil {
+		if err != nil || !filter.match(entry.Tags) {
 			continue
 		}
 		entries = append(entries, entry)
@@ -62,13 +64,32 @@ type listingEntry struct {
 	Name   string `json:"name"`
 	NTests int    `json:"ntests"`
 	// Info about this run.
-	Passes   int       `json:"passes"`
-	Fails    int       `json:"fails"`
-	Clients  []string  `json:"clients"`  // client names involved in this run
-	Start    time.Time `json:"start"`    // timestamp of test start (ISO 8601 format)
-	FileName string    `json:"fileName"` // hive output file
-	Size     int64     `json:"size"`     // size of hive output file
-	SimLog   string    `json:"simLog"`   // simulator log file
+	RunID    string             `json:"runID,omitempty"`
+	Tags     map[string]string  `json:"tags,omitempty"`
+	Shard    *libhive.TestShard `json:"shard,omitempty"`
+	Passes   int                `json:"passes"`
+	Fails    int                `json:"fails"`
+	Skips    int                `json:"skips"`
+	NotRun   int                `json:"notRun,omitempty"`
+	Clients  []string           `json:"clients"`  // client names involved in this run
+	Start    time.Time          `json:"start"`    // timestamp of test start (ISO 8601 format)
+	FileName string             `json:"fileName"` // hive output file
+	Size     int64              `json:"size"`     // size of hive output file
+	SimLog   string             `json:"simLog"`   // simulator log file
+
+	// Number of failures in each failure category.
+	FailureCategories map[libhive.FailureCategory]int `json:"failureCategories,omitempty"`
+	// Results of this run by client name.
+	ClientStats map[string]*clientStats `json:"clientStats,omitempty"`
+}
+
+// clientStats counts the test results of a single client in a run.
+// A test counts for all clients started by it.
+type clientStats struct {
+	Version string `json:"version"`
+	Passes  int    `json:"passes"`
+	Fails   int    `json:"fails"`
+	Skips   int    `json:"skips"`
 }
 
 func convertSummaryFile(logdir string, file os.FileInfo) (listingEntry, error) {
@@ -99,27 +120,93 @@ func suiteToEntry(file os.FileInfo, s *libhive.TestSuite) listingEntry {
 		FileName: file.Name(),
 		Size:     file.Size(),
 		SimLog:   s.SimulatorLog,
+		RunID:    s.RunID,
+		Tags:     s.Tags,
+		Shard:    s.Shard,
 		Clients:  make([]string, 0),
 	}
+	var testClients []string
 	for _, test := range s.TestCases {
 		e.NTests++
-		if test.SummaryResult.Pass {
+		switch {
+		case test.SummaryResult.NotRun:
+			e.NotRun++
+		case test.SummaryResult.Skipped():
+			e.Skips++
+		case test.SummaryResult.Pass:
 			e.Passes++
-		} else {
+		default:
 			e.Fails++
+			if test.SummaryResult.Category != "" {
+				if e.FailureCategories == nil {
+					e.FailureCategories = make(map[libhive.FailureCategory]int)
+				}
+				e.FailureCategories[test.SummaryResult.Category]++
+			}
 		}
 		if e.Start.IsZero() || test.Start.Before(e.Start) {
 			e.Start = test.Start
 		}
+		testClients = testClients[:0]
 		for _, client := range test.ClientInfo {
 			if !contains(e.Clients, client.Name) {
 				e.Clients = append(e.Clients, client.Name)
 			}
+			if !contains(testClients, client.Name) {
+				testClients = append(testClients, client.Name)
+			}
+		}
+		for _, name := range testClients {
+			e.addClientResult(name, s.ClientVersions[name], test.SummaryResult)
 		}
 	}
 	return e
 }
 
+func (e *listingEntry) addClientResult(name, version string, result libhive.TestResult) {
+	if e.ClientStats == nil {
+		e.ClientStats = make(map[string]*clientStats)
+	}
+	cs := e.ClientStats[name]
+	if cs == nil {
+		cs = &clientStats{Version: version}
+		e.ClientStats[name] = cs
+	}
+	switch {
+	case result.Skipped():
+		cs.Skips++
+	case result.Pass:
+		cs.Passes++
+	default:
+		cs.Fails++
+	}
+}
+
+// tagFilter selects runs by their tags. A run matches when it has all tags of the filter.
+type tagFilter map[string]string
+
+// parseTagFilter parses a list of KEY=VALUE tags.
+func parseTagFilter(tags []string) (tagFilter, error) {
+	f := make(tagFilter, len(tags))
+	for _, tag := range tags {
+		eq := strings.IndexByte(tag, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid tag %q, want KEY=VALUE", tag)
+		}
+		f[tag[:eq]] = tag[eq+1:]
+	}
+	return f, nil
+}
+
+func (f tagFilter) match(tags map[string]string) bool {
+	for k, v := range f {
+		if tv, ok := tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
+
 func contains(list []string, s string) bool {
 	for _, elem := range list {
 		if elem == s {
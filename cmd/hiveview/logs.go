@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+// maxLogRange is the maximum size of a byte range of a compressed log.
+// Open-ended ranges are cut off at this size.
+const maxLogRange = 8 * 1024 * 1024
+
+// serveLogs serves the files of the results directory. Compressed logs are decompressed
+// while they are sent. Byte ranges of the decompressed content can be requested using the
+// Range header. Since the decompressed size isn't known without reading the whole log, the
+// complete length in the Content-Range of the response is always '*'.
+type serveLogs struct {
+	dir   string
+	files http.Handler
+}
+
+func newServeLogs(dir string) serveLogs {
+	return serveLogs{dir: dir, files: http.FileServer(http.Dir(dir))}
+}
+
+func (h serveLogs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !libhive.IsCompressedLog(r.URL.Path) {
+		h.files.ServeHTTP(w, r)
+		return
+	}
+	file := filepath.Join(h.dir, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+	fd, err := libhive.OpenLog(file)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer fd.Close()
+
+	w.Header().Set("accept-ranges", "bytes")
+	w.Header().Set("content-type", "text/plain; charset=utf-8")
+	start, end, ok := parseByteRange(r.Header.Get("range"))
+	if !ok {
+		// Not a range request, or a range we don't support. The
+		// Range header may be ignored, so just send the whole log.
+		if _, err := io.Copy(w, fd); err != nil && err != io.ErrUnexpectedEOF {
+			log.Printf("Can't send %s: %v", file, err)
+		}
+		return
+	}
+
+	// Skip to the start of the range, then read it.
+	if end < 0 || end-start >= maxLogRange {
+		end = start + maxLogRange - 1
+	}
+	if _, err := io.CopyN(ioutil.Discard, fd, start); err != nil {
+		w.Header().Set("content-range", "bytes */*")
+		http.Error(w, "range not satisfiable", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	buf := make([]byte, end-start+1)
+	n, err := io.ReadFull(fd, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n == 0 && start > 0 {
+		w.Header().Set("content-range", "bytes */*")
+		http.Error(w, "range not satisfiable", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	w.Header().Set("content-length", strconv.Itoa(n))
+	if n > 0 {
+		w.Header().Set("content-range", fmt.Sprintf("bytes %d-%d/*", start, start+int64(n)-1))
+	}
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(buf[:n])
+}
+
+// parseByteRange parses a Range header containing a single range of the form
+// "bytes=start-end" or "bytes=start-". The end is -1 for open-ended ranges.
+func parseByteRange(header string) (start, end int64, ok bool) {
+	spec := strings.TrimPrefix(header, "bytes=")
+	if spec == header || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	dash := strings.IndexByte(spec, '-')
+	if dash <= 0 {
+		return 0, 0, false // suffix ranges are not supported
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(spec[:dash]), 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	end = -1
+	if s := strings.TrimSpace(spec[dash+1:]); s != "" {
+		end, err = strconv.ParseInt(s, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+	}
+	return start, end, true
+}
@@ -1,5 +1,8 @@
 // The hiveview command generates hive result listing files for the result viewer.
-// It can also serve the viewer and listing via HTTP (with the -server flag).
+// It can also serve the viewer and listing via HTTP (with the -server flag), search
+// the results (with the -search flag), show the progress of a running hive
+// instance (with the -follow flag) and merge the results of sharded runs (with
+// the -merge flag).
 package main
 
 import (
@@ -8,6 +11,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/ethereum/hive/cmd/hiveview/assets"
 	"github.com/gorilla/mux"
@@ -19,21 +23,42 @@ func main() {
 	var (
 		serve   = flag.Bool("serve", false, "Enables the HTTP server")
 		listing = flag.Bool("listing", false, "Generates listing JSON to stdout")
+		query   = flag.String("search", "", "Writes tests matching the search query as JSON to stdout")
+		merge   = flag.Bool("merge", false, "Merges the suites of sharded runs (hive --sim.shard) in the log directory")
+		tags    tagList
 		config  serverConfig
 	)
+	flag.Var(&tags, "tag", "Lists only runs with the given KEY=VALUE tag (with -listing, can be given multiple times)")
 	flag.StringVar(&config.listenAddr, "addr", "0.0.0.0:8080", "HTTP server listen address")
 	flag.StringVar(&config.logdir, "logdir", "workspace/logs", "Path to hive simulator log directory")
 	flag.BoolVar(&config.useLocalAssets, "local-assets", false, "Serve result view app from file system")
+	flag.StringVar(&config.followURL, "follow", "", "Serves a live view of the hive run streaming results to this websocket URL, or writing them to this event log file (implies -serve)")
 	flag.Parse()
 
 	log.SetFlags(log.LstdFlags)
 	switch {
-	case *serve:
+	case *serve || config.followURL != "":
 		runServer(config)
 	case *listing:
-		generateListing(os.Stdout, config.logdir)
+		filter, err := parseTagFilter(tags)
+		if err != nil {
+			log.Fatal(err)
+		}
+		generateListing(os.Stdout, config.logdir, filter)
+	case *query != "":
+		q, err := parseSearchQuery(*query)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := search(os.Stdout, config.logdir, q); err != nil {
+			log.Fatal(err)
+		}
+	case *merge:
+		if err := mergeShards(config.logdir); err != nil {
+			log.Fatal(err)
+		}
 	default:
-		log.Fatalf("Use -serve or -listing to select mode")
+		log.Fatalf("Use -serve, -listing, -search or -merge to select mode")
 	}
 }
 
@@ -41,15 +66,22 @@ type serverConfig struct {
 	listenAddr     string
 	logdir         string
 	useLocalAssets bool
+	followURL      string
 }
 
 func runServer(config serverConfig) {
 	// Create handlers.
-	logHandler := http.FileServer(http.Dir(config.logdir))
+	logHandler := newServeLogs(config.logdir)
 	assetHandler := http.FileServer(assets.Dir(config.useLocalAssets, ""))
 	listingHandler := serveListing{dir: config.logdir}
 	mux := mux.NewRouter()
 	mux.Handle("/listing.jsonl", listingHandler).Methods("GET")
+	mux.Handle("/search.jsonl", serveSearch{dir: config.logdir}).Methods("GET")
+	if config.followURL != "" {
+		run := newLiveRun(config.followURL)
+		go run.follow()
+		mux.Handle("/live.json", run).Methods("GET")
+	}
 	mux.PathPrefix("/results").Handler(http.StripPrefix("/results/", logHandler))
 	mux.PathPrefix("/").Handler(assetHandler)
 
@@ -59,15 +91,49 @@ func runServer(config serverConfig) {
 		log.Fatalf("Can't listen: %v", err)
 	}
 	log.Printf("Serving at http://%v/", l.Addr())
+	if config.followURL != "" {
+		log.Printf("Live view of the run at http://%v/live.html", l.Addr())
+	}
 	http.Serve(l, mux)
 }
 
 type serveListing struct{ dir string }
 
 func (h serveListing) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	log.Printf("Generating listing...")
-	err := generateListing(w, h.dir)
+	err = generateListing(w, h.dir, filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+type serveSearch struct{ dir string }
+
+func (h serveSearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query, err := parseSearchQuery(r.URL.Query().Get("q"))
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Searching for %q...", r.URL.Query().Get("q"))
+	if err := search(w, h.dir, query); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
+
+// tagList is a repeatable string flag.
+type tagList []string
+
+func (l *tagList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *tagList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+// shardFile is a suite file written by a hive run with --sim.shard.
+type shardFile struct {
+	name  string
+	suite *libhive.TestSuite
+}
+
+// mergeShards merges the suites of sharded runs in logdir. The suites of a run's shards
+// have the same name, tags and shard count. For every complete set of shards, a suite
+// file containing the tests of all shards is written to logdir. Sets which were merged
+// before are skipped. The shards of different runs must be told apart by their tags,
+// e.g. by setting the CI pipeline ID as a tag.
+func mergeShards(logdir string) error {
+	files, err := ioutil.ReadDir(logdir)
+	if err != nil {
+		return err
+	}
+	groups := make(map[string][]shardFile)
+	var keys []string
+	for _, finfo := range files {
+		if !strings.HasSuffix(finfo.Name(), ".json") || skipFile(finfo.Name()) {
+			continue
+		}
+		suite := new(libhive.TestSuite)
+		if err := common.LoadJSON(filepath.Join(logdir, finfo.Name()), suite); err != nil || suite.Shard == nil {
+			continue
+		}
+		key := shardGroupKey(suite)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], shardFile{finfo.Name(), suite})
+	}
+
+	for _, key := range keys {
+		shards := groups[key]
+		sort.Slice(shards, func(i, j int) bool { return shards[i].suite.Shard.Index < shards[j].suite.Shard.Index })
+		name := shards[0].suite.Name
+		if err := checkShards(shards); err != nil {
+			log.Printf("Not merging suite %q: %v", name, err)
+			continue
+		}
+		file := filepath.Join(logdir, mergedFileName(shards))
+		if _, err := os.Stat(file); err == nil {
+			continue // merged before
+		}
+		content, err := json.Marshal(mergeSuites(shards))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(file, content, 0644); err != nil {
+			return err
+		}
+		log.Printf("Merged %d shards of suite %q into %s", len(shards), name, filepath.Base(file))
+	}
+	return nil
+}
+
+// shardGroupKey returns the key of the shards belonging to the same run.
+func shardGroupKey(s *libhive.TestSuite) string {
+	tags, _ := json.Marshal(s.Tags) // map keys are sorted
+	return fmt.Sprintf("%s\x00%d\x00%s", s.Name, s.Shard.Count, tags)
+}
+
+// checkShards verifies that shards, sorted by index, contain every shard exactly once.
+func checkShards(shards []shardFile) error {
+	count := shards[0].suite.Shard.Count
+	for i, sf := range shards {
+		switch index := sf.suite.Shard.Index; {
+		case i > 0 && index == shards[i-1].suite.Shard.Index:
+			return fmt.Errorf("shard %d/%d exists twice (%s, %s)", index, count, shards[i-1].name, sf.name)
+		case index != i+1:
+			return fmt.Errorf("shard %d/%d is missing", i+1, count)
+		}
+	}
+	if len(shards) != count {
+		return fmt.Errorf("shard %d/%d is missing", len(shards)+1, count)
+	}
+	return nil
+}
+
+// mergedFileName returns the file name of the merged suite. It is derived from the shard
+// file names, so merging the same shards again yields the same name. The timestamp prefix
+// of the last shard is kept to order the file among the other results.
+func mergedFileName(shards []shardFile) string {
+	h := sha256.New()
+	last := ""
+	for _, sf := range shards {
+		h.Write([]byte(sf.name + "\x00"))
+		if sf.name > last {
+			last = sf.name
+		}
+	}
+	prefix := last
+	if dash := strings.IndexByte(last, '-'); dash > 0 {
+		prefix = last[:dash]
+	}
+	return fmt.Sprintf("%s-merged-%x.json", prefix, h.Sum(nil)[:16])
+}
+
+// mergeSuites combines the tests of all shards into one suite. Tests are numbered
+// in the order in which they started.
+func mergeSuites(shards []shardFile) *libhive.TestSuite {
+	first := shards[0].suite
+	merged := &libhive.TestSuite{
+		ID:             first.ID,
+		Name:           first.Name,
+		Description:    first.Description,
+		ClientVersions: make(map[string]string),
+		TestCases:      make(map[libhive.TestID]*libhive.TestCase),
+		SimulatorLog:   first.SimulatorLog,
+		Tags:           first.Tags,
+	}
+	var tests []*libhive.TestCase
+	for _, sf := range shards {
+		s := sf.suite
+		for client, version := range s.ClientVersions {
+			merged.ClientVersions[client] = version
+		}
+		for _, w := range s.Warnings {
+			merged.Warnings = append(merged.Warnings, fmt.Sprintf("shard %s: %s", s.Shard, w))
+		}
+		for _, test := range s.TestCases {
+			tests = append(tests, test)
+		}
+	}
+	sort.SliceStable(tests, func(i, j int) bool { return tests[i].Start.Before(tests[j].Start) })
+	for i, test := range tests {
+		merged.TestCases[libhive.TestID(i+1)] = test
+	}
+	return merged
+}
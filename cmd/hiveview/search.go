@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+const (
+	searchLimit   = 500     // max number of search results
+	maxLogLine    = 1 << 20 // lines longer than this end the search of a log file
+	snippetLength = 300     // length of text reported in results
+)
+
+// searchQuery is a parsed search query.
+//
+// Queries consist of words, which must all appear in the test name, failure details or
+// log line (case-insensitive). Words can be grouped into phrases with double quotes.
+// A word or phrase prefixed with '-' excludes tests whose name or failure details
+// contain it. Filters have the form key:value:
+//
+//    client:<name>                - tests which ran the client, e.g. client:go-ethereum
+//    suite:<name>                 - test suites whose name contains the value
+//    after:<date>                 - tests started at or after the date (YYYY-MM-DD or RFC 3339)
+//    before:<date>                - tests started before the date
+//    tag:<key>=<value>            - runs with the given tag
+//    status:pass|fail|skip|notrun - tests with the given result
+//    in:logs                      - also search the client logs of tests
+type searchQuery struct {
+	terms    []string
+	excluded []string
+	client   string
+	suite    string
+	after    time.Time
+	before   time.Time
+	tags     tagFilter
+	status   string
+	logs     bool
+}
+
+// parseSearchQuery parses a search query.
+func parseSearchQuery(q string) (*searchQuery, error) {
+	query := &searchQuery{tags: make(tagFilter)}
+	words, err := splitQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range words {
+		if len(w) > 1 && w[0] == '-' {
+			query.excluded = append(query.excluded, strings.ToLower(strings.Trim(w[1:], `"`)))
+			continue
+		}
+		colon := strings.IndexByte(w, ':')
+		if colon <= 0 || w[0] == '"' {
+			query.terms = append(query.terms, strings.ToLower(strings.Trim(w, `"`)))
+			continue
+		}
+		key, value := w[:colon], w[colon+1:]
+		switch key {
+		case "client":
+			query.client = value
+		case "suite":
+			query.suite = strings.ToLower(value)
+		case "after", "before":
+			t, err := parseSearchDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s date %q", key, value)
+			}
+			if key == "after" {
+				query.after = t
+			} else {
+				query.before = t
+			}
+		case "tag":
+			f, err := parseTagFilter([]string{value})
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range f {
+				query.tags[k] = v
+			}
+		case "status":
+			if value != "pass" && value != "fail" && value != "skip" && value != "notrun" {
+				return nil, fmt.Errorf("invalid status %q, want pass, fail, skip or notrun", value)
+			}
+			query.status = value
+		case "in":
+			if value != "logs" {
+				return nil, fmt.Errorf("invalid filter in:%s, only in:logs is supported", value)
+			}
+			query.logs = true
+		default:
+			// Not a filter, e.g. a hash like 0xabc:12.
+			query.terms = append(query.terms, strings.ToLower(w))
+		}
+	}
+	if len(query.terms) == 0 && query.client == "" && query.suite == "" && query.status == "" && len(query.tags) == 0 {
+		if len(query.excluded) > 0 {
+			return nil, fmt.Errorf("excluded words need search words or filters")
+		}
+		return nil, fmt.Errorf("empty search query")
+	}
+	if query.logs && len(query.terms) == 0 {
+		return nil, fmt.Errorf("in:logs needs search words")
+	}
+	return query, nil
+}
+
+// splitQuery splits a query into words. Quoted phrases are returned as a single word,
+// including the quotes.
+func splitQuery(q string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		quoted bool
+	)
+	for _, c := range q {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			word.WriteRune(c)
+		case c == ' ' && !quoted:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(c)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in search query")
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func parseSearchDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// matchText reports whether text contains all search terms.
+func (q *searchQuery) matchText(text string) bool {
+	text = strings.ToLower(text)
+	for _, term := range q.terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *searchQuery) matchTest(test *libhive.TestCase) bool {
+	for _, term := range q.excluded {
+		if strings.Contains(strings.ToLower(test.Name), term) || strings.Contains(strings.ToLower(test.SummaryResult.Details), term) {
+			return false
+		}
+	}
+	switch {
+	case !q.after.IsZero() && test.Start.Before(q.after):
+		return false
+	case !q.before.IsZero() && !test.Start.Before(q.before):
+		return false
+	case q.status == "pass" && (!test.SummaryResult.Pass || test.SummaryResult.Skipped() || test.SummaryResult.NotRun):
+		return false
+	case q.status == "skip" && !test.SummaryResult.Skipped():
+		return false
+	case q.status == "notrun" && !test.SummaryResult.NotRun:
+		return false
+	case q.status == "fail" && test.SummaryResult.Pass:
+		return false
+	}
+	if q.client == "" {
+		return true
+	}
+	for _, client := range test.ClientInfo {
+		if client.Name == q.client || strings.HasPrefix(client.Name, q.client+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// searchResult is a test matching a search query.
+type searchResult struct {
+	FileName  string    `json:"fileName"` // hive output file of the suite
+	Suite     string    `json:"suite"`
+	RunID     string    `json:"runID,omitempty"`
+	TestID    string    `json:"testID"`
+	Test      string    `json:"test"`
+	Start     time.Time `json:"start"`
+	Pass      bool      `json:"pass"`
+	Skip      string    `json:"skip,omitempty"` // skip reason of skipped tests
+	NotRun    bool      `json:"notRun,omitempty"`
+	Clients   []string  `json:"clients"`
+	MatchedIn string    `json:"matchedIn,omitempty"` // "name", "details" or "log"
+	LogFile   string    `json:"logFile,omitempty"`   // matching client log, relative to logdir
+	Line      int       `json:"line,omitempty"`      // line number in the client log
+	Text      string    `json:"text,omitempty"`      // matching text
+}
+
+// search finds tests matching the query in the result files of logdir. The results
+// are written as JSON lines, newest suites first. Logs are searched by reading them,
+// there is no index, so searches with in:logs can take a while.
+func search(output io.Writer, logdir string, query *searchQuery) error {
+	logfiles, err := ioutil.ReadDir(logdir)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(output)
+	count := 0
+	for i := len(logfiles) - 1; i >= 0 && count < searchLimit; i-- {
+		finfo := logfiles[i]
+		if !strings.HasSuffix(finfo.Name(), ".json") || skipFile(finfo.Name()) {
+			continue
+		}
+		suite := new(libhive.TestSuite)
+		if err := common.LoadJSON(filepath.Join(logdir, finfo.Name()), suite); err != nil || !suiteValid(suite) {
+			continue
+		}
+		if !query.tags.match(suite.Tags) || !strings.Contains(strings.ToLower(suite.Name), query.suite) {
+			continue
+		}
+		for _, result := range searchSuite(logdir, suite, query) {
+			result.FileName = finfo.Name()
+			if err := enc.Encode(result); err != nil {
+				return nil
+			}
+			if count++; count >= searchLimit {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// searchSuite returns the matching tests of a suite.
+func searchSuite(logdir string, suite *libhive.TestSuite, query *searchQuery) []*searchResult {
+	ids := make([]libhive.TestID, 0, len(suite.TestCases))
+	for id := range suite.TestCases {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var results []*searchResult
+	for _, id := range ids {
+		test := suite.TestCases[id]
+		if !query.matchTest(test) {
+			continue
+		}
+		result := &searchResult{
+			Suite:   suite.Name,
+			RunID:   suite.RunID,
+			TestID:  fmt.Sprint(id),
+			Test:    test.Name,
+			Start:   test.Start,
+			Pass:    test.SummaryResult.Pass,
+			Skip:    string(test.SummaryResult.Skip),
+			NotRun:  test.SummaryResult.NotRun,
+			Clients: make([]string, 0, len(test.ClientInfo)),
+		}
+		for _, client := range test.ClientInfo {
+			if !contains(result.Clients, client.Name) {
+				result.Clients = append(result.Clients, client.Name)
+			}
+		}
+		sort.Strings(result.Clients)
+
+		switch {
+		case len(query.terms) == 0:
+		case query.matchText(test.Name):
+			result.MatchedIn, result.Text = "name", test.Name
+		case query.matchText(test.SummaryResult.Details):
+			result.MatchedIn = "details"
+			result.Text = snippet(test.SummaryResult.Details, query.terms[0])
+		case query.logs && searchClientLogs(logdir, test, query, result):
+		default:
+			continue
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// searchClientLogs searches the client logs of a test for a line matching the query.
+// The first match is stored in result.
+func searchClientLogs(logdir string, test *libhive.TestCase, query *searchQuery, result *searchResult) bool {
+	files := make([]string, 0, len(test.ClientInfo))
+	for _, client := range test.ClientInfo {
+		files = append(files, client.LogFile)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		line, text, ok := searchLogFile(filepath.Join(logdir, filepath.FromSlash(file)), query)
+		if ok {
+			result.MatchedIn, result.LogFile, result.Line = "log", file, line
+			result.Text = snippet(text, query.terms[0])
+			return true
+		}
+	}
+	return false
+}
+
+func searchLogFile(file string, query *searchQuery) (int, string, bool) {
+	fd, err := libhive.OpenLog(file)
+	if err != nil {
+		return 0, "", false
+	}
+	defer fd.Close()
+
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLine)
+	for line := 1; scanner.Scan(); line++ {
+		if query.matchText(scanner.Text()) {
+			return line, scanner.Text(), true
+		}
+	}
+	return 0, "", false
+}
+
+// snippet returns the part of text around the first occurrence of term.
+func snippet(text, term string) string {
+	pos := strings.Index(strings.ToLower(text), term)
+	if pos < 0 {
+		pos = 0
+	}
+	start := pos - snippetLength/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetLength
+	if end > len(text) {
+		end = len(text)
+	}
+	s := strings.ToValidUTF8(text[start:end], "")
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < len(text) {
+		s += "..."
+	}
+	return s
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+func TestSplitQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		words []string
+		err   bool
+	}{
+		{query: "", words: nil},
+		{query: "  bad   block ", words: []string{"bad", "block"}},
+		{query: `"bad block" 0xabc`, words: []string{`"bad block"`, "0xabc"}},
+		{query: `-"bad  block" x`, words: []string{`-"bad  block"`, "x"}},
+		{query: `suite:"sync tests"`, words: []string{`suite:"sync tests"`}},
+		{query: `"bad block`, err: true},
+	}
+	for _, test := range tests {
+		words, err := splitQuery(test.query)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", test.query, words)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.query, err)
+		} else if !reflect.DeepEqual(words, test.words) {
+			t.Errorf("%q: wrong words %q, want %q", test.query, words, test.words)
+		}
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  *searchQuery
+		err   string
+	}{
+		{
+			query: `Bad "Invalid Block" 0xabc:12`,
+			want:  &searchQuery{terms: []string{"bad", "invalid block", "0xabc:12"}, tags: tagFilter{}},
+		},
+		{
+			query: `client:go-ethereum suite:Sync status:fail tag:pr=12 in:logs timeout`,
+			want: &searchQuery{
+				terms:  []string{"timeout"},
+				client: "go-ethereum",
+				suite:  "sync",
+				status: "fail",
+				tags:   tagFilter{"pr": "12"},
+				logs:   true,
+			},
+		},
+		{
+			query: `after:2021-06-01 before:2021-07-01T12:00:00Z block`,
+			want: &searchQuery{
+				terms:  []string{"block"},
+				after:  time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+				before: time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC),
+				tags:   tagFilter{},
+			},
+		},
+		{
+			query: `"client:geth" -Timeout -"bad block" -`,
+			want: &searchQuery{
+				terms:    []string{"client:geth", "-"},
+				excluded: []string{"timeout", "bad block"},
+				tags:     tagFilter{},
+			},
+		},
+		{
+			query: `status:skip -flaky`,
+			want:  &searchQuery{status: "skip", excluded: []string{"flaky"}, tags: tagFilter{}},
+		},
+		{query: ``, err: "empty search query"},
+		{query: `in:logs`, err: "empty search query"},
+		{query: `-timeout`, err: "excluded words need search words or filters"},
+		{query: `status:pass in:logs`, err: "in:logs needs search words"},
+		{query: `status:broken`, err: `invalid status "broken", want pass, fail, skip or notrun`},
+		{query: `in:names x`, err: "invalid filter in:names, only in:logs is supported"},
+		{query: `after:yesterday x`, err: `invalid after date "yesterday"`},
+		{query: `tag:pr x`, err: `invalid tag "pr", want KEY=VALUE`},
+		{query: `"bad block`, err: "unterminated quote in search query"},
+	}
+	for _, test := range tests {
+		q, err := parseSearchQuery(test.query)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%q: wrong error %v, want %q", test.query, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.query, err)
+		} else if !reflect.DeepEqual(q, test.want) {
+			t.Errorf("%q: wrong query\n got  %+v\n want %+v", test.query, q, test.want)
+		}
+	}
+}
+
+func TestSearchQueryMatchTest(t *testing.T) {
+	q, err := parseSearchQuery(`block -timeout`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		test  libhive.TestCase
+		match bool
+	}{
+		{libhive.TestCase{Name: "import block"}, true},
+		{libhive.TestCase{Name: "import block (Timeout)"}, false},
+		{libhive.TestCase{Name: "import block", SummaryResult: libhive.TestResult{Details: "test timeout"}}, false},
+	}
+	for _, test := range tests {
+		if match := q.matchTest(&test.test); match != test.match {
+			t.Errorf("%q/%q: match %t, want %t", test.test.Name, test.test.SummaryResult.Details, match, test.match)
+		}
+	}
+}
+
+func TestSearchLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hiveview-search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "client.log")
+	log := "starting client\nimported block 1\nINVALID block 2: bad state root\nshutting down\n"
+	if err := ioutil.WriteFile(file, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		line  int
+		text  string
+		found bool
+	}{
+		{query: "block", line: 2, text: "imported block 1", found: true},
+		{query: `"invalid block" root`, line: 3, text: "INVALID block 2: bad state root", found: true},
+		{query: "block missing", found: false},
+	}
+	for _, test := range tests {
+		q, err := parseSearchQuery(test.query)
+		if err != nil {
+			t.Fatalf("%q: %v", test.query, err)
+		}
+		line, text, found := searchLogFile(file, q)
+		if line != test.line || text != test.text || found != test.found {
+			t.Errorf("%q: got (%d, %q, %t), want (%d, %q, %t)", test.query, line, text, found, test.line, test.text, test.found)
+		}
+	}
+	if _, _, found := searchLogFile(filepath.Join(dir, "missing.log"), &searchQuery{terms: []string{"block"}}); found {
+		t.Error("match in missing file")
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/naoina/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// loadConfigFile reads a YAML or TOML run configuration file and applies it to the
+// command-line flags. The keys of the file are flag names, e.g.
+//
+//	sim: ethereum/rpc
+//	client: [go-ethereum, besu]
+//	sim.parallelism: 4
+//	docker.pull: true
+//	sim.env: [SEED=1, DEBUG=1]
+//	client.image:
+//	  go-ethereum_rc: ethereum/client-go:v1.10.8
+//
+// Flags given on the command line take precedence over values in the file. For flags
+// which can be given multiple times, list values set the flag once per element and
+// maps set it once per key as KEY=VALUE.
+// For all other flags, list elements are joined with commas.
+//
+// Files with the .toml extension are read as TOML. Flag names containing dots can be
+// given as quoted keys or as tables, e.g. [docker] pull = true sets docker.pull.
+func loadConfigFile(fs *flag.FlagSet, file string) error {
+	values, err := readConfigFile(file)
+	if err != nil {
+		return err
+	}
+	return applyConfig(fs, values)
+}
+
+func readConfigFile(file string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("can't read config file: %v", err)
+	}
+	var values map[string]interface{}
+	if filepath.Ext(file) == ".toml" {
+		err = toml.Unmarshal(content, &values)
+	} else {
+		err = yaml.Unmarshal(content, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", file, err)
+	}
+	return values, nil
+}
+
+// profileDir is the directory containing run profiles.
+const profileDir = "profiles"
+
+// loadProfiles applies run profiles to the command-line flags. A profile is a config
+// file in the profiles directory, named <name>.yaml or <name>.toml. Profiles can include other
+// profiles using the 'profile' key.
+//
+// Options set on the command line or by the --config file take precedence over
+// profiles. When several profiles are given, later profiles override earlier ones,
+// and profiles override the profiles they include.
+func loadProfiles(fs *flag.FlagSet, dir string, names []string) error {
+	return loadProfileList(fs, dir, names, make(map[string]bool))
+}
+
+// loadProfileList applies profiles in reverse order, so later ones take precedence.
+// The loading map tracks the profiles being loaded to detect include cycles.
+func loadProfileList(fs *flag.FlagSet, dir string, names []string, loading map[string]bool) error {
+	for i := len(names) - 1; i >= 0; i-- {
+		name := names[i]
+		if loading[name] {
+			return fmt.Errorf("profile %q includes itself", name)
+		}
+		loading[name] = true
+		if err := loadProfile(fs, dir, name, loading); err != nil {
+			return err
+		}
+		delete(loading, name)
+	}
+	return nil
+}
+
+func loadProfile(fs *flag.FlagSet, dir, name string, loading map[string]bool) error {
+	file := filepath.Join(dir, name+".yaml")
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		file = filepath.Join(dir, name+".toml")
+	}
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(listProfiles(dir), ", "))
+	}
+	values, err := readConfigFile(file)
+	if err != nil {
+		return err
+	}
+	var include []string
+	switch v := values["profile"].(type) {
+	case nil:
+	case []interface{}:
+		for _, elem := range v {
+			include = append(include, fmt.Sprint(elem))
+		}
+	default:
+		include = splitAndTrim(fmt.Sprint(v), ",")
+	}
+	delete(values, "profile")
+	if err := applyConfig(fs, values); err != nil {
+		return fmt.Errorf("profile %s: %v", name, err)
+	}
+	return loadProfileList(fs, dir, include, loading)
+}
+
+// listProfiles returns the names of all profiles in dir.
+func listProfiles(dir string) []string {
+	yamlFiles, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	tomlFiles, _ := filepath.Glob(filepath.Join(dir, "*.toml"))
+	var names []string
+	for _, file := range append(yamlFiles, tomlFiles...) {
+		base := filepath.Base(file)
+		names = append(names, strings.TrimSuffix(base, filepath.Ext(base)))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyConfig sets flags which weren't set on the command line.
+func applyConfig(fs *flag.FlagSet, values map[string]interface{}) error {
+	setOnCLI := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { setOnCLI[f.Name] = true })
+	values, err := expandTables(fs, "", values)
+	if err != nil {
+		return err
+	}
+
+	// Apply in sorted order so errors are reported deterministically.
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("unknown option %q in config file", name)
+		}
+		if setOnCLI[name] {
+			continue
+		}
+		var settings []string
+		switch v := values[name].(type) {
+		case []interface{}:
+			list := make([]string, len(v))
+			for i := range v {
+				list[i] = fmt.Sprint(v[i])
+			}
+			if _, repeatable := f.Value.(*envFlag); repeatable {
+				settings = list
+			} else {
+				settings = []string{strings.Join(list, ",")}
+			}
+		case map[interface{}]interface{}:
+			if _, repeatable := f.Value.(*envFlag); !repeatable {
+				return fmt.Errorf("option %q in config file can't be a map", name)
+			}
+			for k, kv := range v {
+				settings = append(settings, fmt.Sprintf("%v=%v", k, kv))
+			}
+			sort.Strings(settings)
+		case map[string]interface{}:
+			for k, kv := range v {
+				settings = append(settings, fmt.Sprintf("%v=%v", k, kv))
+			}
+			sort.Strings(settings)
+		case nil:
+			continue
+		default:
+			settings = []string{fmt.Sprint(v)}
+		}
+		for _, s := range settings {
+			if err := fs.Set(name, s); err != nil {
+				return fmt.Errorf("invalid value %q for option %q in config file: %v", s, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expandTables replaces the TOML tables in values by their entries, prefixed with the
+// table name. Tables of repeatable flags are kept, since they set the flag once per key.
+func expandTables(fs *flag.FlagSet, prefix string, values map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(values))
+	for key, v := range values {
+		name := prefix + key
+		table, isTable := v.(map[string]interface{})
+		if f := fs.Lookup(name); isTable && f != nil {
+			_, repeatable := f.Value.(*envFlag)
+			isTable = !repeatable
+		}
+		if !isTable {
+			if _, dup := result[name]; dup {
+				return nil, fmt.Errorf("option %q is set twice in config file", name)
+			}
+			result[name] = v
+			continue
+		}
+		entries, err := expandTables(fs, name+".", table)
+		if err != nil {
+			return nil, err
+		}
+		for name, ev := range entries {
+			if _, dup := result[name]; dup {
+				return nil, fmt.Errorf("option %q is set twice in config file", name)
+			}
+			result[name] = ev
+		}
+	}
+	return result, nil
+}
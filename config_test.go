@@ -0,0 +1,184 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyConfig(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var (
+		sim     = fs.String("sim", "", "")
+		clients = fs.String("client", "go-ethereum", "")
+		par     = fs.Int("sim.parallelism", 1, "")
+		env     envFlag
+	)
+	fs.Var(&env, "sim.env", "")
+	if err := fs.Parse([]string{"--sim", "from-cli"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := applyConfig(fs, map[string]interface{}{
+		"sim":             "from-file",
+		"client":          []interface{}{"besu", "nethermind"},
+		"sim.parallelism": 4,
+		"sim.env":         []interface{}{"A=1", "B=2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *sim != "from-cli" {
+		t.Errorf("command-line flag overridden by config file: %q", *sim)
+	}
+	if *clients != "besu,nethermind" {
+		t.Errorf("wrong client list %q", *clients)
+	}
+	if *par != 4 {
+		t.Errorf("wrong parallelism %d", *par)
+	}
+	if len(env) != 2 || env["A"] != "1" || env["B"] != "2" {
+		t.Errorf("wrong sim.env %v", env)
+	}
+
+	if err := applyConfig(fs, map[string]interface{}{"no.such.flag": 1}); err == nil {
+		t.Error("no error for unknown option")
+	}
+}
+
+func TestApplyConfigMap(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var images envFlag
+	fs.Var(&images, "client.image", "")
+	fs.String("client", "", "")
+
+	// Maps set repeatable flags once per key.
+	err := applyConfig(fs, map[string]interface{}{
+		"client.image": map[interface{}]interface{}{"geth_rc": "ethereum/client-go:v1.10.8", "besu_rc": "hyperledger/besu:21.7.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || images["geth_rc"] != "ethereum/client-go:v1.10.8" || images["besu_rc"] != "hyperledger/besu:21.7.0" {
+		t.Errorf("wrong client.image from map %v", images)
+	}
+	if err := applyConfig(fs, map[string]interface{}{"client": map[interface{}]interface{}{"a": 1}}); err == nil {
+		t.Error("no error for map value of non-repeatable option")
+	}
+}
+
+func TestLoadConfigFileTOML(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var (
+		sim     = fs.String("sim", "", "")
+		clients = fs.String("client", "", "")
+		par     = fs.Int("sim.parallelism", 1, "")
+		pull    = fs.Bool("docker.pull", false, "")
+		env     envFlag
+		images  envFlag
+	)
+	fs.Var(&env, "sim.env", "")
+	fs.Var(&images, "client.image", "")
+	file := filepath.Join(t.TempDir(), "hive.toml")
+	content := `
+sim = "ethereum/rpc"
+client = ["go-ethereum", "besu"]
+"sim.parallelism" = 4
+"sim.env" = ["A=1"]
+"client.image" = { go-ethereum_rc = "ethereum/client-go:v1.10.8" }
+
+[docker]
+pull = true
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfigFile(fs, file); err != nil {
+		t.Fatal(err)
+	}
+	if *sim != "ethereum/rpc" || *clients != "go-ethereum,besu" || *par != 4 || !*pull {
+		t.Errorf("wrong options: sim=%q client=%q parallelism=%d docker.pull=%v", *sim, *clients, *par, *pull)
+	}
+	if len(env) != 1 || env["A"] != "1" {
+		t.Errorf("wrong sim.env %v", env)
+	}
+	if len(images) != 1 || images["go-ethereum_rc"] != "ethereum/client-go:v1.10.8" {
+		t.Errorf("wrong client.image %v", images)
+	}
+
+	// Options must not be set by both a quoted key and a table.
+	dup := map[string]interface{}{
+		"docker.pull": true,
+		"docker":      map[string]interface{}{"pull": false},
+	}
+	if err := applyConfig(fs, dup); err == nil {
+		t.Error("no error for option set twice")
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	dir := t.TempDir()
+	profiles := map[string]string{
+		"base":  "sim: smoke/\nclient: [go-ethereum]\nsim.parallelism: 1\n",
+		"quick": "profile: base\nsim.parallelism: 2\n",
+		"big":   "sim.parallelism: 8\n",
+		"loop":  "profile: loop\n",
+	}
+	for name, content := range profiles {
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "huge.toml"), []byte(`profile = "big"
+"sim.parallelism" = 16
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newFlags := func(args ...string) (*flag.FlagSet, *string, *string, *int) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		sim := fs.String("sim", "", "")
+		clients := fs.String("client", "", "")
+		par := fs.Int("sim.parallelism", 1, "")
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return fs, sim, clients, par
+	}
+
+	// A profile overrides the profiles it includes.
+	fs, sim, clients, par := newFlags()
+	if err := loadProfiles(fs, dir, []string{"quick"}); err != nil {
+		t.Fatal(err)
+	}
+	if *sim != "smoke/" || *clients != "go-ethereum" || *par != 2 {
+		t.Errorf("wrong options from profile: sim=%q client=%q parallelism=%d", *sim, *clients, *par)
+	}
+
+	// Later profiles override earlier ones, and the command line overrides all profiles.
+	fs, sim, _, par = newFlags("--sim", "ethereum/rpc")
+	if err := loadProfiles(fs, dir, []string{"quick", "big"}); err != nil {
+		t.Fatal(err)
+	}
+	if *sim != "ethereum/rpc" || *par != 8 {
+		t.Errorf("wrong options from profiles: sim=%q parallelism=%d", *sim, *par)
+	}
+
+	// Profiles can be TOML files.
+	fs, _, _, par = newFlags()
+	if err := loadProfiles(fs, dir, []string{"huge"}); err != nil {
+		t.Fatal(err)
+	}
+	if *par != 16 {
+		t.Errorf("wrong parallelism from TOML profile %d", *par)
+	}
+
+	fs, _, _, _ = newFlags()
+	if err := loadProfiles(fs, dir, []string{"loop"}); err == nil {
+		t.Error("no error for include cycle")
+	}
+	if err := loadProfiles(fs, dir, []string{"unknown"}); err == nil {
+		t.Error("no error for unknown profile")
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/hive/internal/libdocker"
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+// Resource limits checked by 'hive doctor'.
+const (
+	doctorMinDisk    = 10 << 30 // free disk space for images and results
+	doctorMinMemory  = 4 << 30  // available memory
+	doctorWarnMargin = 2        // below limit/margin, the check fails instead of warning
+)
+
+// doctorStatus is the outcome of a check.
+type doctorStatus int
+
+const (
+	doctorOK doctorStatus = iota
+	doctorWarn
+	doctorFail
+)
+
+func (s doctorStatus) String() string {
+	switch s {
+	case doctorOK:
+		return "ok"
+	case doctorWarn:
+		return "warn"
+	default:
+		return "FAIL"
+	}
+}
+
+// doctorResult is the result of a single check.
+type doctorResult struct {
+	status doctorStatus
+	name   string
+	msg    string
+	hint   string // how to fix the problem
+}
+
+// doctorCommand implements 'hive doctor'. It checks that the environment can run
+// simulations and prints what needs to be fixed.
+func doctorCommand(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	var (
+		dockerEndpoint = fs.String("docker.endpoint", "unix:///var/run/docker.sock", "Endpoint of the local Docker daemon.")
+		dockerEngine   = fs.String("docker.engine", libdocker.EngineDocker, "Container `engine` behind the endpoint: docker or podman.")
+		resultsRoot    = fs.String("results-root", "workspace/logs", "Target `directory` for results files and logs.")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: hive doctor [options]")
+		fmt.Fprintln(os.Stderr, "Checks the docker setup, host resources and the client/simulator inventory.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	endpoint, err := engineEndpoint(fs, *dockerEngine, *dockerEndpoint)
+	if err != nil {
+		fatal(err)
+	}
+
+	var results []doctorResult
+	info, err := libdocker.Info(endpoint)
+	switch {
+	case err != nil && *dockerEngine == libdocker.EnginePodman:
+		results = append(results, doctorResult{
+			status: doctorFail,
+			name:   "podman",
+			msg:    err.Error(),
+			hint:   "Start the podman API service with 'systemctl --user start podman.socket', or set --docker.endpoint.",
+		})
+	case err != nil:
+		results = append(results, doctorResult{
+			status: doctorFail,
+			name:   "docker",
+			msg:    err.Error(),
+			hint:   "Start the docker daemon and make sure your user is in the 'docker' group, or set --docker.endpoint.",
+		})
+	case *dockerEngine == libdocker.EnginePodman:
+		results = append(results, checkPodman(info))
+		results = append(results, checkDisk("podman storage", info.RootDir))
+	default:
+		results = append(results, checkDockerDaemon(info)...)
+		results = append(results, checkCgroups(info, "/sys/fs/cgroup"))
+		if r, ok := checkIptables(info); ok {
+			results = append(results, r)
+		}
+		results = append(results, checkDisk("docker storage", info.RootDir))
+	}
+	results = append(results, checkDisk("results directory", *resultsRoot))
+	results = append(results, checkMemory())
+	results = append(results, checkInventory(".")...)
+
+	failed := false
+	for _, r := range results {
+		fmt.Printf("%-5s %s: %s\n", r.status, r.name, r.msg)
+		if r.status != doctorOK && r.hint != "" {
+			fmt.Printf("      hint: %s\n", r.hint)
+		}
+		failed = failed || r.status == doctorFail
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// checkDockerDaemon checks the docker version and network settings.
+func checkDockerDaemon(info *libdocker.DaemonInfo) []doctorResult {
+	results := []doctorResult{{
+		status: doctorOK,
+		name:   "docker",
+		msg:    fmt.Sprintf("version %s, API %s", info.Version, info.APIVersion),
+	}}
+	switch {
+	case info.OSType != "" && info.OSType != "linux":
+		results[0].status = doctorFail
+		results[0].msg += fmt.Sprintf(", %s containers", info.OSType)
+		results[0].hint = "Hive needs Linux containers. Switch docker to Linux containers."
+	case !versionAtLeast(info.Version, 17, 5):
+		// Multi-stage builds are used by many client and simulator Dockerfiles.
+		results[0].status = doctorFail
+		results[0].hint = "Docker 17.05 or later is required. Upgrade docker."
+	}
+	if !info.IPv4Forwarding {
+		results = append(results, doctorResult{
+			status: doctorFail,
+			name:   "docker network",
+			msg:    "IPv4 forwarding is disabled",
+			hint:   "Containers can't reach the simulation API. Enable it with 'sysctl net.ipv4.ip_forward=1' and restart docker.",
+		})
+	} else if !info.BridgeNfIptables {
+		results = append(results, doctorResult{
+			status: doctorWarn,
+			name:   "docker network",
+			msg:    "bridge-nf-call-iptables is disabled",
+			hint:   "Network isolation between containers may not work. Run 'modprobe br_netfilter'.",
+		})
+	}
+	return results
+}
+
+// checkPodman checks the podman version. The Docker-compatible API of podman is
+// complete enough for hive since podman 3.0.
+func checkPodman(info *libdocker.DaemonInfo) doctorResult {
+	r := doctorResult{status: doctorOK, name: "podman", msg: "version " + info.Version}
+	if !versionAtLeast(info.Version, 3, 0) {
+		r.status = doctorFail
+		r.hint = "Podman 3.0 or later is required. Upgrade podman."
+	}
+	return r
+}
+
+// checkCgroups checks that docker supports the cgroup version of the host.
+func checkCgroups(info *libdocker.DaemonInfo, cgroupRoot string) doctorResult {
+	r := doctorResult{status: doctorOK, name: "cgroups", msg: "v1"}
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		r.msg = "v2"
+		if !versionAtLeast(info.Version, 20, 10) {
+			r.status = doctorFail
+			r.hint = "Docker supports cgroup v2 since version 20.10. Upgrade docker."
+		}
+	}
+	return r
+}
+
+// checkIptables checks the iptables mode of the host. Older docker versions
+// can't set up container networking when iptables uses the nf_tables backend.
+// The check is skipped when iptables isn't installed.
+func checkIptables(info *libdocker.DaemonInfo) (doctorResult, bool) {
+	out, err := exec.Command("iptables", "--version").Output()
+	if err != nil {
+		return doctorResult{}, false
+	}
+	r := doctorResult{status: doctorOK, name: "iptables", msg: strings.TrimSpace(string(out))}
+	if strings.Contains(r.msg, "nf_tables") && !versionAtLeast(info.Version, 20, 10) {
+		r.status = doctorWarn
+		r.hint = "Container networking may fail with the nf_tables backend. Upgrade docker or switch to iptables-legacy."
+	}
+	return r, true
+}
+
+// checkDisk checks the free space of the file system containing dir.
+// If dir doesn't exist, its closest existing parent is checked.
+func checkDisk(name, dir string) doctorResult {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	r := resourceResult(libhive.DiskCheck(dir, doctorMinDisk), libhive.DiskCheck(dir, doctorMinDisk/doctorWarnMargin))
+	r.name = "disk (" + name + ")"
+	if r.status == doctorOK {
+		r.msg = fmt.Sprintf("%s has %d GB or more free", dir, doctorMinDisk>>30)
+	} else {
+		r.hint = "Client images and logs need a lot of space. Free disk space, e.g. with 'docker system prune'."
+	}
+	return r
+}
+
+// checkMemory checks the available memory of the host.
+func checkMemory() doctorResult {
+	r := resourceResult(libhive.MemoryCheck(doctorMinMemory), libhive.MemoryCheck(doctorMinMemory/doctorWarnMargin))
+	r.name = "memory"
+	if r.status == doctorOK {
+		r.msg = fmt.Sprintf("%d GB or more available", doctorMinMemory>>30)
+	} else {
+		r.hint = "Clients may crash or be killed. Stop other programs or reduce --sim.parallelism."
+	}
+	return r
+}
+
+// resourceResult runs a host check with the warning and failure limits.
+func resourceResult(warn, fail libhive.HostCheck) doctorResult {
+	if err := fail.Check(); err != nil {
+		return doctorResult{status: doctorFail, msg: err.Error()}
+	}
+	if err := warn.Check(); err != nil {
+		return doctorResult{status: doctorWarn, msg: err.Error()}
+	}
+	return doctorResult{status: doctorOK}
+}
+
+// checkInventory checks that the client and simulator definitions are usable: client
+// metadata must be valid, and files copied by the Dockerfiles must exist.
+func checkInventory(basedir string) []doctorResult {
+	inv, err := libhive.LoadInventory(basedir)
+	if err != nil {
+		return []doctorResult{{
+			status: doctorFail,
+			name:   "inventory",
+			msg:    err.Error(),
+			hint:   "Run hive in the root directory of the hive repository.",
+		}}
+	}
+	if len(inv.Clients) == 0 {
+		return []doctorResult{{
+			status: doctorFail,
+			name:   "inventory",
+			msg:    "no clients found",
+			hint:   "Run hive in the root directory of the hive repository.",
+		}}
+	}
+
+	var problems []string
+	for _, name := range sortedKeys(inv.Clients) {
+		if _, err := inv.ClientMetadata(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+		problems = append(problems, checkDockerfile(inv.ClientDirectory(name))...)
+	}
+	for _, name := range sortedKeys(inv.Simulators) {
+		if _, err := inv.SimulatorMetadata(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+		problems = append(problems, checkDockerfile(inv.SimulatorDirectory(name))...)
+	}
+
+	summary := fmt.Sprintf("%d clients, %d simulators", len(inv.Clients), len(inv.Simulators))
+	if len(problems) == 0 {
+		return []doctorResult{{status: doctorOK, name: "inventory", msg: summary}}
+	}
+	results := make([]doctorResult, len(problems))
+	for i, p := range problems {
+		results[i] = doctorResult{
+			status: doctorFail,
+			name:   "inventory",
+			msg:    p,
+			hint:   "Building this image will fail. Restore the missing file, e.g. with 'git checkout'.",
+		}
+	}
+	return results
+}
+
+// checkDockerfile checks that the sources of COPY and ADD instructions in the Dockerfile
+// of dir exist. Sources from other build stages, URLs and paths containing build
+// arguments are not checked.
+func checkDockerfile(dir string) []string {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "Dockerfile"))
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var problems []string
+	for _, inst := range dockerfileInstructions(string(content)) {
+		fields := strings.Fields(inst)
+		if len(fields) == 0 {
+			continue
+		}
+		cmd := strings.ToUpper(fields[0])
+		if cmd != "COPY" && cmd != "ADD" {
+			continue
+		}
+		args, fromStage := copySources(strings.TrimSpace(inst[len(fields[0]):]))
+		if fromStage {
+			continue
+		}
+		for _, src := range args {
+			if strings.Contains(src, "$") || strings.Contains(src, "://") {
+				continue
+			}
+			matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(src)))
+			if len(matches) == 0 {
+				problems = append(problems, fmt.Sprintf("%s: %s source %q does not exist", filepath.Join(dir, "Dockerfile"), cmd, src))
+			}
+		}
+	}
+	return problems
+}
+
+// dockerfileInstructions splits a Dockerfile into instructions, joining
+// continuation lines and removing comments.
+func dockerfileInstructions(content string) []string {
+	var (
+		insts   []string
+		current strings.Builder
+	)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\"))
+			current.WriteString(" ")
+			continue
+		}
+		current.WriteString(line)
+		if s := strings.TrimSpace(current.String()); s != "" {
+			insts = append(insts, s)
+		}
+		current.Reset()
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		insts = append(insts, s)
+	}
+	return insts
+}
+
+// copySources returns the source paths of COPY/ADD arguments. The second return value
+// is true when the files come from another build stage or image.
+func copySources(args string) ([]string, bool) {
+	fields := strings.Fields(args)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+		if strings.HasPrefix(fields[0], "--from=") {
+			return nil, true
+		}
+		fields = fields[1:]
+	}
+	rest := strings.Join(fields, " ")
+	if strings.HasPrefix(rest, "[") {
+		var list []string
+		if err := json.Unmarshal([]byte(rest), &list); err == nil {
+			fields = list
+		}
+	}
+	if len(fields) < 2 {
+		return nil, false
+	}
+	return fields[:len(fields)-1], false
+}
+
+// versionAtLeast reports whether the version string v, like "20.10.7", is
+// at least major.minor. Unparseable versions are assumed to be recent.
+func versionAtLeast(v string, major, minor int) bool {
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return true
+	}
+	vmajor, err1 := strconv.Atoi(parts[0])
+	vminor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	return vmajor > major || vmajor == major && vminor >= minor
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDockerfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hive-doctor-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dockerfile := `FROM golang:1-alpine AS builder
+# COPY commented.sh /
+COPY go.mod go.sum /source/
+COPY --from=builder /out/sim /sim
+ADD https://example.com/file.tar.gz /
+ARG script=run.sh
+COPY $script /
+COPY --chown=1000 ["enode.sh", \
+     "missing.sh", "/"]
+COPY *.json /config/
+`
+	files := map[string]string{"Dockerfile": dockerfile, "go.mod": "", "enode.sh": "", "genesis.json": ""}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problems := checkDockerfile(dir)
+	if len(problems) != 2 {
+		t.Fatalf("wrong number of problems: %q", problems)
+	}
+	if !strings.Contains(problems[0], `"go.sum"`) || !strings.Contains(problems[1], `"missing.sh"`) {
+		t.Fatalf("wrong problems: %q", problems)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		v            string
+		major, minor int
+		want         bool
+	}{
+		{"20.10.7", 20, 10, true},
+		{"19.03.12", 20, 10, false},
+		{"17.05.0-ce", 17, 5, true},
+		{"24.0.5", 20, 10, true},
+		{"dev", 20, 10, true},
+	}
+	for _, test := range tests {
+		if got := versionAtLeast(test.v, test.major, test.minor); got != test.want {
+			t.Errorf("versionAtLeast(%q, %d, %d) = %v, want %v", test.v, test.major, test.minor, got, test.want)
+		}
+	}
+}
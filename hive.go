@@ -11,23 +11,54 @@ import (
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/hive/internal/libdocker"
 	"github.com/ethereum/hive/internal/libhive"
+	"github.com/ethereum/hive/internal/libk8s"
 	"gopkg.in/inconshreveable/log15.v2"
 )
 
 func main() {
+	// Handle subcommands.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "stats":
+			statsCommand(os.Args[2:])
+			return
+		case "doctor":
+			doctorCommand(os.Args[2:])
+			return
+		case "inventory":
+			inventoryCommand(os.Args[2:])
+			return
+		case "test-clients":
+			testClientsCommand(os.Args[2:])
+			return
+		}
+	}
+
 	var (
-		testResultsRoot       = flag.String("results-root", "workspace/logs", "Target `directory` for results files and logs.")
-		loglevelFlag          = flag.Int("loglevel", 3, "Log `level` for system events. Supports values 0-5.")
-		dockerEndpoint        = flag.String("docker.endpoint", "unix:///var/run/docker.sock", "Endpoint of the local Docker daemon.")
-		dockerNoCache         = flag.String("docker.nocache", "", "Regular `expression` selecting the docker images to forcibly rebuild.")
-		dockerPull            = flag.Bool("docker.pull", false, "Refresh base images when building images.")
+		configFile      = flag.String("config", "", "Reads options from the given YAML or TOML `file`. Command-line flags override values from the file.")
+		testResultsRoot = flag.String("results-root", "workspace/logs", "Target `directory` for results files and logs.")
+		loglevelFlag    = flag.Int("loglevel", 3, "Log `level` for system events. Supports values 0-5.")
+		dockerEndpoint  = flag.String("docker.endpoint", "unix:///var/run/docker.sock", "Endpoint of the local Docker daemon.")
+		dockerEngine    = flag.String("docker.engine", libdocker.EngineDocker, "Container `engine` behind the endpoint: docker or podman.\n"+
+			"Podman is used through its Docker-compatible API. Unless --docker.endpoint is set,\n"+
+			"the podman socket is used.")
+		dockerNoCache     = flag.String("docker.nocache", "", "Regular `expression` selecting the docker images to forcibly rebuild.")
+		dockerPull        = flag.Bool("docker.pull", false, "Refresh base images when building images.")
+		dockerCacheMounts = flag.String("docker.cachemounts", "", "Comma separated `list` of package manager caches mounted into image builds, e.g. go,cargo,gradle.\n"+
+			"Custom caches are given as NAME=TARGET. The caches persist across builds. Requires docker with BuildKit.")
 		dockerOutput          = flag.Bool("docker.output", false, "Relay all docker output to stderr.")
+		dockerBuildParallel   = flag.Int("docker.build-parallelism", 1, "Max `number` of docker images built concurrently.")
+		dockerProbeImage      = flag.String("docker.probe-image", libdocker.DefaultProbeImage, "iperf3 `image` used for network measurements requested by simulators.")
+		dockerProxyImage      = flag.String("docker.proxy-image", libdocker.DefaultProxyImage, "socat `image` used to forward the standard API ports of clients with nonstandard ports.")
+		dockerNetemImage      = flag.String("docker.netem-image", libdocker.DefaultNetemImage, "tc `image` used to apply network latency, packet loss and bandwidth limits requested by simulators.")
 		simPattern            = flag.String("sim", "", "Regular `expression` selecting the simulators to run.")
 		simParallelism        = flag.Int("sim.parallelism", 1, "Max `number` of parallel clients/containers (interpreted by simulators).")
 		simTestLimit          = flag.Int("sim.testlimit", 0, "Max `number` of tests to execute per client (interpreted by simulators).")
@@ -35,23 +66,132 @@ func main() {
 		simLogLevel           = flag.Int("sim.loglevel", 3, "Selects log `level` of client instances. Supports values 0-5.")
 		simDevMode            = flag.Bool("dev", false, "Only starts the simulator API endpoint (listening at 127.0.0.1:3000 by default) without starting any simulators.")
 		simDevModeAPIEndpoint = flag.String("dev.addr", "127.0.0.1:3000", "Endpoint that the simulator API listens on")
+		simEnvPassthrough     = flag.String("sim.env.passthrough", "", "Comma separated `list` of host environment variables to pass to simulators.\n"+
+			"Entries ending in '*' match all variables with the given prefix.")
+		simEnv       envFlag
+		clientImages envFlag
+		dockerfiles  envFlag
+		runTags      envFlag
+
+		profile = flag.String("profile", "", "Comma separated `list` of run profiles to apply, e.g. smoke. Profiles are YAML files in the\n"+
+			"profiles directory. Command-line flags and --config override values from profiles.")
+
+		cacheDir = flag.String("cache-dir", "", "Build cache `directory`. When set, client and simulator images are only rebuilt\n"+
+			"when their build inputs have changed.")
+		forceRebuild = flag.Bool("force-rebuild", false, "Rebuild all images, ignoring the build cache.")
+
+		simMaxContainers = flag.Int("sim.quota.containers", 0, "Max `number` of client containers a simulator may run at the same time (0 = unlimited).")
+		simMaxNetworks   = flag.Int("sim.quota.networks", 0, "Max `number` of docker networks a simulator may create (0 = unlimited).")
+		simMaxStartRate  = flag.Int("sim.quota.startrate", 0, "Max `number` of client containers a simulator may start per minute (0 = unlimited).")
+
+		hostMinDisk      = flag.Int("host.mindisk", 0, "Min free disk space in `MB` in the results directory. Below this, new tests are paused (0 = no check).")
+		hostMinMemory    = flag.Int("host.minmemory", 0, "Min available host memory in `MB`. Below this, new tests are paused (0 = no check).")
+		hostPauseTimeout = flag.Duration("host.pausetimeout", 5*time.Minute, "Aborts the run when host resources don't recover within this `time`.")
+
+		simOrder = flag.String("sim.order", "", "Order in which simulators run tests: alpha, random, slowest-first or failed-first.\n"+
+			"slowest-first and failed-first use the results of the --summary.baseline run.\n"+
+			"By default, tests run in the order defined by the simulator.")
+		simOrderSeed   = flag.Int64("sim.order.seed", 0, "Random `seed` for --sim.order=random (0 = use a new seed).")
+		simShard       = flag.String("sim.shard", "", "Runs only the tests of shard `i/n` of the simulations, for splitting a run across n hive instances.")
+		simRetries     = flag.Int("sim.retries", 0, "Re-runs failed tests up to this `number` of times. All attempts are kept in the results.")
+		simTestTimeout = flag.Duration("sim.testtimeout", 0, "Default `timeout` of tests. Tests running longer fail. Simulators can set the timeout of each test.")
+		simListParams  = flag.Bool("sim.listparams", false, "Lists the parameters declared by the simulators selected with --sim, and exits.")
 
 		clients = flag.String("client", "go-ethereum", "Comma separated `list` of clients to use. Client names in the list may be given as\n"+
 			"just the client name, or a client_branch specifier. If a branch name is supplied,\n"+
 			"the client image will use the given git branch or docker tag. Multiple instances of\n"+
 			"a single client type may be requested with different branches.\n"+
 			"Example: \"besu_latest,besu_20.10.2\"")
+		clientNoWrap = flag.String("client.nowrap", "", "Comma separated `list` of clients whose --client.image is used as-is, without\n"+
+			"building the client's Dockerfile on top of it. The image must contain the hive client scripts.")
 		clientTimeout = flag.Duration("client.checktimelimit", 3*time.Minute, "The `timeout` of waiting for clients to open up the RPC port.\n"+
 			"If a very long chain is imported, this timeout may need to be quite large.\n"+
 			"A lower value means that hive won't wait as long in case the node crashes and\n"+
 			"never opens the RPC port.")
+		clientStartRetries = flag.Int("client.startretries", 0, "Max `number` of times a client start is retried when the client doesn't come up.")
+		clientCPUs         = flag.Float64("client.cpus", 0, "Limits the `number` of CPUs available to each client container, e.g. 2 or 0.5 (0 = no limit).\n"+
+			"Clients can override the limit in their hive.yaml.")
+		clientMemory libhive.MemorySize
+
+		backendName = flag.String("backend", "docker", "Container `backend` running clients and simulators: docker or k8s.\n"+
+			"Images are always built with docker. The k8s backend runs containers as pods of a Kubernetes cluster.")
+		k8sKubeconfig = flag.String("k8s.kubeconfig", "", "Kubeconfig `file` of the cluster. By default, the in-cluster configuration or\n"+
+			"$KUBECONFIG or ~/.kube/config is used.")
+		k8sNamespace = flag.String("k8s.namespace", "", "Kubernetes `namespace` of the pods (default: the namespace of the kubeconfig context).")
+		k8sRegistry  = flag.String("k8s.registry", "", "Image `registry` the images are pushed to for the cluster, e.g. registry.example.com/hive.\n"+
+			"If empty, the images must already be available on the cluster nodes.")
+		k8sHostIP = flag.String("k8s.hostip", "", "`IP` address at which pods reach the simulator API of hive\n"+
+			"(default: the local address of the connection to the cluster).")
+
+		otlpEndpoint = flag.String("otlp.endpoint", "", "Publishes test suites and tests as trace spans to the OpenTelemetry collector at `URL`\n"+
+			"(OTLP over HTTP, e.g. http://localhost:4318).")
+		otlpHeaders envFlag
+
+		resultsFormat = flag.String("results.format", libhive.ResultFormatJSON, "Comma separated `list` of result file formats written for each test suite: json, junit.\n"+
+			"JSON files are read by hiveview, JUnit XML files by CI test reporting.")
+		resultsCompress  = flag.Bool("results.compress", false, "Compresses client and simulator logs with zstd while they are written.")
+		resultsNoStreams = flag.Bool("results.nostreamlogs", false, "Only writes the combined log of clients, without the separate logs of their stdout and stderr.")
+		resultsStream    = flag.String("results-stream", "", "Serves a websocket stream of test start and end events at ws://`addr`/results,\n"+
+			"e.g. for dashboards showing the progress of the run.")
+		resultsStreamFile = flag.String("results-stream.file", "", "Appends the events of the result stream to `file` as JSON lines, e.g. for hiveview --follow.")
+		resultsWebhook    = flag.String("results.webhook", "", "Posts the result of every test as JSON to `URL` when the test ends,\n"+
+			"e.g. for feeding results into test management systems.")
+		resultsWebhookHeaders envFlag
+
+		summaryFD = flag.Int("summary.fd", 0, "Writes a single-line JSON summary of the run to file descriptor `n` when the run ends.\n"+
+			"When writing to stdout (1) or stderr (2), the line is prefixed by \""+summaryMarker+"\".")
+		summaryBaseline = flag.String("summary.baseline", "", "Summary `file` of a previous run. Tests failing in this run but not in the\n"+
+			"baseline run are reported as regressions in the summary.")
 	)
 
+	flag.Var(&clientImages, "client.image", "Uses a registry image for a client of the --client list, given as CLIENT=IMAGE.\n"+
+		"The image may be pinned with a digest, e.g. go-ethereum_rc=ethereum/client-go:v1.10.8@sha256:<digest>.\n"+
+		"The client's Dockerfile is built on top of the image. Can be given multiple times.")
+	flag.Var(&dockerfiles, "client.dockerfile", "Selects the Dockerfile of a client of the --client list, given as CLIENT=NAME.\n"+
+		"The names of the Dockerfiles supported by a client are declared in its hive.yaml. Can be given multiple times.")
+	flag.Var(&runTags, "tag", "Attaches a KEY=VALUE tag to the run, e.g. release=v1.10.8. The tags are stored in the\n"+
+		"results and run summary and can be used to find runs in hiveview. Can be given multiple times.")
+	flag.Var(&otlpHeaders, "otlp.header", "Adds a `KEY=VALUE` header to requests sent to the --otlp.endpoint, e.g. for API keys.\n"+
+		"Can be given multiple times.")
+	flag.Var(&resultsWebhookHeaders, "results.webhook.header", "Adds a `KEY=VALUE` header to requests sent to the --results.webhook, e.g. for API keys.\n"+
+		"Can be given multiple times.")
+	flag.Var(&clientMemory, "client.memory", "Limits the memory of each client container to `size`, e.g. 4g or 512m (0 = no limit).\n"+
+		"Swap is disabled for limited containers. Clients can override the limit in their hive.yaml.")
+	flag.Var(&simEnv, "sim.env", "Sets an environment variable in the simulator container. Can be given multiple times.\n"+
+		"The value must be of the form `KEY=VALUE`.")
+
 	// Parse the flags and configure the logger.
 	flag.Parse()
+	if *configFile != "" {
+		if err := loadConfigFile(flag.CommandLine, *configFile); err != nil {
+			fatal(err)
+		}
+	}
+	if *profile != "" {
+		if err := loadProfiles(flag.CommandLine, profileDir, splitAndTrim(*profile, ",")); err != nil {
+			fatal(err)
+		}
+	}
 	log15.Root().SetHandler(log15.LvlFilterHandler(log15.Lvl(*loglevelFlag), log15.StreamHandler(os.Stderr, log15.TerminalFormat())))
 
-	inv, err := libhive.LoadInventory(".")
+	resultFormats, err := libhive.ParseResultFormats(splitAndTrim(*resultsFormat, ","))
+	if err != nil {
+		fatal(err)
+	}
+	testOrder, err := makeTestOrder(*simOrder, *simOrderSeed, *summaryBaseline, *simTimeLimit)
+	if err != nil {
+		fatal(err)
+	}
+	testShard, err := libhive.ParseTestShard(*simShard)
+	if err != nil {
+		fatal("bad --sim.shard:", err)
+	}
+	if *clientCPUs < 0 {
+		fatal("bad --client.cpus:", *clientCPUs)
+	}
+
+	invCache := libhive.NewInventoryCache(".")
+	inv, err := invCache.Inventory()
 	if err != nil {
 		fatal(err)
 	}
@@ -66,9 +206,27 @@ func main() {
 	}
 
 	// Create the docker backends.
+	endpoint, err := engineEndpoint(flag.CommandLine, *dockerEngine, *dockerEndpoint)
+	if err != nil {
+		fatal(err)
+	}
+	runID := newRunID()
 	dockerConfig := &libdocker.Config{
-		Inventory:   inv,
-		PullEnabled: *dockerPull,
+		Inventory:    inv,
+		PullEnabled:  *dockerPull,
+		CacheDir:     *cacheDir,
+		ForceRebuild: *forceRebuild,
+		ProbeImage:   *dockerProbeImage,
+		ProxyImage:   *dockerProxyImage,
+		NetemImage:   *dockerNetemImage,
+		Engine:       *dockerEngine,
+		Labels:       map[string]string{libhive.LabelRunID: runID},
+
+		BuildLogDir:       buildLogDir(*testResultsRoot, runID),
+		CompressBuildLogs: *resultsCompress,
+	}
+	if dockerConfig.BuildCacheMounts, err = libhive.ParseBuildCacheMounts(*dockerCacheMounts); err != nil {
+		fatal("bad --docker.cachemounts:", err)
 	}
 	if *dockerNoCache != "" {
 		re, err := regexp.Compile(*dockerNoCache)
@@ -81,10 +239,41 @@ func main() {
 		dockerConfig.ContainerOutput = os.Stderr
 		dockerConfig.BuildOutput = os.Stderr
 	}
-	builder, containerBackend, err := libdocker.Connect(*dockerEndpoint, dockerConfig)
+	builder, dockerBackend, err := libdocker.Connect(endpoint, dockerConfig)
 	if err != nil {
 		fatal(err)
 	}
+	var (
+		containerBackend runBackend = dockerBackend
+		hostIP           net.IP
+	)
+	switch *backendName {
+	case "docker":
+	case "k8s":
+		k8sConfig := &libk8s.Config{
+			Kubeconfig: *k8sKubeconfig,
+			Namespace:  *k8sNamespace,
+			Registry:   *k8sRegistry,
+			PushImage:  builder.PushImage,
+			ProxyImage: *dockerProxyImage,
+			Labels:     dockerConfig.Labels,
+		}
+		if *k8sHostIP != "" {
+			if k8sConfig.HostIP = net.ParseIP(*k8sHostIP); k8sConfig.HostIP == nil {
+				fatal("bad --k8s.hostip:", *k8sHostIP)
+			}
+		}
+		k8sBackend, err := libk8s.Connect(k8sConfig)
+		if err != nil {
+			fatal(err)
+		}
+		if hostIP, err = k8sBackend.HostIP(); err != nil {
+			fatal(err)
+		}
+		containerBackend = k8sBackend
+	default:
+		fatal("bad --backend:", *backendName)
+	}
 
 	// Set up the context for CLI interrupts.
 	sig := make(chan os.Signal, 1)
@@ -95,36 +284,135 @@ func main() {
 		cancel()
 	}()
 
+	// Set up telemetry.
+	var telemetry *libhive.OTLPExporter
+	if *otlpEndpoint != "" {
+		telemetry = libhive.NewOTLPExporter(*otlpEndpoint, otlpHeaders, runID, runTags)
+		defer telemetry.Close()
+	}
+	var stream *libhive.ResultStream
+	if *resultsStream != "" || *resultsStreamFile != "" {
+		stream = libhive.NewResultStream(runID)
+	}
+	if *resultsStream != "" {
+		if err := serveResultStream(*resultsStream, stream); err != nil {
+			fatal(err)
+		}
+	}
+	if *resultsStreamFile != "" {
+		f, err := os.OpenFile(*resultsStreamFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fatal(fmt.Errorf("can't open --results-stream.file: %v", err))
+		}
+		defer f.Close()
+		stream.SetEventLog(f)
+	}
+	var webhook *libhive.ResultWebhook
+	if *resultsWebhook != "" {
+		webhook = libhive.NewResultWebhook(*resultsWebhook, resultsWebhookHeaders, runID, runTags)
+		defer webhook.Close()
+	}
+
 	// Run.
 	runner := simRunner{
-		inv:       inv,
-		builder:   builder,
-		container: containerBackend,
+		inv:              inv,
+		builder:          builder,
+		container:        containerBackend,
+		engine:           *dockerEngine,
+		hostIP:           hostIP,
+		buildParallelism: *dockerBuildParallel,
 		env: libhive.SimEnv{
 			LogDir:             *testResultsRoot,
+			RunID:              runID,
+			Tags:               runTags,
 			SimLogLevel:        *simLogLevel,
 			SimParallelism:     *simParallelism,
 			SimTestLimit:       *simTestLimit,
 			ClientStartTimeout: *clientTimeout,
+			ClientStartRetries: *clientStartRetries,
+			ClientResources:    libhive.ContainerResources{CPUs: *clientCPUs, Memory: clientMemory},
+			Inventory:          invCache,
+			Quotas: libhive.Quotas{
+				MaxContainers:      *simMaxContainers,
+				MaxNetworks:        *simMaxNetworks,
+				MaxStartsPerMinute: *simMaxStartRate,
+			},
+			TestOrder:     testOrder,
+			TestShard:     testShard,
+			TestRetries:   *simRetries,
+			TestTimeout:   *simTestTimeout,
+			ResultFormats: resultFormats,
+			CompressLogs:  *resultsCompress,
+			NoStreamLogs:  *resultsNoStreams,
+			HostGuard:     newHostGuard(containerBackend, *backendName, *testResultsRoot, *hostMinDisk, *hostMinMemory, *hostPauseTimeout),
+			Telemetry:     telemetry,
+			ResultStream:  stream,
+			ResultWebhook: webhook,
 		},
 		SimDurationLimit: *simTimeLimit,
+		SimEnv:           hostEnvPassthrough(os.Environ(), splitAndTrim(*simEnvPassthrough, ",")),
+	}
+	runner.env.SimParams = make(map[string]string)
+	for k, v := range simEnv {
+		runner.SimEnv[k] = v
+		if strings.HasPrefix(k, "HIVE_") {
+			runner.env.SimParams[k] = v
+		}
+	}
+	if *simListParams {
+		if len(simList) == 0 {
+			fatal("--sim.listparams requires --sim")
+		}
+		if err := runner.initSimulators(ctx, simList); err != nil {
+			fatal(err)
+		}
+		if err := runner.listSimParams(ctx, os.Stdout, simList); err != nil {
+			fatal(err)
+		}
+		return
 	}
 	clientList := splitAndTrim(*clients, ",")
-	if err := runner.initClients(ctx, clientList); err != nil {
+	imageRefs, noWrap, err := clientImageSources(clientList, clientImages, splitAndTrim(*clientNoWrap, ","))
+	if err != nil {
+		fatal(err)
+	}
+	if err := checkClientDockerfiles(clientList, dockerfiles, noWrap); err != nil {
+		fatal(err)
+	}
+	if err := runner.initClients(ctx, clientList, imageRefs, noWrap, dockerfiles); err != nil {
+		runner.writeBuildSummary(runID, *summaryFD)
 		fatal(err)
 	}
 
+	log15.Info("starting run", "id", runID)
+	var simErr error
 	if *simDevMode {
 		log15.Info("running in simulator development mode")
+		if err := invCache.Watch(); err != nil {
+			log15.Warn("can't watch inventory for changes", "err", err)
+		}
+		defer invCache.Close()
 		runner.runSimulatorAPIDevMode(ctx, *simDevModeAPIEndpoint)
 	} else if len(simList) > 0 {
 		if err := runner.initSimulators(ctx, simList); err != nil {
-			fatal(err)
-		}
-		if err := runner.runSimulations(ctx, simList); err != nil {
-			fatal(err)
+			runner.writeBuildSummary(runID, *summaryFD)
+			simErr = err
+		} else {
+			simErr = runner.runSimulations(ctx, simList)
+			telemetry.Close()
+			webhook.Close()
+			if err := runner.writeSummary(runID, *summaryFD, *summaryBaseline); err != nil {
+				log15.Error("can't write run summary", "err", err)
+			}
 		}
 	}
+	// The stats are written before exiting because fatal doesn't run deferred calls.
+	if err := writeResourceStats(*testResultsRoot, containerBackend.Stats()); err != nil {
+		log15.Error("can't write resource stats", "err", err)
+	}
+	if simErr != nil {
+		fatal(simErr)
+	}
 }
 
 type simRunner struct {
@@ -133,49 +421,133 @@ type simRunner struct {
 	builder   libhive.Builder
 	env       libhive.SimEnv
 
+	// This is the container engine, libdocker.EngineDocker or EnginePodman.
+	engine string
+
+	// This is the address the simulator API listens on.
+	// If nil, the docker bridge IP is used.
+	hostIP net.IP
+
 	// This holds the image names of all built simulators.
 	simImages map[string]string
 
+	// This is the max number of concurrent image builds.
+	buildParallelism int
+
 	// This is the time limit for a single simulation run.
 	SimDurationLimit time.Duration
+
+	// These environment variables are set in the simulator container.
+	SimEnv map[string]string
+
+	// This holds the results of all simulations which have ended.
+	results []*libhive.TestSuite
+
+	// This holds the client and simulator images which failed to build.
+	buildFailures []buildFailure
 }
 
-// initClients builds client images.
-func (r *simRunner) initClients(ctx context.Context, clientList []string) error {
+// initClients builds client images. Clients with an image reference are pulled from
+// their registry, and their Dockerfile is built on top of the pulled image unless
+// noWrap is set for the client.
+func (r *simRunner) initClients(ctx context.Context, clientList []string, images map[string]imageRef, noWrap map[string]bool, dockerfiles map[string]string) error {
 	r.env.Definitions = make(map[string]*libhive.ClientDefinition)
 
 	if len(clientList) == 0 {
 		return errors.New("client list is empty, cannot simulate")
 	}
 
-	var anyBuilt bool
-	log15.Info(fmt.Sprintf("building %d clients...", len(clientList)))
+	var (
+		mu   sync.Mutex
+		jobs []*buildJob
+	)
 	for _, client := range clientList {
-		if !r.inv.HasClient(client) {
-			return fmt.Errorf("unknown client %q", client)
+		if !noWrap[client] && !r.inv.HasClient(client) {
+			return &libhive.Error{
+				Code:    libhive.CodeInventoryMiss,
+				Message: fmt.Sprintf("unknown client %q", client),
+				Context: map[string]string{"client": client},
+				Hint:    "Clients are defined by the directories in clients/. Registry images without a client directory need --client.image and --client.nowrap.",
+			}
 		}
 		meta, err := r.builder.ReadClientMetadata(client)
 		if err != nil {
 			return err
 		}
-		image, err := r.builder.BuildClientImage(ctx, client)
-		if err != nil {
-			continue
-		}
-		anyBuilt = true
-		version, err := r.builder.ReadFile(image, "/version.txt")
+		client := client
+		_, branch := libhive.SplitClientName(client)
+		build, err := meta.BuildInfo(dockerfiles[client], branch)
 		if err != nil {
-			log15.Warn("can't read version info of "+client, "image", image, "err", err)
+			return &libhive.Error{
+				Code:    libhive.CodeInventoryMiss,
+				Message: fmt.Sprintf("client %s: %v", client, err),
+				Context: map[string]string{"client": client, "dockerfile": dockerfiles[client]},
+				Hint:    "The Dockerfiles of a client are declared in the 'dockerfiles' section of its hive.yaml.",
+			}
 		}
-		r.env.Definitions[client] = &libhive.ClientDefinition{
-			Name:    client,
-			Version: strings.TrimSpace(string(version)),
-			Image:   image,
-			Meta:    *meta,
+		ref, fromRegistry := images[client]
+		bases := libdocker.DockerfileBaseImages(r.inv.ClientDirectory(client), build.Dockerfile, build.BuildArgs)
+		if fromRegistry {
+			bases = []string{ref.String()}
 		}
+		jobs = append(jobs, &buildJob{
+			name:  client,
+			bases: bases,
+			build: func(ctx context.Context) error {
+				var image, source string
+				if fromRegistry {
+					digest, err := r.builder.PullImage(ctx, ref.String())
+					if err != nil {
+						return err
+					}
+					if ref.Digest != "" && digest != ref.Digest {
+						return fmt.Errorf("client %s: pulled image %s has digest %s", client, ref, digest)
+					}
+					ref.Digest = digest
+					source = ref.pinned()
+					if noWrap[client] {
+						image = source
+					} else if branch, err := wrapperBranch(r.inv.ClientDirectory(client), build.Dockerfile, ref); err != nil {
+						return fmt.Errorf("client %s: can't use image %s: %v", client, ref, err)
+					} else {
+						build.BuildArgs["branch"] = branch
+					}
+				}
+				if image == "" {
+					var err error
+					if image, err = r.builder.BuildClientImage(ctx, client, build); err != nil {
+						return err
+					}
+				}
+				version, err := r.builder.ReadFile(image, "/version.txt")
+				if err != nil {
+					log15.Warn("can't read version info of "+client, "image", image, "err", err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				r.env.Definitions[client] = &libhive.ClientDefinition{
+					Name:       client,
+					Version:    strings.TrimSpace(string(version)),
+					Image:      image,
+					Source:     source,
+					Dockerfile: dockerfiles[client],
+					Meta:       *meta,
+				}
+				return nil
+			},
+		})
 	}
-	if !anyBuilt {
-		return errors.New("all clients failed to build")
+
+	log15.Info(fmt.Sprintf("building %d clients...", len(clientList)))
+	buildAll(ctx, r.buildParallelism, jobs)
+	r.recordBuildFailures("client", jobs)
+	err := buildFailures("clients", jobs)
+	if len(r.env.Definitions) == 0 {
+		return err
+	}
+	if err != nil {
+		// Simulations can still run against the other clients.
+		log15.Error(err.Error())
 	}
 	return nil
 }
@@ -184,15 +556,32 @@ func (r *simRunner) initClients(ctx context.Context, clientList []string) error
 func (r *simRunner) initSimulators(ctx context.Context, simList []string) error {
 	r.simImages = make(map[string]string)
 
-	log15.Info(fmt.Sprintf("building %d simulators...", len(simList)))
+	var (
+		mu   sync.Mutex
+		jobs []*buildJob
+	)
 	for _, sim := range simList {
-		image, err := r.builder.BuildSimulatorImage(ctx, sim)
-		if err != nil {
-			return err
-		}
-		r.simImages[sim] = image
+		sim := sim
+		jobs = append(jobs, &buildJob{
+			name:  sim,
+			bases: libdocker.DockerfileBaseImages(r.inv.SimulatorDirectory(sim), "", nil),
+			build: func(ctx context.Context) error {
+				image, err := r.builder.BuildSimulatorImage(ctx, sim)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				r.simImages[sim] = image
+				return nil
+			},
+		})
 	}
-	return nil
+
+	log15.Info(fmt.Sprintf("building %d simulators...", len(simList)))
+	buildAll(ctx, r.buildParallelism, jobs)
+	r.recordBuildFailures("simulator", jobs)
+	return buildFailures("simulators", jobs)
 }
 
 func (r *simRunner) runSimulations(ctx context.Context, simList []string) error {
@@ -202,7 +591,14 @@ func (r *simRunner) runSimulations(ctx context.Context, simList []string) error
 		return err
 	}
 
+	if g := r.env.HostGuard; g != nil {
+		g.Start()
+		defer g.Stop()
+	}
 	for _, sim := range simList {
+		if err := r.env.HostGuard.Err(); err != nil {
+			return err
+		}
 		if err := r.run(ctx, sim); err != nil {
 			return err
 		}
@@ -210,6 +606,107 @@ func (r *simRunner) runSimulations(ctx context.Context, simList []string) error
 	return nil
 }
 
+// hostCheckInterval is the interval of host resource checks.
+const hostCheckInterval = 10 * time.Second
+
+// serveResultStream starts the HTTP server of the result stream.
+func serveResultStream(addr string, stream *libhive.ResultStream) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("can't listen for --results-stream: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/results", stream)
+	log15.Info("serving result stream", "url", "ws://"+l.Addr().String()+"/results")
+	go http.Serve(l, mux)
+	return nil
+}
+
+// runBackend is the container backend of a run.
+type runBackend interface {
+	libhive.ContainerBackend
+	Ping() error
+	Stats() libhive.ResourceStats
+}
+
+// newHostGuard creates the guard for host resources. The container backend is always
+// checked. Disk space and memory are checked when a minimum is configured.
+func newHostGuard(backend runBackend, name, resultsDir string, minDiskMB, minMemoryMB int, pauseTimeout time.Duration) *libhive.HostGuard {
+	checks := []libhive.HostCheck{{Name: name, Check: backend.Ping}}
+	if minDiskMB > 0 {
+		checks = append(checks, libhive.DiskCheck(resultsDir, uint64(minDiskMB)<<20))
+	}
+	if minMemoryMB > 0 {
+		checks = append(checks, libhive.MemoryCheck(uint64(minMemoryMB)<<20))
+	}
+	return libhive.NewHostGuard(hostCheckInterval, pauseTimeout, checks...)
+}
+
+// makeTestOrder creates the test order configuration. The history of slowest-first
+// and failed-first is read from the baseline summary. With a time limit, the history
+// is also loaded when available, to estimate the durations of tests when planning.
+func makeTestOrder(strategy string, seed int64, baselineFile string, timeLimit time.Duration) (libhive.TestOrder, error) {
+	order := libhive.TestOrder{Strategy: strategy, Seed: seed}
+	if err := libhive.CheckOrderStrategy(strategy); err != nil {
+		return order, err
+	}
+	if strategy == libhive.OrderRandom && seed == 0 {
+		order.Seed = time.Now().UnixNano()
+	}
+	if order.NeedsHistory() {
+		if baselineFile == "" {
+			return order, fmt.Errorf("--sim.order=%s requires --summary.baseline", strategy)
+		}
+		baseline, err := loadSummary(baselineFile)
+		if err != nil {
+			return order, err
+		}
+		order.History = baseline.testHistory()
+	} else if timeLimit != 0 && baselineFile != "" {
+		baseline, err := loadSummary(baselineFile)
+		if err != nil {
+			return order, err
+		}
+		order.History = baseline.testHistory()
+	}
+	if strategy != libhive.OrderDefault {
+		log15.Info("test order", "strategy", strategy, "seed", order.Seed)
+	}
+	return order, nil
+}
+
+// writeSummary creates the summary of all simulations and writes it out.
+func (r *simRunner) writeSummary(runID string, fd int, baselineFile string) error {
+	dir, err := filepath.Abs(r.env.LogDir)
+	if err != nil {
+		return err
+	}
+	summary := summarizeRun(runID, dir, r.results)
+	summary.Tags = r.env.Tags
+	summary.BuildFailures = r.buildFailures
+	for name, cs := range summary.Clients {
+		if def := r.env.Definitions[name]; def != nil {
+			cs.Source = def.Source
+		}
+	}
+	if baselineFile != "" {
+		baseline, err := loadSummary(baselineFile)
+		if err != nil {
+			return err
+		}
+		summary.compareBaseline(baseline)
+	}
+	return writeSummary(r.env.LogDir, fd, summary)
+}
+
+// writeBuildSummary writes the summary of a run which was aborted because images
+// failed to build. The summary has no test results, but lists the build failures.
+func (r *simRunner) writeBuildSummary(runID string, fd int) {
+	if err := r.writeSummary(runID, fd, ""); err != nil {
+		log15.Error("can't write run summary", "err", err)
+	}
+}
+
 func (r *simRunner) runSimulatorAPIDevMode(ctx context.Context, endpoint string) error {
 	tm := libhive.NewTestManager(r.env, r.container, -1)
 	defer func() {
@@ -248,31 +745,64 @@ func (r *simRunner) runSimulatorAPIDevMode(ctx context.Context, endpoint string)
 func (r *simRunner) run(ctx context.Context, sim string) error {
 	log15.Info(fmt.Sprintf("running simulation: %s", sim))
 
+	// Check the parameters given with --sim.env before starting the simulation.
+	if len(r.env.SimParams) > 0 {
+		if err := r.checkSimParams(ctx, sim); err != nil {
+			return err
+		}
+	}
+
 	// Start the simulation API.
 	tm := libhive.NewTestManager(r.env, r.container, -1)
+	tm.SetSimulatorName(sim)
+	if r.SimDurationLimit != 0 {
+		tm.SetDeadline(time.Now().Add(r.SimDurationLimit))
+	}
 	defer func() {
 		if err := tm.Terminate(); err != nil {
 			log15.Error("could not terminate test manager", "error", err)
 		}
+		for _, suite := range tm.Results() {
+			r.results = append(r.results, suite)
+		}
 	}()
-	addr, server, err := startTestSuiteAPI(tm)
+	addr, server, err := startTestSuiteAPI(tm, r.engine, r.hostIP)
 	if err != nil {
 		log15.Error("failed to start simulator API", "error", err)
 		return err
 	}
 	defer shutdownServer(server)
 
-	// Create the simulator container.
+	// Create the simulator container. User-supplied variables are applied first
+	// so they can't override the variables set by hive.
 	opts := libhive.ContainerOptions{
-		Env: map[string]string{
-			"HIVE_SIMULATOR":   "http://" + addr.String(),
-			"HIVE_PARALLELISM": strconv.Itoa(r.env.SimParallelism),
-			"HIVE_LOGLEVEL":    strconv.Itoa(r.env.SimLogLevel),
-		},
+		Env:    make(map[string]string),
+		Labels: map[string]string{libhive.LabelSimulator: sim},
 	}
+	for k, v := range r.SimEnv {
+		opts.Env[k] = v
+	}
+	opts.Env["HIVE_SIMULATOR"] = "http://" + addr.String()
+	opts.Env["HIVE_PARALLELISM"] = strconv.Itoa(r.env.SimParallelism)
+	opts.Env["HIVE_LOGLEVEL"] = strconv.Itoa(r.env.SimLogLevel)
 	if r.env.SimTestLimit != 0 {
 		opts.Env["HIVE_SIMLIMIT"] = strconv.Itoa(r.env.SimTestLimit)
 	}
+	if r.env.TestOrder.Strategy != libhive.OrderDefault {
+		opts.Env["HIVE_TEST_ORDER"] = r.env.TestOrder.Strategy
+	}
+	if r.env.TestShard.Enabled() {
+		opts.Env["HIVE_TEST_SHARD"] = r.env.TestShard.String()
+	}
+	if r.env.TestRetries > 0 {
+		opts.Env["HIVE_TEST_RETRIES"] = strconv.Itoa(r.env.TestRetries)
+	}
+	if r.env.TestTimeout > 0 {
+		opts.Env["HIVE_TEST_TIMEOUT"] = strconv.Itoa(int(r.env.TestTimeout.Seconds()))
+	}
+	if r.SimDurationLimit != 0 {
+		opts.Env["HIVE_SIM_TIMELIMIT"] = strconv.Itoa(int(r.SimDurationLimit.Seconds()))
+	}
 	containerID, err := r.container.CreateContainer(ctx, r.simImages[sim], opts)
 	if err != nil {
 		return err
@@ -280,6 +810,9 @@ func (r *simRunner) run(ctx context.Context, sim string) error {
 
 	// Set the log file, and notify TestManager about the container.
 	logbasename := fmt.Sprintf("%d-simulator-%s.log", time.Now().Unix(), containerID)
+	if r.env.CompressLogs {
+		logbasename += libhive.CompressedLogExt
+	}
 	opts.LogFile = filepath.Join(r.env.LogDir, logbasename)
 	tm.SetSimContainerInfo(containerID, logbasename)
 
@@ -315,6 +848,10 @@ func (r *simRunner) run(ctx context.Context, sim string) error {
 	case <-done:
 	case <-timeout:
 		slogger.Info("simulation timed out")
+	case <-r.env.HostGuard.Aborted():
+		err := r.env.HostGuard.Err()
+		slogger.Error("aborting simulation", "err", err)
+		return err
 	case <-ctx.Done():
 		slogger.Info("interrupted, shutting down")
 		return errors.New("simulation interrupted")
@@ -323,15 +860,19 @@ func (r *simRunner) run(ctx context.Context, sim string) error {
 }
 
 // startTestSuiteAPI starts an HTTP webserver listening for simulator commands
-// on the docker bridge and executing them until it is torn down.
-func startTestSuiteAPI(tm *libhive.TestManager) (net.Addr, *http.Server, error) {
+// on the given IP, or the docker bridge if it is nil, and executing them until
+// it is torn down.
+func startTestSuiteAPI(tm *libhive.TestManager, engine string, bridge net.IP) (net.Addr, *http.Server, error) {
 	// Find the IP address of the host container
-	bridge, err := libdocker.LookupBridgeIP(log15.Root())
-	if err != nil {
-		log15.Error("failed to lookup bridge IP", "error", err)
-		return nil, nil, err
+	if bridge == nil {
+		var err error
+		bridge, err = libdocker.LookupBridgeIP(log15.Root(), engine)
+		if err != nil {
+			log15.Error("failed to lookup bridge IP", "error", err)
+			return nil, nil, err
+		}
+		log15.Debug("docker bridge IP found", "ip", bridge)
 	}
-	log15.Debug("docker bridge IP found", "ip", bridge)
 
 	// Serve connections until the listener is terminated
 	log15.Debug("starting simulator API server")
@@ -361,8 +902,33 @@ func shutdownServer(server *http.Server) {
 	}
 }
 
+// engineEndpoint returns the API endpoint of the container engine. For podman, the
+// podman socket is used unless the endpoint flag was set.
+func engineEndpoint(fs *flag.FlagSet, engine, endpoint string) (string, error) {
+	switch engine {
+	case libdocker.EngineDocker:
+		return endpoint, nil
+	case libdocker.EnginePodman:
+		set := false
+		fs.Visit(func(f *flag.Flag) { set = set || f.Name == "docker.endpoint" })
+		if !set {
+			endpoint = libdocker.DefaultPodmanEndpoint()
+		}
+		return endpoint, nil
+	default:
+		return "", fmt.Errorf("invalid --docker.engine %q, want docker or podman", engine)
+	}
+}
+
 func fatal(args ...interface{}) {
 	fmt.Fprintln(os.Stderr, args...)
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			if hint := libhive.ErrorHint(err); hint != "" {
+				fmt.Fprintln(os.Stderr, "hint:", hint)
+			}
+		}
+	}
 	os.Exit(1)
 }
 
@@ -373,3 +939,50 @@ func splitAndTrim(input, sep string) []string {
 	}
 	return list
 }
+
+// envFlag is a repeatable flag collecting KEY=VALUE pairs.
+type envFlag map[string]string
+
+func (f envFlag) String() string {
+	list := make([]string, 0, len(f))
+	for k, v := range f {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+func (f *envFlag) Set(value string) error {
+	eq := strings.IndexByte(value, '=')
+	if eq <= 0 {
+		return fmt.Errorf("invalid value %q, want KEY=VALUE", value)
+	}
+	if *f == nil {
+		*f = make(envFlag)
+	}
+	(*f)[value[:eq]] = value[eq+1:]
+	return nil
+}
+
+// hostEnvPassthrough returns the variables of environ which are matched by allowlist.
+// Allowlist entries ending in '*' match all variables with the given prefix.
+func hostEnvPassthrough(environ []string, allowlist []string) map[string]string {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		eq := strings.IndexByte(kv, '=')
+		if eq <= 0 {
+			continue
+		}
+		key := kv[:eq]
+		for _, pattern := range allowlist {
+			if pattern == "" {
+				continue
+			}
+			if pattern == key || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(key, pattern[:len(pattern)-1])) {
+				vars[key] = kv[eq+1:]
+				break
+			}
+		}
+	}
+	return vars
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvFlag(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   envFlag
+		str    string
+		err    bool
+	}{
+		{values: nil, want: nil, str: ""},
+		{values: []string{"A=1"}, want: envFlag{"A": "1"}, str: "A=1"},
+		{values: []string{"B=2", "A=1"}, want: envFlag{"A": "1", "B": "2"}, str: "A=1,B=2"},
+		{values: []string{"A=1", "A=2"}, want: envFlag{"A": "2"}, str: "A=2"},
+		{values: []string{"A="}, want: envFlag{"A": ""}, str: "A="},
+		{values: []string{"A=x=y"}, want: envFlag{"A": "x=y"}, str: "A=x=y"},
+		{values: []string{"A"}, err: true},
+		{values: []string{"=1"}, err: true},
+	}
+	for _, test := range tests {
+		var f envFlag
+		var err error
+		for _, v := range test.values {
+			if err = f.Set(v); err != nil {
+				break
+			}
+		}
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: no error", test.values)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.values, err)
+			continue
+		}
+		if !reflect.DeepEqual(f, test.want) {
+			t.Errorf("%q: got %v, want %v", test.values, f, test.want)
+		}
+		if f.String() != test.str {
+			t.Errorf("%q: wrong string %q, want %q", test.values, f.String(), test.str)
+		}
+	}
+}
+
+func TestHostEnvPassthrough(t *testing.T) {
+	environ := []string{"HOME=/root", "GITHUB_TOKEN=secret", "GITHUB_SHA=abc", "GOPATH=/go", "EMPTY=", "invalid", "=x"}
+	tests := []struct {
+		allowlist []string
+		want      map[string]string
+	}{
+		{nil, map[string]string{}},
+		{[]string{""}, map[string]string{}},
+		{[]string{"HOME"}, map[string]string{"HOME": "/root"}},
+		{[]string{"HOM"}, map[string]string{}},
+		{[]string{"GITHUB_*"}, map[string]string{"GITHUB_TOKEN": "secret", "GITHUB_SHA": "abc"}},
+		{[]string{"G*", "HOME"}, map[string]string{"GITHUB_TOKEN": "secret", "GITHUB_SHA": "abc", "GOPATH": "/go", "HOME": "/root"}},
+		{[]string{"EMPTY", "MISSING"}, map[string]string{"EMPTY": ""}},
+		{[]string{"*"}, map[string]string{"HOME": "/root", "GITHUB_TOKEN": "secret", "GITHUB_SHA": "abc", "GOPATH": "/go", "EMPTY": ""}},
+	}
+	for _, test := range tests {
+		if got := hostEnvPassthrough(environ, test.allowlist); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.allowlist, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package hivesim
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// These are the default ports of client APIs. Clients may configure different
+// ports in the 'ports' section of their hive.yaml.
+const (
+	DefaultRPCPort    = 8545
+	DefaultEnginePort = 8551
+	DefaultBeaconPort = 4000
+)
+
+// ClientPorts lists the API ports of a client.
+type ClientPorts struct {
+	RPC    int `yaml:"rpc" json:"rpc,omitempty"`
+	Engine int `yaml:"engine" json:"engine,omitempty"`
+	Beacon int `yaml:"beacon" json:"beacon,omitempty"`
+}
+
+// withDefaults returns a copy of p where unset ports are set to their defaults.
+func (p ClientPorts) withDefaults() ClientPorts {
+	if p.RPC == 0 {
+		p.RPC = DefaultRPCPort
+	}
+	if p.Engine == 0 {
+		p.Engine = DefaultEnginePort
+	}
+	if p.Beacon == 0 {
+		p.Beacon = DefaultBeaconPort
+	}
+	return p
+}
+
+// Ports returns the API ports of the client.
+func (c *Client) Ports() ClientPorts {
+	return c.test.Sim.clientPorts(c.Type)
+}
+
+// Eth returns a client for the eth namespace of the client's RPC server.
+func (c *Client) Eth() *ethclient.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.eth == nil {
+		c.eth = ethclient.NewClient(c.rpcLocked())
+	}
+	return c.eth
+}
+
+// Admin returns a client for the admin namespace of the client's RPC server.
+func (c *Client) Admin() *AdminClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.admin == nil {
+		c.admin = &AdminClient{c.rpcLocked()}
+	}
+	return c.admin
+}
+
+// Engine returns an RPC client connected to the client's engine API. Requests are
+// authenticated with a JWT token created from the given secret.
+func (c *Client) Engine(jwtSecret []byte) *rpc.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := string(jwtSecret)
+	if c.engine == nil {
+		c.engine = make(map[string]*rpc.Client)
+	}
+	if c.engine[key] == nil {
+		url := fmt.Sprintf("http://%v:%d", c.IP, c.Ports().Engine)
+		httpClient := &http.Client{Transport: &jwtTransport{secret: jwtSecret}}
+		c.engine[key], _ = rpc.DialHTTPWithClient(url, httpClient)
+	}
+	return c.engine[key]
+}
+
+// Beacon returns a client for the client's beacon node API.
+func (c *Client) Beacon() *BeaconClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.beacon == nil {
+		c.beacon = &BeaconClient{URL: fmt.Sprintf("http://%v:%d", c.IP, c.Ports().Beacon)}
+	}
+	return c.beacon
+}
+
+// rpcLocked returns the RPC client. c.mu must be held.
+func (c *Client) rpcLocked() *rpc.Client {
+	if c.rpc == nil {
+		c.rpc, _ = rpc.DialHTTP(fmt.Sprintf("http://%v:%d", c.IP, c.Ports().RPC))
+	}
+	return c.rpc
+}
+
+// AdminClient provides access to the admin RPC namespace.
+type AdminClient struct {
+	rpc *rpc.Client
+}
+
+// NodeInfo is the response of admin_nodeInfo.
+type NodeInfo struct {
+	ID         string                     `json:"id"`
+	Name       string                     `json:"name"`
+	Enode      string                     `json:"enode"`
+	ENR        string                     `json:"enr"`
+	IP         string                     `json:"ip"`
+	ListenAddr string                     `json:"listenAddr"`
+	Protocols  map[string]json.RawMessage `json:"protocols"`
+}
+
+// PeerInfo is an element of the admin_peers response.
+type PeerInfo struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Enode   string   `json:"enode"`
+	ENR     string   `json:"enr,omitempty"`
+	Caps    []string `json:"caps"`
+	Network struct {
+		LocalAddress  string `json:"localAddress"`
+		RemoteAddress string `json:"remoteAddress"`
+		Inbound       bool   `json:"inbound"`
+	} `json:"network"`
+	Protocols map[string]json.RawMessage `json:"protocols"`
+}
+
+// NodeInfo returns information about the node.
+func (a *AdminClient) NodeInfo(ctx context.Context) (*NodeInfo, error) {
+	var info NodeInfo
+	err := a.rpc.CallContext(ctx, &info, "admin_nodeInfo")
+	return &info, err
+}
+
+// Peers returns the connected peers of the node.
+func (a *AdminClient) Peers(ctx context.Context) ([]*PeerInfo, error) {
+	var peers []*PeerInfo
+	err := a.rpc.CallContext(ctx, &peers, "admin_peers")
+	return peers, err
+}
+
+// AddPeer requests a connection to the given node.
+func (a *AdminClient) AddPeer(ctx context.Context, enode string) error {
+	var ok bool
+	return a.rpc.CallContext(ctx, &ok, "admin_addPeer", enode)
+}
+
+// BeaconClient provides access to the beacon node API.
+type BeaconClient struct {
+	URL string
+}
+
+// Get requests the given API path and decodes the JSON response into result.
+func (b *BeaconClient) Get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s: %s", path, resp.Status, body)
+	}
+	return json.Unmarshal(body, result)
+}
+
+// jwtTransport adds a JWT token to HTTP requests, as required by the engine API.
+type jwtTransport struct {
+	secret []byte
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwtToken(t.secret, time.Now()))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// jwtToken creates an HS256 token with the 'iat' claim set to the given time.
+func jwtToken(secret []byte, now time.Time) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims := enc.EncodeToString([]byte(fmt.Sprintf(`{"iat":%d}`, now.Unix())))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + claims))
+	return header + "." + claims + "." + enc.EncodeToString(mac.Sum(nil))
+}
@@ -1,5 +1,10 @@
 package hivesim
 
+import (
+	"fmt"
+	"time"
+)
+
 // SuiteID identifies a test suite context.
 type SuiteID uint32
 
@@ -8,10 +13,81 @@ type TestID uint32
 
 // TestResult describes the outcome of a test.
 type TestResult struct {
-	Pass    bool   `json:"pass"`
-	Details string `json:"details"`
+	Pass     bool            `json:"pass"`
+	Details  string          `json:"details"`
+	Category FailureCategory `json:"category,omitempty"`
+	Skip     SkipReason      `json:"skip,omitempty"`
+}
+
+// FailureCategory classifies the cause of a test failure. Hive uses it to separate
+// genuine client failures from noise caused by the test environment.
+type FailureCategory string
+
+const (
+	FailureAssertion      FailureCategory = "assertion"      // the client did not behave as expected (the default)
+	FailureClientCrash    FailureCategory = "client-crash"   // a client crashed or didn't start
+	FailureHarnessError   FailureCategory = "harness-error"  // bug in the simulator or test harness
+	FailureTimeout        FailureCategory = "timeout"        // the test did not finish in time
+	FailureInfrastructure FailureCategory = "infrastructure" // docker, network or host problem
+)
+
+// SkipReason classifies why a test was skipped.
+type SkipReason string
+
+const (
+	SkipUnsupportedFork    SkipReason = "unsupported-fork"    // the client doesn't support the fork under test
+	SkipMissingFeature     SkipReason = "missing-feature"     // the client lacks a feature needed by the test
+	SkipPrerequisiteFailed SkipReason = "prerequisite-failed" // a test this test depends on has failed
+	SkipOther              SkipReason = "other"
+)
+
+// quotaExceededCode is the API error code of quota errors.
+const quotaExceededCode = "quota-exceeded"
+
+// QuotaError is returned by API calls which exceed a resource quota of the simulator.
+// Quotas are configured on the hive command line. The error wraps the *APIError of
+// the response.
+type QuotaError struct {
+	Quota   string // "containers", "networks" or "startrate"
+	Limit   int
+	Message string
+
+	apiErr *APIError
+}
+
+func (e *QuotaError) Error() string {
+	return e.Message
+}
+
+func (e *QuotaError) Unwrap() error {
+	return e.apiErr
+}
+
+// APIError is returned by API calls which fail with a classified error. The Code
+// identifies the kind of error, e.g. "no-such-node" or "client-start-failed".
+type APIError struct {
+	StatusCode int               `json:"-"`
+	Code       string            `json:"code"`
+	Message    string            `json:"error"`
+	Context    map[string]string `json:"context"` // details, e.g. the client name
+	Hint       string            `json:"hint"`    // how to fix the problem
+	Retry      bool              `json:"retry"`   // the request may succeed if repeated
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("request failed (%d): %s", e.StatusCode, e.Message)
 }
 
+// Is reports whether target is an *APIError with the same code. This allows
+// checking for errors like ErrClientStartFailed using errors.Is.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// ErrClientStartFailed matches the error returned when a client container didn't come up.
+var ErrClientStartFailed = &APIError{Code: "client-start-failed", Message: "client did not start"}
+
 // ExecInfo is the result of running a command in a client container.
 type ExecInfo struct {
 	Stdout   string `json:"stdout"`
@@ -19,6 +95,36 @@ type ExecInfo struct {
 	ExitCode int    `json:"exitCode"`
 }
 
+// ClientLogs is a range of lines of a client log.
+type ClientLogs struct {
+	Lines    []string `json:"lines"`
+	NextLine int      `json:"nextLine"` // line number to pass to the next ClientLogs call
+}
+
+// NetworkProbe is the result of measuring the network between two clients.
+type NetworkProbe struct {
+	BitsPerSecond float64       `json:"bitsPerSecond"` // achieved TCP throughput
+	RTT           time.Duration `json:"rtt"`           // mean TCP round-trip time
+	MinRTT        time.Duration `json:"minRTT"`
+	MaxRTT        time.Duration `json:"maxRTT"`
+}
+
+// NetworkConditions degrade the network of a client, e.g. for testing sync on a bad
+// network. Zero values mean no degradation. The conditions apply to traffic sent by
+// the client on all of its networks.
+type NetworkConditions struct {
+	Latency time.Duration `json:"latency,omitempty"` // added delay of packets
+	Jitter  time.Duration `json:"jitter,omitempty"`  // random variation of the delay, requires Latency
+	Loss    float64       `json:"loss,omitempty"`    // percentage of dropped packets, 0-100
+	Rate    uint64        `json:"rate,omitempty"`    // bandwidth cap in bits per second
+}
+
+// ProbeOptions configures a network probe.
+type ProbeOptions struct {
+	Network  string        // docker network, defaults to "bridge"
+	Duration time.Duration // defaults to 5s, may be at most 60s
+}
+
 // Params contains client launch parameters.
 // This exists because tests usually want to define common parameters as
 // a global variable and then customize them for specific clients.
@@ -68,7 +68,9 @@
 	implements the following interface:
 
 			type AnyTest interface {
-				runTest(*Simulation, SuiteID) error
+				runTest(*Simulation, SuiteID, time.Duration) (bool, error)
+				testNames(*Simulation) ([]string, error)
+				testInfo() testInfo
 			}
 
 
@@ -15,21 +15,31 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Simulation wraps the simulation HTTP API provided by hive.
 type Simulation struct {
 	url string
+
+	portsMu sync.Mutex
+	ports   map[string]ClientPorts // client type -> ports
 }
 
 // New looks up the hive host URI using the HIVE_SIMULATOR environment variable
 // and connects to it. It will panic if HIVE_SIMULATOR is not set.
+//
+// New also sends the parameters declared with RegisterParams to hive. When hive
+// rejects the parameters given to the simulator, the simulator exits.
 func New() *Simulation {
 	simulator, isSet := os.LookupEnv("HIVE_SIMULATOR")
 	if !isSet {
 		panic("HIVE_SIMULATOR environment variable not set")
 	}
-	return &Simulation{url: simulator}
+	sim := &Simulation{url: simulator}
+	sim.declareParams()
+	return sim
 }
 
 // NewAt creates a simulation connected to the given API endpoint. You'll will rarely need
@@ -54,6 +64,20 @@ func (sim *Simulation) EndTest(testSuite SuiteID, test TestID, summaryResult Tes
 	return err
 }
 
+// RetryTest ends a failed attempt of a test case. The test case keeps running, and the
+// test should run again using the same test ID. Hive only allows retries when it is
+// configured to retry failed tests (--sim.retries).
+func (sim *Simulation) RetryTest(testSuite SuiteID, test TestID, attemptResult TestResult) error {
+	resultData, err := json.Marshal(attemptResult)
+	if err != nil {
+		return err
+	}
+	vals := make(url.Values)
+	vals.Add("summaryresult", string(resultData))
+	_, err = wrapHTTPErrorsPost(fmt.Sprintf("%s/testsuite/%d/test/%d/retry", sim.url, testSuite, test), vals)
+	return err
+}
+
 // StartSuite signals the start of a test suite.
 func (sim *Simulation) StartSuite(name, description, simlog string) (SuiteID, error) {
 	vals := make(url.Values)
@@ -83,9 +107,19 @@ func (sim *Simulation) EndSuite(testSuite SuiteID) error {
 
 // StartTest starts a new test case, returning the testcase id as a context identifier.
 func (sim *Simulation) StartTest(testSuite SuiteID, name string, description string) (TestID, error) {
+	return sim.StartTestWithTimeout(testSuite, name, description, 0)
+}
+
+// StartTestWithTimeout starts a new test case like StartTest. Hive fails the test when it
+// is still running after the timeout. If timeout is zero, the test has the default
+// timeout of the simulation, which is set with the --sim.testtimeout flag.
+func (sim *Simulation) StartTestWithTimeout(testSuite SuiteID, name, description string, timeout time.Duration) (TestID, error) {
 	vals := make(url.Values)
 	vals.Add("name", name)
 	vals.Add("description", description)
+	if timeout > 0 {
+		vals.Add("timeout", strconv.FormatFloat(timeout.Seconds(), 'f', -1, 64))
+	}
 
 	idstring, err := wrapHTTPErrorsPost(fmt.Sprintf("%s/testsuite/%d/test", sim.url, testSuite), vals)
 	if err != nil {
@@ -100,14 +134,25 @@ func (sim *Simulation) StartTest(testSuite SuiteID, name string, description str
 
 // ClientMetadata is part of the ClientDefinition and lists metadata
 type ClientMetadata struct {
-	Roles []string `yaml:"roles" json:"roles"`
+	Roles []string    `yaml:"roles" json:"roles"`
+	Ports ClientPorts `yaml:"ports" json:"ports"`
+
+	// Forks are the forks supported by the client. It is empty if the client
+	// doesn't declare its forks.
+	Forks []string `yaml:"forks" json:"forks,omitempty"`
+
+	// Dockerfiles are the names and files of the Dockerfiles supported by the client.
+	Dockerfiles map[string]string `yaml:"dockerfiles" json:"dockerfiles,omitempty"`
+	// BuildArgs are the default build arguments of the client.
+	BuildArgs map[string]string `yaml:"build_args" json:"buildArgs,omitempty"`
 }
 
 // ClientDefinition is served by the /clients API endpoint to list the available clients
 type ClientDefinition struct {
-	Name    string         `json:"name"`
-	Version string         `json:"version"`
-	Meta    ClientMetadata `json:"meta"`
+	Name       string         `json:"name"`
+	Version    string         `json:"version"`
+	Dockerfile string         `json:"dockerfile,omitempty"` // selected with --client.dockerfile
+	Meta       ClientMetadata `json:"meta"`
 }
 
 func (m *ClientDefinition) HasRole(role string) bool {
@@ -119,6 +164,20 @@ func (m *ClientDefinition) HasRole(role string) bool {
 	return false
 }
 
+// SupportsFork reports whether the client supports the given fork. Clients which
+// don't declare their forks are assumed to support all forks.
+func (m *ClientDefinition) SupportsFork(fork string) bool {
+	if len(m.Meta.Forks) == 0 {
+		return true
+	}
+	for _, f := range m.Meta.Forks {
+		if f == fork {
+			return true
+		}
+	}
+	return false
+}
+
 // ClientTypes returns all client types available to this simulator run. This depends on
 // both the available client set and the command line filters.
 func (sim *Simulation) ClientTypes() (availableClients []*ClientDefinition, err error) {
@@ -138,6 +197,28 @@ func (sim *Simulation) ClientTypes() (availableClients []*ClientDefinition, err
 	return
 }
 
+// clientPorts returns the API ports of a client type. The ports are read from the
+// client metadata once. If the metadata can't be read, the default ports are used.
+func (sim *Simulation) clientPorts(clientType string) ClientPorts {
+	sim.portsMu.Lock()
+	defer sim.portsMu.Unlock()
+
+	if sim.ports == nil {
+		defs, err := sim.ClientTypes()
+		if err != nil {
+			return ClientPorts{}.withDefaults()
+		}
+		sim.ports = make(map[string]ClientPorts, len(defs))
+		for _, def := range defs {
+			sim.ports[def.Name] = def.Meta.Ports.withDefaults()
+		}
+	}
+	if p, ok := sim.ports[clientType]; ok {
+		return p
+	}
+	return ClientPorts{}.withDefaults()
+}
+
 // StartClient starts a new node (or other container) with the specified parameters. One
 // parameter must be named CLIENT and should contain one of the client types from
 // GetClientTypes. The input is used as environment variables in the new container.
@@ -195,13 +276,26 @@ func (sim *Simulation) ClientEnodeURL(testSuite SuiteID, test TestID, node strin
 	return res, nil
 }
 
-// ClientExec runs a command in a running client.
+// ClientExec runs a script in a running client. The first element of cmd is the name
+// of a script in the /hive-bin directory of the client container.
 func (sim *Simulation) ClientExec(testSuite SuiteID, test TestID, nodeid string, cmd []string) (*ExecInfo, error) {
-	type execRequest struct {
-		Command []string `json:"command"`
-	}
-	enc, _ := json.Marshal(&execRequest{cmd})
+	return sim.clientExec(testSuite, test, nodeid, &execRequest{Command: cmd})
+}
 
+// ClientExecShell runs a shell script in a running client, using /bin/sh. This can be
+// used to inspect the client container, e.g. to find the ports the client listens on.
+// The client image must contain a shell.
+func (sim *Simulation) ClientExecShell(testSuite SuiteID, test TestID, nodeid string, script string) (*ExecInfo, error) {
+	return sim.clientExec(testSuite, test, nodeid, &execRequest{Shell: script})
+}
+
+type execRequest struct {
+	Command []string `json:"command,omitempty"`
+	Shell   string   `json:"shell,omitempty"`
+}
+
+func (sim *Simulation) clientExec(testSuite SuiteID, test TestID, nodeid string, request *execRequest) (*ExecInfo, error) {
+	enc, _ := json.Marshal(request)
 	p := fmt.Sprintf("%s/testsuite/%d/test/%d/node/%s/exec", sim.url, testSuite, test, nodeid)
 	req, err := http.NewRequest(http.MethodPost, p, bytes.NewReader(enc))
 	if err != nil {
@@ -212,21 +306,266 @@ func (sim *Simulation) ClientExec(testSuite SuiteID, test TestID, nodeid string,
 	if err != nil {
 		return nil, err
 	}
-	if resp.Body == nil {
-		return nil, errors.New("unexpected empty response body")
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, body)
 	}
-	dec := json.NewDecoder(resp.Body)
 	var res ExecInfo
-	if err := dec.Decode(&res); err != nil {
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ClientLogs returns the log output of a client, starting at the given line number
+// (zero-based). The lines written since a previous call can be read by passing the
+// NextLine of its result. A single call returns at most 10000 lines.
+func (sim *Simulation) ClientLogs(testSuite SuiteID, test TestID, nodeid string, sinceLine int) (*ClientLogs, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/testsuite/%d/test/%d/node/%s/logs?since=%d", sim.url, testSuite, test, nodeid, sinceLine))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, body)
+	}
+	var logs ClientLogs
+	if err := json.Unmarshal(body, &logs); err != nil {
+		return nil, err
+	}
+	return &logs, nil
+}
+
+// ProbeNetwork measures throughput and round-trip time of the network between two
+// clients by sending a TCP stream from client 'from' to client 'to'. The measurement
+// is done by helper containers managed by hive and blocks for the probe duration.
+func (sim *Simulation) ProbeNetwork(testSuite SuiteID, test TestID, from, to string, opt ProbeOptions) (*NetworkProbe, error) {
+	type probeRequest struct {
+		From     string `json:"from"`
+		To       string `json:"to"`
+		Network  string `json:"network,omitempty"`
+		Duration int    `json:"duration,omitempty"`
+	}
+	enc, _ := json.Marshal(&probeRequest{
+		From:     from,
+		To:       to,
+		Network:  opt.Network,
+		Duration: int(opt.Duration.Round(time.Second) / time.Second),
+	})
+
+	p := fmt.Sprintf("%s/testsuite/%d/test/%d/netprobe", sim.url, testSuite, test)
+	resp, err := http.Post(p, "application/json", bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, body)
+	}
+	var res NetworkProbe
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// PauseClient suspends all processes of a running client. The client keeps its
+// network connections, but doesn't respond until it is resumed by UnpauseClient.
+func (sim *Simulation) PauseClient(testSuite SuiteID, test TestID, nodeid string) error {
+	_, err := wrapHTTPErrorsPost(fmt.Sprintf("%s/testsuite/%d/test/%d/node/%s/pause", sim.url, testSuite, test, nodeid), nil)
+	return err
+}
+
+// UnpauseClient resumes a client paused by PauseClient.
+func (sim *Simulation) UnpauseClient(testSuite SuiteID, test TestID, nodeid string) error {
+	endpoint := fmt.Sprintf("%s/testsuite/%d/test/%d/node/%s/pause", sim.url, testSuite, test, nodeid)
+	req, err := http.NewRequest(http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return responseError(resp, body)
+	}
+	return nil
+}
+
+// SetNetworkConditions applies latency, jitter, packet loss and a bandwidth cap to the
+// network of a client. The conditions replace those set by a previous call. They are
+// applied with tc/netem by a helper container, which requires the sch_netem kernel
+// module on the docker host.
+func (sim *Simulation) SetNetworkConditions(testSuite SuiteID, test TestID, nodeid string, cond NetworkConditions) error {
+	enc, _ := json.Marshal(&cond)
+	endpoint := fmt.Sprintf("%s/testsuite/%d/test/%d/node/%s/netem", sim.url, testSuite, test, nodeid)
+	resp, err := http.Post(endpoint, "application/json", bytes.NewReader(enc))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return responseError(resp, body)
+	}
+	return nil
+}
+
+// ClearNetworkConditions removes the network conditions of a client.
+func (sim *Simulation) ClearNetworkConditions(testSuite SuiteID, test TestID, nodeid string) error {
+	endpoint := fmt.Sprintf("%s/testsuite/%d/test/%d/node/%s/netem", sim.url, testSuite, test, nodeid)
+	req, err := http.NewRequest(http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return responseError(resp, body)
+	}
+	return nil
+}
+
+// OrderTests asks the hive server for the order in which tests should run. Each element
+// of tests contains the names of one test definition, i.e. all names of a test which
+// runs once for every client. The result contains indexes into tests. When the tests
+// are sharded, the result contains only the tests which should run in this simulation.
+func (sim *Simulation) OrderTests(testSuite SuiteID, tests [][]string) ([]int, error) {
+	type orderRequest struct {
+		Tests [][]string `json:"tests"`
+	}
+	type orderResponse struct {
+		Order []int `json:"order"`
+	}
+	enc, _ := json.Marshal(&orderRequest{tests})
+	resp, err := http.Post(fmt.Sprintf("%s/testsuite/%d/order", sim.url, testSuite), "application/json", bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
 		return nil, err
 	}
-	return &res, err
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, body)
+	}
+	var res orderResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Order) > len(tests) {
+		return nil, fmt.Errorf("invalid test order: got %d indexes for %d tests", len(res.Order), len(tests))
+	}
+	seen := make(map[int]bool, len(res.Order))
+	for _, index := range res.Order {
+		if seen[index] {
+			return nil, fmt.Errorf("invalid test order: duplicate index %d", index)
+		}
+		seen[index] = true
+	}
+	return res.Order, nil
+}
+
+// PlannedTest describes a test definition for PlanTests.
+type PlannedTest struct {
+	Names       []string `json:"names"` // all names of the test, see OrderTests
+	Description string   `json:"description,omitempty"`
+	Duration    float64  `json:"duration,omitempty"` // estimated run time in seconds
+	Requires    []int    `json:"requires,omitempty"` // indexes of the tests which must run first
+}
+
+// TestPlan is the result of PlanTests.
+type TestPlan struct {
+	Order  []int `json:"order"`            // tests to run, in order
+	NotRun []int `json:"notRun,omitempty"` // tests which don't fit into the time limit
+}
+
+// PlanTests asks the hive server which tests should run and in which order. Unlike
+// OrderTests, it also considers the estimated durations of the tests when hive runs
+// with a simulation time limit (--sim.timelimit). Tests which don't fit into the
+// remaining time are recorded as not run by the server and must not be started.
+// Tests always run after the tests they require.
+func (sim *Simulation) PlanTests(testSuite SuiteID, tests []PlannedTest) (*TestPlan, error) {
+	type planRequest struct {
+		Tests []PlannedTest `json:"tests"`
+	}
+	enc, _ := json.Marshal(&planRequest{tests})
+	resp, err := http.Post(fmt.Sprintf("%s/testsuite/%d/plan", sim.url, testSuite), "application/json", bytes.NewReader(enc))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, body)
+	}
+	var plan TestPlan
+	if err := json.Unmarshal(body, &plan); err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool, len(plan.Order))
+	for _, index := range plan.Order {
+		if index < 0 || index >= len(tests) {
+			return nil, fmt.Errorf("invalid test plan: index %d out of range", index)
+		}
+		if seen[index] {
+			return nil, fmt.Errorf("invalid test plan: duplicate index %d", index)
+		}
+		seen[index] = true
+	}
+	return &plan, nil
 }
 
 // CreateNetwork sends a request to the hive server to create a docker network by
 // the given name.
 func (sim *Simulation) CreateNetwork(testSuite SuiteID, networkName string) error {
-	_, err := http.Post(fmt.Sprintf("%s/testsuite/%d/network/%s", sim.url, testSuite, networkName), "application/json", nil)
+	return sim.CreateNetworkWithOptions(testSuite, networkName, NetworkOptions{})
+}
+
+// NetworkOptions configures a docker network. Zero values select the docker defaults.
+type NetworkOptions struct {
+	Subnet  string // subnet in CIDR notation, e.g. "172.30.0.0/24"
+	Gateway string // gateway IP address, requires Subnet
+	MTU     int
+}
+
+// CreateNetworkWithOptions sends a request to the hive server to create a docker
+// network with an explicit subnet, gateway or MTU.
+func (sim *Simulation) CreateNetworkWithOptions(testSuite SuiteID, networkName string, opt NetworkOptions) error {
+	vals := make(url.Values)
+	if opt.Subnet != "" {
+		vals.Add("subnet", opt.Subnet)
+	}
+	if opt.Gateway != "" {
+		vals.Add("gateway", opt.Gateway)
+	}
+	if opt.MTU != 0 {
+		vals.Add("mtu", strconv.Itoa(opt.MTU))
+	}
+	_, err := wrapHTTPErrorsPost(fmt.Sprintf("%s/testsuite/%d/network/%s", sim.url, testSuite, networkName), vals)
 	return err
 }
 
@@ -249,6 +588,39 @@ func (sim *Simulation) ConnectContainer(testSuite SuiteID, network, containerID
 	return err
 }
 
+// ConnectContainerWithIP connects the given container to the given network and assigns
+// it a static IP address. The address must be in the subnet of the network, which
+// must have been created with an explicit subnet (see CreateNetworkWithOptions).
+func (sim *Simulation) ConnectContainerWithIP(testSuite SuiteID, network, containerID, ip string) error {
+	endpoint := fmt.Sprintf("%s/testsuite/%d/network/%s/%s", sim.url, testSuite, network, containerID)
+	_, err := wrapHTTPErrorsPost(endpoint, url.Values{"ip": {ip}})
+	return err
+}
+
+// ContainerNetworks returns the networks the given container is connected to, as a
+// map from network name to the IP address of the container on the network. Only the
+// "bridge" network and networks of the test suite are reported. If the container ID
+// is "simulation", it returns the networks of the simulator container.
+func (sim *Simulation) ContainerNetworks(testSuite SuiteID, containerID string) (map[string]string, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/testsuite/%d/node/%s/networks", sim.url, testSuite, containerID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, body)
+	}
+	var networks map[string]string
+	if err := json.Unmarshal(body, &networks); err != nil {
+		return nil, err
+	}
+	return networks, nil
+}
+
 // DisconnectContainer sends a request to the hive server to disconnect the given
 // container from the given network.
 func (sim *Simulation) DisconnectContainer(testSuite SuiteID, network, containerID string) error {
@@ -336,7 +708,7 @@ func (setup *clientSetup) postWithFiles(url string) (string, error) {
 	if resp.StatusCode >= 200 && resp.StatusCode <= 300 {
 		return string(body), nil
 	}
-	return "", fmt.Errorf("request failed (%d): %v", resp.StatusCode, string(body))
+	return "", responseError(resp, body)
 }
 
 // wrapHttpErrorsPost wraps http.PostForm to convert responses that are not 200 OK into errors
@@ -352,5 +724,20 @@ func wrapHTTPErrorsPost(url string, data url.Values) (string, error) {
 	if resp.StatusCode >= 200 && resp.StatusCode <= 300 {
 		return string(body), nil
 	}
-	return "", fmt.Errorf("request failed (%d): %v", resp.StatusCode, string(body))
+	return "", responseError(resp, body)
+}
+
+// responseError converts an API error response into an error.
+func responseError(resp *http.Response, body []byte) error {
+	if strings.HasPrefix(resp.Header.Get("content-type"), "application/json") {
+		aerr := &APIError{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(body, aerr); err == nil && aerr.Code != "" {
+			if aerr.Code == quotaExceededCode {
+				limit, _ := strconv.Atoi(aerr.Context["limit"])
+				return &QuotaError{Quota: aerr.Context["quota"], Limit: limit, Message: aerr.Message, apiErr: aerr}
+			}
+			return aerr
+		}
+	}
+	return fmt.Errorf("request failed (%d): %v", resp.StatusCode, string(body))
 }
@@ -1,13 +1,20 @@
 package hivesim
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
+	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/hive/internal/fakes"
@@ -34,12 +41,52 @@ func TestClientTypes(t *testing.T) {
 		{
 			Name:    "client-2",
 			Version: "client-2-version",
-			Meta:    ClientMetadata{Roles: []string{"beacon"}},
+			Meta:    ClientMetadata{Roles: []string{"beacon"}, Forks: []string{"phase0", "altair"}},
 		},
 	}
 	if !reflect.DeepEqual(ctypes, wantClients) {
 		t.Fatalf("wrong client types: %s", spew.Sdump(ctypes))
 	}
+	if !ctypes[0].SupportsFork("altair") {
+		t.Error("client without forks doesn't support altair")
+	}
+	if !ctypes[1].SupportsFork("altair") || ctypes[1].SupportsFork("bellatrix") {
+		t.Error("wrong supported forks of client-2")
+	}
+}
+
+// This checks that the client types can be filtered by role and fork.
+func TestClientTypesFilter(t *testing.T) {
+	tm, srv := newFakeAPI(nil)
+	defer srv.Close()
+	defer tm.Terminate()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"client-1", "client-2"}},
+		{"role=beacon", []string{"client-2"}},
+		{"role=validator", []string{}},
+		{"fork=altair", []string{"client-1", "client-2"}},
+		{"fork=bellatrix", []string{"client-1"}},
+		{"role=beacon&fork=bellatrix", []string{}},
+	}
+	for _, test := range tests {
+		resp, err := http.Get(srv.URL + "/clients?" + test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		err = json.NewDecoder(resp.Body).Decode(&names)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("query %q: %v", test.query, err)
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("query %q: wrong clients %v, want %v", test.query, names, test.want)
+		}
+	}
 }
 
 // This checks that the simulator replaces the IP in enode.sh output with the container IP.
@@ -261,6 +308,346 @@ func TestRunProgram(t *testing.T) {
 	if want := 42; res.ExitCode != want {
 		t.Fatalf("wrong code %q\nwant %q", res.ExitCode, want)
 	}
+
+	// Run a shell script.
+	res, err = sim.ClientExecShell(suiteID, testID, clientID, "ss -ltn")
+	if err != nil {
+		t.Fatal("failed to run shell script:", err)
+	}
+	if want := "out: /bin/sh"; res.Stdout != want {
+		t.Fatalf("wrong std out %q\nwant %q", res.Stdout, want)
+	}
+
+	// Errors are reported.
+	if _, err := sim.ClientExec(suiteID, testID, clientID, []string{"../bin/sh"}); err == nil {
+		t.Fatal("no error for script outside of /hive-bin")
+	}
+	if _, err := sim.ClientExec(suiteID, testID, "unknown", []string{"echo"}); err == nil {
+		t.Fatal("no error for unknown client")
+	}
+}
+
+// This checks that the simulator can read client logs.
+func TestClientLogs(t *testing.T) {
+	logdir, err := ioutil.TempDir("", "hive-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(logdir)
+
+	var logFile string
+	hooks := &fakes.BackendHooks{
+		StartContainer: func(containerID string, opt libhive.ContainerOptions) (*libhive.ContainerInfo, error) {
+			logFile = opt.LogFile
+			return &libhive.ContainerInfo{ID: containerID, IP: "192.0.2.1"}, nil
+		},
+	}
+	env := libhive.SimEnv{
+		LogDir:      logdir,
+		Definitions: map[string]*libhive.ClientDefinition{"client-1": {Name: "client-1"}},
+	}
+	tm := libhive.NewTestManager(env, fakes.NewContainerBackend(hooks), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+	testID, err := sim.StartTest(suiteID, "test", "")
+	if err != nil {
+		t.Fatal("can't start test:", err)
+	}
+	params := map[string]string{"CLIENT": "client-1"}
+	clientID, _, err := sim.StartClient(suiteID, testID, params, nil)
+	if err != nil {
+		t.Fatal("can't start client:", err)
+	}
+
+	// Before the client has logged anything, the log is empty.
+	logs, err := sim.ClientLogs(suiteID, testID, clientID, 0)
+	if err != nil {
+		t.Fatal("can't get logs:", err)
+	}
+	if len(logs.Lines) != 0 || logs.NextLine != 0 {
+		t.Fatalf("wrong logs of new client: %+v", logs)
+	}
+
+	// The unfinished last line is not returned.
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "starting\nimported block 1\r\nbad block detected\nunfinish"
+	if err := ioutil.WriteFile(logFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logs, err = sim.ClientLogs(suiteID, testID, clientID, 1)
+	if err != nil {
+		t.Fatal("can't get logs:", err)
+	}
+	want := &ClientLogs{Lines: []string{"imported block 1", "bad block detected"}, NextLine: 3}
+	if !reflect.DeepEqual(logs, want) {
+		t.Fatalf("wrong logs %+v\nwant %+v", logs, want)
+	}
+
+	// Unknown clients are rejected.
+	if _, err := sim.ClientLogs(suiteID, testID, "unknown", 0); err == nil {
+		t.Fatal("no error for unknown client")
+	}
+}
+
+// This checks that the stdout and stderr output of clients is logged separately,
+// in addition to the combined log.
+func TestClientStreamLogs(t *testing.T) {
+	// The backend only writes the stdout log.
+	options, info := runStreamLogClient(t, false, func(opt libhive.ContainerOptions) *libhive.ContainerInfo {
+		return &libhive.ContainerInfo{IP: "192.0.2.1", StdoutLogFile: opt.StdoutLogFile}
+	})
+	dir := filepath.Dir(options.LogFile)
+	if want := filepath.Join(dir, "client-"+info.ID+".log.zst"); options.LogFile != want {
+		t.Errorf("wrong log file %q, want %q", options.LogFile, want)
+	}
+	if want := filepath.Join(dir, "client-"+info.ID+".stdout.log.zst"); options.StdoutLogFile != want {
+		t.Errorf("wrong stdout log file %q, want %q", options.StdoutLogFile, want)
+	}
+	if want := filepath.Join(dir, "client-"+info.ID+".stderr.log.zst"); options.StderrLogFile != want {
+		t.Errorf("wrong stderr log file %q, want %q", options.StderrLogFile, want)
+	}
+	if want := "client-1/client-" + info.ID + ".stdout.log.zst"; info.StdoutLogFile != want {
+		t.Errorf("wrong stdout log in results %q, want %q", info.StdoutLogFile, want)
+	}
+	if info.StderrLogFile != "" {
+		t.Errorf("stderr log not written by backend is in results: %q", info.StderrLogFile)
+	}
+}
+
+// This checks that no stream logs are requested when they are disabled.
+func TestClientNoStreamLogs(t *testing.T) {
+	options, info := runStreamLogClient(t, true, func(opt libhive.ContainerOptions) *libhive.ContainerInfo {
+		return &libhive.ContainerInfo{IP: "192.0.2.1", StdoutLogFile: opt.StdoutLogFile, StderrLogFile: opt.StderrLogFile}
+	})
+	if options.LogFile == "" {
+		t.Error("no combined log file")
+	}
+	if options.StdoutLogFile != "" || options.StderrLogFile != "" {
+		t.Errorf("stream logs requested: %q, %q", options.StdoutLogFile, options.StderrLogFile)
+	}
+	if info.StdoutLogFile != "" || info.StderrLogFile != "" {
+		t.Errorf("stream logs in results: %q, %q", info.StdoutLogFile, info.StderrLogFile)
+	}
+}
+
+// runStreamLogClient runs a test with one client, which is started by the given
+// function. It returns the options of the client and its info in the results.
+func runStreamLogClient(t *testing.T, noStreamLogs bool, start func(libhive.ContainerOptions) *libhive.ContainerInfo) (libhive.ContainerOptions, *libhive.ClientInfo) {
+	var options libhive.ContainerOptions
+	hooks := &fakes.BackendHooks{
+		StartContainer: func(containerID string, opt libhive.ContainerOptions) (*libhive.ContainerInfo, error) {
+			options = opt
+			return start(opt), nil
+		},
+	}
+	env := libhive.SimEnv{
+		LogDir:       t.TempDir(),
+		CompressLogs: true,
+		NoStreamLogs: noStreamLogs,
+		Definitions:  map[string]*libhive.ClientDefinition{"client-1": {Name: "client-1"}},
+	}
+	tm := libhive.NewTestManager(env, fakes.NewContainerBackend(hooks), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+	testID, err := sim.StartTest(suiteID, "test", "")
+	if err != nil {
+		t.Fatal("can't start test:", err)
+	}
+	params := map[string]string{"CLIENT": "client-1"}
+	clientID, _, err := sim.StartClient(suiteID, testID, params, nil)
+	if err != nil {
+		t.Fatal("can't start client:", err)
+	}
+	if err := sim.EndTest(suiteID, testID, TestResult{Pass: true}); err != nil {
+		t.Fatal("can't end test:", err)
+	}
+	if err := sim.EndSuite(suiteID); err != nil {
+		t.Fatal("can't end suite:", err)
+	}
+	return options, tm.Results()[0].TestCases[libhive.TestID(testID)].ClientInfo[clientID]
+}
+
+// This checks that the simulator can pause and unpause a client.
+func TestPauseClient(t *testing.T) {
+	var calls []string
+	hooks := &fakes.BackendHooks{
+		PauseContainer: func(containerID string) error {
+			calls = append(calls, "pause "+containerID)
+			return nil
+		},
+		UnpauseContainer: func(containerID string) error {
+			calls = append(calls, "unpause "+containerID)
+			return nil
+		},
+	}
+	tm, srv := newFakeAPI(hooks)
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+	testID, err := sim.StartTest(suiteID, "test", "")
+	if err != nil {
+		t.Fatal("can't start test:", err)
+	}
+	params := map[string]string{"CLIENT": "client-1"}
+	clientID, _, err := sim.StartClient(suiteID, testID, params, nil)
+	if err != nil {
+		t.Fatal("can't start client:", err)
+	}
+
+	if err := sim.PauseClient(suiteID, testID, clientID); err != nil {
+		t.Fatal("can't pause client:", err)
+	}
+	if err := sim.UnpauseClient(suiteID, testID, clientID); err != nil {
+		t.Fatal("can't unpause client:", err)
+	}
+	want := []string{"pause " + clientID, "unpause " + clientID}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("wrong backend calls %q\nwant %q", calls, want)
+	}
+
+	// Unknown clients are rejected.
+	if err := sim.PauseClient(suiteID, testID, "unknown"); err == nil {
+		t.Fatal("no error for unknown client")
+	}
+}
+
+// This checks that the simulator can set and clear network conditions of a client.
+func TestNetworkConditions(t *testing.T) {
+	var calls []libhive.NetworkConditions
+	hooks := &fakes.BackendHooks{
+		SetNetworkConditions: func(containerID string, cond libhive.NetworkConditions) error {
+			calls = append(calls, cond)
+			return nil
+		},
+	}
+	tm, srv := newFakeAPI(hooks)
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+	testID, err := sim.StartTest(suiteID, "test", "")
+	if err != nil {
+		t.Fatal("can't start test:", err)
+	}
+	params := map[string]string{"CLIENT": "client-1"}
+	clientID, _, err := sim.StartClient(suiteID, testID, params, nil)
+	if err != nil {
+		t.Fatal("can't start client:", err)
+	}
+
+	cond := NetworkConditions{Latency: 100 * time.Millisecond, Jitter: 10 * time.Millisecond, Loss: 1.5, Rate: 1e6}
+	if err := sim.SetNetworkConditions(suiteID, testID, clientID, cond); err != nil {
+		t.Fatal("can't set network conditions:", err)
+	}
+	if err := sim.ClearNetworkConditions(suiteID, testID, clientID); err != nil {
+		t.Fatal("can't clear network conditions:", err)
+	}
+	want := []libhive.NetworkConditions{
+		{Latency: 100 * time.Millisecond, Jitter: 10 * time.Millisecond, Loss: 1.5, Rate: 1e6},
+		{},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("wrong backend calls %+v\nwant %+v", calls, want)
+	}
+
+	// Invalid conditions and unknown clients are rejected.
+	invalid := []NetworkConditions{{Loss: 101}, {Jitter: time.Millisecond}, {Latency: -time.Second}}
+	for _, cond := range invalid {
+		if err := sim.SetNetworkConditions(suiteID, testID, clientID, cond); err == nil {
+			t.Errorf("no error for invalid conditions %+v", cond)
+		}
+	}
+	if err := sim.SetNetworkConditions(suiteID, testID, "unknown", cond); err == nil {
+		t.Fatal("no error for unknown client")
+	}
+	if len(calls) != 2 {
+		t.Fatalf("backend called for rejected requests")
+	}
+}
+
+// This checks that the simulator can measure the network between two clients.
+func TestProbeNetwork(t *testing.T) {
+	var probed []string
+	hooks := &fakes.BackendHooks{
+		NetworkNameToID: func(name string) (string, error) {
+			return name + "-id", nil
+		},
+		ProbeNetwork: func(from, to, toIP string, duration time.Duration) (*libhive.NetworkProbe, error) {
+			probed = append(probed, fmt.Sprintf("%s -> %s (%s) %v", from, to, toIP, duration))
+			return &libhive.NetworkProbe{BitsPerSecond: 8e9, RTT: time.Millisecond}, nil
+		},
+	}
+	tm, srv := newFakeAPI(hooks)
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+	testID, err := sim.StartTest(suiteID, "test", "")
+	if err != nil {
+		t.Fatal("can't start test:", err)
+	}
+	params := map[string]string{"CLIENT": "client-1"}
+	client1, _, err := sim.StartClient(suiteID, testID, params, nil)
+	if err != nil {
+		t.Fatal("can't start client:", err)
+	}
+	client2, _, err := sim.StartClient(suiteID, testID, params, nil)
+	if err != nil {
+		t.Fatal("can't start client:", err)
+	}
+
+	res, err := sim.ProbeNetwork(suiteID, testID, client1, client2, ProbeOptions{Duration: 2 * time.Second})
+	if err != nil {
+		t.Fatal("probe failed:", err)
+	}
+	want := NetworkProbe{BitsPerSecond: 8e9, RTT: time.Millisecond}
+	if *res != want {
+		t.Fatalf("wrong result %+v\nwant %+v", *res, want)
+	}
+	wantCalls := []string{client1 + " -> " + client2 + " (203.0.113.2) 2s"}
+	if !reflect.DeepEqual(probed, wantCalls) {
+		t.Fatalf("wrong backend calls %q\nwant %q", probed, wantCalls)
+	}
+
+	// Invalid requests are rejected.
+	if _, err := sim.ProbeNetwork(suiteID, testID, client1, client1, ProbeOptions{}); err == nil {
+		t.Fatal("no error for probe of the same client")
+	}
+	if _, err := sim.ProbeNetwork(suiteID, testID, client1, "unknown", ProbeOptions{}); err == nil {
+		t.Fatal("no error for unknown client")
+	}
+	if _, err := sim.ProbeNetwork(suiteID, testID, client1, client2, ProbeOptions{Duration: time.Hour}); err == nil {
+		t.Fatal("no error for too long duration")
+	}
 }
 
 // This test checks for some common errors returned by StartClient.
@@ -298,13 +685,27 @@ func TestStartClientErrors(t *testing.T) {
 	if !strings.Contains(err.Error(), "unknown 'CLIENT'") {
 		t.Fatalf("wrong error for GetNode with unknown CLIENT parameter: %q", err.Error())
 	}
+	aerr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("expected *APIError, got %T", err)
+	}
+	if aerr.Code != "unknown-client" || aerr.Context["client"] != "unknown" || aerr.Hint == "" {
+		t.Fatalf("wrong API error %+v", aerr)
+	}
+	if errors.Is(err, ErrClientStartFailed) {
+		t.Fatal("unknown client error matches ErrClientStartFailed")
+	}
+	startErr := &APIError{StatusCode: 500, Code: "client-start-failed", Message: "client did not start: crashed"}
+	if !errors.Is(startErr, ErrClientStartFailed) {
+		t.Fatal("client start error doesn't match ErrClientStartFailed")
+	}
 }
 
 func newFakeAPI(hooks *fakes.BackendHooks) (*libhive.TestManager, *httptest.Server) {
 	env := libhive.SimEnv{
 		Definitions: map[string]*libhive.ClientDefinition{
 			"client-1": {Name: "client-1", Image: "/ignored/in/api", Version: "client-1-version", Meta: libhive.ClientMetadata{Roles: []string{"eth1"}}},
-			"client-2": {Name: "client-2", Image: "/not/exposed/", Version: "client-2-version", Meta: libhive.ClientMetadata{Roles: []string{"beacon"}}},
+			"client-2": {Name: "client-2", Image: "/not/exposed/", Version: "client-2-version", Meta: libhive.ClientMetadata{Roles: []string{"beacon"}, Forks: []string{"phase0", "altair"}}},
 		},
 	}
 	backend := fakes.NewContainerBackend(hooks)
@@ -312,3 +713,290 @@ func newFakeAPI(hooks *fakes.BackendHooks) (*libhive.TestManager, *httptest.Serv
 	srv := httptest.NewServer(tm.API())
 	return tm, srv
 }
+
+// This checks that clients can be started with a specific branch.
+func TestStartClientBranch(t *testing.T) {
+	env := libhive.SimEnv{
+		Definitions: map[string]*libhive.ClientDefinition{
+			"client-1_old": {Name: "client-1_old", Version: "old-version"},
+			"client-1_new": {Name: "client-1_new", Version: "new-version"},
+		},
+	}
+	tm := libhive.NewTestManager(env, fakes.NewContainerBackend(nil), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+	testID, err := sim.StartTest(suiteID, "test", "")
+	if err != nil {
+		t.Fatal("can't start test:", err)
+	}
+	if _, _, err := sim.StartClientWithOptions(suiteID, testID, "client-1_new", WithBranch("old")); err != nil {
+		t.Fatal("can't start client with branch:", err)
+	}
+	_, _, err = sim.StartClientWithOptions(suiteID, testID, "client-1_new", WithBranch("missing"))
+	if err == nil {
+		t.Fatal("wanted error for unknown branch")
+	}
+	if !strings.Contains(err.Error(), "client-1_new, client-1_old") {
+		t.Fatalf("error does not list available branches: %q", err.Error())
+	}
+	if err := sim.EndTest(suiteID, testID, TestResult{Pass: true}); err != nil {
+		t.Fatal("can't end test:", err)
+	}
+	if err := sim.EndSuite(suiteID); err != nil {
+		t.Fatal("can't end suite:", err)
+	}
+	results := tm.Results()
+	if v := results[0].ClientVersions["client-1_old"]; v != "old-version" {
+		t.Fatalf("wrong client version recorded: %q", v)
+	}
+}
+
+// This checks that client resource limits are passed to the backend, and that
+// limits in the client metadata override the defaults.
+func TestStartClientResources(t *testing.T) {
+	created := make(map[string]libhive.ContainerResources)
+	env := libhive.SimEnv{
+		Definitions: map[string]*libhive.ClientDefinition{
+			"client-1": {Name: "client-1", Image: "image-1"},
+			"client-2": {Name: "client-2", Image: "image-2", Meta: libhive.ClientMetadata{
+				Resources: libhive.ContainerResources{Memory: 8 << 30},
+			}},
+		},
+		ClientResources: libhive.ContainerResources{CPUs: 2, Memory: 4 << 30},
+	}
+	backend := fakes.NewContainerBackend(&fakes.BackendHooks{
+		CreateContainer: func(image string, opt libhive.ContainerOptions) (string, error) {
+			created[image] = opt.Resources
+			return image + "-container", nil
+		},
+	})
+	tm := libhive.NewTestManager(env, backend, -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+	testID, err := sim.StartTest(suiteID, "test", "")
+	if err != nil {
+		t.Fatal("can't start test:", err)
+	}
+	for _, client := range []string{"client-1", "client-2"} {
+		if _, _, err := sim.StartClientWithOptions(suiteID, testID, client); err != nil {
+			t.Fatalf("can't start %s: %v", client, err)
+		}
+	}
+	if r, want := created["image-1"], (libhive.ContainerResources{CPUs: 2, Memory: 4 << 30}); r != want {
+		t.Errorf("wrong resources of client-1: %+v, want %+v", r, want)
+	}
+	if r, want := created["image-2"], (libhive.ContainerResources{CPUs: 2, Memory: 8 << 30}); r != want {
+		t.Errorf("wrong resources of client-2: %+v, want %+v", r, want)
+	}
+}
+
+// This checks that quota errors are reported as *QuotaError.
+func TestStartClientQuota(t *testing.T) {
+	env := libhive.SimEnv{
+		Definitions: map[string]*libhive.ClientDefinition{
+			"client-1": {Name: "client-1", Version: "client-1-version"},
+		},
+		Quotas: libhive.Quotas{MaxContainers: 1},
+	}
+	tm := libhive.NewTestManager(env, fakes.NewContainerBackend(nil), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+	testID, err := sim.StartTest(suiteID, "test", "")
+	if err != nil {
+		t.Fatal("can't start test:", err)
+	}
+	clientID, _, err := sim.StartClientWithOptions(suiteID, testID, "client-1")
+	if err != nil {
+		t.Fatal("can't start client:", err)
+	}
+	_, _, err = sim.StartClientWithOptions(suiteID, testID, "client-1")
+	qerr, ok := err.(*QuotaError)
+	if !ok {
+		t.Fatalf("expected *QuotaError, got %v", err)
+	}
+	if qerr.Quota != "containers" || qerr.Limit != 1 {
+		t.Fatalf("wrong quota error: %+v", qerr)
+	}
+	var aerr *APIError
+	if !errors.As(err, &aerr) || aerr.StatusCode != http.StatusTooManyRequests || !aerr.Retry {
+		t.Fatalf("quota error doesn't wrap API error: %+v", aerr)
+	}
+
+	// Stopping the client gives back the quota.
+	if err := sim.StopClient(suiteID, testID, clientID); err != nil {
+		t.Fatal("can't stop client:", err)
+	}
+	if _, _, err := sim.StartClientWithOptions(suiteID, testID, "client-1"); err != nil {
+		t.Fatal("can't start client after stopping the first one:", err)
+	}
+}
+
+// This test checks that network options are passed to the backend, and that
+// network names contain the run ID.
+func TestCreateNetworkOptions(t *testing.T) {
+	var (
+		created     libhive.NetworkOptions
+		createdName string
+	)
+	hooks := &fakes.BackendHooks{
+		CreateNetwork: func(name string, opt libhive.NetworkOptions) (string, error) {
+			created, createdName = opt, name
+			return "00000001", nil
+		},
+	}
+	tm := libhive.NewTestManager(libhive.SimEnv{RunID: "1612356621-a9a2e71a"}, fakes.NewContainerBackend(hooks), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+
+	opt := NetworkOptions{Subnet: "172.30.0.0/24", Gateway: "172.30.0.1", MTU: 1280}
+	if err := sim.CreateNetworkWithOptions(suiteID, "net1", opt); err != nil {
+		t.Fatal("can't create network:", err)
+	}
+	want := libhive.NetworkOptions{Subnet: "172.30.0.0/24", Gateway: "172.30.0.1", MTU: 1280}
+	if created != want {
+		t.Fatalf("wrong network options %+v, want %+v", created, want)
+	}
+	if !strings.HasPrefix(createdName, "hive_1612356621-a9a2e71a_") || !strings.HasSuffix(createdName, "_net1") {
+		t.Fatalf("wrong network name %q", createdName)
+	}
+
+	// Invalid options are rejected.
+	invalid := []NetworkOptions{
+		{Subnet: "172.30.0.0"},
+		{Gateway: "172.30.0.1"},
+		{Subnet: "172.30.0.0/24", Gateway: "10.0.0.1"},
+		{MTU: 100},
+	}
+	for _, opt := range invalid {
+		if err := sim.CreateNetworkWithOptions(suiteID, "net2", opt); err == nil {
+			t.Errorf("no error for invalid options %+v", opt)
+		}
+	}
+}
+
+// This test checks connecting containers with a static IP and listing their networks.
+func TestContainerNetworks(t *testing.T) {
+	var connectedIP net.IP
+	hooks := &fakes.BackendHooks{
+		NetworkNameToID: func(name string) (string, error) {
+			return "bridge-id", nil
+		},
+		ConnectContainer: func(containerID, networkID string, ip net.IP) error {
+			connectedIP = ip
+			return nil
+		},
+		ContainerNetworks: func(containerID string) (map[string]net.IP, error) {
+			return map[string]net.IP{
+				"bridge-id": {172, 17, 0, 2},
+				"00000001":  connectedIP,
+				"other-id":  {10, 0, 0, 1},
+			}, nil
+		},
+	}
+	tm := libhive.NewTestManager(libhive.SimEnv{}, fakes.NewContainerBackend(hooks), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	sim := NewAt(srv.URL)
+	suiteID, err := sim.StartSuite("suite", "", "")
+	if err != nil {
+		t.Fatal("can't start suite:", err)
+	}
+	if err := sim.CreateNetworkWithOptions(suiteID, "net1", NetworkOptions{Subnet: "172.30.0.0/24"}); err != nil {
+		t.Fatal("can't create network:", err)
+	}
+	if err := sim.ConnectContainerWithIP(suiteID, "net1", "container", "172.30.0.10"); err != nil {
+		t.Fatal("can't connect container:", err)
+	}
+	if !connectedIP.Equal(net.IP{172, 30, 0, 10}) {
+		t.Fatalf("wrong IP %v passed to backend", connectedIP)
+	}
+	if err := sim.ConnectContainerWithIP(suiteID, "net1", "container", "172.30.0"); err == nil {
+		t.Fatal("no error for invalid IP")
+	}
+
+	networks, err := sim.ContainerNetworks(suiteID, "container")
+	if err != nil {
+		t.Fatal("can't get networks:", err)
+	}
+	want := map[string]string{"bridge": "172.17.0.2", "net1": "172.30.0.10"}
+	if !reflect.DeepEqual(networks, want) {
+		t.Fatalf("wrong networks %v, want %v", networks, want)
+	}
+}
+
+// This checks that hive validates the parameters given to the simulator against the
+// declared parameters.
+func TestDeclareParams(t *testing.T) {
+	env := libhive.SimEnv{SimParams: map[string]string{"HIVE_TEST_BLOCKS": "many"}}
+	tm := libhive.NewTestManager(env, fakes.NewContainerBackend(nil), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	params := []SimParam{
+		{Name: "HIVE_TEST_BLOCKS", Type: ParamInt, Default: "10", Description: "number of blocks"},
+		{Name: "HIVE_TEST_SNAP", Type: ParamBool},
+	}
+	sim := NewAt(srv.URL)
+	err := sim.DeclareParams(params)
+	if aerr, ok := err.(*APIError); !ok || aerr.Code != "invalid-param" {
+		t.Fatalf("wrong error for invalid parameter value: %v", err)
+	}
+	if err := sim.DeclareParams([]SimParam{{Name: "BLOCKS", Type: ParamInt}}); err == nil {
+		t.Fatal("no error for invalid parameter name")
+	}
+
+	want := []libhive.SimParam{
+		{Name: "HIVE_TEST_BLOCKS", Type: libhive.ParamInt, Default: "10", Description: "number of blocks"},
+		{Name: "HIVE_TEST_SNAP", Type: libhive.ParamBool},
+	}
+	if got := tm.SimParams(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong declared parameters %+v", got)
+	}
+}
+
+func TestParamValues(t *testing.T) {
+	RegisterParams(
+		SimParam{Name: "HIVE_PARAMTEST_COUNT", Type: ParamInt, Default: "3"},
+		SimParam{Name: "HIVE_PARAMTEST_WAIT", Type: ParamDuration, Default: "1s"},
+	)
+	os.Setenv("HIVE_PARAMTEST_WAIT", "2m")
+	defer os.Unsetenv("HIVE_PARAMTEST_WAIT")
+
+	if v := IntParam("HIVE_PARAMTEST_COUNT"); v != 3 {
+		t.Errorf("wrong default value %d", v)
+	}
+	if v := DurationParam("HIVE_PARAMTEST_WAIT"); v != 2*time.Minute {
+		t.Errorf("wrong value %v", v)
+	}
+}
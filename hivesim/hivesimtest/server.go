@@ -0,0 +1,155 @@
+// Package hivesimtest provides a simulation API server for unit tests of simulators.
+//
+// The server implements the simulation API in memory. Clients are not run in containers,
+// instead the test can provide hooks which are called when the simulator starts a client
+// or runs a command in a client. This makes it possible to test simulator logic with
+// plain 'go test':
+//
+//    srv := hivesimtest.NewServer(hivesimtest.Options{
+//        Clients: []*hivesim.ClientDefinition{{Name: "go-ethereum"}},
+//    })
+//    defer srv.Close()
+//    if err := hivesim.RunSuite(srv.Simulation(), suite); err != nil {
+//        t.Fatal(err)
+//    }
+//    for _, result := range srv.Results() {
+//        ...
+//    }
+package hivesimtest
+
+import (
+	"errors"
+	"net"
+	"net/http/httptest"
+	"sort"
+
+	"github.com/ethereum/hive/hivesim"
+	"github.com/ethereum/hive/internal/fakes"
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+// Options configures the server.
+type Options struct {
+	// Clients are the client types available to the simulator.
+	Clients []*hivesim.ClientDefinition
+
+	// StartClient is called when the simulator starts a client. It receives the client
+	// type and the client parameters and returns the IP address of the client. Tests can
+	// serve the client APIs on the returned address, e.g. with an httptest.Server whose
+	// port is configured in the client metadata.
+	//
+	// If StartClient is nil, clients are started with a fake IP address.
+	StartClient func(clientType string, params hivesim.Params) (net.IP, error)
+
+	// Exec is called when the simulator runs a command in a client.
+	// If nil, commands succeed with empty output.
+	Exec func(containerID string, cmd []string) (*hivesim.ExecInfo, error)
+}
+
+// Server is a simulation API server without container backend.
+type Server struct {
+	tm  *libhive.TestManager
+	srv *httptest.Server
+}
+
+// NewServer starts a server.
+func NewServer(opt Options) *Server {
+	env := libhive.SimEnv{Definitions: make(map[string]*libhive.ClientDefinition)}
+	for _, def := range opt.Clients {
+		env.Definitions[def.Name] = &libhive.ClientDefinition{
+			Name:    def.Name,
+			Version: def.Version,
+			Image:   def.Name,
+			Meta: libhive.ClientMetadata{
+				Roles: def.Meta.Roles,
+				Ports: libhive.ClientPorts(def.Meta.Ports),
+				Forks: def.Meta.Forks,
+			},
+		}
+	}
+
+	hooks := &fakes.BackendHooks{
+		RunProgram: func(containerID string, cmd []string) (*libhive.ExecInfo, error) {
+			if opt.Exec == nil {
+				return &libhive.ExecInfo{}, nil
+			}
+			info, err := opt.Exec(containerID, cmd)
+			if err != nil {
+				return nil, err
+			}
+			return &libhive.ExecInfo{Stdout: info.Stdout, Stderr: info.Stderr, ExitCode: info.ExitCode}, nil
+		},
+	}
+	if opt.StartClient != nil {
+		hooks.StartContainer = func(containerID string, copt libhive.ContainerOptions) (*libhive.ContainerInfo, error) {
+			ip, err := opt.StartClient(copt.Labels[libhive.LabelClient], hivesim.Params(copt.Env))
+			if err != nil {
+				return nil, err
+			}
+			if ip == nil {
+				return nil, errors.New("StartClient hook returned nil IP")
+			}
+			return &libhive.ContainerInfo{IP: ip.String()}, nil
+		}
+	}
+
+	tm := libhive.NewTestManager(env, fakes.NewContainerBackend(hooks), -1)
+	return &Server{tm: tm, srv: httptest.NewServer(tm.API())}
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+	s.tm.Terminate()
+}
+
+// URL returns the address of the simulation API.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Simulation returns an API client connected to the server.
+func (s *Server) Simulation() *hivesim.Simulation {
+	return hivesim.NewAt(s.srv.URL)
+}
+
+// TestResult is the result of a test case.
+type TestResult struct {
+	Suite    string // name of the test suite
+	Name     string
+	Pass     bool
+	Details  string
+	Category hivesim.FailureCategory
+}
+
+// Results returns the results of all tests in ended test suites, in the order
+// in which the tests were started.
+func (s *Server) Results() []TestResult {
+	suites := s.tm.Results()
+	suiteIDs := make([]libhive.TestSuiteID, 0, len(suites))
+	for id := range suites {
+		suiteIDs = append(suiteIDs, id)
+	}
+	sort.Slice(suiteIDs, func(i, j int) bool { return suiteIDs[i] < suiteIDs[j] })
+
+	var results []TestResult
+	for _, id := range suiteIDs {
+		suite := suites[id]
+		testIDs := make([]libhive.TestID, 0, len(suite.TestCases))
+		for id := range suite.TestCases {
+			testIDs = append(testIDs, id)
+		}
+		sort.Slice(testIDs, func(i, j int) bool { return testIDs[i] < testIDs[j] })
+		for _, id := range testIDs {
+			test := suite.TestCases[id]
+			results = append(results, TestResult{
+				Suite:    suite.Name,
+				Name:     test.Name,
+				Pass:     test.SummaryResult.Pass,
+				Details:  test.SummaryResult.Details,
+				Category: hivesim.FailureCategory(test.SummaryResult.Category),
+			})
+		}
+	}
+	return results
+}
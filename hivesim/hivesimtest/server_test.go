@@ -0,0 +1,74 @@
+package hivesimtest
+
+import (
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/hive/hivesim"
+)
+
+type ethService struct{}
+
+func (ethService) ChainId() hexutil.Uint64 { return 1337 }
+
+// This test runs a simulator suite against a fake client serving JSON-RPC.
+func TestServer(t *testing.T) {
+	rpcServer := rpc.NewServer()
+	rpcServer.RegisterName("eth", ethService{})
+	fakeClient := httptest.NewServer(rpcServer)
+	defer fakeClient.Close()
+	_, portString, _ := net.SplitHostPort(fakeClient.Listener.Addr().String())
+	port, _ := strconv.Atoi(portString)
+
+	var started []string
+	srv := NewServer(Options{
+		Clients: []*hivesim.ClientDefinition{
+			{Name: "client-1", Meta: hivesim.ClientMetadata{Roles: []string{"eth1"}, Ports: hivesim.ClientPorts{RPC: port}}},
+		},
+		StartClient: func(clientType string, params hivesim.Params) (net.IP, error) {
+			started = append(started, clientType+" "+params["HIVE_NETWORK_ID"])
+			return net.IP{127, 0, 0, 1}, nil
+		},
+	})
+	defer srv.Close()
+
+	suite := hivesim.Suite{Name: "suite"}
+	suite.Add(hivesim.ClientTestSpec{
+		Name:       "chainid",
+		Parameters: hivesim.Params{"HIVE_NETWORK_ID": "1337"},
+		Run: func(t *hivesim.T, c *hivesim.Client) {
+			var id hexutil.Uint64
+			if err := c.RPC().Call(&id, "eth_chainId"); err != nil {
+				t.Fatal("eth_chainId failed:", err)
+			}
+			if id != 1337 {
+				t.Fatal("wrong chain ID", id)
+			}
+		},
+	})
+	suite.Add(hivesim.TestSpec{
+		Name: "failing",
+		Run:  func(t *hivesim.T) { t.Fatal("boom") },
+	})
+	if err := hivesim.RunSuite(srv.Simulation(), suite); err != nil {
+		t.Fatal("suite run failed:", err)
+	}
+
+	if len(started) != 1 || started[0] != "client-1 1337" {
+		t.Fatalf("wrong client starts %q", started)
+	}
+	results := srv.Results()
+	if len(results) != 2 {
+		t.Fatalf("wrong number of results %d", len(results))
+	}
+	if r := results[0]; r.Name != "chainid (client-1)" || !r.Pass {
+		t.Errorf("wrong result %+v", r)
+	}
+	if r := results[1]; r.Name != "failing" || r.Pass || r.Details != "boom\n" {
+		t.Errorf("wrong result %+v", r)
+	}
+}
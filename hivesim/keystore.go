@@ -0,0 +1,64 @@
+package hivesim
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+// WithKeystore adds accounts to the keystore of the client. The keys are placed into the
+// /keys directory of the client container as encrypted keystore files (version 3), and
+// the password is passed in HIVE_KEYSTORE_PASSWORD. If unlock is true, the client is asked
+// to unlock the accounts at startup (HIVE_UNLOCK).
+//
+// This is useful for testing RPC methods which sign with local accounts, e.g.
+// eth_sign and eth_sendTransaction.
+func WithKeystore(password string, unlock bool, keys ...*ecdsa.PrivateKey) StartOption {
+	var (
+		files     = make(map[string][]byte, len(keys))
+		addresses = make([]string, len(keys))
+	)
+	for i, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		data, err := encryptKey(key, password)
+		if err != nil {
+			panic(fmt.Errorf("can't encrypt key of %s: %v", addr, err))
+		}
+		files[fmt.Sprintf("/keys/%x.json", addr)] = data
+		addresses[i] = addr.Hex()
+	}
+
+	opts := []StartOption{Params{"HIVE_KEYSTORE_PASSWORD": password}}
+	if unlock && len(keys) > 0 {
+		opts = append(opts, Params{"HIVE_UNLOCK": strings.Join(addresses, ",")})
+	}
+	for path, data := range files {
+		data := data
+		opts = append(opts, WithDynamicFile(path, func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}))
+	}
+	return Bundle(opts...)
+}
+
+// encryptKey creates a keystore file for the given key. It uses light scrypt
+// parameters, to keep client startup fast.
+func encryptKey(key *ecdsa.PrivateKey, password string) ([]byte, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	k := &keystore.Key{
+		Id:         id,
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}
+	return keystore.EncryptKey(k, password, keystore.LightScryptN, keystore.LightScryptP)
+}
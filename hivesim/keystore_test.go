@@ -0,0 +1,39 @@
+package hivesim
+
+import (
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestWithKeystore(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	setup := &clientSetup{parameters: make(map[string]string), files: make(map[string]func() (io.ReadCloser, error))}
+	WithKeystore("pw", true, key).Apply(setup)
+
+	if setup.parameters["HIVE_KEYSTORE_PASSWORD"] != "pw" {
+		t.Errorf("wrong password parameter %q", setup.parameters["HIVE_KEYSTORE_PASSWORD"])
+	}
+	if setup.parameters["HIVE_UNLOCK"] != addr.Hex() {
+		t.Errorf("wrong unlock parameter %q", setup.parameters["HIVE_UNLOCK"])
+	}
+	src := setup.files["/keys/"+hex.EncodeToString(addr[:])+".json"]
+	if src == nil {
+		t.Fatalf("keystore file missing, have %v", setup.files)
+	}
+	r, _ := src()
+	data, _ := ioutil.ReadAll(r)
+
+	decrypted, err := keystore.DecryptKey(data, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.Address != addr || decrypted.PrivateKey.D.Cmp(key.D) != 0 {
+		t.Fatal("decrypted key has wrong address")
+	}
+}
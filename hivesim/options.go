@@ -47,6 +47,16 @@ func WithDynamicFile(dstPath string, src func() (io.ReadCloser, error)) StartOpt
 	})
 }
 
+// WithBranch selects a branch of the client. The client must have been built with
+// this branch, i.e. it must be listed as "client_branch" on the hive command line.
+// This is useful for simulators which need to run different versions of the same
+// client against each other.
+func WithBranch(branch string) StartOption {
+	return optionFunc(func(setup *clientSetup) {
+		setup.parameters["BRANCH"] = branch
+	})
+}
+
 // Bundle combines start options, e.g. to bundle files together as option.
 func Bundle(option ...StartOption) StartOption {
 	return optionFunc(func(setup *clientSetup) {
@@ -0,0 +1,143 @@
+package hivesim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// These are the types of simulator parameters.
+const (
+	ParamString   = "string"
+	ParamInt      = "int"
+	ParamBool     = "bool"
+	ParamDuration = "duration"
+)
+
+// SimParam declares a parameter of the simulator. Parameters are environment variables
+// of the simulator container, which are set with hive --sim.env NAME=VALUE. The name
+// must start with HIVE_. Durations are given like "10s" or "1m30s".
+type SimParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+var (
+	simParamsMu sync.Mutex
+	simParams   = make(map[string]SimParam)
+	paramOrder  []string
+)
+
+// RegisterParams declares parameters of the simulator. It must be called before New,
+// usually from an init function of the simulator.
+//
+// New sends the declared parameters to hive, which fails the simulation when it was
+// given an undeclared HIVE_ variable or a value which isn't valid for the parameter
+// type. Hive lists the parameters of simulators with --sim.listparams. Simulators which
+// don't declare any parameters aren't checked.
+func RegisterParams(params ...SimParam) {
+	simParamsMu.Lock()
+	defer simParamsMu.Unlock()
+	for _, p := range params {
+		if _, ok := simParams[p.Name]; ok {
+			panic(fmt.Sprintf("duplicate simulator parameter %s", p.Name))
+		}
+		simParams[p.Name] = p
+		paramOrder = append(paramOrder, p.Name)
+	}
+}
+
+// StringParam returns the value of a declared parameter, or its default if it isn't
+// set. It panics if the parameter isn't declared.
+func StringParam(name string) string {
+	simParamsMu.Lock()
+	p, ok := simParams[name]
+	simParamsMu.Unlock()
+	if !ok {
+		panic(fmt.Sprintf("simulator parameter %s is not declared", name))
+	}
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return p.Default
+}
+
+// IntParam returns the value of a declared parameter of type ParamInt.
+func IntParam(name string) int {
+	v, err := strconv.Atoi(StringParam(name))
+	if err != nil {
+		panic(fmt.Sprintf("simulator parameter %s: %v", name, err))
+	}
+	return v
+}
+
+// BoolParam returns the value of a declared parameter of type ParamBool.
+func BoolParam(name string) bool {
+	v, err := strconv.ParseBool(StringParam(name))
+	if err != nil {
+		panic(fmt.Sprintf("simulator parameter %s: %v", name, err))
+	}
+	return v
+}
+
+// DurationParam returns the value of a declared parameter of type ParamDuration.
+func DurationParam(name string) time.Duration {
+	v, err := time.ParseDuration(StringParam(name))
+	if err != nil {
+		panic(fmt.Sprintf("simulator parameter %s: %v", name, err))
+	}
+	return v
+}
+
+// declareParams sends the declared parameters to hive. When hive only reads the
+// parameters of the simulator, which it signals by setting HIVE_PARAMS_ONLY, the
+// simulator exits afterwards.
+func (sim *Simulation) declareParams() {
+	paramsOnly := os.Getenv("HIVE_PARAMS_ONLY") != ""
+	simParamsMu.Lock()
+	params := make([]SimParam, len(paramOrder))
+	for i, name := range paramOrder {
+		params[i] = simParams[name]
+	}
+	simParamsMu.Unlock()
+
+	if len(params) > 0 || paramsOnly {
+		if err := sim.DeclareParams(params); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid simulator parameters:", err)
+			os.Exit(1)
+		}
+	}
+	if paramsOnly {
+		os.Exit(0)
+	}
+}
+
+// DeclareParams sends parameter declarations to hive. Hive checks the parameters given
+// to the simulator against them. Simulators should use RegisterParams instead.
+func (sim *Simulation) DeclareParams(params []SimParam) error {
+	if params == nil {
+		params = []SimParam{}
+	}
+	enc, _ := json.Marshal(params)
+	resp, err := http.Post(fmt.Sprintf("%s/simulator/params", sim.url), "application/json", bytes.NewReader(enc))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, body)
+	}
+	return nil
+}
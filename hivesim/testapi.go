@@ -1,20 +1,29 @@
 package hivesim
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // Suite is the description of a test suite.
+//
+// TestTimeout is the default timeout of the suite's tests, including subtests. Tests
+// which set their own Timeout override it. If both are zero, tests have the default
+// timeout of the simulation, which is set with the --sim.testtimeout flag.
 type Suite struct {
 	Name        string
 	Description string
+	TestTimeout time.Duration
 	Tests       []AnyTest
 }
 
@@ -26,10 +35,27 @@ func (s *Suite) Add(test AnyTest) *Suite {
 
 // AnyTest is either Test or SingleClientTest.
 type AnyTest interface {
-	runTest(*Simulation, SuiteID) error
+	runTest(*Simulation, SuiteID, time.Duration) (bool, error)
+	testNames(*Simulation) ([]string, error)
+	testInfo() testInfo
+}
+
+// testInfo is the information about a test used for planning the suite.
+type testInfo struct {
+	name        string
+	description string
+	duration    time.Duration
+	requires    []string
 }
 
-// RunSuite runs all tests in a suite.
+// RunSuite runs all tests in a suite. When hive is configured with a test order
+// (--sim.order), the tests run in the order returned by the hive server. When the
+// tests are sharded (--sim.shard), only the tests of the shard run. When hive runs
+// with a time limit (--sim.timelimit), tests which don't fit into the remaining time
+// are left out, based on their estimated Duration.
+//
+// Tests run after the tests they require. If a required test fails or is skipped,
+// the tests requiring it are skipped.
 func RunSuite(host *Simulation, suite Suite) error {
 	logfile := os.Getenv("HIVE_SIMLOG") // TODO: remove this
 	suiteID, err := host.StartSuite(suite.Name, suite.Description, logfile)
@@ -38,11 +64,108 @@ func RunSuite(host *Simulation, suite Suite) error {
 	}
 	defer host.EndSuite(suiteID)
 
-	for _, test := range suite.Tests {
-		if err := test.runTest(host, suiteID); err != nil {
+	tests := suite.Tests
+	if needsPlan(tests) {
+		if tests, err = planTests(host, suiteID, tests); err != nil {
 			return err
 		}
 	}
+	passed := make(map[string]bool, len(tests))
+	for _, test := range tests {
+		info := test.testInfo()
+		if req := missingRequirement(info, passed); req != "" {
+			details := fmt.Sprintf("required test %q did not pass", req)
+			if err := skipTest(host, suiteID, test, SkipPrerequisiteFailed, details); err != nil {
+				return err
+			}
+			passed[info.name] = false
+			continue
+		}
+		ok, err := test.runTest(host, suiteID, suite.TestTimeout)
+		if err != nil {
+			return err
+		}
+		if prev, seen := passed[info.name]; !seen || prev {
+			passed[info.name] = ok
+		}
+	}
+	return nil
+}
+
+// needsPlan reports whether the tests must be planned by the hive server.
+func needsPlan(tests []AnyTest) bool {
+	if os.Getenv("HIVE_TEST_ORDER") != "" || os.Getenv("HIVE_TEST_SHARD") != "" || os.Getenv("HIVE_SIM_TIMELIMIT") != "" {
+		return true
+	}
+	for _, test := range tests {
+		if len(test.testInfo().requires) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// planTests returns the tests in the order planned by the hive server. Tests which
+// are not in the plan are left out.
+func planTests(host *Simulation, suite SuiteID, tests []AnyTest) ([]AnyTest, error) {
+	index := make(map[string]int, len(tests))
+	for i, test := range tests {
+		if name := test.testInfo().name; name != "" {
+			if _, ok := index[name]; !ok {
+				index[name] = i
+			}
+		}
+	}
+	planned := make([]PlannedTest, len(tests))
+	for i, test := range tests {
+		names, err := test.testNames(host)
+		if err != nil {
+			return nil, err
+		}
+		info := test.testInfo()
+		planned[i] = PlannedTest{Names: names, Description: info.description, Duration: info.duration.Seconds()}
+		for _, req := range info.requires {
+			j, ok := index[req]
+			if !ok {
+				return nil, fmt.Errorf("test %q requires unknown test %q", info.name, req)
+			}
+			planned[i].Requires = append(planned[i].Requires, j)
+		}
+	}
+	plan, err := host.PlanTests(suite, planned)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]AnyTest, len(plan.Order))
+	for i, index := range plan.Order {
+		sorted[i] = tests[index]
+	}
+	return sorted, nil
+}
+
+// missingRequirement returns the first test required by a test which didn't pass.
+func missingRequirement(info testInfo, passed map[string]bool) string {
+	for _, req := range info.requires {
+		if !passed[req] {
+			return req
+		}
+	}
+	return ""
+}
+
+// skipTest reports all runs of a test as skipped without running it.
+func skipTest(host *Simulation, suite SuiteID, test AnyTest, reason SkipReason, details string) error {
+	names, err := test.testNames(host)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		testID, err := host.StartTest(suite, name, test.testInfo().description)
+		if err != nil {
+			return err
+		}
+		host.EndTest(suite, testID, TestResult{Pass: true, Skip: reason, Details: details})
+	}
 	return nil
 }
 
@@ -67,9 +190,22 @@ func MustRunSuite(host *Simulation, suite Suite) {
 //
 //    t.RunClientTest(hivesim.ClientTestSpec{...})
 //
+// If Matrix is set, the test runs once for every combination of the matrix parameters.
+// Use t.Param to get the parameter values of the running combination.
+//
+// Duration is the estimated run time of the test. Hive uses it to plan which tests fit
+// into the simulation time limit. Requires contains the names of tests of the suite
+// which must pass before this test runs.
+//
+// Timeout is the max run time of the test. A test running longer fails with the
+// 'timeout' failure category. See Suite for the default timeout.
 type TestSpec struct {
 	Name        string
 	Description string
+	Matrix      Matrix
+	Duration    time.Duration
+	Timeout     time.Duration
+	Requires    []string
 	Run         func(*T)
 }
 
@@ -80,28 +216,95 @@ type TestSpec struct {
 // with the specified Role. If no Role is specified, the test runs with all available clients.
 //
 // If the Name of the test includes "CLIENT", it is replaced by the client name being tested.
+//
+// If Matrix is set, the test runs once per client for every combination of the matrix
+// parameters. The parameters of the combination are added to the client Parameters.
+//
+// Duration, Timeout and Requires are used like in TestSpec. Duration is the estimated run
+// time against all clients, while Timeout applies to the test of each client.
 type ClientTestSpec struct {
 	Name        string
 	Role        string
 	Description string
 	Parameters  Params
 	Files       map[string]string
+	Matrix      Matrix
+	Duration    time.Duration
+	Timeout     time.Duration
+	Requires    []string
 	Run         func(*T, *Client)
 }
 
+// Matrix declares the parameter axes of a test. A test with a matrix runs once for every
+// combination of the axis values, and the values are appended to the test name, e.g.
+//
+//    sync (go-ethereum) [HIVE_NODETYPE=full, TXPOOL_SIZE=1024]
+//
+// This makes it easy to see which combination failed.
+type Matrix []Axis
+
+// Axis is a parameter with the values it takes in a Matrix.
+type Axis struct {
+	Param  string
+	Values []string
+}
+
+// combinations returns all parameter combinations of the matrix. The values of the last
+// axis vary fastest. Axes without values are ignored. An empty matrix has a single, empty
+// combination.
+func (m Matrix) combinations() []Params {
+	combs := []Params{{}}
+	for _, axis := range m {
+		if len(axis.Values) == 0 {
+			continue
+		}
+		next := make([]Params, 0, len(combs)*len(axis.Values))
+		for _, c := range combs {
+			for _, v := range axis.Values {
+				p := c.Copy()
+				p[axis.Param] = v
+				next = append(next, p)
+			}
+		}
+		combs = next
+	}
+	return combs
+}
+
+// testName appends the parameters of a combination to the test name.
+func (m Matrix) testName(name string, comb Params) string {
+	var values []string
+	for _, axis := range m {
+		if v, ok := comb[axis.Param]; ok {
+			values = append(values, axis.Param+"="+v)
+		}
+	}
+	if len(values) == 0 {
+		return name
+	}
+	return name + " [" + strings.Join(values, ", ") + "]"
+}
+
 // Client represents a running client.
 type Client struct {
 	Type      string
 	Container string
 	IP        net.IP
 
-	mu   sync.Mutex
-	rpc  *rpc.Client
-	test *T
+	mu     sync.Mutex
+	rpc    *rpc.Client
+	eth    *ethclient.Client
+	admin  *AdminClient
+	engine map[string]*rpc.Client // keyed by JWT secret
+	beacon *BeaconClient
+	test   *T
 }
 
 // EnodeURL returns the peer-to-peer endpoint of the client.
 func (c *Client) EnodeURL() (string, error) {
+	if err := c.test.checkActive(); err != nil {
+		return "", err
+	}
 	return c.test.Sim.ClientEnodeURL(c.test.SuiteID, c.test.TestID, c.Container)
 }
 
@@ -109,17 +312,75 @@ func (c *Client) EnodeURL() (string, error) {
 func (c *Client) RPC() *rpc.Client {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.rpc == nil {
-		c.rpc, _ = rpc.DialHTTP(fmt.Sprintf("http://%v:8545", c.IP))
-	}
-	return c.rpc
+	return c.rpcLocked()
 }
 
-// Exec runs a script in the client container.
+// Exec runs a script in the client container. See Simulation.ClientExec.
 func (c *Client) Exec(command ...string) (*ExecInfo, error) {
+	if err := c.test.checkActive(); err != nil {
+		return nil, err
+	}
 	return c.test.Sim.ClientExec(c.test.SuiteID, c.test.TestID, c.Container, command)
 }
 
+// ExecShell runs a shell script in the client container. See Simulation.ClientExecShell.
+func (c *Client) ExecShell(script string) (*ExecInfo, error) {
+	if err := c.test.checkActive(); err != nil {
+		return nil, err
+	}
+	return c.test.Sim.ClientExecShell(c.test.SuiteID, c.test.TestID, c.Container, script)
+}
+
+// Logs returns the log output of the client. See Simulation.ClientLogs.
+func (c *Client) Logs(sinceLine int) (*ClientLogs, error) {
+	if err := c.test.checkActive(); err != nil {
+		return nil, err
+	}
+	return c.test.Sim.ClientLogs(c.test.SuiteID, c.test.TestID, c.Container, sinceLine)
+}
+
+// ProbeNetwork measures the network from this client to another client of the
+// test. See Simulation.ProbeNetwork.
+func (c *Client) ProbeNetwork(to *Client, opt ProbeOptions) (*NetworkProbe, error) {
+	if err := c.test.checkActive(); err != nil {
+		return nil, err
+	}
+	return c.test.Sim.ProbeNetwork(c.test.SuiteID, c.test.TestID, c.Container, to.Container, opt)
+}
+
+// SetNetworkConditions degrades the network of the client.
+// See Simulation.SetNetworkConditions.
+func (c *Client) SetNetworkConditions(cond NetworkConditions) error {
+	if err := c.test.checkActive(); err != nil {
+		return err
+	}
+	return c.test.Sim.SetNetworkConditions(c.test.SuiteID, c.test.TestID, c.Container, cond)
+}
+
+// ClearNetworkConditions restores the network of the client.
+func (c *Client) ClearNetworkConditions() error {
+	if err := c.test.checkActive(); err != nil {
+		return err
+	}
+	return c.test.Sim.ClearNetworkConditions(c.test.SuiteID, c.test.TestID, c.Container)
+}
+
+// Pause suspends the client container. See Simulation.PauseClient.
+func (c *Client) Pause() error {
+	if err := c.test.checkActive(); err != nil {
+		return err
+	}
+	return c.test.Sim.PauseClient(c.test.SuiteID, c.test.TestID, c.Container)
+}
+
+// Unpause resumes the client container after Pause.
+func (c *Client) Unpause() error {
+	if err := c.test.checkActive(); err != nil {
+		return err
+	}
+	return c.test.Sim.UnpauseClient(c.test.SuiteID, c.test.TestID, c.Container)
+}
+
 // T is a running test. This is a lot like testing.T, but has some additional methods for
 // launching clients.
 //
@@ -131,37 +392,115 @@ type T struct {
 	SuiteID SuiteID
 	mu      sync.Mutex
 	result  TestResult
+	params  Params // matrix parameters
+
+	subtests     bool          // set when the test starts subtests
+	suiteTimeout time.Duration // default timeout of subtests
+	timeout      time.Duration // zero if the test has no timeout
+	deadline     time.Time
+	timedOut     bool // set when the test exceeded its timeout
+	ended        bool // set when the result has been taken, the T is inert afterwards
+}
+
+// errTestEnded is returned by API calls of tests which have already ended, e.g. from
+// the goroutine of a test which exceeded its timeout.
+var errTestEnded = errors.New("test has already ended")
+
+// checkActive returns errTestEnded if the test has ended.
+func (t *T) checkActive() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ended {
+		return errTestEnded
+	}
+	return nil
+}
+
+// Deadline returns the time at which the test times out. The second return value is
+// false if the test has no timeout. Like FailNow, a test which exceeds its deadline is
+// failed immediately, but since the test function can't be stopped from the outside,
+// long-running tests should check the deadline to end early. Once the test has timed
+// out, starting clients and other API calls of the test fail.
+func (t *T) Deadline() (time.Time, bool) {
+	return t.deadline, !t.deadline.IsZero()
+}
+
+// Param returns the value of a matrix parameter in the running test combination.
+// It returns the empty string if the test has no such parameter.
+func (t *T) Param(name string) string {
+	return t.params[name]
 }
 
 // StartClient starts a client instance. If the client cannot by started, the test fails immediately.
 func (t *T) StartClient(clientType string, option ...StartOption) *Client {
-	container, ip, err := t.Sim.StartClientWithOptions(t.SuiteID, t.TestID, clientType, option...)
+	client, err := t.TryStartClient(clientType, option...)
 	if err != nil {
+		if errors.Is(err, ErrClientStartFailed) {
+			t.SetFailureCategory(FailureClientCrash)
+		} else {
+			t.SetFailureCategory(FailureInfrastructure)
+		}
 		t.Fatalf("can't launch node (type %s): %v", clientType, err)
 	}
-	return &Client{Type: clientType, Container: container, IP: ip, test: t}
+	return client
+}
+
+// TryStartClient starts a client instance. Unlike StartClient, it returns the error
+// instead of failing the test, so it can be used outside of the main test goroutine.
+func (t *T) TryStartClient(clientType string, option ...StartOption) (*Client, error) {
+	if err := t.checkActive(); err != nil {
+		return nil, err
+	}
+	container, ip, err := t.Sim.StartClientWithOptions(t.SuiteID, t.TestID, clientType, option...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Type: clientType, Container: container, IP: ip, test: t}, nil
 }
 
 // RunClient runs the given client test against a single client type.
 // It waits for the subtest to complete.
 func (t *T) RunClient(clientType string, spec ClientTestSpec) {
-	runTest(t.Sim, t.SuiteID, spec.Name, spec.Description, func(t *T) {
-		client := t.StartClient(clientType, spec.Parameters, WithStaticFiles(spec.Files))
-		spec.Run(t, client)
-	})
+	if !t.startedSubtests() {
+		return
+	}
+	for _, comb := range spec.Matrix.combinations() {
+		comb := comb
+		timeout := testTimeout(spec.Timeout, t.suiteTimeout)
+		runTest(t.Sim, t.SuiteID, spec.Matrix.testName(spec.Name, comb), spec.Description, timeout, t.suiteTimeout, func(t *T) {
+			t.params = comb
+			client := t.StartClient(clientType, spec.Parameters, comb, WithStaticFiles(spec.Files))
+			spec.Run(t, client)
+		})
+	}
 }
 
 // RunAllClients runs the given client test against all available client types.
 // It waits for all subtests to complete.
 func (t *T) RunAllClients(spec ClientTestSpec) {
-	spec.runTest(t.Sim, t.SuiteID)
+	if !t.startedSubtests() {
+		return
+	}
+	spec.runTest(t.Sim, t.SuiteID, t.suiteTimeout)
 }
 
 // Run runs a subtest of this test. It waits for the subtest to complete before continuing.
 // It is safe to call this from multiple goroutines concurrently, just be sure to wait for
 // all your tests to finish until returning from the parent test.
 func (t *T) Run(spec TestSpec) {
-	runTest(t.Sim, t.SuiteID, spec.Name, spec.Description, spec.Run)
+	if !t.startedSubtests() {
+		return
+	}
+	spec.runTest(t.Sim, t.SuiteID, t.suiteTimeout)
+}
+
+// startedSubtests records that the test starts subtests. It returns false if the
+// test has ended, subtests can't be started then.
+func (t *T) startedSubtests() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.subtests = true
+	return !t.ended
 }
 
 // Error is like testing.T.Error.
@@ -196,7 +535,9 @@ func (t *T) Logf(format string, values ...interface{}) {
 		format = format + "\n"
 	}
 	fmt.Printf(format, values...)
-	t.result.Details += fmt.Sprintf(format, values...)
+	if !t.ended {
+		t.result.Details += fmt.Sprintf(format, values...)
+	}
 }
 
 // Log prints to standard output, which goes to the simulation log file.
@@ -204,7 +545,9 @@ func (t *T) Log(values ...interface{}) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	fmt.Println(values...)
-	t.result.Details += fmt.Sprintln(values...)
+	if !t.ended {
+		t.result.Details += fmt.Sprintln(values...)
+	}
 }
 
 // Failed reports whether the test has already failed.
@@ -218,7 +561,19 @@ func (t *T) Failed() bool {
 func (t *T) Fail() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.result.Pass = false
+	if !t.ended {
+		t.result.Pass = false
+	}
+}
+
+// SetFailureCategory sets the category reported if the test fails. Only the
+// first category set is kept, since it usually names the root cause.
+func (t *T) SetFailureCategory(c FailureCategory) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.result.Category == "" && !t.ended {
+		t.result.Category = c
+	}
 }
 
 // FailNow signals that the test has failed and exits the test immediately.
@@ -228,25 +583,84 @@ func (t *T) FailNow() {
 	runtime.Goexit()
 }
 
-func runTest(host *Simulation, s SuiteID, name, desc string, runit func(t *T)) error {
-	// Register test on simulation server and initialize the T.
-	t := &T{
-		Sim:     host,
-		SuiteID: s,
+// Skip marks the test as skipped and exits the test immediately. The details are
+// added to the test output. A test which has already failed is reported as failed.
+// As with FailNow, this should only be called from the main test goroutine.
+func (t *T) Skip(reason SkipReason, details ...interface{}) {
+	if len(details) > 0 {
+		t.Log(details...)
+	}
+	t.mu.Lock()
+	if t.result.Pass && !t.ended {
+		t.result.Skip = reason
 	}
-	testID, err := host.StartTest(s, name, desc)
+	t.mu.Unlock()
+	runtime.Goexit()
+}
+
+// Skipped reports whether the test was skipped.
+func (t *T) Skipped() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.result.Skip != ""
+}
+
+// runTest runs a test and reports whether it passed without being skipped.
+// The timeout is that of the test, suiteTimeout is the default timeout of subtests.
+func runTest(host *Simulation, s SuiteID, name, desc string, timeout, suiteTimeout time.Duration, runit func(t *T)) (bool, error) {
+	// Register test on simulation server.
+	testID, err := host.StartTestWithTimeout(s, name, desc, timeout)
 	if err != nil {
-		return err
+		return false, err
 	}
-	t.TestID = testID
-	t.result.Pass = true
-	defer func() {
-		t.mu.Lock()
-		defer t.mu.Unlock()
-		host.EndTest(s, testID, t.result)
-	}()
+	if timeout == 0 {
+		timeout = defaultTestTimeout()
+	}
+
+	// Run the test. When hive retries failed tests, the test runs again with a
+	// new T until it passes or the retries are used up. Tests which started
+	// subtests are not retried, their subtests are retried individually. Tests
+	// which timed out are not retried either, since they may still be running.
+	retries := testRetries()
+	for attempt := 0; ; attempt++ {
+		t := &T{
+			Sim:          host,
+			SuiteID:      s,
+			TestID:       testID,
+			suiteTimeout: suiteTimeout,
+		}
+		if timeout > 0 {
+			t.timeout, t.deadline = timeout, time.Now().Add(timeout)
+		}
+		t.result.Pass = true
+		if attempt > 0 {
+			// Logged through the new T, so the message is in the simulation log
+			// and in the details of the attempt.
+			t.Logf("previous attempt failed, retrying (attempt %d of %d)", attempt+1, retries+1)
+		}
+		result, ranSubtests := t.run(runit)
+		if !result.Pass && !ranSubtests && !t.timedOut && attempt < retries {
+			if err := host.RetryTest(s, testID, result); err == nil {
+				continue
+			}
+		}
+		host.EndTest(s, testID, result)
+		return result.Pass && result.Skip == "", nil
+	}
+}
 
-	// Run the test function.
+// run runs the test function and returns the result. It also reports
+// whether the test started any subtests. When the test exceeds its deadline,
+// it fails without waiting for the test function to return. The T is inert
+// after run has returned: its result doesn't change anymore, and API calls
+// of a test function which is still running fail with errTestEnded.
+func (t *T) run(runit func(t *T)) (TestResult, bool) {
+	var timeout <-chan time.Time
+	if t.timeout > 0 {
+		timer := time.NewTimer(t.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
 	done := make(chan struct{})
 	go func() {
 		defer func() {
@@ -254,21 +668,64 @@ func runTest(host *Simulation, s SuiteID, name, desc string, runit func(t *T)) e
 				buf := make([]byte, 4096)
 				i := runtime.Stack(buf, false)
 				t.Logf("panic: %v\n\n%s", err, buf[:i])
+				t.SetFailureCategory(FailureHarnessError)
 				t.Fail()
 			}
 			close(done)
 		}()
 		runit(t)
 	}()
-	<-done
-	return nil
+	select {
+	case <-done:
+	case <-timeout:
+		t.Logf("test did not end within its timeout of %v", t.timeout)
+		t.SetFailureCategory(FailureTimeout)
+		t.Fail()
+		t.mu.Lock()
+		t.timedOut = true
+		t.mu.Unlock()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ended = true
+	if t.result.Pass {
+		t.result.Category = ""
+	} else {
+		t.result.Skip = ""
+	}
+	return t.result, t.subtests
 }
 
-func (spec ClientTestSpec) runTest(host *Simulation, suite SuiteID) error {
+// testRetries returns the number of times a failed test is retried. Hive sets it in
+// the HIVE_TEST_RETRIES environment variable when started with --sim.retries.
+func testRetries() int {
+	n, _ := strconv.Atoi(os.Getenv("HIVE_TEST_RETRIES"))
+	return n
+}
+
+// defaultTestTimeout returns the default timeout of tests. Hive sets it in the
+// HIVE_TEST_TIMEOUT environment variable when started with --sim.testtimeout.
+func defaultTestTimeout() time.Duration {
+	n, _ := strconv.Atoi(os.Getenv("HIVE_TEST_TIMEOUT"))
+	return time.Duration(n) * time.Second
+}
+
+// testTimeout returns the timeout of a test, or the suite default if the test
+// doesn't set one.
+func testTimeout(timeout, suiteTimeout time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	return suiteTimeout
+}
+
+func (spec ClientTestSpec) runTest(host *Simulation, suite SuiteID, suiteTimeout time.Duration) (bool, error) {
 	clients, err := host.ClientTypes()
 	if err != nil {
-		return err
+		return false, err
 	}
+	passed := true
 	for _, clientDef := range clients {
 		// 'role' is an optional filter, so eth1 tests, beacon node tests,
 		// validator tests, etc. can all live in harmony.
@@ -276,15 +733,43 @@ func (spec ClientTestSpec) runTest(host *Simulation, suite SuiteID) error {
 			continue
 		}
 		name := clientTestName(spec.Name, clientDef.Name)
-		err := runTest(host, suite, name, spec.Description, func(t *T) {
-			client := t.StartClient(clientDef.Name, spec.Parameters, WithStaticFiles(spec.Files))
-			spec.Run(t, client)
-		})
-		if err != nil {
-			return err
+		for _, comb := range spec.Matrix.combinations() {
+			comb := comb
+			timeout := testTimeout(spec.Timeout, suiteTimeout)
+			ok, err := runTest(host, suite, spec.Matrix.testName(name, comb), spec.Description, timeout, suiteTimeout, func(t *T) {
+				t.params = comb
+				client := t.StartClient(clientDef.Name, spec.Parameters, comb, WithStaticFiles(spec.Files))
+				spec.Run(t, client)
+			})
+			if err != nil {
+				return false, err
+			}
+			passed = passed && ok
 		}
 	}
-	return nil
+	return passed, nil
+}
+
+func (spec ClientTestSpec) testNames(host *Simulation) ([]string, error) {
+	clients, err := host.ClientTypes()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, clientDef := range clients {
+		if spec.Role != "" && !clientDef.HasRole(spec.Role) {
+			continue
+		}
+		name := clientTestName(spec.Name, clientDef.Name)
+		for _, comb := range spec.Matrix.combinations() {
+			names = append(names, spec.Matrix.testName(name, comb))
+		}
+	}
+	return names, nil
+}
+
+func (spec ClientTestSpec) testInfo() testInfo {
+	return testInfo{spec.Name, spec.Description, spec.Duration, spec.Requires}
 }
 
 // clientTestName ensures that 'name' contains the client type.
@@ -298,6 +783,31 @@ func clientTestName(name, clientType string) string {
 	return name + " (" + clientType + ")"
 }
 
-func (spec TestSpec) runTest(host *Simulation, suite SuiteID) error {
-	return runTest(host, suite, spec.Name, spec.Description, spec.Run)
+func (spec TestSpec) runTest(host *Simulation, suite SuiteID, suiteTimeout time.Duration) (bool, error) {
+	passed := true
+	timeout := testTimeout(spec.Timeout, suiteTimeout)
+	for _, comb := range spec.Matrix.combinations() {
+		comb := comb
+		ok, err := runTest(host, suite, spec.Matrix.testName(spec.Name, comb), spec.Description, timeout, suiteTimeout, func(t *T) {
+			t.params = comb
+			spec.Run(t)
+		})
+		if err != nil {
+			return false, err
+		}
+		passed = passed && ok
+	}
+	return passed, nil
+}
+
+func (spec TestSpec) testInfo() testInfo {
+	return testInfo{spec.Name, spec.Description, spec.Duration, spec.Requires}
+}
+
+func (spec TestSpec) testNames(*Simulation) ([]string, error) {
+	var names []string
+	for _, comb := range spec.Matrix.combinations() {
+		names = append(names, spec.Matrix.testName(spec.Name, comb))
+	}
+	return names, nil
 }
@@ -1,11 +1,19 @@
 package hivesim
 
 import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
 	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/ethereum/hive/internal/fakes"
 	"github.com/ethereum/hive/internal/libhive"
 )
 
@@ -61,8 +69,9 @@ func TestSuiteReporting(t *testing.T) {
 					Name:        "failing test",
 					Description: "this test fails",
 					SummaryResult: libhive.TestResult{
-						Pass:    false,
-						Details: "message from the failing test\n",
+						Pass:     false,
+						Details:  "message from the failing test\n",
+						Category: libhive.FailureAssertion,
 					},
 				},
 			},
@@ -73,6 +82,83 @@ func TestSuiteReporting(t *testing.T) {
 	}
 }
 
+// This test checks that failure categories are reported through the API.
+func TestFailureCategories(t *testing.T) {
+	suite := Suite{Name: "test suite"}
+	suite.Add(TestSpec{
+		Name: "passing test",
+		Run: func(t *T) {
+			t.SetFailureCategory(FailureInfrastructure)
+		},
+	})
+	suite.Add(TestSpec{
+		Name: "timeout test",
+		Run: func(t *T) {
+			t.SetFailureCategory(FailureTimeout)
+			t.Fatal("timed out")
+		},
+	})
+	suite.Add(TestSpec{
+		Name: "panicking test",
+		Run: func(t *T) {
+			panic("oops")
+		},
+	})
+
+	tm, srv := newFakeAPI(nil)
+	defer srv.Close()
+	if err := RunSuite(NewAt(srv.URL), suite); err != nil {
+		t.Fatal("suite run failed:", err)
+	}
+	tm.Terminate()
+
+	want := map[string]libhive.FailureCategory{
+		"passing test":   "",
+		"timeout test":   libhive.FailureTimeout,
+		"panicking test": libhive.FailureHarnessError,
+	}
+	for _, test := range tm.Results()[0].TestCases {
+		if test.SummaryResult.Category != want[test.Name] {
+			t.Errorf("test %q: wrong category %q, want %q", test.Name, test.SummaryResult.Category, want[test.Name])
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	suite := Suite{Name: "test suite"}
+	suite.Add(TestSpec{
+		Name: "skipped test",
+		Run: func(t *T) {
+			t.Skip(SkipUnsupportedFork, "client does not support cancun")
+			t.Fatal("not reached")
+		},
+	})
+	suite.Add(TestSpec{
+		Name: "failed test",
+		Run: func(t *T) {
+			t.Error("check failed")
+			t.Skip(SkipPrerequisiteFailed)
+		},
+	})
+
+	tm, srv := newFakeAPI(nil)
+	defer srv.Close()
+	if err := RunSuite(NewAt(srv.URL), suite); err != nil {
+		t.Fatal("suite run failed:", err)
+	}
+	tm.Terminate()
+
+	want := map[string]libhive.TestResult{
+		"skipped test": {Pass: true, Skip: libhive.SkipUnsupportedFork, Details: "client does not support cancun\n"},
+		"failed test":  {Pass: false, Category: libhive.FailureAssertion, Details: "check failed\n"},
+	}
+	for _, test := range tm.Results()[0].TestCases {
+		if test.SummaryResult != want[test.Name] {
+			t.Errorf("test %q: wrong result %+v, want %+v", test.Name, test.SummaryResult, want[test.Name])
+		}
+	}
+}
+
 // removeTimestamps removes test timestamps in results so they can be
 // compared using reflect.DeepEqual.
 func removeTimestamps(result map[libhive.TestSuiteID]*libhive.TestSuite) {
@@ -83,3 +169,310 @@ func removeTimestamps(result map[libhive.TestSuiteID]*libhive.TestSuite) {
 		}
 	}
 }
+
+// This test checks that tests run in the order configured in hive.
+func TestSuiteOrder(t *testing.T) {
+	var ran []string
+	suite := Suite{Name: "suite"}
+	for _, name := range []string{"c", "a", "b"} {
+		name := name
+		suite.Add(TestSpec{Name: name, Run: func(t *T) { ran = append(ran, name) }})
+	}
+
+	env := libhive.SimEnv{TestOrder: libhive.TestOrder{Strategy: libhive.OrderAlpha}}
+	tm := libhive.NewTestManager(env, fakes.NewContainerBackend(nil), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	os.Setenv("HIVE_TEST_ORDER", libhive.OrderAlpha)
+	defer os.Unsetenv("HIVE_TEST_ORDER")
+	if err := RunSuite(NewAt(srv.URL), suite); err != nil {
+		t.Fatal("suite run failed:", err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("wrong test order %v, want %v", ran, want)
+	}
+}
+
+// This test checks that sharded suites run every test in exactly one shard.
+func TestSuiteShard(t *testing.T) {
+	var ran []string
+	suite := Suite{Name: "suite"}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("test-%d", i)
+		suite.Add(TestSpec{Name: name, Run: func(t *T) { ran = append(ran, name) }})
+	}
+
+	os.Setenv("HIVE_TEST_SHARD", "x")
+	defer os.Unsetenv("HIVE_TEST_SHARD")
+	for i := 1; i <= 2; i++ {
+		env := libhive.SimEnv{TestShard: libhive.TestShard{Index: i, Count: 2}}
+		tm := libhive.NewTestManager(env, fakes.NewContainerBackend(nil), -1)
+		srv := httptest.NewServer(tm.API())
+		before := len(ran)
+		if err := RunSuite(NewAt(srv.URL), suite); err != nil {
+			t.Fatal("suite run failed:", err)
+		}
+		tm.Terminate()
+		srv.Close()
+		for _, s := range tm.Results() {
+			if s.Shard == nil || *s.Shard != env.TestShard {
+				t.Errorf("shard %d/2: wrong shard %v in results", i, s.Shard)
+			}
+		}
+		if n := len(ran) - before; n == 0 || n == 20 {
+			t.Fatalf("shard %d/2 ran %d tests", i, n)
+		}
+	}
+	sort.Strings(ran)
+	for i := 1; i < len(ran); i++ {
+		if ran[i] == ran[i-1] {
+			t.Errorf("test %s ran twice", ran[i])
+		}
+	}
+	if len(ran) != 20 {
+		t.Errorf("%d of 20 tests ran", len(ran))
+	}
+}
+
+// This test checks that failed tests are retried and that all attempts are recorded.
+func TestRetries(t *testing.T) {
+	runs := make(map[string]int)
+	suite := Suite{Name: "suite"}
+	suite.Add(TestSpec{Name: "flaky", Run: func(t *T) {
+		runs["flaky"]++
+		if runs["flaky"] == 1 {
+			t.Fatal("first attempt fails")
+		}
+	}})
+	suite.Add(TestSpec{Name: "broken", Run: func(t *T) {
+		runs["broken"]++
+		t.Fatal("always fails")
+	}})
+	suite.Add(TestSpec{Name: "parent", Run: func(t *T) {
+		runs["parent"]++
+		t.Run(TestSpec{Name: "child", Run: func(t *T) {}})
+		t.Fail()
+	}})
+
+	env := libhive.SimEnv{TestRetries: 2}
+	tm := libhive.NewTestManager(env, fakes.NewContainerBackend(nil), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	os.Setenv("HIVE_TEST_RETRIES", "2")
+	defer os.Unsetenv("HIVE_TEST_RETRIES")
+	if err := RunSuite(NewAt(srv.URL), suite); err != nil {
+		t.Fatal("suite run failed:", err)
+	}
+	if want := map[string]int{"flaky": 2, "broken": 3, "parent": 1}; !reflect.DeepEqual(runs, want) {
+		t.Errorf("wrong number of runs %v, want %v", runs, want)
+	}
+
+	tests := make(map[string]*libhive.TestCase)
+	for _, s := range tm.Results() {
+		for _, test := range s.TestCases {
+			tests[test.Name] = test
+		}
+	}
+	if len(tests) != 4 {
+		t.Fatalf("wrong number of test cases %d", len(tests))
+	}
+	if test := tests["flaky"]; !test.SummaryResult.Pass || len(test.Attempts) != 1 {
+		t.Errorf("flaky test: pass %t, %d attempts", test.SummaryResult.Pass, len(test.Attempts))
+	} else if a := test.Attempts[0]; a.SummaryResult.Pass || !strings.Contains(a.SummaryResult.Details, "first attempt fails") {
+		t.Errorf("flaky test: wrong attempt result %+v", a.SummaryResult)
+	} else if !strings.Contains(test.SummaryResult.Details, "retrying (attempt 2 of 3)") {
+		t.Errorf("flaky test: retry not logged in details %q", test.SummaryResult.Details)
+	}
+	if test := tests["broken"]; test.SummaryResult.Pass || len(test.Attempts) != 2 {
+		t.Errorf("broken test: pass %t, %d attempts", test.SummaryResult.Pass, len(test.Attempts))
+	}
+	if test := tests["parent"]; test.SummaryResult.Pass || len(test.Attempts) != 0 {
+		t.Errorf("parent test: pass %t, %d attempts", test.SummaryResult.Pass, len(test.Attempts))
+	}
+}
+
+// This test checks that tests exceeding their timeout fail, that timed out
+// tests are not retried, and that their T is inert once the test has ended.
+func TestTimeout(t *testing.T) {
+	var (
+		runs      int32
+		release   = make(chan struct{})
+		lateStart = make(chan error, 1)
+	)
+	suite := Suite{Name: "suite", TestTimeout: time.Hour}
+	suite.Add(TestSpec{Name: "slow", Timeout: 50 * time.Millisecond, Run: func(t *T) {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		_, err := t.TryStartClient("client")
+		t.Log("late output")
+		t.Fail()
+		lateStart <- err
+	}})
+	suite.Add(TestSpec{Name: "fast", Run: func(t *T) {
+		if d, ok := t.Deadline(); !ok || time.Until(d) < 59*time.Minute {
+			t.Fatal("wrong deadline", d, ok)
+		}
+	}})
+
+	env := libhive.SimEnv{TestRetries: 1}
+	tm := libhive.NewTestManager(env, fakes.NewContainerBackend(nil), -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	defer tm.Terminate()
+
+	os.Setenv("HIVE_TEST_RETRIES", "1")
+	defer os.Unsetenv("HIVE_TEST_RETRIES")
+	if err := RunSuite(NewAt(srv.URL), suite); err != nil {
+		t.Fatal("suite run failed:", err)
+	}
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("slow test ran %d times", n)
+	}
+	close(release)
+	if err := <-lateStart; !errors.Is(err, errTestEnded) {
+		t.Errorf("wrong error for client start after timeout: %v", err)
+	}
+
+	tests := make(map[string]*libhive.TestCase)
+	for _, s := range tm.Results() {
+		for _, test := range s.TestCases {
+			tests[test.Name] = test
+		}
+	}
+	slow := tests["slow"]
+	if strings.Contains(slow.SummaryResult.Details, "late output") {
+		t.Error("slow test: output after the end of the test was recorded")
+	}
+	if slow.SummaryResult.Pass || slow.SummaryResult.Category != libhive.FailureTimeout || len(slow.Attempts) != 0 {
+		t.Errorf("slow test: wrong result %+v, %d attempts", slow.SummaryResult, len(slow.Attempts))
+	}
+	if slow.Timeout != 0.05 {
+		t.Errorf("slow test: wrong timeout %v", slow.Timeout)
+	}
+	fast := tests["fast"]
+	if !fast.SummaryResult.Pass || fast.Timeout != 3600 {
+		t.Errorf("fast test: wrong result %+v, timeout %v", fast.SummaryResult, fast.Timeout)
+	}
+}
+
+// This test checks that tests which don't fit into the time limit are recorded as not
+// run, and that tests requiring a failed test are skipped.
+func TestPlanTests(t *testing.T) {
+	var ran []string
+	run := func(name string, pass bool) func(*T) {
+		return func(t *T) {
+			ran = append(ran, name)
+			if !pass {
+				t.Fatal("fails")
+			}
+		}
+	}
+	suite := Suite{Name: "suite"}
+	suite.Add(TestSpec{Name: "setup", Run: run("setup", false)})
+	suite.Add(TestSpec{Name: "too-long", Duration: time.Hour, Run: run("too-long", true)})
+	suite.Add(TestSpec{Name: "quick", Duration: time.Second, Run: run("quick", true)})
+	suite.Add(TestSpec{Name: "uses-setup", Requires: []string{"setup"}, Run: run("uses-setup", true)})
+
+	tm := libhive.NewTestManager(libhive.SimEnv{}, fakes.NewContainerBackend(nil), -1)
+	tm.SetDeadline(time.Now().Add(time.Minute))
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+
+	os.Setenv("HIVE_SIM_TIMELIMIT", "60")
+	defer os.Unsetenv("HIVE_SIM_TIMELIMIT")
+	if err := RunSuite(NewAt(srv.URL), suite); err != nil {
+		t.Fatal("suite run failed:", err)
+	}
+
+	// Requirements on unknown tests are rejected.
+	bad := Suite{Name: "bad"}
+	bad.Add(TestSpec{Name: "bad", Requires: []string{"nope"}, Run: run("bad", true)})
+	if err := RunSuite(NewAt(srv.URL), bad); err == nil || !strings.Contains(err.Error(), "unknown test") {
+		t.Fatal("wrong error for unknown required test:", err)
+	}
+	tm.Terminate()
+
+	if want := []string{"quick", "setup"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("wrong tests ran %v, want %v", ran, want)
+	}
+
+	results := make(map[string]libhive.TestResult)
+	for _, s := range tm.Results() {
+		for _, test := range s.TestCases {
+			results[test.Name] = test.SummaryResult
+		}
+	}
+	if r := results["too-long"]; !r.NotRun || !r.Pass {
+		t.Errorf("wrong result of too-long test: %+v", r)
+	}
+	if r := results["uses-setup"]; r.Skip != libhive.SkipPrerequisiteFailed || r.NotRun {
+		t.Errorf("wrong result of uses-setup test: %+v", r)
+	}
+	if r := results["quick"]; !r.Pass || r.NotRun {
+		t.Errorf("wrong result of quick test: %+v", r)
+	}
+}
+
+// This test checks that matrix tests run once per parameter combination.
+func TestMatrix(t *testing.T) {
+	var ran []string
+	var clientEnv []string
+	suite := Suite{Name: "suite"}
+	suite.Add(TestSpec{
+		Name: "plain",
+		Matrix: Matrix{
+			{Param: "MODE", Values: []string{"full", "snap"}},
+			{Param: "SIZE", Values: []string{"1", "2"}},
+		},
+		Run: func(t *T) {
+			ran = append(ran, t.Param("MODE")+"/"+t.Param("SIZE"))
+		},
+	})
+	suite.Add(ClientTestSpec{
+		Name:       "client CLIENT",
+		Role:       "eth1",
+		Parameters: Params{"HIVE_FOO": "bar"},
+		Matrix:     Matrix{{Param: "HIVE_NODETYPE", Values: []string{"full", "light"}}},
+		Run:        func(t *T, c *Client) {},
+	})
+
+	hooks := &fakes.BackendHooks{
+		CreateContainer: func(image string, opt libhive.ContainerOptions) (string, error) {
+			clientEnv = append(clientEnv, opt.Env["HIVE_FOO"]+"/"+opt.Env["HIVE_NODETYPE"])
+			return fmt.Sprintf("c%d", len(clientEnv)), nil
+		},
+	}
+	tm, srv := newFakeAPI(hooks)
+	defer srv.Close()
+	if err := RunSuite(NewAt(srv.URL), suite); err != nil {
+		t.Fatal("suite run failed:", err)
+	}
+	tm.Terminate()
+
+	if want := []string{"full/1", "full/2", "snap/1", "snap/2"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("wrong combinations %v, want %v", ran, want)
+	}
+	if want := []string{"bar/full", "bar/light"}; !reflect.DeepEqual(clientEnv, want) {
+		t.Errorf("wrong client parameters %v, want %v", clientEnv, want)
+	}
+	var names []string
+	for _, test := range tm.Results()[0].TestCases {
+		names = append(names, test.Name)
+	}
+	sort.Strings(names)
+	want := []string{
+		"client client-1 [HIVE_NODETYPE=full]",
+		"client client-1 [HIVE_NODETYPE=light]",
+		"plain [MODE=full, SIZE=1]",
+		"plain [MODE=full, SIZE=2]",
+		"plain [MODE=snap, SIZE=1]",
+		"plain [MODE=snap, SIZE=2]",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("wrong test names %q\nwant %q", names, want)
+	}
+}
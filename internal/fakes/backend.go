@@ -2,26 +2,32 @@ package fakes
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/ethereum/hive/internal/libhive"
 )
 
 // BackendHooks can be used to override the behavior of the fake backend.
 type BackendHooks struct {
-	CreateContainer func(image string, opt libhive.ContainerOptions) (string, error)
-	StartContainer  func(containerID string, opt libhive.ContainerOptions) (*libhive.ContainerInfo, error)
-	DeleteContainer func(containerID string) error
-	RunEnodeSh      func(containerID string) (string, error)
-	RunProgram      func(containerID string, cmd []string) (*libhive.ExecInfo, error)
+	CreateContainer  func(image string, opt libhive.ContainerOptions) (string, error)
+	StartContainer   func(containerID string, opt libhive.ContainerOptions) (*libhive.ContainerInfo, error)
+	DeleteContainer  func(containerID string) error
+	RunEnodeSh       func(containerID string) (string, error)
+	RunProgram       func(containerID string, cmd []string) (*libhive.ExecInfo, error)
+	PauseContainer   func(containerID string) error
+	UnpauseContainer func(containerID string) error
+	ProbeNetwork     func(from, to, toIP string, duration time.Duration) (*libhive.NetworkProbe, error)
+
+	SetNetworkConditions func(containerID string, cond libhive.NetworkConditions) error
 
 	NetworkNameToID     func(string) (string, error)
-	CreateNetwork       func(string) (string, error)
+	CreateNetwork       func(string, libhive.NetworkOptions) (string, error)
 	RemoveNetwork       func(networkID string) error
 	ContainerIP         func(containerID, networkID string) (net.IP, error)
-	ConnectContainer    func(containerID, networkID string) error
+	ContainerNetworks   func(containerID string) (map[string]net.IP, error)
+	ConnectContainer    func(containerID, networkID string, ip net.IP) error
 	DisconnectContainer func(containerID, networkID string) error
 }
 
@@ -99,16 +105,44 @@ func (b *fakeBackend) RunProgram(ctx context.Context, containerID string, cmd []
 	return &libhive.ExecInfo{Stdout: "std output", Stderr: "std err", ExitCode: 0}, nil
 }
 
+func (b *fakeBackend) PauseContainer(containerID string) error {
+	if b.hooks.PauseContainer != nil {
+		return b.hooks.PauseContainer(containerID)
+	}
+	return nil
+}
+
+func (b *fakeBackend) UnpauseContainer(containerID string) error {
+	if b.hooks.UnpauseContainer != nil {
+		return b.hooks.UnpauseContainer(containerID)
+	}
+	return nil
+}
+
+func (b *fakeBackend) ProbeNetwork(ctx context.Context, from, to, toIP string, duration time.Duration) (*libhive.NetworkProbe, error) {
+	if b.hooks.ProbeNetwork != nil {
+		return b.hooks.ProbeNetwork(from, to, toIP, duration)
+	}
+	return &libhive.NetworkProbe{BitsPerSecond: 1e9, RTT: 100 * time.Microsecond, MinRTT: 50 * time.Microsecond, MaxRTT: 200 * time.Microsecond}, nil
+}
+
+func (b *fakeBackend) SetNetworkConditions(ctx context.Context, containerID string, cond libhive.NetworkConditions) error {
+	if b.hooks.SetNetworkConditions != nil {
+		return b.hooks.SetNetworkConditions(containerID, cond)
+	}
+	return nil
+}
+
 func (b *fakeBackend) NetworkNameToID(name string) (string, error) {
 	if b.hooks.NetworkNameToID != nil {
 		return b.hooks.NetworkNameToID(name)
 	}
-	return "", errors.New("network not found")
+	return "", libhive.ErrNetworkNotFound
 }
 
-func (b *fakeBackend) CreateNetwork(name string) (string, error) {
+func (b *fakeBackend) CreateNetwork(name string, opt libhive.NetworkOptions) (string, error) {
 	if b.hooks.CreateNetwork != nil {
-		return b.hooks.CreateNetwork(name)
+		return b.hooks.CreateNetwork(name, opt)
 	}
 	b.netCounter++
 	id := fmt.Sprintf("%0.8x", b.netCounter)
@@ -129,9 +163,16 @@ func (b *fakeBackend) ContainerIP(containerID, networkID string) (net.IP, error)
 	return net.IP{203, 0, 113, 2}, nil
 }
 
-func (b *fakeBackend) ConnectContainer(containerID, networkID string) error {
+func (b *fakeBackend) ContainerNetworks(containerID string) (map[string]net.IP, error) {
+	if b.hooks.ContainerNetworks != nil {
+		return b.hooks.ContainerNetworks(containerID)
+	}
+	return make(map[string]net.IP), nil
+}
+
+func (b *fakeBackend) ConnectContainer(containerID, networkID string, ip net.IP) error {
 	if b.hooks.ConnectContainer != nil {
-		return b.hooks.ConnectContainer(containerID, networkID)
+		return b.hooks.ConnectContainer(containerID, networkID, ip)
 	}
 	return nil
 }
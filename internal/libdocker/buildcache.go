@@ -0,0 +1,243 @@
+package libdocker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/pkg/fileutils"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// buildCacheFile is the name of the cache file in the cache directory.
+const buildCacheFile = "build-cache.json"
+
+// buildCache remembers the inputs of image builds. An image doesn't need to be rebuilt
+// when its build inputs are unchanged and the image built from them still exists.
+//
+// The build inputs are the files of the build context, the build arguments, the IDs of
+// the base images and the commits of git repositories cloned by the Dockerfile.
+type buildCache struct {
+	file string
+
+	mu      sync.Mutex
+	entries map[string]buildCacheEntry // image tag -> entry
+}
+
+type buildCacheEntry struct {
+	Key     string    `json:"key"`
+	ImageID string    `json:"imageID"`
+	Built   time.Time `json:"built"`
+}
+
+// openBuildCache loads the cache file in dir. A missing or invalid file is
+// treated as an empty cache.
+func openBuildCache(dir string) *buildCache {
+	c := &buildCache{
+		file:    filepath.Join(dir, buildCacheFile),
+		entries: make(map[string]buildCacheEntry),
+	}
+	if content, err := ioutil.ReadFile(c.file); err == nil {
+		json.Unmarshal(content, &c.entries)
+	}
+	return c
+}
+
+func (c *buildCache) get(tag string) (buildCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[tag]
+	return e, ok
+}
+
+// put stores an entry and writes the cache file.
+func (c *buildCache) put(tag string, e buildCacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[tag] = e
+	content, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0755); err != nil {
+		return err
+	}
+	tmp := c.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.file)
+}
+
+// cachedImage reports whether the image built from the given inputs still exists.
+func (b *Builder) cachedImage(key, imageTag string) bool {
+	entry, ok := b.cache.get(imageTag)
+	if !ok || entry.Key != key {
+		return false
+	}
+	img, err := b.client.InspectImage(imageTag)
+	return err == nil && img.ID == entry.ImageID
+}
+
+// storeCacheEntry records the build inputs of a newly built image.
+func (b *Builder) storeCacheEntry(key, imageTag string) error {
+	img, err := b.client.InspectImage(imageTag)
+	if err != nil {
+		return err
+	}
+	return b.cache.put(imageTag, buildCacheEntry{Key: key, ImageID: img.ID, Built: time.Now()})
+}
+
+// buildKey computes the hash of the build inputs of an image.
+func (b *Builder) buildKey(ctx context.Context, contextDir, dockerfile string, args map[string]string, imageTag string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "hive-build-cache-v2\x00%s\x00dockerfile=%s\x00", imageTag, dockerfile)
+	for _, name := range sortedKeys(args) {
+		fmt.Fprintf(h, "arg=%s=%s\x00", name, args[name])
+	}
+
+	// Hash the build context.
+	if err := hashDirectory(h, contextDir); err != nil {
+		return "", err
+	}
+	// Hash the base images. They must be available locally, otherwise
+	// the build has to pull them anyway.
+	for _, base := range DockerfileBaseImages(contextDir, dockerfile, args) {
+		img, err := b.client.InspectImage(base)
+		if err != nil {
+			return "", fmt.Errorf("base image %s: %v", base, err)
+		}
+		fmt.Fprintf(h, "base=%s@%s\x00", base, img.ID)
+	}
+	// Hash the commits of cloned repositories.
+	for _, src := range dockerfileGitSources(contextDir, dockerfile, args) {
+		commit, err := gitRemoteCommit(ctx, src)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "git=%s#%s@%s\x00", src.URL, src.Ref, commit)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashDirectory writes the names, modes and contents of all files in dir to h.
+// Files excluded by the .dockerignore file of dir are skipped, like they are left out
+// of the build context sent to docker.
+func hashDirectory(h io.Writer, dir string) error {
+	ignore, err := readDockerignore(dir)
+	if err != nil {
+		return err
+	}
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if rel == "." || ignore == nil {
+			if info.Mode().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		}
+		skip, err := ignore.Matches(rel)
+		if err != nil {
+			return err
+		}
+		if skip {
+			// Files in an excluded directory can be included again by
+			// exception patterns, so the directory is only skipped
+			// when there are none.
+			if info.IsDir() && !ignore.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, file)
+		fmt.Fprintf(h, "file=%s\x00mode=%v\x00size=%d\x00", filepath.ToSlash(rel), info.Mode(), info.Size())
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDockerignore reads the .dockerignore file in dir. It returns nil if there
+// is no such file.
+func readDockerignore(dir string) (*fileutils.PatternMatcher, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	patterns, err := dockerignore.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("can't read .dockerignore: %v", err)
+	}
+	return fileutils.NewPatternMatcher(patterns)
+}
+
+// gitRemoteCommit resolves a branch or tag of a remote repository to a commit hash.
+func gitRemoteCommit(ctx context.Context, src gitSource) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", "ls-remote", src.URL, src.Ref).Output()
+	if err != nil {
+		return "", fmt.Errorf("can't resolve %s of %s: %v", src.Ref, src.URL, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%s has no ref %s", src.URL, src.Ref)
+	}
+	return fields[0], nil
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isCacheable reports whether the build cache may be used for a build with the given options.
+// Builds which pull base images or avoid the docker cache always run.
+func isCacheable(opts docker.BuildImageOptions) bool {
+	return !opts.Pull && !opts.NoCache
+}
@@ -0,0 +1,52 @@
+package libdocker
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashDirectoryDockerignore(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash := func() [32]byte {
+		h := sha256.New()
+		if err := hashDirectory(h, dir); err != nil {
+			t.Fatal(err)
+		}
+		var sum [32]byte
+		copy(sum[:], h.Sum(nil))
+		return sum
+	}
+	write(".dockerignore", "# comment\nworkspace\n*.log\n!keep.log\n**/*.tmp\ncache\n!cache/config\n")
+	write("sim/main.go", "package main")
+	initial := hash()
+
+	// Ignored files don't change the hash.
+	write("workspace/logs/results.json", "{}")
+	write("run.log", "output")
+	write("sim/data/state.tmp", "x")
+	write("cache/blocks", "x")
+	if hash() != initial {
+		t.Error("hash changed by ignored files")
+	}
+	// Other files do.
+	for _, name := range []string{"sim/go.mod", "keep.log", "cache/config"} {
+		write(name, "content")
+		if h := hash(); h == initial {
+			t.Errorf("hash not changed by %s", name)
+		} else {
+			initial = h
+		}
+	}
+}
@@ -5,11 +5,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"gopkg.in/yaml.v2"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/ethereum/hive/internal/libhive"
 	docker "github.com/fsouza/go-dockerclient"
@@ -18,9 +18,11 @@ import (
 
 // Builder takes care of building docker images.
 type Builder struct {
-	client *docker.Client
-	config *Config
-	logger log15.Logger
+	client   *docker.Client
+	config   *Config
+	logger   log15.Logger
+	cache    *buildCache // nil if the build cache is disabled
+	endpoint string      // docker endpoint, for builds through the docker CLI
 }
 
 func NewBuilder(client *docker.Client, cfg *Config) *Builder {
@@ -28,46 +30,136 @@ func NewBuilder(client *docker.Client, cfg *Config) *Builder {
 	if b.logger == nil {
 		b.logger = log15.Root()
 	}
+	if cfg.CacheDir != "" {
+		b.cache = openBuildCache(cfg.CacheDir)
+	}
 	return b
 }
 
 // ReadClientMetadata reads metadata of the given client.
 func (b *Builder) ReadClientMetadata(name string) (*libhive.ClientMetadata, error) {
-	dir := b.config.Inventory.ClientDirectory(name)
-	f, err := os.Open(filepath.Join(dir, "hive.yaml"))
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Eth1 client by default.
-			return &libhive.ClientMetadata{Roles: []string{"eth1"}}, nil
-		} else {
-			return nil, fmt.Errorf("failed to read hive metadata file in '%s': %v", dir, err)
-		}
-	}
-	defer f.Close()
-	var out libhive.ClientMetadata
-	if err := yaml.NewDecoder(f).Decode(&out); err != nil {
-		return nil, fmt.Errorf("failed to decode hive metadata file in '%s': %v", dir, err)
-	}
-	return &out, nil
+	return b.config.Inventory.ClientMetadata(name)
 }
 
 // BuildClientImage builds a docker image of the given client.
-func (b *Builder) BuildClientImage(ctx context.Context, name string) (string, error) {
+func (b *Builder) BuildClientImage(ctx context.Context, name string, info libhive.ClientBuildInfo) (string, error) {
 	dir := b.config.Inventory.ClientDirectory(name)
-	_, branch := libhive.SplitClientName(name)
 	tag := fmt.Sprintf("hive/clients/%s:latest", name)
-	err := b.buildImage(ctx, dir, branch, tag)
+	err := b.buildImage(ctx, name, dir, info.Dockerfile, info.BuildArgs, tag, b.buildLogFile("clients", name))
 	return tag, err
 }
 
 // BuildSimulatorImage builds a docker image of a simulator.
 func (b *Builder) BuildSimulatorImage(ctx context.Context, name string) (string, error) {
-	dir := b.config.Inventory.SimulatorDirectory(name)
+	dir, dockerfile, err := b.config.Inventory.SimulatorBuildContext(name)
+	if err != nil {
+		return "", err
+	}
+	if dockerfile == "Dockerfile" {
+		dockerfile = ""
+	}
 	tag := fmt.Sprintf("hive/simulators/%s:latest", name)
-	err := b.buildImage(ctx, dir, "", tag)
+	err = b.buildImage(ctx, name, dir, dockerfile, nil, tag, b.buildLogFile("simulators", name))
 	return tag, err
 }
 
+// PullImage pulls an image from a registry and returns its digest.
+func (b *Builder) PullImage(ctx context.Context, image string) (string, error) {
+	logger := b.logger.New("image", image)
+	opts := docker.PullImageOptions{Context: ctx, Repository: image, OutputStream: ioutil.Discard}
+	if b.config.BuildOutput != nil {
+		out := newLinePrefixWriter(b.config.BuildOutput, fmt.Sprintf("[%s] ", image))
+		defer out.Close()
+		opts.OutputStream = out
+	}
+	repo := image
+	if at := strings.IndexByte(image, '@'); at < 0 {
+		// Split off the tag. Digests are split off by go-dockerclient.
+		if colon := strings.LastIndexByte(image, ':'); colon > strings.LastIndexByte(image, '/') {
+			repo, opts.Repository, opts.Tag = image[:colon], image[:colon], image[colon+1:]
+		}
+	} else {
+		repo = image[:at]
+	}
+
+	logger.Info("pulling image")
+	if err := b.client.PullImage(opts, docker.AuthConfiguration{}); err != nil {
+		logger.Error("image pull failed", "err", err)
+		cause := libhive.ClassifyBuildFailure(err.Error())
+		return "", &libhive.Error{
+			Code:    libhive.CodePullFailed,
+			Message: "can't pull " + image,
+			Context: map[string]string{"image": image, "cause": string(cause)},
+			Hint:    "Check that the image exists and that docker is logged in to its registry.",
+			Retry:   true,
+			Err:     err,
+		}
+	}
+	info, err := b.client.InspectImage(image)
+	if err != nil {
+		return "", err
+	}
+	for _, rd := range info.RepoDigests {
+		if strings.HasPrefix(rd, repo+"@") {
+			digest := strings.TrimPrefix(rd, repo+"@")
+			logger.Info("pulled image", "digest", digest)
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("image %s has no digest for repository %s", image, repo)
+}
+
+// PushImage tags a local image with the target name and pushes it to the registry of
+// the target. Credentials are taken from the docker configuration of the user.
+func (b *Builder) PushImage(ctx context.Context, image, target string) error {
+	logger := b.logger.New("image", image, "target", target)
+	repo, tag := target, "latest"
+	if colon := strings.LastIndexByte(target, ':'); colon > strings.LastIndexByte(target, '/') {
+		repo, tag = target[:colon], target[colon+1:]
+	}
+	err := b.client.TagImage(image, docker.TagImageOptions{Context: ctx, Repo: repo, Tag: tag, Force: true})
+	if err != nil {
+		return fmt.Errorf("can't tag %s as %s: %v", image, target, err)
+	}
+	opts := docker.PushImageOptions{Context: ctx, Name: repo, Tag: tag, OutputStream: ioutil.Discard}
+	if b.config.BuildOutput != nil {
+		out := newLinePrefixWriter(b.config.BuildOutput, fmt.Sprintf("[%s] ", target))
+		defer out.Close()
+		opts.OutputStream = out
+	}
+	var auth docker.AuthConfiguration
+	if auths, err := docker.NewAuthConfigurationsFromDockerCfg(); err == nil {
+		auth = auths.Configs[registryHost(repo)]
+	}
+	logger.Info("pushing image")
+	if err := b.client.PushImage(opts, auth); err != nil {
+		logger.Error("image push failed", "err", err)
+		return &libhive.Error{
+			Code:    libhive.CodePullFailed,
+			Message: "can't push " + target,
+			Context: map[string]string{"image": image, "target": target},
+			Hint:    "Check that the registry is reachable and that docker is logged in to it.",
+			Retry:   true,
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// registryHost returns the registry of an image repository. Repositories without
+// a registry host are on Docker Hub.
+func registryHost(repo string) string {
+	slash := strings.IndexByte(repo, '/')
+	if slash < 0 {
+		return "https://index.docker.io/v1/"
+	}
+	host := repo[:slash]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return "https://index.docker.io/v1/"
+	}
+	return host
+}
+
 // ReadFile returns the content of a file in the given image. To do so, it creates a
 // temporary container, downloads the file from it and destroys the container.
 func (b *Builder) ReadFile(image, path string) ([]byte, error) {
@@ -108,9 +200,24 @@ func (b *Builder) ReadFile(image, path string) ([]byte, error) {
 	}
 }
 
+// buildLogFile returns the file storing the build output of a client or simulator.
+// It returns the empty string if build logs are disabled.
+func (b *Builder) buildLogFile(kind, name string) string {
+	if b.config.BuildLogDir == "" {
+		return ""
+	}
+	file := filepath.Join(b.config.BuildLogDir, kind, filepath.FromSlash(name)+".log")
+	if b.config.CompressBuildLogs {
+		file += libhive.CompressedLogExt
+	}
+	return file
+}
+
 // buildImage builds a single docker image from the specified context.
 // branch specifes a build argument to use a specific base image branch or github source branch.
-func (b *Builder) buildImage(ctx context.Context, contextDir, branch, imageTag string) error {
+// Build output lines are prefixed with name, so concurrent builds can be told apart.
+// The output is also stored in logFile, unless it is empty.
+func (b *Builder) buildImage(ctx context.Context, name, contextDir, dockerfile string, args map[string]string, imageTag, logFile string) error {
 	nocache := false
 	if b.config.NoCachePattern != nil {
 		nocache = b.config.NoCachePattern.MatchString(imageTag)
@@ -130,20 +237,137 @@ func (b *Builder) buildImage(ctx context.Context, contextDir, branch, imageTag s
 		Dockerfile:   "Dockerfile",
 		NoCache:      nocache,
 		Pull:         b.config.PullEnabled,
-	}
-	if b.config.BuildOutput != nil {
-		opts.OutputStream = b.config.BuildOutput
+		Labels:       b.config.Labels,
 	}
 	logctx := []interface{}{"dir", contextDir, "nocache", opts.NoCache, "pull", opts.Pull}
-	if branch != "" {
+	if dockerfile != "" {
+		opts.Dockerfile = filepath.ToSlash(dockerfile)
+		logctx = append(logctx, "dockerfile", opts.Dockerfile)
+	}
+	if branch := args["branch"]; branch != "" {
 		logctx = append(logctx, "branch", branch)
-		opts.BuildArgs = []docker.BuildArg{{Name: "branch", Value: branch}}
+	}
+	if len(b.config.BuildCacheMounts) > 0 {
+		logctx = append(logctx, "cachemounts", len(b.config.BuildCacheMounts))
+	}
+	for _, name := range sortedKeys(args) {
+		opts.BuildArgs = append(opts.BuildArgs, docker.BuildArg{Name: name, Value: args[name]})
 	}
 
+	// Check whether the image was already built from the same inputs.
+	var cacheKey string
+	if b.cache != nil && !b.config.ForceRebuild && isCacheable(opts) {
+		key, err := b.buildKey(ctx, contextDir, dockerfile, args, imageTag)
+		switch {
+		case err != nil:
+			logger.Debug("can't compute build cache key", "err", err)
+		case b.cachedImage(key, imageTag):
+			logger.Info("using cached image", logctx...)
+			return nil
+		default:
+			cacheKey = key
+		}
+	}
+
+	// The tail of the output is kept to classify build failures.
+	tail := &tailWriter{max: buildOutputTail}
+	outputs := []io.Writer{tail}
+	if b.config.BuildOutput != nil {
+		out := newLinePrefixWriter(b.config.BuildOutput, fmt.Sprintf("[%s] ", name))
+		defer out.Close()
+		outputs = append(outputs, out)
+	}
+	if logFile != "" {
+		log, err := createBuildLog(logFile)
+		if err != nil {
+			logger.Warn("can't create build log", "file", logFile, "err", err)
+			logFile = ""
+		} else {
+			defer log.Close()
+			outputs = append(outputs, log)
+		}
+	}
+	opts.OutputStream = io.MultiWriter(outputs...)
+
 	logger.Info("building image", logctx...)
-	if err := b.client.BuildImage(opts); err != nil {
+	build := b.client.BuildImage
+	if len(b.config.BuildCacheMounts) > 0 {
+		build = func(opts docker.BuildImageOptions) error { return b.buildWithCacheMounts(ctx, opts) }
+	}
+	if err := build(opts); err != nil {
 		logger.Error("image build failed", "err", err)
-		return err
+		cause := libhive.ClassifyBuildFailure(string(tail.buf) + "\n" + err.Error())
+		e := &libhive.Error{
+			Code:    libhive.CodeBuildFailed,
+			Message: "can't build " + imageTag,
+			Context: map[string]string{"image": imageTag, "dir": contextDir, "cause": string(cause)},
+			Retry:   cause == libhive.BuildCauseNetwork,
+			Err:     err,
+		}
+		switch {
+		case logFile != "":
+			e.Context["log"] = logFile
+			e.Hint = "The build output is stored in " + logFile + "."
+		case b.config.BuildOutput == nil:
+			e.Hint = "Run hive with --docker.output to see the build output."
+		}
+		return e
+	}
+	if b.cache != nil && isCacheable(opts) {
+		if cacheKey == "" {
+			// The key can be unavailable before the build, e.g. when base images
+			// are not present locally yet.
+			key, err := b.buildKey(ctx, contextDir, dockerfile, args, imageTag)
+			if err != nil {
+				logger.Debug("can't compute build cache key", "err", err)
+				return nil
+			}
+			cacheKey = key
+		}
+		if err := b.storeCacheEntry(cacheKey, imageTag); err != nil {
+			logger.Warn("can't update build cache", "err", err)
+		}
 	}
 	return nil
 }
+
+// buildOutputTail is the amount of build output kept for classifying build failures.
+const buildOutputTail = 64 * 1024
+
+// tailWriter keeps the last max bytes written to it.
+type tailWriter struct {
+	buf []byte
+	max int
+}
+
+func (w *tailWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	if len(w.buf) > w.max {
+		w.buf = append(w.buf[:0], w.buf[len(w.buf)-w.max:]...)
+	}
+	return len(b), nil
+}
+
+// buildLog is an open build log file.
+type buildLog struct {
+	io.WriteCloser
+	fd *os.File
+}
+
+// createBuildLog creates a build log file, compressing its content if the
+// file name has the extension of compressed logs.
+func createBuildLog(file string) (*buildLog, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+	fd, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	return &buildLog{WriteCloser: libhive.NewLogWriter(file, fd), fd: fd}, nil
+}
+
+func (l *buildLog) Close() error {
+	l.WriteCloser.Close()
+	return l.fd.Close()
+}
@@ -0,0 +1,79 @@
+package libdocker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// checkCacheMounts verifies that images can be built with cache mounts. Recent docker
+// CLIs only build with BuildKit through the buildx plugin, so it must be installed.
+func checkCacheMounts(engine string) error {
+	if engine == EnginePodman {
+		return errors.New("build cache mounts are not supported with podman")
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		return fmt.Errorf("build cache mounts need the docker CLI: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, "docker", "buildx", "version").CombinedOutput(); err != nil {
+		return fmt.Errorf("build cache mounts need BuildKit, but docker buildx is not available: %v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// buildWithCacheMounts builds an image with BuildKit. The classic builder, which is
+// used through the docker API, doesn't support cache mounts, so the build runs through
+// the docker CLI. The caches are mounted into all RUN instructions of a copy of the
+// Dockerfile.
+func (b *Builder) buildWithCacheMounts(ctx context.Context, opts docker.BuildImageOptions) error {
+	content, err := ioutil.ReadFile(filepath.Join(opts.ContextDir, filepath.FromSlash(opts.Dockerfile)))
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile("", "hive-dockerfile-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(addCacheMounts(content, b.config.BuildCacheMounts))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	args := []string{"build", "--progress=plain", "--file", f.Name(), "--tag", opts.Name}
+	for _, k := range sortedKeys(opts.Labels) {
+		args = append(args, "--label", k+"="+opts.Labels[k])
+	}
+	for _, arg := range opts.BuildArgs {
+		args = append(args, "--build-arg", arg.Name+"="+arg.Value)
+	}
+	if opts.NoCache {
+		args = append(args, "--no-cache")
+	}
+	if opts.Pull {
+		args = append(args, "--pull")
+	}
+	args = append(args, opts.ContextDir)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1", "DOCKER_HOST="+b.endpoint)
+	cmd.Stdout = opts.OutputStream
+	cmd.Stderr = opts.OutputStream
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker build: %v", err)
+	}
+	return nil
+}
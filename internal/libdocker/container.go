@@ -4,7 +4,6 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,6 +11,8 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,16 +25,42 @@ type ContainerBackend struct {
 	client *docker.Client
 	config *Config
 	logger log15.Logger
+
+	// resource accounting
+	statsMu sync.Mutex
+	stats   libhive.ResourceStats
+	running map[string]*containerUsage // short ID -> usage
+	named   uint64                     // number of named containers
+
+	proxyMu sync.Mutex
+	proxies map[string]string // client container ID -> port proxy container ID
 }
 
 func NewContainerBackend(c *docker.Client, cfg *Config) *ContainerBackend {
-	b := &ContainerBackend{client: c, config: cfg, logger: cfg.Logger}
+	b := &ContainerBackend{
+		client:  c,
+		config:  cfg,
+		logger:  cfg.Logger,
+		stats:   libhive.ResourceStats{Start: time.Now()},
+		running: make(map[string]*containerUsage),
+		proxies: make(map[string]string),
+	}
 	if b.logger == nil {
 		b.logger = log15.Root()
 	}
 	return b
 }
 
+// containerError creates the error of a failed operation on a container.
+func containerError(msg, containerID string, err error) *libhive.Error {
+	return &libhive.Error{
+		Code:    libhive.CodeContainerFailed,
+		Message: msg,
+		Context: map[string]string{"container": containerID},
+		Err:     err,
+	}
+}
+
 // RunEnodeSh runs the enode.sh script in a container.
 func (b *ContainerBackend) RunEnodeSh(ctx context.Context, containerID string) (string, error) {
 	exec, err := b.client.CreateExec(docker.CreateExecOptions{
@@ -45,7 +72,7 @@ func (b *ContainerBackend) RunEnodeSh(ctx context.Context, containerID string) (
 		Container:    containerID,
 	})
 	if err != nil {
-		return "", fmt.Errorf("can't create enode.sh exec in %s: %v", containerID, err)
+		return "", containerError("can't create enode.sh exec in "+containerID, containerID, err)
 	}
 	outputBuf := new(bytes.Buffer)
 	err = b.client.StartExec(exec.ID, docker.StartExecOptions{
@@ -54,7 +81,7 @@ func (b *ContainerBackend) RunEnodeSh(ctx context.Context, containerID string) (
 		OutputStream: outputBuf,
 	})
 	if err != nil {
-		return "", fmt.Errorf("can't run enode.sh in %s: %v", containerID, err)
+		return "", containerError("can't run enode.sh in "+containerID, containerID, err)
 	}
 	return outputBuf.String(), nil
 }
@@ -69,7 +96,7 @@ func (b *ContainerBackend) RunProgram(ctx context.Context, containerID string, c
 		Container:    containerID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("can't create exec %v: %v", cmd, err)
+		return nil, containerError(fmt.Sprintf("can't create exec %v", cmd), containerID, err)
 	}
 	outputBuf := new(bytes.Buffer)
 	errBuf := new(bytes.Buffer)
@@ -80,11 +107,11 @@ func (b *ContainerBackend) RunProgram(ctx context.Context, containerID string, c
 		ErrorStream:  errBuf,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("can't run exec %v: %v", cmd, err)
+		return nil, containerError(fmt.Sprintf("can't run exec %v", cmd), containerID, err)
 	}
 	insp, err := b.client.InspectExec(exec.ID)
 	if err != nil {
-		return nil, fmt.Errorf("can't check execution result of %v: %v", cmd, err)
+		return nil, containerError(fmt.Sprintf("can't check execution result of %v", cmd), containerID, err)
 	}
 
 	return &libhive.ExecInfo{
@@ -100,16 +127,33 @@ func (b *ContainerBackend) CreateContainer(ctx context.Context, imageName string
 	for key, val := range opt.Env {
 		vars = append(vars, key+"="+val)
 	}
+	labels := make(map[string]string)
+	for key, val := range b.config.Labels {
+		labels[key] = val
+	}
+	for key, val := range opt.Labels {
+		labels[key] = val
+	}
+	b.statsMu.Lock()
+	b.named++
+	name := containerName(b.config.Labels[libhive.LabelRunID], labels, b.named)
+	b.statsMu.Unlock()
 	c, err := b.client.CreateContainer(docker.CreateContainerOptions{
 		Context: ctx,
+		Name:    name,
 		Config: &docker.Config{
-			Image: imageName,
-			Env:   vars,
+			Image:  imageName,
+			Env:    vars,
+			Labels: labels,
 		},
+		HostConfig: resourceLimits(opt.Resources),
 	})
 	if err != nil {
 		return "", err
 	}
+	b.statsMu.Lock()
+	b.stats.ContainersCreated++
+	b.statsMu.Unlock()
 	logger := b.logger.New("image", imageName, "container", c.ID[:8])
 
 	// Now upload files.
@@ -122,18 +166,38 @@ func (b *ContainerBackend) CreateContainer(ctx context.Context, imageName string
 	return c.ID, err
 }
 
+// resourceLimits converts container resource limits to docker host config.
+// It returns nil when there are no limits.
+func resourceLimits(r libhive.ContainerResources) *docker.HostConfig {
+	if r.CPUs == 0 && r.Memory == 0 {
+		return nil
+	}
+	return &docker.HostConfig{
+		NanoCPUs: int64(r.CPUs * 1e9),
+		Memory:   int64(r.Memory),
+		// Setting the swap limit to the memory limit disables swap, which would
+		// otherwise make memory-constrained clients slow rather than failing.
+		MemorySwap: int64(r.Memory),
+	}
+}
+
 // StartContainer starts a docker container.
 func (b *ContainerBackend) StartContainer(ctx context.Context, containerID string, opt libhive.ContainerOptions) (*libhive.ContainerInfo, error) {
 	info := &libhive.ContainerInfo{ID: containerID[:8], LogFile: opt.LogFile}
+	if opt.LogFile != "" {
+		// The stream logs are written along with the combined log.
+		info.StdoutLogFile, info.StderrLogFile = opt.StdoutLogFile, opt.StderrLogFile
+	}
 	logger := b.logger.New("container", info.ID)
 
 	// Run the container.
 	var startTime = time.Now()
-	waiter, err := b.runContainer(ctx, logger, containerID, info.LogFile)
+	waiter, err := b.runContainer(ctx, logger, containerID, opt)
 	if err != nil {
 		b.DeleteContainer(containerID)
-		return nil, fmt.Errorf("container did not start: %v", err)
+		return nil, containerError("container did not start", containerID, err)
 	}
+	b.trackStart(containerID)
 
 	// This goroutine waits for the container to end and closes log
 	// files when done.
@@ -160,6 +224,16 @@ func (b *ContainerBackend) StartContainer(ctx context.Context, containerID strin
 	info.IP = container.NetworkSettings.IPAddress
 	info.MAC = container.NetworkSettings.MacAddress
 
+	// Start the port proxy if requested.
+	if len(opt.PortProxy) > 0 {
+		if err := b.startPortProxy(ctx, containerID, opt.PortProxy); err != nil {
+			b.DeleteContainer(containerID)
+			info.Wait()
+			info.Wait = nil
+			return info, err
+		}
+	}
+
 	// Set up the port check if requested.
 	hasStarted := make(chan struct{})
 	if opt.CheckLive != 0 {
@@ -177,9 +251,9 @@ func (b *ContainerBackend) StartContainer(ctx context.Context, containerID strin
 	case <-hasStarted:
 		logger.Debug("container online", "time", time.Since(startTime))
 	case <-containerExit:
-		checkErr = errors.New("terminated unexpectedly")
+		checkErr = libhive.ErrContainerTerminated
 	case <-ctx.Done():
-		checkErr = errors.New("timed out waiting for container startup")
+		checkErr = libhive.ErrContainerStartTimeout
 	}
 	if checkErr != nil {
 		b.DeleteContainer(containerID)
@@ -189,6 +263,120 @@ func (b *ContainerBackend) StartContainer(ctx context.Context, containerID strin
 	return info, checkErr
 }
 
+// Stats returns the resource usage of all containers created by the backend.
+func (b *ContainerBackend) Stats() libhive.ResourceStats {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	stats := b.stats
+	stats.RunID = b.config.Labels[libhive.LabelRunID]
+	stats.End = time.Now()
+	return stats
+}
+
+// containerUsage tracks the CPU time of a running container.
+type containerUsage struct {
+	id      string
+	cpu     time.Duration      // highest sampled CPU time, guarded by statsMu
+	stop    context.CancelFunc // ends sampling
+	sampled chan struct{}      // closed when sampling has ended
+}
+
+// trackStart records that a container is running and starts sampling its CPU time.
+func (b *ContainerBackend) trackStart(containerID string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	u := &containerUsage{id: containerID, stop: cancel, sampled: make(chan struct{})}
+	b.statsMu.Lock()
+	b.running[containerID[:8]] = u
+	if len(b.running) > b.stats.PeakConcurrent {
+		b.stats.PeakConcurrent = len(b.running)
+	}
+	b.statsMu.Unlock()
+	go b.sampleCPU(ctx, u)
+}
+
+// sampleCPU records the CPU time of a container from the docker stats stream until
+// sampling is ended. The cgroup of a container is removed when it exits, so the CPU
+// time of exited containers can't be read when they are deleted. Docker reports zero
+// usage after the exit, which is why the highest sample is kept.
+func (b *ContainerBackend) sampleCPU(ctx context.Context, u *containerUsage) {
+	defer close(u.sampled)
+	ch := make(chan *docker.Stats)
+	go func() {
+		err := b.client.Stats(docker.StatsOptions{Context: ctx, ID: u.id, Stats: ch, Stream: true})
+		if err != nil && ctx.Err() == nil {
+			b.logger.Debug("can't sample container stats", "container", u.id[:8], "err", err)
+		}
+	}()
+	for s := range ch {
+		cpu := time.Duration(s.CPUStats.CPUUsage.TotalUsage)
+		b.statsMu.Lock()
+		if cpu > u.cpu {
+			u.cpu = cpu
+		}
+		b.statsMu.Unlock()
+	}
+}
+
+// trackStop records the CPU usage of a container that's about to be removed.
+func (b *ContainerBackend) trackStop(containerID string) {
+	// Containers may be referenced by their short ID here.
+	b.statsMu.Lock()
+	u, ok := b.running[containerID[:8]]
+	delete(b.running, containerID[:8])
+	b.statsMu.Unlock()
+	if !ok {
+		return
+	}
+	u.stop()
+	<-u.sampled
+	// The cgroup is more accurate than the last sample if the container is still running.
+	cpu := containerCPUTime(u.id)
+	b.statsMu.Lock()
+	if u.cpu > cpu {
+		cpu = u.cpu
+	}
+	b.stats.CPUSeconds += cpu.Seconds()
+	b.statsMu.Unlock()
+}
+
+// containerCPUTime reads the CPU time used by a container from its cgroup.
+// This only works when hive runs on the docker host. It returns zero if
+// the cgroup can't be found.
+func containerCPUTime(id string) time.Duration {
+	// cgroup v2 reports microseconds in cpu.stat. Podman containers are in
+	// machine.slice, or in the user slice for rootless podman.
+	dirs := []string{"system.slice/docker-" + id + ".scope", "docker/" + id, "machine.slice/libpod-" + id + ".scope"}
+	rootless, _ := filepath.Glob(filepath.Join("/sys/fs/cgroup", "user.slice", "user-*.slice", "user@*.service", "user.slice", "libpod-"+id+".scope"))
+	for _, dir := range rootless {
+		rel, _ := filepath.Rel("/sys/fs/cgroup", dir)
+		dirs = append(dirs, rel)
+	}
+	for _, dir := range dirs {
+		content, err := ioutil.ReadFile(filepath.Join("/sys/fs/cgroup", dir, "cpu.stat"))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if f := strings.Fields(line); len(f) == 2 && f[0] == "usage_usec" {
+				usec, _ := strconv.ParseInt(f[1], 10, 64)
+				return time.Duration(usec) * time.Microsecond
+			}
+		}
+	}
+	// cgroup v1 reports nanoseconds in cpuacct.usage.
+	for _, dir := range []string{"cpuacct", "cpu,cpuacct"} {
+		for _, sub := range []string{"docker/" + id, "machine.slice/libpod-" + id + ".scope"} {
+			content, err := ioutil.ReadFile(filepath.Join("/sys/fs/cgroup", dir, sub, "cpuacct.usage"))
+			if err != nil {
+				continue
+			}
+			nsec, _ := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+			return time.Duration(nsec)
+		}
+	}
+	return 0
+}
+
 // checkPort waits for the given TCP address to accept a connection.
 func checkPort(ctx context.Context, logger log15.Logger, addr string, notify chan<- struct{}) {
 	var (
@@ -218,6 +406,8 @@ func checkPort(ctx context.Context, logger log15.Logger, addr string, notify cha
 
 // DeleteContainer removes the given container. If the container is running, it is stopped.
 func (b *ContainerBackend) DeleteContainer(containerID string) error {
+	b.trackStop(containerID)
+	b.stopPortProxy(containerID)
 	b.logger.Debug("removing container", "container", containerID[:8])
 	err := b.client.RemoveContainer(docker.RemoveContainerOptions{ID: containerID, Force: true})
 	if err != nil {
@@ -226,13 +416,60 @@ func (b *ContainerBackend) DeleteContainer(containerID string) error {
 	return err
 }
 
+// PauseContainer suspends all processes in the given container.
+func (b *ContainerBackend) PauseContainer(containerID string) error {
+	b.logger.Debug("pausing container", "container", containerID[:8])
+	return b.client.PauseContainer(containerID)
+}
+
+// UnpauseContainer resumes a paused container.
+func (b *ContainerBackend) UnpauseContainer(containerID string) error {
+	b.logger.Debug("unpausing container", "container", containerID[:8])
+	return b.client.UnpauseContainer(containerID)
+}
+
+// Ping checks that the docker daemon is responding.
+func (b *ContainerBackend) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return b.client.PingWithContext(ctx)
+}
+
 // CreateNetwork creates a docker network.
-func (b *ContainerBackend) CreateNetwork(name string) (string, error) {
-	network, err := b.client.CreateNetwork(docker.CreateNetworkOptions{
+func (b *ContainerBackend) CreateNetwork(name string, opt libhive.NetworkOptions) (string, error) {
+	createOpts := docker.CreateNetworkOptions{
 		Name:           name,
 		CheckDuplicate: true,
 		Attachable:     true,
-	})
+		Labels:         b.config.Labels,
+	}
+	if opt.Subnet != "" {
+		if err := checkSubnet(opt.Subnet, nil); err != nil {
+			return "", err
+		}
+		createOpts.IPAM = &docker.IPAMOptions{
+			Driver: "default",
+			Config: []docker.IPAMConfig{{Subnet: opt.Subnet, Gateway: opt.Gateway}},
+		}
+	}
+	if opt.MTU != 0 {
+		createOpts.Options = map[string]interface{}{
+			"com.docker.network.driver.mtu": strconv.Itoa(opt.MTU),
+		}
+	}
+	network, err := b.client.CreateNetwork(createOpts)
+	if err != nil && opt.Subnet != "" && isPoolOverlap(err) {
+		// Docker allocates the subnet atomically, but doesn't say which network has
+		// the conflicting subnet. Find it, or retry if it has been removed meanwhile.
+		networks, listErr := b.client.ListNetworks()
+		if listErr != nil {
+			return "", err
+		}
+		if err := checkSubnet(opt.Subnet, networks); err != nil {
+			return "", err
+		}
+		network, err = b.client.CreateNetwork(createOpts)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -282,14 +519,39 @@ func (b *ContainerBackend) ContainerIP(containerID, networkID string) (net.IP, e
 			return net.ParseIP(network.IPAddress), nil
 		}
 	}
-	return nil, fmt.Errorf("network not found")
+	return nil, libhive.ErrNetworkNotFound
 }
 
-// ConnectContainer connects the given container to a network.
-func (b *ContainerBackend) ConnectContainer(containerID, networkID string) error {
-	return b.client.ConnectNetwork(networkID, docker.NetworkConnectionOptions{
-		Container: containerID,
+// ContainerNetworks returns the IP addresses of a container on all networks
+// it is connected to.
+func (b *ContainerBackend) ContainerNetworks(containerID string) (map[string]net.IP, error) {
+	details, err := b.client.InspectContainerWithOptions(docker.InspectContainerOptions{
+		ID: containerID,
 	})
+	if err != nil {
+		return nil, err
+	}
+	ips := make(map[string]net.IP, len(details.NetworkSettings.Networks))
+	for _, network := range details.NetworkSettings.Networks {
+		ips[network.NetworkID] = net.ParseIP(network.IPAddress)
+	}
+	return ips, nil
+}
+
+// ConnectContainer connects the given container to a network. A static IP address
+// can only be assigned on networks with a user-configured subnet.
+func (b *ContainerBackend) ConnectContainer(containerID, networkID string, ip net.IP) error {
+	opts := docker.NetworkConnectionOptions{Container: containerID}
+	if ip != nil {
+		endpoint := &docker.EndpointConfig{IPAMConfig: new(docker.EndpointIPAMConfig)}
+		if ip.To4() != nil {
+			endpoint.IPAMConfig.IPv4Address = ip.String()
+		} else {
+			endpoint.IPAMConfig.IPv6Address = ip.String()
+		}
+		opts.EndpointConfig = endpoint
+	}
+	return b.client.ConnectNetwork(networkID, opts)
 }
 
 // DisconnectContainer disconnects the given container from a network.
@@ -348,20 +610,17 @@ func (b *ContainerBackend) uploadFiles(ctx context.Context, id string, files map
 // runContainer attaches to the output streams of an existing container, then
 // starts executing the container and returns the CloseWaiter to allow the caller
 // to wait for termination.
-func (b *ContainerBackend) runContainer(ctx context.Context, logger log15.Logger, id, logfile string) (docker.CloseWaiter, error) {
-	var stream io.Writer
+func (b *ContainerBackend) runContainer(ctx context.Context, logger log15.Logger, id string, opt libhive.ContainerOptions) (docker.CloseWaiter, error) {
+	var stream, stdout, stderr io.Writer
 
 	// Redirect container output to logfile.
 	closer := newFileCloser(logger)
-	if logfile != "" {
-		if err := os.MkdirAll(filepath.Dir(logfile), 0755); err != nil {
-			return nil, err
-		}
-		log, err := os.OpenFile(logfile, os.O_WRONLY|os.O_CREATE|os.O_SYNC|os.O_TRUNC, 0644)
+	if opt.LogFile != "" {
+		log, err := openLogFile(closer, opt.LogFile)
 		if err != nil {
+			closer.closeFiles()
 			return nil, err
 		}
-		closer.addFile(log)
 		stream = log
 
 		// If console logging was requested, tee the output and tag it with the container id.
@@ -370,14 +629,34 @@ func (b *ContainerBackend) runContainer(ctx context.Context, logger log15.Logger
 			closer.addFile(prefixer)
 			stream = io.MultiWriter(log, prefixer)
 		}
+		stdout, stderr = stream, stream
+
+		// The output streams are also written to their own logs. The combined log
+		// still receives the output in order.
+		if opt.StdoutLogFile != "" {
+			log, err := openLogFile(closer, opt.StdoutLogFile)
+			if err != nil {
+				closer.closeFiles()
+				return nil, err
+			}
+			stdout = io.MultiWriter(stream, log)
+		}
+		if opt.StderrLogFile != "" {
+			log, err := openLogFile(closer, opt.StderrLogFile)
+			if err != nil {
+				closer.closeFiles()
+				return nil, err
+			}
+			stderr = io.MultiWriter(stream, log)
+		}
 	}
 
 	// Attach the output stream.
 	logger.Debug("attaching to container")
 	attach := docker.AttachToContainerOptions{Container: id}
 	if stream != nil {
-		attach.OutputStream = stream
-		attach.ErrorStream = stream
+		attach.OutputStream = stdout
+		attach.ErrorStream = stderr
 		attach.Stream = true
 		attach.Stdout = true
 		attach.Stderr = true
@@ -399,6 +678,23 @@ func (b *ContainerBackend) runContainer(ctx context.Context, logger log15.Logger
 	return closer, nil
 }
 
+// openLogFile creates a container log file. The file is closed by closer.
+func openLogFile(closer *fileCloser, logfile string) (io.Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(logfile), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(logfile, os.O_WRONLY|os.O_CREATE|os.O_SYNC|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// Compressed logs are compressed on the fly. The compressor
+	// must be closed before the file to write the last block.
+	log := libhive.NewLogWriter(logfile, file)
+	closer.addFile(log)
+	closer.addFile(file)
+	return log, nil
+}
+
 // fileCloser wraps a docker.CloseWaiter and closes all io.Closer instances held in it,
 // after it is done waiting.
 type fileCloser struct {
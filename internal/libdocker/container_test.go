@@ -0,0 +1,66 @@
+package libdocker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// This test checks that the CPU time of a container is counted when it has exited
+// before it is deleted.
+func TestContainerCPUStats(t *testing.T) {
+	const id = "0123456789abcdef"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/containers/"+id+"/stats") {
+			http.NotFound(w, r)
+			return
+		}
+		// Docker reports zero usage after the container has exited.
+		enc := json.NewEncoder(w)
+		for _, usage := range []uint64{1e9, 2e9, 0} {
+			var s docker.Stats
+			s.CPUStats.CPUUsage.TotalUsage = usage
+			enc.Encode(&s)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	client, err := docker.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewContainerBackend(client, &Config{})
+
+	b.trackStart(id)
+	u := b.running[id[:8]]
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		b.statsMu.Lock()
+		cpu := u.cpu
+		b.statsMu.Unlock()
+		if cpu == 2*time.Second {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("CPU time not sampled, have %v", cpu)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	b.trackStop(id[:8])
+
+	stats := b.Stats()
+	if stats.CPUSeconds != 2 {
+		t.Errorf("wrong CPU seconds %v, want 2", stats.CPUSeconds)
+	}
+	if stats.PeakConcurrent != 1 {
+		t.Errorf("wrong peak concurrent containers %d, want 1", stats.PeakConcurrent)
+	}
+	if len(b.running) != 0 {
+		t.Errorf("container still tracked after stop")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -13,6 +15,24 @@ import (
 	"gopkg.in/inconshreveable/log15.v2"
 )
 
+// Container engines supported by the backend. Podman is used through its
+// Docker-compatible API.
+const (
+	EngineDocker = "docker"
+	EnginePodman = "podman"
+)
+
+// DefaultPodmanEndpoint returns the API socket of podman. For rootless podman, this is
+// the socket of the user service, which is started by 'systemctl --user start podman.socket'.
+func DefaultPodmanEndpoint() string {
+	if os.Geteuid() != 0 {
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			return "unix://" + filepath.Join(dir, "podman", "podman.sock")
+		}
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
 // Config is the configuration of the docker backend.
 type Config struct {
 	Inventory libhive.Inventory
@@ -26,9 +46,44 @@ type Config struct {
 	// This forces pulling of base images when building clients and simulators.
 	PullEnabled bool
 
+	// CacheDir is the directory of the build cache. When set, images are not rebuilt
+	// if their build inputs are unchanged. ForceRebuild disables cache lookups, but
+	// still records the inputs of new builds.
+	CacheDir     string
+	ForceRebuild bool
+
+	// BuildCacheMounts are mounted into the RUN instructions of all image builds. Builds
+	// with cache mounts use BuildKit through the docker CLI.
+	BuildCacheMounts []libhive.BuildCacheMount
+
 	// These two are log destinations for output from docker.
 	ContainerOutput io.Writer
 	BuildOutput     io.Writer
+
+	// BuildLogDir is the directory where the output of image builds is stored, in
+	// files clients/<name>.log and simulators/<name>.log. Builds are not logged if
+	// it is empty. CompressBuildLogs makes the builder store the logs compressed.
+	BuildLogDir       string
+	CompressBuildLogs bool
+
+	// These labels are set on all images, containers and networks.
+	Labels map[string]string
+
+	// ProbeImage is the iperf3 image used for network probes.
+	// If empty, DefaultProbeImage is used.
+	ProbeImage string
+
+	// ProxyImage is the socat image used for client port proxies.
+	// If empty, DefaultProxyImage is used.
+	ProxyImage string
+
+	// NetemImage is the tc image used to apply network conditions to clients.
+	// If empty, DefaultNetemImage is used.
+	NetemImage string
+
+	// Engine is the container engine behind the endpoint, EngineDocker or EnginePodman.
+	// If empty, docker is assumed.
+	Engine string
 }
 
 func Connect(dockerEndpoint string, cfg *Config) (*Builder, *ContainerBackend, error) {
@@ -45,24 +100,78 @@ func Connect(dockerEndpoint string, cfg *Config) (*Builder, *ContainerBackend, e
 	if err != nil {
 		return nil, nil, fmt.Errorf("can't get docker version: %v", err)
 	}
-	logger.Debug("docker daemon online", "version", env.Get("Version"))
+	engine := cfg.Engine
+	if engine == "" {
+		engine = EngineDocker
+	}
+	logger.Debug("container engine online", "engine", engine, "version", env.Get("Version"))
+	if len(cfg.BuildCacheMounts) > 0 {
+		if err := checkCacheMounts(engine); err != nil {
+			return nil, nil, err
+		}
+	}
 	builder := NewBuilder(client, cfg)
+	builder.endpoint = dockerEndpoint
 	backend := NewContainerBackend(client, cfg)
 	return builder, backend, nil
 }
 
-// LookupBridgeIP attempts to locate the IPv4 address of the local docker0 bridge
-// network adapter.
-func LookupBridgeIP(logger log15.Logger) (net.IP, error) {
-	// Find the local IPv4 address of the docker0 bridge adapter
+// DaemonInfo describes the docker daemon.
+type DaemonInfo struct {
+	Version          string
+	APIVersion       string
+	OSType           string
+	RootDir          string // docker storage directory
+	IPv4Forwarding   bool
+	BridgeNfIptables bool
+}
+
+// Info connects to the docker daemon and returns information about it.
+func Info(dockerEndpoint string) (*DaemonInfo, error) {
+	client, err := docker.NewClient(dockerEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to docker: %v", err)
+	}
+	env, err := client.Version()
+	if err != nil {
+		return nil, fmt.Errorf("can't get docker version: %v", err)
+	}
+	info, err := client.Info()
+	if err != nil {
+		return nil, fmt.Errorf("can't get docker info: %v", err)
+	}
+	return &DaemonInfo{
+		Version:          env.Get("Version"),
+		APIVersion:       env.Get("ApiVersion"),
+		OSType:           info.OSType,
+		RootDir:          info.DockerRootDir,
+		IPv4Forwarding:   info.IPv4Forwarding,
+		BridgeNfIptables: info.BridgeNfIptables,
+	}, nil
+}
+
+// LookupBridgeIP attempts to locate the IPv4 address of the local bridge network adapter
+// of the container engine: docker0 for docker, and podman0 (netavark) or cni-podman0
+// (CNI) for podman.
+func LookupBridgeIP(logger log15.Logger, engine string) (net.IP, error) {
+	isBridge := func(name string) bool {
+		return name == "docker0" || strings.Contains(name, "vEthernet")
+	}
+	if engine == EnginePodman {
+		isBridge = func(name string) bool {
+			return name == "podman0" || name == "cni-podman0"
+		}
+	}
+
+	// Find the local IPv4 address of the bridge adapter
 	interfaces, err := net.Interfaces()
 	if err != nil {
 		logger.Error("failed to list network interfaces", "err", err)
 		return nil, err
 	}
-	// Iterate over all the interfaces and find the docker0 bridge
+	// Iterate over all the interfaces and find the bridge
 	for _, iface := range interfaces {
-		if iface.Name == "docker0" || strings.Contains(iface.Name, "vEthernet") {
+		if isBridge(iface.Name) {
 			// Retrieve all the addresses assigned to the bridge adapter
 			addrs, err := iface.Addrs()
 			if err != nil {
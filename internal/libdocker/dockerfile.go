@@ -0,0 +1,158 @@
+package libdocker
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+var (
+	dockerfileArgRE  = regexp.MustCompile(`(?i)^\s*ARG\s+([A-Za-z0-9_]+)(?:=(\S*))?`)
+	dockerfileFromRE = regexp.MustCompile(`(?i)^\s*FROM\s+(?:--\S+\s+)*(\S+)(?:\s+AS\s+(\S+))?`)
+	dockerfileVarRE  = regexp.MustCompile(`\$\{?([A-Za-z0-9_]+)\}?`)
+)
+
+// DockerfileBaseImages returns the external base images referenced by FROM instructions
+// in a Dockerfile of the given directory. The file name defaults to "Dockerfile". The
+// non-empty build arguments in args override the defaults of ARG instructions.
+func DockerfileBaseImages(dir, dockerfile string, args map[string]string) []string {
+	f, err := openDockerfile(dir, dockerfile)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var (
+		defaults = make(map[string]string)
+		stages   = make(map[string]bool)
+		bases    []string
+		scan     = bufio.NewScanner(f)
+	)
+	for scan.Scan() {
+		line := scan.Text()
+		if m := dockerfileArgRE.FindStringSubmatch(line); m != nil {
+			defaults[m[1]] = m[2]
+			continue
+		}
+		m := dockerfileFromRE.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		image := dockerfileVarRE.ReplaceAllStringFunc(m[1], func(v string) string {
+			name := dockerfileVarRE.FindStringSubmatch(v)[1]
+			if args[name] != "" {
+				return args[name]
+			}
+			return defaults[name]
+		})
+		if !stages[strings.ToLower(image)] {
+			bases = append(bases, image)
+		}
+		if m[2] != "" {
+			stages[strings.ToLower(m[2])] = true
+		}
+	}
+	return bases
+}
+
+var (
+	dockerfileEnvRE      = regexp.MustCompile(`(?i)^\s*ENV\s+([A-Za-z0-9_]+)(?:=|\s+)(\S*)`)
+	dockerfileGitCloneRE = regexp.MustCompile(`git\s+clone\s+[^&;|]*`)
+	gitBranchFlagRE      = regexp.MustCompile(`(?:--branch(?:=|\s+)|-b\s+)(\S+)`)
+	gitURLRE             = regexp.MustCompile(`(?:https?|git|ssh)://\S+|git@\S+`)
+)
+
+// gitSource is a git repository cloned by a Dockerfile.
+type gitSource struct {
+	URL string
+	Ref string // branch or tag, HEAD if not given
+}
+
+// dockerfileGitSources returns the repositories cloned by 'git clone' commands in a
+// Dockerfile of the given directory. Variables in the URL and branch are replaced with
+// the defaults of ARG and ENV instructions. The non-empty build arguments in args
+// override the defaults of ARG instructions.
+func dockerfileGitSources(dir, dockerfile string, args map[string]string) []gitSource {
+	f, err := openDockerfile(dir, dockerfile)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var (
+		vars    = make(map[string]string)
+		sources []gitSource
+		scan    = bufio.NewScanner(f)
+	)
+	expand := func(s string) string {
+		return dockerfileVarRE.ReplaceAllStringFunc(s, func(v string) string {
+			name := dockerfileVarRE.FindStringSubmatch(v)[1]
+			if args[name] != "" {
+				return args[name]
+			}
+			return vars[name]
+		})
+	}
+	for scan.Scan() {
+		line := scan.Text()
+		if m := dockerfileArgRE.FindStringSubmatch(line); m != nil {
+			vars[m[1]] = m[2]
+			continue
+		}
+		if m := dockerfileEnvRE.FindStringSubmatch(line); m != nil {
+			vars[m[1]] = m[2]
+			continue
+		}
+		for _, clone := range dockerfileGitCloneRE.FindAllString(line, -1) {
+			clone = expand(clone)
+			url := gitURLRE.FindString(clone)
+			if url == "" {
+				continue
+			}
+			src := gitSource{URL: url, Ref: "HEAD"}
+			if m := gitBranchFlagRE.FindStringSubmatch(clone); m != nil {
+				src.Ref = m[1]
+			}
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// openDockerfile opens a Dockerfile in dir. The file name defaults to "Dockerfile".
+func openDockerfile(dir, dockerfile string) (*os.File, error) {
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	return os.Open(filepath.Join(dir, filepath.FromSlash(dockerfile)))
+}
+
+var dockerfileRunRE = regexp.MustCompile(`(?i)^\s*RUN\s`)
+
+// addCacheMounts adds the given cache mounts to all RUN instructions of a Dockerfile.
+func addCacheMounts(dockerfile []byte, mounts []libhive.BuildCacheMount) []byte {
+	opts := make([]string, len(mounts))
+	for i, m := range mounts {
+		opts[i] = m.String()
+	}
+	var (
+		lines     = strings.Split(string(dockerfile), "\n")
+		continued bool
+	)
+	for i, line := range lines {
+		if !continued {
+			if loc := dockerfileRunRE.FindStringIndex(line); loc != nil {
+				lines[i] = line[:loc[1]] + strings.Join(opts, " ") + " " + line[loc[1]:]
+			}
+		}
+		// Instructions continue after lines ending in a backslash. Comments and
+		// empty lines within an instruction don't end it.
+		trimmed := strings.TrimSpace(line)
+		continued = strings.HasSuffix(trimmed, "\\") || (continued && (trimmed == "" || strings.HasPrefix(trimmed, "#")))
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,86 @@
+package libdocker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+func TestDockerfileBaseImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hive-build-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dockerfile := `ARG branch=latest
+FROM golang:1-alpine AS builder
+RUN go build .
+FROM --platform=linux/amd64 example/client:${branch}
+COPY --from=builder /sim /
+`
+	ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(dockerfile), 0644)
+
+	if bases := DockerfileBaseImages(dir, "", nil); !reflect.DeepEqual(bases, []string{"golang:1-alpine", "example/client:latest"}) {
+		t.Errorf("wrong base images %q", bases)
+	}
+	if bases := DockerfileBaseImages(dir, "", map[string]string{"branch": "v1.0"}); !reflect.DeepEqual(bases, []string{"golang:1-alpine", "example/client:v1.0"}) {
+		t.Errorf("wrong base images with branch %q", bases)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "minimal.Dockerfile"), []byte("ARG preset=mainnet\nFROM example/client:${preset}\n"), 0644)
+	if bases := DockerfileBaseImages(dir, "minimal.Dockerfile", map[string]string{"preset": "minimal"}); !reflect.DeepEqual(bases, []string{"example/client:minimal"}) {
+		t.Errorf("wrong base images of named Dockerfile %q", bases)
+	}
+}
+
+func TestDockerfileGitSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hive-build-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dockerfile := `FROM alpine:latest
+ARG branch=master
+ENV repo=https://github.com/example/client
+RUN git clone --depth 1 --branch ${branch} $repo && cd client && make
+RUN git clone https://github.com/example/tools.git /tools
+`
+	ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(dockerfile), 0644)
+
+	want := []gitSource{
+		{URL: "https://github.com/example/client", Ref: "master"},
+		{URL: "https://github.com/example/tools.git", Ref: "HEAD"},
+	}
+	if srcs := dockerfileGitSources(dir, "", nil); !reflect.DeepEqual(srcs, want) {
+		t.Errorf("wrong git sources %+v", srcs)
+	}
+	want[0].Ref = "v1.0"
+	if srcs := dockerfileGitSources(dir, "", map[string]string{"branch": "v1.0"}); !reflect.DeepEqual(srcs, want) {
+		t.Errorf("wrong git sources with branch %+v", srcs)
+	}
+}
+
+func TestAddCacheMounts(t *testing.T) {
+	dockerfile := `FROM golang:1-alpine
+RUN apk add git
+run go build . && \
+    # RUN in a comment
+    RUN=1 go test .
+RUN ["go", "vet"]
+`
+	mounts := []libhive.BuildCacheMount{{ID: "hive-go-mod", Target: "/go/pkg/mod"}}
+	want := `FROM golang:1-alpine
+RUN --mount=type=cache,id=hive-go-mod,target=/go/pkg/mod,sharing=locked apk add git
+run --mount=type=cache,id=hive-go-mod,target=/go/pkg/mod,sharing=locked go build . && \
+    # RUN in a comment
+    RUN=1 go test .
+RUN --mount=type=cache,id=hive-go-mod,target=/go/pkg/mod,sharing=locked ["go", "vet"]
+`
+	if got := string(addCacheMounts([]byte(dockerfile), mounts)); got != want {
+		t.Errorf("wrong Dockerfile:\n%s", got)
+	}
+}
@@ -0,0 +1,70 @@
+package libdocker
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ethereum/hive/internal/libhive"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// containerName returns the name of the n'th container created in a hive run. Names
+// contain the run ID, so containers of hive instances running concurrently on the
+// same host can be told apart. Without a run ID, docker picks a random name.
+func containerName(runID string, labels map[string]string, n uint64) string {
+	if runID == "" {
+		return ""
+	}
+	kind := "container"
+	switch {
+	case labels[libhive.LabelClient] != "":
+		kind = labels[libhive.LabelClient]
+	case labels[libhive.LabelSimulator] != "":
+		kind = "sim-" + labels[libhive.LabelSimulator]
+	}
+	return fmt.Sprintf("hive_%s_%s_%d", runID, dockerNameSafe(kind), n)
+}
+
+// dockerNameSafe replaces characters not allowed in docker object names.
+func dockerNameSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, s)
+}
+
+// isPoolOverlap reports whether network creation failed because the requested subnet
+// overlaps the subnet of another network.
+func isPoolOverlap(err error) bool {
+	return strings.Contains(err.Error(), "Pool overlaps")
+}
+
+// checkSubnet verifies that a subnet requested for a new network doesn't overlap the
+// subnets of existing networks. Docker rejects such networks with an error which doesn't
+// say what the conflict is. When hive instances run concurrently, the other network
+// usually belongs to another run, which is reported using the run ID label.
+func checkSubnet(subnet string, networks []docker.Network) error {
+	_, want, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return fmt.Errorf("invalid subnet %q: %v", subnet, err)
+	}
+	for _, network := range networks {
+		for _, cfg := range network.IPAM.Config {
+			_, have, err := net.ParseCIDR(cfg.Subnet)
+			if err != nil || !(have.Contains(want.IP) || want.Contains(have.IP)) {
+				continue
+			}
+			owner := ""
+			if run := network.Labels[libhive.LabelRunID]; run != "" {
+				owner = fmt.Sprintf(" of hive run %s", run)
+			}
+			return fmt.Errorf("subnet %s overlaps subnet %s of network %q%s", subnet, cfg.Subnet, network.Name, owner)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package libdocker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/hive/internal/libhive"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+func TestContainerName(t *testing.T) {
+	tests := []struct {
+		labels map[string]string
+		want   string
+	}{
+		{map[string]string{libhive.LabelClient: "go-ethereum_v1.10.8", libhive.LabelSimulator: "devp2p/discv4"}, "hive_1612356621-a9a2e71a_go-ethereum_v1.10.8_3"},
+		{map[string]string{libhive.LabelSimulator: "devp2p/discv4"}, "hive_1612356621-a9a2e71a_sim-devp2p-discv4_3"},
+		{nil, "hive_1612356621-a9a2e71a_container_3"},
+	}
+	for _, test := range tests {
+		if got := containerName("1612356621-a9a2e71a", test.labels, 3); got != test.want {
+			t.Errorf("wrong name for %v: got %q, want %q", test.labels, got, test.want)
+		}
+	}
+	if got := containerName("", nil, 1); got != "" {
+		t.Errorf("container without run ID has name %q", got)
+	}
+}
+
+func TestCheckSubnet(t *testing.T) {
+	networks := []docker.Network{
+		{Name: "bridge", IPAM: docker.IPAMOptions{Config: []docker.IPAMConfig{{Subnet: "172.17.0.0/16"}}}},
+		{
+			Name:   "hive_1612356621-a9a2e71a_devp2p-discv4_0_net1",
+			Labels: map[string]string{libhive.LabelRunID: "1612356621-a9a2e71a"},
+			IPAM:   docker.IPAMOptions{Config: []docker.IPAMConfig{{Subnet: "172.30.0.0/24"}}},
+		},
+	}
+	if err := checkSubnet("172.30.1.0/24", networks); err != nil {
+		t.Errorf("unexpected error for free subnet: %v", err)
+	}
+	err := checkSubnet("172.30.0.128/25", networks)
+	if err == nil || !strings.Contains(err.Error(), "hive run 1612356621-a9a2e71a") {
+		t.Errorf("wrong error for subnet of other run: %v", err)
+	}
+	err = checkSubnet("172.0.0.0/8", networks)
+	if err == nil || !strings.Contains(err.Error(), `"bridge"`) {
+		t.Errorf("wrong error for subnet containing bridge: %v", err)
+	}
+	if err := checkSubnet("172.30.0.0", networks); err == nil {
+		t.Error("no error for invalid subnet")
+	}
+}
+
+// This test checks that a network whose subnet conflicts with a network created
+// concurrently gets an error naming the other network, and that creation is retried
+// when the other network is already gone.
+func TestCreateNetworkOverlap(t *testing.T) {
+	var (
+		creates  int
+		networks []docker.Network
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/networks/create"):
+			creates++
+			if creates == 1 {
+				http.Error(w, `{"message":"Pool overlaps with other one on this address space"}`, http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"ID": "net2"})
+		case strings.HasSuffix(r.URL.Path, "/networks"):
+			json.NewEncoder(w).Encode(networks)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client, err := docker.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewContainerBackend(client, &Config{})
+	opt := libhive.NetworkOptions{Subnet: "172.30.0.0/24"}
+
+	networks = []docker.Network{{
+		Name:   "net1",
+		Labels: map[string]string{libhive.LabelRunID: "1612356621-a9a2e71a"},
+		IPAM:   docker.IPAMOptions{Config: []docker.IPAMConfig{{Subnet: "172.30.0.0/16"}}},
+	}}
+	_, err = b.CreateNetwork("net2", opt)
+	if err == nil || !strings.Contains(err.Error(), "hive run 1612356621-a9a2e71a") {
+		t.Errorf("wrong error for overlapping subnet: %v", err)
+	}
+
+	creates, networks = 0, nil
+	id, err := b.CreateNetwork("net2", opt)
+	if err != nil || id != "net2" || creates != 2 {
+		t.Errorf("network creation not retried: id %q, %d creates, err %v", id, creates, err)
+	}
+}
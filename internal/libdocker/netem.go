@@ -0,0 +1,95 @@
+package libdocker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/hive/internal/libhive"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// DefaultNetemImage is the image used to apply network conditions. It must contain
+// sh and tc from iproute2.
+const DefaultNetemImage = "gaiadocker/iproute2"
+
+// SetNetworkConditions applies network conditions to a container using tc/netem. The
+// rules are set by a helper container which shares the network stack of the container.
+// It needs the NET_ADMIN capability and the sch_netem kernel module on the docker host.
+func (b *ContainerBackend) SetNetworkConditions(ctx context.Context, containerID string, cond libhive.NetworkConditions) error {
+	image := b.config.NetemImage
+	if image == "" {
+		image = DefaultNetemImage
+	}
+	if err := b.ensureImage(ctx, image); err != nil {
+		return err
+	}
+	c, err := b.client.CreateContainer(docker.CreateContainerOptions{
+		Context: ctx,
+		Config: &docker.Config{
+			Image:      image,
+			Entrypoint: []string{"/bin/sh", "-c", netemScript(cond)},
+			Labels:     b.config.Labels,
+		},
+		HostConfig: &docker.HostConfig{
+			NetworkMode: "container:" + containerID,
+			CapAdd:      []string{"NET_ADMIN"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("can't create netem container: %v", err)
+	}
+	defer b.removeHelperContainer(c.ID)
+
+	if err := b.client.StartContainerWithContext(c.ID, nil, ctx); err != nil {
+		return fmt.Errorf("can't start netem container: %v", err)
+	}
+	exitCode, err := b.client.WaitContainerWithContext(c.ID, ctx)
+	if err != nil {
+		return fmt.Errorf("netem container failed: %v", err)
+	}
+	if exitCode != 0 {
+		var output bytes.Buffer
+		b.client.Logs(docker.LogsOptions{
+			Context:      ctx,
+			Container:    c.ID,
+			OutputStream: &output,
+			ErrorStream:  &output,
+			Stdout:       true,
+			Stderr:       true,
+		})
+		return fmt.Errorf("tc failed with exit code %d: %s", exitCode, strings.TrimSpace(output.String()))
+	}
+	b.logger.Debug("network conditions set", "container", containerID[:8], "netem", netemArgs(cond))
+	return nil
+}
+
+// netemScript returns the shell script which applies the conditions to all network
+// interfaces except loopback. Zero conditions remove the netem qdisc.
+func netemScript(cond libhive.NetworkConditions) string {
+	cmd := `tc qdisc del dev "$dev" root 2>/dev/null || true`
+	if !cond.IsZero() {
+		cmd = `tc qdisc replace dev "$dev" root netem ` + netemArgs(cond)
+	}
+	return `set -e; for dev in $(ls /sys/class/net); do [ "$dev" = lo ] && continue; ` + cmd + `; done`
+}
+
+// netemArgs returns the tc netem parameters of the conditions.
+func netemArgs(cond libhive.NetworkConditions) string {
+	var args []string
+	if cond.Latency > 0 {
+		args = append(args, "delay", fmt.Sprintf("%dus", cond.Latency.Microseconds()))
+		if cond.Jitter > 0 {
+			args = append(args, fmt.Sprintf("%dus", cond.Jitter.Microseconds()))
+		}
+	}
+	if cond.Loss > 0 {
+		args = append(args, "loss", strconv.FormatFloat(cond.Loss, 'f', -1, 64)+"%")
+	}
+	if cond.Rate > 0 {
+		args = append(args, "rate", strconv.FormatUint(cond.Rate, 10)+"bit")
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,26 @@
+package libdocker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+func TestNetemArgs(t *testing.T) {
+	tests := []struct {
+		cond libhive.NetworkConditions
+		want string
+	}{
+		{libhive.NetworkConditions{Latency: 100 * time.Millisecond}, "delay 100000us"},
+		{libhive.NetworkConditions{Latency: time.Second, Jitter: 50 * time.Millisecond}, "delay 1000000us 50000us"},
+		{libhive.NetworkConditions{Loss: 0.5}, "loss 0.5%"},
+		{libhive.NetworkConditions{Rate: 1000000}, "rate 1000000bit"},
+		{libhive.NetworkConditions{Latency: time.Millisecond, Loss: 10, Rate: 8}, "delay 1000us loss 10% rate 8bit"},
+	}
+	for _, test := range tests {
+		if got := netemArgs(test.cond); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.cond, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package libdocker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// DefaultProbeImage is the image used for network probes. Its entry point must be iperf3.
+const DefaultProbeImage = "networkstatic/iperf3"
+
+// probePort is the TCP port of the probe server. It is chosen to avoid conflicts
+// with ports opened by clients, since the server shares the client's network stack.
+const probePort = "45201"
+
+// ProbeNetwork measures the network between two containers using iperf3. The iperf3
+// server and client run in helper containers which share the network namespace of the
+// 'to' and 'from' containers, so the measurement covers the same path as the traffic
+// between the containers.
+func (b *ContainerBackend) ProbeNetwork(ctx context.Context, from, to, toIP string, duration time.Duration) (*libhive.NetworkProbe, error) {
+	image := b.config.ProbeImage
+	if image == "" {
+		image = DefaultProbeImage
+	}
+	if err := b.ensureImage(ctx, image); err != nil {
+		return nil, err
+	}
+
+	// Start the server. It keeps serving until it is removed, so client attempts which
+	// fail after connecting can be retried.
+	server, err := b.createProbeContainer(ctx, image, to, "-s", "-p", probePort)
+	if err != nil {
+		return nil, err
+	}
+	defer b.removeHelperContainer(server)
+	if err := b.client.StartContainerWithContext(server, nil, ctx); err != nil {
+		return nil, fmt.Errorf("can't start probe server: %v", err)
+	}
+
+	// Run the client. The server may not be listening yet right after starting, so
+	// connection failures are retried a few times.
+	secs := strconv.Itoa(int(duration.Round(time.Second) / time.Second))
+	for attempt := 0; ; attempt++ {
+		output, err := b.runProbeClient(ctx, image, from, "-c", toIP, "-p", probePort, "-t", secs, "-J")
+		if err != nil {
+			return nil, err
+		}
+		result, err := parseIperfResult(output)
+		if err == nil || attempt == 4 {
+			return result, err
+		}
+		b.logger.Debug("network probe failed, retrying", "from", from[:8], "to", to[:8], "error", err)
+		select {
+		case <-time.After(500 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// runProbeClient runs the iperf3 client and returns its output.
+func (b *ContainerBackend) runProbeClient(ctx context.Context, image, netContainer string, args ...string) ([]byte, error) {
+	id, err := b.createProbeContainer(ctx, image, netContainer, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer b.removeHelperContainer(id)
+
+	if err := b.client.StartContainerWithContext(id, nil, ctx); err != nil {
+		return nil, fmt.Errorf("can't start probe client: %v", err)
+	}
+	if _, err := b.client.WaitContainerWithContext(id, ctx); err != nil {
+		return nil, fmt.Errorf("probe client failed: %v", err)
+	}
+	var output bytes.Buffer
+	err = b.client.Logs(docker.LogsOptions{
+		Context:      ctx,
+		Container:    id,
+		OutputStream: &output,
+		ErrorStream:  ioutil.Discard,
+		Stdout:       true,
+		Stderr:       true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read probe output: %v", err)
+	}
+	return output.Bytes(), nil
+}
+
+// createProbeContainer creates a container in the network namespace of netContainer.
+func (b *ContainerBackend) createProbeContainer(ctx context.Context, image, netContainer string, args ...string) (string, error) {
+	c, err := b.client.CreateContainer(docker.CreateContainerOptions{
+		Context: ctx,
+		Config: &docker.Config{
+			Image:  image,
+			Cmd:    args,
+			Labels: b.config.Labels,
+		},
+		HostConfig: &docker.HostConfig{
+			NetworkMode: "container:" + netContainer,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't create probe container: %v", err)
+	}
+	return c.ID, nil
+}
+
+func (b *ContainerBackend) removeHelperContainer(id string) {
+	err := b.client.RemoveContainer(docker.RemoveContainerOptions{ID: id, Force: true, RemoveVolumes: true})
+	if err != nil {
+		b.logger.Error("can't remove helper container", "container", id[:8], "error", err)
+	}
+}
+
+// ensureImage pulls the given image if it isn't available locally.
+func (b *ContainerBackend) ensureImage(ctx context.Context, image string) error {
+	_, err := b.client.InspectImage(image)
+	if err == nil {
+		return nil
+	}
+	if err != docker.ErrNoSuchImage {
+		return err
+	}
+	repo, tag := image, "latest"
+	if colon := strings.LastIndexByte(image, ':'); colon > strings.LastIndexByte(image, '/') {
+		repo, tag = image[:colon], image[colon+1:]
+	}
+	b.logger.Info("pulling helper image", "image", image)
+	opts := docker.PullImageOptions{Context: ctx, Repository: repo, Tag: tag}
+	if err := b.client.PullImage(opts, docker.AuthConfiguration{}); err != nil {
+		return fmt.Errorf("can't pull %s: %v", image, err)
+	}
+	return nil
+}
+
+// iperfResult is the relevant part of the iperf3 JSON output.
+type iperfResult struct {
+	Error string `json:"error"`
+	End   struct {
+		SumReceived struct {
+			BitsPerSecond float64 `json:"bits_per_second"`
+		} `json:"sum_received"`
+		Streams []struct {
+			Sender struct {
+				MeanRTT int64 `json:"mean_rtt"` // microseconds
+				MinRTT  int64 `json:"min_rtt"`
+				MaxRTT  int64 `json:"max_rtt"`
+			} `json:"sender"`
+		} `json:"streams"`
+	} `json:"end"`
+}
+
+func parseIperfResult(output []byte) (*libhive.NetworkProbe, error) {
+	var r iperfResult
+	if err := json.Unmarshal(output, &r); err != nil {
+		return nil, fmt.Errorf("invalid iperf3 output: %v", err)
+	}
+	if r.Error != "" {
+		return nil, fmt.Errorf("iperf3: %s", r.Error)
+	}
+	probe := &libhive.NetworkProbe{BitsPerSecond: r.End.SumReceived.BitsPerSecond}
+	if len(r.End.Streams) > 0 {
+		s := r.End.Streams[0].Sender
+		probe.RTT = time.Duration(s.MeanRTT) * time.Microsecond
+		probe.MinRTT = time.Duration(s.MinRTT) * time.Microsecond
+		probe.MaxRTT = time.Duration(s.MaxRTT) * time.Microsecond
+	}
+	return probe, nil
+}
@@ -0,0 +1,77 @@
+package libdocker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// DefaultProxyImage is the image used for port proxies. It must contain sh and socat.
+const DefaultProxyImage = "alpine/socat"
+
+// startPortProxy starts a helper container which forwards ports of a client container
+// to other ports of the same container. Like network probes, the proxy shares the
+// client's network stack, so the forwarded ports are reachable at the client IP.
+func (b *ContainerBackend) startPortProxy(ctx context.Context, containerID string, ports map[uint16]uint16) error {
+	image := b.config.ProxyImage
+	if image == "" {
+		image = DefaultProxyImage
+	}
+	if err := b.ensureImage(ctx, image); err != nil {
+		return err
+	}
+	c, err := b.client.CreateContainer(docker.CreateContainerOptions{
+		Context: ctx,
+		Config: &docker.Config{
+			Image:      image,
+			Entrypoint: []string{"/bin/sh", "-c", portProxyScript(ports)},
+			Labels:     b.config.Labels,
+		},
+		HostConfig: &docker.HostConfig{
+			NetworkMode: "container:" + containerID,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("can't create port proxy: %v", err)
+	}
+	if err := b.client.StartContainerWithContext(c.ID, nil, ctx); err != nil {
+		b.removeHelperContainer(c.ID)
+		return fmt.Errorf("can't start port proxy: %v", err)
+	}
+
+	b.proxyMu.Lock()
+	b.proxies[containerID] = c.ID
+	b.proxyMu.Unlock()
+	b.logger.Debug("port proxy started", "container", containerID[:8], "proxy", c.ID[:8], "ports", ports)
+	return nil
+}
+
+// stopPortProxy removes the port proxy of a client container, if there is one.
+func (b *ContainerBackend) stopPortProxy(containerID string) {
+	b.proxyMu.Lock()
+	id, ok := b.proxies[containerID]
+	delete(b.proxies, containerID)
+	b.proxyMu.Unlock()
+	if ok {
+		b.removeHelperContainer(id)
+	}
+}
+
+// portProxyScript returns the shell script run by the proxy container. It starts
+// one socat instance per port.
+func portProxyScript(ports map[uint16]uint16) string {
+	listen := make([]int, 0, len(ports))
+	for port := range ports {
+		listen = append(listen, int(port))
+	}
+	sort.Ints(listen)
+	var script strings.Builder
+	for _, port := range listen {
+		fmt.Fprintf(&script, "socat TCP-LISTEN:%d,fork,reuseaddr TCP:127.0.0.1:%d & ", port, ports[uint16(port)])
+	}
+	script.WriteString("wait")
+	return script.String()
+}
@@ -1,6 +1,7 @@
 package libhive
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,6 +10,7 @@ import (
 	"mime/multipart"
 	"net"
 	"net/http"
+	"os"
 	"path"
 	"path/filepath"
 	"sort"
@@ -28,6 +30,16 @@ const hiveEnvvarPrefix = "HIVE_"
 // This is the default timeout for starting clients.
 const defaultStartTimeout = time.Duration(60 * time.Second)
 
+// This is the delay before the first client start retry. It doubles for every
+// subsequent retry.
+var startRetryBackoff = 1 * time.Second
+
+// These are the bounds for the MTU of networks created by simulators.
+const (
+	minNetworkMTU = 576
+	maxNetworkMTU = 65535
+)
+
 // newSimulationAPI creates handlers for the simulation API.
 func newSimulationAPI(b ContainerBackend, env SimEnv, tm *TestManager) http.Handler {
 	api := &simAPI{backend: b, env: env, tm: tm}
@@ -35,55 +47,139 @@ func newSimulationAPI(b ContainerBackend, env SimEnv, tm *TestManager) http.Hand
 	// API routes.
 	router := mux.NewRouter()
 	router.HandleFunc("/clients", api.getClientTypes).Methods("GET")
+	router.HandleFunc("/inventory", api.getInventory).Methods("GET")
 	router.HandleFunc("/testsuite/{suite}/test/{test}/node/{node}/exec", api.execInClient).Methods("POST")
+	router.HandleFunc("/testsuite/{suite}/test/{test}/node/{node}/logs", api.getClientLogs).Methods("GET")
+	router.HandleFunc("/testsuite/{suite}/test/{test}/node/{node}/pause", api.pauseClient).Methods("POST")
+	router.HandleFunc("/testsuite/{suite}/test/{test}/node/{node}/pause", api.unpauseClient).Methods("DELETE")
+	router.HandleFunc("/testsuite/{suite}/test/{test}/node/{node}/netem", api.setNetworkConditions).Methods("POST")
+	router.HandleFunc("/testsuite/{suite}/test/{test}/node/{node}/netem", api.clearNetworkConditions).Methods("DELETE")
 	router.HandleFunc("/testsuite/{suite}/test/{test}/node/{node}", api.getEnodeURL).Methods("GET")
 	router.HandleFunc("/testsuite/{suite}/test/{test}/node", api.startClient).Methods("POST")
+	router.HandleFunc("/testsuite/{suite}/test/{test}/netprobe", api.probeNetwork).Methods("POST")
 	router.HandleFunc("/testsuite/{suite}/test/{test}/node/{node}", api.stopClient).Methods("DELETE")
 	router.HandleFunc("/testsuite/{suite}/test", api.startTest).Methods("POST")
 	// post because the delete http verb does not always support a message body
 	router.HandleFunc("/testsuite/{suite}/test/{test}", api.endTest).Methods("POST")
+	router.HandleFunc("/testsuite/{suite}/test/{test}/retry", api.retryTest).Methods("POST")
 	router.HandleFunc("/testsuite", api.startSuite).Methods("POST")
 	router.HandleFunc("/testsuite/{suite}", api.endSuite).Methods("DELETE")
+	router.HandleFunc("/testsuite/{suite}/order", api.orderTests).Methods("POST")
+	router.HandleFunc("/testsuite/{suite}/plan", api.planTests).Methods("POST")
 	router.HandleFunc("/testsuite/{suite}/network/{network}", api.networkCreate).Methods("POST")
 	router.HandleFunc("/testsuite/{suite}/network/{network}", api.networkRemove).Methods("DELETE")
 	router.HandleFunc("/testsuite/{suite}/network/{network}/{node}", api.networkIPGet).Methods("GET")
 	router.HandleFunc("/testsuite/{suite}/network/{network}/{node}", api.networkConnect).Methods("POST")
 	router.HandleFunc("/testsuite/{suite}/network/{network}/{node}", api.networkDisconnect).Methods("DELETE")
+	router.HandleFunc("/testsuite/{suite}/node/{node}/networks", api.nodeNetworks).Methods("GET")
+	router.HandleFunc("/simulator/params", api.getParams).Methods("GET")
+	router.HandleFunc("/simulator/params", api.declareParams).Methods("POST")
+	if env.ParamsOnly {
+		return paramsOnly(router, tm)
+	}
 	return router
 }
 
+// paramsOnly rejects all requests except those declaring the parameters of the
+// simulator. Simulators which don't declare parameters fail on their first request.
+// The test manager is notified when the parameters are declared or another request
+// arrives.
+func paramsOnly(h http.Handler, tm *TestManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simulator/params" {
+			writeError(w, http.StatusServiceUnavailable, &Error{Code: CodeInvalidRequest, Message: "hive is reading the simulator parameters, no tests can run"})
+			tm.endParamsOnly()
+			return
+		}
+		h.ServeHTTP(w, r)
+		if r.Method == http.MethodPost {
+			tm.endParamsOnly()
+		}
+	})
+}
+
 type simAPI struct {
 	backend ContainerBackend
 	env     SimEnv
 	tm      *TestManager
 }
 
-// getClientTypes returns all known client types.
+// getClientTypes returns all known client types. The clients can be filtered by
+// role and supported fork using the 'role' and 'fork' query parameters.
 func (api *simAPI) getClientTypes(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if r.URL.Query().Get("metadata") != "" {
-		// New-style response with metadata included.
-		clients := make([]*ClientDefinition, 0, len(api.env.Definitions))
-		for _, def := range api.env.Definitions {
-			clients = append(clients, def)
+	var (
+		query   = r.URL.Query()
+		role    = query.Get("role")
+		fork    = query.Get("fork")
+		clients = make([]*ClientDefinition, 0, len(api.env.Definitions))
+	)
+	for _, def := range api.env.Definitions {
+		if role != "" && !def.Meta.HasRole(role) {
+			continue
+		}
+		if fork != "" && !def.Meta.SupportsFork(fork) {
+			continue
 		}
-		sort.Slice(clients, func(i, j int) bool { return clients[i].Name < clients[j].Name })
+		clients = append(clients, def)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Name < clients[j].Name })
+
+	if query.Get("metadata") != "" {
+		// New-style response with metadata included.
 		json.NewEncoder(w).Encode(clients)
 	} else {
-		clients := make([]string, 0, len(api.env.Definitions))
-		for name := range api.env.Definitions {
-			clients = append(clients, name)
+		names := make([]string, len(clients))
+		for i, def := range clients {
+			names[i] = def.Name
 		}
-		sort.Strings(clients)
-		json.NewEncoder(w).Encode(clients)
+		json.NewEncoder(w).Encode(names)
+	}
+}
+
+// InventoryResponse is the response of the /inventory endpoint.
+type InventoryResponse struct {
+	Clients    []InventoryClient `json:"clients"`
+	Simulators []string          `json:"simulators"`
+}
+
+// getInventory returns all clients and simulators available in the hive directory.
+// Unlike /clients, this includes clients which were not built for the current run.
+func (api *simAPI) getInventory(w http.ResponseWriter, r *http.Request) {
+	if api.env.Inventory == nil {
+		http.Error(w, "inventory not available", http.StatusNotFound)
+		return
+	}
+	inv, err := api.env.Inventory.Inventory()
+	if err != nil {
+		log15.Error("API: can't load inventory", "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
 	}
+
+	catalog, err := inv.Catalog()
+	if err != nil {
+		log15.Error("API: can't read inventory metadata", "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := InventoryResponse{
+		Clients:    catalog.Clients,
+		Simulators: make([]string, len(catalog.Simulators)),
+	}
+	for i, sim := range catalog.Simulators {
+		resp.Simulators[i] = sim.Name
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&resp)
 }
 
 // startSuite starts a suite.
 func (api *simAPI) startSuite(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -92,7 +188,7 @@ func (api *simAPI) startSuite(w http.ResponseWriter, r *http.Request) {
 	suiteID, err := api.tm.StartTestSuite(name, desc)
 	if err != nil {
 		log15.Error("API: StartTestSuite failed", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
 	}
 	log15.Info("API: suite started", "suite", suiteID, "name", name)
 	fmt.Fprintf(w, "%d", suiteID)
@@ -102,12 +198,12 @@ func (api *simAPI) startSuite(w http.ResponseWriter, r *http.Request) {
 func (api *simAPI) endSuite(w http.ResponseWriter, r *http.Request) {
 	suiteID, err := api.requestSuite(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 	if err := api.tm.EndTestSuite(suiteID); err != nil {
 		log15.Error("API: EndTestSuite failed", "suite", suiteID, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 	log15.Info("API: suite ended", "suite", suiteID)
@@ -117,19 +213,32 @@ func (api *simAPI) endSuite(w http.ResponseWriter, r *http.Request) {
 func (api *simAPI) startTest(w http.ResponseWriter, r *http.Request) {
 	suiteID, err := api.requestSuite(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
+	if err := api.env.HostGuard.Wait(r.Context()); err != nil {
+		writeError(w, http.StatusServiceUnavailable, hostResourceError("can't start test case", err))
+		return
+	}
+	var timeout time.Duration
+	if v := r.Form.Get("timeout"); v != "" {
+		seconds, err := strconv.ParseFloat(v, 64)
+		if err != nil || seconds < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid timeout %q", v))
+			return
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
 	name := r.Form.Get("name")
-	testID, err := api.tm.StartTest(suiteID, name, r.Form.Get("description"))
+	testID, err := api.tm.StartTest(suiteID, name, r.Form.Get("description"), timeout)
 	if err != nil {
-		msg := fmt.Sprintf("can't start test case: %s", err.Error())
-		http.Error(w, msg, http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, wrapError("can't start test case", err))
+		return
 	}
 	log15.Info("API: test started", "suite", suiteID, "test", testID, "name", name)
 	fmt.Fprintf(w, "%d", testID)
@@ -140,11 +249,11 @@ func (api *simAPI) startTest(w http.ResponseWriter, r *http.Request) {
 func (api *simAPI) endTest(w http.ResponseWriter, r *http.Request) {
 	suiteID, testID, err := api.requestSuiteAndTest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -160,39 +269,68 @@ func (api *simAPI) endTest(w http.ResponseWriter, r *http.Request) {
 		}
 		log15.Error("API: EndTest failed", "suite", suiteID, "test", testID, "error", err)
 		if !responseWritten {
-			msg := fmt.Sprintf("can't end test case: %v", err)
-			http.Error(w, msg, http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, wrapError("can't end test case", err))
 		}
 	}()
 
 	// Summary is required.
 	summaryData := r.Form.Get("summaryresult")
 	if summaryData == "" {
-		http.Error(w, "missing 'summaryresult' in request", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: "missing 'summaryresult' in request"})
 		responseWritten = true
 		return
 	}
 	if err = json.Unmarshal([]byte(summaryData), &summary); err != nil {
 		log15.Error("API: invalid summary data in endTest", "test", testID, "error", err)
-		msg := fmt.Sprintf("can't unmarshal 'summaryresult': %v", err)
-		http.Error(w, msg, http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: "can't unmarshal 'summaryresult'", Err: err})
 		responseWritten = true
 		return
 	}
 }
 
+// retryTest ends a failed attempt of a test case. The test case keeps running,
+// and the simulator runs the test again.
+func (api *simAPI) retryTest(w http.ResponseWriter, r *http.Request) {
+	suiteID, testID, err := api.requestSuiteAndTest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	summaryData := r.FormValue("summaryresult")
+	if summaryData == "" {
+		writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: "missing 'summaryresult' in request"})
+		return
+	}
+	var result TestResult
+	if err := json.Unmarshal([]byte(summaryData), &result); err != nil {
+		writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: "can't unmarshal 'summaryresult'", Err: err})
+		return
+	}
+
+	switch err := api.tm.RetryTest(suiteID, testID, &result); {
+	case errors.Is(err, ErrNoSuchTestCase):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrNoRetry):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		log15.Info("API: retrying test", "suite", suiteID, "test", testID)
+	}
+}
+
 // startClient starts a client container.
 func (api *simAPI) startClient(w http.ResponseWriter, r *http.Request) {
 	suiteID, testID, err := api.requestSuiteAndTest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
 	// Client launch parameters are given as multipart/form-data.
 	if err := r.ParseMultipartForm((1 << 10) * 4); err != nil {
 		log15.Error("API: could not parse node request", "error", err)
-		http.Error(w, "could not parse node request", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: "could not parse node request"})
 		return
 	}
 	files := make(map[string]*multipart.FileHeader)
@@ -223,38 +361,165 @@ func (api *simAPI) startClient(w http.ResponseWriter, r *http.Request) {
 	if timeout == 0 {
 		timeout = defaultStartTimeout
 	}
-	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+
+	// by default: check the eth1 port
+	checkLive := uint16(StandardRPCPort)
+	if portStr := env["HIVE_CHECK_LIVE_PORT"]; portStr != "" {
+		v, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			log15.Error("API: could not parse check-live port", "error", err)
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		checkLive = uint16(v)
+	}
+	// Clients with nonstandard ports get a proxy for the standard ports.
+	// The proxy accepts connections right away, so the check must go to the client.
+	proxy := clientDef.Meta.Ports.ProxyPorts()
+	if port, ok := proxy[checkLive]; ok {
+		checkLive = port
+	}
+
+	// Wait for host resources before starting the container.
+	if err := api.env.HostGuard.Wait(r.Context()); err != nil {
+		log15.Error("API: client start refused", "client", clientDef.Name, "error", err)
+		writeError(w, http.StatusServiceUnavailable, hostResourceError("client start refused", err))
+		return
+	}
+
+	// Account for the container. The quota is given back if the client
+	// doesn't start.
+	if err := api.tm.quotas.acquireContainer(time.Now()); err != nil {
+		log15.Error("API: client quota exceeded", "client", clientDef.Name, "error", err)
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	started := false
+	defer func() {
+		if !started {
+			api.tm.quotas.releaseContainer()
+		}
+	}()
+
+	// Start it! If the client doesn't come up, it is retried with exponential
+	// backoff up to the configured number of times.
+	var (
+		info     *ContainerInfo
+		attempts []*startAttempt
+		backoff  = startRetryBackoff
+	)
+	for i := 0; i <= api.env.ClientStartRetries; i++ {
+		if i > 0 {
+			log15.Warn("API: retrying client start", "client", clientDef.Name, "attempt", i+1, "backoff", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-r.Context().Done():
+				writeError(w, http.StatusInternalServerError, &Error{Code: CodeClientStartFailed, Message: "client start aborted", Retry: true, Err: r.Context().Err()})
+				return
+			}
+			backoff *= 2
+		}
+		options := ContainerOptions{
+			Env:       env,
+			Files:     files,
+			CheckLive: checkLive,
+			PortProxy: proxy,
+			Labels:    api.clientLabels(suiteID, clientDef),
+			Resources: api.env.ClientResources.Override(clientDef.Meta.Resources),
+		}
+		attempt := api.startClientContainer(r.Context(), suiteID, testID, clientDef, options, timeout)
+		if attempt.createErr != nil {
+			log15.Error("API: client container create failed", "client", clientDef.Name, "error", attempt.createErr)
+			writeError(w, http.StatusInternalServerError, &Error{
+				Code:    CodeContainerFailed,
+				Message: "client container create failed",
+				Context: map[string]string{"client": clientDef.Name},
+				Err:     attempt.createErr,
+			})
+			return
+		}
+		if attempt.suiteEnded {
+			writeError(w, http.StatusNotFound, ErrNoSuchTestSuite)
+			return
+		}
+		attempts = append(attempts, attempt)
+		if attempt.err == nil {
+			info = attempt.info
+			break
+		}
+		log15.Error("API: could not start client", "client", clientDef.Name, "container", attempt.containerID[:8], "error", attempt.err)
+	}
+	if info == nil {
+		diag := diagnoseStartFailure(attempts)
+		log15.Error("API: client did not start", "client", clientDef.Name, "attempts", len(attempts), "diagnosis", diag.Kind)
+		writeError(w, http.StatusInternalServerError, &Error{
+			Code:    CodeClientStartFailed,
+			Message: "client did not start: " + diag.String(),
+			Context: map[string]string{
+				"client":   clientDef.Name,
+				"kind":     string(diag.Kind),
+				"attempts": strconv.Itoa(diag.Attempts),
+			},
+			Hint: diag.Kind.hint(),
+		})
+		return
+	}
+	started = true
+	log15.Info("API: client "+clientDef.Name+" started", "suite", suiteID, "test", testID, "container", info.ID[:8])
+	fmt.Fprintf(w, "%s@%s@%s", info.ID, info.IP, info.MAC)
+}
+
+// clientLabels returns the docker labels of a client container.
+func (api *simAPI) clientLabels(suiteID TestSuiteID, clientDef *ClientDefinition) map[string]string {
+	labels := map[string]string{
+		LabelRunID:     api.env.RunID,
+		LabelSimulator: api.tm.simName,
+		LabelClient:    clientDef.Name,
+	}
+	if suite, ok := api.tm.IsTestSuiteRunning(suiteID); ok {
+		labels[LabelSuite] = suite.Name
+	}
+	return labels
+}
+
+// startAttempt is the outcome of a single client container launch.
+type startAttempt struct {
+	containerID string
+	logFile     string
+	info        *ContainerInfo
+	err         error
+
+	createErr  error // set when the container could not be created
+	suiteEnded bool  // set when the test suite is no longer running
+}
+
+// startClientContainer creates and starts a client container and registers it
+// with the test.
+func (api *simAPI) startClientContainer(ctx context.Context, suiteID TestSuiteID, testID TestID, clientDef *ClientDefinition, options ContainerOptions, timeout time.Duration) *startAttempt {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Create the client container.
-	options := ContainerOptions{Env: env, Files: files}
 	containerID, err := api.backend.CreateContainer(ctx, clientDef.Image, options)
 	if err != nil {
-		log15.Error("API: client container create failed", "client", clientDef.Name, "error", err)
-		http.Error(w, "client container create failed: "+err.Error(), http.StatusInternalServerError)
-		return
+		return &startAttempt{createErr: err}
 	}
 
 	// Set the log file. We need the container ID for this,
 	// so it can only be set after creating the container.
-	logPath, logFilePath := api.clientLogFilePaths(clientDef.Name, containerID)
+	logPath, logFilePath := api.clientLogFilePaths(clientDef.Name, containerID, "")
+	stdoutPath, stdoutFilePath := api.clientLogFilePaths(clientDef.Name, containerID, "stdout")
+	stderrPath, stderrFilePath := api.clientLogFilePaths(clientDef.Name, containerID, "stderr")
 	options.LogFile = logFilePath
-
-	// by default: check the eth1 port
-	options.CheckLive = 8545
-	if portStr := env["HIVE_CHECK_LIVE_PORT"]; portStr != "" {
-		v, err := strconv.ParseUint(portStr, 10, 16)
-		if err != nil {
-			log15.Error("API: could not parse check-live port", "error", err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		options.CheckLive = uint16(v)
+	if !api.env.NoStreamLogs {
+		options.StdoutLogFile = stdoutFilePath
+		options.StderrLogFile = stderrFilePath
 	}
 
 	// Start it!
-	info, err := api.backend.StartContainer(ctx, containerID, options)
-	if info != nil {
+	attempt := &startAttempt{containerID: containerID, logFile: logFilePath}
+	attempt.info, attempt.err = api.backend.StartContainer(ctx, containerID, options)
+	if info := attempt.info; info != nil {
 		clientInfo := &ClientInfo{
 			ID:             info.ID,
 			IP:             info.IP,
@@ -263,13 +528,20 @@ func (api *simAPI) startClient(w http.ResponseWriter, r *http.Request) {
 			LogFile:        logPath,
 			wait:           info.Wait,
 		}
+		// Only the stream logs written by the backend are listed.
+		if info.StdoutLogFile != "" {
+			clientInfo.StdoutLogFile = stdoutPath
+		}
+		if info.StderrLogFile != "" {
+			clientInfo.StderrLogFile = stderrPath
+		}
 		api.tm.testSuiteMutex.Lock()
 
 		// log client version in test suite
 		if suite, ok := api.tm.runningTestSuites[suiteID]; !ok {
-			http.Error(w, ErrNoSuchTestSuite.Error(), http.StatusNotFound)
 			api.tm.testSuiteMutex.Unlock()
-			return
+			attempt.suiteEnded = true
+			return attempt
 		} else {
 			suite.ClientVersions[clientDef.Name] = clientDef.Version
 		}
@@ -278,22 +550,21 @@ func (api *simAPI) startClient(w http.ResponseWriter, r *http.Request) {
 		// register the node
 		api.tm.RegisterNode(testID, info.ID, clientInfo)
 	}
-	if err != nil {
-		log15.Error("API: could not start client", "client", clientDef.Name, "container", containerID[:8], "error", err)
-		http.Error(w, "client did not start: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-	log15.Info("API: client "+clientDef.Name+" started", "suite", suiteID, "test", testID, "container", containerID[:8])
-	fmt.Fprintf(w, "%s@%s@%s", info.ID, info.IP, info.MAC)
+	return attempt
 }
 
-// clientLogFilePaths determines the log file path of a client container.
+// clientLogFilePaths determines the log file path of a client container. If stream is
+// non-empty, the path of the log containing only this output stream is returned.
 // Note that jsonPath gets written to the result JSON and always uses '/' as the separator.
 // The filePath is passed to the docker backend and uses the platform separator.
-func (api *simAPI) clientLogFilePaths(clientName, containerID string) (jsonPath string, file string) {
+func (api *simAPI) clientLogFilePaths(clientName, containerID, stream string) (jsonPath string, file string) {
 	// TODO: might be nice to put timestamp into the filename as well.
 	safeDir := strings.Replace(clientName, string(filepath.Separator), "_", -1)
-	jsonPath = path.Join(safeDir, fmt.Sprintf("client-%s.log", containerID))
+	name := fmt.Sprintf("client-%s.log", containerID)
+	if stream != "" {
+		name = fmt.Sprintf("client-%s.%s.log", containerID, stream)
+	}
+	jsonPath = path.Join(safeDir, api.env.logFileName(name))
 	file = filepath.Join(api.env.LogDir, filepath.FromSlash(jsonPath))
 	return jsonPath, file
 }
@@ -302,34 +573,68 @@ func (api *simAPI) checkClient(r *http.Request, w http.ResponseWriter) (*ClientD
 	name := r.FormValue("CLIENT")
 	if name == "" {
 		log15.Error("API: missing client name in start node request")
-		http.Error(w, "missing 'CLIENT' in request", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: "missing 'CLIENT' in request"})
 		return nil, false
 	}
+	// The simulator may request a specific branch of the client. This must be one
+	// of the branches built for this run.
+	if branch := r.FormValue("BRANCH"); branch != "" {
+		base, _ := SplitClientName(name)
+		name = base + branchDelimiter + branch
+		if _, ok := api.env.Definitions[name]; !ok {
+			log15.Error("API: unknown client branch in start node request", "client", name)
+			msg := fmt.Sprintf("unknown 'BRANCH' %q for client %s, available: %s", branch, base, strings.Join(api.clientBranches(base), ", "))
+			writeError(w, http.StatusBadRequest, &Error{
+				Code:    CodeUnknownClient,
+				Message: msg,
+				Context: map[string]string{"client": name},
+				Hint:    "Client branches must be built for the run, e.g. --client " + name + ".",
+			})
+			return nil, false
+		}
+	}
 	def, ok := api.env.Definitions[name]
 	if ok {
 		return def, true
 	}
 	// Client name not found.
 	log15.Error("API: unknown client name in start node request")
-	http.Error(w, "unknown 'CLIENT' type in request", http.StatusBadRequest)
+	writeError(w, http.StatusBadRequest, &Error{
+		Code:    CodeUnknownClient,
+		Message: "unknown 'CLIENT' type in request",
+		Context: map[string]string{"client": name},
+		Hint:    "Clients must be selected with the --client option of hive.",
+	})
 	return nil, false
 }
 
+// clientBranches returns the names of all built variants of a client.
+func (api *simAPI) clientBranches(base string) []string {
+	var names []string
+	for name := range api.env.Definitions {
+		if b, _ := SplitClientName(name); b == base {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // stopClient terminates a client container.
 func (api *simAPI) stopClient(w http.ResponseWriter, r *http.Request) {
 	_, testID, err := api.requestSuiteAndTest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 	node := mux.Vars(r)["node"]
 
 	err = api.tm.StopNode(testID, node)
-	if err == ErrNoSuchNode {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if errors.Is(err, ErrNoSuchNode) {
+		writeError(w, http.StatusBadRequest, err)
 		return
 	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 }
@@ -338,7 +643,7 @@ func (api *simAPI) stopClient(w http.ResponseWriter, r *http.Request) {
 func (api *simAPI) getEnodeURL(w http.ResponseWriter, r *http.Request) {
 	suiteID, testID, err := api.requestSuiteAndTest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -346,13 +651,13 @@ func (api *simAPI) getEnodeURL(w http.ResponseWriter, r *http.Request) {
 	nodeInfo, err := api.tm.GetNodeInfo(suiteID, testID, node)
 	if err != nil {
 		log15.Error("API: can't find node", "node", node, "error", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, http.StatusNotFound, err)
 		return
 	}
 	output, err := api.backend.RunEnodeSh(r.Context(), nodeInfo.ID)
 	if err != nil {
 		log15.Error("API: error running enode.sh", "node", node, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -361,7 +666,7 @@ func (api *simAPI) getEnodeURL(w http.ResponseWriter, r *http.Request) {
 	n, err := enode.ParseV4(output)
 	if err != nil {
 		log15.Error("API: enode.sh returned bad URL", "node", node, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 	tcpPort := n.TCP()
@@ -384,14 +689,14 @@ func (api *simAPI) getEnodeURL(w http.ResponseWriter, r *http.Request) {
 func (api *simAPI) execInClient(w http.ResponseWriter, r *http.Request) {
 	suiteID, testID, err := api.requestSuiteAndTest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 	node := mux.Vars(r)["node"]
 	nodeInfo, err := api.tm.GetNodeInfo(suiteID, testID, node)
 	if err != nil {
 		log15.Error("API: can't find node", "node", node, "error", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, http.StatusNotFound, err)
 		return
 	}
 
@@ -399,26 +704,228 @@ func (api *simAPI) execInClient(w http.ResponseWriter, r *http.Request) {
 	commandline, err := parseExecRequest(r.Body)
 	if err != nil {
 		log15.Error("API: invalid exec request", "node", node, "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 	info, err := api.backend.RunProgram(r.Context(), nodeInfo.ID, commandline)
 	if err != nil {
 		log15.Error("API: client script exec error", "node", node, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 	json.NewEncoder(w).Encode(&info)
 }
 
-// parseExecRequest decodes and validates a client script exec request.
+// getClientLogs returns the lines of a client log starting at line 'since'
+// (zero-based). A line which is still being written is not returned.
+func (api *simAPI) getClientLogs(w http.ResponseWriter, r *http.Request) {
+	suiteID, testID, err := api.requestSuiteAndTest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	node := mux.Vars(r)["node"]
+	nodeInfo, err := api.tm.GetNodeInfo(suiteID, testID, node)
+	if err != nil {
+		log15.Error("API: can't find node", "node", node, "error", err)
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	since := 0
+	if s := r.URL.Query().Get("since"); s != "" {
+		since, err = strconv.Atoi(s)
+		if err != nil || since < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid line number %q", s))
+			return
+		}
+	}
+
+	file := filepath.Join(api.env.LogDir, filepath.FromSlash(nodeInfo.LogFile))
+	logs, err := readLogLines(file, since, clientLogsMaxLines)
+	if err != nil {
+		log15.Error("API: can't read client log", "node", node, "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(logs)
+}
+
+// pauseClient suspends a client container.
+func (api *simAPI) pauseClient(w http.ResponseWriter, r *http.Request) {
+	api.setClientPaused(w, r, true)
+}
+
+// unpauseClient resumes a paused client container.
+func (api *simAPI) unpauseClient(w http.ResponseWriter, r *http.Request) {
+	api.setClientPaused(w, r, false)
+}
+
+func (api *simAPI) setClientPaused(w http.ResponseWriter, r *http.Request, pause bool) {
+	suiteID, testID, err := api.requestSuiteAndTest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	node := mux.Vars(r)["node"]
+	nodeInfo, err := api.tm.GetNodeInfo(suiteID, testID, node)
+	if err != nil {
+		log15.Error("API: can't find node", "node", node, "error", err)
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if pause {
+		err = api.backend.PauseContainer(nodeInfo.ID)
+	} else {
+		err = api.backend.UnpauseContainer(nodeInfo.ID)
+	}
+	if err != nil {
+		log15.Error("API: can't change client pause state", "node", node, "pause", pause, "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log15.Info("API: client pause state changed", "node", node, "paused", pause)
+}
+
+// setNetworkConditions degrades the network of a client container.
+func (api *simAPI) setNetworkConditions(w http.ResponseWriter, r *http.Request) {
+	var cond NetworkConditions
+	if err := json.NewDecoder(r.Body).Decode(&cond); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := cond.Validate(); err != nil {
+		log15.Error("API: invalid network conditions", "error", err)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	api.applyNetworkConditions(w, r, cond)
+}
+
+// clearNetworkConditions restores the network of a client container.
+func (api *simAPI) clearNetworkConditions(w http.ResponseWriter, r *http.Request) {
+	api.applyNetworkConditions(w, r, NetworkConditions{})
+}
+
+func (api *simAPI) applyNetworkConditions(w http.ResponseWriter, r *http.Request, cond NetworkConditions) {
+	suiteID, testID, err := api.requestSuiteAndTest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	node := mux.Vars(r)["node"]
+	nodeInfo, err := api.tm.GetNodeInfo(suiteID, testID, node)
+	if err != nil {
+		log15.Error("API: can't find node", "node", node, "error", err)
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := api.backend.SetNetworkConditions(r.Context(), nodeInfo.ID, cond); err != nil {
+		log15.Error("API: can't set network conditions", "node", node, "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log15.Info("API: client network conditions changed", "node", node, "latency", cond.Latency, "jitter", cond.Jitter, "loss", cond.Loss, "rate", cond.Rate)
+}
+
+// probeNetwork measures the network between two client containers.
+func (api *simAPI) probeNetwork(w http.ResponseWriter, r *http.Request) {
+	suiteID, testID, err := api.requestSuiteAndTest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	req, err := parseProbeRequest(r.Body)
+	if err != nil {
+		log15.Error("API: invalid network probe request", "error", err)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	fromInfo, err := api.tm.GetNodeInfo(suiteID, testID, req.From)
+	if err != nil {
+		log15.Error("API: can't find node", "node", req.From, "error", err)
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	toInfo, err := api.tm.GetNodeInfo(suiteID, testID, req.To)
+	if err != nil {
+		log15.Error("API: can't find node", "node", req.To, "error", err)
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	toIP, err := api.tm.ContainerIP(suiteID, req.Network, toInfo.ID)
+	if err != nil {
+		log15.Error("API: can't get container IP", "node", req.To, "network", req.Network, "error", err)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	duration := time.Duration(req.Duration) * time.Second
+	probe, err := api.backend.ProbeNetwork(r.Context(), fromInfo.ID, toInfo.ID, toIP, duration)
+	if err != nil {
+		log15.Error("API: network probe failed", "from", req.From, "to", req.To, "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log15.Info("API: network probe done", "from", req.From, "to", req.To, "network", req.Network, "bps", probe.BitsPerSecond, "rtt", probe.RTT)
+	json.NewEncoder(w).Encode(probe)
+}
+
+// probeRequest is the body of a network probe request.
+type probeRequest struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Network  string `json:"network"`  // defaults to "bridge"
+	Duration int    `json:"duration"` // in seconds
+}
+
+// Limits of the network probe duration.
+const (
+	defaultProbeDuration = 5
+	maxProbeDuration     = 60
+)
+
+// parseProbeRequest decodes and validates a network probe request.
+func parseProbeRequest(r io.Reader) (*probeRequest, error) {
+	var req probeRequest
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if req.From == "" || req.To == "" {
+		return nil, errors.New("missing 'from' or 'to' node")
+	}
+	if req.From == req.To {
+		return nil, errors.New("can't probe network between a node and itself")
+	}
+	if req.Network == "" {
+		req.Network = "bridge"
+	}
+	switch {
+	case req.Duration == 0:
+		req.Duration = defaultProbeDuration
+	case req.Duration < 0 || req.Duration > maxProbeDuration:
+		return nil, fmt.Errorf("invalid duration %d, must be between 1 and %d seconds", req.Duration, maxProbeDuration)
+	}
+	return &req, nil
+}
+
+// parseExecRequest decodes and validates a client exec request. Requests either run a
+// script from the /hive-bin directory of the client, or a shell script.
 func parseExecRequest(r io.Reader) ([]string, error) {
 	var request struct {
 		Command []string `json:"command"`
+		Shell   string   `json:"shell"`
 	}
 	if err := json.NewDecoder(r).Decode(&request); err != nil {
 		return nil, fmt.Errorf("invalid JSON: %v", err)
 	}
+	if request.Shell != "" {
+		if len(request.Command) > 0 {
+			return nil, errors.New("request has both command and shell script")
+		}
+		return []string{"/bin/sh", "-c", request.Shell}, nil
+	}
 	if len(request.Command) == 0 {
 		return nil, errors.New("empty command")
 	}
@@ -430,30 +937,209 @@ func parseExecRequest(r io.Reader) ([]string, error) {
 	return request.Command, nil
 }
 
+// clientLogsMaxLines is the maximum number of lines returned by a client log request.
+// Simulators read longer logs using multiple requests.
+const clientLogsMaxLines = 10000
+
+// readLogLines reads up to max lines of a log file, starting at line 'since'. Log files
+// may be written while they are read, so a last line without newline is not returned.
+// A missing file is treated as empty, since clients might not have logged anything yet.
+func readLogLines(file string, since, max int) (*ClientLogs, error) {
+	logs := &ClientLogs{Lines: []string{}, NextLine: since}
+	fd, err := OpenLog(file)
+	if os.IsNotExist(err) {
+		return logs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	rd := bufio.NewReader(fd)
+	for line := 0; len(logs.Lines) < max; line++ {
+		text, err := rd.ReadString('\n')
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// Compressed logs which are still being written end
+			// in the middle of a block.
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if line >= since {
+			logs.Lines = append(logs.Lines, strings.TrimRight(text, "\r\n"))
+			logs.NextLine = line + 1
+		}
+	}
+	return logs, nil
+}
+
 // networkCreate creates a docker network.
 func (api *simAPI) networkCreate(w http.ResponseWriter, r *http.Request) {
 	suiteID, err := api.requestSuite(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
 	networkName := mux.Vars(r)["network"]
-	err = api.tm.CreateNetwork(suiteID, networkName)
+	opt, err := parseNetworkOptions(r)
+	if err != nil {
+		log15.Error("API: invalid network options", "network", networkName, "error", err)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	err = api.tm.CreateNetwork(suiteID, networkName, opt)
+	if ErrorCodeOf(err) == CodeQuotaExceeded {
+		log15.Error("API: network quota exceeded", "network", networkName, "error", err)
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
 	if err != nil {
 		log15.Error("API: failed to create network", "network", networkName, "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 	log15.Info("API: network created", "name", networkName)
 	fmt.Fprint(w, "success")
 }
 
+// TestOrderRequest is the request body of the test order endpoint.
+type TestOrderRequest struct {
+	Tests [][]string `json:"tests"`
+}
+
+// TestOrderResponse is the response of the test order endpoint.
+type TestOrderResponse struct {
+	Order []int `json:"order"`
+}
+
+// orderTests returns the order in which the given tests of a suite should run.
+// When tests are sharded, only the tests of this hive instance's shard are returned.
+func (api *simAPI) orderTests(w http.ResponseWriter, r *http.Request) {
+	suiteID, err := api.requestSuite(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, ok := api.tm.IsTestSuiteRunning(suiteID); !ok {
+		writeError(w, http.StatusNotFound, ErrNoSuchTestSuite)
+		return
+	}
+	var req TestOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: "invalid request body", Err: err})
+		return
+	}
+	tests := make([]PlannedTest, len(req.Tests))
+	for i, names := range req.Tests {
+		tests[i].Names = names
+	}
+	plan, err := api.tm.PlanTests(suiteID, tests)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	log15.Debug("API: test order", "suite", suiteID, "strategy", api.env.TestOrder.Strategy, "shard", api.env.TestShard, "tests", len(plan.Order))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&TestOrderResponse{Order: plan.Order})
+}
+
+// planTests returns the tests of a suite which should run and their order. Tests which
+// don't fit into the time limit of the simulation are recorded as not run.
+func (api *simAPI) planTests(w http.ResponseWriter, r *http.Request) {
+	suiteID, err := api.requestSuite(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, ok := api.tm.IsTestSuiteRunning(suiteID); !ok {
+		writeError(w, http.StatusNotFound, ErrNoSuchTestSuite)
+		return
+	}
+	var req TestPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: "invalid request body", Err: err})
+		return
+	}
+	plan, err := api.tm.PlanTests(suiteID, req.Tests)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	log15.Debug("API: test plan", "suite", suiteID, "tests", len(plan.Order), "notrun", len(plan.NotRun))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// declareParams records the parameters declared by the simulator. It fails when the
+// parameters given to the simulator are unknown or invalid.
+func (api *simAPI) declareParams(w http.ResponseWriter, r *http.Request) {
+	var params []SimParam
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: "invalid request body", Err: err})
+		return
+	}
+	if err := api.tm.DeclareSimParams(params); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	log15.Debug("API: simulator parameters declared", "params", len(params))
+	w.WriteHeader(http.StatusOK)
+}
+
+// getParams returns the parameters declared by the simulator, with the values
+// given to the simulator.
+func (api *simAPI) getParams(w http.ResponseWriter, r *http.Request) {
+	params := api.tm.SimParams()
+	resp := make([]SimParamValue, len(params))
+	for i, p := range params {
+		resp[i] = SimParamValue{SimParam: p, Value: p.Default}
+		if v, ok := api.env.SimParams[p.Name]; ok {
+			resp[i].Value = v
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseNetworkOptions reads the optional subnet, gateway and mtu form values
+// of a network creation request.
+func parseNetworkOptions(r *http.Request) (NetworkOptions, error) {
+	opt := NetworkOptions{
+		Subnet:  r.FormValue("subnet"),
+		Gateway: r.FormValue("gateway"),
+	}
+	if opt.Subnet != "" {
+		_, subnet, err := net.ParseCIDR(opt.Subnet)
+		if err != nil {
+			return opt, fmt.Errorf("invalid subnet %q", opt.Subnet)
+		}
+		if opt.Gateway != "" {
+			gw := net.ParseIP(opt.Gateway)
+			if gw == nil {
+				return opt, fmt.Errorf("invalid gateway %q", opt.Gateway)
+			}
+			if !subnet.Contains(gw) {
+				return opt, fmt.Errorf("gateway %s is not in subnet %s", opt.Gateway, opt.Subnet)
+			}
+		}
+	} else if opt.Gateway != "" {
+		return opt, fmt.Errorf("gateway requires subnet")
+	}
+	if mtu := r.FormValue("mtu"); mtu != "" {
+		v, err := strconv.Atoi(mtu)
+		if err != nil || v < minNetworkMTU || v > maxNetworkMTU {
+			return opt, fmt.Errorf("invalid mtu %q (must be between %d and %d)", mtu, minNetworkMTU, maxNetworkMTU)
+		}
+		opt.MTU = v
+	}
+	return opt, nil
+}
+
 // networkRemove removes a docker network.
 func (api *simAPI) networkRemove(w http.ResponseWriter, r *http.Request) {
 	suiteID, err := api.requestSuite(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -461,7 +1147,7 @@ func (api *simAPI) networkRemove(w http.ResponseWriter, r *http.Request) {
 	err = api.tm.RemoveNetwork(suiteID, network)
 	if err != nil {
 		log15.Error("API: failed to remove network", "network", network, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 	log15.Info("API: docker network removed", "network", network)
@@ -472,7 +1158,7 @@ func (api *simAPI) networkRemove(w http.ResponseWriter, r *http.Request) {
 func (api *simAPI) networkIPGet(w http.ResponseWriter, r *http.Request) {
 	suiteID, err := api.requestSuite(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -481,7 +1167,7 @@ func (api *simAPI) networkIPGet(w http.ResponseWriter, r *http.Request) {
 	ipAddr, err := api.tm.ContainerIP(suiteID, network, node)
 	if err != nil {
 		log15.Error("API: failed to get container IP", "container", node, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
 		return
 	}
 	log15.Info("API: container IP requested", "network", network, "container", node, "ip", ipAddr)
@@ -492,25 +1178,52 @@ func (api *simAPI) networkIPGet(w http.ResponseWriter, r *http.Request) {
 func (api *simAPI) networkConnect(w http.ResponseWriter, r *http.Request) {
 	suiteID, err := api.requestSuite(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
 	name := mux.Vars(r)["network"]
 	containerID := mux.Vars(r)["node"]
-	if err := api.tm.ConnectContainer(suiteID, name, containerID); err != nil {
+	var ip net.IP
+	if ipStr := r.FormValue("ip"); ipStr != "" {
+		if ip = net.ParseIP(ipStr); ip == nil {
+			log15.Error("API: invalid container IP", "network", name, "container", containerID, "ip", ipStr)
+			writeError(w, http.StatusBadRequest, &Error{Code: CodeInvalidRequest, Message: fmt.Sprintf("invalid IP address %q", ipStr)})
+			return
+		}
+	}
+	if err := api.tm.ConnectContainer(suiteID, name, containerID, ip); err != nil {
 		log15.Error("API: failed to connect container", "network", name, "container", containerID, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log15.Info("API: container connected to network", "network", name, "container", containerID, "ip", ip)
+}
+
+// nodeNetworks returns the networks of a container and its IP addresses on them.
+func (api *simAPI) nodeNetworks(w http.ResponseWriter, r *http.Request) {
+	suiteID, err := api.requestSuite(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	log15.Info("API: container connected to network", "network", name, "container", containerID)
+
+	containerID := mux.Vars(r)["node"]
+	networks, err := api.tm.ContainerNetworks(suiteID, containerID)
+	if err != nil {
+		log15.Error("API: failed to get container networks", "container", containerID, "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(networks)
 }
 
 // networkDisconnect disconnects a container from a network.
 func (api *simAPI) networkDisconnect(w http.ResponseWriter, r *http.Request) {
 	suiteID, err := api.requestSuite(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -518,7 +1231,7 @@ func (api *simAPI) networkDisconnect(w http.ResponseWriter, r *http.Request) {
 	containerID := mux.Vars(r)["node"]
 	if err := api.tm.DisconnectContainer(suiteID, network, containerID); err != nil {
 		log15.Error("API: disconnecting container failed", "network", network, "container", containerID, "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 	log15.Info("API: container disconnected", "network", network, "container", containerID)
@@ -531,11 +1244,11 @@ func (api *simAPI) requestSuite(r *http.Request) (TestSuiteID, error) {
 
 	testSuite, err := strconv.Atoi(suite)
 	if err != nil {
-		return 0, fmt.Errorf("invalid test suite %q", suite)
+		return 0, &Error{Code: CodeInvalidRequest, Message: fmt.Sprintf("invalid test suite %q", suite)}
 	}
 	testSuiteID := TestSuiteID(testSuite)
 	if _, running := api.tm.IsTestSuiteRunning(testSuiteID); !running {
-		return 0, fmt.Errorf("test suite %d not running", testSuite)
+		return 0, ErrNoSuchTestSuite.With("suite", suite)
 	}
 	return testSuiteID, nil
 }
@@ -547,11 +1260,11 @@ func (api *simAPI) requestTest(r *http.Request) (TestID, error) {
 
 	testCase, err := strconv.Atoi(testString)
 	if err != nil {
-		return 0, fmt.Errorf("invalid test case id %q", testString)
+		return 0, &Error{Code: CodeInvalidRequest, Message: fmt.Sprintf("invalid test case id %q", testString)}
 	}
 	testCaseID := TestID(testCase)
 	if _, running := api.tm.IsTestRunning(testCaseID); !running {
-		return 0, fmt.Errorf("test case %d is not running", testCaseID)
+		return 0, ErrNoSuchTestCase.With("test", testString)
 	}
 	return testCaseID, nil
 }
@@ -0,0 +1,88 @@
+package libhive
+
+import (
+	"errors"
+	"strings"
+)
+
+// BuildFailureCause classifies why an image build failed. It tells apart
+// failures caused by the infrastructure from broken client or simulator builds.
+type BuildFailureCause string
+
+const (
+	BuildCauseBaseImage BuildFailureCause = "base-image-missing" // base image or pulled image not found
+	BuildCauseNetwork   BuildFailureCause = "network"            // fetching sources or packages failed
+	BuildCauseCompile   BuildFailureCause = "compile-error"      // the build itself failed
+	BuildCauseUnknown   BuildFailureCause = "unknown"
+)
+
+// These substrings of the build output identify the cause of a build failure.
+// The causes are checked in order. Network errors are checked before compile
+// errors because a failed download usually also makes the build step fail.
+var buildFailurePatterns = []struct {
+	cause    BuildFailureCause
+	patterns []string
+}{
+	{BuildCauseBaseImage, []string{
+		"pull access denied",
+		"manifest unknown",
+		"not found: manifest",
+		"repository does not exist",
+		"no such image",
+	}},
+	{BuildCauseNetwork, []string{
+		"could not resolve host",
+		"temporary failure in name resolution",
+		"no such host",
+		"connection refused",
+		"connection reset by peer",
+		"connection timed out",
+		"i/o timeout",
+		"tls handshake timeout",
+		"network is unreachable",
+		"failed to fetch",
+		"unable to connect to",
+		"toomanyrequests",
+		"unexpected eof",
+	}},
+	{BuildCauseCompile, []string{
+		"could not compile",
+		"compilation failed",
+		"compilation error",
+		"build failed",
+		"build failure",
+		"undefined:",
+		"cannot find package",
+		"npm err!",
+		"make: ***",
+		"returned a non-zero code",
+	}},
+}
+
+// ClassifyBuildFailure determines the cause of a failed build from its output.
+func ClassifyBuildFailure(output string) BuildFailureCause {
+	output = strings.ToLower(output)
+	for _, c := range buildFailurePatterns {
+		for _, p := range c.patterns {
+			if strings.Contains(output, p) {
+				return c.cause
+			}
+		}
+	}
+	return BuildCauseUnknown
+}
+
+// BuildFailureOf returns the cause and build log file of a failed build. The cause
+// is empty if err isn't a build or pull error. The log file is empty if the build
+// output wasn't stored.
+func BuildFailureOf(err error) (cause BuildFailureCause, logFile string) {
+	var e *Error
+	if !errors.As(err, &e) || (e.Code != CodeBuildFailed && e.Code != CodePullFailed) {
+		return "", ""
+	}
+	cause = BuildFailureCause(e.Context["cause"])
+	if cause == "" {
+		cause = BuildCauseUnknown
+	}
+	return cause, e.Context["log"]
+}
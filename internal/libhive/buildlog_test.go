@@ -0,0 +1,48 @@
+package libhive
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyBuildFailure(t *testing.T) {
+	tests := []struct {
+		output string
+		want   BuildFailureCause
+	}{
+		{"Step 1/8 : FROM golang:nope\nmanifest for golang:nope not found: manifest unknown", BuildCauseBaseImage},
+		{"pull access denied for hive/nope, repository does not exist", BuildCauseBaseImage},
+		{"fatal: unable to access 'https://github.com/x/y/': Could not resolve host: github.com", BuildCauseNetwork},
+		{"E: Failed to fetch http://deb.debian.org/debian/pool/main/g/gcc.deb\nmake: *** [all] Error 100", BuildCauseNetwork},
+		{"go: downloading x\ndial tcp 1.2.3.4:443: i/o timeout", BuildCauseNetwork},
+		{"./main.go:12:2: undefined: foo\nmake: *** [geth] Error 2", BuildCauseCompile},
+		{"error: could not compile `lighthouse` due to previous error", BuildCauseCompile},
+		{"The command '/bin/sh -c ./build.sh' returned a non-zero code: 1", BuildCauseCompile},
+		{"something odd happened", BuildCauseUnknown},
+	}
+	for _, test := range tests {
+		if cause := ClassifyBuildFailure(test.output); cause != test.want {
+			t.Errorf("wrong cause %q for output %q, want %q", cause, test.output, test.want)
+		}
+	}
+}
+
+func TestBuildFailureOf(t *testing.T) {
+	err := &Error{
+		Code:    CodeBuildFailed,
+		Message: "can't build x",
+		Context: map[string]string{"cause": string(BuildCauseNetwork), "log": "builds/run/clients/x.log"},
+	}
+	cause, log := BuildFailureOf(fmt.Errorf("client x: %w", err))
+	if cause != BuildCauseNetwork || log != "builds/run/clients/x.log" {
+		t.Errorf("wrong build failure %q %q", cause, log)
+	}
+	cause, _ = BuildFailureOf(&Error{Code: CodePullFailed, Message: "can't pull x"})
+	if cause != BuildCauseUnknown {
+		t.Errorf("wrong cause %q for pull error without cause", cause)
+	}
+	if cause, _ := BuildFailureOf(errors.New("other")); cause != "" {
+		t.Errorf("wrong cause %q for other error", cause)
+	}
+}
@@ -0,0 +1,96 @@
+package libhive
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// BuildCacheMount is a cache directory which persists across image builds. It is
+// mounted into the RUN instructions of Dockerfiles, so package managers don't download
+// all dependencies again when an image is rebuilt, e.g. for another branch.
+type BuildCacheMount struct {
+	ID     string // identifies the cache on the docker host
+	Target string // mount point in the build container
+}
+
+// buildCachePresets are the cache mounts of common package managers. The targets
+// are the default cache locations in the official images of the toolchains.
+//
+// Files in cache mounts are not stored in the image. The go preset doesn't include
+// the module cache, because some client images run go commands which need the
+// downloaded modules after the build.
+var buildCachePresets = map[string][]BuildCacheMount{
+	"go": {{ID: "hive-go-build", Target: "/root/.cache/go-build"}},
+	"cargo": {
+		{ID: "hive-cargo-registry", Target: "/usr/local/cargo/registry"},
+		{ID: "hive-cargo-git", Target: "/usr/local/cargo/git"},
+	},
+	"gradle": {{ID: "hive-gradle", Target: "/root/.gradle/caches"}},
+	"maven":  {{ID: "hive-maven", Target: "/root/.m2/repository"}},
+	"nuget":  {{ID: "hive-nuget", Target: "/root/.nuget/packages"}},
+	"npm":    {{ID: "hive-npm", Target: "/root/.npm"}},
+}
+
+// BuildCachePresets returns the names of the predefined build cache mounts.
+func BuildCachePresets() []string {
+	names := make([]string, 0, len(buildCachePresets))
+	for name := range buildCachePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseBuildCacheMounts parses a comma-separated list of build cache mounts. Each
+// element is either the name of a preset, e.g. "go", or NAME=TARGET for a cache
+// mounted at the absolute path TARGET.
+func ParseBuildCacheMounts(spec string) ([]BuildCacheMount, error) {
+	var (
+		mounts  []BuildCacheMount
+		targets = make(map[string]bool)
+	)
+	add := func(m BuildCacheMount) error {
+		if targets[m.Target] {
+			return fmt.Errorf("duplicate cache mount target %s", m.Target)
+		}
+		targets[m.Target] = true
+		mounts = append(mounts, m)
+		return nil
+	}
+	for _, elem := range strings.Split(spec, ",") {
+		elem = strings.TrimSpace(elem)
+		if elem == "" {
+			continue
+		}
+		if eq := strings.IndexByte(elem, '='); eq >= 0 {
+			name, target := elem[:eq], elem[eq+1:]
+			if name == "" || strings.ContainsAny(name, " ,") {
+				return nil, fmt.Errorf("invalid cache mount name %q", name)
+			}
+			if !path.IsAbs(target) || strings.ContainsAny(target, " ,") {
+				return nil, fmt.Errorf("cache mount %s: target %q is not an absolute path", name, target)
+			}
+			if err := add(BuildCacheMount{ID: "hive-" + name, Target: path.Clean(target)}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		preset, ok := buildCachePresets[elem]
+		if !ok {
+			return nil, fmt.Errorf("unknown cache mount %q, want NAME=TARGET or one of %s", elem, strings.Join(BuildCachePresets(), ", "))
+		}
+		for _, m := range preset {
+			if err := add(m); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return mounts, nil
+}
+
+// String returns the mount option of the cache in a Dockerfile RUN instruction.
+func (m BuildCacheMount) String() string {
+	return fmt.Sprintf("--mount=type=cache,id=%s,target=%s,sharing=locked", m.ID, m.Target)
+}
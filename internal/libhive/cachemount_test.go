@@ -0,0 +1,29 @@
+package libhive
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBuildCacheMounts(t *testing.T) {
+	mounts, err := ParseBuildCacheMounts("gradle, deps=/opt/deps/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BuildCacheMount{
+		{ID: "hive-gradle", Target: "/root/.gradle/caches"},
+		{ID: "hive-deps", Target: "/opt/deps"},
+	}
+	if !reflect.DeepEqual(mounts, want) {
+		t.Errorf("wrong mounts %v", mounts)
+	}
+	if mounts, err := ParseBuildCacheMounts(""); err != nil || len(mounts) != 0 {
+		t.Errorf("wrong result for empty list: %v, %v", mounts, err)
+	}
+
+	for _, spec := range []string{"unknown", "x=relative/path", "=/abs", "maven,m2=/root/.m2/repository"} {
+		if _, err := ParseBuildCacheMounts(spec); err == nil {
+			t.Errorf("no error for %q", spec)
+		}
+	}
+}
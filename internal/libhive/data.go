@@ -1,6 +1,9 @@
 package libhive
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"strconv"
 	"time"
 )
@@ -28,6 +31,20 @@ type TestSuite struct {
 	TestCases      map[TestID]*TestCase `json:"testCases"`
 	// the log-file pertaining to the simulator. (may encompass more than just one TestSuite)
 	SimulatorLog string `json:"simLog"`
+	// warnings about host resources emitted while the suite was running.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// The hive run which executed the suite, and the tags given to the run.
+	RunID string            `json:"runID,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+
+	// The shard of the tests run by the hive instance. Suites of all shards of a
+	// run can be merged, see cmd/hiveview.
+	Shard *TestShard `json:"shard,omitempty"`
+
+	// shardTests records for every planned test name whether the test is in the
+	// shard. It is nil if the simulator didn't plan the suite.
+	shardTests map[string]bool
 }
 
 // TestCase represents a single test case in a test suite.
@@ -38,12 +55,79 @@ type TestCase struct {
 	End           time.Time              `json:"end"`
 	SummaryResult TestResult             `json:"summaryResult"` // The result of the whole test case.
 	ClientInfo    map[string]*ClientInfo `json:"clientInfo"`    // Info about each client.
+
+	// Timeout of the test in seconds, zero if the test has no timeout.
+	Timeout float64 `json:"timeout,omitempty"`
+
+	// Failed attempts of the test before the final one, when failed tests are retried.
+	Attempts []TestAttempt `json:"attempts,omitempty"`
+}
+
+// TestAttempt is a failed run of a test case which was retried.
+type TestAttempt struct {
+	Start         time.Time              `json:"start"`
+	End           time.Time              `json:"end"`
+	SummaryResult TestResult             `json:"summaryResult"`
+	ClientInfo    map[string]*ClientInfo `json:"clientInfo,omitempty"`
 }
 
 // TestResult is the payload submitted to the EndTest endpoint.
 type TestResult struct {
-	Pass    bool   `json:"pass"`
-	Details string `json:"details"`
+	Pass     bool            `json:"pass"`
+	Details  string          `json:"details"`
+	Category FailureCategory `json:"category,omitempty"` // Set for failed tests.
+
+	// Skip is set for skipped tests. Skipped tests have Pass set, so tools which
+	// don't know about skipping don't count them as failures.
+	Skip SkipReason `json:"skip,omitempty"`
+
+	// NotRun is set for tests which didn't run because they didn't fit into the time
+	// limit of the simulation. Like skipped tests, they have Pass set.
+	NotRun bool `json:"notRun,omitempty"`
+}
+
+// Skipped reports whether the test was skipped.
+func (r TestResult) Skipped() bool {
+	return r.Skip != ""
+}
+
+// FailureCategory classifies the cause of a test failure.
+type FailureCategory string
+
+const (
+	FailureAssertion      FailureCategory = "assertion"      // the client did not behave as expected
+	FailureClientCrash    FailureCategory = "client-crash"   // a client crashed or didn't start
+	FailureHarnessError   FailureCategory = "harness-error"  // bug in the simulator or test harness
+	FailureTimeout        FailureCategory = "timeout"        // the test did not finish in time
+	FailureInfrastructure FailureCategory = "infrastructure" // docker, network or host problem
+)
+
+// Valid reports whether c is a known failure category.
+func (c FailureCategory) Valid() bool {
+	switch c {
+	case FailureAssertion, FailureClientCrash, FailureHarnessError, FailureTimeout, FailureInfrastructure:
+		return true
+	}
+	return false
+}
+
+// SkipReason classifies why a test was skipped.
+type SkipReason string
+
+const (
+	SkipUnsupportedFork    SkipReason = "unsupported-fork"    // the client doesn't support the fork under test
+	SkipMissingFeature     SkipReason = "missing-feature"     // the client lacks a feature needed by the test
+	SkipPrerequisiteFailed SkipReason = "prerequisite-failed" // a test this test depends on has failed
+	SkipOther              SkipReason = "other"
+)
+
+// Valid reports whether r is a known skip reason.
+func (r SkipReason) Valid() bool {
+	switch r {
+	case SkipUnsupportedFork, SkipMissingFeature, SkipPrerequisiteFailed, SkipOther:
+		return true
+	}
+	return false
 }
 
 // ClientInfo describes a client that participated in a test case.
@@ -54,6 +138,10 @@ type ClientInfo struct {
 	InstantiatedAt time.Time `json:"instantiatedAt"`
 	LogFile        string    `json:"logFile"` //Absolute path to the logfile.
 
+	// These logs contain the stdout and stderr output of the client separately.
+	StdoutLogFile string `json:"stdoutLogFile,omitempty"`
+	StderrLogFile string `json:"stderrLogFile,omitempty"`
+
 	wait func()
 }
 
@@ -63,3 +151,44 @@ type ExecInfo struct {
 	Stderr   string `json:"stderr"`
 	ExitCode int    `json:"exitCode"`
 }
+
+// ClientLogs is a range of lines of a client log.
+type ClientLogs struct {
+	Lines    []string `json:"lines"`
+	NextLine int      `json:"nextLine"` // number of the line after the last returned line
+}
+
+// NetworkConditions degrade the network of a container. Zero values mean no
+// degradation. The conditions apply to traffic sent by the container.
+type NetworkConditions struct {
+	Latency time.Duration `json:"latency,omitempty"` // added delay of packets
+	Jitter  time.Duration `json:"jitter,omitempty"`  // random variation of the delay, requires Latency
+	Loss    float64       `json:"loss,omitempty"`    // percentage of dropped packets
+	Rate    uint64        `json:"rate,omitempty"`    // bandwidth cap in bits per second
+}
+
+// Validate checks that the conditions can be applied.
+func (c *NetworkConditions) Validate() error {
+	switch {
+	case c.Latency < 0 || c.Jitter < 0:
+		return errors.New("latency and jitter must not be negative")
+	case c.Jitter > 0 && c.Latency == 0:
+		return errors.New("jitter requires latency")
+	case c.Loss < 0 || c.Loss > 100 || math.IsNaN(c.Loss):
+		return fmt.Errorf("invalid loss percentage %v", c.Loss)
+	}
+	return nil
+}
+
+// IsZero reports whether the conditions don't degrade the network.
+func (c *NetworkConditions) IsZero() bool {
+	return *c == NetworkConditions{}
+}
+
+// NetworkProbe is the result of measuring the network between two containers.
+type NetworkProbe struct {
+	BitsPerSecond float64       `json:"bitsPerSecond"` // achieved TCP throughput
+	RTT           time.Duration `json:"rtt"`           // mean TCP round-trip time
+	MinRTT        time.Duration `json:"minRTT"`
+	MaxRTT        time.Duration `json:"maxRTT"`
+}
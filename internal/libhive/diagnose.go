@@ -0,0 +1,110 @@
+package libhive
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// StartFailureKind classifies why a client container did not start.
+type StartFailureKind string
+
+const (
+	StartFailureUnknown         StartFailureKind = "unknown"
+	StartFailurePortNotOpen     StartFailureKind = "port-not-open"    // client runs, but never opened the checked port
+	StartFailureCrash           StartFailureKind = "crash"            // client exited during startup
+	StartFailureCrashLoop       StartFailureKind = "crash-loop"       // client exited during every startup attempt
+	StartFailureGenesisMismatch StartFailureKind = "genesis-mismatch" // client rejected the genesis configuration
+)
+
+// hint returns a suggestion for fixing the start failure.
+func (k StartFailureKind) hint() string {
+	switch k {
+	case StartFailurePortNotOpen:
+		return "The client did not open its port in time. Increase --client.checktimelimit if the client needs more time to start."
+	case StartFailureCrash, StartFailureCrashLoop:
+		return "The client exited during startup. Check the client log for the cause."
+	case StartFailureGenesisMismatch:
+		return "The client rejected the genesis configuration. Check that the client supports the fork configuration of the test."
+	}
+	return ""
+}
+
+// These errors are returned by ContainerBackend.StartContainer when
+// the container doesn't come up.
+var (
+	ErrContainerTerminated   = errors.New("terminated unexpectedly")
+	ErrContainerStartTimeout = errors.New("timed out waiting for container startup")
+)
+
+// startLogExcerptLines is the number of client log lines included in
+// the diagnosis of a failed client start.
+const startLogExcerptLines = 20
+
+var genesisErrorPattern = regexp.MustCompile(`(?i)genesis.*(mismatch|incompatible|invalid|wrong|unsupported)|(mismatch|incompatible|invalid|wrong).*genesis`)
+
+// startDiagnosis explains a failed client start.
+type startDiagnosis struct {
+	Kind       StartFailureKind
+	Attempts   int
+	Err        error  // error of the last attempt
+	LogExcerpt string // last lines of the client log of the last attempt
+}
+
+func (d *startDiagnosis) String() string {
+	msg := fmt.Sprintf("%s after %d attempt(s): %v", d.Kind, d.Attempts, d.Err)
+	if d.LogExcerpt != "" {
+		msg += "\n\nlast lines of client log:\n" + d.LogExcerpt
+	}
+	return msg
+}
+
+// diagnoseStartFailure classifies the failure of the given client start attempts.
+func diagnoseStartFailure(attempts []*startAttempt) *startDiagnosis {
+	if len(attempts) == 0 {
+		return &startDiagnosis{Kind: StartFailureUnknown, Err: errors.New("no start attempts")}
+	}
+	last := attempts[len(attempts)-1]
+	d := &startDiagnosis{
+		Kind:       StartFailureUnknown,
+		Attempts:   len(attempts),
+		Err:        last.err,
+		LogExcerpt: readLogTail(last.logFile, startLogExcerptLines),
+	}
+
+	crashes := 0
+	for _, a := range attempts {
+		if errors.Is(a.err, ErrContainerTerminated) {
+			crashes++
+		}
+	}
+	switch {
+	case genesisErrorPattern.MatchString(d.LogExcerpt):
+		d.Kind = StartFailureGenesisMismatch
+	case crashes > 1 && crashes == len(attempts):
+		d.Kind = StartFailureCrashLoop
+	case errors.Is(last.err, ErrContainerTerminated):
+		d.Kind = StartFailureCrash
+	case errors.Is(last.err, ErrContainerStartTimeout):
+		d.Kind = StartFailurePortNotOpen
+	}
+	return d
+}
+
+// readLogTail returns the last n lines of the given log file.
+func readLogTail(file string, n int) string {
+	if file == "" {
+		return ""
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
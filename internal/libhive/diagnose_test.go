@@ -0,0 +1,39 @@
+package libhive
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagnoseStartFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hive-diagnose-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	genesisLog := filepath.Join(dir, "genesis.log")
+	ioutil.WriteFile(genesisLog, []byte("starting...\nFatal: invalid genesis file\n"), 0644)
+
+	timeout := &startAttempt{err: ErrContainerStartTimeout}
+	crash := &startAttempt{err: ErrContainerTerminated}
+	tests := []struct {
+		attempts []*startAttempt
+		want     StartFailureKind
+	}{
+		{[]*startAttempt{timeout}, StartFailurePortNotOpen},
+		{[]*startAttempt{crash}, StartFailureCrash},
+		{[]*startAttempt{timeout, crash}, StartFailureCrash},
+		{[]*startAttempt{crash, crash}, StartFailureCrashLoop},
+		{[]*startAttempt{{err: ErrContainerTerminated, logFile: genesisLog}}, StartFailureGenesisMismatch},
+		{[]*startAttempt{{err: errors.New("boom")}}, StartFailureUnknown},
+	}
+	for i, test := range tests {
+		d := diagnoseStartFailure(test.attempts)
+		if d.Kind != test.want {
+			t.Errorf("test %d: wrong diagnosis %q, want %q", i, d.Kind, test.want)
+		}
+	}
+}
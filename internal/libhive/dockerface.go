@@ -2,9 +2,9 @@ package libhive
 
 import (
 	"context"
-	"fmt"
 	"mime/multipart"
 	"net"
+	"time"
 )
 
 // ContainerBackend captures the docker interactions of the simulation API.
@@ -20,27 +20,75 @@ type ContainerBackend interface {
 	// RunProgram runs a command in the given container and returns its outputs and exit code.
 	RunProgram(ctx context.Context, containerID string, cmdline []string) (*ExecInfo, error)
 
+	// PauseContainer suspends all processes in the given container.
+	// UnpauseContainer resumes them.
+	PauseContainer(containerID string) error
+	UnpauseContainer(containerID string) error
+
+	// ProbeNetwork measures throughput and round-trip time of a TCP stream sent
+	// from container 'from' to container 'to', which is reachable at the given IP.
+	ProbeNetwork(ctx context.Context, from, to, toIP string, duration time.Duration) (*NetworkProbe, error)
+
+	// SetNetworkConditions applies latency, packet loss and bandwidth limits to
+	// all network interfaces of the given container. Zero conditions remove them.
+	SetNetworkConditions(ctx context.Context, containerID string, cond NetworkConditions) error
+
 	// These methods configure docker networks.
 	NetworkNameToID(name string) (string, error)
-	CreateNetwork(name string) (string, error)
+	CreateNetwork(name string, opt NetworkOptions) (string, error)
 	RemoveNetwork(id string) error
 	ContainerIP(containerID, networkID string) (net.IP, error)
-	ConnectContainer(containerID, networkID string) error
+	// ContainerNetworks returns the IP addresses of a container on all of its
+	// networks, by network ID.
+	ContainerNetworks(containerID string) (map[string]net.IP, error)
+	// ConnectContainer connects a container to a network. If ip is non-nil, the
+	// container gets this address, otherwise docker assigns one.
+	ConnectContainer(containerID, networkID string, ip net.IP) error
 	DisconnectContainer(containerID, networkID string) error
 }
 
 // This error is returned by NetworkNameToID if a docker network is not present.
-var ErrNetworkNotFound = fmt.Errorf("network not found")
+var ErrNetworkNotFound = &Error{Code: CodeNetworkNotFound, Message: "network not found"}
+
+// These labels are set on docker objects created by hive.
+const (
+	LabelRunID     = "hive.run"       // ID of the hive run
+	LabelSimulator = "hive.simulator" // simulator name
+	LabelSuite     = "hive.suite"     // test suite name
+	LabelClient    = "hive.client"    // client name
+)
 
 // ContainerOptions contains the launch parameters for docker containers.
 type ContainerOptions struct {
 	// These options apply when creating the container.
-	Env   map[string]string
-	Files map[string]*multipart.FileHeader
+	Env    map[string]string
+	Files  map[string]*multipart.FileHeader
+	Labels map[string]string
+
+	// Resources are the CPU and memory limits of the container.
+	Resources ContainerResources
 
 	// These options apply when starting the container.
 	CheckLive uint16 // requests check for the given TCP port
 	LogFile   string // if set, container output is written to this file
+
+	// If set, the stdout and stderr output of the container is also written to
+	// these files, so the stream of each line is known. Backends which can't
+	// separate the streams ignore them.
+	StdoutLogFile string
+	StderrLogFile string
+
+	// PortProxy lists ports forwarded to other ports of the container,
+	// i.e. proxy port -> container port.
+	PortProxy map[uint16]uint16
+}
+
+// NetworkOptions contains the parameters for creating docker networks.
+// Zero values select the docker defaults.
+type NetworkOptions struct {
+	Subnet  string // subnet in CIDR notation
+	Gateway string // gateway IP address, must be in Subnet
+	MTU     int
 }
 
 // ContainerInfo is returned by StartContainer.
@@ -50,6 +98,11 @@ type ContainerInfo struct {
 	MAC     string // MAC address. TODO: remove
 	LogFile string
 
+	// These are the stream logs written by the backend. They are empty
+	// when the backend doesn't write the log of a stream.
+	StdoutLogFile string
+	StderrLogFile string
+
 	// The wait function returns when the container is stopped.
 	// This must be called for all containers that were started
 	// to avoid resource leaks.
@@ -58,15 +111,79 @@ type ContainerInfo struct {
 
 // ClientMetadata is metadata to describe the client in more detail, configured with a YAML file in the client dir.
 type ClientMetadata struct {
-	Roles []string `yaml:"roles" json:"roles"`
+	Roles []string    `yaml:"roles" json:"roles"`
+	Ports ClientPorts `yaml:"ports" json:"ports"`
+
+	// Forks are the forks supported by the client, e.g. "london" or "altair". Clients
+	// which don't declare forks are assumed to support all forks.
+	Forks []string `yaml:"forks" json:"forks,omitempty"`
+
+	// Dockerfiles maps the names of the Dockerfiles supported by the client to their
+	// files in the client directory. The Dockerfile named "default", or the file
+	// "Dockerfile" if there is none, is built unless another one is selected.
+	Dockerfiles map[string]string `yaml:"dockerfiles" json:"dockerfiles,omitempty"`
+	// BuildArgs are the default build arguments of the client's Dockerfiles.
+	BuildArgs map[string]string `yaml:"build_args" json:"buildArgs,omitempty"`
+
+	// Resources override the --client.cpus and --client.memory limits for the client.
+	Resources ContainerResources `yaml:"resources" json:"resources"`
+}
+
+// ClientPorts configures the API ports of a client. Zero values mean the client
+// uses the default port.
+type ClientPorts struct {
+	RPC    int `yaml:"rpc" json:"rpc,omitempty"`
+	Engine int `yaml:"engine" json:"engine,omitempty"`
+	Beacon int `yaml:"beacon" json:"beacon,omitempty"`
+}
+
+// These are the standard API ports of clients.
+const (
+	StandardRPCPort    = 8545
+	StandardEnginePort = 8551
+	StandardBeaconPort = 4000
+)
+
+// ProxyPorts returns the standard ports which must be forwarded to the client's own
+// ports, i.e. standard port -> client port. Standard ports which the client uses
+// itself are not forwarded.
+func (p ClientPorts) ProxyPorts() map[uint16]uint16 {
+	used := map[int]bool{p.RPC: true, p.Engine: true, p.Beacon: true}
+	ports := make(map[uint16]uint16)
+	add := func(standard, port int) {
+		if port != 0 && port != standard && !used[standard] {
+			ports[uint16(standard)] = uint16(port)
+		}
+	}
+	add(StandardRPCPort, p.RPC)
+	add(StandardEnginePort, p.Engine)
+	add(StandardBeaconPort, p.Beacon)
+	return ports
+}
+
+// ResourceStats summarizes the container usage of a hive run.
+type ResourceStats struct {
+	RunID             string    `json:"runID"`
+	Start             time.Time `json:"start"`
+	End               time.Time `json:"end"`
+	ContainersCreated int       `json:"containersCreated"`
+	PeakConcurrent    int       `json:"peakConcurrentContainers"`
+	CPUSeconds        float64   `json:"cpuSeconds"` // total CPU time of all containers
 }
 
 // Builder can build docker images of clients and simulators.
 type Builder interface {
 	ReadClientMetadata(name string) (*ClientMetadata, error)
-	BuildClientImage(ctx context.Context, name string) (string, error)
+	// BuildClientImage builds the image of a client from the Dockerfile and with
+	// the build arguments of the build info.
+	BuildClientImage(ctx context.Context, name string, info ClientBuildInfo) (string, error)
 	BuildSimulatorImage(ctx context.Context, name string) (string, error)
 
+	// PullImage pulls an image from its registry and returns the digest of the image,
+	// e.g. "sha256:...". When the reference contains a digest, the pulled image is
+	// verified against it by docker.
+	PullImage(ctx context.Context, image string) (string, error)
+
 	// ReadFile returns the content of a file in the given image.
 	ReadFile(image, path string) ([]byte, error)
 }
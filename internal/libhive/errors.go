@@ -0,0 +1,142 @@
+package libhive
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrorCode is a machine-readable classification of an error.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest    ErrorCode = "invalid-request"     // malformed API request
+	CodeNoSuchTestSuite   ErrorCode = "no-such-suite"       // test suite unknown or ended
+	CodeNoSuchTestCase    ErrorCode = "no-such-test"        // test case unknown or ended
+	CodeNoSuchNode        ErrorCode = "no-such-node"        // client container unknown
+	CodeNetworkNotFound   ErrorCode = "network-not-found"   // docker network unknown
+	CodeUnknownClient     ErrorCode = "unknown-client"      // client not built for this run
+	CodeInventoryMiss     ErrorCode = "inventory-miss"      // client or simulator not in the inventory
+	CodeInvalidMetadata   ErrorCode = "invalid-metadata"    // hive.yaml of a client or simulator is invalid
+	CodeBuildFailed       ErrorCode = "build-failed"        // docker image build failed
+	CodePullFailed        ErrorCode = "pull-failed"         // docker image pull failed
+	CodeContainerFailed   ErrorCode = "container-failed"    // docker container operation failed
+	CodeClientStartFailed ErrorCode = "client-start-failed" // client container didn't come up
+	CodeHostResources     ErrorCode = "host-resources"      // host is low on resources
+	CodeTestSuiteState    ErrorCode = "suite-state"         // operation not allowed in the current suite state
+	CodeInvalidParam      ErrorCode = "invalid-param"       // simulator parameter unknown or invalid
+	CodeQuotaExceeded     ErrorCode = "quota-exceeded"      // simulator exceeded a resource quota
+	CodeInternal          ErrorCode = "internal"
+)
+
+// Error is an error with a machine-readable code. The simulation API responds with
+// these errors encoded as JSON, so API consumers can react to the code and present
+// the hint instead of parsing the message.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Context map[string]string // details, e.g. the client name
+	Hint    string            // how to fix the problem, for users
+	Retry   bool              // set if the operation may succeed when repeated later
+	Err     error             // underlying error, may be nil
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error with the same code and message. This makes
+// errors created by With match the error they were created from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Message == e.Message
+}
+
+// With returns a copy of the error with a context value added.
+func (e *Error) With(key, value string) *Error {
+	cpy := *e
+	cpy.Context = make(map[string]string, len(e.Context)+1)
+	for k, v := range e.Context {
+		cpy.Context[k] = v
+	}
+	cpy.Context[key] = value
+	return &cpy
+}
+
+// ErrorCodeOf returns the code of the first *Error in the chain of err.
+// It returns the empty string if there is no *Error.
+func ErrorCodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// ErrorHint returns the hint of the first *Error in the chain of err which has one.
+func ErrorHint(err error) string {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if e, ok := err.(*Error); ok && e.Hint != "" {
+			return e.Hint
+		}
+	}
+	return ""
+}
+
+// errorResponse is the JSON encoding of an *Error in API responses.
+type errorResponse struct {
+	Code    ErrorCode         `json:"code"`
+	Message string            `json:"error"`
+	Context map[string]string `json:"context,omitempty"`
+	Hint    string            `json:"hint,omitempty"`
+	Retry   bool              `json:"retry,omitempty"`
+}
+
+// writeError sends an error as the response to an API request. Errors with a code
+// are sent as JSON, other errors as plain text.
+func writeError(w http.ResponseWriter, status int, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&errorResponse{
+		Code:    e.Code,
+		Message: err.Error(),
+		Context: e.Context,
+		Hint:    ErrorHint(err),
+		Retry:   e.Retry,
+	})
+}
+
+// wrapError adds a message to err, keeping its code.
+func wrapError(msg string, err error) *Error {
+	code := ErrorCodeOf(err)
+	if code == "" {
+		code = CodeInternal
+	}
+	return &Error{Code: code, Message: msg, Err: err}
+}
+
+// hostResourceError wraps an error returned by HostGuard.Wait.
+func hostResourceError(msg string, err error) *Error {
+	e := &Error{Code: CodeHostResources, Message: msg, Err: err}
+	var herr *HostResourceError
+	if errors.As(err, &herr) {
+		e.Hint = "The host is low on resources. Free disk space or memory, or adjust the --host.* options."
+	} else {
+		// The request was canceled while waiting for resources.
+		e.Retry = true
+	}
+	return e
+}
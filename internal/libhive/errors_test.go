@@ -0,0 +1,62 @@
+package libhive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", ErrNoSuchNode.With("node", "abc"))
+	if !errors.Is(err, ErrNoSuchNode) {
+		t.Error("error with context doesn't match ErrNoSuchNode")
+	}
+	if errors.Is(err, ErrNoSuchTestCase) {
+		t.Error("error matches ErrNoSuchTestCase")
+	}
+	if code := ErrorCodeOf(err); code != CodeNoSuchNode {
+		t.Errorf("wrong code %q", code)
+	}
+	if len(ErrNoSuchNode.Context) != 0 {
+		t.Error("With modified the original error")
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	inner := &Error{Code: CodeBuildFailed, Message: "can't build", Hint: "check the Dockerfile"}
+	err := &Error{
+		Code:    CodeClientStartFailed,
+		Message: "client start failed",
+		Context: map[string]string{"client": "c1"},
+		Err:     inner,
+	}
+	rec := httptest.NewRecorder()
+	writeError(rec, 500, err)
+
+	if ct := rec.Header().Get("content-type"); ct != "application/json" {
+		t.Fatalf("wrong content type %q", ct)
+	}
+	var resp errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := errorResponse{
+		Code:    CodeClientStartFailed,
+		Message: "client start failed: can't build",
+		Context: map[string]string{"client": "c1"},
+		Hint:    "check the Dockerfile",
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Fatalf("wrong response %+v\nwant %+v", resp, want)
+	}
+
+	// Errors without code are sent as text.
+	rec = httptest.NewRecorder()
+	writeError(rec, 400, errors.New("plain"))
+	if body := rec.Body.String(); body != "plain\n" {
+		t.Fatalf("wrong body %q", body)
+	}
+}
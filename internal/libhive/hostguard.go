@@ -0,0 +1,250 @@
+package libhive
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// HostCheck checks a host resource. The check function returns an error
+// describing the problem when the resource is exhausted.
+type HostCheck struct {
+	Name  string
+	Check func() error
+}
+
+// HostGuard watches host resources during a run. When a check fails, starting tests
+// and clients is paused until the resource recovers. If it does not recover within the
+// pause timeout, the guard aborts the run.
+type HostGuard struct {
+	checks       []HostCheck
+	interval     time.Duration
+	pauseTimeout time.Duration
+
+	mu          sync.Mutex
+	pausedSince time.Time
+	resume      chan struct{} // closed when the pause ends
+	abort       chan struct{} // closed when the run is aborted
+	abortErr    error
+	warnings    []guardWarning
+	stop        chan struct{}
+}
+
+type guardWarning struct {
+	time time.Time
+	msg  string
+}
+
+// NewHostGuard creates a guard which runs the given checks at the given interval.
+func NewHostGuard(interval, pauseTimeout time.Duration, checks ...HostCheck) *HostGuard {
+	return &HostGuard{
+		checks:       checks,
+		interval:     interval,
+		pauseTimeout: pauseTimeout,
+		abort:        make(chan struct{}),
+		stop:         make(chan struct{}),
+	}
+}
+
+// Start starts checking resources in the background.
+func (g *HostGuard) Start() {
+	go g.loop()
+}
+
+// Stop ends the background checks.
+func (g *HostGuard) Stop() {
+	close(g.stop)
+}
+
+func (g *HostGuard) loop() {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		g.check(time.Now())
+		select {
+		case <-ticker.C:
+		case <-g.abort:
+			return
+		case <-g.stop:
+			return
+		}
+	}
+}
+
+// check runs all checks and updates the guard state.
+func (g *HostGuard) check(now time.Time) {
+	var problems []string
+	for _, c := range g.checks {
+		if err := c.Check(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", c.Name, err))
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	paused := !g.pausedSince.IsZero()
+	switch {
+	case g.abortErr != nil:
+		return
+	case len(problems) > 0 && !paused:
+		g.pausedSince = now
+		g.resume = make(chan struct{})
+		g.warn(now, "host resources exhausted, pausing new tests: "+strings.Join(problems, "; "))
+	case len(problems) > 0 && now.Sub(g.pausedSince) >= g.pauseTimeout:
+		g.abortErr = &HostResourceError{Problems: problems, Paused: now.Sub(g.pausedSince)}
+		g.warn(now, "aborting run: "+g.abortErr.Error())
+		close(g.abort)
+		close(g.resume)
+	case len(problems) == 0 && paused:
+		g.warn(now, fmt.Sprintf("host resources recovered after %v, resuming tests", now.Sub(g.pausedSince).Round(time.Second)))
+		g.pausedSince = time.Time{}
+		close(g.resume)
+	}
+}
+
+func (g *HostGuard) warn(now time.Time, msg string) {
+	log15.Warn(msg)
+	g.warnings = append(g.warnings, guardWarning{now, msg})
+}
+
+// Wait blocks while new tests are paused. It returns an error if the run was aborted.
+// Calling Wait on a nil guard returns immediately.
+func (g *HostGuard) Wait(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	resume, paused, err := g.resume, !g.pausedSince.IsZero(), g.abortErr
+	g.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if !paused {
+		return nil
+	}
+	select {
+	case <-resume:
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.abortErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Aborted returns a channel which is closed when the guard aborts the run.
+func (g *HostGuard) Aborted() <-chan struct{} {
+	if g == nil {
+		return nil
+	}
+	return g.abort
+}
+
+// Err returns the reason for aborting the run.
+func (g *HostGuard) Err() error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.abortErr
+}
+
+// WarningsSince returns the warnings emitted at or after the given time.
+func (g *HostGuard) WarningsSince(t time.Time) []string {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var msgs []string
+	for _, w := range g.warnings {
+		if !w.time.Before(t) {
+			msgs = append(msgs, w.msg)
+		}
+	}
+	return msgs
+}
+
+// HostResourceError is returned when the run is aborted because host
+// resources did not recover.
+type HostResourceError struct {
+	Problems []string
+	Paused   time.Duration
+}
+
+func (e *HostResourceError) Error() string {
+	return fmt.Sprintf("host resources did not recover within %v: %s", e.Paused.Round(time.Second), strings.Join(e.Problems, "; "))
+}
+
+var errDiskCheckUnsupported = errors.New("disk space check not supported")
+
+// DiskCheck checks that the file system containing dir has at least
+// minFree bytes available.
+func DiskCheck(dir string, minFree uint64) HostCheck {
+	return HostCheck{Name: "disk", Check: func() error {
+		free, err := diskFree(dir)
+		if err == errDiskCheckUnsupported {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%s has %d MB free, need %d MB", dir, free>>20, minFree>>20)
+		}
+		return nil
+	}}
+}
+
+// MemoryCheck checks that the host has at least minFree bytes of memory available.
+// The check is skipped on hosts without /proc/meminfo.
+func MemoryCheck(minFree uint64) HostCheck {
+	return HostCheck{Name: "memory", Check: func() error {
+		free, err := availableMemory("/proc/meminfo")
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%d MB available, need %d MB", free>>20, minFree>>20)
+		}
+		return nil
+	}}
+}
+
+// availableMemory reads MemAvailable from a meminfo file.
+func availableMemory(file string) (uint64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid MemAvailable in %s: %v", file, err)
+			}
+			return kb << 10, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no MemAvailable in %s", file)
+}
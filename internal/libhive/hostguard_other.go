@@ -0,0 +1,8 @@
+// +build !linux,!darwin
+
+package libhive
+
+// diskFree is not supported on this platform.
+func diskFree(dir string) (uint64, error) {
+	return 0, errDiskCheckUnsupported
+}
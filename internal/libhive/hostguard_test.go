@@ -0,0 +1,86 @@
+package libhive
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHostGuard(t *testing.T) {
+	var failing bool
+	check := HostCheck{Name: "test", Check: func() error {
+		if failing {
+			return errors.New("exhausted")
+		}
+		return nil
+	}}
+	g := NewHostGuard(time.Second, time.Minute, check)
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+
+	g.check(now)
+	if err := g.Wait(ctx); err != nil {
+		t.Fatal("Wait returned error while resources are fine:", err)
+	}
+
+	// When the check fails, Wait blocks until resources recover.
+	failing = true
+	g.check(now.Add(time.Second))
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- g.Wait(ctx) }()
+	select {
+	case err := <-waitErr:
+		t.Fatal("Wait returned while paused:", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	failing = false
+	g.check(now.Add(2 * time.Second))
+	if err := <-waitErr; err != nil {
+		t.Fatal("Wait returned error after resume:", err)
+	}
+
+	// When resources don't recover within the pause timeout, the run is aborted.
+	failing = true
+	g.check(now.Add(3 * time.Second))
+	g.check(now.Add(3*time.Second + time.Minute))
+	select {
+	case <-g.Aborted():
+	default:
+		t.Fatal("guard did not abort")
+	}
+	if _, ok := g.Wait(ctx).(*HostResourceError); !ok {
+		t.Fatalf("wrong Wait error after abort: %v", g.Wait(ctx))
+	}
+
+	if n := len(g.WarningsSince(now)); n != 4 {
+		t.Errorf("got %d warnings, want 4", n)
+	}
+	if n := len(g.WarningsSince(now.Add(3 * time.Second))); n != 2 {
+		t.Errorf("got %d warnings since pause, want 2", n)
+	}
+}
+
+func TestAvailableMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hive-meminfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "meminfo")
+	content := "MemTotal:       16314480 kB\nMemFree:         1011884 kB\nMemAvailable:    8388608 kB\n"
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	free, err := availableMemory(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free != 8<<30 {
+		t.Fatalf("wrong available memory %d", free)
+	}
+}
@@ -0,0 +1,15 @@
+// +build linux darwin
+
+package libhive
+
+import "syscall"
+
+// diskFree returns the number of bytes available to unprivileged users
+// in the file system containing dir.
+func diskFree(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), nil
+}
@@ -1,11 +1,14 @@
 package libhive
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
 // branchDelimiter is what separates the client name from the branch, eg: aleth_nightly, go-ethereum_master.
@@ -41,6 +44,147 @@ func (inv Inventory) ClientDirectory(name string) string {
 	return filepath.Join(inv.BaseDir, "clients", filepath.FromSlash(name))
 }
 
+// ClientMetadata reads the hive.yaml metadata file of the given client.
+// The client name may contain a branch specifier.
+func (inv Inventory) ClientMetadata(name string) (*ClientMetadata, error) {
+	dir := inv.ClientDirectory(name)
+	f, err := os.Open(filepath.Join(dir, "hive.yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Eth1 client by default.
+			return &ClientMetadata{Roles: []string{"eth1"}}, nil
+		} else {
+			return nil, metadataError("failed to read hive metadata file", dir, err)
+		}
+	}
+	defer f.Close()
+	var out ClientMetadata
+	if err := yaml.NewDecoder(f).Decode(&out); err != nil {
+		return nil, metadataError("failed to decode hive metadata file", dir, err)
+	}
+	if len(out.Roles) == 0 {
+		out.Roles = []string{"eth1"}
+	}
+	if err := out.validate(dir); err != nil {
+		return nil, metadataError("invalid hive metadata file", dir, err)
+	}
+	return &out, nil
+}
+
+// metadataError creates an error about the hive.yaml file in dir.
+func metadataError(msg, dir string, err error) *Error {
+	return &Error{
+		Code:    CodeInvalidMetadata,
+		Message: fmt.Sprintf("%s in '%s'", msg, dir),
+		Context: map[string]string{"dir": dir},
+		Err:     err,
+	}
+}
+
+// validate checks the metadata of the client in dir.
+func (m *ClientMetadata) validate(dir string) error {
+	for _, role := range m.Roles {
+		if role == "" {
+			return &Error{Code: CodeInvalidMetadata, Message: "empty role"}
+		}
+	}
+	for _, fork := range m.Forks {
+		if fork == "" {
+			return &Error{Code: CodeInvalidMetadata, Message: "empty fork"}
+		}
+	}
+	for name, port := range map[string]int{"rpc": m.Ports.RPC, "engine": m.Ports.Engine, "beacon": m.Ports.Beacon} {
+		if port < 0 || port > 65535 {
+			return &Error{Code: CodeInvalidMetadata, Message: fmt.Sprintf("invalid %s port %d", name, port)}
+		}
+	}
+	if err := m.Resources.validate(); err != nil {
+		return &Error{Code: CodeInvalidMetadata, Message: "resources", Err: err}
+	}
+	for name, file := range m.Dockerfiles {
+		clean := filepath.Clean(filepath.FromSlash(file))
+		switch {
+		case name == "" || file == "":
+			return &Error{Code: CodeInvalidMetadata, Message: "empty Dockerfile name or file"}
+		case filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)):
+			return &Error{Code: CodeInvalidMetadata, Message: fmt.Sprintf("Dockerfile %q is outside of the client directory", name)}
+		}
+		if _, err := os.Stat(filepath.Join(dir, clean)); err != nil {
+			return &Error{Code: CodeInvalidMetadata, Message: fmt.Sprintf("Dockerfile %q", name), Err: err}
+		}
+	}
+	return nil
+}
+
+// HasRole reports whether the client has the given role.
+func (m *ClientMetadata) HasRole(role string) bool {
+	for _, r := range m.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsFork reports whether the client supports the given fork. Clients which
+// don't declare their forks are assumed to support all forks.
+func (m *ClientMetadata) SupportsFork(fork string) bool {
+	if len(m.Forks) == 0 {
+		return true
+	}
+	for _, f := range m.Forks {
+		if f == fork {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultDockerfile is the name of the Dockerfile built when none is selected.
+const DefaultDockerfile = "default"
+
+// BuildInfo returns how the client image is built from the Dockerfile of the given
+// name. The branch, if non-empty, overrides the default 'branch' build argument.
+func (m *ClientMetadata) BuildInfo(dockerfile, branch string) (ClientBuildInfo, error) {
+	info := ClientBuildInfo{Dockerfile: "Dockerfile", BuildArgs: make(map[string]string)}
+	if dockerfile == "" {
+		dockerfile = DefaultDockerfile
+	}
+	if file, ok := m.Dockerfiles[dockerfile]; ok {
+		info.Dockerfile = file
+	} else if dockerfile != DefaultDockerfile {
+		return info, &Error{
+			Code:    CodeInventoryMiss,
+			Message: fmt.Sprintf("unknown Dockerfile %q, supported: %s", dockerfile, strings.Join(m.DockerfileNames(), ", ")),
+		}
+	}
+	for k, v := range m.BuildArgs {
+		info.BuildArgs[k] = v
+	}
+	if branch != "" {
+		info.BuildArgs["branch"] = branch
+	}
+	return info, nil
+}
+
+// DockerfileNames returns the names of the supported Dockerfiles.
+func (m *ClientMetadata) DockerfileNames() []string {
+	names := []string{DefaultDockerfile}
+	for name := range m.Dockerfiles {
+		if name != DefaultDockerfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// ClientBuildInfo describes how a client image is built.
+type ClientBuildInfo struct {
+	Dockerfile string            // path of the Dockerfile, relative to the client directory
+	BuildArgs  map[string]string // build arguments, including 'branch'
+}
+
 // HasSimulator returns true if the inventory contains the given simulator.
 func (inv Inventory) HasSimulator(name string) bool {
 	_, ok := inv.Simulators[name]
@@ -52,6 +196,134 @@ func (inv Inventory) SimulatorDirectory(name string) string {
 	return filepath.Join(inv.BaseDir, "simulators", filepath.FromSlash(name))
 }
 
+// SimulatorMetadata is the content of the optional hive.yaml file in a simulator directory.
+type SimulatorMetadata struct {
+	Description string `yaml:"description" json:"description,omitempty"`
+
+	// Roles are the client roles the simulator tests, e.g. "eth1" or "beacon".
+	Roles []string `yaml:"roles" json:"roles,omitempty"`
+	// Forks are the forks the tested clients must support.
+	Forks []string `yaml:"forks" json:"forks,omitempty"`
+
+	// BuildContext is the docker build context of the simulator image, relative to the
+	// simulator directory. It must be a parent directory of the simulator inside the
+	// hive directory. Simulators which use the hivesim package of this repository through
+	// a replace directive in their go.mod are built with the repository as context.
+	BuildContext string `yaml:"build_context" json:"buildContext,omitempty"`
+
+	// Params is set when the simulator declares parameters. Hive only asks simulators
+	// with this flag for their parameters.
+	Params bool `yaml:"params" json:"params,omitempty"`
+}
+
+// SimulatorMetadata reads the hive.yaml metadata file of the given simulator.
+// Simulators without metadata file have empty metadata.
+func (inv Inventory) SimulatorMetadata(name string) (*SimulatorMetadata, error) {
+	dir := inv.SimulatorDirectory(name)
+	f, err := os.Open(filepath.Join(dir, "hive.yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &SimulatorMetadata{}, nil
+		}
+		return nil, metadataError("failed to read hive metadata file", dir, err)
+	}
+	defer f.Close()
+	var out SimulatorMetadata
+	if err := yaml.NewDecoder(f).Decode(&out); err != nil {
+		return nil, metadataError("failed to decode hive metadata file", dir, err)
+	}
+	for _, list := range [][]string{out.Roles, out.Forks} {
+		for _, elem := range list {
+			if elem == "" {
+				return nil, metadataError("invalid hive metadata file", dir, &Error{Code: CodeInvalidMetadata, Message: "empty role or fork"})
+			}
+		}
+	}
+	if out.BuildContext != "" {
+		if _, _, err := inv.simulatorBuildContext(dir, out.BuildContext); err != nil {
+			return nil, metadataError("invalid hive metadata file", dir, err)
+		}
+	}
+	return &out, nil
+}
+
+// SimulatorBuildContext returns the docker build context directory of the given
+// simulator and the path of its Dockerfile relative to the context.
+func (inv Inventory) SimulatorBuildContext(name string) (contextDir, dockerfile string, err error) {
+	meta, err := inv.SimulatorMetadata(name)
+	if err != nil {
+		return "", "", err
+	}
+	dir := inv.SimulatorDirectory(name)
+	if meta.BuildContext == "" {
+		return dir, "Dockerfile", nil
+	}
+	return inv.simulatorBuildContext(dir, meta.BuildContext)
+}
+
+func (inv Inventory) simulatorBuildContext(dir, buildContext string) (string, string, error) {
+	contextDir := filepath.Join(dir, filepath.FromSlash(buildContext))
+	base, err := filepath.Abs(inv.BaseDir)
+	if err != nil {
+		return "", "", err
+	}
+	abs, err := filepath.Abs(contextDir)
+	if err != nil {
+		return "", "", err
+	}
+	if rel, err := filepath.Rel(base, abs); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", &Error{Code: CodeInvalidMetadata, Message: fmt.Sprintf("build context %q is outside of the hive directory", buildContext)}
+	}
+	dockerfile, err := filepath.Rel(contextDir, filepath.Join(dir, "Dockerfile"))
+	if err != nil || strings.HasPrefix(dockerfile, "..") {
+		return "", "", &Error{Code: CodeInvalidMetadata, Message: fmt.Sprintf("build context %q does not contain the simulator directory", buildContext)}
+	}
+	return contextDir, dockerfile, nil
+}
+
+// InventoryClient is a client with its metadata.
+type InventoryClient struct {
+	Name string         `json:"name"`
+	Meta ClientMetadata `json:"meta"`
+}
+
+// InventorySimulator is a simulator with its metadata.
+type InventorySimulator struct {
+	Name string            `json:"name"`
+	Meta SimulatorMetadata `json:"meta"`
+}
+
+// Catalog lists all clients and simulators of an inventory with their metadata.
+type Catalog struct {
+	Clients    []InventoryClient    `json:"clients"`
+	Simulators []InventorySimulator `json:"simulators"`
+}
+
+// Catalog reads the metadata of all clients and simulators. The lists are sorted by name.
+func (inv Inventory) Catalog() (*Catalog, error) {
+	c := &Catalog{
+		Clients:    make([]InventoryClient, 0, len(inv.Clients)),
+		Simulators: make([]InventorySimulator, 0, len(inv.Simulators)),
+	}
+	for name := range inv.Clients {
+		meta, err := inv.ClientMetadata(name)
+		if err != nil {
+			return nil, err
+		}
+		c.Clients = append(c.Clients, InventoryClient{Name: name, Meta: *meta})
+	}
+	for name := range inv.Simulators {
+		meta, err := inv.SimulatorMetadata(name)
+		if err != nil {
+			return nil, err
+		}
+		c.Simulators = append(c.Simulators, InventorySimulator{Name: name, Meta: *meta})
+	}
+	sort.Slice(c.Clients, func(i, j int) bool { return c.Clients[i].Name < c.Clients[j].Name })
+	sort.Slice(c.Simulators, func(i, j int) bool { return c.Simulators[i].Name < c.Simulators[j].Name })
+	return c, nil
+}
+
 // AddClient ensures the given client name is known to the inventory.
 // This method exists for unit testing purposes only.
 func (inv *Inventory) AddClient(name string) {
@@ -0,0 +1,127 @@
+package libhive
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// InventoryCache holds the inventory of a hive directory. The inventory is loaded
+// on first use and reloaded after the file system has changed.
+type InventoryCache struct {
+	basedir string
+
+	mu      sync.Mutex
+	inv     Inventory
+	valid   bool
+	watcher *fsnotify.Watcher
+}
+
+// NewInventoryCache creates a cache for the inventory of basedir.
+func NewInventoryCache(basedir string) *InventoryCache {
+	return &InventoryCache{basedir: basedir}
+}
+
+// Inventory returns the current inventory.
+func (c *InventoryCache) Inventory() (Inventory, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.valid {
+		inv, err := LoadInventory(c.basedir)
+		if err != nil {
+			return inv, err
+		}
+		c.inv, c.valid = inv, true
+	}
+	return c.inv, nil
+}
+
+// Invalidate drops the cached inventory, forcing a reload on next access.
+func (c *InventoryCache) Invalidate() {
+	c.mu.Lock()
+	c.valid = false
+	c.mu.Unlock()
+}
+
+// Watch starts watching the clients and simulators directories for changes.
+// The cached inventory is invalidated whenever a file is created, removed or
+// renamed in these directories.
+func (c *InventoryCache) Watch() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.watcher != nil {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{"clients", "simulators"} {
+		if err := addWatchTree(w, filepath.Join(c.basedir, dir)); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	c.watcher = w
+	go c.watchLoop(w)
+	return nil
+}
+
+// Close stops watching the file system.
+func (c *InventoryCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.watcher == nil {
+		return nil
+	}
+	err := c.watcher.Close()
+	c.watcher = nil
+	return err
+}
+
+func (c *InventoryCache) watchLoop(w *fsnotify.Watcher) {
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			// New directories are watched as well, so clients added
+			// below them are found.
+			if ev.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					addWatchTree(w, ev.Name)
+				}
+			}
+			log15.Debug("inventory changed", "path", ev.Name, "op", ev.Op)
+			c.Invalidate()
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log15.Warn("inventory watch error", "err", err)
+		}
+	}
+}
+
+// addWatchTree adds dir and all directories below it to the watcher.
+func addWatchTree(w *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return w.Add(path)
+		}
+		return nil
+	})
+}
@@ -1,8 +1,12 @@
 package libhive
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestSplitClientName(t *testing.T) {
@@ -22,6 +26,24 @@ func TestSplitClientName(t *testing.T) {
 	}
 }
 
+func TestClientPortsProxy(t *testing.T) {
+	tests := []struct {
+		ports ClientPorts
+		want  map[uint16]uint16
+	}{
+		{ClientPorts{}, map[uint16]uint16{}},
+		{ClientPorts{RPC: 8545, Engine: 8551}, map[uint16]uint16{}},
+		{ClientPorts{RPC: 8546, Beacon: 5052}, map[uint16]uint16{8545: 8546, 4000: 5052}},
+		// The client's own port 8545 can't be forwarded.
+		{ClientPorts{RPC: 8551, Engine: 8545}, map[uint16]uint16{}},
+	}
+	for _, test := range tests {
+		if proxy := test.ports.ProxyPorts(); !reflect.DeepEqual(proxy, test.want) {
+			t.Errorf("%+v: wrong proxy ports %v, want %v", test.ports, proxy, test.want)
+		}
+	}
+}
+
 func TestInventory(t *testing.T) {
 	basedir := filepath.FromSlash("../..")
 	inv, err := LoadInventory(basedir)
@@ -49,3 +71,196 @@ func TestInventory(t *testing.T) {
 		}
 	})
 }
+
+func TestClientMetadata(t *testing.T) {
+	basedir := t.TempDir()
+	inv := Inventory{BaseDir: basedir}
+	mkclient := func(name, meta string, files ...string) {
+		dir := filepath.Join(basedir, "clients", name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range append(files, "Dockerfile", "hive.yaml") {
+			content := ""
+			if f == "hive.yaml" {
+				content = meta
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, f), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	mkclient("client", `
+dockerfiles:
+  minimal: minimal.Dockerfile
+build_args:
+  branch: stable
+  preset: mainnet
+resources:
+  cpus: 1.5
+  memory: 2g
+`, "minimal.Dockerfile")
+	mkclient("bad-file", "dockerfiles: {x: missing.Dockerfile}")
+	mkclient("bad-dir", "dockerfiles: {x: ../client/Dockerfile}")
+	mkclient("bad-port", "ports: {rpc: 70000}")
+	mkclient("bad-fork", `forks: [""]`)
+	mkclient("bad-memory", "resources: {memory: 2x}")
+	mkclient("bad-cpus", "resources: {cpus: -1}")
+
+	meta, err := inv.ClientMetadata("client_v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(meta.Roles, []string{"eth1"}) {
+		t.Errorf("wrong default roles %v", meta.Roles)
+	}
+	if want := (ContainerResources{CPUs: 1.5, Memory: 2 << 30}); meta.Resources != want {
+		t.Errorf("wrong resources %+v", meta.Resources)
+	}
+	if names := meta.DockerfileNames(); !reflect.DeepEqual(names, []string{"default", "minimal"}) {
+		t.Errorf("wrong Dockerfile names %v", names)
+	}
+	info, err := meta.BuildInfo("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ClientBuildInfo{Dockerfile: "Dockerfile", BuildArgs: map[string]string{"branch": "stable", "preset": "mainnet"}}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("wrong default build info %+v", info)
+	}
+	info, err = meta.BuildInfo("minimal", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = ClientBuildInfo{Dockerfile: "minimal.Dockerfile", BuildArgs: map[string]string{"branch": "v1", "preset": "mainnet"}}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("wrong build info %+v", info)
+	}
+	if _, err := meta.BuildInfo("unknown", ""); err == nil {
+		t.Error("no error for unknown Dockerfile")
+	}
+
+	for _, name := range []string{"bad-file", "bad-dir", "bad-port", "bad-fork", "bad-memory", "bad-cpus"} {
+		if _, err := inv.ClientMetadata(name); err == nil {
+			t.Errorf("no error for invalid metadata of %s", name)
+		}
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	inv, err := LoadInventory(filepath.FromSlash("../.."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := inv.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(catalog.Clients) != len(inv.Clients) || len(catalog.Simulators) != len(inv.Simulators) {
+		t.Fatalf("catalog has %d clients and %d simulators, want %d and %d", len(catalog.Clients), len(catalog.Simulators), len(inv.Clients), len(inv.Simulators))
+	}
+	for _, sim := range catalog.Simulators {
+		if sim.Name != "eth2/testnet" {
+			continue
+		}
+		if want := []string{"eth1", "beacon", "validator"}; !reflect.DeepEqual(sim.Meta.Roles, want) {
+			t.Errorf("wrong roles of eth2/testnet %v, want %v", sim.Meta.Roles, want)
+		}
+		if sim.Meta.Description == "" {
+			t.Error("eth2/testnet has no description")
+		}
+		return
+	}
+	t.Error("eth2/testnet missing in catalog")
+}
+
+func TestSimulatorMetadata(t *testing.T) {
+	basedir := t.TempDir()
+	inv := Inventory{BaseDir: basedir}
+	mksim := func(name, meta string) {
+		dir := filepath.Join(basedir, "simulators", name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if meta != "" {
+			if err := ioutil.WriteFile(filepath.Join(dir, "hive.yaml"), []byte(meta), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	mksim("no-meta", "")
+	mksim("sim", "description: test\nroles: [beacon]\nparams: true")
+	mksim("bad-role", `roles: [""]`)
+	mksim("group/repo-context", "build_context: ../../..")
+	mksim("bad-context", "build_context: ../../../..")
+
+	meta, err := inv.SimulatorMetadata("no-meta")
+	if err != nil || !reflect.DeepEqual(meta, &SimulatorMetadata{}) {
+		t.Errorf("wrong metadata of simulator without hive.yaml: %+v, %v", meta, err)
+	}
+	meta, err = inv.SimulatorMetadata("sim")
+	if want := (&SimulatorMetadata{Description: "test", Roles: []string{"beacon"}, Params: true}); err != nil || !reflect.DeepEqual(meta, want) {
+		t.Errorf("wrong metadata %+v, %v", meta, err)
+	}
+	if _, err := inv.SimulatorMetadata("bad-role"); ErrorCodeOf(err) != CodeInvalidMetadata {
+		t.Errorf("wrong error for empty role: %v", err)
+	}
+	if _, err := inv.SimulatorMetadata("bad-context"); err == nil {
+		t.Error("no error for build context outside of the hive directory")
+	}
+
+	dir, dockerfile, err := inv.SimulatorBuildContext("sim")
+	if err != nil || dir != inv.SimulatorDirectory("sim") || dockerfile != "Dockerfile" {
+		t.Errorf("wrong default build context %q %q, %v", dir, dockerfile, err)
+	}
+	dir, dockerfile, err = inv.SimulatorBuildContext("group/repo-context")
+	if err != nil || filepath.Clean(dir) != filepath.Clean(basedir) || dockerfile != filepath.Join("simulators", "group", "repo-context", "Dockerfile") {
+		t.Errorf("wrong build context %q %q, %v", dir, dockerfile, err)
+	}
+}
+
+func TestInventoryCacheWatch(t *testing.T) {
+	basedir := t.TempDir()
+	mkclient := func(name string) {
+		dir := filepath.Join(basedir, "clients", name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mkclient("client-1")
+	os.MkdirAll(filepath.Join(basedir, "simulators"), 0755)
+
+	cache := NewInventoryCache(basedir)
+	if err := cache.Watch(); err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	inv, err := cache.Inventory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inv.HasClient("client-1") {
+		t.Fatal("can't find client-1")
+	}
+
+	// Add a client. It should appear in the inventory after the watcher has
+	// processed the change.
+	mkclient("group/client-2")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		inv, err = cache.Inventory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inv.HasClient("group/client-2") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("new client not found in inventory")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
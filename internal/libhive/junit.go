@@ -0,0 +1,217 @@
+package libhive
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+// These are the supported result file formats.
+const (
+	ResultFormatJSON  = "json"  // hive's own format, read by hiveview
+	ResultFormatJUnit = "junit" // JUnit XML, for CI test reporting
+)
+
+// ResultFormats is the list of result file formats written for each test suite.
+// An empty list selects the JSON format.
+type ResultFormats []string
+
+// ParseResultFormats parses a list of result formats.
+func ParseResultFormats(list []string) (ResultFormats, error) {
+	var formats ResultFormats
+	for _, f := range list {
+		switch f {
+		case ResultFormatJSON, ResultFormatJUnit:
+			formats = append(formats, f)
+		default:
+			return nil, fmt.Errorf("unknown result format %q", f)
+		}
+	}
+	return formats, nil
+}
+
+// Has reports whether the format f is selected.
+func (formats ResultFormats) Has(f string) bool {
+	if len(formats) == 0 {
+		return f == ResultFormatJSON
+	}
+	for _, format := range formats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+// junitMaxMessage is the max length of the failure message attribute. The full
+// failure details are stored in the element content.
+const junitMaxMessage = 200
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Details string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+	Details string `xml:",chardata"`
+}
+
+// junitReport converts a test suite to JUnit XML. Failures caused by the simulator
+// or the host are reported as errors, all other failures as test failures.
+func junitReport(s *TestSuite, started time.Time) *junitTestSuites {
+	suite := junitTestSuite{
+		Name:      s.Name,
+		Timestamp: started.UTC().Format("2006-01-02T15:04:05"),
+	}
+	if s.RunID != "" {
+		suite.Properties = append(suite.Properties, junitProperty{"runID", s.RunID})
+	}
+	for _, k := range sortedStringKeys(s.Tags) {
+		suite.Properties = append(suite.Properties, junitProperty{"tag." + k, s.Tags[k]})
+	}
+	for _, k := range sortedStringKeys(s.ClientVersions) {
+		suite.Properties = append(suite.Properties, junitProperty{"client." + k, s.ClientVersions[k]})
+	}
+
+	ids := make([]TestID, 0, len(s.TestCases))
+	for id := range s.TestCases {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	end := started
+	for _, id := range ids {
+		test := s.TestCases[id]
+		tc := junitTestCase{
+			Name:      test.Name,
+			ClassName: s.Name,
+			Time:      junitDuration(test.End.Sub(test.Start)),
+		}
+		switch {
+		case test.SummaryResult.NotRun:
+			tc.Skipped = &junitSkipped{Message: "not run", Details: test.SummaryResult.Details}
+			suite.Skipped++
+		case test.SummaryResult.Skipped():
+			tc.Skipped = &junitSkipped{Message: string(test.SummaryResult.Skip), Details: test.SummaryResult.Details}
+			suite.Skipped++
+		}
+		if !test.SummaryResult.Pass {
+			f := &junitFailure{
+				Message: junitMessage(test.SummaryResult.Details),
+				Type:    string(test.SummaryResult.Category),
+				Details: test.SummaryResult.Details,
+			}
+			switch test.SummaryResult.Category {
+			case FailureHarnessError, FailureInfrastructure:
+				tc.Error = f
+				suite.Errors++
+			default:
+				tc.Failure = f
+				suite.Failures++
+			}
+		}
+		if len(test.ClientInfo) > 0 {
+			var logs []string
+			for _, id := range sortedClientInfoKeys(test.ClientInfo) {
+				info := test.ClientInfo[id]
+				logs = append(logs, fmt.Sprintf("client %s (%s): %s", info.Name, id, info.LogFile))
+			}
+			tc.SystemOut = strings.Join(logs, "\n")
+		}
+		if test.End.After(end) {
+			end = test.End
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+	suite.Time = junitDuration(end.Sub(started))
+	return &junitTestSuites{Suites: []junitTestSuite{suite}}
+}
+
+// writeJUnitFile writes the JUnit report of a test suite to file.
+func writeJUnitFile(s *TestSuite, started time.Time, file string) error {
+	content, err := xml.MarshalIndent(junitReport(s, started), "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append([]byte(xml.Header), content...)
+	return ioutil.WriteFile(file, append(content, '\n'), 0644)
+}
+
+// junitDuration formats a duration in seconds.
+func junitDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// junitMessage returns the first line of the failure details.
+func junitMessage(details string) string {
+	msg := strings.TrimSpace(details)
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	if len(msg) > junitMaxMessage {
+		msg = msg[:junitMaxMessage] + "..."
+	}
+	if msg == "" {
+		msg = "test failed"
+	}
+	return msg
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedClientInfoKeys(m map[string]*ClientInfo) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
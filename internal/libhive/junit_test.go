@@ -0,0 +1,117 @@
+package libhive
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJUnitReport(t *testing.T) {
+	start := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	suite := &TestSuite{
+		Name:           "suite",
+		RunID:          "run1",
+		Tags:           map[string]string{"pr": "1"},
+		ClientVersions: map[string]string{"go-ethereum": "Geth/v1.10.8"},
+		TestCases: map[TestID]*TestCase{
+			3: {Name: "error", Start: start, End: start.Add(3 * time.Second), SummaryResult: TestResult{Details: "docker died", Category: FailureInfrastructure}},
+			1: {Name: "ok", Start: start, End: start.Add(1500 * time.Millisecond), SummaryResult: TestResult{Pass: true}},
+			2: {
+				Name: "bad", Start: start, End: start.Add(time.Second),
+				SummaryResult: TestResult{Details: "wrong balance\nwant 1, got 2 \x00", Category: FailureAssertion},
+				ClientInfo:    map[string]*ClientInfo{"abc": {ID: "abc", Name: "go-ethereum", LogFile: "go-ethereum/client-abc.log"}},
+			},
+			4: {Name: "skipped", Start: start, End: start, SummaryResult: TestResult{Pass: true, Skip: SkipUnsupportedFork, Details: "no cancun"}},
+		},
+	}
+
+	content, err := xml.Marshal(junitReport(suite, start))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(content, &report); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, content)
+	}
+	s := report.Suites[0]
+	if s.Name != "suite" || s.Tests != 4 || s.Failures != 1 || s.Errors != 1 || s.Skipped != 1 {
+		t.Fatalf("wrong suite: %+v", s)
+	}
+	if s.Time != "3.000" || s.Timestamp != "2021-03-04T05:06:07" {
+		t.Errorf("wrong suite time: %s %s", s.Time, s.Timestamp)
+	}
+	wantProps := []junitProperty{{"runID", "run1"}, {"tag.pr", "1"}, {"client.go-ethereum", "Geth/v1.10.8"}}
+	if len(s.Properties) != len(wantProps) {
+		t.Fatalf("wrong properties: %v", s.Properties)
+	}
+	for i := range wantProps {
+		if s.Properties[i] != wantProps[i] {
+			t.Errorf("wrong property %d: %v", i, s.Properties[i])
+		}
+	}
+
+	ok, bad, errored := s.TestCases[0], s.TestCases[1], s.TestCases[2]
+	if ok.Name != "ok" || ok.Time != "1.500" || ok.Failure != nil || ok.Error != nil {
+		t.Errorf("wrong passing test: %+v", ok)
+	}
+	if bad.Failure == nil || bad.Failure.Message != "wrong balance" || bad.Failure.Type != "assertion" {
+		t.Fatalf("wrong failure: %+v", bad.Failure)
+	}
+	if !strings.HasPrefix(bad.Failure.Details, "wrong balance\nwant 1, got 2") {
+		t.Errorf("wrong failure details: %q", bad.Failure.Details)
+	}
+	if bad.SystemOut != "client go-ethereum (abc): go-ethereum/client-abc.log" {
+		t.Errorf("wrong system-out: %q", bad.SystemOut)
+	}
+	if errored.Error == nil || errored.Failure != nil || errored.Error.Type != "infrastructure" {
+		t.Errorf("infrastructure failure not reported as error: %+v", errored)
+	}
+	if skipped := s.TestCases[3]; skipped.Skipped == nil || skipped.Skipped.Message != "unsupported-fork" || skipped.Failure != nil {
+		t.Errorf("wrong skipped test: %+v", skipped)
+	}
+}
+
+func TestWriteSuiteFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hive-results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	suite := &TestSuite{Name: "suite", TestCases: map[TestID]*TestCase{}}
+	tests := []struct {
+		formats ResultFormats
+		want    []string
+	}{
+		{nil, []string{".json"}},
+		{ResultFormats{ResultFormatJUnit}, []string{".xml"}},
+		{ResultFormats{ResultFormatJUnit, ResultFormatJSON}, []string{".json", ".xml"}},
+	}
+	for i, test := range tests {
+		sub := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeSuiteFiles(suite, time.Now(), sub, test.formats); err != nil {
+			t.Fatal(err)
+		}
+		files, _ := ioutil.ReadDir(sub)
+		if len(files) != len(test.want) {
+			t.Fatalf("formats %v: got %d files, want %d", test.formats, len(files), len(test.want))
+		}
+		for j, f := range files {
+			if filepath.Ext(f.Name()) != test.want[j] {
+				t.Errorf("formats %v: wrong file %s", test.formats, f.Name())
+			}
+		}
+	}
+
+	if _, err := ParseResultFormats([]string{"json", "xml"}); err == nil {
+		t.Error("no error for unknown format")
+	}
+}
@@ -0,0 +1,72 @@
+package libhive
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressedLogExt is the file name extension of zstd-compressed log files. Log files
+// with this extension are compressed by the container backend while they are written.
+const CompressedLogExt = ".zst"
+
+// IsCompressedLog reports whether the log file is compressed.
+func IsCompressedLog(file string) bool {
+	return strings.HasSuffix(file, CompressedLogExt)
+}
+
+// logFileName returns the name of a log file with base name 'name'.
+func (env *SimEnv) logFileName(name string) string {
+	if env.CompressLogs {
+		return name + CompressedLogExt
+	}
+	return name
+}
+
+// NewLogWriter returns a writer which compresses the output written to w if the
+// log file is compressed. Otherwise it returns w. The returned writer must be closed
+// before w is closed.
+func NewLogWriter(file string, w io.Writer) io.WriteCloser {
+	if IsCompressedLog(file) {
+		// NewWriter only fails for invalid options.
+		zw, _ := zstd.NewWriter(w)
+		return zw
+	}
+	return nopWriteCloser{w}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// OpenLog opens a log file for reading. Compressed log files are decompressed while
+// reading. The compressor holds back the most recent output of a log which is still
+// being written, so the content of such logs can lag behind by up to 128KB.
+func OpenLog(file string) (io.ReadCloser, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	if !IsCompressedLog(file) {
+		return fd, nil
+	}
+	zr, err := zstd.NewReader(fd)
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+	return &logReader{Decoder: zr, fd: fd}, nil
+}
+
+// logReader decompresses a log file and closes the file when closed.
+type logReader struct {
+	*zstd.Decoder
+	fd *os.File
+}
+
+func (r *logReader) Close() error {
+	r.Decoder.Close()
+	return r.fd.Close()
+}
@@ -0,0 +1,74 @@
+package libhive
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompressedLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hive-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"client.log", "client.log" + CompressedLogExt} {
+		file := filepath.Join(dir, name)
+		fd, err := os.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewLogWriter(file, fd)
+		w.Write([]byte("line 1\nline 2\n"))
+		w.Write([]byte("line 3\n"))
+		w.Close()
+		fd.Close()
+
+		logs, err := readLogLines(file, 1, 10)
+		if err != nil {
+			t.Fatalf("%s: can't read: %v", name, err)
+		}
+		want := &ClientLogs{Lines: []string{"line 2", "line 3"}, NextLine: 3}
+		if !reflect.DeepEqual(logs, want) {
+			t.Errorf("%s: wrong lines %+v", name, logs)
+		}
+	}
+
+	// The compressed log must actually be compressed.
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "client.log"+CompressedLogExt))
+	if string(content) == "line 1\nline 2\nline 3\n" {
+		t.Error("log not compressed")
+	}
+}
+
+// This test checks that compressed logs which are still being written can be read.
+func TestCompressedLogWriting(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "client.log"+CompressedLogExt)
+	fd, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	w := NewLogWriter(file, fd)
+	defer w.Close()
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(w, "line %d with some output of the client\n", i)
+	}
+
+	logs, err := readLogLines(file, 0, clientLogsMaxLines)
+	if err != nil {
+		t.Fatal("can't read log being written:", err)
+	}
+	if len(logs.Lines) == 0 {
+		t.Fatal("no lines read from log being written")
+	}
+	for i, line := range logs.Lines {
+		if want := fmt.Sprintf("line %d with some output of the client", i); line != want {
+			t.Fatalf("wrong line %d: %q", i, line)
+		}
+	}
+}
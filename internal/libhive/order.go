@@ -0,0 +1,101 @@
+package libhive
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// These are the strategies for ordering the tests of a suite.
+const (
+	OrderDefault      = ""              // tests run in the order defined by the simulator
+	OrderAlpha        = "alpha"         // tests are sorted by name
+	OrderRandom       = "random"        // tests are shuffled
+	OrderSlowestFirst = "slowest-first" // slowest tests of the baseline run come first
+	OrderFailedFirst  = "failed-first"  // tests failed in the baseline run come first
+)
+
+// TestOrder configures the order in which simulators run tests.
+type TestOrder struct {
+	Strategy string
+	Seed     int64 // seed of the random order
+
+	// History contains the results of the baseline run, keyed by "suite/test".
+	History map[string]TestHistory
+}
+
+// TestHistory is the result of a test in the baseline run.
+type TestHistory struct {
+	Duration time.Duration
+	Failed   bool
+}
+
+// CheckOrderStrategy validates a test order strategy.
+func CheckOrderStrategy(strategy string) error {
+	switch strategy {
+	case OrderDefault, OrderAlpha, OrderRandom, OrderSlowestFirst, OrderFailedFirst:
+		return nil
+	default:
+		return fmt.Errorf("unknown test order %q", strategy)
+	}
+}
+
+// NeedsHistory reports whether the strategy uses the results of a baseline run.
+func (o *TestOrder) NeedsHistory() bool {
+	return o.Strategy == OrderSlowestFirst || o.Strategy == OrderFailedFirst
+}
+
+// Sort returns the order in which the tests of a suite should run, as indexes into
+// tests. Each element of tests contains the names of a single test definition. A test
+// can have more than one name when it runs once for every client.
+func (o *TestOrder) Sort(suite string, tests [][]string) []int {
+	order := make([]int, len(tests))
+	for i := range order {
+		order[i] = i
+	}
+	switch o.Strategy {
+	case OrderAlpha:
+		sort.SliceStable(order, func(i, j int) bool {
+			return firstName(tests[order[i]]) < firstName(tests[order[j]])
+		})
+	case OrderRandom:
+		// The seed is combined with the suite name, so suites with the
+		// same number of tests are not shuffled the same way.
+		h := fnv.New64a()
+		h.Write([]byte(suite))
+		rng := rand.New(rand.NewSource(o.Seed ^ int64(h.Sum64())))
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	case OrderSlowestFirst:
+		durations := make([]time.Duration, len(tests))
+		for i, names := range tests {
+			for _, name := range names {
+				if d := o.History[suite+"/"+name].Duration; d > durations[i] {
+					durations[i] = d
+				}
+			}
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return durations[order[i]] > durations[order[j]]
+		})
+	case OrderFailedFirst:
+		failed := make([]bool, len(tests))
+		for i, names := range tests {
+			for _, name := range names {
+				failed[i] = failed[i] || o.History[suite+"/"+name].Failed
+			}
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return failed[order[i]] && !failed[order[j]]
+		})
+	}
+	return order
+}
+
+func firstName(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
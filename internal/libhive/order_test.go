@@ -0,0 +1,51 @@
+package libhive
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOrderSort(t *testing.T) {
+	tests := [][]string{{"c"}, {"a (geth)", "a (besu)"}, {"b"}, {"d"}}
+	history := map[string]TestHistory{
+		"s/a (besu)": {Duration: 5 * time.Second, Failed: true},
+		"s/b":        {Duration: 10 * time.Second},
+		"s/d":        {Duration: time.Second, Failed: true},
+	}
+	cases := []struct {
+		strategy string
+		want     []int
+	}{
+		{OrderDefault, []int{0, 1, 2, 3}},
+		{OrderAlpha, []int{1, 2, 0, 3}},
+		{OrderSlowestFirst, []int{2, 1, 3, 0}},
+		{OrderFailedFirst, []int{1, 3, 0, 2}},
+	}
+	for _, c := range cases {
+		o := TestOrder{Strategy: c.strategy, History: history}
+		if order := o.Sort("s", tests); !reflect.DeepEqual(order, c.want) {
+			t.Errorf("%q: wrong order %v, want %v", c.strategy, order, c.want)
+		}
+	}
+}
+
+func TestOrderRandom(t *testing.T) {
+	tests := make([][]string, 20)
+	o := TestOrder{Strategy: OrderRandom, Seed: 1}
+	order := o.Sort("s", tests)
+	if !reflect.DeepEqual(order, o.Sort("s", tests)) {
+		t.Fatal("random order is not deterministic for the same seed")
+	}
+	seen := make(map[int]bool)
+	for _, i := range order {
+		seen[i] = true
+	}
+	if len(seen) != len(tests) {
+		t.Fatalf("order is not a permutation: %v", order)
+	}
+	o.Seed = 2
+	if reflect.DeepEqual(order, o.Sort("s", tests)) {
+		t.Fatal("different seeds give the same order")
+	}
+}
@@ -0,0 +1,316 @@
+package libhive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	otlpBatchSize     = 100             // spans per export request
+	otlpFlushInterval = 5 * time.Second // max time a span waits for export
+)
+
+// OTLPExporter publishes test suites and test cases as trace spans to an
+// OpenTelemetry collector, using OTLP over HTTP with JSON encoding.
+//
+// Every test suite is a trace. The suite span is the root, and test cases are its
+// children. Failed tests have error status and a 'failure' event containing the
+// failure category and details. Spans are exported when the suite or test ends.
+type OTLPExporter struct {
+	nopSink
+
+	url      string
+	headers  map[string]string
+	resource []otlpAttribute
+	client   *http.Client
+	queue    *sinkQueue
+}
+
+// NewOTLPExporter creates an exporter sending to the given collector endpoint, e.g.
+// http://localhost:4318. The headers are added to all requests, e.g. for API keys.
+// The run ID and tags are published as resource attributes.
+func NewOTLPExporter(endpoint string, headers map[string]string, runID string, tags map[string]string) *OTLPExporter {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	resource := []otlpAttribute{stringAttr("service.name", "hive")}
+	if runID != "" {
+		resource = append(resource, stringAttr("hive.run", runID))
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resource = append(resource, stringAttr("hive.tag."+k, tags[k]))
+	}
+
+	e := &OTLPExporter{
+		url:      url,
+		headers:  headers,
+		resource: resource,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	e.queue = newSinkQueue(otlpBatchSize, otlpFlushInterval, e.export)
+	return e
+}
+
+// Close exports all pending spans and stops the exporter.
+// It is safe to call Close multiple times.
+func (e *OTLPExporter) Close() {
+	if e == nil {
+		return
+	}
+	e.queue.close()
+}
+
+// suiteEnded exports the span of a test suite.
+func (e *OTLPExporter) suiteEnded(sim string, suiteID TestSuiteID, suite *TestSuite, start time.Time) {
+	if e == nil {
+		return
+	}
+	tests, fails, skips := suiteCounts(suite)
+	span := otlpSpan{
+		TraceID: traceID(sim, suiteID, suite.RunID),
+		SpanID:  spanID(sim, suiteID, suite.RunID, 0),
+		Name:    suite.Name,
+		Kind:    otlpSpanKindInternal,
+		Start:   otlpTime(start),
+		End:     otlpTime(time.Now()),
+		Attributes: []otlpAttribute{
+			stringAttr("hive.simulator", sim),
+			stringAttr("hive.suite", suite.Name),
+			intAttr("hive.suite.tests", tests),
+			intAttr("hive.suite.fails", fails),
+			intAttr("hive.suite.skips", skips),
+		},
+		Status: otlpStatus{Code: otlpStatusOK},
+	}
+	if fails > 0 {
+		span.Status = otlpStatus{Code: otlpStatusError, Message: fmt.Sprintf("%d tests failed", fails)}
+	}
+	e.add(span)
+}
+
+// testEnded exports the span of a test case.
+func (e *OTLPExporter) testEnded(sim string, suiteID TestSuiteID, suite *TestSuite, testID TestID, test *TestCase) {
+	if e == nil {
+		return
+	}
+	var clients []string
+	for _, c := range test.ClientInfo {
+		if !contains(clients, c.Name) {
+			clients = append(clients, c.Name)
+		}
+	}
+	sort.Strings(clients)
+
+	span := otlpSpan{
+		TraceID:  traceID(sim, suiteID, suite.RunID),
+		SpanID:   spanID(sim, suiteID, suite.RunID, testID),
+		ParentID: spanID(sim, suiteID, suite.RunID, 0),
+		Name:     test.Name,
+		Kind:     otlpSpanKindInternal,
+		Start:    otlpTime(test.Start),
+		End:      otlpTime(test.End),
+		Attributes: []otlpAttribute{
+			stringAttr("hive.simulator", sim),
+			stringAttr("hive.suite", suite.Name),
+			stringAttr("hive.test", test.Name),
+			intAttr("hive.test.id", int(testID)),
+			stringAttr("hive.clients", strings.Join(clients, ",")),
+			boolAttr("hive.test.pass", test.SummaryResult.Pass),
+		},
+		Status: otlpStatus{Code: otlpStatusOK},
+	}
+	if test.SummaryResult.NotRun {
+		span.Attributes = append(span.Attributes, boolAttr("hive.test.notrun", true))
+	}
+	if test.SummaryResult.Skipped() {
+		span.Attributes = append(span.Attributes, stringAttr("hive.skip.reason", string(test.SummaryResult.Skip)))
+	}
+	if !test.SummaryResult.Pass {
+		details := truncateDetails(test.SummaryResult.Details)
+		category := string(test.SummaryResult.Category)
+		span.Attributes = append(span.Attributes, stringAttr("hive.failure.category", category))
+		span.Status = otlpStatus{Code: otlpStatusError, Message: category}
+		span.Events = []otlpEvent{{
+			Time: otlpTime(test.End),
+			Name: "failure",
+			Attributes: []otlpAttribute{
+				stringAttr("hive.failure.category", category),
+				stringAttr("hive.failure.details", details),
+			},
+		}}
+	}
+	e.add(span)
+}
+
+func (e *OTLPExporter) add(span otlpSpan) {
+	if !e.queue.add(span) {
+		log15.Warn("telemetry queue full, dropping span", "name", span.Name)
+	}
+}
+
+// export sends a batch of spans.
+func (e *OTLPExporter) export(batch []interface{}) {
+	spans := make([]otlpSpan, len(batch))
+	for i, span := range batch {
+		spans[i] = span.(otlpSpan)
+	}
+	if err := e.send(spans); err != nil {
+		log15.Warn("can't export test spans", "url", e.url, "spans", len(spans), "err", err)
+	}
+}
+
+func (e *OTLPExporter) send(spans []otlpSpan) error {
+	body, err := json.Marshal(&otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: e.resource},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "hive"}, Spans: spans}},
+	}}})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// traceID derives the trace ID of a test suite. IDs are derived from the run, simulator
+// and suite, so spans of the same suite can be created independently.
+func traceID(sim string, suiteID TestSuiteID, runID string) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d", runID, sim, suiteID)))
+	return hex.EncodeToString(h[:16])
+}
+
+// spanID derives the span ID of a test case. The suite span has test ID zero.
+func spanID(sim string, suiteID TestSuiteID, runID string, testID TestID) string {
+	var id [8]byte
+	binary.BigEndian.PutUint32(id[4:], uint32(testID))
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00span", runID, sim, suiteID)))
+	for i := range id {
+		id[i] ^= h[i]
+	}
+	return hex.EncodeToString(id[:])
+}
+
+func contains(list []string, s string) bool {
+	for _, elem := range list {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
+
+// These types are the OTLP/JSON encoding of ExportTraceServiceRequest.
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID    string          `json:"traceId"`
+	SpanID     string          `json:"spanId"`
+	ParentID   string          `json:"parentSpanId,omitempty"`
+	Name       string          `json:"name"`
+	Kind       int             `json:"kind"`
+	Start      string          `json:"startTimeUnixNano"`
+	End        string          `json:"endTimeUnixNano"`
+	Attributes []otlpAttribute `json:"attributes,omitempty"`
+	Events     []otlpEvent     `json:"events,omitempty"`
+	Status     otlpStatus      `json:"status"`
+}
+
+type otlpEvent struct {
+	Time       string          `json:"timeUnixNano"`
+	Name       string          `json:"name"`
+	Attributes []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	String *string `json:"stringValue,omitempty"`
+	Bool   *bool   `json:"boolValue,omitempty"`
+	Int    string  `json:"intValue,omitempty"` // int64 is encoded as a string
+}
+
+func stringAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{String: &value}}
+}
+
+func boolAttr(key string, value bool) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{Bool: &value}}
+}
+
+func intAttr(key string, value int) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{Int: strconv.Itoa(value)}}
+}
+
+// otlpTime encodes a timestamp as nanoseconds since the epoch.
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,70 @@
+package libhive
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []otlpRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("x-api-key") != "secret" {
+			t.Errorf("wrong request: %s %v", r.URL.Path, r.Header)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	e := NewOTLPExporter(srv.URL, map[string]string{"x-api-key": "secret"}, "run1", map[string]string{"pr": "1"})
+	start := time.Now()
+	suite := &TestSuite{Name: "suite", RunID: "run1", TestCases: map[TestID]*TestCase{
+		1: {Name: "ok", Start: start, End: start, SummaryResult: TestResult{Pass: true}},
+		2: {Name: "bad", Start: start, End: start, SummaryResult: TestResult{Details: "boom", Category: FailureAssertion}},
+	}}
+	e.testEnded("sim", 0, suite, 1, suite.TestCases[1])
+	e.testEnded("sim", 0, suite, 2, suite.TestCases[2])
+	e.suiteEnded("sim", 0, suite, start)
+	e.Close()
+	e.Close()
+
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	rs := requests[0].ResourceSpans[0]
+	if len(rs.Resource.Attributes) != 3 || *rs.Resource.Attributes[2].Value.String != "1" {
+		t.Errorf("wrong resource attributes: %+v", rs.Resource.Attributes)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	ok, bad, root := spans[0], spans[1], spans[2]
+	if root.ParentID != "" || ok.ParentID != root.SpanID || bad.ParentID != root.SpanID {
+		t.Error("test spans are not children of the suite span")
+	}
+	if ok.TraceID != root.TraceID || bad.TraceID != root.TraceID {
+		t.Error("spans have different trace IDs")
+	}
+	if ok.SpanID == bad.SpanID {
+		t.Error("test spans have the same ID")
+	}
+	if ok.Status.Code != otlpStatusOK || bad.Status.Code != otlpStatusError || root.Status.Code != otlpStatusError {
+		t.Errorf("wrong span status: ok %d, bad %d, suite %d", ok.Status.Code, bad.Status.Code, root.Status.Code)
+	}
+	if len(bad.Events) != 1 || *bad.Events[0].Attributes[1].Value.String != "boom" {
+		t.Errorf("wrong failure event: %+v", bad.Events)
+	}
+}
@@ -0,0 +1,193 @@
+package libhive
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PlannedTest describes a test definition of a suite for planning the suite.
+type PlannedTest struct {
+	// Names contains the names of the test. A test can have more than one
+	// name when it runs once for every client.
+	Names       []string `json:"names"`
+	Description string   `json:"description,omitempty"`
+	// Duration is the estimated run time of the test in seconds.
+	Duration float64 `json:"duration,omitempty"`
+	// Requires contains the indexes of the tests which must run before this test.
+	Requires []int `json:"requires,omitempty"`
+}
+
+// TestPlanRequest is the request body of the test plan endpoint.
+type TestPlanRequest struct {
+	Tests []PlannedTest `json:"tests"`
+}
+
+// TestPlan is the response of the test plan endpoint.
+type TestPlan struct {
+	Order  []int `json:"order"`            // tests to run, in order
+	NotRun []int `json:"notRun,omitempty"` // tests which don't fit in the time budget
+}
+
+// planner creates the plan of a suite's tests.
+type planner struct {
+	order   TestOrder
+	shard   TestShard
+	limited bool          // set if the tests have a time budget
+	budget  time.Duration // remaining time, if limited
+}
+
+// plan returns the tests of the suite which should run, and the order in which they run.
+//
+// The tests are sorted according to the test order. When there is a time budget and no
+// test order is configured, the longest tests come first. Tests always run after the
+// tests they require. Tests connected by requirements are sharded together. When there is
+// a time budget, tests which don't fit into the remaining budget, and tests requiring
+// them, are left out. Tests without an estimated duration always fit.
+func (p *planner) plan(suite string, tests []PlannedTest) (*TestPlan, error) {
+	if err := checkRequirements(tests); err != nil {
+		return nil, err
+	}
+	names := make([][]string, len(tests))
+	for i, t := range tests {
+		names[i] = t.Names
+	}
+	order := p.order.Sort(suite, names)
+	if p.limited && p.order.Strategy == OrderDefault {
+		sort.SliceStable(order, func(i, j int) bool {
+			return p.estimate(suite, tests[order[i]]) > p.estimate(suite, tests[order[j]])
+		})
+	}
+	order = orderRequirements(tests, order)
+	order = p.shard.Filter(suite, shardKeys(tests), order)
+
+	plan := &TestPlan{Order: []int{}}
+	if !p.limited {
+		plan.Order = append(plan.Order, order...)
+		return plan, nil
+	}
+	var (
+		remaining = p.budget
+		notRun    = make(map[int]bool)
+	)
+	for _, index := range order {
+		skip := false
+		for _, req := range tests[index].Requires {
+			skip = skip || notRun[req]
+		}
+		if d := p.estimate(suite, tests[index]); !skip && d <= remaining {
+			remaining -= d
+			plan.Order = append(plan.Order, index)
+		} else {
+			notRun[index] = true
+			plan.NotRun = append(plan.NotRun, index)
+		}
+	}
+	return plan, nil
+}
+
+// estimate returns the estimated duration of a test. Tests without an estimate
+// take as long as in the baseline run.
+func (p *planner) estimate(suite string, t PlannedTest) time.Duration {
+	if t.Duration > 0 {
+		return time.Duration(t.Duration * float64(time.Second))
+	}
+	var d time.Duration
+	for _, name := range t.Names {
+		if h := p.order.History[suite+"/"+name].Duration; h > d {
+			d = h
+		}
+	}
+	return d
+}
+
+// checkRequirements verifies that all required tests exist and that there
+// are no circular requirements.
+func checkRequirements(tests []PlannedTest) error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(tests))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return &Error{Code: CodeInvalidRequest, Message: fmt.Sprintf("test %q requires itself", firstName(tests[i].Names))}
+		case done:
+			return nil
+		}
+		state[i] = visiting
+		for _, req := range tests[i].Requires {
+			if req < 0 || req >= len(tests) {
+				return &Error{Code: CodeInvalidRequest, Message: fmt.Sprintf("test %q requires unknown test %d", firstName(tests[i].Names), req)}
+			}
+			if err := visit(req); err != nil {
+				return err
+			}
+		}
+		state[i] = done
+		return nil
+	}
+	for i := range tests {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderRequirements reorders tests so that every test comes after the tests it
+// requires. Otherwise, the tests keep their order. The requirements must not be circular.
+func orderRequirements(tests []PlannedTest, order []int) []int {
+	var (
+		out  = make([]int, 0, len(order))
+		done = make(map[int]bool, len(order))
+	)
+	var add func(i int)
+	add = func(i int) {
+		if done[i] {
+			return
+		}
+		done[i] = true
+		for _, req := range tests[i].Requires {
+			add(req)
+		}
+		out = append(out, i)
+	}
+	for _, index := range order {
+		add(index)
+	}
+	return out
+}
+
+// shardKeys returns the shard key of every test. Tests connected by requirements
+// have the same key, the name of the first test among them.
+func shardKeys(tests []PlannedTest) []string {
+	group := make([]int, len(tests))
+	for i := range group {
+		group[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if group[i] != i {
+			group[i] = find(group[i])
+		}
+		return group[i]
+	}
+	for i, t := range tests {
+		for _, req := range t.Requires {
+			a, b := find(i), find(req)
+			if a > b {
+				a, b = b, a
+			}
+			group[b] = a
+		}
+	}
+	keys := make([]string, len(tests))
+	for i := range tests {
+		keys[i] = firstName(tests[find(i)].Names)
+	}
+	return keys
+}
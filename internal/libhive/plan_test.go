@@ -0,0 +1,93 @@
+package libhive
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanBudget(t *testing.T) {
+	tests := []PlannedTest{
+		{Names: []string{"short"}, Duration: 10},
+		{Names: []string{"long"}, Duration: 60},
+		{Names: []string{"unknown"}},
+		{Names: []string{"medium"}, Duration: 30},
+		{Names: []string{"after-long"}, Duration: 1, Requires: []int{1}},
+	}
+	p := planner{limited: true, budget: 45 * time.Second}
+	plan, err := p.plan("suite", tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Longest first: long (60s) doesn't fit, and after-long requires it.
+	// medium and short fit, the test without estimate always fits.
+	if want := []int{3, 0, 2}; !reflect.DeepEqual(plan.Order, want) {
+		t.Errorf("wrong order %v, want %v", plan.Order, want)
+	}
+	if want := []int{1, 4}; !reflect.DeepEqual(plan.NotRun, want) {
+		t.Errorf("wrong notRun %v, want %v", plan.NotRun, want)
+	}
+
+	// Without budget, all tests run in definition order.
+	plan, err = (&planner{}).plan("suite", tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1, 2, 3, 4}; !reflect.DeepEqual(plan.Order, want) || len(plan.NotRun) > 0 {
+		t.Errorf("wrong plan without budget %+v", plan)
+	}
+}
+
+func TestPlanHistoryEstimate(t *testing.T) {
+	tests := []PlannedTest{
+		{Names: []string{"a (geth)", "a (besu)"}},
+		{Names: []string{"b"}},
+	}
+	p := planner{
+		order: TestOrder{History: map[string]TestHistory{
+			"suite/a (besu)": {Duration: time.Minute},
+			"suite/b":        {Duration: 10 * time.Second},
+		}},
+		limited: true,
+		budget:  30 * time.Second,
+	}
+	plan, err := p.plan("suite", tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plan.Order, []int{1}) || !reflect.DeepEqual(plan.NotRun, []int{0}) {
+		t.Errorf("wrong plan %+v", plan)
+	}
+}
+
+func TestPlanRequirements(t *testing.T) {
+	tests := []PlannedTest{
+		{Names: []string{"c"}, Requires: []int{1}},
+		{Names: []string{"b"}, Requires: []int{2}},
+		{Names: []string{"a"}},
+		{Names: []string{"d"}},
+	}
+	plan, err := (&planner{}).plan("suite", tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 1, 0, 3}; !reflect.DeepEqual(plan.Order, want) {
+		t.Errorf("wrong order %v, want %v", plan.Order, want)
+	}
+
+	// Connected tests are in the same shard.
+	keys := shardKeys(tests)
+	if keys[0] != keys[1] || keys[1] != keys[2] || keys[3] == keys[0] {
+		t.Errorf("wrong shard keys %q", keys)
+	}
+
+	for _, invalid := range [][]PlannedTest{
+		{{Names: []string{"a"}, Requires: []int{1}}, {Names: []string{"b"}, Requires: []int{0}}},
+		{{Names: []string{"a"}, Requires: []int{0}}},
+		{{Names: []string{"a"}, Requires: []int{5}}},
+	} {
+		if _, err := (&planner{}).plan("suite", invalid); err == nil {
+			t.Errorf("no error for invalid requirements %+v", invalid)
+		}
+	}
+}
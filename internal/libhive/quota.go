@@ -0,0 +1,100 @@
+package libhive
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Quotas limits the docker resources a simulator may use. Zero values mean no limit.
+type Quotas struct {
+	MaxContainers      int // client containers running at the same time
+	MaxNetworks        int // networks existing at the same time
+	MaxStartsPerMinute int // client container starts within one minute
+}
+
+// These are the names of quotas, as reported in the "quota" context value of
+// quota errors.
+const (
+	QuotaContainers = "containers"
+	QuotaNetworks   = "networks"
+	QuotaStartRate  = "startrate"
+)
+
+// quotaError creates the error returned when a simulator exceeds a quota. It has code
+// CodeQuotaExceeded, and the simulation API responds with status 429.
+func quotaError(quota string, limit int, msg string) *Error {
+	return &Error{
+		Code:    CodeQuotaExceeded,
+		Message: msg,
+		Context: map[string]string{"quota": quota, "limit": strconv.Itoa(limit)},
+		Hint:    "Quotas are given back when clients are stopped and networks are removed. They are set with the --sim.quota.* options.",
+		Retry:   true,
+	}
+}
+
+// quotaTracker counts resource usage against quotas.
+type quotaTracker struct {
+	quotas Quotas
+
+	mu         sync.Mutex
+	containers int
+	networks   int
+	starts     []time.Time // client starts within the last minute
+}
+
+// acquireContainer accounts for a new client container.
+func (qt *quotaTracker) acquireContainer(now time.Time) *Error {
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+
+	if max := qt.quotas.MaxContainers; max > 0 && qt.containers >= max {
+		return quotaError(QuotaContainers, max, fmt.Sprintf("too many client containers (limit %d)", max))
+	}
+	if max := qt.quotas.MaxStartsPerMinute; max > 0 {
+		// Drop starts older than one minute.
+		cutoff := now.Add(-time.Minute)
+		i := 0
+		for i < len(qt.starts) && !qt.starts[i].After(cutoff) {
+			i++
+		}
+		qt.starts = qt.starts[i:]
+		if len(qt.starts) >= max {
+			return quotaError(QuotaStartRate, max, fmt.Sprintf("too many client starts (limit %d per minute)", max))
+		}
+		qt.starts = append(qt.starts, now)
+	}
+	qt.containers++
+	return nil
+}
+
+// releaseContainer accounts for a stopped client container.
+func (qt *quotaTracker) releaseContainer() {
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	if qt.containers > 0 {
+		qt.containers--
+	}
+}
+
+// acquireNetwork accounts for a new network.
+func (qt *quotaTracker) acquireNetwork() *Error {
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+
+	if max := qt.quotas.MaxNetworks; max > 0 && qt.networks >= max {
+		return quotaError(QuotaNetworks, max, fmt.Sprintf("too many networks (limit %d)", max))
+	}
+	qt.networks++
+	return nil
+}
+
+// releaseNetwork accounts for a removed network.
+func (qt *quotaTracker) releaseNetwork() {
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	if qt.networks > 0 {
+		qt.networks--
+	}
+}
@@ -0,0 +1,53 @@
+package libhive
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaContainers(t *testing.T) {
+	qt := &quotaTracker{quotas: Quotas{MaxContainers: 2}}
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := qt.acquireContainer(now); err != nil {
+			t.Fatalf("acquire %d failed: %v", i, err)
+		}
+	}
+	err := qt.acquireContainer(now)
+	if err == nil || err.Context["quota"] != QuotaContainers {
+		t.Fatalf("expected containers quota error, got %v", err)
+	}
+	qt.releaseContainer()
+	if err := qt.acquireContainer(now); err != nil {
+		t.Fatalf("acquire after release failed: %v", err)
+	}
+}
+
+func TestQuotaStartRate(t *testing.T) {
+	qt := &quotaTracker{quotas: Quotas{MaxStartsPerMinute: 2}}
+	start := time.Now()
+	qt.acquireContainer(start)
+	qt.acquireContainer(start.Add(30 * time.Second))
+	err := qt.acquireContainer(start.Add(45 * time.Second))
+	if err == nil || err.Context["quota"] != QuotaStartRate {
+		t.Fatalf("expected start rate quota error, got %v", err)
+	}
+	// The first start is more than a minute ago now.
+	if err := qt.acquireContainer(start.Add(61 * time.Second)); err != nil {
+		t.Fatalf("acquire after one minute failed: %v", err)
+	}
+}
+
+func TestQuotaNetworks(t *testing.T) {
+	qt := &quotaTracker{quotas: Quotas{MaxNetworks: 1}}
+	if err := qt.acquireNetwork(); err != nil {
+		t.Fatal(err)
+	}
+	if err := qt.acquireNetwork(); err == nil || err.Context["quota"] != QuotaNetworks {
+		t.Fatalf("expected networks quota error, got %v", err)
+	}
+	qt.releaseNetwork()
+	if err := qt.acquireNetwork(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,99 @@
+package libhive
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ContainerResources are resource limits of a container. Zero values mean no limit.
+type ContainerResources struct {
+	CPUs   float64    `yaml:"cpus" json:"cpus,omitempty"`     // number of CPUs, may be fractional
+	Memory MemorySize `yaml:"memory" json:"memory,omitempty"` // memory limit in bytes
+}
+
+// Override returns r with the non-zero limits of o applied.
+func (r ContainerResources) Override(o ContainerResources) ContainerResources {
+	if o.CPUs != 0 {
+		r.CPUs = o.CPUs
+	}
+	if o.Memory != 0 {
+		r.Memory = o.Memory
+	}
+	return r
+}
+
+// validate checks that the limits are not negative.
+func (r ContainerResources) validate() error {
+	if r.CPUs < 0 || math.IsNaN(r.CPUs) || math.IsInf(r.CPUs, 0) {
+		return fmt.Errorf("invalid cpus %v", r.CPUs)
+	}
+	if r.Memory < 0 {
+		return fmt.Errorf("invalid memory %d", r.Memory)
+	}
+	return nil
+}
+
+// MemorySize is an amount of memory in bytes. It is given as a number with an
+// optional unit suffix b, k, m or g (powers of 1024), e.g. "512m" or "4g".
+type MemorySize int64
+
+// ParseMemorySize parses a memory size like "4g".
+func ParseMemorySize(s string) (MemorySize, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "b")
+	mult := int64(1)
+	if n := len(str); n > 0 {
+		switch str[n-1] {
+		case 'k':
+			mult = 1 << 10
+		case 'm':
+			mult = 1 << 20
+		case 'g':
+			mult = 1 << 30
+		}
+		if mult != 1 {
+			str = str[:n-1]
+		}
+	}
+	v, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || v < 0 || v > math.MaxInt64/mult {
+		return 0, fmt.Errorf("invalid memory size %q", s)
+	}
+	return MemorySize(v * mult), nil
+}
+
+// String returns the size in the largest unit which represents it exactly.
+func (m MemorySize) String() string {
+	switch {
+	case m == 0:
+		return "0"
+	case m%(1<<30) == 0:
+		return fmt.Sprintf("%dg", m>>30)
+	case m%(1<<20) == 0:
+		return fmt.Sprintf("%dm", m>>20)
+	case m%(1<<10) == 0:
+		return fmt.Sprintf("%dk", m>>10)
+	}
+	return strconv.FormatInt(int64(m), 10)
+}
+
+// Set implements flag.Value.
+func (m *MemorySize) Set(s string) error {
+	v, err := ParseMemorySize(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (m *MemorySize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return m.Set(s)
+}
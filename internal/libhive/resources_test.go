@@ -0,0 +1,48 @@
+package libhive
+
+import "testing"
+
+func TestParseMemorySize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  MemorySize
+		str   string
+	}{
+		{"0", 0, "0"},
+		{"1000", 1000, "1000"},
+		{"512m", 512 << 20, "512m"},
+		{"512MB", 512 << 20, "512m"},
+		{"4g", 4 << 30, "4g"},
+		{"1024k", 1 << 20, "1m"},
+		{"100b", 100, "100"},
+	}
+	for _, test := range tests {
+		v, err := ParseMemorySize(test.input)
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if v != test.want {
+			t.Errorf("%q: got %d, want %d", test.input, v, test.want)
+		}
+		if v.String() != test.str {
+			t.Errorf("%q: wrong string %q, want %q", test.input, v.String(), test.str)
+		}
+	}
+	for _, input := range []string{"", "g", "-1g", "1.5g", "4t", "99999999999g"} {
+		if _, err := ParseMemorySize(input); err == nil {
+			t.Errorf("%q: no error", input)
+		}
+	}
+}
+
+func TestContainerResourcesOverride(t *testing.T) {
+	defaults := ContainerResources{CPUs: 2, Memory: 4 << 30}
+	if r := defaults.Override(ContainerResources{}); r != defaults {
+		t.Errorf("empty override changed limits: %+v", r)
+	}
+	r := defaults.Override(ContainerResources{Memory: 8 << 30})
+	if want := (ContainerResources{CPUs: 2, Memory: 8 << 30}); r != want {
+		t.Errorf("wrong limits %+v, want %+v", r, want)
+	}
+}
@@ -0,0 +1,150 @@
+package libhive
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	sinkQueueSize    = 1024 // items waiting for delivery
+	resultMaxDetails = 4096 // max length of failure details published by sinks
+)
+
+// resultSink publishes test events outside of hive. The test manager reports suites and
+// tests to all configured sinks, see SimEnv.
+type resultSink interface {
+	suiteStarted(sim string, suiteID TestSuiteID, suite *TestSuite)
+	suiteEnded(sim string, suiteID TestSuiteID, suite *TestSuite, start time.Time)
+	testStarted(sim string, suiteID TestSuiteID, suite *TestSuite, testID TestID, test *TestCase)
+	testEnded(sim string, suiteID TestSuiteID, suite *TestSuite, testID TestID, test *TestCase)
+}
+
+// nopSink ignores all events. Sinks embed it to ignore the events they don't publish.
+type nopSink struct{}
+
+func (nopSink) suiteStarted(string, TestSuiteID, *TestSuite)                   {}
+func (nopSink) suiteEnded(string, TestSuiteID, *TestSuite, time.Time)          {}
+func (nopSink) testStarted(string, TestSuiteID, *TestSuite, TestID, *TestCase) {}
+func (nopSink) testEnded(string, TestSuiteID, *TestSuite, TestID, *TestCase)   {}
+
+// resultSinks returns the sinks configured in the environment.
+func (env *SimEnv) resultSinks() []resultSink {
+	var sinks []resultSink
+	if env.Telemetry != nil {
+		sinks = append(sinks, env.Telemetry)
+	}
+	if env.ResultStream != nil {
+		sinks = append(sinks, env.ResultStream)
+	}
+	if env.ResultWebhook != nil {
+		sinks = append(sinks, env.ResultWebhook)
+	}
+	return sinks
+}
+
+// suiteCounts returns the number of tests in a suite, and how many of them failed or
+// were skipped.
+func suiteCounts(suite *TestSuite) (tests, fails, skips int) {
+	for _, test := range suite.TestCases {
+		switch {
+		case !test.SummaryResult.Pass:
+			fails++
+		case test.SummaryResult.Skipped():
+			skips++
+		}
+	}
+	return len(suite.TestCases), fails, skips
+}
+
+// truncateDetails shortens failure details to the length published by sinks.
+func truncateDetails(details string) string {
+	if len(details) > resultMaxDetails {
+		return details[:resultMaxDetails] + "..."
+	}
+	return details
+}
+
+// sinkQueue delivers the items of a sink in a background goroutine, in the order in
+// which they were added. Items are delivered in batches of up to batchSize items. A
+// partial batch is delivered after flushInterval, or right away if flushInterval is zero.
+type sinkQueue struct {
+	batchSize     int
+	flushInterval time.Duration
+	deliver       func([]interface{})
+
+	items     chan interface{}
+	closing   chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newSinkQueue(batchSize int, flushInterval time.Duration, deliver func([]interface{})) *sinkQueue {
+	q := &sinkQueue{
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		deliver:       deliver,
+		items:         make(chan interface{}, sinkQueueSize),
+		closing:       make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+	go q.loop()
+	return q
+}
+
+// add queues an item. It returns false if the queue is full and the item was dropped.
+func (q *sinkQueue) add(item interface{}) bool {
+	select {
+	case q.items <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+// close delivers all queued items and stops the queue.
+// It is safe to call close multiple times.
+func (q *sinkQueue) close() {
+	q.closeOnce.Do(func() { close(q.closing) })
+	<-q.closed
+}
+
+func (q *sinkQueue) loop() {
+	defer close(q.closed)
+	var flush <-chan time.Time
+	if q.flushInterval > 0 {
+		ticker := time.NewTicker(q.flushInterval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+	var batch []interface{}
+	for {
+		select {
+		case item := <-q.items:
+			batch = append(batch, item)
+			if len(batch) < q.batchSize && q.flushInterval > 0 {
+				continue
+			}
+		case <-flush:
+		case <-q.closing:
+			for {
+				select {
+				case item := <-q.items:
+					batch = append(batch, item)
+					if len(batch) == q.batchSize {
+						q.deliver(batch)
+						batch = nil
+					}
+				default:
+					if len(batch) > 0 {
+						q.deliver(batch)
+					}
+					return
+				}
+			}
+		}
+		if len(batch) > 0 {
+			q.deliver(batch)
+			batch = nil
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package libhive
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSinkQueue(t *testing.T) {
+	var batches [][]interface{}
+	// The flush interval is long, so batches are only delivered when full or on close.
+	q := newSinkQueue(2, time.Hour, func(batch []interface{}) {
+		batches = append(batches, batch)
+	})
+	for i := 1; i <= 5; i++ {
+		if !q.add(i) {
+			t.Fatalf("item %d dropped", i)
+		}
+	}
+	q.close()
+	q.close()
+
+	var got []interface{}
+	for _, batch := range batches {
+		if len(batch) > 2 {
+			t.Errorf("batch too large: %v", batch)
+		}
+		got = append(got, batch...)
+	}
+	if want := []interface{}{1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong items delivered: %v, want %v", got, want)
+	}
+}
+
+func TestSuiteCounts(t *testing.T) {
+	suite := &TestSuite{TestCases: map[TestID]*TestCase{
+		1: {SummaryResult: TestResult{Pass: true}},
+		2: {SummaryResult: TestResult{Pass: false}},
+		3: {SummaryResult: TestResult{Pass: true, Skip: SkipOther}},
+	}}
+	if tests, fails, skips := suiteCounts(suite); tests != 3 || fails != 1 || skips != 1 {
+		t.Fatalf("wrong counts: tests %d, fails %d, skips %d", tests, fails, skips)
+	}
+	if d := truncateDetails(strings.Repeat("x", resultMaxDetails+1)); len(d) != resultMaxDetails+3 || !strings.HasSuffix(d, "...") {
+		t.Fatalf("details not truncated, length %d", len(d))
+	}
+}
@@ -0,0 +1,210 @@
+package libhive
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	streamBufferSize   = 256              // events buffered per subscriber
+	streamWriteTimeout = 10 * time.Second // max time to write an event
+)
+
+// ResultStream pushes test events to websocket subscribers as they happen, so
+// dashboards can show the progress of a run. Events are JSON objects:
+//
+//    {"type": "suiteStart", "time": "...", "simulator": "...", "suiteID": 0, "suite": "..."}
+//    {"type": "testStart", ..., "testID": 1, "test": "..."}
+//    {"type": "testEnd", ..., "testID": 1, "test": "...", "pass": false, "category": "...", "details": "..."}
+//    {"type": "suiteEnd", ..., "tests": 10, "fails": 1, "skips": 2}
+//
+// Subscribers only receive events which happen after they have connected. A subscriber
+// which can't keep up with the events is disconnected. All events can also be written
+// to an event log, see SetEventLog.
+type ResultStream struct {
+	runID    string
+	upgrader websocket.Upgrader
+
+	mu   sync.Mutex
+	subs map[chan *StreamEvent]struct{}
+	log  *json.Encoder
+}
+
+// StreamEvent is an event of the result stream.
+type StreamEvent struct {
+	Type      string      `json:"type"`
+	Time      time.Time   `json:"time"`
+	RunID     string      `json:"runID,omitempty"`
+	Simulator string      `json:"simulator"`
+	SuiteID   TestSuiteID `json:"suiteID"`
+	Suite     string      `json:"suite"`
+	TestID    TestID      `json:"testID,omitempty"`
+	Test      string      `json:"test,omitempty"`
+
+	// These are set for testEnd events.
+	Pass     *bool           `json:"pass,omitempty"`
+	Skip     SkipReason      `json:"skip,omitempty"`
+	NotRun   bool            `json:"notRun,omitempty"`
+	Category FailureCategory `json:"category,omitempty"`
+	Details  string          `json:"details,omitempty"`
+
+	// These are set for suiteEnd events.
+	Tests int `json:"tests,omitempty"`
+	Fails int `json:"fails,omitempty"`
+	Skips int `json:"skips,omitempty"`
+}
+
+// NewResultStream creates a result stream.
+func NewResultStream(runID string) *ResultStream {
+	return &ResultStream{
+		runID: runID,
+		subs:  make(map[chan *StreamEvent]struct{}),
+		// Dashboards are usually served from another origin.
+		upgrader: websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }},
+	}
+}
+
+// SetEventLog makes the stream write all events to w, one JSON object per line.
+func (s *ResultStream) SetEventLog(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = json.NewEncoder(w)
+}
+
+// ServeHTTP accepts a websocket subscriber and sends it events until it disconnects.
+func (s *ResultStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has already responded.
+	}
+	defer conn.Close()
+
+	ch := s.subscribe()
+	defer s.unsubscribe(ch)
+
+	// Read from the connection to handle close messages.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				log15.Warn("result stream subscriber too slow, disconnecting", "addr", r.RemoteAddr)
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (s *ResultStream) subscribe() chan *StreamEvent {
+	ch := make(chan *StreamEvent, streamBufferSize)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *ResultStream) unsubscribe(ch chan *StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[ch]; ok {
+		delete(s.subs, ch)
+		close(ch)
+	}
+}
+
+// send delivers an event to all subscribers. Subscribers with a full
+// buffer are dropped.
+func (s *ResultStream) send(ev *StreamEvent) {
+	ev.RunID = s.runID
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log != nil {
+		if err := s.log.Encode(ev); err != nil {
+			log15.Warn("can't write result stream event log", "err", err)
+		}
+	}
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (s *ResultStream) suiteStarted(sim string, suiteID TestSuiteID, suite *TestSuite) {
+	if s == nil {
+		return
+	}
+	s.send(&StreamEvent{Type: "suiteStart", Time: time.Now(), Simulator: sim, SuiteID: suiteID, Suite: suite.Name})
+}
+
+func (s *ResultStream) suiteEnded(sim string, suiteID TestSuiteID, suite *TestSuite, start time.Time) {
+	if s == nil {
+		return
+	}
+	ev := &StreamEvent{Type: "suiteEnd", Time: time.Now(), Simulator: sim, SuiteID: suiteID, Suite: suite.Name}
+	ev.Tests, ev.Fails, ev.Skips = suiteCounts(suite)
+	s.send(ev)
+}
+
+func (s *ResultStream) testStarted(sim string, suiteID TestSuiteID, suite *TestSuite, testID TestID, test *TestCase) {
+	if s == nil {
+		return
+	}
+	s.send(&StreamEvent{
+		Type:      "testStart",
+		Time:      test.Start,
+		Simulator: sim,
+		SuiteID:   suiteID,
+		Suite:     suite.Name,
+		TestID:    testID,
+		Test:      test.Name,
+	})
+}
+
+func (s *ResultStream) testEnded(sim string, suiteID TestSuiteID, suite *TestSuite, testID TestID, test *TestCase) {
+	if s == nil {
+		return
+	}
+	pass := test.SummaryResult.Pass
+	ev := &StreamEvent{
+		Type:      "testEnd",
+		Time:      test.End,
+		Simulator: sim,
+		SuiteID:   suiteID,
+		Suite:     suite.Name,
+		TestID:    testID,
+		Test:      test.Name,
+		Pass:      &pass,
+		Skip:      test.SummaryResult.Skip,
+		NotRun:    test.SummaryResult.NotRun,
+	}
+	if !pass || ev.NotRun {
+		ev.Category = test.SummaryResult.Category
+		ev.Details = truncateDetails(test.SummaryResult.Details)
+	}
+	s.send(ev)
+}
@@ -0,0 +1,100 @@
+package libhive
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestResultStream(t *testing.T) {
+	stream := NewResultStream("run1")
+	var eventLog bytes.Buffer
+	stream.SetEventLog(&eventLog)
+	srv := httptest.NewServer(stream)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// Wait for the subscription.
+	for i := 0; ; i++ {
+		stream.mu.Lock()
+		n := len(stream.subs)
+		stream.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("subscriber not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	suite := &TestSuite{Name: "suite", TestCases: map[TestID]*TestCase{
+		1: {Name: "ok", Start: start, End: start, SummaryResult: TestResult{Pass: true}},
+		2: {Name: "bad", Start: start, End: start, SummaryResult: TestResult{Details: "boom", Category: FailureAssertion}},
+	}}
+	stream.suiteStarted("sim", 0, suite)
+	stream.testStarted("sim", 0, suite, 1, suite.TestCases[1])
+	stream.testEnded("sim", 0, suite, 1, suite.TestCases[1])
+	stream.testEnded("sim", 0, suite, 2, suite.TestCases[2])
+	stream.suiteEnded("sim", 0, suite, time.Now())
+
+	var events []StreamEvent
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(events) < 5 {
+		var ev StreamEvent
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, ev)
+	}
+	types := []string{"suiteStart", "testStart", "testEnd", "testEnd", "suiteEnd"}
+	for i, ev := range events {
+		if ev.Type != types[i] || ev.RunID != "run1" || ev.Simulator != "sim" || ev.Suite != "suite" {
+			t.Errorf("wrong event %d: %+v", i, ev)
+		}
+	}
+	if ev := events[2]; ev.Pass == nil || !*ev.Pass || ev.Test != "ok" {
+		t.Errorf("wrong pass event: %+v", ev)
+	}
+	if ev := events[3]; ev.Pass == nil || *ev.Pass || ev.Category != FailureAssertion || ev.Details != "boom" {
+		t.Errorf("wrong fail event: %+v", ev)
+	}
+	if ev := events[4]; ev.Tests != 2 || ev.Fails != 1 {
+		t.Errorf("wrong suite end event: %+v", ev)
+	}
+
+	// The event log has the same events.
+	var (
+		logged []StreamEvent
+		dec    = json.NewDecoder(&eventLog)
+	)
+	for dec.More() {
+		var ev StreamEvent
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+		logged = append(logged, ev)
+	}
+	if len(logged) != len(events) {
+		t.Fatalf("event log has %d events, want %d", len(logged), len(events))
+	}
+	for i, ev := range logged {
+		if ev.Type != types[i] || ev.RunID != "run1" || ev.Test != events[i].Test {
+			t.Errorf("wrong logged event %d: %+v", i, ev)
+		}
+	}
+
+	// The stream methods must be callable when the stream is disabled.
+	var disabled *ResultStream
+	disabled.suiteStarted("sim", 0, suite)
+}
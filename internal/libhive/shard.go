@@ -0,0 +1,63 @@
+package libhive
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// TestShard selects the part of the tests run by one of several hive instances, e.g.
+// CI jobs which share the tests of a run. Index is 1-based. The zero value runs all tests.
+type TestShard struct {
+	Index int `json:"index"`
+	Count int `json:"count"`
+}
+
+// ParseTestShard parses a shard of the form "i/n". The empty string selects all tests.
+func ParseTestShard(s string) (TestShard, error) {
+	if s == "" {
+		return TestShard{}, nil
+	}
+	slash := strings.IndexByte(s, '/')
+	if slash < 0 {
+		return TestShard{}, fmt.Errorf("invalid test shard %q, want i/n", s)
+	}
+	index, err1 := strconv.Atoi(s[:slash])
+	count, err2 := strconv.Atoi(s[slash+1:])
+	if err1 != nil || err2 != nil || count < 1 || index < 1 || index > count {
+		return TestShard{}, fmt.Errorf("invalid test shard %q, want i/n with 1 <= i <= n", s)
+	}
+	return TestShard{Index: index, Count: count}, nil
+}
+
+// Enabled reports whether the shard selects a part of the tests.
+func (s TestShard) Enabled() bool {
+	return s.Count > 1
+}
+
+func (s TestShard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// Filter removes the tests of other shards from order, which contains indexes into
+// keys. Tests are assigned to shards by the hash of their suite and key, usually the
+// test name, so all shards agree on the assignment regardless of the order of tests.
+func (s TestShard) Filter(suite string, keys []string, order []int) []int {
+	if !s.Enabled() {
+		return order
+	}
+	out := order[:0]
+	for _, index := range order {
+		if s.contains(suite, keys[index]) {
+			out = append(out, index)
+		}
+	}
+	return out
+}
+
+func (s TestShard) contains(suite, test string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(suite + "/" + test))
+	return int(h.Sum64()%uint64(s.Count)) == s.Index-1
+}
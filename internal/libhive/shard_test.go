@@ -0,0 +1,130 @@
+package libhive
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseTestShard(t *testing.T) {
+	tests := []struct {
+		input string
+		want  TestShard
+		err   bool
+	}{
+		{input: "", want: TestShard{}},
+		{input: "1/1", want: TestShard{Index: 1, Count: 1}},
+		{input: "2/3", want: TestShard{Index: 2, Count: 3}},
+		{input: "0/3", err: true},
+		{input: "4/3", err: true},
+		{input: "1/0", err: true},
+		{input: "1", err: true},
+		{input: "a/b", err: true},
+	}
+	for _, test := range tests {
+		shard, err := ParseTestShard(test.input)
+		if (err != nil) != test.err {
+			t.Errorf("%q: wrong error %v", test.input, err)
+			continue
+		}
+		if shard != test.want {
+			t.Errorf("%q: wrong shard %+v", test.input, shard)
+		}
+	}
+}
+
+func TestShardFilter(t *testing.T) {
+	var tests []string
+	for i := 0; i < 50; i++ {
+		tests = append(tests, fmt.Sprintf("test-%d", i))
+	}
+	order := func() []int {
+		o := make([]int, len(tests))
+		for i := range o {
+			o[i] = len(tests) - 1 - i
+		}
+		return o
+	}
+
+	// Every test must be in exactly one shard.
+	var all []int
+	for i := 1; i <= 3; i++ {
+		part := TestShard{Index: i, Count: 3}.Filter("suite", tests, order())
+		if len(part) == 0 {
+			t.Errorf("shard %d/3 is empty", i)
+		}
+		if !sort.SliceIsSorted(part, func(i, j int) bool { return part[i] > part[j] }) {
+			t.Errorf("shard %d/3 doesn't keep the order: %v", i, part)
+		}
+		all = append(all, part...)
+	}
+	sort.Ints(all)
+	want := order()
+	sort.Ints(want)
+	if !reflect.DeepEqual(all, want) {
+		t.Fatalf("shards don't partition the tests: %v", all)
+	}
+
+	if got := (TestShard{}).Filter("suite", tests, order()); !reflect.DeepEqual(got, order()) {
+		t.Errorf("disabled shard filters tests: %v", got)
+	}
+}
+
+// This test checks that the test manager rejects tests of other shards.
+func TestStartTestShard(t *testing.T) {
+	shard := TestShard{Index: 1, Count: 2}
+	tm := NewTestManager(SimEnv{TestShard: shard}, nil, -1)
+	defer tm.Terminate()
+
+	// Without a plan, every test is sharded by its name.
+	suite, err := tm.StartTestSuite("unplanned", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("test-%d", i)
+		_, err := tm.StartTest(suite, name, "", 0)
+		if want := shard.contains("unplanned", name); (err == nil) != want {
+			t.Errorf("unplanned test %s: got error %v, in shard %t", name, err, want)
+		}
+		if err != nil && !errors.Is(err, ErrTestNotInShard) {
+			t.Errorf("unplanned test %s: wrong error %v", name, err)
+		}
+	}
+
+	// With a plan, tests are assigned by the plan. Unplanned names are subtests.
+	suite, err = tm.StartTestSuite("planned", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests []PlannedTest
+	for i := 0; i < 20; i++ {
+		tests = append(tests, PlannedTest{Names: []string{fmt.Sprintf("test-%d (a)", i), fmt.Sprintf("test-%d (b)", i)}})
+	}
+	plan, err := tm.PlanTests(suite, tests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Order) == 0 || len(plan.Order) == len(tests) {
+		t.Fatalf("plan has %d of %d tests", len(plan.Order), len(tests))
+	}
+	planned := make(map[int]bool)
+	for _, index := range plan.Order {
+		planned[index] = true
+	}
+	for i, test := range tests {
+		for _, name := range test.Names {
+			_, err := tm.StartTest(suite, name, "", 0)
+			if (err == nil) != planned[i] {
+				t.Errorf("planned test %s: got error %v, in plan %t", name, err, planned[i])
+			}
+		}
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := tm.StartTest(suite, fmt.Sprintf("subtest-%d", i), "", 0); err != nil {
+			t.Errorf("subtest rejected: %v", err)
+		}
+	}
+}
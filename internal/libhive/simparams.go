@@ -0,0 +1,166 @@
+package libhive
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// These are the types of simulator parameters.
+const (
+	ParamString   = "string"
+	ParamInt      = "int"
+	ParamBool     = "bool"
+	ParamDuration = "duration"
+)
+
+// SimParam is a parameter declared by a simulator. Parameters are environment
+// variables of the simulator container, set with --sim.env NAME=VALUE.
+type SimParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SimParamValue is a simulator parameter with the value given to the simulator,
+// or the default if no value was given.
+type SimParamValue struct {
+	SimParam
+	Value string `json:"value"`
+}
+
+// check verifies that value is valid for the type of the parameter.
+func (p *SimParam) check(value string) error {
+	var err error
+	switch p.Type {
+	case ParamString:
+	case ParamInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case ParamBool:
+		_, err = strconv.ParseBool(value)
+	case ParamDuration:
+		_, err = time.ParseDuration(value)
+	default:
+		return fmt.Errorf("unknown type %q", p.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q", p.Type, value)
+	}
+	return nil
+}
+
+// checkSimParams verifies the parameter declarations of a simulator.
+func checkSimParams(params []SimParam) error {
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		if !strings.HasPrefix(p.Name, hiveEnvvarPrefix) || len(p.Name) == len(hiveEnvvarPrefix) {
+			return fmt.Errorf("invalid parameter name %q, must start with %s", p.Name, hiveEnvvarPrefix)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate parameter %s", p.Name)
+		}
+		seen[p.Name] = true
+		switch p.Type {
+		case ParamString, ParamInt, ParamBool, ParamDuration:
+		default:
+			return fmt.Errorf("parameter %s: unknown type %q", p.Name, p.Type)
+		}
+		if p.Default != "" {
+			if err := p.check(p.Default); err != nil {
+				return fmt.Errorf("parameter %s: bad default: %v", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateSimParams checks the parameters given to a simulator against the parameters
+// it declares. All variables in env with the HIVE_ prefix must be declared parameters,
+// and their values must be valid for the parameter type. When no parameters are
+// declared, nothing is checked.
+func ValidateSimParams(params []SimParam, env map[string]string) error {
+	if len(params) == 0 {
+		return nil
+	}
+	declared := make(map[string]*SimParam, len(params))
+	for i := range params {
+		declared[params[i].Name] = &params[i]
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		if strings.HasPrefix(name, hiveEnvvarPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var errs []string
+	for _, name := range names {
+		p := declared[name]
+		if p == nil {
+			msg := "unknown parameter " + name
+			if similar := similarParam(name, params); similar != "" {
+				msg += fmt.Sprintf(" (did you mean %s?)", similar)
+			}
+			errs = append(errs, msg)
+			continue
+		}
+		if err := p.check(env[name]); err != nil {
+			errs = append(errs, fmt.Sprintf("parameter %s: %v", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return &Error{
+			Code:    CodeInvalidParam,
+			Message: strings.Join(errs, ", "),
+			Hint:    "Run hive with --sim.listparams to see the parameters of the simulator.",
+		}
+	}
+	return nil
+}
+
+// similarParam returns the declared parameter closest to name, if it is
+// likely to be a misspelling.
+func similarParam(name string, params []SimParam) string {
+	best, bestDist := "", len(name)/3+1
+	for _, p := range params {
+		if d := editDistance(name, p.Name); d < bestDist {
+			best, bestDist = p.Name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
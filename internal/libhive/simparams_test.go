@@ -0,0 +1,125 @@
+package libhive
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testSimParams = []SimParam{
+	{Name: "HIVE_SYNC_BLOCKS", Type: ParamInt, Default: "100"},
+	{Name: "HIVE_SYNC_SNAP", Type: ParamBool},
+	{Name: "HIVE_SYNC_TIMEOUT", Type: ParamDuration, Default: "5m"},
+	{Name: "HIVE_SYNC_MODE", Type: ParamString},
+}
+
+func TestValidateSimParams(t *testing.T) {
+	valid := map[string]string{
+		"HIVE_SYNC_BLOCKS":  "20",
+		"HIVE_SYNC_SNAP":    "true",
+		"HIVE_SYNC_TIMEOUT": "90s",
+		"HIVE_SYNC_MODE":    "full",
+		"GITHUB_TOKEN":      "not a parameter",
+	}
+	if err := ValidateSimParams(testSimParams, valid); err != nil {
+		t.Fatal("error for valid parameters:", err)
+	}
+
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"HIVE_SYNC_BLOKS": "20"}, "unknown parameter HIVE_SYNC_BLOKS (did you mean HIVE_SYNC_BLOCKS?)"},
+		{map[string]string{"HIVE_OTHER": "1"}, "unknown parameter HIVE_OTHER"},
+		{map[string]string{"HIVE_SYNC_BLOCKS": "many"}, `parameter HIVE_SYNC_BLOCKS: invalid int value "many"`},
+		{map[string]string{"HIVE_SYNC_TIMEOUT": "90"}, `parameter HIVE_SYNC_TIMEOUT: invalid duration value "90"`},
+	}
+	for _, test := range tests {
+		err := ValidateSimParams(testSimParams, test.env)
+		var e *Error
+		if !errors.As(err, &e) || e.Code != CodeInvalidParam || e.Message != test.want {
+			t.Errorf("env %v: wrong error %v, want %q", test.env, err, test.want)
+		}
+	}
+}
+
+func TestCheckSimParams(t *testing.T) {
+	if err := checkSimParams(testSimParams); err != nil {
+		t.Fatal("error for valid declarations:", err)
+	}
+	invalid := [][]SimParam{
+		{{Name: "SYNC_BLOCKS", Type: ParamInt}},
+		{{Name: "HIVE_", Type: ParamInt}},
+		{{Name: "HIVE_X", Type: "float"}},
+		{{Name: "HIVE_X", Type: ParamBool, Default: "maybe"}},
+		{{Name: "HIVE_X", Type: ParamInt}, {Name: "HIVE_X", Type: ParamString}},
+	}
+	for _, params := range invalid {
+		if err := checkSimParams(params); err == nil {
+			t.Errorf("no error for invalid declarations %+v", params)
+		} else if !strings.Contains(err.Error(), "HIVE_") {
+			t.Errorf("error doesn't name the parameter: %v", err)
+		}
+	}
+}
+
+// This test checks that an empty parameter declaration doesn't reject parameters.
+func TestDeclareEmptySimParams(t *testing.T) {
+	env := map[string]string{"HIVE_SYNC_BLOCKS": "20"}
+	if err := ValidateSimParams([]SimParam{}, env); err != nil {
+		t.Fatal("error for empty declaration:", err)
+	}
+	tm := NewTestManager(SimEnv{SimParams: env}, nil, -1)
+	if err := tm.DeclareSimParams([]SimParam{}); err != nil {
+		t.Fatal("error for empty declaration:", err)
+	}
+	if params := tm.SimParams(); params != nil {
+		t.Fatalf("empty declaration recorded as %v", params)
+	}
+}
+
+// This test checks that hive is notified when a simulator in parameters-only mode
+// is done with the parameters.
+func TestParamsOnlyDone(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+	}{
+		{"POST", "/simulator/params", `[{"name": "HIVE_SYNC_BLOCKS", "type": "int"}]`},
+		{"POST", "/simulator/params", `[]`},
+		{"POST", "/testsuite", ""},
+		{"GET", "/clients", ""},
+	}
+	for _, test := range tests {
+		tm := NewTestManager(SimEnv{ParamsOnly: true}, nil, -1)
+		srv := httptest.NewServer(tm.API())
+		req, _ := http.NewRequest(test.method, srv.URL+test.path, strings.NewReader(test.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		select {
+		case <-tm.ParamsDone():
+		default:
+			t.Errorf("%s %s: not done", test.method, test.path)
+		}
+		srv.Close()
+	}
+
+	// Reading the parameters doesn't end parameters-only mode.
+	tm := NewTestManager(SimEnv{ParamsOnly: true}, nil, -1)
+	srv := httptest.NewServer(tm.API())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/simulator/params")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	select {
+	case <-tm.ParamsDone():
+		t.Errorf("GET /simulator/params: done")
+	default:
+	}
+}
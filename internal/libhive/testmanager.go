@@ -3,12 +3,14 @@ package libhive
 import (
 	"crypto/rand"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,30 +18,41 @@ import (
 )
 
 var (
-	ErrNoSuchNode               = errors.New("no such node")
-	ErrNoSuchTestSuite          = errors.New("no such test suite")
-	ErrNoSuchTestCase           = errors.New("no such test case")
-	ErrMissingClientType        = errors.New("missing client type")
-	ErrNoAvailableClients       = errors.New("no available clients")
-	ErrTestSuiteRunning         = errors.New("test suite still has running tests")
-	ErrMissingOutputDestination = errors.New("test suite requires an output")
-	ErrNoSummaryResult          = errors.New("test case must be ended with a summary result")
-	ErrDBUpdateFailed           = errors.New("could not update results set")
-	ErrTestSuiteLimited         = errors.New("testsuite test count is limited")
+	ErrNoSuchNode               = &Error{Code: CodeNoSuchNode, Message: "no such node"}
+	ErrNoSuchTestSuite          = &Error{Code: CodeNoSuchTestSuite, Message: "no such test suite"}
+	ErrNoSuchTestCase           = &Error{Code: CodeNoSuchTestCase, Message: "no such test case"}
+	ErrMissingClientType        = &Error{Code: CodeInvalidRequest, Message: "missing client type"}
+	ErrNoAvailableClients       = &Error{Code: CodeUnknownClient, Message: "no available clients"}
+	ErrTestSuiteRunning         = &Error{Code: CodeTestSuiteState, Message: "test suite still has running tests"}
+	ErrMissingOutputDestination = &Error{Code: CodeInvalidRequest, Message: "test suite requires an output"}
+	ErrNoSummaryResult          = &Error{Code: CodeInvalidRequest, Message: "test case must be ended with a summary result"}
+	ErrDBUpdateFailed           = &Error{Code: CodeInternal, Message: "could not update results set"}
+	ErrTestSuiteLimited         = &Error{Code: CodeTestSuiteState, Message: "testsuite test count is limited"}
+	ErrNoRetry                  = &Error{Code: CodeTestSuiteState, Message: "test case can't be retried"}
+	ErrTestNotInShard           = &Error{Code: CodeTestSuiteState, Message: "test case is not in the shard of this hive instance"}
 )
 
 // ClientDefinition is served by the /clients API endpoint to list the available clients
 type ClientDefinition struct {
-	Name    string         `json:"name"`
-	Version string         `json:"version"`
-	Image   string         `json:"-"` // not exposed via API
-	Meta    ClientMetadata `json:"meta"`
+	Name       string         `json:"name"`
+	Version    string         `json:"version"`
+	Image      string         `json:"-"`                    // not exposed via API
+	Source     string         `json:"source,omitempty"`     // registry image by digest, for --client.image
+	Dockerfile string         `json:"dockerfile,omitempty"` // selected Dockerfile, for --client.dockerfile
+	Meta       ClientMetadata `json:"meta"`
 }
 
 // SimEnv contains the simulation parameters.
 type SimEnv struct {
 	LogDir string
 
+	// RunID identifies the hive run. It is set as a label on containers.
+	RunID string
+
+	// Tags are user-defined KEY=VALUE labels of the run. They are stored
+	// in the test suite results.
+	Tags map[string]string
+
 	// Parameters of simulation.
 	SimLogLevel    int
 	SimParallelism int
@@ -49,8 +62,66 @@ type SimEnv struct {
 	// for the client to open port 8545 after launching the container.
 	ClientStartTimeout time.Duration
 
+	// This is the number of times a client start is retried
+	// when the client doesn't come up.
+	ClientStartRetries int
+
+	// ClientResources are the default resource limits of client containers.
+	// Clients can override them in their hive.yaml.
+	ClientResources ContainerResources
+
 	// client name -> client definition
 	Definitions map[string]*ClientDefinition
+
+	// Inventory is the inventory of the hive directory. It is optional
+	// and only used for the /inventory API endpoint.
+	Inventory *InventoryCache
+
+	// Resource limits of the simulator.
+	Quotas Quotas
+
+	// This configures the order in which simulators run tests.
+	TestOrder TestOrder
+
+	// TestShard selects the tests run by this hive instance.
+	TestShard TestShard
+
+	// TestRetries is the max number of times a failed test can be re-run.
+	TestRetries int
+
+	// TestTimeout is the default timeout of tests. Simulators can set the timeout
+	// of each test. Zero means tests have no timeout by default.
+	TestTimeout time.Duration
+
+	// These are the formats of the result files written for each test suite.
+	ResultFormats ResultFormats
+
+	// CompressLogs enables zstd compression of client and simulator logs.
+	CompressLogs bool
+
+	// NoStreamLogs disables the separate stdout and stderr logs of clients.
+	NoStreamLogs bool
+
+	// HostGuard pauses starting tests and clients when host resources are
+	// exhausted. It is optional.
+	HostGuard *HostGuard
+
+	// Telemetry publishes test suites and tests as trace spans. It is optional.
+	Telemetry *OTLPExporter
+
+	// ResultStream publishes test events to websocket subscribers. It is optional.
+	ResultStream *ResultStream
+
+	// ResultWebhook posts test results to an HTTP endpoint. It is optional.
+	ResultWebhook *ResultWebhook
+
+	// SimParams are the HIVE_ variables given to the simulator with --sim.env. They
+	// are checked against the parameters declared by the simulator.
+	SimParams map[string]string
+
+	// ParamsOnly restricts the simulation API to declaring parameters. It is set
+	// while hive reads the parameters declared by a simulator.
+	ParamsOnly bool
 }
 
 // TestManager collects test results during a simulation run.
@@ -61,18 +132,33 @@ type TestManager struct {
 
 	simContainerID string
 	simLogFile     string
+	simName        string
+	simDeadline    time.Time // zero if the simulation has no time limit
 
 	// all networks started by a specific test suite, where key
 	// is network name and value is network ID
 	networks     map[TestSuiteID]map[string]string
 	networkMutex sync.RWMutex
 
+	// resource usage of the simulator
+	quotas *quotaTracker
+
+	// parameters declared by the simulator, nil if it hasn't declared any
+	simParams   []SimParam
+	paramsMutex sync.Mutex
+	paramsDone  chan struct{}
+	paramsOnce  sync.Once
+
 	testCaseMutex     sync.RWMutex
 	testSuiteMutex    sync.RWMutex
 	runningTestSuites map[TestSuiteID]*TestSuite
 	runningTestCases  map[TestID]*TestCase
+	testTimers        map[TestID]*time.Timer
+	timeoutGrace      time.Duration
 	testSuiteCounter  uint32
 	testCaseCounter   uint32
+	suiteStarted      map[TestSuiteID]time.Time
+	sinks             []resultSink
 	results           map[TestSuiteID]*TestSuite
 }
 
@@ -83,8 +169,14 @@ func NewTestManager(config SimEnv, b ContainerBackend, testLimiter int) *TestMan
 		testLimiter:       testLimiter,
 		runningTestSuites: make(map[TestSuiteID]*TestSuite),
 		runningTestCases:  make(map[TestID]*TestCase),
+		testTimers:        make(map[TestID]*time.Timer),
+		timeoutGrace:      testTimeoutGrace,
 		results:           make(map[TestSuiteID]*TestSuite),
+		paramsDone:        make(chan struct{}),
 		networks:          make(map[TestSuiteID]map[string]string),
+		quotas:            &quotaTracker{quotas: config.Quotas},
+		suiteStarted:      make(map[TestSuiteID]time.Time),
+		sinks:             config.resultSinks(),
 	}
 }
 
@@ -95,6 +187,53 @@ func (manager *TestManager) SetSimContainerInfo(id, logFile string) {
 	manager.simLogFile = logFile
 }
 
+// SetDeadline sets the time at which the simulation is aborted. Tests are planned
+// to end before the deadline.
+func (manager *TestManager) SetDeadline(deadline time.Time) {
+	manager.simDeadline = deadline
+}
+
+// SetSimulatorName sets the name of the simulator, which is used to label client containers.
+func (manager *TestManager) SetSimulatorName(name string) {
+	manager.simName = name
+}
+
+// DeclareSimParams records the parameters declared by the simulator and checks
+// the parameters given to the simulator against them. An empty declaration is
+// treated like no declaration, i.e. the parameters are not checked.
+func (manager *TestManager) DeclareSimParams(params []SimParam) error {
+	if err := checkSimParams(params); err != nil {
+		return &Error{Code: CodeInvalidRequest, Message: err.Error()}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	manager.paramsMutex.Lock()
+	manager.simParams = append([]SimParam{}, params...)
+	manager.paramsMutex.Unlock()
+	return ValidateSimParams(params, manager.config.SimParams)
+}
+
+// ParamsDone returns a channel which is closed when a simulator running in
+// parameters-only mode has declared its parameters, or has made any other
+// request, which means it won't declare them. The simulator container can be
+// stopped at that point.
+func (manager *TestManager) ParamsDone() <-chan struct{} {
+	return manager.paramsDone
+}
+
+func (manager *TestManager) endParamsOnly() {
+	manager.paramsOnce.Do(func() { close(manager.paramsDone) })
+}
+
+// SimParams returns the parameters declared by the simulator, or nil if the
+// simulator hasn't declared parameters.
+func (manager *TestManager) SimParams() []SimParam {
+	manager.paramsMutex.Lock()
+	defer manager.paramsMutex.Unlock()
+	return manager.simParams
+}
+
 // Results returns the results for all suites that have already ended.
 func (manager *TestManager) Results() map[TestSuiteID]*TestSuite {
 	manager.testSuiteMutex.RLock()
@@ -132,10 +271,18 @@ func (manager *TestManager) IsTestRunning(test TestID) (*TestCase, bool) {
 // Terminate forces the termination of any running tests with
 // an error message. This can be called as a cleanup method.
 // If there are no running tests, there is no effect.
+//
+// Tests which are still running when the simulation deadline has passed are
+// reported as timed out.
 func (manager *TestManager) Terminate() error {
 	terminationSummary := &TestResult{
-		Pass:    false,
-		Details: "Test was terminated by host",
+		Pass:     false,
+		Details:  "Test was terminated by host",
+		Category: FailureHarnessError,
+	}
+	if !manager.simDeadline.IsZero() && !time.Now().Before(manager.simDeadline) {
+		terminationSummary.Details = "Test was terminated because the simulation time limit was reached"
+		terminationSummary.Category = FailureTimeout
 	}
 	manager.testSuiteMutex.Lock()
 	defer manager.testSuiteMutex.Unlock()
@@ -175,7 +322,7 @@ func (manager *TestManager) GetNodeInfo(testSuite TestSuiteID, test TestID, node
 }
 
 // CreateNetwork creates a docker network with the given network name.
-func (manager *TestManager) CreateNetwork(testSuite TestSuiteID, name string) error {
+func (manager *TestManager) CreateNetwork(testSuite TestSuiteID, name string, opt NetworkOptions) error {
 	_, ok := manager.IsTestSuiteRunning(testSuite)
 	if !ok {
 		return ErrNoSuchTestSuite
@@ -185,8 +332,12 @@ func (manager *TestManager) CreateNetwork(testSuite TestSuiteID, name string) er
 	manager.networkMutex.Lock()
 	defer manager.networkMutex.Unlock()
 
-	id, err := manager.backend.CreateNetwork(getUniqueName(testSuite, name))
+	if err := manager.quotas.acquireNetwork(); err != nil {
+		return err
+	}
+	id, err := manager.backend.CreateNetwork(manager.networkName(testSuite, name), opt)
 	if err != nil {
+		manager.quotas.releaseNetwork()
 		return err
 	}
 	if _, exists := manager.networks[testSuite]; !exists {
@@ -197,9 +348,16 @@ func (manager *TestManager) CreateNetwork(testSuite TestSuiteID, name string) er
 	return nil
 }
 
-// getUniqueName returns a unique network name to prevent network collisions
-func getUniqueName(testSuite TestSuiteID, name string) string {
-	return fmt.Sprintf("hive_%d_%d_%s", os.Getpid(), testSuite, name)
+// networkName returns the docker name of a test suite network. Names contain the run ID,
+// so hive instances running concurrently on the same host don't collide. The process ID
+// is used when the run has no ID.
+func (manager *TestManager) networkName(testSuite TestSuiteID, name string) string {
+	run := manager.config.RunID
+	if run == "" {
+		run = strconv.Itoa(os.Getpid())
+	}
+	sim := strings.ReplaceAll(manager.simName, "/", "-")
+	return fmt.Sprintf("hive_%s_%s_%d_%s", run, sim, testSuite, name)
 }
 
 // RemoveNetwork removes a docker network by the given network name.
@@ -215,6 +373,7 @@ func (manager *TestManager) RemoveNetwork(testSuite TestSuiteID, network string)
 	if err := manager.backend.RemoveNetwork(id); err != nil {
 		return err
 	}
+	manager.quotas.releaseNetwork()
 	delete(manager.networks[testSuite], network)
 	return nil
 }
@@ -272,8 +431,44 @@ func (manager *TestManager) ContainerIP(testSuite TestSuiteID, networkName, cont
 	return ipAddr.String(), nil
 }
 
+// ContainerNetworks returns the IP addresses of the given container on all networks
+// it is connected to, by network name. Only the "bridge" network and networks of the
+// test suite are reported.
+func (manager *TestManager) ContainerNetworks(testSuite TestSuiteID, containerID string) (map[string]string, error) {
+	manager.networkMutex.RLock()
+	defer manager.networkMutex.RUnlock()
+
+	_, ok := manager.IsTestSuiteRunning(testSuite)
+	if !ok {
+		return nil, ErrNoSuchTestSuite
+	}
+	if containerID == "simulation" {
+		containerID = manager.simContainerID
+	}
+
+	names := make(map[string]string) // network ID -> name
+	for name, id := range manager.networks[testSuite] {
+		names[id] = name
+	}
+	if id, err := manager.backend.NetworkNameToID("bridge"); err == nil {
+		names[id] = "bridge"
+	}
+	ips, err := manager.backend.ContainerNetworks(containerID)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(ips))
+	for id, ip := range ips {
+		if name, ok := names[id]; ok {
+			result[name] = ip.String()
+		}
+	}
+	return result, nil
+}
+
 // ConnectContainer connects the given container to the given network.
-func (manager *TestManager) ConnectContainer(testSuite TestSuiteID, networkName, containerID string) error {
+// If ip is non-nil, it is assigned as the static address of the container.
+func (manager *TestManager) ConnectContainer(testSuite TestSuiteID, networkName, containerID string, ip net.IP) error {
 	manager.networkMutex.RLock()
 	defer manager.networkMutex.RUnlock()
 
@@ -289,7 +484,7 @@ func (manager *TestManager) ConnectContainer(testSuite TestSuiteID, networkName,
 	if !exists {
 		return ErrNetworkNotFound
 	}
-	return manager.backend.ConnectContainer(containerID, networkID)
+	return manager.backend.ConnectContainer(containerID, networkID, ip)
 }
 
 // DisconnectContainer disconnects the given container from the given network.
@@ -332,9 +527,10 @@ func (manager *TestManager) doEndSuite(testSuite TestSuiteID) error {
 			return ErrTestSuiteRunning
 		}
 	}
+	suite.Warnings = manager.config.HostGuard.WarningsSince(manager.suiteStarted[testSuite])
 	// Write the result.
 	if manager.config.LogDir != "" {
-		err := writeSuiteFile(suite, manager.config.LogDir)
+		err := writeSuiteFiles(suite, manager.suiteStarted[testSuite], manager.config.LogDir, manager.config.ResultFormats)
 		if err != nil {
 			return err
 		}
@@ -345,8 +541,13 @@ func (manager *TestManager) doEndSuite(testSuite TestSuiteID) error {
 			log15.Error("could not remove network", "err", err)
 		}
 	}
+	for _, sink := range manager.sinks {
+		sink.suiteEnded(manager.simName, testSuite, suite, manager.suiteStarted[testSuite])
+	}
+
 	// Move the suite to results.
 	delete(manager.runningTestSuites, testSuite)
+	delete(manager.suiteStarted, testSuite)
 	manager.results[testSuite] = suite
 	return nil
 }
@@ -364,13 +565,28 @@ func (manager *TestManager) StartTestSuite(name string, description string) (Tes
 		ClientVersions: make(map[string]string),
 		TestCases:      make(map[TestID]*TestCase),
 		SimulatorLog:   manager.simLogFile,
+		RunID:          manager.config.RunID,
+		Tags:           manager.config.Tags,
+	}
+	if manager.config.TestShard.Enabled() {
+		shard := manager.config.TestShard
+		manager.runningTestSuites[newSuiteID].Shard = &shard
 	}
+	manager.suiteStarted[newSuiteID] = time.Now()
 	manager.testSuiteCounter++
+	for _, sink := range manager.sinks {
+		sink.suiteStarted(manager.simName, newSuiteID, manager.runningTestSuites[newSuiteID])
+	}
 	return newSuiteID, nil
 }
 
-//StartTest starts a new test case, returning the testcase id as a context identifier
-func (manager *TestManager) StartTest(testSuiteID TestSuiteID, name string, description string) (TestID, error) {
+// testTimeoutGrace is the time after the timeout of a test at which hive ends the test.
+// Simulators get the chance to end the test themselves first.
+const testTimeoutGrace = 30 * time.Second
+
+// StartTest starts a new test case, returning the testcase id as a context identifier.
+// If timeout is zero, the test has the default timeout of the simulation.
+func (manager *TestManager) StartTest(testSuiteID TestSuiteID, name string, description string, timeout time.Duration) (TestID, error) {
 	manager.testCaseMutex.Lock()
 	defer manager.testCaseMutex.Unlock()
 
@@ -383,6 +599,9 @@ func (manager *TestManager) StartTest(testSuiteID TestSuiteID, name string, desc
 	if manager.testLimiter >= 0 && len(testSuite.TestCases) >= manager.testLimiter {
 		return 0, ErrTestSuiteLimited
 	}
+	if !manager.inShard(testSuite, name) {
+		return 0, ErrTestNotInShard
+	}
 	// increment the testcasecounter
 	manager.testCaseCounter++
 	var newCaseID = TestID(manager.testCaseCounter)
@@ -396,15 +615,47 @@ func (manager *TestManager) StartTest(testSuiteID TestSuiteID, name string, desc
 	testSuite.TestCases[newCaseID] = newTestCase
 	// and to the general map of id:testcases
 	manager.runningTestCases[newCaseID] = newTestCase
+	if timeout == 0 {
+		timeout = manager.config.TestTimeout
+	}
+	if timeout > 0 {
+		newTestCase.Timeout = timeout.Seconds()
+		manager.testTimers[newCaseID] = time.AfterFunc(timeout+manager.timeoutGrace, func() {
+			manager.timeoutTest(testSuiteID, newCaseID)
+		})
+	}
+	for _, sink := range manager.sinks {
+		sink.testStarted(manager.simName, testSuiteID, testSuite, newCaseID, newTestCase)
+	}
 
 	return newCaseID, nil
 }
 
+// inShard reports whether a test may run in the shard of this hive instance. Tests of
+// planned suites are assigned to shards by the plan, and tests which weren't planned
+// are subtests, which run in the shard of their parent. Simulators which don't plan
+// their suites get every test sharded by its name.
+func (manager *TestManager) inShard(suite *TestSuite, name string) bool {
+	shard := manager.config.TestShard
+	if !shard.Enabled() {
+		return true
+	}
+	if suite.shardTests != nil {
+		in, planned := suite.shardTests[name]
+		return in || !planned
+	}
+	return shard.contains(suite.Name, name)
+}
+
 // EndTest finishes the test case
 func (manager *TestManager) EndTest(testSuiteRun TestSuiteID, testID TestID, summaryResult *TestResult) error {
 	manager.testCaseMutex.Lock()
 	defer manager.testCaseMutex.Unlock()
+	return manager.endTest(testSuiteRun, testID, summaryResult)
+}
 
+// endTest finishes the test case. This must be called with testCaseMutex held.
+func (manager *TestManager) endTest(testSuiteRun TestSuiteID, testID TestID, summaryResult *TestResult) error {
 	// Check if the test case is running
 	testCase, ok := manager.runningTestCases[testID]
 	if !ok {
@@ -417,20 +668,184 @@ func (manager *TestManager) EndTest(testSuiteRun TestSuiteID, testID TestID, sum
 
 	// Add the results to the test case
 	testCase.End = time.Now()
-	testCase.SummaryResult = *summaryResult
+	testCase.SummaryResult = normalizeResult(*summaryResult)
+	manager.stopTestClients(testCase)
+
+	if suite, ok := manager.runningTestSuites[testSuiteRun]; ok {
+		manager.reportTestEnd(testSuiteRun, suite, testID, testCase)
+	}
+
+	// Delete from running, if it's still there.
+	delete(manager.runningTestCases, testID)
+	if timer := manager.testTimers[testID]; timer != nil {
+		timer.Stop()
+		delete(manager.testTimers, testID)
+	}
+	return nil
+}
+
+// reportTestEnd publishes the result of a test to the result sinks.
+func (manager *TestManager) reportTestEnd(suiteID TestSuiteID, suite *TestSuite, testID TestID, test *TestCase) {
+	for _, sink := range manager.sinks {
+		sink.testEnded(manager.simName, suiteID, suite, testID, test)
+	}
+}
+
+// timeoutTest fails a test which is still running after its timeout.
+func (manager *TestManager) timeoutTest(testSuite TestSuiteID, testID TestID) {
+	manager.testCaseMutex.Lock()
+	defer manager.testCaseMutex.Unlock()
+
+	testCase, ok := manager.runningTestCases[testID]
+	if !ok {
+		return
+	}
+	timeout := time.Duration(testCase.Timeout * float64(time.Second))
+	if time.Since(testCase.Start) < timeout {
+		return // the test was retried in the meantime
+	}
+	log15.Warn("test timed out", "suite", testSuite, "test", testID, "name", testCase.Name, "timeout", timeout)
+	manager.endTest(testSuite, testID, &TestResult{
+		Pass:     false,
+		Details:  fmt.Sprintf("test did not end within its timeout of %v", timeout),
+		Category: FailureTimeout,
+	})
+}
+
+// PlanTests returns the tests of a suite which should run, and their order. When the
+// simulation has a deadline, the tests which don't fit into the remaining time are left
+// out. They are added to the suite as not run.
+func (manager *TestManager) PlanTests(testSuite TestSuiteID, tests []PlannedTest) (*TestPlan, error) {
+	manager.testCaseMutex.Lock()
+	defer manager.testCaseMutex.Unlock()
 
-	// Stop running clients.
+	suite, ok := manager.runningTestSuites[testSuite]
+	if !ok {
+		return nil, ErrNoSuchTestSuite
+	}
+	p := planner{order: manager.config.TestOrder, shard: manager.config.TestShard}
+	if !manager.simDeadline.IsZero() {
+		p.limited = true
+		if p.budget = time.Until(manager.simDeadline); p.budget < 0 {
+			p.budget = 0 // no time left, only tests without estimate can run
+		}
+	}
+	plan, err := p.plan(suite.Name, tests)
+	if err != nil {
+		return nil, err
+	}
+	if p.shard.Enabled() {
+		if suite.shardTests == nil {
+			suite.shardTests = make(map[string]bool)
+		}
+		for _, t := range tests {
+			for _, name := range t.Names {
+				if _, ok := suite.shardTests[name]; !ok {
+					suite.shardTests[name] = false
+				}
+			}
+		}
+		for _, index := range append(plan.Order[:len(plan.Order):len(plan.Order)], plan.NotRun...) {
+			for _, name := range tests[index].Names {
+				suite.shardTests[name] = true
+			}
+		}
+	}
+
+	var (
+		now    = time.Now()
+		notRun = make(map[int]bool, len(plan.NotRun))
+	)
+	for _, index := range plan.NotRun {
+		notRun[index] = true
+		details := fmt.Sprintf("not run: estimated duration %v doesn't fit into the time limit (%v left for all tests)", p.estimate(suite.Name, tests[index]).Round(time.Second), p.budget.Round(time.Second))
+		for _, req := range tests[index].Requires {
+			if notRun[req] {
+				details = fmt.Sprintf("not run: required test %q was not run", firstName(tests[req].Names))
+				break
+			}
+		}
+		for _, name := range tests[index].Names {
+			manager.testCaseCounter++
+			testID := TestID(manager.testCaseCounter)
+			suite.TestCases[testID] = &TestCase{
+				Name:          name,
+				Description:   tests[index].Description,
+				Start:         now,
+				End:           now,
+				SummaryResult: TestResult{Pass: true, NotRun: true, Details: details},
+			}
+			manager.reportTestEnd(testSuite, suite, testID, suite.TestCases[testID])
+		}
+	}
+	return plan, nil
+}
+
+// RetryTest ends a failed attempt of a running test case. The result and clients of the
+// attempt are kept in the test case, and the clients are stopped. The test case keeps
+// running for the next attempt. Tests can be retried up to SimEnv.TestRetries times.
+func (manager *TestManager) RetryTest(testSuite TestSuiteID, testID TestID, attemptResult *TestResult) error {
+	manager.testCaseMutex.Lock()
+	defer manager.testCaseMutex.Unlock()
+
+	testCase, ok := manager.runningTestCases[testID]
+	if !ok {
+		return ErrNoSuchTestCase
+	}
+	if attemptResult == nil {
+		return ErrNoSummaryResult
+	}
+	if attemptResult.Pass || len(testCase.Attempts) >= manager.config.TestRetries {
+		return ErrNoRetry
+	}
+
+	manager.stopTestClients(testCase)
+	now := time.Now()
+	testCase.Attempts = append(testCase.Attempts, TestAttempt{
+		Start:         testCase.Start,
+		End:           now,
+		SummaryResult: normalizeResult(*attemptResult),
+		ClientInfo:    testCase.ClientInfo,
+	})
+	testCase.Start = now
+	testCase.ClientInfo = nil
+	if timer := manager.testTimers[testID]; timer != nil {
+		timer.Reset(time.Duration(testCase.Timeout*float64(time.Second)) + manager.timeoutGrace)
+	}
+	return nil
+}
+
+// normalizeResult ensures that the skip reason and failure category of a
+// test result are consistent with the outcome of the test.
+func normalizeResult(r TestResult) TestResult {
+	r.NotRun = false // only set by PlanTests
+	if r.Pass {
+		r.Category = ""
+		if r.Skipped() && !r.Skip.Valid() {
+			r.Skip = SkipOther
+		}
+	} else {
+		// A test which failed before it was skipped counts as failed.
+		r.Skip = ""
+		if !r.Category.Valid() {
+			// Failures not classified by the simulator are assumed to be genuine client failures.
+			r.Category = FailureAssertion
+		}
+	}
+	return r
+}
+
+// stopTestClients stops the running clients of a test case.
+// This must be called with testCaseMutex held.
+func (manager *TestManager) stopTestClients(testCase *TestCase) {
 	for _, v := range testCase.ClientInfo {
 		if v.wait != nil {
 			manager.backend.DeleteContainer(v.ID)
 			v.wait()
 			v.wait = nil
+			manager.quotas.releaseContainer()
 		}
 	}
-
-	// Delete from running, if it's still there.
-	delete(manager.runningTestCases, testID)
-	return nil
 }
 
 // RegisterNode is used by test suite hosts to register the creation of a node in the context of a test
@@ -470,21 +885,33 @@ func (manager *TestManager) StopNode(testID TestID, nodeID string) error {
 		}
 		nodeInfo.wait()
 		nodeInfo.wait = nil
+		manager.quotas.releaseContainer()
 	}
 	return nil
 }
 
-// writeSuiteFile writes the simulation result to the log directory.
-func writeSuiteFile(s *TestSuite, logdir string) error {
-	suiteData, err := json.Marshal(s)
-	if err != nil {
-		return err
-	}
+// writeSuiteFiles writes the simulation result to the log directory, in all selected
+// formats. The files of a suite share the same name, with the format's extension.
+func writeSuiteFiles(s *TestSuite, started time.Time, logdir string, formats ResultFormats) error {
 	// Randomize the name, but make it so that it's ordered by date - makes cleanups easier
 	b := make([]byte, 16)
 	rand.Read(b)
-	suiteFileName := fmt.Sprintf("%v-%x.json", time.Now().Unix(), b)
+	suiteFileName := fmt.Sprintf("%v-%x", time.Now().Unix(), b)
 	suiteFile := filepath.Join(logdir, suiteFileName)
-	// Write it.
-	return ioutil.WriteFile(suiteFile, suiteData, 0644)
+
+	if formats.Has(ResultFormatJSON) {
+		suiteData, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(suiteFile+".json", suiteData, 0644); err != nil {
+			return err
+		}
+	}
+	if formats.Has(ResultFormatJUnit) {
+		if err := writeJUnitFile(s, started, suiteFile+".xml"); err != nil {
+			return err
+		}
+	}
+	return nil
 }
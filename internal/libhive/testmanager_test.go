@@ -0,0 +1,67 @@
+package libhive
+
+import (
+	"testing"
+	"time"
+)
+
+// This test checks that tests terminated by the simulation time limit are
+// reported as timed out.
+func TestTerminateDeadline(t *testing.T) {
+	tests := []struct {
+		deadline time.Time
+		want     FailureCategory
+	}{
+		{time.Time{}, FailureHarnessError},
+		{time.Now().Add(time.Hour), FailureHarnessError},
+		{time.Now().Add(-time.Second), FailureTimeout},
+	}
+	for i, test := range tests {
+		tm := NewTestManager(SimEnv{}, nil, -1)
+		tm.SetDeadline(test.deadline)
+		suite, err := tm.StartTestSuite("suite", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tm.StartTest(suite, "test", "", 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := tm.Terminate(); err != nil {
+			t.Fatal(err)
+		}
+		for _, tc := range tm.Results()[0].TestCases {
+			if tc.SummaryResult.Category != test.want {
+				t.Errorf("test %d: wrong category %q, want %q", i, tc.SummaryResult.Category, test.want)
+			}
+		}
+	}
+}
+
+// This test checks that tests left out of the plan because the time limit has been
+// reached are reported as not run, and that tests without an estimate still run.
+func TestPlanTestsDeadline(t *testing.T) {
+	stream := NewResultStream("run1")
+	events := stream.subscribe()
+	tm := NewTestManager(SimEnv{ResultStream: stream}, nil, -1)
+	tm.SetDeadline(time.Now().Add(-time.Second))
+	suite, err := tm.StartTestSuite("suite", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events // suiteStart
+
+	plan, err := tm.PlanTests(suite, []PlannedTest{
+		{Names: []string{"long"}, Duration: 10},
+		{Names: []string{"unknown"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Order) != 1 || plan.Order[0] != 1 {
+		t.Errorf("wrong plan order %v", plan.Order)
+	}
+	ev := <-events
+	if ev.Type != "testEnd" || ev.Test != "long" || !ev.NotRun || ev.Pass == nil || !*ev.Pass || ev.Details == "" {
+		t.Errorf("wrong not-run event: %+v", ev)
+	}
+}
@@ -0,0 +1,191 @@
+package libhive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	webhookRetries    = 2                // extra attempts of a failed delivery
+	webhookRetryDelay = 2 * time.Second  // wait time before the first retry
+	webhookTimeout    = 10 * time.Second // max time of a request
+)
+
+// ResultWebhook posts the result of every test to an HTTP endpoint when the test ends,
+// so that test management systems can record hive results as they happen.
+//
+// Results are delivered one at a time, in the order in which the tests ended. Failed
+// deliveries are retried a few times. When the endpoint can't keep up, results are
+// dropped.
+type ResultWebhook struct {
+	nopSink
+
+	url     string
+	headers map[string]string
+	runID   string
+	tags    map[string]string
+	client  *http.Client
+	queue   *sinkQueue
+}
+
+// WebhookResult is the request body sent to the webhook.
+type WebhookResult struct {
+	RunID     string            `json:"runID,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Simulator string            `json:"simulator"`
+	SuiteID   TestSuiteID       `json:"suiteID"`
+	Suite     string            `json:"suite"`
+	TestID    TestID            `json:"testID"`
+	Test      string            `json:"test"`
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	Duration  float64           `json:"duration"` // in seconds
+
+	Pass     bool            `json:"pass"`
+	Skip     SkipReason      `json:"skip,omitempty"`
+	NotRun   bool            `json:"notRun,omitempty"`
+	Category FailureCategory `json:"category,omitempty"`
+	Details  string          `json:"details,omitempty"`
+	Attempts int             `json:"attempts,omitempty"` // number of failed attempts before the final one
+
+	Clients   []WebhookClient   `json:"clients"`
+	Artifacts []WebhookArtifact `json:"artifacts"`
+}
+
+// WebhookClient is a client used by a test.
+type WebhookClient struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// WebhookArtifact is a file in the results directory belonging to a test.
+// The path is relative to the results directory.
+type WebhookArtifact struct {
+	Type   string `json:"type"` // "clientLog" or "simulatorLog"
+	Path   string `json:"path"`
+	Client string `json:"client,omitempty"` // client ID of client logs
+}
+
+// NewResultWebhook creates a webhook posting to the given URL. The headers are added to
+// all requests, e.g. for API keys. The run ID and tags are included in all results.
+func NewResultWebhook(url string, headers map[string]string, runID string, tags map[string]string) *ResultWebhook {
+	h := &ResultWebhook{
+		url:     url,
+		headers: headers,
+		runID:   runID,
+		tags:    tags,
+		client:  &http.Client{Timeout: webhookTimeout},
+	}
+	h.queue = newSinkQueue(1, 0, func(batch []interface{}) { h.deliver(batch[0].(*WebhookResult)) })
+	return h
+}
+
+// Close delivers all queued results and stops the webhook.
+// It is safe to call Close multiple times.
+func (h *ResultWebhook) Close() {
+	if h == nil {
+		return
+	}
+	h.queue.close()
+}
+
+// testEnded queues the result of a test for delivery.
+func (h *ResultWebhook) testEnded(sim string, suiteID TestSuiteID, suite *TestSuite, testID TestID, test *TestCase) {
+	if h == nil {
+		return
+	}
+	result := &WebhookResult{
+		RunID:     h.runID,
+		Tags:      h.tags,
+		Simulator: sim,
+		SuiteID:   suiteID,
+		Suite:     suite.Name,
+		TestID:    testID,
+		Test:      test.Name,
+		Start:     test.Start,
+		End:       test.End,
+		Duration:  test.End.Sub(test.Start).Seconds(),
+		Pass:      test.SummaryResult.Pass,
+		Skip:      test.SummaryResult.Skip,
+		NotRun:    test.SummaryResult.NotRun,
+		Category:  test.SummaryResult.Category,
+		Details:   truncateDetails(test.SummaryResult.Details),
+		Attempts:  len(test.Attempts),
+		Clients:   []WebhookClient{},
+		Artifacts: []WebhookArtifact{},
+	}
+
+	ids := make([]string, 0, len(test.ClientInfo))
+	for id := range test.ClientInfo {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c := test.ClientInfo[id]
+		result.Clients = append(result.Clients, WebhookClient{ID: c.ID, Name: c.Name, Version: suite.ClientVersions[c.Name]})
+		if c.LogFile != "" {
+			result.Artifacts = append(result.Artifacts, WebhookArtifact{Type: "clientLog", Path: c.LogFile, Client: c.ID})
+		}
+	}
+	if suite.SimulatorLog != "" {
+		result.Artifacts = append(result.Artifacts, WebhookArtifact{Type: "simulatorLog", Path: suite.SimulatorLog})
+	}
+
+	if !h.queue.add(result) {
+		log15.Warn("result webhook queue full, dropping result", "suite", suite.Name, "test", test.Name)
+	}
+}
+
+// deliver sends a result, retrying failed requests.
+func (h *ResultWebhook) deliver(result *WebhookResult) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		log15.Error("can't encode webhook result", "err", err)
+		return
+	}
+	delay := webhookRetryDelay
+	for attempt := 0; attempt <= webhookRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(delay):
+				delay *= 2
+			case <-h.queue.closing:
+				// Retry without waiting to avoid holding up shutdown.
+			}
+		}
+		if err = h.send(body); err == nil {
+			return
+		}
+	}
+	log15.Warn("can't deliver result to webhook", "url", h.url, "suite", result.Suite, "test", result.Test, "err", err)
+}
+
+func (h *ResultWebhook) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package libhive
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResultWebhook(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		results []WebhookResult
+		failed  bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("x-api-key") != "secret" {
+			t.Errorf("wrong request: %s %v", r.Method, r.Header)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		// The first delivery fails and is retried.
+		if !failed {
+			failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var result WebhookResult
+		if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+			t.Error(err)
+		}
+		results = append(results, result)
+	}))
+	defer srv.Close()
+
+	h := NewResultWebhook(srv.URL, map[string]string{"x-api-key": "secret"}, "run1", map[string]string{"pr": "1"})
+	start := time.Now()
+	suite := &TestSuite{
+		Name:           "suite",
+		SimulatorLog:   "sim.log",
+		ClientVersions: map[string]string{"go-ethereum": "Geth/v1.10.8"},
+		TestCases: map[TestID]*TestCase{
+			1: {Name: "ok", Start: start, End: start.Add(2 * time.Second), SummaryResult: TestResult{Pass: true}},
+			2: {
+				Name:          "bad",
+				Start:         start,
+				End:           start,
+				SummaryResult: TestResult{Details: "boom", Category: FailureAssertion},
+				ClientInfo:    map[string]*ClientInfo{"abc": {ID: "abc", Name: "go-ethereum", LogFile: "go-ethereum/client-abc.log"}},
+				Attempts:      []TestAttempt{{}},
+			},
+		},
+	}
+	h.testEnded("sim", 0, suite, 1, suite.TestCases[1])
+	h.testEnded("sim", 0, suite, 2, suite.TestCases[2])
+	h.Close()
+	h.Close()
+
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	ok, bad := results[0], results[1]
+	if ok.Test != "ok" || !ok.Pass || ok.Duration != 2 || ok.RunID != "run1" || ok.Tags["pr"] != "1" {
+		t.Errorf("wrong result of passing test: %+v", ok)
+	}
+	if bad.Test != "bad" || bad.Pass || bad.Category != FailureAssertion || bad.Details != "boom" || bad.Attempts != 1 {
+		t.Errorf("wrong result of failing test: %+v", bad)
+	}
+	if len(bad.Clients) != 1 || bad.Clients[0] != (WebhookClient{ID: "abc", Name: "go-ethereum", Version: "Geth/v1.10.8"}) {
+		t.Errorf("wrong clients: %+v", bad.Clients)
+	}
+	wantArtifacts := []WebhookArtifact{
+		{Type: "clientLog", Path: "go-ethereum/client-abc.log", Client: "abc"},
+		{Type: "simulatorLog", Path: "sim.log"},
+	}
+	if len(bad.Artifacts) != 2 || bad.Artifacts[0] != wantArtifacts[0] || bad.Artifacts[1] != wantArtifacts[1] {
+		t.Errorf("wrong artifacts: %+v", bad.Artifacts)
+	}
+}
@@ -0,0 +1,664 @@
+package libk8s
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/hive/internal/libdocker"
+	"github.com/ethereum/hive/internal/libhive"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// maxFilesSize is the maximum total size of the files of a container. The files are
+// stored in a ConfigMap, which is limited to 1MiB by the API server.
+const maxFilesSize = 1<<20 - 16*1024
+
+// bridgeNetwork is the ID of the pod network. All pods are connected to it.
+const bridgeNetwork = "bridge"
+
+// mainContainer is the name of the container running the image in hive pods.
+const mainContainer = "main"
+
+// labelContainer is the pod label holding the container ID.
+const labelContainer = "hive.container"
+
+// ContainerBackend runs containers as pods of a kubernetes cluster. A pod is created
+// when its container is started, and the files of the container are mounted from a
+// ConfigMap.
+//
+// Kubernetes has no per-test networks. All pods are connected to the flat pod network
+// of the cluster, which is the "bridge" network of the backend, and other networks
+// can't be created.
+type ContainerBackend struct {
+	api       *apiClient
+	config    *Config
+	namespace string
+	logger    log15.Logger
+
+	mu      sync.Mutex
+	pods    map[string]*pod       // container ID -> pod
+	images  map[string]*imagePush // image -> registry push
+	stats   libhive.ResourceStats
+	running int
+}
+
+// pod is a container of the backend.
+type pod struct {
+	id      string
+	name    string
+	spec    podSpec
+	ip      string
+	files   bool // set if the pod has a ConfigMap
+	started bool
+	stop    context.CancelFunc // ends log streaming and waiting
+}
+
+// imagePush pushes an image to the registry once.
+type imagePush struct {
+	once sync.Once
+	err  error
+}
+
+// newContainerBackend creates a backend using the given API client.
+func newContainerBackend(api *apiClient, namespace string, cfg *Config) *ContainerBackend {
+	b := &ContainerBackend{
+		api:       api,
+		config:    cfg,
+		namespace: namespace,
+		logger:    cfg.Logger,
+		pods:      make(map[string]*pod),
+		images:    make(map[string]*imagePush),
+		stats:     libhive.ResourceStats{Start: time.Now()},
+	}
+	if b.logger == nil {
+		b.logger = log15.Root()
+	}
+	return b
+}
+
+// Ping checks that the API server is responding.
+func (b *ContainerBackend) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return b.api.do(ctx, "GET", "/version", nil, nil, nil)
+}
+
+// Stats returns the resource usage of all containers created by the backend. The CPU
+// time of pods is not measured.
+func (b *ContainerBackend) Stats() libhive.ResourceStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	stats.RunID = b.config.Labels[libhive.LabelRunID]
+	stats.End = time.Now()
+	return stats
+}
+
+// HostIP returns the address at which pods reach hive.
+func (b *ContainerBackend) HostIP() (net.IP, error) {
+	if b.config.HostIP != nil {
+		return b.config.HostIP, nil
+	}
+	u, err := url.Parse(b.api.server)
+	if err != nil {
+		return nil, err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	// Connecting a UDP socket sends no packets, but selects the local address.
+	conn, err := net.Dial("udp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return nil, fmt.Errorf("can't find local address of the cluster connection: %v", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+func (b *ContainerBackend) podPath(name string) string {
+	return "/api/v1/namespaces/" + b.namespace + "/pods/" + name
+}
+
+func (b *ContainerBackend) configMapPath(name string) string {
+	return "/api/v1/namespaces/" + b.namespace + "/configmaps/" + name
+}
+
+// lookup finds a pod by its container ID. Like docker, the backend accepts unique
+// prefixes of container IDs.
+func (b *ContainerBackend) lookup(containerID string) (*pod, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if p, ok := b.pods[containerID]; ok {
+		return p, nil
+	}
+	var found *pod
+	for id, p := range b.pods {
+		if strings.HasPrefix(id, containerID) {
+			if found != nil {
+				return nil, fmt.Errorf("container ID %s is ambiguous", containerID)
+			}
+			found = p
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no such container: %s", containerID)
+	}
+	return found, nil
+}
+
+// CreateContainer prepares a pod. The files of the container are stored in a ConfigMap,
+// the pod itself is created by StartContainer.
+func (b *ContainerBackend) CreateContainer(ctx context.Context, image string, opt libhive.ContainerOptions) (string, error) {
+	imageRef, err := b.pushImage(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	idBytes := make([]byte, 16)
+	rand.Read(idBytes)
+	id := hex.EncodeToString(idBytes)
+	p := &pod{id: id, name: "hive-" + id}
+
+	main := containerSpec{Name: mainContainer, Image: imageRef, ImagePullPolicy: "IfNotPresent"}
+	if b.config.Registry != "" {
+		main.ImagePullPolicy = "Always"
+	}
+	for key, val := range opt.Env {
+		main.Env = append(main.Env, envVar{Name: key, Value: val})
+	}
+	sort.Slice(main.Env, func(i, j int) bool { return main.Env[i].Name < main.Env[j].Name })
+	main.Resources = resourceLimits(opt.Resources)
+	p.spec = podSpec{
+		RestartPolicy:                 "Never",
+		AutomountServiceAccountToken:  new(bool),
+		TerminationGracePeriodSeconds: new(int64),
+	}
+
+	// Store the files.
+	if len(opt.Files) > 0 {
+		cm, mounts, vol, err := filesConfigMap(p.name, opt.Files)
+		if err != nil {
+			return "", err
+		}
+		cm.Metadata.Labels = b.podLabels(p)
+		if err := b.api.do(ctx, "POST", b.configMapPath(""), nil, cm, nil); err != nil {
+			return "", fmt.Errorf("can't store container files: %v", err)
+		}
+		p.files = true
+		main.VolumeMounts = mounts
+		p.spec.Volumes = []volume{vol}
+	}
+	p.spec.Containers = []containerSpec{main}
+	b.addPortProxy(p, opt.PortProxy)
+
+	b.mu.Lock()
+	b.pods[id] = p
+	b.stats.ContainersCreated++
+	b.mu.Unlock()
+	b.logger.Debug("created container", "image", image, "container", id[:8], "pod", p.name)
+	return id, nil
+}
+
+// pushImage returns the image reference used by pods, pushing the image to the registry
+// if one is configured.
+func (b *ContainerBackend) pushImage(ctx context.Context, image string) (string, error) {
+	if b.config.Registry == "" {
+		return image, nil
+	}
+	target := strings.TrimSuffix(b.config.Registry, "/") + "/" + image
+	if b.config.PushImage == nil {
+		return target, nil
+	}
+	b.mu.Lock()
+	push, ok := b.images[image]
+	if !ok {
+		push = new(imagePush)
+		b.images[image] = push
+	}
+	b.mu.Unlock()
+	push.once.Do(func() { push.err = b.config.PushImage(ctx, image, target) })
+	return target, push.err
+}
+
+// podLabels returns the labels of a pod. Kubernetes restricts label values, so the
+// labels of the configuration and container are stored as annotations, and only the
+// run ID and container ID are labels.
+func (b *ContainerBackend) podLabels(p *pod) map[string]string {
+	labels := map[string]string{labelContainer: p.id}
+	if run := b.config.Labels[libhive.LabelRunID]; run != "" {
+		labels[libhive.LabelRunID] = labelValue(run)
+	}
+	return labels
+}
+
+// labelValue replaces the characters which aren't allowed in label values.
+func labelValue(v string) string {
+	r := []rune(v)
+	for i, c := range r {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			r[i] = '_'
+		}
+	}
+	if len(r) > 63 {
+		r = r[:63]
+	}
+	return strings.Trim(string(r), "-_.")
+}
+
+// resourceLimits converts the resource limits of a container.
+func resourceLimits(r libhive.ContainerResources) *resourceRequirements {
+	limits := make(map[string]string)
+	if r.CPUs > 0 {
+		limits["cpu"] = strconv.FormatInt(int64(r.CPUs*1000), 10) + "m"
+	}
+	if r.Memory > 0 {
+		limits["memory"] = strconv.FormatInt(int64(r.Memory), 10)
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+	return &resourceRequirements{Limits: limits}
+}
+
+// filesConfigMap creates the ConfigMap holding the files of a container, and the
+// volume and mounts placing them at their paths.
+func filesConfigMap(name string, files map[string]*multipart.FileHeader) (*configMapObject, []volumeMount, volume, error) {
+	cm := &configMapObject{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   objectMeta{Name: name},
+		BinaryData: make(map[string][]byte),
+	}
+	mode := int32(0777)
+	vol := volume{Name: "files", ConfigMap: &configMapVolume{Name: name, DefaultMode: &mode}}
+	paths := make([]string, 0, len(files))
+	for file := range files {
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+
+	var mounts []volumeMount
+	size := 0
+	for i, file := range paths {
+		f, err := files[file].Open()
+		if err != nil {
+			return nil, nil, vol, err
+		}
+		data, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, vol, err
+		}
+		if size += len(data); size > maxFilesSize {
+			return nil, nil, vol, &libhive.Error{
+				Code:    libhive.CodeInvalidRequest,
+				Message: "container files are too large for the kubernetes backend",
+				Context: map[string]string{"limit": strconv.Itoa(maxFilesSize)},
+			}
+		}
+		key := "file" + strconv.Itoa(i)
+		cm.BinaryData[key] = data
+		vol.ConfigMap.Items = append(vol.ConfigMap.Items, keyToPath{Key: key, Path: key})
+		mounts = append(mounts, volumeMount{Name: vol.Name, MountPath: path.Join("/", file), SubPath: key})
+	}
+	return cm, mounts, vol, nil
+}
+
+// addPortProxy adds a socat container for each proxied port. The containers of a pod
+// share its network, so the proxy reaches the client at localhost.
+func (b *ContainerBackend) addPortProxy(p *pod, ports map[uint16]uint16) {
+	image := b.config.ProxyImage
+	if image == "" {
+		image = libdocker.DefaultProxyImage
+	}
+	listen := make([]int, 0, len(ports))
+	for port := range ports {
+		listen = append(listen, int(port))
+	}
+	sort.Ints(listen)
+	for _, port := range listen {
+		p.spec.Containers = append(p.spec.Containers, containerSpec{
+			Name:            fmt.Sprintf("proxy-%d", port),
+			Image:           image,
+			ImagePullPolicy: "IfNotPresent",
+			Command: []string{
+				"socat",
+				fmt.Sprintf("TCP-LISTEN:%d,fork,reuseaddr", port),
+				fmt.Sprintf("TCP:127.0.0.1:%d", ports[uint16(port)]),
+			},
+		})
+	}
+}
+
+// StartContainer creates the pod of a container and waits for it to run.
+func (b *ContainerBackend) StartContainer(ctx context.Context, containerID string, opt libhive.ContainerOptions) (*libhive.ContainerInfo, error) {
+	p, err := b.lookup(containerID)
+	if err != nil {
+		return nil, err
+	}
+	info := &libhive.ContainerInfo{ID: p.id[:8], LogFile: opt.LogFile}
+	logger := b.logger.New("container", info.ID, "pod", p.name)
+	startTime := time.Now()
+
+	annotations := make(map[string]string)
+	for key, val := range b.config.Labels {
+		annotations[key] = val
+	}
+	for key, val := range opt.Labels {
+		annotations[key] = val
+	}
+	obj := &podObject{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Metadata:   objectMeta{Name: p.name, Labels: b.podLabels(p), Annotations: annotations},
+		Spec:       p.spec,
+	}
+	logger.Debug("creating pod")
+	if err := b.api.do(ctx, "POST", b.podPath(""), nil, obj, nil); err != nil {
+		b.DeleteContainer(p.id)
+		return nil, fmt.Errorf("container did not start: %v", err)
+	}
+	b.mu.Lock()
+	p.started = true
+	b.running++
+	if b.running > b.stats.PeakConcurrent {
+		b.stats.PeakConcurrent = b.running
+	}
+	waitCtx, stop := context.WithCancel(context.Background())
+	p.stop = stop
+	b.mu.Unlock()
+
+	// Wait for the pod to run.
+	status, err := b.waitRunning(ctx, p)
+	if err != nil {
+		b.DeleteContainer(p.id)
+		return info, err
+	}
+	b.mu.Lock()
+	p.ip = status.PodIP
+	b.mu.Unlock()
+	info.IP = status.PodIP
+
+	// Stream the logs and wait for the pod to end.
+	containerExit := make(chan struct{})
+	go func() {
+		defer close(containerExit)
+		if opt.LogFile != "" {
+			if err := b.streamLogs(waitCtx, p, opt.LogFile); err != nil {
+				logger.Error("can't stream container logs", "err", err)
+			}
+		}
+		b.waitTerminated(waitCtx, p)
+		logger.Debug("container exited")
+	}()
+	info.Wait = func() { <-containerExit }
+
+	// Wait for the port check if requested.
+	hasStarted := make(chan struct{})
+	if opt.CheckLive != 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		addr := net.JoinHostPort(info.IP, strconv.Itoa(int(opt.CheckLive)))
+		go checkPort(ctx, logger, addr, hasStarted)
+	} else {
+		close(hasStarted)
+	}
+	var checkErr error
+	select {
+	case <-hasStarted:
+		logger.Debug("container online", "time", time.Since(startTime))
+	case <-containerExit:
+		checkErr = libhive.ErrContainerTerminated
+	case <-ctx.Done():
+		checkErr = libhive.ErrContainerStartTimeout
+	}
+	if checkErr != nil {
+		b.DeleteContainer(p.id)
+		info.Wait()
+		info.Wait = nil
+	}
+	return info, checkErr
+}
+
+// waitRunning polls the pod until it runs.
+func (b *ContainerBackend) waitRunning(ctx context.Context, p *pod) (*podStatus, error) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		var obj podObject
+		if err := b.api.do(ctx, "GET", b.podPath(p.name), nil, nil, &obj); err != nil {
+			if ctx.Err() != nil {
+				return nil, libhive.ErrContainerStartTimeout
+			}
+			return nil, err
+		}
+		switch obj.Status.Phase {
+		case podRunning:
+			return &obj.Status, nil
+		case podSucceeded, podFailed:
+			return nil, libhive.ErrContainerTerminated
+		}
+		// Image problems don't resolve by waiting.
+		for _, cs := range obj.Status.ContainerStatuses {
+			if w := cs.State.Waiting; w != nil {
+				switch w.Reason {
+				case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError", "CreateContainerError":
+					return nil, fmt.Errorf("container did not start: %s: %s", w.Reason, w.Message)
+				}
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, libhive.ErrContainerStartTimeout
+		}
+	}
+}
+
+// waitTerminated polls the pod until its main container has exited or the pod is gone.
+func (b *ContainerBackend) waitTerminated(ctx context.Context, p *pod) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		var obj podObject
+		err := b.api.do(ctx, "GET", b.podPath(p.name), nil, nil, &obj)
+		if err != nil && (ctx.Err() != nil || isNotFound(err)) {
+			return
+		}
+		if err == nil {
+			if obj.Status.Phase == podSucceeded || obj.Status.Phase == podFailed {
+				return
+			}
+			for _, cs := range obj.Status.ContainerStatuses {
+				if cs.Name == mainContainer && cs.State.Terminated != nil {
+					return
+				}
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// streamLogs writes the output of the main container to the log file until it exits.
+// Kubernetes merges stdout and stderr, so the streams are not logged separately.
+func (b *ContainerBackend) streamLogs(ctx context.Context, p *pod, logfile string) error {
+	if err := os.MkdirAll(filepath.Dir(logfile), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(logfile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	log := libhive.NewLogWriter(logfile, file)
+	defer log.Close()
+
+	query := url.Values{"container": {mainContainer}, "follow": {"true"}}
+	stream, err := b.api.stream(ctx, b.podPath(p.name)+"/log", query)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(log, stream)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// checkPort waits for the given TCP address to accept a connection.
+func checkPort(ctx context.Context, logger log15.Logger, addr string, notify chan<- struct{}) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			conn.Close()
+			close(notify)
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			logger.Debug("port check ended", "addr", addr, "err", ctx.Err())
+			return
+		}
+	}
+}
+
+// DeleteContainer removes the pod and files of a container.
+func (b *ContainerBackend) DeleteContainer(containerID string) error {
+	p, err := b.lookup(containerID)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	delete(b.pods, p.id)
+	if p.started {
+		b.running--
+	}
+	b.mu.Unlock()
+	if p.stop != nil {
+		p.stop()
+	}
+
+	b.logger.Debug("removing container", "container", p.id[:8], "pod", p.name)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var errs []string
+	if p.started {
+		if err := b.api.do(ctx, "DELETE", b.podPath(p.name), nil, nil, nil); err != nil && !isNotFound(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	if p.files {
+		if err := b.api.do(ctx, "DELETE", b.configMapPath(p.name), nil, nil, nil); err != nil && !isNotFound(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		err := fmt.Errorf("can't remove pod %s: %s", p.name, strings.Join(errs, "; "))
+		b.logger.Error("can't remove container", "container", p.id[:8], "err", err)
+		return err
+	}
+	return nil
+}
+
+// PauseContainer is not supported, kubernetes can't suspend pods.
+func (b *ContainerBackend) PauseContainer(containerID string) error {
+	return errNotSupported
+}
+
+// UnpauseContainer is not supported, kubernetes can't suspend pods.
+func (b *ContainerBackend) UnpauseContainer(containerID string) error {
+	return errNotSupported
+}
+
+// ProbeNetwork is not supported by the kubernetes backend.
+func (b *ContainerBackend) ProbeNetwork(ctx context.Context, from, to, toIP string, duration time.Duration) (*libhive.NetworkProbe, error) {
+	return nil, errNotSupported
+}
+
+// SetNetworkConditions is not supported by the kubernetes backend.
+func (b *ContainerBackend) SetNetworkConditions(ctx context.Context, containerID string, cond libhive.NetworkConditions) error {
+	return errNotSupported
+}
+
+// CreateNetwork is not supported by the kubernetes backend. Pods can't be isolated
+// from each other without NetworkPolicies, so networks would only be names.
+func (b *ContainerBackend) CreateNetwork(name string, opt libhive.NetworkOptions) (string, error) {
+	return "", errNotSupported
+}
+
+// NetworkNameToID finds the network ID of network by the given name. Only the pod
+// network exists.
+func (b *ContainerBackend) NetworkNameToID(name string) (string, error) {
+	if name == bridgeNetwork {
+		return bridgeNetwork, nil
+	}
+	return "", libhive.ErrNetworkNotFound
+}
+
+// RemoveNetwork is not supported by the kubernetes backend.
+func (b *ContainerBackend) RemoveNetwork(id string) error {
+	return errNotSupported
+}
+
+// ContainerIP returns the IP of a container on the pod network.
+func (b *ContainerBackend) ContainerIP(containerID, networkID string) (net.IP, error) {
+	p, err := b.lookup(containerID)
+	if err != nil {
+		return nil, err
+	}
+	if networkID != bridgeNetwork {
+		return nil, libhive.ErrNetworkNotFound
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if p.ip == "" {
+		return nil, fmt.Errorf("container %s is not running", p.id[:8])
+	}
+	return net.ParseIP(p.ip), nil
+}
+
+// ContainerNetworks returns the IP addresses of a container on all networks
+// it is connected to, which is just the pod network.
+func (b *ContainerBackend) ContainerNetworks(containerID string) (map[string]net.IP, error) {
+	p, err := b.lookup(containerID)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return map[string]net.IP{bridgeNetwork: net.ParseIP(p.ip)}, nil
+}
+
+// ConnectContainer is not supported by the kubernetes backend.
+func (b *ContainerBackend) ConnectContainer(containerID, networkID string, ip net.IP) error {
+	return errNotSupported
+}
+
+// DisconnectContainer is not supported by the kubernetes backend.
+func (b *ContainerBackend) DisconnectContainer(containerID, networkID string) error {
+	return errNotSupported
+}
@@ -0,0 +1,258 @@
+package libk8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+	"github.com/gorilla/websocket"
+)
+
+// fakeCluster is a minimal kubernetes API server. Pods run as soon as they are created,
+// and exit when their logs have been read.
+type fakeCluster struct {
+	mu         sync.Mutex
+	pods       map[string]*podObject
+	configMaps map[string]*configMapObject
+	exited     map[string]bool
+}
+
+func newFakeCluster() *fakeCluster {
+	return &fakeCluster{
+		pods:       make(map[string]*podObject),
+		configMaps: make(map[string]*configMapObject),
+		exited:     make(map[string]bool),
+	}
+}
+
+func (c *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/api/v1/namespaces/test/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	switch {
+	case r.Method == "POST" && path[0] == "configmaps":
+		var cm configMapObject
+		json.NewDecoder(r.Body).Decode(&cm)
+		c.configMaps[cm.Metadata.Name] = &cm
+	case r.Method == "DELETE" && path[0] == "configmaps" && c.configMaps[path[1]] != nil:
+		delete(c.configMaps, path[1])
+	case r.Method == "POST" && path[0] == "pods":
+		var p podObject
+		json.NewDecoder(r.Body).Decode(&p)
+		p.Status = podStatus{Phase: podRunning, PodIP: "10.0.0.1"}
+		c.pods[p.Metadata.Name] = &p
+	case r.Method == "GET" && path[0] == "pods" && len(path) == 2 && c.pods[path[1]] != nil:
+		p := *c.pods[path[1]]
+		if c.exited[path[1]] {
+			p.Status.Phase = podSucceeded
+		}
+		json.NewEncoder(w).Encode(&p)
+	case r.Method == "GET" && path[0] == "pods" && len(path) == 3 && path[2] == "log":
+		c.exited[path[1]] = true
+		w.Write([]byte("client output\n"))
+	case r.Method == "GET" && path[0] == "pods" && len(path) == 3 && path[2] == "exec":
+		c.serveExec(w, r)
+	case r.Method == "DELETE" && path[0] == "pods" && c.pods[path[1]] != nil:
+		delete(c.pods, path[1])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(&apiError{Code: http.StatusNotFound, Message: "not found"})
+	}
+}
+
+// serveExec runs a command which fails with exit code 3.
+func (c *fakeCluster) serveExec(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{execProtocol}}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	status := `{"status":"Failure","reason":"NonZeroExitCode","details":{"causes":[{"reason":"ExitCode","message":"3"}]}}`
+	conn.WriteMessage(websocket.BinaryMessage, append([]byte{execStdout}, strings.Join(r.URL.Query()["command"], " ")...))
+	conn.WriteMessage(websocket.BinaryMessage, append([]byte{execStderr}, "failed"...))
+	conn.WriteMessage(websocket.BinaryMessage, append([]byte{execStatus}, status...))
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
+
+func newTestBackend(t *testing.T, cfg *Config) (*ContainerBackend, *fakeCluster) {
+	cluster := newFakeCluster()
+	srv := httptest.NewServer(cluster)
+	t.Cleanup(srv.Close)
+	return newContainerBackend(newAPIClient(srv.URL, "", nil), "test", cfg), cluster
+}
+
+// testFiles creates the files of a container.
+func testFiles(t *testing.T, files map[string]string) map[string]*multipart.FileHeader {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for name, content := range files {
+		fw, _ := w.CreateFormFile(name, filepath.Base(name))
+		fw.Write([]byte(content))
+	}
+	w.Close()
+	form, err := multipart.NewReader(&buf, w.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]*multipart.FileHeader)
+	for name, fh := range form.File {
+		result[name] = fh[0]
+	}
+	return result
+}
+
+func TestContainerLifecycle(t *testing.T) {
+	var pushed []string
+	cfg := &Config{
+		Registry: "registry.example.com/hive/",
+		PushImage: func(ctx context.Context, image, target string) error {
+			pushed = append(pushed, image+" "+target)
+			return nil
+		},
+		Labels: map[string]string{libhive.LabelRunID: "run:1"},
+	}
+	b, cluster := newTestBackend(t, cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := libhive.ContainerOptions{
+		Env:     map[string]string{"HIVE_NETWORK_ID": "1"},
+		Files:   testFiles(t, map[string]string{"/genesis.json": "{}"}),
+		Labels:  map[string]string{libhive.LabelClient: "go-ethereum"},
+		LogFile: filepath.Join(t.TempDir(), "client.log"),
+	}
+	id, err := b.CreateContainer(ctx, "hive/clients/go-ethereum", opts)
+	if err != nil {
+		t.Fatal("create failed:", err)
+	}
+	if _, err := b.CreateContainer(ctx, "hive/clients/go-ethereum", libhive.ContainerOptions{}); err != nil {
+		t.Fatal("second create failed:", err)
+	}
+	want := []string{"hive/clients/go-ethereum registry.example.com/hive/hive/clients/go-ethereum"}
+	if strings.Join(pushed, ",") != strings.Join(want, ",") {
+		t.Errorf("wrong image pushes %q, want %q", pushed, want)
+	}
+	cm := cluster.configMaps["hive-"+id]
+	if cm == nil || string(cm.BinaryData["file0"]) != "{}" {
+		t.Fatalf("files not stored: %+v", cm)
+	}
+
+	info, err := b.StartContainer(ctx, id, opts)
+	if err != nil {
+		t.Fatal("start failed:", err)
+	}
+	if info.ID != id[:8] || info.IP != "10.0.0.1" {
+		t.Errorf("wrong container info %+v", info)
+	}
+	p := cluster.pods["hive-"+id]
+	if p.Metadata.Labels[libhive.LabelRunID] != "run_1" {
+		t.Errorf("wrong run label %q", p.Metadata.Labels[libhive.LabelRunID])
+	}
+	if p.Metadata.Annotations[libhive.LabelClient] != "go-ethereum" {
+		t.Errorf("missing client annotation: %v", p.Metadata.Annotations)
+	}
+	mounts := p.Spec.Containers[0].VolumeMounts
+	if len(mounts) != 1 || mounts[0].MountPath != "/genesis.json" || mounts[0].SubPath != "file0" {
+		t.Errorf("wrong volume mounts %+v", mounts)
+	}
+
+	// The container exits when its logs are read.
+	info.Wait()
+	log, _ := ioutil.ReadFile(opts.LogFile)
+	if string(log) != "client output\n" {
+		t.Errorf("wrong log file content %q", log)
+	}
+
+	// The short ID is accepted.
+	if err := b.DeleteContainer(info.ID); err != nil {
+		t.Fatal("delete failed:", err)
+	}
+	if len(cluster.pods) != 0 || len(cluster.configMaps) != 0 {
+		t.Errorf("objects not deleted: %d pods, %d configmaps", len(cluster.pods), len(cluster.configMaps))
+	}
+	stats := b.Stats()
+	if stats.RunID != "run:1" || stats.ContainersCreated != 2 || stats.PeakConcurrent != 1 {
+		t.Errorf("wrong stats %+v", stats)
+	}
+}
+
+func TestRunProgram(t *testing.T) {
+	b, _ := newTestBackend(t, &Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	id, err := b.CreateContainer(ctx, "hive/clients/go-ethereum", libhive.ContainerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := b.RunProgram(ctx, id, []string{"echo", "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Stdout != "echo hello" || info.Stderr != "failed" || info.ExitCode != 3 {
+		t.Errorf("wrong exec result %+v", info)
+	}
+	if _, err := b.RunEnodeSh(ctx, id); err == nil {
+		t.Error("RunEnodeSh didn't fail for exit code 3")
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	b, _ := newTestBackend(t, &Config{})
+	if id, err := b.NetworkNameToID("bridge"); err != nil || id != bridgeNetwork {
+		t.Errorf("wrong bridge network ID %q, %v", id, err)
+	}
+	if _, err := b.NetworkNameToID("net1"); !errors.Is(err, libhive.ErrNetworkNotFound) {
+		t.Errorf("wrong error for unknown network: %v", err)
+	}
+
+	b.pods["c1"] = &pod{id: "c1", name: "hive-c1", ip: "10.0.0.1"}
+	ip, err := b.ContainerIP("c1", bridgeNetwork)
+	if err != nil || ip.String() != "10.0.0.1" {
+		t.Errorf("wrong container IP %v, %v", ip, err)
+	}
+	if _, err := b.ContainerIP("c1", "net1"); !errors.Is(err, libhive.ErrNetworkNotFound) {
+		t.Errorf("wrong error for unknown network: %v", err)
+	}
+	if nets, _ := b.ContainerNetworks("c1"); len(nets) != 1 || nets[bridgeNetwork].String() != "10.0.0.1" {
+		t.Errorf("wrong container networks %v", nets)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	b, _ := newTestBackend(t, &Config{})
+	ctx := context.Background()
+	errs := map[string]error{
+		"pause":      b.PauseContainer("c1"),
+		"unpause":    b.UnpauseContainer("c1"),
+		"netem":      b.SetNetworkConditions(ctx, "c1", libhive.NetworkConditions{}),
+		"connect":    b.ConnectContainer("c1", bridgeNetwork, nil),
+		"disconnect": b.DisconnectContainer("c1", bridgeNetwork),
+		"rmnetwork":  b.RemoveNetwork(bridgeNetwork),
+	}
+	_, errs["mknetwork"] = b.CreateNetwork("net1", libhive.NetworkOptions{})
+	_, errs["probe"] = b.ProbeNetwork(ctx, "c1", "c2", "10.0.0.2", time.Second)
+	for op, err := range errs {
+		var herr *libhive.Error
+		if !errors.As(err, &herr) || herr.Code != libhive.CodeInvalidRequest {
+			t.Errorf("%s: wrong error %v", op, err)
+		}
+	}
+}
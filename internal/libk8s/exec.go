@@ -0,0 +1,145 @@
+package libk8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/hive/internal/libhive"
+	"github.com/gorilla/websocket"
+)
+
+// execProtocol is the websocket subprotocol of exec streams. Each message starts with
+// the number of its stream.
+const execProtocol = "v4.channel.k8s.io"
+
+// These are the streams of execProtocol.
+const (
+	execStdout = 1
+	execStderr = 2
+	execStatus = 3
+)
+
+// execResult is the status object sent on the status stream when the command has ended.
+type execResult struct {
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	Details struct {
+		Causes []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"causes"`
+	} `json:"details"`
+}
+
+// RunEnodeSh runs the /enode.sh script in a container and returns its output.
+func (b *ContainerBackend) RunEnodeSh(ctx context.Context, containerID string) (string, error) {
+	info, err := b.RunProgram(ctx, containerID, []string{"/enode.sh"})
+	if err != nil {
+		return "", fmt.Errorf("can't run enode.sh in %s: %v", containerID, err)
+	}
+	if info.ExitCode != 0 {
+		return "", fmt.Errorf("enode.sh in %s failed with exit code %d: %s", containerID, info.ExitCode, strings.TrimSpace(info.Stderr))
+	}
+	return info.Stdout, nil
+}
+
+// RunProgram runs a command in a container and returns its outputs and exit code.
+func (b *ContainerBackend) RunProgram(ctx context.Context, containerID string, cmd []string) (*libhive.ExecInfo, error) {
+	p, err := b.lookup(containerID)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"container": {mainContainer}, "stdout": {"true"}, "stderr": {"true"}, "command": cmd}
+	conn, err := b.api.dialWebsocket(ctx, b.podPath(p.name)+"/exec", query, execProtocol)
+	if err != nil {
+		return nil, fmt.Errorf("can't run exec %v: %v", cmd, err)
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var stdout, stderr bytes.Buffer
+	var result *execResult
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) || result != nil {
+				break
+			}
+			return nil, fmt.Errorf("exec %v failed: %v", cmd, err)
+		}
+		if len(msg) == 0 {
+			continue
+		}
+		switch msg[0] {
+		case execStdout:
+			stdout.Write(msg[1:])
+		case execStderr:
+			stderr.Write(msg[1:])
+		case execStatus:
+			result = new(execResult)
+			if err := json.Unmarshal(msg[1:], result); err != nil {
+				return nil, fmt.Errorf("invalid exec status: %v", err)
+			}
+		}
+	}
+	code, err := result.exitCode()
+	if err != nil {
+		return nil, fmt.Errorf("exec %v failed: %v", cmd, err)
+	}
+	return &libhive.ExecInfo{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: code}, nil
+}
+
+// exitCode returns the exit code of the command.
+func (r *execResult) exitCode() (int, error) {
+	switch {
+	case r == nil:
+		return 0, fmt.Errorf("no exit status")
+	case r.Status == "Success":
+		return 0, nil
+	case r.Reason == "NonZeroExitCode":
+		for _, c := range r.Details.Causes {
+			if c.Reason == "ExitCode" {
+				return strconv.Atoi(c.Message)
+			}
+		}
+	}
+	return 0, fmt.Errorf("%s", r.Message)
+}
+
+// dialWebsocket opens a websocket connection to the API server.
+func (c *apiClient) dialWebsocket(ctx context.Context, path string, query url.Values, protocol string) (*websocket.Conn, error) {
+	u := c.server + path + "?" + query.Encode()
+	switch {
+	case strings.HasPrefix(u, "https://"):
+		u = "wss://" + strings.TrimPrefix(u, "https://")
+	case strings.HasPrefix(u, "http://"):
+		u = "ws://" + strings.TrimPrefix(u, "http://")
+	}
+	dialer := websocket.Dialer{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: c.tls,
+		Subprotocols:    []string{protocol},
+	}
+	header := make(http.Header)
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	conn, resp, err := dialer.DialContext(ctx, u, header)
+	if err != nil {
+		if resp != nil {
+			return nil, &apiError{Code: resp.StatusCode, Message: fmt.Sprintf("%v: %s", err, resp.Status)}
+		}
+		return nil, err
+	}
+	return conn, nil
+}
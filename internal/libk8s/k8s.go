@@ -0,0 +1,368 @@
+// Package libk8s implements a container backend which runs client and simulator
+// containers as pods of a Kubernetes cluster. It uses the REST API of the cluster
+// directly, like the podman support uses the Docker-compatible API of podman.
+package libk8s
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+	"gopkg.in/inconshreveable/log15.v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the configuration of the kubernetes backend.
+type Config struct {
+	// Kubeconfig is the kubeconfig file used to connect to the cluster. If empty, the
+	// in-cluster configuration is used when hive runs in a pod, and $KUBECONFIG or
+	// ~/.kube/config otherwise.
+	Kubeconfig string
+
+	// Namespace is the namespace of the pods. If empty, the namespace of the kubeconfig
+	// context is used, or "default".
+	Namespace string
+
+	// Registry is prepended to image names, e.g. "registry.example.com/hive". Images
+	// are pushed there using PushImage before their first container is created. If
+	// empty, images must already be available on the cluster nodes.
+	Registry  string
+	PushImage func(ctx context.Context, image, target string) error
+
+	// HostIP is the address at which pods reach hive. If nil, the local address of
+	// the connection to the cluster is used.
+	HostIP net.IP
+
+	// ProxyImage is the socat image used for client port proxies.
+	// If empty, libdocker.DefaultProxyImage is used.
+	ProxyImage string
+
+	// These labels are set on all pods.
+	Labels map[string]string
+
+	Logger log15.Logger
+}
+
+// Connect connects to the cluster and creates the backend.
+func Connect(cfg *Config) (*ContainerBackend, error) {
+	api, namespace, err := loadAPIConfig(cfg.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Namespace != "" {
+		namespace = cfg.Namespace
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var version struct {
+		GitVersion string `json:"gitVersion"`
+	}
+	if err := api.do(ctx, "GET", "/version", nil, nil, &version); err != nil {
+		return nil, fmt.Errorf("can't connect to kubernetes: %v", err)
+	}
+	b := newContainerBackend(api, namespace, cfg)
+	b.logger.Debug("kubernetes cluster online", "server", api.server, "version", version.GitVersion, "namespace", namespace)
+	return b, nil
+}
+
+// apiClient performs requests to the kubernetes API server.
+type apiClient struct {
+	server string // base URL
+	token  string // bearer token, may be empty
+	tls    *tls.Config
+	http   *http.Client
+}
+
+func newAPIClient(server, token string, tlsConfig *tls.Config) *apiClient {
+	return &apiClient{
+		server: strings.TrimSuffix(server, "/"),
+		token:  token,
+		tls:    tlsConfig,
+		http:   &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment}},
+	}
+}
+
+// apiError is the Status object returned by the API server for failed requests.
+type apiError struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("kubernetes API error %d", e.Code)
+	}
+	return e.Message
+}
+
+// isNotFound reports whether err is a 404 response of the API server.
+func isNotFound(err error) bool {
+	var aerr *apiError
+	return errors.As(err, &aerr) && aerr.Code == http.StatusNotFound
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into out.
+func (c *apiClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		enc, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(enc)
+	}
+	resp, err := c.request(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// stream sends a GET request and returns the response body.
+func (c *apiClient) stream(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	resp, err := c.request(ctx, "GET", path, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *apiClient) request(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	u := c.server + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		aerr := &apiError{Code: resp.StatusCode}
+		content, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(content, aerr) != nil || aerr.Message == "" {
+			aerr.Message = fmt.Sprintf("%s %s: %s", method, path, resp.Status)
+		}
+		aerr.Code = resp.StatusCode
+		return nil, aerr
+	}
+	return resp, nil
+}
+
+// serviceAccountDir contains the service account credentials in pods.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// loadAPIConfig creates the API client from the kubeconfig file, or from the service
+// account of the pod if no file is given and hive runs in the cluster. It also returns
+// the default namespace.
+func loadAPIConfig(kubeconfig string) (*apiClient, string, error) {
+	if kubeconfig == "" {
+		if host := os.Getenv("KUBERNETES_SERVICE_HOST"); host != "" {
+			return inClusterConfig(host, os.Getenv("KUBERNETES_SERVICE_PORT"))
+		}
+		kubeconfig = os.Getenv("KUBECONFIG")
+		if i := strings.IndexByte(kubeconfig, os.PathListSeparator); i >= 0 {
+			kubeconfig = kubeconfig[:i]
+		}
+	}
+	if kubeconfig == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, "", fmt.Errorf("can't find kubeconfig: %v", err)
+		}
+		kubeconfig = filepath.Join(home, ".kube", "config")
+	}
+	content, err := ioutil.ReadFile(kubeconfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("can't read kubeconfig: %v", err)
+	}
+	return parseKubeconfig(content, filepath.Dir(kubeconfig))
+}
+
+func inClusterConfig(host, port string) (*apiClient, string, error) {
+	token, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return nil, "", fmt.Errorf("can't read service account token: %v", err)
+	}
+	ca, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, "", fmt.Errorf("can't read service account CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, "", errors.New("invalid service account CA")
+	}
+	namespace := "default"
+	if ns, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		namespace = strings.TrimSpace(string(ns))
+	}
+	server := "https://" + net.JoinHostPort(host, port)
+	return newAPIClient(server, strings.TrimSpace(string(token)), &tls.Config{RootCAs: pool}), namespace, nil
+}
+
+// kubeconfig is the part of the kubeconfig file format used by hive.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string      `yaml:"token"`
+			TokenFile             string      `yaml:"tokenFile"`
+			ClientCertificate     string      `yaml:"client-certificate"`
+			ClientCertificateData string      `yaml:"client-certificate-data"`
+			ClientKey             string      `yaml:"client-key"`
+			ClientKeyData         string      `yaml:"client-key-data"`
+			Exec                  interface{} `yaml:"exec"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// parseKubeconfig creates the API client for the current context of a kubeconfig
+// file. Relative paths in the file are resolved against dir.
+func parseKubeconfig(content []byte, dir string) (*apiClient, string, error) {
+	var kc kubeconfig
+	if err := yaml.Unmarshal(content, &kc); err != nil {
+		return nil, "", fmt.Errorf("invalid kubeconfig: %v", err)
+	}
+	var clusterName, userName, namespace string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == kc.CurrentContext {
+			clusterName, userName, namespace = c.Context.Cluster, c.Context.User, c.Context.Namespace
+			found = true
+		}
+	}
+	if !found {
+		return nil, "", fmt.Errorf("kubeconfig has no context %q", kc.CurrentContext)
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	readData := func(data, file string) ([]byte, error) {
+		if data != "" {
+			return base64.StdEncoding.DecodeString(data)
+		}
+		if file == "" {
+			return nil, nil
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		return ioutil.ReadFile(file)
+	}
+
+	var (
+		server    string
+		tlsConfig = new(tls.Config)
+		token     string
+	)
+	found = false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		server = c.Cluster.Server
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		ca, err := readData(c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority)
+		if err != nil {
+			return nil, "", fmt.Errorf("can't read CA of cluster %q: %v", clusterName, err)
+		}
+		if ca != nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, "", fmt.Errorf("invalid CA of cluster %q", clusterName)
+			}
+		}
+	}
+	if !found {
+		return nil, "", fmt.Errorf("kubeconfig has no cluster %q", clusterName)
+	}
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		if u.User.Exec != nil {
+			return nil, "", fmt.Errorf("user %q: exec credential plugins are not supported", userName)
+		}
+		token = u.User.Token
+		if u.User.TokenFile != "" {
+			t, err := readData("", u.User.TokenFile)
+			if err != nil {
+				return nil, "", fmt.Errorf("can't read token of user %q: %v", userName, err)
+			}
+			token = strings.TrimSpace(string(t))
+		}
+		cert, err := readData(u.User.ClientCertificateData, u.User.ClientCertificate)
+		if err != nil {
+			return nil, "", fmt.Errorf("can't read client certificate of user %q: %v", userName, err)
+		}
+		key, err := readData(u.User.ClientKeyData, u.User.ClientKey)
+		if err != nil {
+			return nil, "", fmt.Errorf("can't read client key of user %q: %v", userName, err)
+		}
+		if cert != nil || key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid client certificate of user %q: %v", userName, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+	return newAPIClient(server, token, tlsConfig), namespace, nil
+}
+
+// errNotSupported is returned for operations which have no equivalent in kubernetes.
+var errNotSupported = &libhive.Error{
+	Code:    libhive.CodeInvalidRequest,
+	Message: "operation not supported by the kubernetes backend",
+	Hint:    "Run the simulation with the docker backend.",
+}
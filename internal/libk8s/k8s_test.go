@@ -0,0 +1,77 @@
+package libk8s
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKubeconfig = `
+apiVersion: v1
+kind: Config
+current-context: ci
+contexts:
+- name: local
+  context: {cluster: local, user: admin}
+- name: ci
+  context: {cluster: ci, user: hive, namespace: hive-ci}
+clusters:
+- name: local
+  cluster: {server: "https://127.0.0.1:6443"}
+- name: ci
+  cluster: {server: "https://k8s.example.com/", insecure-skip-tls-verify: true}
+users:
+- name: admin
+  user: {token: admin-token}
+- name: hive
+  user: {tokenFile: token}
+`
+
+func TestParseKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "token"), []byte("hive-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	api, namespace, err := parseKubeconfig([]byte(testKubeconfig), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.server != "https://k8s.example.com" {
+		t.Errorf("wrong server %q", api.server)
+	}
+	if api.token != "hive-token" {
+		t.Errorf("wrong token %q", api.token)
+	}
+	if !api.tls.InsecureSkipVerify {
+		t.Error("insecure-skip-tls-verify not applied")
+	}
+	if namespace != "hive-ci" {
+		t.Errorf("wrong namespace %q", namespace)
+	}
+}
+
+func TestParseKubeconfigErrors(t *testing.T) {
+	tests := []struct {
+		config, err string
+	}{
+		{
+			config: strings.Replace(testKubeconfig, "current-context: ci", "current-context: prod", 1),
+			err:    `kubeconfig has no context "prod"`,
+		},
+		{
+			config: strings.Replace(testKubeconfig, "- name: ci\n  cluster:", "- name: other\n  cluster:", 1),
+			err:    `kubeconfig has no cluster "ci"`,
+		},
+		{
+			config: strings.Replace(testKubeconfig, "{tokenFile: token}", "{exec: {command: aws}}", 1),
+			err:    `user "hive": exec credential plugins are not supported`,
+		},
+	}
+	for _, test := range tests {
+		_, _, err := parseKubeconfig([]byte(test.config), t.TempDir())
+		if err == nil || err.Error() != test.err {
+			t.Errorf("wrong error %v, want %q", err, test.err)
+		}
+	}
+}
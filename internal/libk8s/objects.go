@@ -0,0 +1,107 @@
+package libk8s
+
+// This file contains the parts of the kubernetes API objects used by the backend.
+
+type objectMeta struct {
+	Name        string            `json:"name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type podObject struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   objectMeta `json:"metadata"`
+	Spec       podSpec    `json:"spec"`
+	Status     podStatus  `json:"status,omitempty"`
+}
+
+type podSpec struct {
+	Containers                    []containerSpec `json:"containers"`
+	Volumes                       []volume        `json:"volumes,omitempty"`
+	RestartPolicy                 string          `json:"restartPolicy,omitempty"`
+	AutomountServiceAccountToken  *bool           `json:"automountServiceAccountToken,omitempty"`
+	TerminationGracePeriodSeconds *int64          `json:"terminationGracePeriodSeconds,omitempty"`
+}
+
+type containerSpec struct {
+	Name            string                `json:"name"`
+	Image           string                `json:"image"`
+	ImagePullPolicy string                `json:"imagePullPolicy,omitempty"`
+	Command         []string              `json:"command,omitempty"`
+	Env             []envVar              `json:"env,omitempty"`
+	VolumeMounts    []volumeMount         `json:"volumeMounts,omitempty"`
+	Resources       *resourceRequirements `json:"resources,omitempty"`
+}
+
+type envVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type volumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+	SubPath   string `json:"subPath,omitempty"`
+}
+
+type volume struct {
+	Name      string           `json:"name"`
+	ConfigMap *configMapVolume `json:"configMap,omitempty"`
+}
+
+type configMapVolume struct {
+	Name        string      `json:"name"`
+	DefaultMode *int32      `json:"defaultMode,omitempty"`
+	Items       []keyToPath `json:"items,omitempty"`
+}
+
+type keyToPath struct {
+	Key  string `json:"key"`
+	Path string `json:"path"`
+}
+
+type resourceRequirements struct {
+	Limits map[string]string `json:"limits,omitempty"`
+}
+
+type podStatus struct {
+	Phase             string            `json:"phase,omitempty"`
+	PodIP             string            `json:"podIP,omitempty"`
+	Message           string            `json:"message,omitempty"`
+	ContainerStatuses []containerStatus `json:"containerStatuses,omitempty"`
+}
+
+type containerStatus struct {
+	Name  string         `json:"name"`
+	Ready bool           `json:"ready"`
+	State containerState `json:"state"`
+}
+
+type containerState struct {
+	Waiting    *containerStateReason `json:"waiting,omitempty"`
+	Running    *struct{}             `json:"running,omitempty"`
+	Terminated *containerStateReason `json:"terminated,omitempty"`
+}
+
+type containerStateReason struct {
+	Reason   string `json:"reason,omitempty"`
+	Message  string `json:"message,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+}
+
+type configMapObject struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   objectMeta        `json:"metadata"`
+	BinaryData map[string][]byte `json:"binaryData,omitempty"`
+}
+
+// Pod phases.
+const (
+	podPending   = "Pending"
+	podRunning   = "Running"
+	podSucceeded = "Succeeded"
+	podFailed    = "Failed"
+)
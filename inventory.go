@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+// inventoryCommand implements 'hive inventory'. It lists all clients and simulators
+// with their metadata.
+func inventoryCommand(args []string) {
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Prints the catalog as JSON, for use by other tools.")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: hive inventory [options]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	inv, err := libhive.LoadInventory(".")
+	if err != nil {
+		fatal(err)
+	}
+	catalog, err := inv.Catalog()
+	if err != nil {
+		fatal(err)
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(catalog); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	printCatalog(os.Stdout, catalog)
+}
+
+// printCatalog writes the catalog in human-readable form.
+func printCatalog(w io.Writer, c *libhive.Catalog) {
+	fmt.Fprintln(w, "clients:")
+	for _, client := range c.Clients {
+		fmt.Fprintf(w, "  %s\n", client.Name)
+		fmt.Fprintf(w, "      roles: %s\n", strings.Join(client.Meta.Roles, ", "))
+		if len(client.Meta.Forks) > 0 {
+			fmt.Fprintf(w, "      forks: %s\n", strings.Join(client.Meta.Forks, ", "))
+		}
+		if len(client.Meta.Dockerfiles) > 0 {
+			fmt.Fprintf(w, "      dockerfiles: %s\n", strings.Join(client.Meta.DockerfileNames(), ", "))
+		}
+	}
+	fmt.Fprintln(w, "simulators:")
+	for _, sim := range c.Simulators {
+		fmt.Fprintf(w, "  %s\n", sim.Name)
+		if sim.Meta.Description != "" {
+			desc := strings.TrimSpace(sim.Meta.Description)
+			fmt.Fprintf(w, "      %s\n", strings.ReplaceAll(desc, "\n", "\n      "))
+		}
+		if len(sim.Meta.Roles) > 0 {
+			fmt.Fprintf(w, "      roles: %s\n", strings.Join(sim.Meta.Roles, ", "))
+		}
+		if len(sim.Meta.Forks) > 0 {
+			fmt.Fprintf(w, "      forks: %s\n", strings.Join(sim.Meta.Forks, ", "))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// simParamsTimeout is the max time a simulator may take to declare its parameters.
+const simParamsTimeout = time.Minute
+
+// readSimParams runs a simulator in parameters-only mode and returns the parameters
+// it declares. The result is nil if the simulator doesn't declare parameters, e.g.
+// because it doesn't use the hivesim Go package. Simulators whose metadata doesn't
+// set 'params' aren't started. Otherwise, the simulator is stopped as soon as it
+// declares its parameters or makes any other API request. Simulators which make no
+// request at all run until they exit, or for at most simParamsTimeout.
+func (r *simRunner) readSimParams(ctx context.Context, sim string) ([]libhive.SimParam, error) {
+	meta, err := r.inv.SimulatorMetadata(sim)
+	if err != nil {
+		return nil, err
+	}
+	if !meta.Params {
+		return nil, nil
+	}
+
+	tm := libhive.NewTestManager(libhive.SimEnv{ParamsOnly: true}, r.container, -1)
+	addr, server, err := startTestSuiteAPI(tm, r.engine, r.hostIP)
+	if err != nil {
+		return nil, err
+	}
+	defer shutdownServer(server)
+
+	opts := libhive.ContainerOptions{
+		Env: map[string]string{
+			"HIVE_SIMULATOR":   "http://" + addr.String(),
+			"HIVE_PARAMS_ONLY": "1",
+		},
+		Labels: map[string]string{libhive.LabelSimulator: sim},
+	}
+	containerID, err := r.container.CreateContainer(ctx, r.simImages[sim], opts)
+	if err != nil {
+		return nil, err
+	}
+	defer r.container.DeleteContainer(containerID)
+	sc, err := r.container.StartContainer(ctx, containerID, opts)
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		sc.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-tm.ParamsDone():
+	case <-time.After(simParamsTimeout):
+		log15.Warn("simulator did not exit after declaring its parameters", "sim", sim)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return tm.SimParams(), nil
+}
+
+// checkSimParams verifies the parameters given to a simulator with --sim.env before
+// the simulation starts.
+func (r *simRunner) checkSimParams(ctx context.Context, sim string) error {
+	params, err := r.readSimParams(ctx, sim)
+	if err != nil {
+		return fmt.Errorf("can't read parameters of simulator %s: %v", sim, err)
+	}
+	if params == nil {
+		log15.Warn("simulator does not declare parameters, --sim.env values are not checked", "sim", sim)
+		return nil
+	}
+	if err := libhive.ValidateSimParams(params, r.env.SimParams); err != nil {
+		if e, ok := err.(*libhive.Error); ok {
+			return e.With("simulator", sim)
+		}
+		return err
+	}
+	return nil
+}
+
+// listSimParams prints the parameters declared by the simulators.
+func (r *simRunner) listSimParams(ctx context.Context, w io.Writer, simList []string) error {
+	for i, sim := range simList {
+		params, err := r.readSimParams(ctx, sim)
+		if err != nil {
+			return fmt.Errorf("can't read parameters of simulator %s: %v", sim, err)
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, sim)
+		if params == nil {
+			fmt.Fprintln(w, "  (parameters not declared)")
+		}
+		for _, p := range params {
+			def := ""
+			if p.Default != "" {
+				def = fmt.Sprintf(" (default %s)", p.Default)
+			}
+			fmt.Fprintf(w, "  %s %s%s\n", p.Name, p.Type, def)
+			if p.Description != "" {
+				fmt.Fprintf(w, "      %s\n", strings.ReplaceAll(p.Description, "\n", "\n      "))
+			}
+		}
+	}
+	return nil
+}
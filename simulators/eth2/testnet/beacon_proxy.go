@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Faults are injected into the beacon API by proxies between the validator clients and
+// beacon nodes. When beacon API proxies are enabled, every validator client is connected
+// to its beacon node through one proxy, and to the beacon node of the next testnet node
+// through another proxy, which serves as the fallback. Validator clients receive the
+// addresses of both in HIVE_ETH2_BN_API_ADDRS, the primary one first.
+//
+// Proxies also withhold blocks: a block of a held slot which the validator client
+// publishes is acknowledged right away, but forwarded to the beacon node only when
+// the hold ends.
+
+// faultKind is the way a faulty response differs from the real one.
+type faultKind int
+
+const (
+	faultError    faultKind = iota // the request fails with status 500
+	faultTruncate                  // the response body is cut off halfway
+	faultStale                     // the last good response of the endpoint is repeated
+)
+
+var faultKinds = map[string]faultKind{
+	"error":    faultError,
+	"truncate": faultTruncate,
+	"stale":    faultStale,
+}
+
+func (k faultKind) String() string {
+	for name, kind := range faultKinds {
+		if kind == k {
+			return name
+		}
+	}
+	return fmt.Sprintf("faultKind(%d)", int(k))
+}
+
+// apiFault is a fault injected into the responses of beacon API endpoints.
+type apiFault struct {
+	kind  faultKind
+	paths *regexp.Regexp // endpoints having the fault, nil for all endpoints
+}
+
+func (f *apiFault) String() string {
+	if f.paths == nil {
+		return f.kind.String()
+	}
+	return fmt.Sprintf("%v on %s", f.kind, f.paths)
+}
+
+// validatorPaths matches the beacon API endpoints used for validator duties.
+var validatorPaths = regexp.MustCompile(`^/eth/v[0-9]+/validator/`)
+
+// blockPaths matches the beacon API endpoints for publishing blocks.
+var blockPaths = regexp.MustCompile(`^/eth/v[0-9]+/beacon/(blinded_)?blocks$`)
+
+// blockHold withholds the blocks of a slot until the given time.
+type blockHold struct {
+	slot  common.Slot
+	until time.Time
+
+	wg   sync.WaitGroup // pending releases
+	mu   sync.Mutex
+	held int   // number of blocks held
+	err  error // first error forwarding a held block
+}
+
+// heldBlocks returns the number of blocks held. It waits until they are forwarded.
+// The hold must be removed from all proxies first, so no more blocks are added while
+// waiting.
+func (h *blockHold) heldBlocks() (int, error) {
+	h.wg.Wait()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.held, h.err
+}
+
+// blockSlot returns the slot of a signed block published by a validator client.
+func blockSlot(body []byte) (common.Slot, bool) {
+	var block struct {
+		Message *struct {
+			Slot common.Slot `json:"slot"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &block); err != nil || block.Message == nil {
+		return 0, false
+	}
+	return block.Message.Slot, true
+}
+
+// maxStaleResponse is the size limit of responses kept for stale faults.
+const maxStaleResponse = 1 << 20
+
+// proxyResponse is a response of a beacon node.
+type proxyResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// write sends the response, but only the first n bytes of the body. The content length
+// is that of the full body, so a truncated body fails to decode on the client side.
+func (resp *proxyResponse) write(w http.ResponseWriter, n int) {
+	for key, values := range resp.header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Connection", "Content-Length", "Transfer-Encoding":
+			continue
+		}
+		w.Header()[key] = values
+	}
+	w.Header().Set("Content-Length", fmt.Sprint(len(resp.body)))
+	w.WriteHeader(resp.status)
+	w.Write(resp.body[:n])
+}
+
+// beaconProxy forwards the beacon API requests of a validator client to a beacon node
+// and injects faults into the responses.
+type beaconProxy struct {
+	IP     string // address of the simulator container
+	Port   int
+	URL    string // beacon API address of the proxy
+	target string // beacon API address of the beacon node
+	srv    *http.Server
+	client *http.Client
+
+	mu            sync.Mutex
+	faults        []*apiFault
+	holds         []*blockHold
+	last          map[string]*proxyResponse // last good response by method and path
+	lastValidator time.Time                 // time of the last good validator duty response
+	faulted       int                       // number of faulty responses
+}
+
+// startBeaconProxy starts a proxy for the beacon API of bn. The proxy is reachable
+// at the given IP address of the simulator container.
+func startBeaconProxy(ip string, bn *BeaconNode) (*beaconProxy, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	p := &beaconProxy{
+		IP:     ip,
+		Port:   port,
+		URL:    fmt.Sprintf("http://%s:%d", ip, port),
+		target: bn.API.Addr,
+		client: &http.Client{Timeout: 30 * time.Second},
+		last:   make(map[string]*proxyResponse),
+	}
+	p.srv = &http.Server{Handler: p}
+	go p.srv.Serve(l)
+	return p, nil
+}
+
+// Close stops the proxy.
+func (p *beaconProxy) Close() error {
+	return p.srv.Close()
+}
+
+// addFault injects a fault until it is removed. When faults overlap, the fault added
+// first applies.
+func (p *beaconProxy) addFault(f *apiFault) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.faults = append(p.faults, f)
+}
+
+func (p *beaconProxy) removeFault(f *apiFault) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.faults {
+		if p.faults[i] == f {
+			p.faults = append(p.faults[:i], p.faults[i+1:]...)
+			return
+		}
+	}
+}
+
+// addHold withholds the blocks of a slot until the hold is removed.
+func (p *beaconProxy) addHold(h *blockHold) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.holds = append(p.holds, h)
+}
+
+func (p *beaconProxy) removeHold(h *blockHold) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.holds {
+		if p.holds[i] == h {
+			p.holds = append(p.holds[:i], p.holds[i+1:]...)
+			return
+		}
+	}
+}
+
+// holdBlock checks whether a request publishes a block of a held slot. If so, the
+// block is forwarded when the hold ends.
+func (p *beaconProxy) holdBlock(r *http.Request, body []byte) bool {
+	if r.Method != http.MethodPost || !blockPaths.MatchString(r.URL.Path) {
+		return false
+	}
+	slot, ok := blockSlot(body)
+	if !ok {
+		return false
+	}
+	// The block is added to the hold while p.mu is held, so it can't be added after
+	// the hold was removed and heldBlocks has started waiting.
+	p.mu.Lock()
+	var hold *blockHold
+	for _, h := range p.holds {
+		if h.slot == slot {
+			hold = h
+			break
+		}
+	}
+	if hold != nil {
+		hold.mu.Lock()
+		hold.held++
+		hold.mu.Unlock()
+		hold.wg.Add(1)
+	}
+	p.mu.Unlock()
+	if hold == nil {
+		return false
+	}
+
+	header := r.Header.Clone()
+	go func() {
+		defer hold.wg.Done()
+		time.Sleep(time.Until(hold.until))
+		resp, err := p.forward(context.Background(), r.Method, r.URL.RequestURI(), header, body)
+		if err == nil && resp.status != http.StatusOK && resp.status != http.StatusAccepted {
+			err = fmt.Errorf("beacon node rejected block of slot %d: %d %s", slot, resp.status, resp.body)
+		}
+		if err != nil {
+			hold.mu.Lock()
+			if hold.err == nil {
+				hold.err = err
+			}
+			hold.mu.Unlock()
+		}
+	}()
+	return true
+}
+
+// faultedResponses returns the number of faulty responses sent by the proxy.
+func (p *beaconProxy) faultedResponses() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.faulted
+}
+
+// lastValidatorResponse returns the time at which the proxy last forwarded a good
+// response of a validator duty endpoint.
+func (p *beaconProxy) lastValidatorResponse() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastValidator
+}
+
+func (p *beaconProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if p.holdBlock(r, body) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	key := r.Method + " " + r.URL.Path
+
+	p.mu.Lock()
+	var fault *apiFault
+	for _, f := range p.faults {
+		if f.paths == nil || f.paths.MatchString(r.URL.Path) {
+			fault = f
+			break
+		}
+	}
+	stale := p.last[key]
+	if fault != nil {
+		p.faulted++
+	}
+	p.mu.Unlock()
+
+	switch {
+	case fault != nil && fault.kind == faultError:
+		writeAPIError(w, http.StatusInternalServerError, "fault injected by hive")
+		return
+	case fault != nil && fault.kind == faultStale && stale != nil:
+		stale.write(w, len(stale.body))
+		return
+	}
+	resp, err := p.forward(r.Context(), r.Method, r.URL.RequestURI(), r.Header, body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if fault != nil && fault.kind == faultTruncate {
+		resp.write(w, len(resp.body)/2)
+		return
+	}
+	if fault == nil && resp.status == http.StatusOK {
+		p.mu.Lock()
+		if len(resp.body) <= maxStaleResponse {
+			p.last[key] = resp
+		}
+		if validatorPaths.MatchString(r.URL.Path) {
+			p.lastValidator = time.Now()
+		}
+		p.mu.Unlock()
+	}
+	resp.write(w, len(resp.body))
+}
+
+// forward sends a request to the beacon node.
+func (p *beaconProxy) forward(ctx context.Context, method, uri string, header http.Header, body []byte) (*proxyResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.target+uri, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &proxyResponse{status: resp.StatusCode, header: resp.Header, body: respBody}, nil
+}
+
+// writeAPIError writes an error response in the format of the beacon API.
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": status, "message": msg})
+}
+
+// startBeaconProxies starts the proxies of a validator client using the given beacon
+// node. The first proxy forwards to that beacon node, the second one to the fallback,
+// which is the beacon node of the next testnet node.
+func (t *Testnet) startBeaconProxies(bnIndex int) ([]*beaconProxy, error) {
+	if len(t.beacons) < 2 {
+		return nil, fmt.Errorf("beacon API proxies need at least 2 beacon nodes")
+	}
+	if t.simIP == "" {
+		ip, err := t.t.Sim.ContainerNetworkIP(t.t.SuiteID, "bridge", "simulation")
+		if err != nil {
+			return nil, fmt.Errorf("can't get IP address of simulator container: %v", err)
+		}
+		t.simIP = ip
+	}
+	var proxies []*beaconProxy
+	for _, index := range []int{bnIndex, (bnIndex + 1) % len(t.beacons)} {
+		proxy, err := startBeaconProxy(t.simIP, t.beacons[index])
+		if err != nil {
+			closeProxies(proxies)
+			return nil, fmt.Errorf("can't start beacon API proxy: %v", err)
+		}
+		t.t.Logf("beacon API proxy %s forwards to beacon %d", proxy.URL, index)
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+func closeProxies(proxies []*beaconProxy) {
+	for _, proxy := range proxies {
+		proxy.Close()
+	}
+}
+
+// closeBeaconProxies stops the beacon API proxies of all validator clients.
+func (t *Testnet) closeBeaconProxies() {
+	for _, vc := range t.validatorClients() {
+		closeProxies(vc.proxies)
+	}
+}
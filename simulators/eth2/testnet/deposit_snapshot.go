@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/hive/hivesim"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// depositTreeDepth is the depth of the deposit contract merkle tree.
+const depositTreeDepth = 32
+
+// Function selectors of the deposit contract.
+var (
+	getDepositRootSelector  = []byte{0xc5, 0xf2, 0x89, 0x2f} // get_deposit_root()
+	getDepositCountSelector = []byte{0x62, 0x1f, 0xd1, 0x30} // get_deposit_count()
+)
+
+var errSnapshotUnsupported = errors.New("deposit snapshot endpoint not supported")
+
+// depositSnapshot is the EIP-4881 deposit tree snapshot returned by
+// /eth/v1/beacon/deposit_snapshot.
+type depositSnapshot struct {
+	Finalized            []common.Root `json:"finalized"`
+	DepositRoot          common.Root   `json:"deposit_root"`
+	DepositCount         quotedUint64  `json:"deposit_count"`
+	ExecutionBlockHash   common.Root   `json:"execution_block_hash"`
+	ExecutionBlockHeight quotedUint64  `json:"execution_block_height"`
+}
+
+// quotedUint64 is an integer encoded as a decimal string, as usual in the beacon API.
+type quotedUint64 uint64
+
+func (v *quotedUint64) UnmarshalJSON(input []byte) error {
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		return err
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	*v = quotedUint64(n)
+	return err
+}
+
+// VerifyDepositSnapshots checks the deposit tree snapshots of all beacon nodes once
+// finality is reached, and again every two epochs. The snapshots must be internally
+// consistent, agree between beacon nodes at the same execution block, and match the
+// deposit contract state of the execution client. A later snapshot of a node must extend
+// its earlier snapshots.
+//
+// The check also starts an extra beacon node without validators, which is restarted
+// once its snapshot has been checked. After the restart, the node has to serve a
+// snapshot extending the one from before the restart.
+//
+// Nodes which don't support the endpoint are skipped.
+func (t *Testnet) VerifyDepositSnapshots(ctx context.Context, prep *PreparedTestnet, beaconDef *hivesim.ClientDefinition) {
+	epochDuration := time.Duration(t.spec.SLOTS_PER_EPOCH) * time.Duration(t.spec.SECONDS_PER_SLOT) * time.Second
+
+	nodes := append([]*BeaconNode(nil), t.beacons...)
+	restartIndex := -1
+	if bn, err := prep.newBeaconNode(t, beaconDef, []int{0}); err != nil {
+		t.t.Errorf("deposit snapshot: can't start beacon node for restart check: %v", err)
+	} else {
+		restartIndex = len(nodes)
+		nodes = append(nodes, bn)
+		t.t.Logf("deposit snapshot: beacon %d will be restarted", restartIndex)
+	}
+
+	// Wait for the deposit tree to be finalized. The eth1 follow distance and voting
+	// period delay this by a few epochs.
+	next := t.GenesisTime().Add(6 * epochDuration)
+	var (
+		skip       = make(map[int]bool)
+		previous   = make(map[int]*depositSnapshot)
+		restarted  bool
+		catchingUp bool // restarted node hasn't reached its snapshot from before the restart
+	)
+	for waitUntil(ctx, next) {
+		next = next.Add(2 * epochDuration)
+
+		byBlock := make(map[common.Root]int) // execution block hash -> beacon node index
+		for i, b := range nodes {
+			if skip[i] {
+				continue
+			}
+			snap, err := b.DepositSnapshot(ctx)
+			if i == restartIndex && catchingUp {
+				// The restarted node has to sync and finalize the deposits again before
+				// its snapshot can be compared with the one from before the restart.
+				if err != nil {
+					t.t.Logf("deposit snapshot: restarted beacon %d is catching up: %v", i, err)
+					continue
+				}
+				if snap.ExecutionBlockHeight < previous[i].ExecutionBlockHeight {
+					t.t.Logf("deposit snapshot: restarted beacon %d is catching up: at execution block %d, was at %d before restart",
+						i, snap.ExecutionBlockHeight, previous[i].ExecutionBlockHeight)
+					continue
+				}
+				catchingUp = false
+			}
+			if err == errSnapshotUnsupported {
+				t.t.Logf("deposit snapshot: beacon %d (%s) does not support the endpoint", i, b.Type)
+				skip[i] = true
+				continue
+			} else if err != nil {
+				t.t.Errorf("deposit snapshot: beacon %d: %v", i, err)
+				continue
+			}
+			if err := snap.verify(); err != nil {
+				t.t.Errorf("deposit snapshot: beacon %d (%s) returned invalid snapshot: %v", i, b.Type, err)
+				continue
+			}
+			if prev := previous[i]; prev != nil {
+				if err := snap.checkExtends(prev); err != nil {
+					t.t.Errorf("deposit snapshot: beacon %d (%s): %v", i, b.Type, err)
+				}
+			}
+			previous[i] = snap
+			if j, ok := byBlock[snap.ExecutionBlockHash]; ok {
+				if other := previous[j]; snap.DepositRoot != other.DepositRoot || snap.DepositCount != other.DepositCount {
+					t.t.Errorf("deposit snapshot: beacon %d and %d disagree at execution block %s: root %s/%s, count %d/%d",
+						i, j, snap.ExecutionBlockHash, snap.DepositRoot, other.DepositRoot, snap.DepositCount, other.DepositCount)
+				}
+				continue
+			}
+			byBlock[snap.ExecutionBlockHash] = i
+			if err := t.checkDepositContract(ctx, b, snap); err != nil {
+				t.t.Errorf("deposit snapshot: beacon %d (%s): %v", i, b.Type, err)
+				continue
+			}
+			t.t.Logf("deposit snapshot: beacon %d: %d deposits, root %s at execution block %d",
+				i, snap.DepositCount, snap.DepositRoot, snap.ExecutionBlockHeight)
+		}
+
+		// Restart the extra node once, after its first snapshot was checked.
+		if restartIndex >= 0 && !restarted && previous[restartIndex] != nil {
+			restarted = true
+			bn, err := prep.restartBeaconNode(t, nodes[restartIndex], beaconDef, []int{0})
+			if err != nil {
+				t.t.Errorf("deposit snapshot: can't restart beacon %d: %v", restartIndex, err)
+				skip[restartIndex] = true
+				continue
+			}
+			t.t.Logf("deposit snapshot: restarted beacon %d", restartIndex)
+			nodes[restartIndex] = bn
+			catchingUp = true
+		}
+	}
+}
+
+// DepositSnapshot fetches the deposit tree snapshot of the beacon node.
+func (bn *BeaconNode) DepositSnapshot(ctx context.Context) (*depositSnapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bn.API.Addr+"/eth/v1/beacon/deposit_snapshot", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("accept", "application/json")
+	resp, err := bn.API.Cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented:
+		return nil, errSnapshotUnsupported
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("deposit snapshot request failed: %s", resp.Status)
+	}
+	var out struct {
+		Data depositSnapshot `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid deposit snapshot: %v", err)
+	}
+	return &out.Data, nil
+}
+
+// verify checks that the finalized subtree roots of the snapshot produce its deposit root.
+func (s *depositSnapshot) verify() error {
+	count := uint64(s.DepositCount)
+	if n := bits.OnesCount64(count); len(s.Finalized) != n {
+		return fmt.Errorf("have %d finalized roots for %d deposits, want %d", len(s.Finalized), count, n)
+	}
+	if root := depositTreeRoot(s.Finalized, count); root != s.DepositRoot {
+		return fmt.Errorf("finalized roots produce deposit root %s, snapshot has %s", root, s.DepositRoot)
+	}
+	return nil
+}
+
+// checkExtends checks that the snapshot can follow an earlier snapshot of the same node.
+func (s *depositSnapshot) checkExtends(prev *depositSnapshot) error {
+	switch {
+	case s.ExecutionBlockHeight < prev.ExecutionBlockHeight:
+		return fmt.Errorf("snapshot went back from execution block %d to %d", prev.ExecutionBlockHeight, s.ExecutionBlockHeight)
+	case s.DepositCount < prev.DepositCount:
+		return fmt.Errorf("deposit count went back from %d to %d", prev.DepositCount, s.DepositCount)
+	case s.DepositCount == prev.DepositCount && s.DepositRoot != prev.DepositRoot:
+		return fmt.Errorf("deposit root changed from %s to %s without new deposits", prev.DepositRoot, s.DepositRoot)
+	}
+	return nil
+}
+
+// depositTreeRoot computes the deposit root from the roots of the finalized subtrees,
+// in the same way as the deposit contract computes it from its branch. The finalized
+// roots are ordered from the largest subtree to the smallest.
+func depositTreeRoot(finalized []common.Root, count uint64) common.Root {
+	var zero [depositTreeDepth]common.Root
+	for h := 1; h < depositTreeDepth; h++ {
+		zero[h] = sha256.Sum256(append(zero[h-1][:], zero[h-1][:]...))
+	}
+	var (
+		node common.Root
+		next = len(finalized) - 1
+	)
+	for h := 0; h < depositTreeDepth; h++ {
+		if count&(1<<uint(h)) != 0 {
+			node = sha256.Sum256(append(finalized[next][:], node[:]...))
+			next--
+		} else {
+			node = sha256.Sum256(append(node[:], zero[h][:]...))
+		}
+	}
+	var size [32]byte
+	binary.LittleEndian.PutUint64(size[:], count)
+	return sha256.Sum256(append(node[:], size[:]...))
+}
+
+// checkDepositContract compares the snapshot with the deposit contract state in the
+// execution client of the beacon node.
+func (t *Testnet) checkDepositContract(ctx context.Context, b *BeaconNode, snap *depositSnapshot) error {
+	if len(b.eth1) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	en := b.eth1[0]
+	addr, err := en.UserRPCAddress()
+	if err != nil {
+		return err
+	}
+	client, err := ethclient.DialContext(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("can't connect to execution client %s: %v", en.Type, err)
+	}
+	defer client.Close()
+
+	number := new(big.Int).SetUint64(uint64(snap.ExecutionBlockHeight))
+	header, err := client.HeaderByNumber(ctx, number)
+	if err != nil {
+		return fmt.Errorf("execution client %s can't provide block %d: %v", en.Type, number, err)
+	}
+	if header.Hash() != ethcommon.Hash(snap.ExecutionBlockHash) {
+		return fmt.Errorf("snapshot execution block %d has hash %s, execution client %s has %s", number, snap.ExecutionBlockHash, en.Type, header.Hash())
+	}
+
+	contract := t.eth1Genesis.DepositAddress
+	root, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: getDepositRootSelector}, number)
+	if err != nil {
+		return fmt.Errorf("can't get deposit root from execution client %s: %v", en.Type, err)
+	}
+	if len(root) != 32 {
+		return fmt.Errorf("execution client %s returned %d bytes for deposit root, want 32", en.Type, len(root))
+	}
+	count, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: getDepositCountSelector}, number)
+	if err != nil {
+		return fmt.Errorf("can't get deposit count from execution client %s: %v", en.Type, err)
+	}
+	if len(count) < 72 {
+		return fmt.Errorf("execution client %s returned %d bytes for deposit count, want at least 72", en.Type, len(count))
+	}
+	// The count is returned as ABI-encoded bytes holding a little-endian uint64.
+	elCount := binary.LittleEndian.Uint64(count[64:72])
+
+	if common.Root(ethcommon.BytesToHash(root)) != snap.DepositRoot || elCount != uint64(snap.DepositCount) {
+		return fmt.Errorf("snapshot (root %s, count %d) doesn't match deposit contract in %s (root %x, count %d) at block %d",
+			snap.DepositRoot, snap.DepositCount, en.Type, root, elCount, number)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// emptyDepositRoot is the root of the deposit contract before the first deposit.
+var emptyDepositRoot = common.Root{
+	0xd7, 0x0a, 0x23, 0x47, 0x31, 0x28, 0x5c, 0x68, 0x04, 0xc2, 0xa4, 0xf5, 0x67, 0x11, 0xdd, 0xb8,
+	0xc8, 0x2c, 0x99, 0x74, 0x0f, 0x20, 0x78, 0x54, 0x89, 0x10, 0x28, 0xaf, 0x34, 0xe2, 0x7e, 0x5e,
+}
+
+func TestDepositTreeRoot(t *testing.T) {
+	if root := depositTreeRoot(nil, 0); root != emptyDepositRoot {
+		t.Fatalf("wrong root of empty tree: got %s, want %s", root, emptyDepositRoot)
+	}
+
+	var leaves []common.Root
+	for count := 1; count <= 9; count++ {
+		leaves = append(leaves, sha256.Sum256([]byte{byte(count)}))
+		finalized := finalizedSubtrees(leaves)
+		want := naiveDepositRoot(leaves)
+		if root := depositTreeRoot(finalized, uint64(count)); root != want {
+			t.Errorf("count %d: wrong root %s, want %s", count, root, want)
+		}
+
+		snap := &depositSnapshot{Finalized: finalized, DepositRoot: want, DepositCount: quotedUint64(count)}
+		if err := snap.verify(); err != nil {
+			t.Errorf("count %d: valid snapshot rejected: %v", count, err)
+		}
+		snap.Finalized[0][0]++
+		if err := snap.verify(); err == nil || !strings.Contains(err.Error(), "produce deposit root") {
+			t.Errorf("count %d: snapshot with modified subtree root not rejected, err %v", count, err)
+		}
+	}
+}
+
+// naiveDepositRoot computes the deposit root by hashing the whole tree.
+func naiveDepositRoot(leaves []common.Root) common.Root {
+	level := append([]common.Root(nil), leaves...)
+	var zero common.Root
+	for h := 0; h < depositTreeDepth; h++ {
+		if len(level)%2 == 1 {
+			level = append(level, zero)
+		}
+		next := make([]common.Root, len(level)/2)
+		for i := range next {
+			next[i] = hashPair(level[2*i], level[2*i+1])
+		}
+		level, zero = next, hashPair(zero, zero)
+	}
+	var size common.Root
+	binary.LittleEndian.PutUint64(size[:], uint64(len(leaves)))
+	if len(level) == 0 {
+		return hashPair(zero, size)
+	}
+	return hashPair(level[0], size)
+}
+
+// finalizedSubtrees returns the roots of the complete subtrees covering the leaves,
+// largest first, as in an EIP-4881 snapshot.
+func finalizedSubtrees(leaves []common.Root) []common.Root {
+	var roots []common.Root
+	for len(leaves) > 0 {
+		size := 1
+		for size*2 <= len(leaves) {
+			size *= 2
+		}
+		level := leaves[:size]
+		for len(level) > 1 {
+			next := make([]common.Root, len(level)/2)
+			for i := range next {
+				next[i] = hashPair(level[2*i], level[2*i+1])
+			}
+			level = next
+		}
+		roots = append(roots, level[0])
+		leaves = leaves[size:]
+	}
+	return roots
+}
+
+func hashPair(a, b common.Root) common.Root {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/hive/hivesim"
+	"github.com/protolambda/eth2api"
+	"github.com/protolambda/eth2api/client/beaconapi"
+	"github.com/protolambda/eth2api/client/nodeapi"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+const (
+	// preGenesisDelay is the time between the creation of the genesis state and genesis
+	// in the genesis delay test. Clients start right after the state is created, so they
+	// idle for several minutes before genesis.
+	preGenesisDelay = 6 * time.Minute
+
+	// cleanGenesisSlots is the number of slots after genesis checked by the genesis
+	// delay test. Of these, at most cleanGenesisMaxMissed may be without a block.
+	cleanGenesisSlots     = 8
+	cleanGenesisMaxMissed = 2
+)
+
+// GenesisDelayTest starts a testnet with one node for every pairing of client types long
+// before genesis. It checks that the nodes wait for genesis without producing anything,
+// and that the chain starts cleanly at genesis, with all nodes following the same blocks.
+func (nc *ClientDefinitionsByRole) GenesisDelayTest() hivesim.TestSpec {
+	return hivesim.TestSpec{
+		Name:        "genesis-delay",
+		Description: "This starts a testnet with all client pairings several minutes before genesis, checks their behavior before genesis and verifies that they all start at genesis.",
+		Run: func(t *hivesim.T) {
+			pairings := nc.Pairings()
+			if len(pairings) == 0 {
+				t.Fatalf("need at least 1 eth1, beacon and validator client type")
+			}
+			for _, p := range pairings {
+				t.Logf("client pairing: %s", p)
+			}
+			// A single pairing runs two nodes, so blocks are exchanged over the network.
+			nodes := pairings
+			if len(nodes) == 1 {
+				nodes = append(nodes, nodes[0])
+			}
+			_, testnet := startTestnetNodes(t, nodes, preGenesisDelay, false)
+
+			ctx := context.Background()
+			testnet.VerifyPreGenesis(ctx)
+			testnet.VerifyCleanGenesis(ctx)
+		},
+	}
+}
+
+// VerifyPreGenesis checks the nodes of the testnet once per slot until shortly before
+// genesis. Execution clients must serve the configured genesis block, and beacon nodes
+// must report the configured genesis and stay at the genesis block. Validator clients
+// must not publish blocks or attestations before genesis.
+//
+// The calls between beacon nodes and execution clients aren't visible to the simulator.
+// Before genesis, there is no slot to propose a block for, and a beacon node which
+// produces a block or requests a payload would have to put it on top of the genesis
+// block, which is checked here.
+func (t *Testnet) VerifyPreGenesis(ctx context.Context) {
+	slotDuration := time.Duration(t.spec.SECONDS_PER_SLOT) * time.Second
+	// Stop a slot early, the nodes may legitimately prepare for the first slot.
+	end := t.GenesisTime().Add(-slotDuration)
+	ticker := time.NewTicker(slotDuration)
+	defer ticker.Stop()
+
+	genesisHash := t.eth1Genesis.Genesis.ToBlock(nil).Hash()
+	checks := 0
+	for time.Now().Before(end) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.t.Logf("pre-genesis: time till genesis: %s", time.Until(t.GenesisTime()).Round(time.Second))
+		for i, en := range t.eth1 {
+			if err := t.verifyEth1PreGenesis(ctx, en, genesisHash); err != nil {
+				t.t.Errorf("pre-genesis: [eth1 %d] %v", i, err)
+			}
+		}
+		for i, b := range t.beacons {
+			if err := t.verifyBeaconPreGenesis(ctx, b); err != nil {
+				t.t.Errorf("pre-genesis: [beacon %d] %v", i, err)
+			}
+		}
+		checks++
+	}
+	t.t.Logf("pre-genesis: checked all nodes %d times before genesis", checks)
+}
+
+// verifyEth1PreGenesis checks that an execution client is ready for genesis.
+func (t *Testnet) verifyEth1PreGenesis(ctx context.Context, en *Eth1Node, genesisHash ethcommon.Hash) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	addr, err := en.UserRPCAddress()
+	if err != nil {
+		return err
+	}
+	client, err := ethclient.DialContext(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("can't connect: %v", err)
+	}
+	defer client.Close()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get chain ID: %v", err)
+	}
+	if want := t.eth1Genesis.Genesis.Config.ChainID; chainID.Cmp(want) != 0 {
+		return fmt.Errorf("wrong chain ID %d, want %d", chainID, want)
+	}
+	genesis, err := client.HeaderByNumber(ctx, big.NewInt(0))
+	if err != nil {
+		return fmt.Errorf("can't get genesis block: %v", err)
+	}
+	if genesis.Hash() != genesisHash {
+		return fmt.Errorf("wrong genesis block %s, want %s", genesis.Hash(), genesisHash)
+	}
+	return nil
+}
+
+// verifyBeaconPreGenesis checks that a beacon node is waiting for genesis.
+func (t *Testnet) verifyBeaconPreGenesis(ctx context.Context, b *BeaconNode) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var genesis eth2api.GenesisResponse
+	if exists, err := beaconapi.Genesis(ctx, b.API, &genesis); err != nil {
+		return fmt.Errorf("can't get genesis: %v", err)
+	} else if !exists {
+		return fmt.Errorf("genesis unknown, but the genesis state was provided")
+	}
+	if genesis.GenesisTime != t.genesisTime {
+		return fmt.Errorf("wrong genesis time %d, want %d", genesis.GenesisTime, t.genesisTime)
+	}
+	if genesis.GenesisValidatorsRoot != t.genesisValidatorsRoot {
+		return fmt.Errorf("wrong genesis validators root %s, want %s", genesis.GenesisValidatorsRoot, t.genesisValidatorsRoot)
+	}
+
+	var head eth2api.BeaconBlockHeaderAndInfo
+	if exists, err := beaconapi.BlockHeader(ctx, b.API, eth2api.BlockHead, &head); err != nil {
+		return fmt.Errorf("can't get head block: %v", err)
+	} else if !exists {
+		return fmt.Errorf("no head block")
+	}
+	if slot := head.Header.Message.Slot; slot != 0 {
+		return fmt.Errorf("head block %s at slot %d before genesis", head.Root, slot)
+	}
+	var syncing eth2api.SyncingStatus
+	if err := nodeapi.SyncingStatus(ctx, b.API, &syncing); err != nil {
+		return fmt.Errorf("can't get sync status: %v", err)
+	}
+	if syncing.HeadSlot != 0 {
+		return fmt.Errorf("sync status head slot %d before genesis", syncing.HeadSlot)
+	}
+	var attestations []phase0.Attestation
+	if err := beaconapi.PoolAttestations(ctx, b.API, nil, nil, &attestations); err != nil {
+		return fmt.Errorf("can't get attestation pool: %v", err)
+	}
+	if len(attestations) > 0 {
+		return fmt.Errorf("%d attestations in pool before genesis", len(attestations))
+	}
+	return nil
+}
+
+// VerifyCleanGenesis waits for the first slots after genesis and checks that all beacon
+// nodes have the same block, or no block, at each of these slots. Few slots may be missed.
+func (t *Testnet) VerifyCleanGenesis(ctx context.Context) {
+	slotDuration := time.Duration(t.spec.SECONDS_PER_SLOT) * time.Second
+	// Give the block of the last slot time to propagate.
+	if !waitUntil(ctx, t.GenesisTime().Add((cleanGenesisSlots+1)*slotDuration)) {
+		return
+	}
+
+	missed := 0
+	for slot := common.Slot(1); slot <= cleanGenesisSlots; slot++ {
+		root, proposer, err := t.agreedBlockAt(ctx, slot)
+		switch {
+		case err != nil:
+			t.t.Errorf("genesis: slot %d: %v", slot, err)
+		case root == (common.Root{}):
+			t.t.Logf("genesis: slot %d: no block", slot)
+			missed++
+		default:
+			vc := t.validatorClientOf(proposer)
+			t.t.Logf("genesis: slot %d: block %s proposed by validator %d (validator client %d)", slot, root, proposer, vc)
+		}
+	}
+	if missed > cleanGenesisMaxMissed {
+		t.t.Errorf("genesis: %d of the first %d slots have no block, want at most %d", missed, cleanGenesisSlots, cleanGenesisMaxMissed)
+	}
+}
+
+// agreedBlockAt returns the block of a slot, checking that all beacon nodes have the same
+// block. The root is zero when the slot is empty.
+func (t *Testnet) agreedBlockAt(ctx context.Context, slot common.Slot) (common.Root, common.ValidatorIndex, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var (
+		agreed   common.Root
+		proposer common.ValidatorIndex
+	)
+	for i, b := range t.beacons {
+		var header eth2api.BeaconBlockHeaderAndInfo
+		exists, err := beaconapi.BlockHeader(ctx, b.API, eth2api.BlockIdSlot(slot), &header)
+		if err != nil {
+			return common.Root{}, 0, fmt.Errorf("[beacon %d] can't get block: %v", i, err)
+		}
+		var root common.Root
+		if exists {
+			root, proposer = header.Root, header.Header.Message.ProposerIndex
+		}
+		if i > 0 && root != agreed {
+			return common.Root{}, 0, fmt.Errorf("[beacon %d] has block %s, beacon 0 has block %s", i, root, agreed)
+		}
+		agreed = root
+	}
+	return agreed, proposer, nil
+}
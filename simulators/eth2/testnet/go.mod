@@ -27,3 +27,7 @@ require (
 	golang.org/x/sys v0.0.0-20210910150752-751e447fb3d0 // indirect
 	golang.org/x/text v0.3.7 // indirect
 )
+
+// The simulator uses the hivesim package of this repository. It is built
+// with the repository as docker build context, see hive.yaml.
+replace github.com/ethereum/hive => ../../..
@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"github.com/ethereum/hive/hivesim"
+	"sync"
 	"time"
+
+	"github.com/ethereum/hive/hivesim"
 )
 
 func jsonStr(v interface{}) string {
@@ -31,6 +33,17 @@ func main() {
 			t.Log("clients by role:", jsonStr(clientTypes))
 			byRole := ClientsByRole(clientTypes)
 			t.Log("clients by role:", jsonStr(byRole))
+
+			// Scenarios and the genesis delay test run first because the simple
+			// testnet tracks finality until the simulation is stopped.
+			scenarios, err := loadScenarios(scenarioDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, sc := range scenarios {
+				t.Run(byRole.ScenarioTest(sc))
+			}
+			t.Run(byRole.GenesisDelayTest())
 			simpleTest := byRole.SimpleTestnetTest()
 			t.Run(simpleTest)
 		},
@@ -43,39 +56,92 @@ func (nc *ClientDefinitionsByRole) SimpleTestnetTest() hivesim.TestSpec {
 		Name:        "single-client-testnet",
 		Description: "This runs quick eth2 single-client type testnet, with 4 nodes and 2**14 (minimum) validators",
 		Run: func(t *hivesim.T) {
-			prep := prepareTestnet(t, 1<<14, 4)
-			testnet := prep.createTestnet(t)
-
-			genesisTime := testnet.GenesisTime()
-			countdown := genesisTime.Sub(time.Now())
-			t.Logf("created new testnet, genesis at %s (%s from now)", genesisTime, countdown)
+			prep, testnet := nc.startTestnet(t, false)
 
-			// TODO: we can mix things for a multi-client testnet
-			if len(nc.Eth1) != 1 {
-				t.Fatalf("choose 1 eth1 client type")
+			// The background checks are stopped when finality tracking ends, so they
+			// don't report to the test after it has finished.
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			defer wg.Wait()
+			defer cancel()
+			background := func(check func()) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					check()
+				}()
 			}
-			if len(nc.Beacon) != 1 {
-				t.Fatalf("choose 1 beacon client type")
-			}
-			if len(nc.Validator) != 1 {
-				t.Fatalf("choose 1 validator client type")
-			}
-
-			// for each key partition, we start a validator client with its own beacon node and eth1 node
-			for i := 0; i < len(prep.keyTranches); i++ {
-				prep.startEth1Node(testnet, nc.Eth1[0])
-				prep.startBeaconNode(testnet, nc.Beacon[0], []int{i})
-				prep.startValidatorClient(testnet, nc.Validator[0], i, i)
-			}
-			t.Logf("started all nodes!")
-
-			ctx := context.Background()
 			// TODO: maybe run other assertions / tests in the background?
+			background(func() { testnet.TrackSyncCommittees(ctx) })
+			background(func() { testnet.VerifyExecutionPayloads(ctx) })
+			background(func() { testnet.VerifyDepositSnapshots(ctx, prep, nc.Beacon[0]) })
+			// The validators of the first client are moved to the second validator client
+			// type, or to a new client of the same type if only one type was chosen.
+			background(func() { testnet.VerifySlashingProtection(ctx, prep, 0, nc.Validator[1%len(nc.Validator)]) })
 			testnet.TrackFinality(ctx)
 		},
 	}
 }
 
+// ScenarioTest runs a single-client testnet with the events of a scenario file.
+func (nc *ClientDefinitionsByRole) ScenarioTest(sc *scenario) hivesim.TestSpec {
+	return hivesim.TestSpec{
+		Name:        "scenario-" + sc.name,
+		Description: "This runs a single-client testnet with 4 nodes and executes the steps of scenario " + sc.name,
+		Run: func(t *hivesim.T) {
+			_, testnet := nc.startTestnet(t, sc.usesBeaconProxies())
+			testnet.RunScenario(context.Background(), sc)
+		},
+	}
+}
+
+// defaultGenesisDelay is the time from the creation of a testnet until genesis.
+const defaultGenesisDelay = 2 * time.Minute
+
+// startTestnet starts a single-client testnet with 4 nodes. Every node consists
+// of an eth1 node, a beacon node and a validator client. When beaconProxies is set,
+// the validator clients are connected to the beacon nodes through beacon API proxies.
+func (nc *ClientDefinitionsByRole) startTestnet(t *hivesim.T, beaconProxies bool) (*PreparedTestnet, *Testnet) {
+	// TODO: we can mix things for a multi-client testnet
+	if len(nc.Eth1) != 1 {
+		t.Fatalf("choose 1 eth1 client type")
+	}
+	if len(nc.Beacon) != 1 {
+		t.Fatalf("choose 1 beacon client type")
+	}
+	if len(nc.Validator) == 0 {
+		t.Fatalf("choose at least 1 validator client type")
+	}
+	node := NodePairing{Eth1: nc.Eth1[0], Beacon: nc.Beacon[0], Validator: nc.Validator[0]}
+	return startTestnetNodes(t, []NodePairing{node, node, node, node}, defaultGenesisDelay, beaconProxies)
+}
+
+// startTestnetNodes starts a testnet with a node for each of the given client pairings.
+// The validator keys are split evenly among the nodes. Genesis is at genesisDelay after
+// the creation of the genesis state, the nodes are started right away.
+func startTestnetNodes(t *hivesim.T, nodes []NodePairing, genesisDelay time.Duration, beaconProxies bool) (*PreparedTestnet, *Testnet) {
+	prep := prepareTestnet(t, 1<<14, uint64(len(nodes)), genesisDelay)
+	prep.beaconProxies = beaconProxies
+	testnet := prep.createTestnet(t)
+
+	genesisTime := testnet.GenesisTime()
+	countdown := genesisTime.Sub(time.Now())
+	t.Logf("created new testnet, genesis at %s (%s from now)", genesisTime, countdown)
+
+	// for each key partition, we start a validator client with its own beacon node and eth1 node.
+	// The validator clients start last, so their fallback beacon nodes are running.
+	for i, node := range nodes {
+		prep.startEth1Node(testnet, node.Eth1)
+		prep.startBeaconNode(testnet, node.Beacon, []int{i})
+	}
+	for i, node := range nodes {
+		prep.startValidatorClient(testnet, node.Validator, i, i)
+	}
+	t.Logf("started all nodes!")
+
+	return prep, testnet
+}
+
 /*
 	TODO More testnet ideas:
 
@@ -7,6 +7,7 @@ import (
 	"github.com/ethereum/hive/hivesim"
 	"github.com/protolambda/eth2api"
 	"github.com/protolambda/eth2api/client/nodeapi"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"net/http"
 	"time"
 )
@@ -22,8 +23,8 @@ const (
 	PortValidatorAPI = 5000
 )
 
-// TODO: we assume the clients were configured with default ports.
-// Would be cleaner to run a script in the client to get the address without assumptions
+// Clients which use nonstandard RPC, engine or beacon API ports declare them in their
+// hive.yaml, and hive forwards the standard ports to them.
 
 type Eth1Node struct {
 	*hivesim.Client
@@ -41,6 +42,9 @@ func (en *Eth1Node) EngineRPCAddress() (string, error) {
 type BeaconNode struct {
 	*hivesim.Client
 	API *eth2api.Eth2HttpClient
+
+	// execution clients used by this beacon node
+	eth1 []*Eth1Node
 }
 
 func NewBeaconNode(cl *hivesim.Client) *BeaconNode {
@@ -71,4 +75,15 @@ func (bn *BeaconNode) EnodeURL() (string, error) {
 
 type ValidatorClient struct {
 	*hivesim.Client
+	// Indices is the range [start, end) of validator indices run by this client.
+	Indices [2]common.ValidatorIndex
+
+	beaconIndex int            // index of the beacon node used by the client
+	keyIndex    int            // index of the key tranche run by the client
+	proxies     []*beaconProxy // primary and fallback beacon API proxies, if enabled
+}
+
+// HasValidator reports whether the validator client runs the given validator.
+func (vc *ValidatorClient) HasValidator(index common.ValidatorIndex) bool {
+	return index >= vc.Indices[0] && index < vc.Indices[1]
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/protolambda/eth2api"
+	"github.com/protolambda/eth2api/client/beaconapi"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// payloadWalkLimit is the max number of blocks verified for a beacon node at once.
+// It limits the walk back to the last verified block when a node is first checked.
+const payloadWalkLimit = 64
+
+// VerifyExecutionPayloads checks, for every new block in the chain of every beacon node,
+// that the execution payload embedded in the beacon block matches the block stored by the
+// execution client connected to that beacon node. Mismatches fail the test.
+//
+// The block hash, parent hash, number, transaction count and, from the capella fork
+// on, the withdrawals are compared. Blocks are read as JSON, so that payloads of all
+// forks after the merge can be checked.
+func (t *Testnet) VerifyExecutionPayloads(ctx context.Context) {
+	var (
+		slotDuration = time.Duration(t.spec.SECONDS_PER_SLOT) * time.Second
+		timer        = time.NewTicker(slotDuration)
+		verified     = make(map[tree.Root]bool)
+	)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			for i, b := range t.beacons {
+				root, err := t.verifyHeadPayload(ctx, b, verified)
+				if err != nil {
+					t.t.Errorf("[beacon %d] execution payload of block %s: %v", i, root, err)
+				}
+			}
+		}
+	}
+}
+
+// payloadBlock is a signed beacon block, decoded only as far as needed for reading
+// its execution payload.
+type payloadBlock struct {
+	Data struct {
+		Message struct {
+			ParentRoot tree.Root `json:"parent_root"`
+			Body       struct {
+				ExecutionPayload *clPayload `json:"execution_payload"`
+			} `json:"body"`
+		} `json:"message"`
+	} `json:"data"`
+}
+
+// clPayload is an execution payload as returned by the beacon API.
+type clPayload struct {
+	ParentHash   ethcommon.Hash  `json:"parent_hash"`
+	BlockHash    ethcommon.Hash  `json:"block_hash"`
+	BlockNumber  view.Uint64View `json:"block_number"`
+	Transactions []hexutil.Bytes `json:"transactions"`
+	Withdrawals  []clWithdrawal  `json:"withdrawals"` // nil before capella
+}
+
+type clWithdrawal struct {
+	Index     view.Uint64View   `json:"index"`
+	Validator view.Uint64View   `json:"validator_index"`
+	Address   ethcommon.Address `json:"address"`
+	Amount    view.Uint64View   `json:"amount"`
+}
+
+// elBlock is a block as returned by eth_getBlockByHash, without transaction bodies.
+type elBlock struct {
+	ParentHash   ethcommon.Hash   `json:"parentHash"`
+	Number       hexutil.Uint64   `json:"number"`
+	Transactions []ethcommon.Hash `json:"transactions"`
+	Withdrawals  []elWithdrawal   `json:"withdrawals"`
+}
+
+type elWithdrawal struct {
+	Index     hexutil.Uint64    `json:"index"`
+	Validator hexutil.Uint64    `json:"validatorIndex"`
+	Address   ethcommon.Address `json:"address"`
+	Amount    hexutil.Uint64    `json:"amount"`
+}
+
+// verifyHeadPayload cross-checks the execution payloads of the blocks of a beacon node,
+// walking back from the head block to the last verified block.
+func (t *Testnet) verifyHeadPayload(ctx context.Context, b *BeaconNode, verified map[tree.Root]bool) (tree.Root, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var head eth2api.BeaconBlockHeaderAndInfo
+	if exists, err := beaconapi.BlockHeader(ctx, b.API, eth2api.BlockHead, &head); err != nil || !exists {
+		// Polling failures are reported by the finality tracker.
+		return tree.Root{}, nil
+	}
+
+	// Collect the blocks which haven't been verified yet, newest first.
+	var (
+		roots    []tree.Root
+		payloads []*clPayload
+	)
+	for root := head.Root; !verified[root] && len(roots) < payloadWalkLimit; {
+		var block payloadBlock
+		if exists, err := eth2api.SimpleRequest(ctx, b.API, eth2api.FmtGET("/eth/v2/beacon/blocks/%s", root), &block); err != nil || !exists {
+			// The block may have been pruned, or polling failed.
+			break
+		}
+		payload := block.Data.Message.Body.ExecutionPayload
+		if payload == nil || payload.BlockHash == (ethcommon.Hash{}) {
+			// No execution payload before the merge fork, or the block precedes the
+			// terminal PoW block. The same applies to all ancestors.
+			verified[root] = true
+			break
+		}
+		roots = append(roots, root)
+		payloads = append(payloads, payload)
+		root = block.Data.Message.ParentRoot
+	}
+
+	// Verify them, oldest first.
+	for i := len(roots) - 1; i >= 0; i-- {
+		if err := t.verifyPayload(ctx, b, payloads[i]); err != nil {
+			return roots[i], err
+		}
+		verified[roots[i]] = true
+	}
+	return head.Root, nil
+}
+
+// verifyPayload compares an execution payload with the blocks of the execution clients.
+func (t *Testnet) verifyPayload(ctx context.Context, b *BeaconNode, payload *clPayload) error {
+	for _, en := range b.eth1 {
+		addr, err := en.UserRPCAddress()
+		if err != nil {
+			return err
+		}
+		client, err := rpc.DialContext(ctx, addr)
+		if err != nil {
+			return fmt.Errorf("can't connect to execution client %s: %v", en.Type, err)
+		}
+		var block *elBlock
+		err = client.CallContext(ctx, &block, "eth_getBlockByHash", payload.BlockHash, false)
+		client.Close()
+		if err == nil && block == nil {
+			err = fmt.Errorf("not found")
+		}
+		if err != nil {
+			return fmt.Errorf("execution client %s can't provide block %s: %v", en.Type, payload.BlockHash, err)
+		}
+		if err := comparePayload(payload, block); err != nil {
+			return fmt.Errorf("%v (EL %s)", err, en.Type)
+		}
+	}
+	return nil
+}
+
+// comparePayload checks that an execution payload and an execution block match.
+func comparePayload(payload *clPayload, block *elBlock) error {
+	switch {
+	case block.ParentHash != payload.ParentHash:
+		return fmt.Errorf("parent hash mismatch: CL %s, EL %s", payload.ParentHash, block.ParentHash)
+	case uint64(block.Number) != uint64(payload.BlockNumber):
+		return fmt.Errorf("block number mismatch: CL %d, EL %d", payload.BlockNumber, block.Number)
+	case len(block.Transactions) != len(payload.Transactions):
+		return fmt.Errorf("transaction count mismatch: CL %d, EL %d", len(payload.Transactions), len(block.Transactions))
+	case len(block.Withdrawals) != len(payload.Withdrawals):
+		return fmt.Errorf("withdrawal count mismatch: CL %d, EL %d", len(payload.Withdrawals), len(block.Withdrawals))
+	}
+	for i, cw := range payload.Withdrawals {
+		ew := block.Withdrawals[i]
+		if uint64(cw.Index) != uint64(ew.Index) || uint64(cw.Validator) != uint64(ew.Validator) || cw.Address != ew.Address || uint64(cw.Amount) != uint64(ew.Amount) {
+			return fmt.Errorf("withdrawal %d mismatch: CL {index %d, validator %d, address %s, amount %d}, EL {index %d, validator %d, address %s, amount %d}",
+				i, cw.Index, cw.Validator, cw.Address, cw.Amount, ew.Index, ew.Validator, ew.Address, ew.Amount)
+		}
+	}
+	return nil
+}
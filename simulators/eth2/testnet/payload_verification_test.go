@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/protolambda/eth2api"
+	"github.com/protolambda/ztyp/tree"
+)
+
+func TestComparePayload(t *testing.T) {
+	const clJSON = `{
+		"parent_hash": "0x1111111111111111111111111111111111111111111111111111111111111111",
+		"block_hash": "0x2222222222222222222222222222222222222222222222222222222222222222",
+		"block_number": "12",
+		"transactions": ["0x01", "0x02"],
+		"withdrawals": [{"index": "3", "validator_index": "7", "address": "0x00000000000000000000000000000000000000aa", "amount": "1000"}]
+	}`
+	const elJSON = `{
+		"parentHash": "0x1111111111111111111111111111111111111111111111111111111111111111",
+		"number": "0xc",
+		"transactions": [
+			"0x3333333333333333333333333333333333333333333333333333333333333333",
+			"0x4444444444444444444444444444444444444444444444444444444444444444"
+		],
+		"withdrawals": [{"index": "0x3", "validatorIndex": "0x7", "address": "0x00000000000000000000000000000000000000aa", "amount": "0x3e8"}]
+	}`
+	tests := []struct {
+		modify func(*clPayload, *elBlock)
+		err    string
+	}{
+		{modify: func(*clPayload, *elBlock) {}},
+		{
+			modify: func(p *clPayload, b *elBlock) { p.Withdrawals, b.Withdrawals = nil, nil },
+		},
+		{
+			modify: func(p *clPayload, b *elBlock) { b.Number++ },
+			err:    "block number mismatch: CL 12, EL 13",
+		},
+		{
+			modify: func(p *clPayload, b *elBlock) { b.Transactions = b.Transactions[:1] },
+			err:    "transaction count mismatch: CL 2, EL 1",
+		},
+		{
+			modify: func(p *clPayload, b *elBlock) { b.Withdrawals = nil },
+			err:    "withdrawal count mismatch: CL 1, EL 0",
+		},
+		{
+			modify: func(p *clPayload, b *elBlock) { b.Withdrawals[0].Amount = 999 },
+			err:    "withdrawal 0 mismatch: CL {index 3, validator 7, address 0x00000000000000000000000000000000000000AA, amount 1000}, EL {index 3, validator 7, address 0x00000000000000000000000000000000000000AA, amount 999}",
+		},
+	}
+	for i, test := range tests {
+		var (
+			payload clPayload
+			block   elBlock
+		)
+		if err := json.Unmarshal([]byte(clJSON), &payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(elJSON), &block); err != nil {
+			t.Fatal(err)
+		}
+		test.modify(&payload, &block)
+		err := comparePayload(&payload, &block)
+		if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+			t.Errorf("test %d: wrong error %v, want %q", i, err, test.err)
+		}
+	}
+}
+
+// fakeChain is a beacon API serving a chain of blocks. Only the first block has no
+// execution payload.
+type fakeChain struct {
+	mu       sync.Mutex
+	length   int
+	requests []int // requested blocks
+}
+
+func chainRoot(n int) tree.Root {
+	return tree.Root{byte(n + 1)}
+}
+
+func (c *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r.URL.Path == "/eth/v1/beacon/headers/head" {
+		var header eth2api.BeaconBlockHeaderAndInfo
+		header.Root = chainRoot(c.length - 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": header})
+		return
+	}
+	for n := 0; n < c.length; n++ {
+		if r.URL.Path != "/eth/v2/beacon/blocks/"+chainRoot(n).String() {
+			continue
+		}
+		c.requests = append(c.requests, n)
+		body := "{}"
+		if n > 0 {
+			body = fmt.Sprintf(`{"execution_payload": {"block_hash": "0x%064x", "block_number": "%d"}}`, n, n)
+		}
+		fmt.Fprintf(w, `{"data": {"message": {"parent_root": "%s", "body": %s}}}`, chainRoot(n-1), body)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// This checks that payloads are verified from the head back to the last verified block.
+func TestVerifyPayloadsWalk(t *testing.T) {
+	chain := &fakeChain{length: 4}
+	srv := httptest.NewServer(chain)
+	defer srv.Close()
+	testnet := new(Testnet)
+	bn := &BeaconNode{API: &eth2api.Eth2HttpClient{Addr: srv.URL, Cli: &http.Client{}, Codec: eth2api.JSONCodec{}}}
+	verified := make(map[tree.Root]bool)
+
+	check := func(want string) {
+		t.Helper()
+		chain.mu.Lock()
+		chain.requests = nil
+		chain.mu.Unlock()
+		if _, err := testnet.verifyHeadPayload(context.Background(), bn, verified); err != nil {
+			t.Fatal(err)
+		}
+		chain.mu.Lock()
+		defer chain.mu.Unlock()
+		if got := strings.Trim(fmt.Sprint(chain.requests), "[]"); got != want {
+			t.Errorf("wrong blocks requested: %s, want %s", got, want)
+		}
+	}
+	check("3 2 1 0")
+	check("")
+	chain.mu.Lock()
+	chain.length = 6
+	chain.mu.Unlock()
+	check("5 4")
+	for n := 0; n < chain.length; n++ {
+		if !verified[chainRoot(n)] {
+			t.Errorf("block %d not verified", n)
+		}
+	}
+}
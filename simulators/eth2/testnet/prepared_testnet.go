@@ -33,9 +33,16 @@ type PreparedTestnet struct {
 
 	// a tranche is a group of validator keys to run on 1 node
 	keyTranches []hivesim.StartOption
+	// validator index range [start, end) of each key tranche
+	keyTrancheRanges [][2]common.ValidatorIndex
+
+	// connect validator clients to beacon nodes through beacon API proxies
+	beaconProxies bool
 }
 
-func prepareTestnet(t *hivesim.T, valCount uint64, keyTranches uint64) *PreparedTestnet {
+// prepareTestnet creates the configuration and genesis state of a testnet with valCount
+// validators, split into keyTranches groups. Genesis is at genesisDelay from now.
+func prepareTestnet(t *hivesim.T, valCount uint64, keyTranches uint64, genesisDelay time.Duration) *PreparedTestnet {
 
 	var depositAddress common.Eth1Address
 	depositAddress.UnmarshalText([]byte("0x4242424242424242424242424242424242424242"))
@@ -72,11 +79,13 @@ func prepareTestnet(t *hivesim.T, valCount uint64, keyTranches uint64) *Prepared
 		t.Fatal(err)
 	}
 	keyOpts := make([]hivesim.StartOption, 0, keyTranches)
+	keyRanges := make([][2]common.ValidatorIndex, 0, keyTranches)
 	for i := uint64(0); i < keyTranches; i++ {
 		// Give each validator client an equal subset of the genesis validator keys
 		startIndex := valCount * i / keyTranches
 		endIndex := valCount * (i + 1) / keyTranches
 		keyOpts = append(keyOpts, setup.KeysBundle(keys[startIndex:endIndex]))
+		keyRanges = append(keyRanges, [2]common.ValidatorIndex{common.ValidatorIndex(startIndex), common.ValidatorIndex(endIndex)})
 	}
 
 	t.Log("building beacon state...")
@@ -102,7 +111,7 @@ func prepareTestnet(t *hivesim.T, valCount uint64, keyTranches uint64) *Prepared
 	}
 
 	// we need a new genesis time, so we take the template state and prepare a tar with updated time
-	stateOpt, err := setup.StateBundle(state, time.Now().Add(2*time.Minute))
+	stateOpt, err := setup.StateBundle(state, time.Now().Add(genesisDelay))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -119,6 +128,7 @@ func prepareTestnet(t *hivesim.T, valCount uint64, keyTranches uint64) *Prepared
 		eth2ConfigOpt:         eth2Config,
 		beaconStateOpt:        stateOpt,
 		keyTranches:           keyOpts,
+		keyTrancheRanges:      keyRanges,
 	}
 }
 
@@ -158,13 +168,35 @@ func (p *PreparedTestnet) startEth1Node(testnet *Testnet, eth1Def *hivesim.Clien
 }
 
 func (p *PreparedTestnet) startBeaconNode(testnet *Testnet, beaconDef *hivesim.ClientDefinition, eth1Endpoints []int) {
+	bn, err := p.newBeaconNode(testnet, beaconDef, eth1Endpoints)
+	if err != nil {
+		testnet.t.Fatalf("%v", err)
+	}
+	testnet.beacons = append(testnet.beacons, bn)
+}
+
+// restartBeaconNode stops a beacon node and starts a node of the same type with the same
+// eth1 nodes in its place. Containers can't be restarted in place, so the new node starts
+// with an empty database and syncs from the testnet. It doesn't fail the test, so it can
+// be called from background checks.
+func (p *PreparedTestnet) restartBeaconNode(testnet *Testnet, bn *BeaconNode, beaconDef *hivesim.ClientDefinition, eth1Endpoints []int) (*BeaconNode, error) {
+	t := testnet.t
+	if err := t.Sim.StopClient(t.SuiteID, t.TestID, bn.Container); err != nil {
+		return nil, fmt.Errorf("failed to stop beacon node: %v", err)
+	}
+	return p.newBeaconNode(testnet, beaconDef, eth1Endpoints)
+}
+
+// newBeaconNode starts a beacon node connected to the given eth1 nodes. The node doesn't
+// get added to the testnet.
+func (p *PreparedTestnet) newBeaconNode(testnet *Testnet, beaconDef *hivesim.ClientDefinition, eth1Endpoints []int) (*BeaconNode, error) {
 	testnet.t.Logf("starting beacon node: %s (%s)", beaconDef.Name, beaconDef.Version)
 
 	opts := []hivesim.StartOption{p.eth2ConfigOpt, p.beaconStateOpt, p.commonBeaconParams}
 	// Hook up beacon node to (maybe multiple) eth1 nodes
 	for _, index := range eth1Endpoints {
 		if index < 0 || index >= len(testnet.eth1) {
-			testnet.t.Fatalf("only have %d eth1 nodes, cannot find index %d for BN", len(testnet.eth1), index)
+			return nil, fmt.Errorf("only have %d eth1 nodes, cannot find index %d for BN", len(testnet.eth1), index)
 		}
 	}
 
@@ -173,7 +205,7 @@ func (p *PreparedTestnet) startBeaconNode(testnet *Testnet, beaconDef *hivesim.C
 		eth1Node := testnet.eth1[index]
 		userRPC, err := eth1Node.UserRPCAddress()
 		if err != nil {
-			testnet.t.Fatalf("eth1 node used for beacon without available RPC: %v", err)
+			return nil, fmt.Errorf("eth1 node used for beacon without available RPC: %v", err)
 		}
 		addrs = append(addrs, userRPC)
 	}
@@ -182,7 +214,7 @@ func (p *PreparedTestnet) startBeaconNode(testnet *Testnet, beaconDef *hivesim.C
 	if len(testnet.beacons) > 0 {
 		bootnodeENR, err := testnet.beacons[0].ENR()
 		if err != nil {
-			testnet.t.Fatalf("failed to get ENR as bootnode for beacon node: %v", err)
+			return nil, fmt.Errorf("failed to get ENR as bootnode for beacon node: %v", err)
 		}
 		opts = append(opts, hivesim.Params{"HIVE_ETH2_BOOTNODE_ENRS": bootnodeENR})
 	}
@@ -191,32 +223,93 @@ func (p *PreparedTestnet) startBeaconNode(testnet *Testnet, beaconDef *hivesim.C
 	//if p.configName != "mainnet" && hasBuildTarget(beaconDef, p.configName) {
 	//	opts = append(opts, hivesim.WithBuildTarget(p.configName))
 	//}
-	bn := NewBeaconNode(testnet.t.StartClient(beaconDef.Name, opts...))
-	testnet.beacons = append(testnet.beacons, bn)
+	client, err := testnet.t.TryStartClient(beaconDef.Name, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("can't launch beacon node (type %s): %v", beaconDef.Name, err)
+	}
+	bn := NewBeaconNode(client)
+	for _, index := range eth1Endpoints {
+		bn.eth1 = append(bn.eth1, testnet.eth1[index])
+	}
+	return bn, nil
 }
 
 func (p *PreparedTestnet) startValidatorClient(testnet *Testnet, validatorDef *hivesim.ClientDefinition, bnIndex int, keyIndex int) {
+	vc, err := p.newValidatorClient(testnet, validatorDef, bnIndex, keyIndex)
+	if err != nil {
+		testnet.t.Fatalf("%v", err)
+	}
+	testnet.validatorsMu.Lock()
+	testnet.validators = append(testnet.validators, vc)
+	testnet.validatorsMu.Unlock()
+}
+
+// replaceValidatorClient stops a running validator client and starts a client of the given
+// type with the same validator keys and beacon node in its place. It doesn't fail the test,
+// so it can be called from background checks.
+func (p *PreparedTestnet) replaceValidatorClient(testnet *Testnet, vcIndex int, validatorDef *hivesim.ClientDefinition, extra ...hivesim.StartOption) (*ValidatorClient, error) {
+	old := testnet.validatorClient(vcIndex)
+	t := testnet.t
+	if err := t.Sim.StopClient(t.SuiteID, t.TestID, old.Container); err != nil {
+		return nil, fmt.Errorf("failed to stop validator client %d: %v", vcIndex, err)
+	}
+	closeProxies(old.proxies)
+	vc, err := p.newValidatorClient(testnet, validatorDef, old.beaconIndex, old.keyIndex, extra...)
+	if err != nil {
+		return nil, err
+	}
+	testnet.validatorsMu.Lock()
+	testnet.validators[vcIndex] = vc
+	testnet.validatorsMu.Unlock()
+	return vc, nil
+}
+
+func (p *PreparedTestnet) newValidatorClient(testnet *Testnet, validatorDef *hivesim.ClientDefinition, bnIndex int, keyIndex int, extra ...hivesim.StartOption) (*ValidatorClient, error) {
 	testnet.t.Logf("starting validator client: %s (%s)", validatorDef.Name, validatorDef.Version)
 
 	if bnIndex >= len(testnet.beacons) {
-		testnet.t.Fatalf("only have %d beacon nodes, cannot find index %d for VC", len(testnet.beacons), bnIndex)
+		return nil, fmt.Errorf("only have %d beacon nodes, cannot find index %d for VC", len(testnet.beacons), bnIndex)
+	}
+	if keyIndex >= len(p.keyTranches) {
+		return nil, fmt.Errorf("only have %d key tranches, cannot find index %d for VC", len(p.keyTranches), keyIndex)
 	}
 	bn := testnet.beacons[bnIndex]
 	// Hook up validator to beacon node
 	bnAPIOpt := hivesim.Params{
 		"HIVE_ETH2_BN_API_IP": bn.IP.String(),
 	}
-	if keyIndex >= len(p.keyTranches) {
-		testnet.t.Fatalf("only have %d key tranches, cannot find index %d for VC", len(p.keyTranches), keyIndex)
+	var proxies []*beaconProxy
+	if p.beaconProxies {
+		var err error
+		if proxies, err = testnet.startBeaconProxies(bnIndex); err != nil {
+			return nil, err
+		}
+		primary, fallback := proxies[0], proxies[1]
+		bnAPIOpt = hivesim.Params{
+			"HIVE_ETH2_BN_API_IP":    primary.IP,
+			"HIVE_ETH2_BN_API_PORT":  fmt.Sprintf("%d", primary.Port),
+			"HIVE_ETH2_BN_API_ADDRS": primary.URL + "," + fallback.URL,
+		}
 	}
 	keysOpt := p.keyTranches[keyIndex]
 	opts := []hivesim.StartOption{
 		p.eth2ConfigOpt, keysOpt, p.commonValidatorParams, bnAPIOpt,
 	}
+	opts = append(opts, extra...)
 	// TODO
 	//if p.configName != "mainnet" && hasBuildTarget(validatorDef, p.configName) {
 	//	opts = append(opts, hivesim.WithBuildTarget(p.configName))
 	//}
-	vc := &ValidatorClient{testnet.t.StartClient(validatorDef.Name, opts...)}
-	testnet.validators = append(testnet.validators, vc)
+	client, err := testnet.t.TryStartClient(validatorDef.Name, opts...)
+	if err != nil {
+		closeProxies(proxies)
+		return nil, fmt.Errorf("can't launch validator client (type %s): %v", validatorDef.Name, err)
+	}
+	return &ValidatorClient{
+		Client:      client,
+		Indices:     p.keyTrancheRanges[keyIndex],
+		beaconIndex: bnIndex,
+		keyIndex:    keyIndex,
+		proxies:     proxies,
+	}, nil
 }
@@ -1,6 +1,10 @@
 package main
 
-import "github.com/ethereum/hive/hivesim"
+import (
+	"strings"
+
+	"github.com/ethereum/hive/hivesim"
+)
 
 type ClientDefinitionsByRole struct {
 	Beacon    []*hivesim.ClientDefinition `json:"beacon"`
@@ -24,3 +28,42 @@ func ClientsByRole(available []*hivesim.ClientDefinition) *ClientDefinitionsByRo
 	}
 	return &out
 }
+
+// NodePairing is the combination of client types of a testnet node.
+type NodePairing struct {
+	Eth1      *hivesim.ClientDefinition
+	Beacon    *hivesim.ClientDefinition
+	Validator *hivesim.ClientDefinition
+}
+
+func (p NodePairing) String() string {
+	return p.Eth1.Name + "/" + p.Beacon.Name + "/" + p.Validator.Name
+}
+
+// Pairings returns the pairings of all eth1 client types with all beacon node types.
+// Each beacon node is matched with the validator client of the same client family,
+// or with the first validator client type if there is none.
+func (nc *ClientDefinitionsByRole) Pairings() []NodePairing {
+	if len(nc.Validator) == 0 {
+		return nil
+	}
+	var out []NodePairing
+	for _, eth1 := range nc.Eth1 {
+		for _, beacon := range nc.Beacon {
+			out = append(out, NodePairing{Eth1: eth1, Beacon: beacon, Validator: nc.preferredValidator(beacon)})
+		}
+	}
+	return out
+}
+
+// preferredValidator returns the validator client type matching a beacon node type.
+// Client names are of the form "<family>-bn" and "<family>-vc".
+func (nc *ClientDefinitionsByRole) preferredValidator(beacon *hivesim.ClientDefinition) *hivesim.ClientDefinition {
+	family := strings.SplitN(beacon.Name, "-", 2)[0]
+	for _, vc := range nc.Validator {
+		if strings.SplitN(vc.Name, "-", 2)[0] == family {
+			return vc
+		}
+	}
+	return nc.Validator[0]
+}
@@ -22,15 +22,46 @@ type Testnet struct {
 	// Execution chain configuration and genesis info
 	eth1Genesis *setup.Eth1Genesis
 
-	beacons    []*BeaconNode
-	validators []*ValidatorClient
-	eth1       []*Eth1Node
+	beacons []*BeaconNode
+	eth1    []*Eth1Node
+
+	// Validator clients can be replaced while background checks read them.
+	validatorsMu sync.RWMutex
+	validators   []*ValidatorClient
+
+	// IP address of the simulator container, for beacon API proxies
+	simIP string
 }
 
 func (t *Testnet) GenesisTime() time.Time {
 	return time.Unix(int64(t.genesisTime), 0)
 }
 
+// validatorClient returns the validator client at the given index.
+func (t *Testnet) validatorClient(index int) *ValidatorClient {
+	t.validatorsMu.RLock()
+	defer t.validatorsMu.RUnlock()
+	return t.validators[index]
+}
+
+// validatorClients returns a copy of the validator client list.
+func (t *Testnet) validatorClients() []*ValidatorClient {
+	t.validatorsMu.RLock()
+	defer t.validatorsMu.RUnlock()
+	return append([]*ValidatorClient(nil), t.validators...)
+}
+
+// validatorClientOf returns the index of the validator client running the given
+// validator, or -1 if no client runs it.
+func (t *Testnet) validatorClientOf(index common.ValidatorIndex) int {
+	for i, vc := range t.validatorClients() {
+		if vc.HasValidator(index) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (t *Testnet) TrackFinality(ctx context.Context) {
 
 	genesis := t.GenesisTime()
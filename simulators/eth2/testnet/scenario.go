@@ -0,0 +1,1215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/ethereum/hive/hivesim"
+	"github.com/protolambda/eth2api"
+	"github.com/protolambda/eth2api/client/beaconapi"
+	"github.com/protolambda/eth2api/client/validatorapi"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scenario files describe events which are injected into a running testnet, and the
+// outcome expected from them. Every line of a scenario file holds one step:
+//
+//	# comment
+//	at slot 12: delay proposer by 3s
+//	at slot 20: withhold block for 2 slots
+//	at slot 24: expect slot 20 orphaned
+//	at epoch 2: partition nodes 0-1 for 2 epochs
+//	at epoch 4: netem node 3 latency 300ms jitter 50ms loss 5 rate 10mbit for 1 epoch
+//	at epoch 8: expect finalized epoch >= 5
+//	at epoch 2: fault beacon api of node 0 with error for 1 epoch
+//	at epoch 3: fault beacon api of node 0 with truncate on ^/eth/v1/validator/ for 4 slots
+//	at slot 70: expect node 0 uses fallback beacon api
+//	at epoch 4: expect no missed proposals by node 0 for 2 epochs
+//	at epoch 9: end
+//	expect reorg depth <= 2
+//
+// Timed steps start at the beginning of the given slot or epoch. Nodes are numbered
+// from zero, a node being an eth1 node, beacon node and validator client triple.
+//
+// Partitioned nodes lose all packets they send, which cuts them off from the rest of
+// the testnet and from the simulator. Since this also applies to the traffic between
+// the clients of a node, their validators can't perform their duties. The nodes fall
+// behind the rest of the testnet and must catch up once the partition ends. A delayed
+// proposer is paused from the start of its slot, which makes its block late.
+//
+// The netem step degrades the network of nodes instead of cutting them off. The packets
+// sent by their clients get the given latency and jitter, a percentage of them is lost,
+// and the bandwidth is capped to the given rate in bits per second (suffixes kbit, mbit).
+// Degraded nodes keep participating, so expectations also check them. A node can only
+// have one set of network conditions: while it is partitioned, the partition applies, and
+// when netem steps overlap, the latest one applies until it ends.
+//
+// A withheld block is built and signed at the start of its slot, but published later.
+// The beacon API proxies of the proposer's validator client acknowledge the block, and
+// forward it to the beacon nodes when the withholding ends. The 'expect slot N
+// canonical|orphaned' step checks whether the withheld block of a slot ended up in the
+// chain of all running beacon nodes. It fails if no block was withheld at the slot.
+//
+// The reorg depth is the number of blocks removed from the canonical chain of a beacon
+// node when it switches to another head. It is checked for the whole scenario, and the
+// deepest reorg of every beacon node is logged when the scenario ends.
+//
+// Scenarios with beacon API or withholding steps connect the validator clients to the
+// beacon nodes through proxies, see beacon_proxy.go. A fault step makes the proxy to the beacon node
+// of a node respond with errors, truncated bodies or stale responses, either for all
+// endpoints or those matching a regular expression. The 'expect node N uses primary|fallback
+// beacon api' step checks which beacon node served the validator duty requests of the
+// node's validator client during the previous slot. The 'expect no missed proposals' step
+// checks that the validators of a node propose all their blocks during the given time.
+const scenarioExt = ".scenario"
+
+// scenarioDir is the directory containing the scenario files.
+const scenarioDir = "scenarios"
+
+// stepLead is the time before the start of a slot at which its steps are executed.
+const stepLead = 500 * time.Millisecond
+
+type scenario struct {
+	name  string
+	steps []*scenarioStep
+
+	end           *scenarioTime
+	maxReorgDepth int // -1 if not checked
+}
+
+type scenarioStep struct {
+	line   int
+	at     scenarioTime
+	action stepAction
+}
+
+type stepAction interface {
+	run(ctx context.Context, r *scenarioRun, slot common.Slot) error
+	String() string
+}
+
+// scenarioTime is a slot or epoch number.
+type scenarioTime struct {
+	n     uint64
+	epoch bool
+}
+
+func (st scenarioTime) slot(spec *common.Spec) common.Slot {
+	if st.epoch {
+		return common.Slot(st.n) * spec.SLOTS_PER_EPOCH
+	}
+	return common.Slot(st.n)
+}
+
+func (st scenarioTime) String() string {
+	if st.epoch {
+		return fmt.Sprintf("epoch %d", st.n)
+	}
+	return fmt.Sprintf("slot %d", st.n)
+}
+
+// span is a length of time, either in slots or epochs or as a plain duration.
+type span struct {
+	n        uint64
+	unit     string // "slot" or "epoch", empty for plain durations
+	duration time.Duration
+}
+
+func (s span) get(spec *common.Spec) time.Duration {
+	slot := time.Duration(spec.SECONDS_PER_SLOT) * time.Second
+	switch s.unit {
+	case "slot":
+		return time.Duration(s.n) * slot
+	case "epoch":
+		return time.Duration(s.n) * time.Duration(spec.SLOTS_PER_EPOCH) * slot
+	default:
+		return s.duration
+	}
+}
+
+func (s span) String() string {
+	switch {
+	case s.unit == "":
+		return s.duration.String()
+	case s.n == 1:
+		return "1 " + s.unit
+	default:
+		return fmt.Sprintf("%d %ss", s.n, s.unit)
+	}
+}
+
+// usesBeaconProxies reports whether the scenario has beacon API steps, which need the
+// validator clients to be connected through beacon API proxies.
+func (sc *scenario) usesBeaconProxies() bool {
+	for _, step := range sc.steps {
+		switch step.action.(type) {
+		case *faultBeaconAPI, *expectBeaconAPI, *withholdBlock:
+			return true
+		}
+	}
+	return false
+}
+
+// loadScenarios reads all scenario files in the given directory.
+func loadScenarios(dir string) ([]*scenario, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+scenarioExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var list []*scenario
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(file), scenarioExt)
+		sc, err := parseScenario(name, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		list = append(list, sc)
+	}
+	return list, nil
+}
+
+// parseScenario parses a scenario file.
+func parseScenario(name string, r io.Reader) (*scenario, error) {
+	sc := &scenario{name: name, maxReorgDepth: -1}
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := s.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if err := sc.parseLine(line, text); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(sc.steps) == 0 {
+		return nil, fmt.Errorf("scenario has no timed steps")
+	}
+	return sc, nil
+}
+
+func (sc *scenario) parseLine(line int, text string) error {
+	var at *scenarioTime
+	if i := strings.IndexByte(text, ':'); i >= 0 {
+		t, err := parseTime(strings.Fields(text[:i]))
+		if err != nil {
+			return err
+		}
+		at, text = &t, text[i+1:]
+	}
+	words := strings.Fields(text)
+
+	switch {
+	case match(words, "end"):
+		if at == nil {
+			return fmt.Errorf("end needs a time")
+		}
+		if sc.end != nil {
+			return fmt.Errorf("duplicate end")
+		}
+		sc.end = at
+		return nil
+	case match(words, "expect", "reorg", "depth", "<="):
+		if at != nil {
+			return fmt.Errorf("reorg depth is checked for the whole scenario and can't have a time")
+		}
+		if len(words) != 5 {
+			return fmt.Errorf("want \"expect reorg depth <= N\"")
+		}
+		n, err := strconv.Atoi(words[4])
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid reorg depth %q", words[4])
+		}
+		sc.maxReorgDepth = n
+		return nil
+	}
+
+	if at == nil {
+		return fmt.Errorf("step %q needs a time", text)
+	}
+	action, err := parseAction(words)
+	if err != nil {
+		return err
+	}
+	sc.steps = append(sc.steps, &scenarioStep{line: line, at: *at, action: action})
+	return nil
+}
+
+// parseTime parses "slot N" or "epoch N", preceded by "at".
+func parseTime(words []string) (scenarioTime, error) {
+	if len(words) != 3 || words[0] != "at" || (words[1] != "slot" && words[1] != "epoch") {
+		return scenarioTime{}, fmt.Errorf("invalid time %q, want \"at slot N\" or \"at epoch N\"", strings.Join(words, " "))
+	}
+	n, err := strconv.ParseUint(words[2], 10, 64)
+	if err != nil {
+		return scenarioTime{}, fmt.Errorf("invalid %s number %q", words[1], words[2])
+	}
+	return scenarioTime{n: n, epoch: words[1] == "epoch"}, nil
+}
+
+// parseSpan parses "N slots", "N epochs" or a duration like "3s".
+func parseSpan(words []string) (span, error) {
+	switch len(words) {
+	case 1:
+		d, err := time.ParseDuration(words[0])
+		if err != nil || d <= 0 {
+			return span{}, fmt.Errorf("invalid duration %q", words[0])
+		}
+		return span{duration: d}, nil
+	case 2:
+		n, err := strconv.ParseUint(words[0], 10, 64)
+		if err != nil || n == 0 {
+			return span{}, fmt.Errorf("invalid count %q", words[0])
+		}
+		unit := strings.TrimSuffix(words[1], "s")
+		if unit != "slot" && unit != "epoch" {
+			return span{}, fmt.Errorf("invalid unit %q, want slots or epochs", words[1])
+		}
+		return span{n: n, unit: unit}, nil
+	default:
+		return span{}, fmt.Errorf("invalid time span %q", strings.Join(words, " "))
+	}
+}
+
+// parseNodes parses "N" or "N-M".
+func parseNodes(s string) ([2]int, error) {
+	var r [2]int
+	first, last := s, s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		first, last = s[:i], s[i+1:]
+	}
+	var err1, err2 error
+	r[0], err1 = strconv.Atoi(first)
+	r[1], err2 = strconv.Atoi(last)
+	if err1 != nil || err2 != nil || r[0] < 0 || r[1] < r[0] {
+		return r, fmt.Errorf("invalid node range %q", s)
+	}
+	return r, nil
+}
+
+func parseAction(words []string) (stepAction, error) {
+	switch {
+	case match(words, "delay", "proposer", "by"):
+		d, err := parseSpan(words[3:])
+		if err != nil {
+			return nil, err
+		}
+		return &delayProposer{delay: d}, nil
+
+	case match(words, "withhold", "block", "for"):
+		d, err := parseSpan(words[3:])
+		if err != nil {
+			return nil, err
+		}
+		return &withholdBlock{length: d}, nil
+
+	case match(words, "expect", "slot") && len(words) == 4 && (words[3] == "canonical" || words[3] == "orphaned"):
+		n, err := strconv.ParseUint(words[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slot %q", words[2])
+		}
+		return &expectBlock{slot: common.Slot(n), canonical: words[3] == "canonical"}, nil
+
+	case match(words, "partition") && len(words) >= 5 && (words[1] == "node" || words[1] == "nodes") && words[3] == "for":
+		nodes, err := parseNodes(words[2])
+		if err != nil {
+			return nil, err
+		}
+		d, err := parseSpan(words[4:])
+		if err != nil {
+			return nil, err
+		}
+		return &partition{nodes: nodes, length: d}, nil
+
+	case match(words, "netem") && len(words) >= 3 && (words[1] == "node" || words[1] == "nodes"):
+		nodes, err := parseNodes(words[2])
+		if err != nil {
+			return nil, err
+		}
+		a := &netem{nodes: nodes}
+		rest := words[3:]
+		for len(rest) >= 2 && rest[0] != "for" {
+			if err := a.parseCondition(rest[0], rest[1]); err != nil {
+				return nil, err
+			}
+			rest = rest[2:]
+		}
+		if len(rest) < 2 || rest[0] != "for" || a.cond == (hivesim.NetworkConditions{}) {
+			return nil, fmt.Errorf("want \"netem nodes N-M [latency D] [jitter D] [loss P] [rate R] for TIME\"")
+		}
+		if a.cond.Jitter > 0 && a.cond.Latency == 0 {
+			return nil, fmt.Errorf("netem jitter needs latency")
+		}
+		if a.length, err = parseSpan(rest[1:]); err != nil {
+			return nil, err
+		}
+		return a, nil
+
+	case match(words, "fault", "beacon", "api", "of", "node") && len(words) >= 9 && words[6] == "with":
+		node, err := strconv.Atoi(words[5])
+		if err != nil || node < 0 {
+			return nil, fmt.Errorf("invalid node %q", words[5])
+		}
+		kind, ok := faultKinds[words[7]]
+		if !ok {
+			return nil, fmt.Errorf("unknown fault %q, want error, truncate or stale", words[7])
+		}
+		fault := &apiFault{kind: kind}
+		rest := words[8:]
+		if rest[0] == "on" && len(rest) >= 4 {
+			if fault.paths, err = regexp.Compile(rest[1]); err != nil {
+				return nil, fmt.Errorf("invalid endpoint pattern %q: %v", rest[1], err)
+			}
+			rest = rest[2:]
+		}
+		if rest[0] != "for" {
+			return nil, fmt.Errorf("want \"fault beacon api of node N with FAULT [on PATTERN] for TIME\"")
+		}
+		d, err := parseSpan(rest[1:])
+		if err != nil {
+			return nil, err
+		}
+		return &faultBeaconAPI{node: node, fault: fault, length: d}, nil
+
+	case match(words, "expect", "node") && len(words) == 7 && words[3] == "uses" && words[5] == "beacon" && words[6] == "api":
+		node, err := strconv.Atoi(words[2])
+		if err != nil || node < 0 {
+			return nil, fmt.Errorf("invalid node %q", words[2])
+		}
+		if words[4] != "primary" && words[4] != "fallback" {
+			return nil, fmt.Errorf("want \"expect node N uses primary|fallback beacon api\"")
+		}
+		return &expectBeaconAPI{node: node, fallback: words[4] == "fallback"}, nil
+
+	case match(words, "expect", "no", "missed", "proposals", "by", "node") && len(words) >= 9 && words[7] == "for":
+		node, err := strconv.Atoi(words[6])
+		if err != nil || node < 0 {
+			return nil, fmt.Errorf("invalid node %q", words[6])
+		}
+		d, err := parseSpan(words[8:])
+		if err != nil {
+			return nil, err
+		}
+		return &expectProposals{node: node, length: d}, nil
+
+	case match(words, "expect", "finalized", "epoch", ">="):
+		if len(words) != 5 {
+			return nil, fmt.Errorf("want \"expect finalized epoch >= N\"")
+		}
+		n, err := strconv.ParseUint(words[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid epoch %q", words[4])
+		}
+		return &expectFinalized{epoch: common.Epoch(n)}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown step %q", strings.Join(words, " "))
+	}
+}
+
+// match reports whether words starts with the given prefix.
+func match(words []string, prefix ...string) bool {
+	if len(words) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if words[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scenarioRun is a scenario executing against a testnet.
+type scenarioRun struct {
+	sc *scenario
+	t  *Testnet
+
+	mu          sync.Mutex
+	paused      map[string]int                        // pause count by container ID
+	partitioned map[string]int                        // partition count by container ID
+	netem       map[string][]*netem                   // active netem steps by container ID, latest last
+	withheld    map[common.Slot]common.ValidatorIndex // proposers of released withheld blocks
+	reorgDepth  int                                   // deepest reorg seen
+	reorgNode   int                                   // beacon node of the deepest reorg
+	nodeDepths  []int                                 // deepest reorg by beacon node
+}
+
+// RunScenario executes the steps of a scenario and checks its expectations.
+func (t *Testnet) RunScenario(ctx context.Context, sc *scenario) {
+	defer t.closeBeaconProxies()
+
+	steps := make([]*scenarioStep, len(sc.steps))
+	copy(steps, sc.steps)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].at.slot(t.spec) < steps[j].at.slot(t.spec)
+	})
+	end := steps[len(steps)-1].at.slot(t.spec) + t.spec.SLOTS_PER_EPOCH
+	if sc.end != nil {
+		end = sc.end.slot(t.spec)
+	}
+	if last := steps[len(steps)-1]; end < last.at.slot(t.spec) {
+		t.t.Fatalf("scenario %s: ends at %v, before step of line %d", sc.name, sc.end, last.line)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r := &scenarioRun{
+		sc:          sc,
+		t:           t,
+		paused:      make(map[string]int),
+		partitioned: make(map[string]int),
+		netem:       make(map[string][]*netem),
+		withheld:    make(map[common.Slot]common.ValidatorIndex),
+		nodeDepths:  make([]int, len(t.beacons)),
+	}
+	var wg sync.WaitGroup
+	if sc.maxReorgDepth >= 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.trackReorgs(ctx)
+		}()
+	}
+
+	for _, step := range steps {
+		slot := step.at.slot(t.spec)
+		if !waitUntil(ctx, t.slotTime(slot).Add(-stepLead)) {
+			break
+		}
+		t.t.Logf("scenario %s: %v: %v", sc.name, step.at, step.action)
+		wg.Add(1)
+		go func(step *scenarioStep) {
+			defer wg.Done()
+			if err := step.action.run(ctx, r, slot); err != nil {
+				t.t.Errorf("scenario %s: line %d (%v): %v", sc.name, step.line, step.action, err)
+			}
+		}(step)
+	}
+	waitUntil(ctx, t.slotTime(end))
+	cancel()
+	wg.Wait()
+
+	if sc.maxReorgDepth >= 0 {
+		t.t.Logf("scenario %s: deepest reorg by beacon node: %v", sc.name, r.nodeDepths)
+		if r.reorgDepth > sc.maxReorgDepth {
+			t.t.Errorf("scenario %s: beacon %d reorged %d blocks, expected depth <= %d", sc.name, r.reorgNode, r.reorgDepth, sc.maxReorgDepth)
+		} else {
+			t.t.Logf("scenario %s: deepest reorg removed %d blocks", sc.name, r.reorgDepth)
+		}
+	}
+}
+
+// slotTime returns the start time of a slot.
+func (t *Testnet) slotTime(slot common.Slot) time.Time {
+	return t.GenesisTime().Add(time.Duration(slot) * time.Duration(t.spec.SECONDS_PER_SLOT) * time.Second)
+}
+
+// nodeClients returns the containers of a node.
+func (t *Testnet) nodeClients(node int) []*hivesim.Client {
+	bn := t.beacons[node]
+	var clients []*hivesim.Client
+	for _, en := range bn.eth1 {
+		clients = append(clients, en.Client)
+	}
+	clients = append(clients, bn.Client)
+	for _, vc := range t.validatorClients() {
+		if vc.beaconIndex == node {
+			clients = append(clients, vc.Client)
+		}
+	}
+	return clients
+}
+
+// pause pauses the given containers until the given time. When steps overlap,
+// containers are resumed after the last step using them has ended.
+func (r *scenarioRun) pause(ctx context.Context, clients []*hivesim.Client, until time.Time) error {
+	var paused []*hivesim.Client
+	defer func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, c := range paused {
+			if r.paused[c.Container]--; r.paused[c.Container] > 0 {
+				continue
+			}
+			delete(r.paused, c.Container)
+			if err := c.Unpause(); err != nil {
+				r.t.t.Errorf("scenario %s: can't unpause %s: %v", r.sc.name, c.Type, err)
+			}
+		}
+	}()
+
+	r.mu.Lock()
+	for _, c := range clients {
+		if r.paused[c.Container] == 0 {
+			if err := c.Pause(); err != nil {
+				r.mu.Unlock()
+				return fmt.Errorf("can't pause %s: %v", c.Type, err)
+			}
+		}
+		r.paused[c.Container]++
+		paused = append(paused, c)
+	}
+	r.mu.Unlock()
+
+	waitUntil(ctx, until)
+	return nil
+}
+
+// cutOff makes the given containers drop all packets they send until the given time.
+// When steps overlap, the network is restored after the last step using it has ended.
+func (r *scenarioRun) cutOff(ctx context.Context, clients []*hivesim.Client, until time.Time) error {
+	var cut []*hivesim.Client
+	defer func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, c := range cut {
+			if r.partitioned[c.Container]--; r.partitioned[c.Container] > 0 {
+				continue
+			}
+			delete(r.partitioned, c.Container)
+			if err := r.restoreNetwork(c); err != nil {
+				r.t.t.Errorf("scenario %s: can't restore network of %s: %v", r.sc.name, c.Type, err)
+			}
+		}
+	}()
+
+	r.mu.Lock()
+	for _, c := range clients {
+		if r.partitioned[c.Container] == 0 {
+			if err := c.SetNetworkConditions(hivesim.NetworkConditions{Loss: 100}); err != nil {
+				r.mu.Unlock()
+				return fmt.Errorf("can't cut off %s: %v", c.Type, err)
+			}
+		}
+		r.partitioned[c.Container]++
+		cut = append(cut, c)
+	}
+	r.mu.Unlock()
+
+	waitUntil(ctx, until)
+	return nil
+}
+
+// degrade applies the network conditions of a netem step to the given containers until
+// the given time. Partitions take precedence over netem steps, and of overlapping netem
+// steps, the latest one applies.
+func (r *scenarioRun) degrade(ctx context.Context, a *netem, clients []*hivesim.Client, until time.Time) error {
+	var degraded []*hivesim.Client
+	defer func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, c := range degraded {
+			steps := r.netem[c.Container]
+			for i := range steps {
+				if steps[i] == a {
+					steps = append(steps[:i], steps[i+1:]...)
+					break
+				}
+			}
+			if len(steps) == 0 {
+				delete(r.netem, c.Container)
+			} else {
+				r.netem[c.Container] = steps
+			}
+			if r.partitioned[c.Container] > 0 {
+				continue
+			}
+			if err := r.restoreNetwork(c); err != nil {
+				r.t.t.Errorf("scenario %s: can't restore network of %s: %v", r.sc.name, c.Type, err)
+			}
+		}
+	}()
+
+	r.mu.Lock()
+	for _, c := range clients {
+		if r.partitioned[c.Container] == 0 {
+			if err := c.SetNetworkConditions(a.cond); err != nil {
+				r.mu.Unlock()
+				return fmt.Errorf("can't degrade network of %s: %v", c.Type, err)
+			}
+		}
+		r.netem[c.Container] = append(r.netem[c.Container], a)
+		degraded = append(degraded, c)
+	}
+	r.mu.Unlock()
+
+	waitUntil(ctx, until)
+	return nil
+}
+
+// restoreNetwork sets the network conditions of a container which isn't partitioned:
+// those of the latest active netem step, or none. It must be called with r.mu held.
+func (r *scenarioRun) restoreNetwork(c *hivesim.Client) error {
+	if steps := r.netem[c.Container]; len(steps) > 0 {
+		return c.SetNetworkConditions(steps[len(steps)-1].cond)
+	}
+	return c.ClearNetworkConditions()
+}
+
+// activeBeacons returns the indexes of beacon nodes which are neither paused nor
+// partitioned.
+func (r *scenarioRun) activeBeacons() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var active []int
+	for i, bn := range r.t.beacons {
+		if r.paused[bn.Container] == 0 && r.partitioned[bn.Container] == 0 {
+			active = append(active, i)
+		}
+	}
+	return active
+}
+
+// delayProposer pauses the validator client of the proposer of a slot.
+type delayProposer struct {
+	delay span
+}
+
+func (a *delayProposer) String() string {
+	return "delay proposer by " + a.delay.String()
+}
+
+func (a *delayProposer) run(ctx context.Context, r *scenarioRun, slot common.Slot) error {
+	t := r.t
+	proposer, err := r.proposer(ctx, slot)
+	if err != nil {
+		return err
+	}
+	vc := t.validatorClientOf(proposer)
+	if vc < 0 {
+		return fmt.Errorf("no validator client runs proposer %d", proposer)
+	}
+	t.t.Logf("scenario %s: delaying validator client %d, proposer %d of slot %d", r.sc.name, vc, proposer, slot)
+	return r.pause(ctx, []*hivesim.Client{t.validatorClient(vc).Client}, t.slotTime(slot).Add(a.delay.get(t.spec)))
+}
+
+// proposer returns the index of the validator proposing at the given slot.
+func (r *scenarioRun) proposer(ctx context.Context, slot common.Slot) (common.ValidatorIndex, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var lastErr error
+	for _, i := range r.activeBeacons() {
+		var duties eth2api.DependentProposerDuty
+		if syncing, err := validatorapi.ProposerDuties(ctx, r.t.beacons[i].API, r.t.spec.SlotToEpoch(slot), &duties); err != nil {
+			lastErr = fmt.Errorf("beacon %d: can't get proposer duties: %v", i, err)
+			continue
+		} else if syncing {
+			lastErr = fmt.Errorf("beacon %d is syncing", i)
+			continue
+		}
+		for _, duty := range duties.Data {
+			if duty.Slot == slot {
+				return duty.ValidatorIndex, nil
+			}
+		}
+		return 0, fmt.Errorf("no proposer duty for slot %d", slot)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("all beacon nodes are paused or partitioned")
+	}
+	return 0, lastErr
+}
+
+// withholdBlock makes the beacon API proxies of the proposer's validator client hold
+// back the block of a slot.
+type withholdBlock struct {
+	length span
+}
+
+func (a *withholdBlock) String() string {
+	return "withhold block for " + a.length.String()
+}
+
+func (a *withholdBlock) run(ctx context.Context, r *scenarioRun, slot common.Slot) error {
+	t := r.t
+	proposer, err := r.proposer(ctx, slot)
+	if err != nil {
+		return err
+	}
+	index := t.validatorClientOf(proposer)
+	if index < 0 {
+		return fmt.Errorf("no validator client runs proposer %d", proposer)
+	}
+	vc := t.validatorClient(index)
+	if len(vc.proxies) == 0 {
+		return fmt.Errorf("validator client %d has no beacon API proxy", index)
+	}
+	hold := &blockHold{slot: slot, until: t.slotTime(slot).Add(a.length.get(t.spec))}
+	for _, proxy := range vc.proxies {
+		proxy.addHold(hold)
+		defer proxy.removeHold(hold)
+	}
+	t.t.Logf("scenario %s: withholding block of slot %d, proposer %d on validator client %d", r.sc.name, slot, proposer, index)
+	if !waitUntil(ctx, hold.until) {
+		return nil
+	}
+
+	for _, proxy := range vc.proxies {
+		proxy.removeHold(hold)
+	}
+	held, err := hold.heldBlocks()
+	switch {
+	case held == 0:
+		return fmt.Errorf("validator client %d did not publish a block for slot %d", index, slot)
+	case err != nil:
+		return fmt.Errorf("can't release withheld block of slot %d: %v", slot, err)
+	}
+	t.t.Logf("scenario %s: released withheld block of slot %d", r.sc.name, slot)
+	r.mu.Lock()
+	r.withheld[slot] = proposer
+	r.mu.Unlock()
+	return nil
+}
+
+// expectBlock checks whether the withheld block of a slot is in the chain of all
+// running beacon nodes.
+type expectBlock struct {
+	slot      common.Slot
+	canonical bool
+}
+
+func (a *expectBlock) String() string {
+	if a.canonical {
+		return fmt.Sprintf("expect slot %d canonical", a.slot)
+	}
+	return fmt.Sprintf("expect slot %d orphaned", a.slot)
+}
+
+func (a *expectBlock) run(ctx context.Context, r *scenarioRun, slot common.Slot) error {
+	t := r.t
+	if a.slot >= slot {
+		return fmt.Errorf("slot %d can only be checked after it has passed", a.slot)
+	}
+	if !waitUntil(ctx, t.slotTime(slot)) {
+		return nil
+	}
+	r.mu.Lock()
+	proposer, isWithheld := r.withheld[a.slot]
+	r.mu.Unlock()
+	if !isWithheld {
+		return fmt.Errorf("no withheld block of slot %d was released", a.slot)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	for _, i := range r.activeBeacons() {
+		var header eth2api.BeaconBlockHeaderAndInfo
+		exists, err := beaconapi.BlockHeader(ctx, t.beacons[i].API, eth2api.BlockIdSlot(a.slot), &header)
+		if err != nil && exists {
+			return fmt.Errorf("beacon %d: can't get block of slot %d: %v", i, a.slot, err)
+		}
+		// Only the proposer of the slot can sign its block, and its block was
+		// withheld, so a block at the slot is the withheld one.
+		canonical := exists && header.Header.Message.ProposerIndex == proposer
+		switch {
+		case canonical && !a.canonical:
+			return fmt.Errorf("beacon %d: block %s of slot %d is canonical, want orphaned", i, header.Root, a.slot)
+		case !canonical && a.canonical:
+			return fmt.Errorf("beacon %d: block of slot %d is orphaned, want canonical", i, a.slot)
+		}
+	}
+	return nil
+}
+
+// partition cuts a range of nodes off the network.
+type partition struct {
+	nodes  [2]int
+	length span
+}
+
+func (a *partition) String() string {
+	return fmt.Sprintf("partition nodes %d-%d for %v", a.nodes[0], a.nodes[1], a.length)
+}
+
+func (a *partition) run(ctx context.Context, r *scenarioRun, slot common.Slot) error {
+	t := r.t
+	if a.nodes[1] >= len(t.beacons) {
+		return fmt.Errorf("testnet has only %d nodes", len(t.beacons))
+	}
+	var clients []*hivesim.Client
+	for i := a.nodes[0]; i <= a.nodes[1]; i++ {
+		clients = append(clients, t.nodeClients(i)...)
+	}
+	return r.cutOff(ctx, clients, t.slotTime(slot).Add(a.length.get(t.spec)))
+}
+
+// netem degrades the network of nodes.
+type netem struct {
+	nodes  [2]int
+	cond   hivesim.NetworkConditions
+	length span
+}
+
+// parseCondition parses a network condition of the netem step.
+func (a *netem) parseCondition(key, value string) error {
+	switch key {
+	case "latency", "jitter":
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s %q", key, value)
+		}
+		if key == "latency" {
+			a.cond.Latency = d
+		} else {
+			a.cond.Jitter = d
+		}
+	case "loss":
+		loss, err := strconv.ParseFloat(value, 64)
+		if err != nil || loss <= 0 || loss > 100 {
+			return fmt.Errorf("invalid loss %q, want percentage", value)
+		}
+		a.cond.Loss = loss
+	case "rate":
+		multiplier := uint64(1)
+		for _, unit := range []struct {
+			suffix string
+			mult   uint64
+		}{{"kbit", 1000}, {"mbit", 1000 * 1000}} {
+			if strings.HasSuffix(value, unit.suffix) {
+				value, multiplier = strings.TrimSuffix(value, unit.suffix), unit.mult
+				break
+			}
+		}
+		rate, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || rate == 0 {
+			return fmt.Errorf("invalid rate %q", value)
+		}
+		a.cond.Rate = rate * multiplier
+	default:
+		return fmt.Errorf("unknown netem condition %q, want latency, jitter, loss or rate", key)
+	}
+	return nil
+}
+
+func (a *netem) String() string {
+	s := fmt.Sprintf("netem nodes %d-%d", a.nodes[0], a.nodes[1])
+	if a.cond.Latency > 0 {
+		s += " latency " + a.cond.Latency.String()
+	}
+	if a.cond.Jitter > 0 {
+		s += " jitter " + a.cond.Jitter.String()
+	}
+	if a.cond.Loss > 0 {
+		s += " loss " + strconv.FormatFloat(a.cond.Loss, 'f', -1, 64)
+	}
+	switch rate := a.cond.Rate; {
+	case rate == 0:
+	case rate%(1000*1000) == 0:
+		s += fmt.Sprintf(" rate %dmbit", rate/(1000*1000))
+	case rate%1000 == 0:
+		s += fmt.Sprintf(" rate %dkbit", rate/1000)
+	default:
+		s += fmt.Sprintf(" rate %d", rate)
+	}
+	return s + " for " + a.length.String()
+}
+
+func (a *netem) run(ctx context.Context, r *scenarioRun, slot common.Slot) error {
+	t := r.t
+	if a.nodes[1] >= len(t.beacons) {
+		return fmt.Errorf("testnet has only %d nodes", len(t.beacons))
+	}
+	var clients []*hivesim.Client
+	for i := a.nodes[0]; i <= a.nodes[1]; i++ {
+		clients = append(clients, t.nodeClients(i)...)
+	}
+	return r.degrade(ctx, a, clients, t.slotTime(slot).Add(a.length.get(t.spec)))
+}
+
+// expectFinalized checks the finalized checkpoint of all running beacon nodes.
+type expectFinalized struct {
+	epoch common.Epoch
+}
+
+func (a *expectFinalized) String() string {
+	return fmt.Sprintf("expect finalized epoch >= %d", a.epoch)
+}
+
+func (a *expectFinalized) run(ctx context.Context, r *scenarioRun, slot common.Slot) error {
+	t := r.t
+	if !waitUntil(ctx, t.slotTime(slot)) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	for _, i := range r.activeBeacons() {
+		var out eth2api.FinalityCheckpoints
+		if exists, err := beaconapi.FinalityCheckpoints(ctx, t.beacons[i].API, eth2api.StateHead, &out); err != nil {
+			return fmt.Errorf("beacon %d: can't get finality checkpoints: %v", i, err)
+		} else if !exists {
+			return fmt.Errorf("beacon %d: no head state", i)
+		}
+		if out.Finalized.Epoch < a.epoch {
+			return fmt.Errorf("beacon %d: finalized epoch %d, want >= %d", i, out.Finalized.Epoch, a.epoch)
+		}
+	}
+	return nil
+}
+
+// nodeValidators returns the validator clients using the beacon node of a node.
+func (r *scenarioRun) nodeValidators(node int) ([]*ValidatorClient, error) {
+	if node >= len(r.t.beacons) {
+		return nil, fmt.Errorf("testnet has only %d nodes", len(r.t.beacons))
+	}
+	var vcs []*ValidatorClient
+	for _, vc := range r.t.validatorClients() {
+		if vc.beaconIndex != node {
+			continue
+		}
+		if len(vc.proxies) == 0 {
+			return nil, fmt.Errorf("validator client of node %d has no beacon API proxy", node)
+		}
+		vcs = append(vcs, vc)
+	}
+	return vcs, nil
+}
+
+// faultBeaconAPI injects a fault into the beacon API responses to the validator
+// clients of a node.
+type faultBeaconAPI struct {
+	node   int
+	fault  *apiFault
+	length span
+}
+
+func (a *faultBeaconAPI) String() string {
+	return fmt.Sprintf("fault beacon api of node %d with %v for %v", a.node, a.fault, a.length)
+}
+
+func (a *faultBeaconAPI) run(ctx context.Context, r *scenarioRun, slot common.Slot) error {
+	vcs, err := r.nodeValidators(a.node)
+	if err != nil {
+		return err
+	}
+	for _, vc := range vcs {
+		vc.proxies[0].addFault(a.fault)
+		defer vc.proxies[0].removeFault(a.fault)
+	}
+	waitUntil(ctx, r.t.slotTime(slot).Add(a.length.get(r.t.spec)))
+	for _, vc := range vcs {
+		r.t.t.Logf("scenario %s: beacon API proxy of %s sent %d faulty responses in total", r.sc.name, vc.Type, vc.proxies[0].faultedResponses())
+	}
+	return nil
+}
+
+// expectBeaconAPI checks which beacon node served the validator duty requests of
+// the validator clients of a node during the previous slot.
+type expectBeaconAPI struct {
+	node     int
+	fallback bool
+}
+
+func (a *expectBeaconAPI) String() string {
+	if a.fallback {
+		return fmt.Sprintf("expect node %d uses fallback beacon api", a.node)
+	}
+	return fmt.Sprintf("expect node %d uses primary beacon api", a.node)
+}
+
+func (a *expectBeaconAPI) run(ctx context.Context, r *scenarioRun, slot common.Slot) error {
+	if slot == 0 {
+		return fmt.Errorf("beacon API use can't be checked before slot 1")
+	}
+	vcs, err := r.nodeValidators(a.node)
+	if err != nil {
+		return err
+	}
+	if !waitUntil(ctx, r.t.slotTime(slot)) {
+		return nil
+	}
+	proxy, name := 0, "primary"
+	if a.fallback {
+		proxy, name = 1, "fallback"
+	}
+	since := r.t.slotTime(slot - 1)
+	for _, vc := range vcs {
+		if last := vc.proxies[proxy].lastValidatorResponse(); last.Before(since) {
+			return fmt.Errorf("%s did not use its %s beacon node in slot %d", vc.Type, name, slot-1)
+		}
+	}
+	return nil
+}
+
+// expectProposals checks that the validators of a node propose all blocks they
+// are assigned to.
+type expectProposals struct {
+	node   int
+	length span
+}
+
+func (a *expectProposals) String() string {
+	return fmt.Sprintf("expect no missed proposals by node %d for %v", a.node, a.length)
+}
+
+func (a *expectProposals) run(ctx context.Context, r *scenarioRun, slot common.Slot) error {
+	t := r.t
+	if a.node >= len(t.beacons) {
+		return fmt.Errorf("testnet has only %d nodes", len(t.beacons))
+	}
+	slotDuration := time.Duration(t.spec.SECONDS_PER_SLOT) * time.Second
+	end := slot + common.Slot(a.length.get(t.spec)/slotDuration)
+
+	var proposed int
+	var missed []common.Slot
+	for s := slot; s < end; s++ {
+		if !waitUntil(ctx, t.slotTime(s).Add(-stepLead)) {
+			return nil
+		}
+		proposer, err := r.proposer(ctx, s)
+		if err != nil {
+			return err
+		}
+		vc := t.validatorClientOf(proposer)
+		if vc < 0 || t.validatorClient(vc).beaconIndex != a.node {
+			continue
+		}
+		// Give the block time to propagate.
+		if !waitUntil(ctx, t.slotTime(s+1)) {
+			return nil
+		}
+		if ok, err := r.proposed(ctx, s, proposer); err != nil {
+			return err
+		} else if ok {
+			proposed++
+		} else {
+			missed = append(missed, s)
+		}
+	}
+	t.t.Logf("scenario %s: validators of node %d proposed %d blocks, missed %d", r.sc.name, a.node, proposed, len(missed))
+	if len(missed) > 0 {
+		return fmt.Errorf("validators of node %d missed their proposals at slots %v", a.node, missed)
+	}
+	return nil
+}
+
+// proposed reports whether the block of a slot was proposed by the given validator
+// and is in the chain of a running beacon node.
+func (r *scenarioRun) proposed(ctx context.Context, slot common.Slot, proposer common.ValidatorIndex) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	active := r.activeBeacons()
+	if len(active) == 0 {
+		return false, fmt.Errorf("all beacon nodes are paused or partitioned")
+	}
+	var header eth2api.BeaconBlockHeaderAndInfo
+	exists, err := beaconapi.BlockHeader(ctx, r.t.beacons[active[0]].API, eth2api.BlockIdSlot(slot), &header)
+	if err != nil {
+		return false, fmt.Errorf("beacon %d: can't get block of slot %d: %v", active[0], slot, err)
+	}
+	return exists && header.Header.Message.ProposerIndex == proposer, nil
+}
+
+// blockInfo is a block in the chain of a beacon node.
+type blockInfo struct {
+	slot         common.Slot
+	root, parent common.Root
+}
+
+// trackReorgs polls the heads of all running beacon nodes and records the
+// deepest reorg.
+func (r *scenarioRun) trackReorgs(ctx context.Context) {
+	heads := make([]*blockInfo, len(r.t.beacons))
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, i := range r.activeBeacons() {
+				r.checkHead(ctx, i, &heads[i])
+			}
+		}
+	}
+}
+
+func (r *scenarioRun) checkHead(ctx context.Context, i int, last **blockInfo) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	bn := r.t.beacons[i]
+	head, err := fetchBlockInfo(ctx, bn, eth2api.BlockHead)
+	if err != nil {
+		return
+	}
+	prev := *last
+	*last = head
+	if prev == nil || prev.root == head.root {
+		return
+	}
+	depth, err := reorgDepth(ctx, bn, prev, head)
+	if err != nil {
+		r.t.t.Logf("scenario %s: beacon %d: can't check head change %s -> %s: %v", r.sc.name, i, prev.root, head.root, err)
+		return
+	}
+	if depth == 0 {
+		return
+	}
+	r.t.t.Logf("scenario %s: beacon %d: reorg of %d blocks at slot %d, head %s -> %s", r.sc.name, i, depth, head.slot, prev.root, head.root)
+	r.mu.Lock()
+	if depth > r.reorgDepth {
+		r.reorgDepth, r.reorgNode = depth, i
+	}
+	if depth > r.nodeDepths[i] {
+		r.nodeDepths[i] = depth
+	}
+	r.mu.Unlock()
+}
+
+// reorgDepth returns the number of blocks in the chain of old which are not
+// ancestors of head.
+func reorgDepth(ctx context.Context, bn *BeaconNode, old, head *blockInfo) (int, error) {
+	depth := 0
+	for old.root != head.root {
+		var err error
+		if head.slot > old.slot {
+			head, err = fetchBlockInfo(ctx, bn, eth2api.BlockIdRoot(head.parent))
+		} else {
+			old, err = fetchBlockInfo(ctx, bn, eth2api.BlockIdRoot(old.parent))
+			depth++
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return depth, nil
+}
+
+func fetchBlockInfo(ctx context.Context, bn *BeaconNode, id eth2api.BlockId) (*blockInfo, error) {
+	var header eth2api.BeaconBlockHeaderAndInfo
+	if exists, err := beaconapi.BlockHeader(ctx, bn.API, id, &header); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, fmt.Errorf("block %s not found", id.BlockId())
+	}
+	return &blockInfo{
+		slot:   header.Header.Message.Slot,
+		root:   header.Root,
+		parent: header.Header.Message.ParentRoot,
+	}, nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/hive/hivesim"
+	"github.com/protolambda/eth2api"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+func TestParseScenario(t *testing.T) {
+	input := `
+# comment
+at slot 12: delay proposer by 3s
+at slot 20: withhold block for 2 slots   # trailing comment
+at slot 24: expect slot 20 orphaned
+at epoch 2: partition nodes 0-1 for 2 epochs
+at epoch 2: netem node 2 latency 200ms jitter 50ms loss 2.5 rate 1000kbit for 3 epochs
+at epoch 3: fault beacon api of node 0 with truncate on ^/eth/v1/validator/ for 4 slots
+at slot 70: expect node 0 uses fallback beacon api
+at epoch 4: expect no missed proposals by node 0 for 1 epoch
+at epoch 8: expect finalized epoch >= 5
+at epoch 9: end
+expect reorg depth <= 2
+`
+	sc, err := parseScenario("test", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"delay proposer by 3s",
+		"withhold block for 2 slots",
+		"expect slot 20 orphaned",
+		"partition nodes 0-1 for 2 epochs",
+		"netem nodes 2-2 latency 200ms jitter 50ms loss 2.5 rate 1mbit for 3 epochs",
+		"fault beacon api of node 0 with truncate on ^/eth/v1/validator/ for 4 slots",
+		"expect node 0 uses fallback beacon api",
+		"expect no missed proposals by node 0 for 1 epoch",
+		"expect finalized epoch >= 5",
+	}
+	if len(sc.steps) != len(want) {
+		t.Fatalf("wrong number of steps %d, want %d", len(sc.steps), len(want))
+	}
+	for i, step := range sc.steps {
+		if step.action.String() != want[i] {
+			t.Errorf("step %d: got %q, want %q", i, step.action, want[i])
+		}
+	}
+	if sc.steps[1].line != 4 || sc.steps[1].at != (scenarioTime{n: 20}) {
+		t.Errorf("wrong withhold step position: line %d, %v", sc.steps[1].line, sc.steps[1].at)
+	}
+	if sc.end == nil || *sc.end != (scenarioTime{n: 9, epoch: true}) {
+		t.Errorf("wrong end %v", sc.end)
+	}
+	if sc.maxReorgDepth != 2 {
+		t.Errorf("wrong max reorg depth %d", sc.maxReorgDepth)
+	}
+	if !sc.usesBeaconProxies() {
+		t.Errorf("scenario doesn't use beacon proxies")
+	}
+}
+
+func TestParseScenarioErrors(t *testing.T) {
+	tests := []struct {
+		input, err string
+	}{
+		{"", "scenario has no timed steps"},
+		{"expect reorg depth <= 1", "scenario has no timed steps"},
+		{"withhold block for 1s", `line 1: step "withhold block for 1s" needs a time`},
+		{"at block 1: end", "line 1: invalid time"},
+		{"at slot 1: withhold block for 0 slots", `line 1: invalid count "0"`},
+		{"at slot 1: withhold block for 2 blocks", `line 1: invalid unit "blocks"`},
+		{"at slot 1: expect slot x orphaned", `line 1: invalid slot "x"`},
+		{"at slot 1: expect slot 1 missing", `line 1: unknown step "expect slot 1 missing"`},
+		{"at slot 1: partition nodes 2-1 for 1s", `line 1: invalid node range "2-1"`},
+		{"at slot 1: netem node 0 for 1s", `line 1: want "netem nodes N-M`},
+		{"at slot 1: netem node 0 delay 1s for 1s", `line 1: unknown netem condition "delay"`},
+		{"at slot 1: netem node 0 loss 101 for 1s", `line 1: invalid loss "101"`},
+		{"at slot 1: netem node 0 rate 1gbit for 1s", `line 1: invalid rate "1gbit"`},
+		{"at slot 1: netem node 0 jitter 10ms for 1s", "line 1: netem jitter needs latency"},
+		{"at slot 1: fault beacon api of node 0 with slow for 1s", `line 1: unknown fault "slow"`},
+		{"at slot 1: end\nat slot 2: end", "line 2: duplicate end"},
+		{"at slot 1: expect reorg depth <= 1", "line 1: reorg depth is checked for the whole scenario"},
+	}
+	for _, test := range tests {
+		_, err := parseScenario("test", strings.NewReader(test.input))
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%q: got error %v, want %q", test.input, err, test.err)
+		}
+	}
+}
+
+// This test checks that the scenario files of the simulator are valid.
+func TestLoadScenarios(t *testing.T) {
+	list, err := loadScenarios(scenarioDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no scenarios found")
+	}
+	for _, sc := range list {
+		for _, step := range sc.steps {
+			if _, ok := step.action.(*withholdBlock); ok && !sc.usesBeaconProxies() {
+				t.Errorf("scenario %s withholds blocks without beacon proxies", sc.name)
+			}
+		}
+	}
+}
+
+// newTestScenarioRun creates a scenario run against a testnet with one beacon node,
+// which serves the given block header at every slot. The testnet is at slot 10.
+func newTestScenarioRun(t *testing.T, header *eth2api.BeaconBlockHeaderAndInfo) *scenarioRun {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/eth/v1/beacon/headers/") {
+			http.NotFound(w, r)
+			return
+		}
+		if header == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 404, "message": "not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": header})
+	}))
+	t.Cleanup(srv.Close)
+
+	spec := new(common.Spec)
+	spec.SECONDS_PER_SLOT = 1
+	spec.SLOTS_PER_EPOCH = 4
+	testnet := &Testnet{
+		spec:        spec,
+		genesisTime: common.Timestamp(time.Now().Add(-10 * time.Second).Unix()),
+		beacons: []*BeaconNode{{
+			Client: &hivesim.Client{Type: "beacon", Container: "beacon-0"},
+			API:    &eth2api.Eth2HttpClient{Addr: srv.URL, Cli: &http.Client{}, Codec: eth2api.JSONCodec{}},
+		}},
+	}
+	return &scenarioRun{
+		sc:          &scenario{name: "test"},
+		t:           testnet,
+		paused:      make(map[string]int),
+		partitioned: make(map[string]int),
+		netem:       make(map[string][]*netem),
+		withheld:    make(map[common.Slot]common.ValidatorIndex),
+	}
+}
+
+func TestExpectBlock(t *testing.T) {
+	header := new(eth2api.BeaconBlockHeaderAndInfo)
+	header.Canonical = true
+	header.Header.Message.Slot = 5
+	header.Header.Message.ProposerIndex = 7
+
+	tests := []struct {
+		header    *eth2api.BeaconBlockHeaderAndInfo
+		withheld  bool
+		canonical bool
+		err       string
+	}{
+		// Blocks which weren't withheld fail both expectations.
+		{header: header, canonical: true, err: "no withheld block of slot 5 was released"},
+		{header: nil, canonical: false, err: "no withheld block of slot 5 was released"},
+		// The withheld block is in the chain.
+		{header: header, withheld: true, canonical: true},
+		{header: header, withheld: true, canonical: false, err: "is canonical, want orphaned"},
+		// The slot is empty.
+		{header: nil, withheld: true, canonical: false},
+		{header: nil, withheld: true, canonical: true, err: "is orphaned, want canonical"},
+	}
+	for i, test := range tests {
+		r := newTestScenarioRun(t, test.header)
+		if test.withheld {
+			r.withheld[5] = 7
+		}
+		a := &expectBlock{slot: 5, canonical: test.canonical}
+		err := a.run(context.Background(), r, 8)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("test %d: got error %v, want %q", i, err, test.err)
+		}
+	}
+}
+
+// This test checks that beacon API proxies hold back blocks of withheld slots.
+func TestProxyWithholdBlock(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		published = make(map[string]time.Time)
+	)
+	bn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		published[string(body)] = time.Now()
+		mu.Unlock()
+	}))
+	defer bn.Close()
+	proxy, err := startBeaconProxy("127.0.0.1", &BeaconNode{API: &eth2api.Eth2HttpClient{Addr: bn.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	hold := &blockHold{slot: 5, until: time.Now().Add(500 * time.Millisecond)}
+	proxy.addHold(hold)
+	defer proxy.removeHold(hold)
+
+	publish := func(slot int) {
+		body := fmt.Sprintf(`{"message": {"slot": "%d"}, "signature": "0x00"}`, slot)
+		resp, err := http.Post(proxy.URL+"/eth/v1/beacon/blocks", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("slot %d: wrong status %d", slot, resp.StatusCode)
+		}
+	}
+	start := time.Now()
+	publish(5)
+	publish(6)
+	if time.Since(start) >= 500*time.Millisecond {
+		t.Fatal("publishing a withheld block blocks the validator client")
+	}
+	mu.Lock()
+	_, early := published[`{"message": {"slot": "5"}, "signature": "0x00"}`]
+	_, other := published[`{"message": {"slot": "6"}, "signature": "0x00"}`]
+	mu.Unlock()
+	if early {
+		t.Error("withheld block published before the hold ended")
+	}
+	if !other {
+		t.Error("block of other slot not published")
+	}
+
+	proxy.removeHold(hold)
+	held, err := hold.heldBlocks()
+	if held != 1 || err != nil {
+		t.Fatalf("wrong held blocks %d, error %v", held, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if at, ok := published[`{"message": {"slot": "5"}, "signature": "0x00"}`]; !ok || at.Before(hold.until) {
+		t.Errorf("withheld block published at %v, hold ends at %v", at, hold.until)
+	}
+}
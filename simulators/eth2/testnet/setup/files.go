@@ -42,3 +42,9 @@ func KeysBundle(keys []*KeyDetails) hivesim.StartOption {
 	}
 	return hivesim.Bundle(opts...)
 }
+
+// SlashingProtectionBundle provides EIP-3076 slashing protection data to a validator
+// client, which the client imports before it starts validating.
+func SlashingProtectionBundle(interchange []byte) hivesim.StartOption {
+	return hivesim.WithDynamicFile("/hive/input/slashing_protection.json", bytesSource(interchange))
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/hive/hivesim"
+	"github.com/ethereum/hive/simulators/eth2/testnet/setup"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Validator clients should provide the /hive-bin/export_slashing_protection script, which
+// prints the slashing protection database in EIP-3076 interchange format. When the file
+// /hive/input/slashing_protection.json exists, the client must import it before it starts
+// validating. The check is skipped for clients without the script, see docs/clients.md.
+const (
+	exportSlashingProtectionScript = "export_slashing_protection"
+	interchangeFormatVersion       = "5"
+)
+
+// interchange is the EIP-3076 slashing protection interchange format.
+type interchange struct {
+	Metadata struct {
+		InterchangeFormatVersion string      `json:"interchange_format_version"`
+		GenesisValidatorsRoot    common.Root `json:"genesis_validators_root"`
+	} `json:"metadata"`
+	Data []interchangeValidator `json:"data"`
+}
+
+type interchangeValidator struct {
+	Pubkey             common.BLSPubkey         `json:"pubkey"`
+	SignedBlocks       []interchangeBlock       `json:"signed_blocks"`
+	SignedAttestations []interchangeAttestation `json:"signed_attestations"`
+}
+
+type interchangeBlock struct {
+	Slot        common.Slot  `json:"slot"`
+	SigningRoot *common.Root `json:"signing_root,omitempty"`
+}
+
+type interchangeAttestation struct {
+	SourceEpoch common.Epoch `json:"source_epoch"`
+	TargetEpoch common.Epoch `json:"target_epoch"`
+	SigningRoot *common.Root `json:"signing_root,omitempty"`
+}
+
+// VerifySlashingProtection exports the slashing protection database of a validator client,
+// moves its validators to a client of the given type with the exported data imported, and
+// checks that the new client continues validating without signing slashable messages.
+func (t *Testnet) VerifySlashingProtection(ctx context.Context, p *PreparedTestnet, vcIndex int, replacement *hivesim.ClientDefinition) {
+	epochDuration := time.Duration(t.spec.SLOTS_PER_EPOCH) * time.Duration(t.spec.SECONDS_PER_SLOT) * time.Second
+
+	vc := t.validatorClient(vcIndex)
+	if !t.canExportSlashingProtection(vc) {
+		return
+	}
+	// Let the validators sign some messages first.
+	if !waitUntil(ctx, t.GenesisTime().Add(4*epochDuration)) {
+		return
+	}
+	exported, err := t.exportSlashingProtection(vc)
+	if err != nil {
+		t.t.Errorf("slashing protection: %v", err)
+		return
+	}
+	if err := t.checkInterchange(vc, exported); err != nil {
+		t.t.Errorf("slashing protection: invalid interchange exported by validator client %d (%s): %v", vcIndex, vc.Type, err)
+		return
+	}
+	t.t.Logf("slashing protection: exported data of %d validators from validator client %d (%s)", len(exported.Data), vcIndex, vc.Type)
+
+	// Replace the validator client, importing the exported data.
+	data, _ := json.Marshal(exported)
+	vc, err = p.replaceValidatorClient(t, vcIndex, replacement, setup.SlashingProtectionBundle(data))
+	if err != nil {
+		t.t.Errorf("slashing protection: %v", err)
+		return
+	}
+
+	// Check the new client's database after it has been validating for a while.
+	if !t.canExportSlashingProtection(vc) {
+		return
+	}
+	if !waitUntil(ctx, time.Now().Add(3*epochDuration)) {
+		return
+	}
+	after, err := t.exportSlashingProtection(vc)
+	if err != nil {
+		t.t.Errorf("slashing protection: %v", err)
+		return
+	}
+	if err := t.checkInterchange(vc, after); err != nil {
+		t.t.Errorf("slashing protection: invalid interchange exported by validator client %d (%s): %v", vcIndex, vc.Type, err)
+		return
+	}
+	if err := checkSlashable(exported, after); err != nil {
+		t.t.Errorf("slashing protection: validator client %d (%s) signed slashable message: %v", vcIndex, vc.Type, err)
+		return
+	}
+	if maxTargetEpoch(after) <= maxTargetEpoch(exported) {
+		t.t.Errorf("slashing protection: validator client %d (%s) did not attest after import", vcIndex, vc.Type)
+		return
+	}
+	t.t.Logf("slashing protection: validator client %d (%s) continues validating after import", vcIndex, vc.Type)
+}
+
+// canExportSlashingProtection reports whether a validator client has the export script.
+// When it doesn't, the reason for skipping the check is logged.
+func (t *Testnet) canExportSlashingProtection(vc *ValidatorClient) bool {
+	info, err := vc.ExecShell("test -x /hive-bin/" + exportSlashingProtectionScript)
+	switch {
+	case err != nil:
+		t.t.Errorf("slashing protection: can't look for export script in %s: %v", vc.Type, err)
+		return false
+	case info.ExitCode != 0:
+		t.t.Logf("slashing protection: check skipped, %s has no /hive-bin/%s script", vc.Type, exportSlashingProtectionScript)
+		return false
+	}
+	return true
+}
+
+// exportSlashingProtection runs the export script of a validator client.
+func (t *Testnet) exportSlashingProtection(vc *ValidatorClient) (*interchange, error) {
+	info, err := vc.Exec(exportSlashingProtectionScript)
+	if err != nil {
+		return nil, fmt.Errorf("can't export from %s: %v", vc.Type, err)
+	}
+	if info.ExitCode != 0 {
+		return nil, fmt.Errorf("export script of %s failed with exit code %d: %s", vc.Type, info.ExitCode, info.Stderr)
+	}
+	data := new(interchange)
+	if err := json.Unmarshal([]byte(info.Stdout), data); err != nil {
+		return nil, fmt.Errorf("can't decode interchange exported by %s: %v", vc.Type, err)
+	}
+	return data, nil
+}
+
+// checkInterchange validates the structure of exported slashing protection data.
+func (t *Testnet) checkInterchange(vc *ValidatorClient, data *interchange) error {
+	if v := data.Metadata.InterchangeFormatVersion; v != interchangeFormatVersion {
+		return fmt.Errorf("wrong interchange format version %q", v)
+	}
+	if root := data.Metadata.GenesisValidatorsRoot; root != t.genesisValidatorsRoot {
+		return fmt.Errorf("wrong genesis validators root %s", root)
+	}
+	if n := int(vc.Indices[1] - vc.Indices[0]); len(data.Data) > n {
+		return fmt.Errorf("data of %d validators, but client runs only %d", len(data.Data), n)
+	}
+	seen := make(map[common.BLSPubkey]bool)
+	attestations := 0
+	for _, v := range data.Data {
+		if seen[v.Pubkey] {
+			return fmt.Errorf("duplicate entry for validator %s", v.Pubkey)
+		}
+		seen[v.Pubkey] = true
+		for _, a := range v.SignedAttestations {
+			if a.SourceEpoch > a.TargetEpoch {
+				return fmt.Errorf("validator %s: attestation source epoch %d after target epoch %d", v.Pubkey, a.SourceEpoch, a.TargetEpoch)
+			}
+		}
+		attestations += len(v.SignedAttestations)
+	}
+	if attestations == 0 {
+		return fmt.Errorf("no signed attestations")
+	}
+	return nil
+}
+
+// checkSlashable checks that messages signed by the new client don't conflict
+// with the messages in the imported data or with each other.
+func checkSlashable(imported, after *interchange) error {
+	for _, v := range imported.Data {
+		if findValidator(after, v.Pubkey) == nil {
+			return fmt.Errorf("imported validator %s missing in database", v.Pubkey)
+		}
+	}
+	for _, v := range after.Data {
+		var old interchangeValidator
+		if iv := findValidator(imported, v.Pubkey); iv != nil {
+			old = *iv
+		}
+
+		// Check blocks.
+		var newBlocks []interchangeBlock
+		for _, b := range v.SignedBlocks {
+			if !containsBlock(old.SignedBlocks, b) {
+				newBlocks = append(newBlocks, b)
+			}
+		}
+		for i, b := range newBlocks {
+			others := append(old.SignedBlocks[:len(old.SignedBlocks):len(old.SignedBlocks)], newBlocks[i+1:]...)
+			for _, o := range others {
+				if b.Slot == o.Slot && !sameRoot(b.SigningRoot, o.SigningRoot) {
+					return fmt.Errorf("validator %s: double proposal at slot %d", v.Pubkey, b.Slot)
+				}
+			}
+		}
+
+		// Check attestations.
+		var newAtts []interchangeAttestation
+		for _, a := range v.SignedAttestations {
+			if !containsAttestation(old.SignedAttestations, a) {
+				newAtts = append(newAtts, a)
+			}
+		}
+		for i, a := range newAtts {
+			others := append(old.SignedAttestations[:len(old.SignedAttestations):len(old.SignedAttestations)], newAtts[i+1:]...)
+			for _, o := range others {
+				switch {
+				case a.TargetEpoch == o.TargetEpoch && !(a.SourceEpoch == o.SourceEpoch && sameRoot(a.SigningRoot, o.SigningRoot)):
+					return fmt.Errorf("validator %s: double vote for target epoch %d", v.Pubkey, a.TargetEpoch)
+				case surrounds(a, o) || surrounds(o, a):
+					return fmt.Errorf("validator %s: surround vote %d->%d, %d->%d", v.Pubkey, a.SourceEpoch, a.TargetEpoch, o.SourceEpoch, o.TargetEpoch)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func findValidator(data *interchange, pubkey common.BLSPubkey) *interchangeValidator {
+	for i := range data.Data {
+		if data.Data[i].Pubkey == pubkey {
+			return &data.Data[i]
+		}
+	}
+	return nil
+}
+
+func containsBlock(list []interchangeBlock, b interchangeBlock) bool {
+	for _, x := range list {
+		if x.Slot == b.Slot && equalRoot(x.SigningRoot, b.SigningRoot) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAttestation(list []interchangeAttestation, a interchangeAttestation) bool {
+	for _, x := range list {
+		if x.SourceEpoch == a.SourceEpoch && x.TargetEpoch == a.TargetEpoch && equalRoot(x.SigningRoot, a.SigningRoot) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalRoot reports whether two optional signing roots are equal or both absent.
+func equalRoot(a, b *common.Root) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sameRoot reports whether two optional signing roots are known to be equal.
+func sameRoot(a, b *common.Root) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// surrounds reports whether attestation a surrounds b.
+func surrounds(a, b interchangeAttestation) bool {
+	return a.SourceEpoch < b.SourceEpoch && b.TargetEpoch < a.TargetEpoch
+}
+
+func maxTargetEpoch(data *interchange) (max common.Epoch) {
+	for _, v := range data.Data {
+		for _, a := range v.SignedAttestations {
+			if a.TargetEpoch > max {
+				max = a.TargetEpoch
+			}
+		}
+	}
+	return max
+}
+
+// waitUntil blocks until the given time. It returns false if the context
+// is canceled before that.
+func waitUntil(ctx context.Context, t time.Time) bool {
+	select {
+	case <-time.After(time.Until(t)):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/protolambda/eth2api"
+	"github.com/protolambda/eth2api/client/beaconapi"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+const (
+	// A validator client is considered to be failing its sync committee duties
+	// when its participation rate stays below this threshold...
+	minSyncParticipation = 0.5
+	// ...for this many consecutive epochs.
+	maxLowParticipationEpochs = 2
+)
+
+// syncParticipation counts sync committee duties of a validator client in one epoch.
+type syncParticipation struct {
+	Expected uint64 `json:"expected"`
+	Signed   uint64 `json:"signed"`
+}
+
+func (p syncParticipation) rate() float64 {
+	if p.Expected == 0 {
+		return 1
+	}
+	return float64(p.Signed) / float64(p.Expected)
+}
+
+// syncCommitteeTracker collects sync committee participation per validator client.
+type syncCommitteeTracker struct {
+	testnet *Testnet
+
+	// current sync committee, cached per sync committee period.
+	period    uint64
+	committee []common.ValidatorIndex
+
+	// participation of the epoch which is currently being processed, by validator client index.
+	epoch   common.Epoch
+	current map[int]*syncParticipation
+	// number of consecutive epochs with low participation, by validator client index.
+	lowEpochs map[int]int
+}
+
+func newSyncCommitteeTracker(testnet *Testnet) *syncCommitteeTracker {
+	return &syncCommitteeTracker{
+		testnet:   testnet,
+		period:    ^uint64(0),
+		current:   make(map[int]*syncParticipation),
+		lowEpochs: make(map[int]int),
+	}
+}
+
+// TrackSyncCommittees polls the first beacon node for blocks and records which validator
+// clients contributed sync committee signatures. Per-epoch participation is logged to the
+// test output, and the test fails if any validator client keeps missing its duties.
+func (t *Testnet) TrackSyncCommittees(ctx context.Context) {
+	var (
+		tracker      = newSyncCommitteeTracker(t)
+		slotDuration = time.Duration(t.spec.SECONDS_PER_SLOT) * time.Second
+		altairStart  = t.GenesisTime().Add(time.Duration(t.spec.SLOTS_PER_EPOCH) * time.Duration(t.spec.ALTAIR_FORK_EPOCH) * slotDuration)
+		timer        = time.NewTicker(slotDuration)
+		lastSlot     common.Slot
+	)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tim := <-timer.C:
+			// Sync committees only exist after the altair fork.
+			if tim.Before(altairStart.Add(slotDuration)) || len(t.beacons) == 0 {
+				continue
+			}
+			slot := t.spec.TimeToSlot(common.Timestamp(tim.Unix()), t.genesisTime)
+			// Blocks are processed once their slot has completed.
+			for s := lastSlot + 1; s < slot; s++ {
+				if err := tracker.processSlot(ctx, s); err != nil {
+					t.t.Logf("sync committee tracker: can't process slot %d: %v", s, err)
+				}
+			}
+			lastSlot = slot - 1
+		}
+	}
+}
+
+// processSlot records the sync aggregate of the block at the given slot. The aggregate
+// signs the previous slot, so it is counted for the committee of that slot.
+func (tr *syncCommitteeTracker) processSlot(ctx context.Context, slot common.Slot) error {
+	spec := tr.testnet.spec
+	if slot == 0 {
+		return nil
+	}
+	signed := slot - 1
+	if ep := spec.SlotToEpoch(signed); ep != tr.epoch {
+		tr.finishEpoch()
+		tr.epoch = ep
+	}
+	if spec.SlotToEpoch(signed) < spec.ALTAIR_FORK_EPOCH {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	bn := tr.testnet.beacons[0]
+
+	if err := tr.updateCommittee(ctx, bn, signed); err != nil {
+		return err
+	}
+	var block eth2api.VersionedSignedBeaconBlock
+	if exists, err := beaconapi.BlockV2(ctx, bn.API, eth2api.BlockIdSlot(slot), &block); err != nil {
+		return err
+	} else if !exists {
+		// Empty slot, nothing to count.
+		return nil
+	}
+	altairBlock, ok := block.Data.(*altair.SignedBeaconBlock)
+	if !ok {
+		return nil
+	}
+	bits := altairBlock.Message.Body.SyncAggregate.SyncCommitteeBits
+	for i, validator := range tr.committee {
+		vc := tr.testnet.validatorClientOf(validator)
+		if vc < 0 {
+			continue
+		}
+		p := tr.current[vc]
+		if p == nil {
+			p = new(syncParticipation)
+			tr.current[vc] = p
+		}
+		p.Expected++
+		if bits[i/8]&(1<<(uint(i)%8)) != 0 {
+			p.Signed++
+		}
+	}
+	return nil
+}
+
+// updateCommittee fetches the sync committee if the sync committee period changed.
+func (tr *syncCommitteeTracker) updateCommittee(ctx context.Context, bn *BeaconNode, slot common.Slot) error {
+	spec := tr.testnet.spec
+	epoch := spec.SlotToEpoch(slot)
+	period := uint64(epoch) / uint64(spec.EPOCHS_PER_SYNC_COMMITTEE_PERIOD)
+	if period == tr.period {
+		return nil
+	}
+	var committees eth2api.SyncCommittees
+	if exists, err := beaconapi.SyncCommittees(ctx, bn.API, eth2api.StateHead, &epoch, &committees); err != nil {
+		return err
+	} else if !exists {
+		return nil
+	}
+	tr.committee = committees.Validators
+	tr.period = period
+	return nil
+}
+
+// finishEpoch logs the participation metrics of the current epoch and checks
+// that no validator client keeps missing its duties.
+func (tr *syncCommitteeTracker) finishEpoch() {
+	if len(tr.current) == 0 {
+		return
+	}
+	t := tr.testnet.t
+	vcs := make([]int, 0, len(tr.current))
+	for vc := range tr.current {
+		vcs = append(vcs, vc)
+	}
+	sort.Ints(vcs)
+	for _, vc := range vcs {
+		p := tr.current[vc]
+		t.Logf("sync committee participation: epoch %d, validator client %d (%s): %d/%d (%.1f%%)",
+			tr.epoch, vc, tr.testnet.validatorClient(vc).Type, p.Signed, p.Expected, 100*p.rate())
+
+		if p.rate() < minSyncParticipation {
+			tr.lowEpochs[vc]++
+		} else {
+			tr.lowEpochs[vc] = 0
+		}
+		if tr.lowEpochs[vc] == maxLowParticipationEpochs {
+			t.Errorf("validator client %d (%s) missed sync committee duties for %d consecutive epochs",
+				vc, tr.testnet.validatorClient(vc).Type, maxLowParticipationEpochs)
+		}
+	}
+	tr.current = make(map[int]*syncParticipation)
+}
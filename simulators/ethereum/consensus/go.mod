@@ -6,3 +6,5 @@ require (
 	github.com/ethereum/go-ethereum v1.10.8
 	github.com/ethereum/hive v0.0.0-20201105181315-a9685e29a08a
 )
+
+replace github.com/ethereum/hive => ../../..
@@ -8,7 +8,6 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -252,6 +251,23 @@ var ruleset = map[string]envvars{
 	},
 }
 
+func init() {
+	hivesim.RegisterParams(
+		hivesim.SimParam{
+			Name:        "HIVE_PARALLELISM",
+			Type:        hivesim.ParamInt,
+			Default:     "16",
+			Description: "Number of clients running tests at the same time. Hive sets it from --sim.parallelism.",
+		},
+		hivesim.SimParam{
+			Name:        "HIVE_SIMLIMIT",
+			Type:        hivesim.ParamInt,
+			Default:     "-1",
+			Description: "Max number of tests to run, -1 runs all tests. Hive sets it from --sim.testlimit.",
+		},
+	)
+}
+
 func main() {
 	suite := hivesim.Suite{
 		Name: "consensus",
@@ -277,22 +293,8 @@ func loaderTest(t *hivesim.T) {
 		t.Fatal("can't get client types:", err)
 	}
 
-	parallelism := 16
-	if val, ok := os.LookupEnv("HIVE_PARALLELISM"); ok {
-		if p, err := strconv.Atoi(val); err != nil {
-			t.Logf("Warning: invalid HIVE_PARALLELISM value %q", val)
-		} else {
-			parallelism = p
-		}
-	}
-	testLimit := -1
-	if val, ok := os.LookupEnv("HIVE_SIMLIMIT"); ok {
-		if p, err := strconv.Atoi(val); err != nil {
-			t.Logf("Warning: invalid HIVE_SIMLIMIT value %q", val)
-		} else {
-			testLimit = p
-		}
-	}
+	parallelism := hivesim.IntParam("HIVE_PARALLELISM")
+	testLimit := hivesim.IntParam("HIVE_SIMLIMIT")
 	t.Log("parallelism:", parallelism, "testlimit:", testLimit)
 
 	// Find the tests directory.
@@ -323,7 +325,7 @@ func loaderTest(t *hivesim.T) {
 	loadTests(t, fileRoot, testLimit, func(tc testcase) {
 		for _, client := range clientTypes {
 			tc := tc // shallow copy
-			tc.clientType = client
+			tc.clientType = client.Name
 			testCh <- &tc
 		}
 	})
@@ -403,7 +405,7 @@ func (tc *testcase) run(t *hivesim.T) {
 	}
 
 	// update the parameters with test-specific stuff
-	env := map[string]string{
+	env := hivesim.Params{
 		"HIVE_FORK_DAO_VOTE": "1",
 		"HIVE_CHAIN_ID":      "1",
 	}
@@ -418,7 +420,7 @@ func (tc *testcase) run(t *hivesim.T) {
 	}
 
 	t1 := time.Now()
-	client := t.StartClient(tc.clientType, env, files)
+	client := t.StartClient(tc.clientType, env, hivesim.WithStaticFiles(files))
 
 	t2 := time.Now()
 	genesisHash, genesisResponse, err := getBlock(client.RPC(), "0x0")
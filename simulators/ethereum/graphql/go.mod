@@ -3,3 +3,5 @@ module github.com/ethereum/hive/simulators/ethereum/graphql
 go 1.13
 
 require github.com/ethereum/hive v0.0.0-20201105000855-c76ca0f10a01
+
+replace github.com/ethereum/hive => ../../..
@@ -10,13 +10,21 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
-	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/ethereum/hive/hivesim"
 )
 
+func init() {
+	hivesim.RegisterParams(hivesim.SimParam{
+		Name:        "HIVE_PARALLELISM",
+		Type:        hivesim.ParamInt,
+		Default:     "16",
+		Description: "Number of test cases run against the client at the same time. Hive sets it from --sim.parallelism.",
+	})
+}
+
 func main() {
 	suite := hivesim.Suite{
 		Name: "graphql",
@@ -44,14 +52,7 @@ the client launched by this test.`,
 }
 
 func graphqlTest(t *hivesim.T, c *hivesim.Client) {
-	parallelism := 16
-	if val, ok := os.LookupEnv("HIVE_PARALLELISM"); ok {
-		if p, err := strconv.Atoi(val); err != nil {
-			t.Logf("Warning: invalid HIVE_PARALLELISM value %q", val)
-		} else {
-			parallelism = p
-		}
-	}
+	parallelism := hivesim.IntParam("HIVE_PARALLELISM")
 
 	var wg sync.WaitGroup
 	testCh := deliverTests(t, &wg, -1)
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/hive/hivesim"
+)
+
+// fork is a fork scheduled on the test chain.
+type fork struct {
+	name  string
+	param string // client parameter which schedules the fork
+	block uint64
+
+	// newFields are the response fields which may appear at the fork,
+	// keyed by RPC method.
+	newFields map[string][]string
+}
+
+var (
+	chainID     = big.NewInt(7)
+	chainLength = 24
+
+	forks = []fork{
+		{name: "Berlin", param: "HIVE_FORK_BERLIN", block: 8, newFields: map[string][]string{
+			"eth_getBlockByNumber":                    {"type", "accessList", "chainId"},
+			"eth_getTransactionByBlockNumberAndIndex": {"type", "accessList", "chainId"},
+			"eth_getTransactionByHash":                {"type", "accessList", "chainId"},
+			"eth_getTransactionReceipt":               {"type"},
+		}},
+		{name: "London", param: "HIVE_FORK_LONDON", block: 16, newFields: map[string][]string{
+			"eth_getBlockByNumber":      {"baseFeePerGas"},
+			"eth_getBlockByHash":        {"baseFeePerGas"},
+			"eth_getTransactionReceipt": {"effectiveGasPrice"},
+		}},
+	}
+
+	senderKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sender       = crypto.PubkeyToAddress(senderKey.PublicKey)
+
+	// The contract stores the block number in slot zero and logs it.
+	//
+	//   NUMBER PUSH1 0 SSTORE NUMBER PUSH1 0 MSTORE PUSH1 32 PUSH1 0 LOG0 STOP
+	contract     = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	contractCode = common.FromHex("0x436000554360005260206000a000")
+)
+
+// testChain is the chain imported by all clients.
+type testChain struct {
+	genesis *core.Genesis
+	blocks  []*types.Block
+}
+
+// generateChain creates the test chain. Every block contains a legacy transaction
+// calling the contract. From Berlin on, blocks also contain an access list
+// transaction, and from London on a dynamic fee transaction.
+func generateChain() *testChain {
+	config := &params.ChainConfig{
+		ChainID:             chainID,
+		HomesteadBlock:      big.NewInt(0),
+		EIP150Block:         big.NewInt(0),
+		EIP155Block:         big.NewInt(0),
+		EIP158Block:         big.NewInt(0),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(0),
+		IstanbulBlock:       big.NewInt(0),
+		MuirGlacierBlock:    big.NewInt(0),
+		BerlinBlock:         new(big.Int).SetUint64(forks[0].block),
+		LondonBlock:         new(big.Int).SetUint64(forks[1].block),
+		Ethash:              new(params.EthashConfig),
+	}
+	genesis := &core.Genesis{
+		Config:     config,
+		Difficulty: big.NewInt(0x20000),
+		GasLimit:   5000000,
+		Alloc: core.GenesisAlloc{
+			sender:   {Balance: new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))},
+			contract: {Code: contractCode, Balance: new(big.Int)},
+		},
+	}
+
+	db := rawdb.NewMemoryDatabase()
+	genesisBlock := genesis.MustCommit(db)
+	signer := types.LatestSigner(config)
+	gasPrice := big.NewInt(2 * params.GWei)
+	blocks, _ := core.GenerateChain(config, genesisBlock, ethash.NewFaker(), db, chainLength, func(i int, b *core.BlockGen) {
+		number := b.Number()
+		b.AddTx(types.MustSignNewTx(senderKey, signer, &types.LegacyTx{
+			Nonce:    b.TxNonce(sender),
+			To:       &contract,
+			Gas:      100000,
+			GasPrice: gasPrice,
+		}))
+		if config.IsBerlin(number) {
+			b.AddTx(types.MustSignNewTx(senderKey, signer, &types.AccessListTx{
+				ChainID:    chainID,
+				Nonce:      b.TxNonce(sender),
+				To:         &contract,
+				Gas:        100000,
+				GasPrice:   gasPrice,
+				AccessList: types.AccessList{{Address: contract, StorageKeys: []common.Hash{{}}}},
+			}))
+		}
+		if config.IsLondon(number) {
+			b.AddTx(types.MustSignNewTx(senderKey, signer, &types.DynamicFeeTx{
+				ChainID:   chainID,
+				Nonce:     b.TxNonce(sender),
+				To:        &contract,
+				Gas:       100000,
+				GasTipCap: big.NewInt(params.GWei),
+				GasFeeCap: gasPrice,
+			}))
+		}
+	})
+	return &testChain{genesis: genesis, blocks: blocks}
+}
+
+// params returns the client parameters which configure the chain.
+func (c *testChain) params() hivesim.Params {
+	p := hivesim.Params{
+		"HIVE_NETWORK_ID":          chainID.String(),
+		"HIVE_CHAIN_ID":            chainID.String(),
+		"HIVE_FORK_HOMESTEAD":      "0",
+		"HIVE_FORK_TANGERINE":      "0",
+		"HIVE_FORK_SPURIOUS":       "0",
+		"HIVE_FORK_BYZANTIUM":      "0",
+		"HIVE_FORK_CONSTANTINOPLE": "0",
+		"HIVE_FORK_PETERSBURG":     "0",
+		"HIVE_FORK_ISTANBUL":       "0",
+		"HIVE_FORK_MUIRGLACIER":    "0",
+		"HIVE_SKIP_POW":            "1",
+		"HIVE_NODETYPE":            "archive",
+	}
+	for _, f := range forks {
+		p[f.param] = strconv.FormatUint(f.block, 10)
+	}
+	return p
+}
+
+// writeFiles stores genesis.json and chain.rlp in dir and returns the client
+// files which load them.
+func (c *testChain) writeFiles(dir string) (map[string]string, error) {
+	genesis, err := json.Marshal(c.genesis)
+	if err != nil {
+		return nil, err
+	}
+	genesisFile := filepath.Join(dir, "genesis.json")
+	if err := ioutil.WriteFile(genesisFile, genesis, 0644); err != nil {
+		return nil, err
+	}
+	chainFile := filepath.Join(dir, "chain.rlp")
+	f, err := os.Create(chainFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	for _, block := range c.blocks {
+		if err := rlp.Encode(f, block); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]string{"genesis.json": genesisFile, "chain.rlp": chainFile}, nil
+}
+
+// head returns the last block of the chain.
+func (c *testChain) head() *types.Block {
+	return c.blocks[len(c.blocks)-1]
+}
+
+// block returns the block with the given number.
+func (c *testChain) block(number uint64) *types.Block {
+	return c.blocks[number-1]
+}
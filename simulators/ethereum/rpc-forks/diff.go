@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// difference is a mismatch between two JSON values.
+type difference struct {
+	path string
+	a, b string
+}
+
+// diffValues compares two decoded JSON values.
+func diffValues(path string, a, b interface{}) []difference {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		var diffs []difference
+		for _, key := range unionKeys(a, b) {
+			diffs = append(diffs, diffValues(path+"."+key, a[key], b[key])...)
+		}
+		return diffs
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			break
+		}
+		var diffs []difference
+		for i := range a {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%d]", path, i), a[i], b[i])...)
+		}
+		return diffs
+	default:
+		if a == b {
+			return nil
+		}
+	}
+	return []difference{{path: path, a: encodeValue(a), b: encodeValue(b)}}
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func encodeValue(v interface{}) string {
+	if v == nil {
+		return "<missing>"
+	}
+	enc, _ := json.Marshal(v)
+	if len(enc) > 80 {
+		return string(enc[:77]) + "..."
+	}
+	return string(enc)
+}
+
+// fieldKinds collects the JSON kind of every field in v, keyed by path. Only the
+// first element of arrays is visited, so results containing a different number of
+// elements can be compared.
+func fieldKinds(path string, v interface{}, kinds map[string]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		kinds[path] = "object"
+		for key, elem := range v {
+			fieldKinds(path+"."+key, elem, kinds)
+		}
+	case []interface{}:
+		kinds[path] = "array"
+		if len(v) > 0 {
+			fieldKinds(path+"[]", v[0], kinds)
+		}
+	case string:
+		kinds[path] = "string"
+	case float64:
+		kinds[path] = "number"
+	case bool:
+		kinds[path] = "bool"
+	case nil:
+		kinds[path] = "null"
+	}
+}
+
+// fieldChanges is the difference between the fields of a query result before and
+// after a fork.
+type fieldChanges struct {
+	removed  []string
+	added    []string // fields not expected to appear at the fork
+	expected []string // fields expected to appear at the fork
+	changed  []string // fields with a different JSON kind
+}
+
+func (fc *fieldChanges) empty() bool {
+	return len(fc.removed) == 0 && len(fc.added) == 0 && len(fc.changed) == 0
+}
+
+// diffFields compares the fields of two results. Fields named in newFields may
+// appear in the second result.
+func diffFields(before, after interface{}, newFields []string) *fieldChanges {
+	a, b := make(map[string]string), make(map[string]string)
+	fieldKinds("result", before, a)
+	fieldKinds("result", after, b)
+
+	fc := new(fieldChanges)
+	for path, kind := range a {
+		switch newKind, ok := b[path]; {
+		case !ok:
+			fc.removed = append(fc.removed, path)
+		case newKind != kind:
+			fc.changed = append(fc.changed, fmt.Sprintf("%s (%s -> %s)", path, kind, newKind))
+		}
+	}
+	for path := range b {
+		if _, ok := a[path]; ok {
+			continue
+		}
+		if containsString(newFields, fieldName(path)) {
+			fc.expected = append(fc.expected, path)
+		} else {
+			fc.added = append(fc.added, path)
+		}
+	}
+	sort.Strings(fc.removed)
+	sort.Strings(fc.added)
+	sort.Strings(fc.expected)
+	sort.Strings(fc.changed)
+	return fc
+}
+
+// fieldName returns the last element of a field path.
+func fieldName(path string) string {
+	path = strings.TrimSuffix(path, "[]")
+	return path[strings.LastIndexByte(path, '.')+1:]
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
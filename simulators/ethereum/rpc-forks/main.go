@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/hive/hivesim"
+)
+
+func main() {
+	chain := generateChain()
+	dir, err := ioutil.TempDir("", "rpc-forks")
+	if err != nil {
+		panic(err)
+	}
+	files, err := chain.writeFiles(dir)
+	if err != nil {
+		panic(err)
+	}
+
+	suite := hivesim.Suite{
+		Name: "rpc-forks",
+		Description: `This suite runs the same set of eth_* RPC queries against the blocks before and
+after each scheduled fork of a test chain. Within each client, the results before and
+after the fork are compared to find fields which disappear or change their type at the
+fork. The results of all clients are then compared with each other.`,
+	}
+	suite.Add(hivesim.TestSpec{
+		Name:        "fork boundaries",
+		Description: "Imports the test chain into all clients and compares RPC results across forks and clients.",
+		Run: func(t *hivesim.T) {
+			runForkTests(t, chain, files)
+		},
+	})
+	hivesim.MustRunSuite(hivesim.New(), suite)
+}
+
+// clientResults holds the query results of a client.
+type clientResults struct {
+	client  string
+	queries []query
+	results map[string]response // keyed by query string
+}
+
+func runForkTests(t *hivesim.T, chain *testChain, files map[string]string) {
+	clientTypes, err := t.Sim.ClientTypes()
+	if err != nil {
+		t.Fatal("can't get client types:", err)
+	}
+
+	// Collect the results of all clients.
+	var all []*clientResults
+	for _, clientType := range clientTypes {
+		if !clientType.HasRole("eth1") {
+			continue
+		}
+		var results *clientResults
+		t.Run(hivesim.TestSpec{
+			Name:        fmt.Sprintf("fork boundaries (%s)", clientType.Name),
+			Description: "Runs the queries against the blocks around each fork and compares the results across the fork boundary.",
+			Run: func(t *hivesim.T) {
+				client := t.StartClient(clientType.Name, chain.params(), hivesim.WithStaticFiles(files))
+				if err := checkHead(client, chain); err != nil {
+					t.Fatal(err)
+				}
+				results = runQueries(client, chain)
+				checkForks(t, chain, results)
+			},
+		})
+		if results != nil {
+			all = append(all, results)
+		}
+	}
+
+	// Compare the clients with the first one.
+	if len(all) < 2 {
+		return
+	}
+	ref := all[0]
+	for _, results := range all[1:] {
+		results := results
+		t.Run(hivesim.TestSpec{
+			Name:        fmt.Sprintf("compare %s with %s", results.client, ref.client),
+			Description: "Compares the query results of two clients.",
+			Run: func(t *hivesim.T) {
+				compareClients(t, ref, results)
+			},
+		})
+	}
+}
+
+// checkHead verifies that the client has imported the test chain.
+func checkHead(client *hivesim.Client, chain *testChain) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	head, err := ethclient.NewClient(client.RPC()).HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't query chain head: %v", err)
+	}
+	want := chain.head()
+	if head.Hash() != want.Hash() {
+		return fmt.Errorf("wrong chain head %d (%s), want %d (%s)", head.Number, head.Hash().TerminalString(), want.Number(), want.Hash().TerminalString())
+	}
+	return nil
+}
+
+// runQueries runs the queries against the blocks before and after each fork.
+func runQueries(client *hivesim.Client, chain *testChain) *clientResults {
+	results := &clientResults{client: client.Type, results: make(map[string]response)}
+	for _, f := range forks {
+		for _, number := range []uint64{f.block - 1, f.block} {
+			for _, q := range chain.blockQueries(number) {
+				results.queries = append(results.queries, q)
+				results.results[q.String()] = q.run(client.RPC())
+			}
+		}
+	}
+	return results
+}
+
+// checkForks compares the results of the queries before and after each fork.
+func checkForks(t *hivesim.T, chain *testChain, results *clientResults) {
+	for _, f := range forks {
+		before, after := chain.blockQueries(f.block-1), chain.blockQueries(f.block)
+		for i := range before {
+			method := before[i].method
+			a, b := results.results[before[i].String()], results.results[after[i].String()]
+			switch {
+			case a.err != "" && b.err != "":
+				t.Errorf("%s: %s failed: %s", f.name, method, a.err)
+				continue
+			case a.err != "":
+				t.Errorf("%s: %s failed before fork: %s", f.name, method, a.err)
+				continue
+			case b.err != "":
+				t.Errorf("%s: %s failed after fork: %s", f.name, method, b.err)
+				continue
+			}
+
+			fc := diffFields(a.result, b.result, f.newFields[method])
+			if len(fc.expected) > 0 {
+				t.Logf("%s: %s has new fields %s", f.name, method, strings.Join(fc.expected, ", "))
+			}
+			if fc.empty() {
+				continue
+			}
+			if len(fc.removed) > 0 {
+				t.Errorf("%s: %s lost fields %s", f.name, method, strings.Join(fc.removed, ", "))
+			}
+			if len(fc.added) > 0 {
+				t.Errorf("%s: %s has unexpected new fields %s", f.name, method, strings.Join(fc.added, ", "))
+			}
+			if len(fc.changed) > 0 {
+				t.Errorf("%s: %s changed field types %s", f.name, method, strings.Join(fc.changed, ", "))
+			}
+		}
+	}
+}
+
+// compareClients compares the query results of two clients.
+func compareClients(t *hivesim.T, ref, results *clientResults) {
+	for _, q := range ref.queries {
+		key := q.String()
+		a, b := ref.results[key], results.results[key]
+		switch {
+		case a.err != "" || b.err != "":
+			if (a.err == "") != (b.err == "") {
+				t.Errorf("%s: %s error %q, %s error %q", key, ref.client, a.err, results.client, b.err)
+			}
+		default:
+			for _, d := range diffValues("result", a.result, b.result) {
+				t.Errorf("%s: %s: %s has %s, %s has %s", key, d.path, ref.client, d.a, results.client, d.b)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// query is an RPC call against a block of the test chain.
+type query struct {
+	method string
+	params []interface{}
+}
+
+func (q query) String() string {
+	params, _ := json.Marshal(q.params)
+	return q.method + string(params)
+}
+
+// response is the outcome of a query.
+type response struct {
+	result interface{} // decoded JSON result
+	err    string      // set if the call failed
+}
+
+// blockQueries returns the canned queries for the given block. The queries only
+// refer to the block's first transaction, which is a legacy transaction in all
+// blocks, so the queries against blocks before and after a fork are comparable.
+func (c *testChain) blockQueries(number uint64) []query {
+	var (
+		block = c.block(number)
+		num   = hexutil.EncodeUint64(number)
+		tx    = block.Transactions()[0].Hash()
+	)
+	return []query{
+		{"eth_getBlockByNumber", []interface{}{num, false}},
+		{"eth_getBlockByNumber", []interface{}{num, true}},
+		{"eth_getBlockByHash", []interface{}{block.Hash(), false}},
+		{"eth_getBlockTransactionCountByNumber", []interface{}{num}},
+		{"eth_getUncleCountByBlockNumber", []interface{}{num}},
+		{"eth_getTransactionByBlockNumberAndIndex", []interface{}{num, "0x0"}},
+		{"eth_getTransactionByHash", []interface{}{tx}},
+		{"eth_getTransactionReceipt", []interface{}{tx}},
+		{"eth_getBalance", []interface{}{sender, num}},
+		{"eth_getTransactionCount", []interface{}{sender, num}},
+		{"eth_getCode", []interface{}{contract, num}},
+		{"eth_getStorageAt", []interface{}{contract, "0x0", num}},
+		{"eth_getLogs", []interface{}{map[string]interface{}{"fromBlock": num, "toBlock": num}}},
+		{"eth_call", []interface{}{map[string]interface{}{"from": sender, "to": contract, "gas": "0x186a0"}, num}},
+	}
+}
+
+// run performs the query.
+func (q query) run(client *rpc.Client) response {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var result json.RawMessage
+	if err := client.CallContext(ctx, &result, q.method, q.params...); err != nil {
+		return response{err: err.Error()}
+	}
+	var resp response
+	if err := json.Unmarshal(result, &resp.result); err != nil {
+		return response{err: fmt.Sprintf("invalid JSON result: %v", err)}
+	}
+	return resp
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+// newRunID creates a unique identifier for a hive run.
+// IDs are prefixed by the start time, so they sort by date.
+func newRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%d-%x", time.Now().Unix(), b)
+}
+
+// statsFile returns the path of the resource stats file of a run.
+func statsFile(resultsRoot, runID string) string {
+	return filepath.Join(resultsRoot, "stats", runID+".json")
+}
+
+// writeResourceStats stores the resource usage of a run in the results directory.
+func writeResourceStats(resultsRoot string, stats libhive.ResourceStats) error {
+	file := statsFile(resultsRoot, stats.RunID)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, content, 0644)
+}
+
+// statsCommand implements 'hive stats <run>'.
+func statsCommand(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	resultsRoot := fs.String("results-root", "workspace/logs", "Results `directory` of the run.")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: hive stats [options] <run ID>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var stats libhive.ResourceStats
+	content, err := ioutil.ReadFile(statsFile(*resultsRoot, fs.Arg(0)))
+	if err != nil {
+		fatal(err)
+	}
+	if err := json.Unmarshal(content, &stats); err != nil {
+		fatal("invalid stats file:", err)
+	}
+	fmt.Printf("run:                          %s\n", stats.RunID)
+	fmt.Printf("duration:                     %v\n", stats.End.Sub(stats.Start).Round(time.Second))
+	fmt.Printf("containers created:           %d\n", stats.ContainersCreated)
+	fmt.Printf("peak concurrent containers:   %d\n", stats.PeakConcurrent)
+	fmt.Printf("total container CPU time:     %.1fs\n", stats.CPUSeconds)
+}
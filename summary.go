@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+// summaryMarker is prepended to the summary line when it is written to stdout or
+// stderr, so it can be told apart from other output.
+const summaryMarker = "hive-summary: "
+
+// runSummary is the machine-readable summary of a hive run.
+type runSummary struct {
+	RunID      string            `json:"runID"`
+	ResultsDir string            `json:"resultsDir"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Pass       int               `json:"pass"`
+	Fail       int               `json:"fail"`
+	Timeout    int               `json:"timeout"`
+	Skip       int               `json:"skip"`
+	NotRun     int               `json:"notRun"` // tests left out due to the time limit
+
+	Clients     map[string]*clientSummary `json:"clients"`
+	Regressions []regression              `json:"regressions,omitempty"`
+
+	// These are keyed by "suite/test" and include tests which didn't start a client.
+	Failures  []string           `json:"failures,omitempty"`
+	Flaky     []string           `json:"flaky,omitempty"`     // passed after failed attempts
+	Durations map[string]float64 `json:"durations,omitempty"` // run time in seconds
+
+	BuildFailures []buildFailure `json:"buildFailures,omitempty"`
+}
+
+// clientSummary counts the results of tests which started a client.
+type clientSummary struct {
+	Version  string   `json:"version"`
+	Source   string   `json:"source,omitempty"` // registry image by digest
+	Pass     int      `json:"pass"`
+	Fail     int      `json:"fail"`
+	Timeout  int      `json:"timeout"`
+	Skip     int      `json:"skip"`
+	Failures []string `json:"failures,omitempty"` // failed tests as "suite/test"
+}
+
+// buildFailure is a client or simulator image which failed to build.
+type buildFailure struct {
+	Name  string                    `json:"name"`
+	Kind  string                    `json:"kind"` // "client" or "simulator"
+	Cause libhive.BuildFailureCause `json:"cause"`
+	Log   string                    `json:"log,omitempty"` // build output, relative to the results directory
+	Error string                    `json:"error"`
+}
+
+// regression is a test which failed in this run, but not in the baseline run.
+type regression struct {
+	Client string `json:"client"`
+	Test   string `json:"test"`
+}
+
+// summarizeRun creates the summary of the given test suites.
+func summarizeRun(runID, resultsDir string, suites []*libhive.TestSuite) *runSummary {
+	s := &runSummary{
+		RunID:      runID,
+		ResultsDir: resultsDir,
+		Clients:    make(map[string]*clientSummary),
+		Durations:  make(map[string]float64),
+	}
+	for _, suite := range suites {
+		for _, test := range suite.TestCases {
+			name := suite.Name + "/" + test.Name
+			if test.SummaryResult.NotRun {
+				s.NotRun++
+				continue
+			}
+			s.Pass, s.Fail, s.Timeout, s.Skip = countResult(test.SummaryResult, s.Pass, s.Fail, s.Timeout, s.Skip)
+			if !test.SummaryResult.Pass {
+				s.Failures = append(s.Failures, name)
+			} else if len(test.Attempts) > 0 {
+				s.Flaky = append(s.Flaky, name)
+			}
+			if !test.End.IsZero() {
+				s.Durations[name] = test.End.Sub(test.Start).Seconds()
+			}
+
+			seen := make(map[string]bool)
+			for _, client := range test.ClientInfo {
+				if seen[client.Name] {
+					continue
+				}
+				seen[client.Name] = true
+				cs := s.Clients[client.Name]
+				if cs == nil {
+					cs = &clientSummary{Version: suite.ClientVersions[client.Name]}
+					s.Clients[client.Name] = cs
+				}
+				cs.Pass, cs.Fail, cs.Timeout, cs.Skip = countResult(test.SummaryResult, cs.Pass, cs.Fail, cs.Timeout, cs.Skip)
+				if !test.SummaryResult.Pass {
+					cs.Failures = append(cs.Failures, name)
+				}
+			}
+		}
+	}
+	for _, cs := range s.Clients {
+		sort.Strings(cs.Failures)
+	}
+	sort.Strings(s.Failures)
+	sort.Strings(s.Flaky)
+	return s
+}
+
+func countResult(r libhive.TestResult, pass, fail, timeout, skip int) (int, int, int, int) {
+	switch {
+	case r.Pass && r.Skipped():
+		skip++
+	case r.Pass:
+		pass++
+	case r.Category == libhive.FailureTimeout:
+		timeout++
+	default:
+		fail++
+	}
+	return pass, fail, timeout, skip
+}
+
+// compareBaseline sets the regressions of s relative to a previous run. Clients which
+// are not part of the baseline run are not compared.
+func (s *runSummary) compareBaseline(base *runSummary) {
+	s.Regressions = nil
+	names := make([]string, 0, len(s.Clients))
+	for name := range s.Clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		baseClient, ok := base.Clients[name]
+		if !ok {
+			continue
+		}
+		baseFailures := make(map[string]bool, len(baseClient.Failures))
+		for _, test := range baseClient.Failures {
+			baseFailures[test] = true
+		}
+		for _, test := range s.Clients[name].Failures {
+			if !baseFailures[test] {
+				s.Regressions = append(s.Regressions, regression{Client: name, Test: test})
+			}
+		}
+	}
+}
+
+// testHistory returns the test results of the summarized run.
+func (s *runSummary) testHistory() map[string]libhive.TestHistory {
+	h := make(map[string]libhive.TestHistory, len(s.Durations))
+	for name, d := range s.Durations {
+		h[name] = libhive.TestHistory{Duration: time.Duration(d * float64(time.Second))}
+	}
+	for _, name := range s.Failures {
+		th := h[name]
+		th.Failed = true
+		h[name] = th
+	}
+	return h
+}
+
+// summaryFile returns the path of the summary file of a run.
+func summaryFile(resultsRoot, runID string) string {
+	return filepath.Join(resultsRoot, "summaries", runID+".json")
+}
+
+// loadSummary reads a run summary file.
+func loadSummary(file string) (*runSummary, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s := new(runSummary)
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("invalid summary file %s: %v", file, err)
+	}
+	return s, nil
+}
+
+// writeSummary stores the summary in the results directory and writes it as a
+// single line to the given file descriptor. If fd is zero, the summary is only
+// stored.
+func writeSummary(resultsRoot string, fd int, s *runSummary) error {
+	content, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	file := summaryFile(resultsRoot, s.RunID)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, content, 0644); err != nil {
+		return err
+	}
+	switch fd {
+	case 0:
+	case 1:
+		_, err = fmt.Fprintf(os.Stdout, "%s%s\n", summaryMarker, content)
+	case 2:
+		_, err = fmt.Fprintf(os.Stderr, "%s%s\n", summaryMarker, content)
+	default:
+		f := os.NewFile(uintptr(fd), "summary")
+		defer f.Close()
+		_, err = fmt.Fprintf(f, "%s\n", content)
+	}
+	return err
+}
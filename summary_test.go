@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+func TestSummarizeRun(t *testing.T) {
+	client := func(name string) map[string]*libhive.ClientInfo {
+		return map[string]*libhive.ClientInfo{"c1": {Name: name}, "c2": {Name: name}}
+	}
+	suites := []*libhive.TestSuite{{
+		Name:           "suite",
+		ClientVersions: map[string]string{"geth": "v1"},
+		TestCases: map[libhive.TestID]*libhive.TestCase{
+			1: {Name: "a", SummaryResult: libhive.TestResult{Pass: true}, ClientInfo: client("geth")},
+			2: {Name: "b", SummaryResult: libhive.TestResult{Category: libhive.FailureAssertion}, ClientInfo: client("geth")},
+			3: {Name: "c", SummaryResult: libhive.TestResult{Category: libhive.FailureTimeout}, ClientInfo: client("geth")},
+			4: {Name: "d", SummaryResult: libhive.TestResult{Category: libhive.FailureAssertion}},
+			5: {Name: "e", SummaryResult: libhive.TestResult{Pass: true, Skip: libhive.SkipMissingFeature}, ClientInfo: client("geth")},
+			6: {Name: "f", SummaryResult: libhive.TestResult{Pass: true}, Attempts: []libhive.TestAttempt{{}}},
+		},
+	}}
+	start := time.Unix(1000, 0)
+	suites[0].TestCases[1].Start, suites[0].TestCases[1].End = start, start.Add(90*time.Second)
+	s := summarizeRun("run", "/results", suites)
+	if s.Pass != 2 || s.Fail != 2 || s.Timeout != 1 || s.Skip != 1 {
+		t.Errorf("wrong totals: pass %d, fail %d, timeout %d, skip %d", s.Pass, s.Fail, s.Timeout, s.Skip)
+	}
+	want := &clientSummary{Version: "v1", Pass: 1, Fail: 1, Timeout: 1, Skip: 1, Failures: []string{"suite/b", "suite/c"}}
+	if !reflect.DeepEqual(s.Clients["geth"], want) {
+		t.Errorf("wrong client summary: %+v", s.Clients["geth"])
+	}
+
+	if want := []string{"suite/b", "suite/c", "suite/d"}; !reflect.DeepEqual(s.Failures, want) {
+		t.Errorf("wrong failures: %v", s.Failures)
+	}
+	if want := []string{"suite/f"}; !reflect.DeepEqual(s.Flaky, want) {
+		t.Errorf("wrong flaky tests: %v", s.Flaky)
+	}
+	history := s.testHistory()
+	if h := history["suite/a"]; h.Duration != 90*time.Second || h.Failed {
+		t.Errorf("wrong history of suite/a: %+v", h)
+	}
+	if h := history["suite/d"]; !h.Failed {
+		t.Errorf("wrong history of suite/d: %+v", h)
+	}
+
+	baseline := &runSummary{Clients: map[string]*clientSummary{
+		"geth": {Failures: []string{"suite/c"}},
+	}}
+	s.compareBaseline(baseline)
+	wantRegressions := []regression{{Client: "geth", Test: "suite/b"}}
+	if !reflect.DeepEqual(s.Regressions, wantRegressions) {
+		t.Errorf("wrong regressions: %+v", s.Regressions)
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/hive/internal/libdocker"
+	"github.com/ethereum/hive/internal/libhive"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// smokeVar is a client environment variable toggled by 'hive test-clients'.
+type smokeVar struct {
+	name, value string
+}
+
+// clientSmokeVars are the documented client variables, with the value used to
+// toggle them. See the 'Environment' section of docs/clients.md.
+var clientSmokeVars = []smokeVar{
+	{"HIVE_LOGLEVEL", "5"},
+	{"HIVE_NODETYPE", "full"},
+	{"HIVE_BOOTNODE", "enode://a61215641fb8714a373c80edbfa0ea8878243193f57c96eeb44d0bc019ef295abd4e044fd619bfc4c59731a73fb79afe84e9ab6da0c743ceb479cbb6d263fa91@192.0.2.1:30303"},
+	{"HIVE_GRAPHQL_ENABLED", "1"},
+	{"HIVE_KEYSTORE_PASSWORD", "hive"},
+	{"HIVE_MINER", "0x658bdf435d810c91414ec09147daa6db62406379"},
+	{"HIVE_MINER_EXTRA", "0x686976652d736d6f6b65"},
+	{"HIVE_CLIQUE_PERIOD", "1"},
+	{"HIVE_CLIQUE_PRIVATEKEY", "9c647b8b7c4e7c3490668fb6c11473619db80c93704c70893d3813af4090c39c"},
+	{"HIVE_SKIP_POW", "1"},
+	{"HIVE_NETWORK_ID", "7"},
+	{"HIVE_CHAIN_ID", "7"},
+}
+
+// clientSmokeForks are the documented fork variables in activation order. Forks are
+// toggled cumulatively, i.e. when a fork is enabled, all earlier forks are enabled
+// as well.
+var clientSmokeForks = []string{
+	"HIVE_FORK_HOMESTEAD",
+	"HIVE_FORK_DAO_BLOCK",
+	"HIVE_FORK_TANGERINE",
+	"HIVE_FORK_SPURIOUS",
+	"HIVE_FORK_BYZANTIUM",
+	"HIVE_FORK_CONSTANTINOPLE",
+	"HIVE_FORK_PETERSBURG",
+	"HIVE_FORK_ISTANBUL",
+	"HIVE_FORK_MUIRGLACIER",
+	"HIVE_FORK_BERLIN",
+	"HIVE_FORK_LONDON",
+	"HIVE_SHANGHAI_TIMESTAMP",
+	"HIVE_CANCUN_TIMESTAMP",
+}
+
+// clientSmokeForkConfig maps the fork variables to the chain config fields of the
+// geth genesis format.
+var clientSmokeForkConfig = map[string][]string{
+	"HIVE_FORK_HOMESTEAD":      {"homesteadBlock"},
+	"HIVE_FORK_DAO_BLOCK":      {"daoForkBlock"},
+	"HIVE_FORK_TANGERINE":      {"eip150Block"},
+	"HIVE_FORK_SPURIOUS":       {"eip155Block", "eip158Block"},
+	"HIVE_FORK_BYZANTIUM":      {"byzantiumBlock"},
+	"HIVE_FORK_CONSTANTINOPLE": {"constantinopleBlock"},
+	"HIVE_FORK_PETERSBURG":     {"petersburgBlock"},
+	"HIVE_FORK_ISTANBUL":       {"istanbulBlock"},
+	"HIVE_FORK_MUIRGLACIER":    {"muirGlacierBlock"},
+	"HIVE_FORK_BERLIN":         {"berlinBlock"},
+	"HIVE_FORK_LONDON":         {"londonBlock"},
+	"HIVE_SHANGHAI_TIMESTAMP":  {"shanghaiTime"},
+	"HIVE_CANCUN_TIMESTAMP":    {"cancunTime"},
+}
+
+// clientSmokeSkipped are documented variables which can't be toggled on their own.
+var clientSmokeSkipped = map[string]string{
+	"HIVE_UNLOCK": "needs keystore files in /keys",
+}
+
+// smokeCase is a client configuration started by 'hive test-clients'.
+type smokeCase struct {
+	name string
+	env  map[string]string
+}
+
+// clientSmokeCases returns the parameter matrix: the default configuration, each
+// variable on its own, and each fork with all earlier forks.
+func clientSmokeCases() []smokeCase {
+	cases := []smokeCase{{name: "default", env: map[string]string{}}}
+	for _, v := range clientSmokeVars {
+		cases = append(cases, smokeCase{
+			name: v.name + "=" + v.value,
+			env:  map[string]string{v.name: v.value},
+		})
+	}
+	for i, fork := range clientSmokeForks {
+		env := make(map[string]string, i+1)
+		for _, f := range clientSmokeForks[:i+1] {
+			env[f] = "0"
+		}
+		cases = append(cases, smokeCase{name: fork + "=0", env: env})
+	}
+	return cases
+}
+
+// genesis returns a minimal genesis in geth format with the fork variables of the
+// case applied to the chain config. Clients require /genesis.json to start.
+func (c smokeCase) genesis() ([]byte, error) {
+	config := map[string]interface{}{"chainId": 1}
+	if v, ok := c.env["HIVE_CHAIN_ID"]; ok {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HIVE_CHAIN_ID %q", v)
+		}
+		config["chainId"] = id
+	}
+	for _, fork := range clientSmokeForks {
+		v, ok := c.env[fork]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", fork, v)
+		}
+		for _, field := range clientSmokeForkConfig[fork] {
+			config[field] = n
+		}
+	}
+	// Forks after the merge need a chain which is merged from genesis.
+	if _, ok := config["shanghaiTime"]; ok {
+		config["terminalTotalDifficulty"] = 0
+		config["terminalTotalDifficultyPassed"] = true
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"config":     config,
+		"nonce":      "0x0",
+		"timestamp":  "0x0",
+		"extraData":  "0x",
+		"gasLimit":   "0x1c9c380",
+		"difficulty": "0x20000",
+		"coinbase":   "0x0000000000000000000000000000000000000000",
+		"alloc":      map[string]interface{}{},
+	}, "", "  ")
+}
+
+// containerFiles turns file contents into the form of ContainerOptions.Files. Files
+// are keyed by their path in the container.
+func containerFiles(contents map[string][]byte) (map[string]*multipart.FileHeader, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, data := range contents {
+		fw, err := w.CreateFormFile(name, filepath.Base(name))
+		if err != nil {
+			return nil, err
+		}
+		fw.Write(data)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(int64(body.Len()) + 1)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*multipart.FileHeader, len(form.File))
+	for name, fheaders := range form.File {
+		files[name] = fheaders[0]
+	}
+	return files, nil
+}
+
+// unhandledVars returns the documented variables which aren't mentioned by any
+// file in the client directory dir. These are usually misspelled in the entry
+// point script or mapper.
+func unhandledVars(dir string) ([]string, error) {
+	var content strings.Builder
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		content.Write(data)
+		content.WriteByte('\n')
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, v := range clientSmokeVars {
+		names = append(names, v.name)
+	}
+	names = append(names, clientSmokeForks...)
+	for name := range clientSmokeSkipped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var missing []string
+	for _, name := range names {
+		re := regexp.MustCompile(`\b` + name + `\b`)
+		if !re.MatchString(content.String()) {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// testClientsCommand implements 'hive test-clients'. It builds clients and starts
+// them once for every configuration of the parameter matrix, checking that the
+// client comes up. This catches entry point script regressions without running
+// a simulation.
+func testClientsCommand(args []string) {
+	fs := flag.NewFlagSet("test-clients", flag.ExitOnError)
+	var (
+		dockerEndpoint = fs.String("docker.endpoint", "unix:///var/run/docker.sock", "Endpoint of the local Docker daemon.")
+		dockerEngine   = fs.String("docker.engine", libdocker.EngineDocker, "Container `engine` behind the endpoint: docker or podman.")
+		dockerPull     = fs.Bool("docker.pull", false, "Refresh base images when building images.")
+		dockerOutput   = fs.Bool("docker.output", false, "Relay all docker output to stderr.")
+		resultsRoot    = fs.String("results-root", "workspace/logs", "Target `directory` for client logs.")
+		clients        = fs.String("client", "", "Comma separated `list` of clients to test. By default, all eth1 clients are tested.")
+		clientTimeout  = fs.Duration("client.checktimelimit", 3*time.Minute, "The `timeout` of waiting for clients to open up the RPC port.")
+		loglevel       = fs.Int("loglevel", 3, "Log `level` for system events. Supports values 0-5.")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: hive test-clients [options]")
+		fmt.Fprintln(os.Stderr, "Starts clients with each documented HIVE_* variable and checks that they come up.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	log15.Root().SetHandler(log15.LvlFilterHandler(log15.Lvl(*loglevel), log15.StreamHandler(os.Stderr, log15.TerminalFormat())))
+
+	inv, err := libhive.LoadInventory(".")
+	if err != nil {
+		fatal(err)
+	}
+	clientList := splitAndTrim(*clients, ",")
+	if len(clientList) == 0 {
+		for _, name := range sortedKeys(inv.Clients) {
+			if meta, err := inv.ClientMetadata(name); err == nil && meta.HasRole("eth1") {
+				clientList = append(clientList, name)
+			}
+		}
+	}
+	for _, client := range clientList {
+		if !inv.HasClient(client) {
+			fatal(fmt.Errorf("unknown client %q", client))
+		}
+	}
+
+	endpoint, err := engineEndpoint(fs, *dockerEngine, *dockerEndpoint)
+	if err != nil {
+		fatal(err)
+	}
+	runID := newRunID()
+	dockerConfig := &libdocker.Config{
+		Inventory:   inv,
+		PullEnabled: *dockerPull,
+		Engine:      *dockerEngine,
+		Labels:      map[string]string{libhive.LabelRunID: runID},
+	}
+	if *dockerOutput {
+		dockerConfig.ContainerOutput = os.Stderr
+		dockerConfig.BuildOutput = os.Stderr
+	}
+	builder, backend, err := libdocker.Connect(endpoint, dockerConfig)
+	if err != nil {
+		fatal(err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	t := &clientTester{
+		builder:     builder,
+		backend:     backend,
+		inv:         inv,
+		runID:       runID,
+		logDir:      filepath.Join(*resultsRoot, "test-clients", runID),
+		liveTimeout: *clientTimeout,
+	}
+	failed := false
+	for _, client := range clientList {
+		if !t.testClient(ctx, client) {
+			failed = true
+		}
+		if ctx.Err() != nil {
+			fatal("interrupted")
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// clientTester runs the parameter matrix of 'hive test-clients'.
+type clientTester struct {
+	builder     libhive.Builder
+	backend     libhive.ContainerBackend
+	inv         libhive.Inventory
+	runID       string
+	logDir      string
+	liveTimeout time.Duration
+}
+
+// testClient builds a client and runs all smoke cases. It prints the results
+// and reports whether all cases passed.
+func (t *clientTester) testClient(ctx context.Context, client string) bool {
+	meta, err := t.inv.ClientMetadata(client)
+	if err != nil {
+		fmt.Printf("%-5s %s: %v\n", doctorFail, client, err)
+		return false
+	}
+	if !meta.HasRole("eth1") {
+		fmt.Printf("%-5s %s: skipped, client roles are %s\n", doctorWarn, client, strings.Join(meta.Roles, ", "))
+		return true
+	}
+	if missing, err := unhandledVars(t.inv.ClientDirectory(client)); err != nil {
+		fmt.Printf("%-5s %s: %v\n", doctorFail, client, err)
+		return false
+	} else if len(missing) > 0 {
+		fmt.Printf("%-5s %s: client files don't mention %s\n", doctorWarn, client, strings.Join(missing, ", "))
+	}
+
+	_, branch := libhive.SplitClientName(client)
+	build, err := meta.BuildInfo("", branch)
+	if err != nil {
+		fmt.Printf("%-5s %s: %v\n", doctorFail, client, err)
+		return false
+	}
+	image, err := t.builder.BuildClientImage(ctx, client, build)
+	if err != nil {
+		fmt.Printf("%-5s %s: build failed: %v\n", doctorFail, client, err)
+		return false
+	}
+
+	checkPort := uint16(libhive.StandardRPCPort)
+	if meta.Ports.RPC != 0 {
+		checkPort = uint16(meta.Ports.RPC)
+	}
+	ok := true
+	for _, c := range clientSmokeCases() {
+		if ctx.Err() != nil {
+			return false
+		}
+		logFile := filepath.Join(t.logDir, client, smokeLogName(c.name))
+		if err := t.runCase(ctx, client, image, checkPort, c, logFile); err != nil {
+			fmt.Printf("%-5s %s %s: %v\n", doctorFail, client, c.name, err)
+			fmt.Printf("      log: %s\n", logFile)
+			ok = false
+		} else {
+			fmt.Printf("%-5s %s %s\n", doctorOK, client, c.name)
+		}
+	}
+	return ok
+}
+
+// runCase starts a client container with the environment and genesis of a smoke
+// case and waits for its RPC port to open.
+func (t *clientTester) runCase(ctx context.Context, client, image string, checkPort uint16, c smokeCase, logFile string) error {
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		return err
+	}
+	opts, err := c.containerOptions(checkPort)
+	if err != nil {
+		return err
+	}
+	opts.LogFile = logFile
+	opts.Labels = map[string]string{libhive.LabelRunID: t.runID, libhive.LabelClient: client}
+	id, err := t.backend.CreateContainer(ctx, image, opts)
+	if err != nil {
+		return err
+	}
+	startCtx, cancel := context.WithTimeout(ctx, t.liveTimeout)
+	defer cancel()
+	info, err := t.backend.StartContainer(startCtx, id, opts)
+	if info != nil && info.Wait != nil {
+		defer info.Wait()
+	}
+	if err != nil {
+		return err
+	}
+	return t.backend.DeleteContainer(id)
+}
+
+// containerOptions returns the options of the client container of a smoke case.
+func (c smokeCase) containerOptions(checkPort uint16) (libhive.ContainerOptions, error) {
+	genesis, err := c.genesis()
+	if err != nil {
+		return libhive.ContainerOptions{}, err
+	}
+	files, err := containerFiles(map[string][]byte{"/genesis.json": genesis})
+	if err != nil {
+		return libhive.ContainerOptions{}, err
+	}
+	return libhive.ContainerOptions{Env: c.env, Files: files, CheckLive: checkPort}, nil
+}
+
+// smokeLogName turns a case name into a log file name.
+func smokeLogName(name string) string {
+	name = strings.ToLower(name)
+	if i := strings.IndexByte(name, '='); i >= 0 {
+		name = name[:i]
+	}
+	return name + ".log"
+}
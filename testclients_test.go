@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/hive/internal/fakes"
+	"github.com/ethereum/hive/internal/libhive"
+)
+
+// This test checks that the parameter matrix of 'hive test-clients' covers
+// the variables documented in docs/clients.md.
+func TestClientSmokeVarsDocumented(t *testing.T) {
+	content, err := ioutil.ReadFile(filepath.Join("docs", "clients.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var documented []string
+	for _, m := range regexp.MustCompile("(?m)^\\| `(HIVE_[A-Z0-9_]+)`").FindAllStringSubmatch(string(content), -1) {
+		documented = append(documented, m[1])
+	}
+	sort.Strings(documented)
+
+	var matrix []string
+	for _, v := range clientSmokeVars {
+		matrix = append(matrix, v.name)
+	}
+	matrix = append(matrix, clientSmokeForks...)
+	for name := range clientSmokeSkipped {
+		matrix = append(matrix, name)
+	}
+	sort.Strings(matrix)
+
+	if !reflect.DeepEqual(matrix, documented) {
+		t.Fatalf("matrix doesn't match docs\nmatrix: %v\ndocs:   %v", matrix, documented)
+	}
+}
+
+func TestClientSmokeCases(t *testing.T) {
+	cases := clientSmokeCases()
+	if want := 1 + len(clientSmokeVars) + len(clientSmokeForks); len(cases) != want {
+		t.Fatalf("wrong number of cases: got %d, want %d", len(cases), want)
+	}
+	if len(cases[0].env) != 0 {
+		t.Fatalf("default case has env %v", cases[0].env)
+	}
+	// Fork cases enable all earlier forks.
+	berlin := cases[1+len(clientSmokeVars)+9]
+	if berlin.name != "HIVE_FORK_BERLIN=0" {
+		t.Fatalf("wrong case name %q", berlin.name)
+	}
+	if len(berlin.env) != 10 || berlin.env["HIVE_FORK_HOMESTEAD"] != "0" || berlin.env["HIVE_FORK_LONDON"] != "" {
+		t.Fatalf("wrong env for %s: %v", berlin.name, berlin.env)
+	}
+}
+
+func TestUnhandledVars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hive-test-clients")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var script string
+	for _, v := range clientSmokeVars {
+		script += "echo $" + v.name + "\n"
+	}
+	for _, fork := range clientSmokeForks {
+		if fork != "HIVE_FORK_MUIRGLACIER" && fork != "HIVE_FORK_LONDON" {
+			script += "echo $" + fork + "\n"
+		}
+	}
+	// Misspelled and prefixed names don't count.
+	script += "echo $HIVE_FORK_MUIR_GLACIER $HIVE_FORK_LONDON_BLOCK\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "client.sh"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "mapper.jq"), []byte("env.HIVE_UNLOCK"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	missing, err := unhandledVars(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"HIVE_FORK_LONDON", "HIVE_FORK_MUIRGLACIER"}
+	if !reflect.DeepEqual(missing, want) {
+		t.Fatalf("wrong result: got %v, want %v", missing, want)
+	}
+}
+
+// This test checks that smoke cases start the client with a genesis file which has
+// the fork variables of the case applied.
+func TestRunCaseGenesis(t *testing.T) {
+	var created libhive.ContainerOptions
+	backend := fakes.NewContainerBackend(&fakes.BackendHooks{
+		CreateContainer: func(image string, opt libhive.ContainerOptions) (string, error) {
+			created = opt
+			return image + "-container", nil
+		},
+	})
+	tester := &clientTester{backend: backend, runID: "run", liveTimeout: time.Second}
+	c := smokeCase{name: "HIVE_FORK_SPURIOUS=0", env: map[string]string{
+		"HIVE_FORK_HOMESTEAD": "0",
+		"HIVE_FORK_TANGERINE": "0",
+		"HIVE_FORK_SPURIOUS":  "0",
+	}}
+	logFile := filepath.Join(t.TempDir(), "client.log")
+	if err := tester.runCase(context.Background(), "client", "image", 8545, c, logFile); err != nil {
+		t.Fatal(err)
+	}
+
+	fh := created.Files["/genesis.json"]
+	if fh == nil {
+		t.Fatalf("no /genesis.json in container files: %v", created.Files)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var genesis struct {
+		Config map[string]interface{} `json:"config"`
+	}
+	if err := json.NewDecoder(f).Decode(&genesis); err != nil {
+		t.Fatal("invalid genesis:", err)
+	}
+	for _, field := range []string{"homesteadBlock", "eip150Block", "eip155Block", "eip158Block"} {
+		if v, ok := genesis.Config[field]; !ok || v != float64(0) {
+			t.Errorf("wrong %s in genesis config: %v", field, v)
+		}
+	}
+	if _, ok := genesis.Config["byzantiumBlock"]; ok {
+		t.Errorf("byzantiumBlock is set in genesis config")
+	}
+	if created.Env["HIVE_FORK_SPURIOUS"] != "0" || created.LogFile != logFile {
+		t.Errorf("wrong container options: %+v", created)
+	}
+}
+
+func TestClientSmokeForkConfig(t *testing.T) {
+	for _, fork := range clientSmokeForks {
+		if len(clientSmokeForkConfig[fork]) == 0 {
+			t.Errorf("no genesis config field for %s", fork)
+		}
+	}
+}